@@ -21,6 +21,7 @@ import (
 
 	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
 	internalcreate "sigs.k8s.io/kind/pkg/cluster/internal/create"
+	"sigs.k8s.io/kind/pkg/commons"
 	internalencoding "sigs.k8s.io/kind/pkg/internal/apis/config/encoding"
 )
 
@@ -94,6 +95,51 @@ func CreateWithAvoidCreation(avoidCreation bool) CreateOption {
 	})
 }
 
+// CreateWithAdoptKubeconfig adopts the existing cluster reachable with the
+// given kubeconfig instead of creating the workload cluster
+func CreateWithAdoptKubeconfig(adoptKubeconfig string) CreateOption {
+	return createOptionAdapter(func(o *internalcreate.ClusterOptions) error {
+		o.AdoptKubeconfig = adoptKubeconfig
+		return nil
+	})
+}
+
+// CreateWithTemplatesDir loads the templates found in the given directory
+// instead of the embedded ones
+func CreateWithTemplatesDir(templatesDir string) CreateOption {
+	return createOptionAdapter(func(o *internalcreate.ClusterOptions) error {
+		o.TemplatesDir = templatesDir
+		return nil
+	})
+}
+
+// CreateWithMetricsPushgateway pushes the duration and outcome of the provisioning
+// phases to the given Prometheus Pushgateway
+func CreateWithMetricsPushgateway(pushgatewayURL string) CreateOption {
+	return createOptionAdapter(func(o *internalcreate.ClusterOptions) error {
+		o.MetricsPushgateway = pushgatewayURL
+		return nil
+	})
+}
+
+// CreateWithOperationID reports the provisioning to the management cluster as the
+// asynchronous operation with the given ID
+func CreateWithOperationID(operationID string) CreateOption {
+	return createOptionAdapter(func(o *internalcreate.ClusterOptions) error {
+		o.OperationID = operationID
+		return nil
+	})
+}
+
+// CreateWithBootstrapConfig tunes the local cluster with the given node image, mounts,
+// API server port and resources
+func CreateWithBootstrapConfig(bootstrap commons.BootstrapConfig) CreateOption {
+	return createOptionAdapter(func(o *internalcreate.ClusterOptions) error {
+		o.Bootstrap = bootstrap
+		return nil
+	})
+}
+
 // CreateWithWaitForceDelete removes local cluster container
 func CreateWithForceDelete(forceDelete bool) CreateOption {
 	return createOptionAdapter(func(o *internalcreate.ClusterOptions) error {
@@ -102,6 +148,15 @@ func CreateWithForceDelete(forceDelete bool) CreateOption {
 	})
 }
 
+// CreateWithReuseBootstrap reuses the local cluster if it already exists, and keeps it
+// after the provisioning for the following runs
+func CreateWithReuseBootstrap(reuseBootstrap bool) CreateOption {
+	return createOptionAdapter(func(o *internalcreate.ClusterOptions) error {
+		o.ReuseBootstrap = reuseBootstrap
+		return nil
+	})
+}
+
 // CreateWithWaitForReady configures a maximum wait time for the control plane
 // node(s) to be ready. By default no waiting is performed
 func CreateWithWaitForReady(waitTime time.Duration) CreateOption {
@@ -0,0 +1,179 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"encoding/base64"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const accessKubeconfigsNamespace = "keos-access"
+
+// The ClusterRoles of the audiences aggregate the built-in edit and view ones, which only grant
+// access to namespaced resources, with the read access to the cluster-scoped ones
+var accessClusterRoles = map[string]string{
+	"operator": "edit",
+	"viewer":   "view",
+}
+
+var accessClusterScopedRules = []map[string]interface{}{
+	{"apiGroups": []string{""}, "resources": []string{"nodes", "namespaces", "persistentvolumes"}, "verbs": []string{"get", "list", "watch"}},
+	{"apiGroups": []string{"storage.k8s.io"}, "resources": []string{"storageclasses"}, "verbs": []string{"get", "list", "watch"}},
+}
+
+// createAccessKubeconfigs writes a kubeconfig for each audience next to the admin one, as
+// <kubeconfig>-<audience>, with the token of a ServiceAccount bound to its keos-<audience> ClusterRole
+func createAccessKubeconfigs(n nodes.Node, k string, localKubeconfigPath string, audiences []string) error {
+	adminKubeconfig, err := os.ReadFile(localKubeconfigPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to read the workload cluster kubeconfig")
+	}
+	var kubeconfig struct {
+		Clusters []struct {
+			Cluster struct {
+				Server                   string `yaml:"server"`
+				CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			} `yaml:"cluster"`
+		} `yaml:"clusters"`
+	}
+	if err = yaml.Unmarshal(adminKubeconfig, &kubeconfig); err != nil || len(kubeconfig.Clusters) == 0 {
+		return errors.New("failed to parse the workload cluster kubeconfig")
+	}
+	cluster := kubeconfig.Clusters[0].Cluster
+
+	batch := newManifestBatch(k)
+	err = batch.add(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Namespace",
+		"metadata":   map[string]string{"name": accessKubeconfigsNamespace},
+	})
+	if err != nil {
+		return err
+	}
+	for _, audience := range audiences {
+		name := "keos-" + audience
+		err = batch.add(
+			map[string]interface{}{
+				"apiVersion": "rbac.authorization.k8s.io/v1",
+				"kind":       "ClusterRole",
+				"metadata":   map[string]interface{}{"name": name},
+				"aggregationRule": map[string]interface{}{
+					"clusterRoleSelectors": []map[string]interface{}{
+						{"matchLabels": map[string]string{"rbac.authorization.k8s.io/aggregate-to-" + accessClusterRoles[audience]: "true"}},
+						{"matchLabels": map[string]string{"keos.stratio.com/aggregate-to-" + audience: "true"}},
+					},
+				},
+			},
+			map[string]interface{}{
+				"apiVersion": "rbac.authorization.k8s.io/v1",
+				"kind":       "ClusterRole",
+				"metadata": map[string]interface{}{
+					"name":   name + "-cluster-scoped",
+					"labels": map[string]string{"keos.stratio.com/aggregate-to-" + audience: "true"},
+				},
+				"rules": accessClusterScopedRules,
+			},
+			map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ServiceAccount",
+				"metadata":   map[string]string{"name": name, "namespace": accessKubeconfigsNamespace},
+			},
+			map[string]interface{}{
+				"apiVersion": "rbac.authorization.k8s.io/v1",
+				"kind":       "ClusterRoleBinding",
+				"metadata":   map[string]string{"name": name},
+				"roleRef":    map[string]string{"apiGroup": "rbac.authorization.k8s.io", "kind": "ClusterRole", "name": name},
+				"subjects":   []map[string]string{{"kind": "ServiceAccount", "name": name, "namespace": accessKubeconfigsNamespace}},
+			},
+			map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Secret",
+				"type":       "kubernetes.io/service-account-token",
+				"metadata": map[string]interface{}{
+					"name":        name + "-token",
+					"namespace":   accessKubeconfigsNamespace,
+					"annotations": map[string]string{"kubernetes.io/service-account.name": name},
+				},
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	if err = batch.apply(n); err != nil {
+		return errors.Wrap(err, "failed to create the ServiceAccounts of the kubeconfigs")
+	}
+
+	for _, audience := range audiences {
+		name := "keos-" + audience
+		// The token is populated by the token controller
+		var token string
+		for i := 0; i < 30 && token == ""; i++ {
+			if i > 0 {
+				time.Sleep(2 * time.Second)
+			}
+			c := "kubectl --kubeconfig " + commons.ShellQuote(k) + " -n " + accessKubeconfigsNamespace + " get secret " + commons.ShellQuote(name) + "-token -o jsonpath='{.data.token}'"
+			token, err = commons.ExecuteCommand(n, c, 5, 3)
+			if err != nil {
+				return errors.Wrap(err, "failed to get the "+audience+" token")
+			}
+			token = strings.TrimSpace(token)
+		}
+		if token == "" {
+			return errors.New("the " + audience + " token has not been populated")
+		}
+		tokenBytes, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return errors.Wrap(err, "failed to decode the "+audience+" token")
+		}
+
+		accessKubeconfig, err := yaml.Marshal(map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Config",
+			"clusters": []map[string]interface{}{{
+				"name": "workload-cluster",
+				"cluster": map[string]string{
+					"server":                     cluster.Server,
+					"certificate-authority-data": cluster.CertificateAuthorityData,
+				},
+			}},
+			"users": []map[string]interface{}{{
+				"name": name,
+				"user": map[string]string{"token": string(tokenBytes)},
+			}},
+			"contexts": []map[string]interface{}{{
+				"name":    name,
+				"context": map[string]string{"cluster": "workload-cluster", "user": name},
+			}},
+			"current-context": name,
+		})
+		if err != nil {
+			return err
+		}
+		if err = os.WriteFile(localKubeconfigPath+"-"+audience, accessKubeconfig, 0600); err != nil {
+			return errors.Wrap(err, "failed to save the "+audience+" kubeconfig")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const addonPDBSuffix = "-keos-pdb"
+
+// createAddonPDBs creates a PodDisruptionBudget for each addon Deployment installed with Helm which
+// has none, so the node rollouts only evict some of its pods at a time. The ones already selected by
+// a PodDisruptionBudget are skipped, as the pods matched by several of them cannot be evicted
+func createAddonPDBs(n nodes.Node, k string, addonsPDB commons.AddonsPDB, chartsList map[string]commons.ChartEntry) error {
+	var maxUnavailable interface{} = 1
+	if addonsPDB.MaxUnavailable != "" {
+		maxUnavailable = addonsPDB.MaxUnavailable
+		if value, err := strconv.Atoi(addonsPDB.MaxUnavailable); err == nil {
+			maxUnavailable = value
+		}
+	}
+
+	batch := newManifestBatch(k)
+	for _, namespace := range getAddonNamespaces(chartsList) {
+		deployments, err := getNamespaceDeployments(n, k, namespace)
+		if err != nil {
+			return err
+		}
+		if len(deployments) == 0 {
+			continue
+		}
+		selectors, err := getNamespacePDBSelectors(n, k, namespace)
+		if err != nil {
+			return err
+		}
+
+		for _, deployment := range deployments {
+			name := deployment.Metadata.Name
+			if !deployment.isHelmManaged() || commons.Contains(addonsPDB.Exclude, namespace+"/"+name) {
+				continue
+			}
+			if isSelectedByPDB(selectors, deployment.Spec.Template.Metadata.Labels) {
+				continue
+			}
+			err = batch.add(map[string]interface{}{
+				"apiVersion": "policy/v1",
+				"kind":       "PodDisruptionBudget",
+				"metadata": map[string]interface{}{
+					"name":      name + addonPDBSuffix,
+					"namespace": namespace,
+					"labels":    map[string]string{"app.kubernetes.io/managed-by": applyFieldManager},
+				},
+				"spec": map[string]interface{}{
+					"maxUnavailable": maxUnavailable,
+					"selector":       deployment.Spec.Selector,
+				},
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if err := batch.apply(n); err != nil {
+		return errors.Wrap(err, "failed to create the PodDisruptionBudgets of the addons")
+	}
+	return nil
+}
+
+// getNamespacePDBSelectors returns the selectors of the PodDisruptionBudgets of a namespace, but the
+// ones created for the addons, which are re-applied
+func getNamespacePDBSelectors(n nodes.Node, k string, namespace string) ([]map[string]interface{}, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Spec struct {
+				Selector map[string]interface{} `json:"selector"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	raw := bytes.Buffer{}
+	cmd := n.Command("kubectl", "--kubeconfig", k, "-n", namespace, "get", "pdb", "-o", "json")
+	if err := cmd.SetStdout(&raw).Run(); err != nil {
+		return nil, errors.Wrap(err, "failed to get the PodDisruptionBudgets of the "+namespace+" namespace")
+	}
+	if err := json.Unmarshal(raw.Bytes(), &list); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the PodDisruptionBudgets of the "+namespace+" namespace")
+	}
+	var selectors []map[string]interface{}
+	for _, pdb := range list.Items {
+		if !strings.HasSuffix(pdb.Metadata.Name, addonPDBSuffix) {
+			selectors = append(selectors, pdb.Spec.Selector)
+		}
+	}
+	return selectors, nil
+}
+
+// isSelectedByPDB returns whether the pod labels match any of the selectors. The selectors with
+// expressions are assumed to match, so no pod is ever selected twice
+func isSelectedByPDB(selectors []map[string]interface{}, labels map[string]string) bool {
+	for _, selector := range selectors {
+		if expressions, ok := selector["matchExpressions"].([]interface{}); ok && len(expressions) > 0 {
+			return true
+		}
+		matchLabels, _ := selector["matchLabels"].(map[string]interface{})
+		matched := true
+		for key, value := range matchLabels {
+			if labels[key] != value {
+				matched = false
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
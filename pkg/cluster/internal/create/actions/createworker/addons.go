@@ -0,0 +1,83 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+// addonDeployment is a Deployment of the addons installed by the provisioner, with the fields its
+// scheduling and disruptions are set from
+type addonDeployment struct {
+	Metadata struct {
+		Name      string            `json:"name"`
+		Namespace string            `json:"namespace"`
+		Labels    map[string]string `json:"labels"`
+	} `json:"metadata"`
+	Spec struct {
+		Selector map[string]interface{} `json:"selector"`
+		Template struct {
+			Metadata struct {
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+			Spec struct {
+				NodeSelector map[string]string        `json:"nodeSelector"`
+				Tolerations  []map[string]interface{} `json:"tolerations"`
+			} `json:"spec"`
+		} `json:"template"`
+	} `json:"spec"`
+}
+
+// getAddonNamespaces returns the namespaces the charts are installed in, along with kube-system
+func getAddonNamespaces(chartsList map[string]commons.ChartEntry) []string {
+	namespaces := []string{"kube-system"}
+	for _, entry := range chartsList {
+		if !commons.Contains(namespaces, entry.Namespace) {
+			namespaces = append(namespaces, entry.Namespace)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// getNamespaceDeployments returns the Deployments of a namespace
+func getNamespaceDeployments(n nodes.Node, k string, namespace string) ([]addonDeployment, error) {
+	var list struct {
+		Items []addonDeployment `json:"items"`
+	}
+	raw := bytes.Buffer{}
+	cmd := n.Command("kubectl", "--kubeconfig", k, "-n", namespace, "get", "deployments", "-o", "json")
+	if err := cmd.SetStdout(&raw).Run(); err != nil {
+		return nil, errors.Wrap(err, "failed to get the Deployments of the "+namespace+" namespace")
+	}
+	if err := json.Unmarshal(raw.Bytes(), &list); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the Deployments of the "+namespace+" namespace")
+	}
+	return list.Items, nil
+}
+
+// isHelmManaged returns whether the Deployment was installed by a chart, as the rest of the ones of
+// the addon namespaces are reconciled by the provider or the operators
+func (d addonDeployment) isHelmManaged() bool {
+	return d.Metadata.Labels["app.kubernetes.io/managed-by"] == "Helm"
+}
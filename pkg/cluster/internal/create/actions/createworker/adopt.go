@@ -0,0 +1,271 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/kind/pkg/cluster/internal/create/actions"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+// externallyManagedAnnotation marks the cluster infrastructure as not managed by Cluster API
+const externallyManagedAnnotation = "cluster.x-k8s.io/managed-by"
+
+type adoptAction struct {
+	vaultPassword      string
+	descriptorPath     string
+	adoptKubeconfig    string
+	keosCluster        commons.KeosCluster
+	clusterCredentials commons.ClusterCredentials
+	clusterConfig      *commons.ClusterConfig
+}
+
+// NewAdoptAction returns a new action for bringing an existing managed cluster under management
+func NewAdoptAction(vaultPassword string, descriptorPath string, adoptKubeconfig string, keosCluster commons.KeosCluster, clusterCredentials commons.ClusterCredentials, clusterConfig *commons.ClusterConfig) actions.Action {
+	return &adoptAction{
+		vaultPassword:      vaultPassword,
+		descriptorPath:     descriptorPath,
+		adoptKubeconfig:    adoptKubeconfig,
+		keosCluster:        keosCluster,
+		clusterCredentials: clusterCredentials,
+		clusterConfig:      clusterConfig,
+	}
+}
+
+// Execute runs the action
+func (a *adoptAction) Execute(ctx *actions.ActionContext) error {
+	commons.SetCommandLogger(ctx.Logger)
+	commons.SetCommandSecrets(append(commons.GetCredentialsSecrets(a.clusterCredentials), a.vaultPassword)...)
+	started := time.Now()
+	err := a.adopt(ctx)
+
+	// The operation is recorded in the workload cluster, whatever its result
+	n, nodeErr := ctx.GetNode()
+	if nodeErr == nil {
+		if recordErr := recordOperation(n, "adopt", a.keosCluster, a.descriptorPath, started, err); recordErr != nil {
+			ctx.Logger.Warn(recordErr.Error())
+		}
+	}
+	return err
+}
+
+func (a *adoptAction) adopt(ctx *actions.ActionContext) error {
+	var c string
+	var err error
+	var keosRegistry KeosRegistry
+	var helmRegistry HelmRegistry
+	majorVersion = strings.Split(a.keosCluster.Spec.K8SVersion, ".")[1]
+
+	// Get the target node
+	n, err := ctx.GetNode()
+	if err != nil {
+		return err
+	}
+
+	providerParams := ProviderParams{
+		ClusterName:        a.keosCluster.Metadata.Name,
+		Region:             a.keosCluster.Spec.Region,
+		Managed:            a.keosCluster.Spec.ControlPlane.Managed,
+		Credentials:        a.clusterCredentials.ProviderCredentials,
+		GithubToken:        a.clusterCredentials.GithubToken,
+		StorageClass:       a.keosCluster.Spec.StorageClass,
+		CAPXConfig:         a.clusterConfig.Spec.CAPXConfig,
+		ControlPlaneFlavor: a.keosCluster.Spec.ControlPlane.Flavor,
+	}
+
+	providerBuilder := getBuilder(a.keosCluster.Spec.InfraProvider)
+	infra := newInfra(providerBuilder)
+	provider := infra.buildProvider(providerParams)
+
+	ctx.Status.Start("Pulling initial Helm Charts 🧭")
+	defer ctx.Status.End(false)
+
+	err = loginHelmRepo(n, a.keosCluster, a.clusterCredentials, &helmRegistry, infra, providerParams)
+	if err != nil {
+		return err
+	}
+
+	err = infra.pullProviderCharts(n, &a.clusterConfig.Spec, a.keosCluster.Spec, a.clusterCredentials)
+	if err != nil {
+		return err
+	}
+
+	ctx.Status.End(true) // End Pulling initial Helm Charts
+
+	for _, registry := range a.keosCluster.Spec.DockerRegistries {
+		if registry.KeosRegistry {
+			keosRegistry.url = registry.URL
+			keosRegistry.registryType = registry.Type
+			continue
+		}
+	}
+
+	if keosRegistry.registryType != "generic" {
+		keosRegistry.user, keosRegistry.pass, err = infra.getRegistryCredentials(providerParams, keosRegistry.url)
+		if err != nil {
+			return errors.Wrap(err, "failed to get docker registry credentials")
+		}
+	} else {
+		keosRegistry.user = a.clusterCredentials.KeosRegistryCredentials["User"]
+		keosRegistry.pass = a.clusterCredentials.KeosRegistryCredentials["Pass"]
+	}
+
+	privateParams := PrivateParams{
+		KeosCluster: a.keosCluster,
+		KeosRegUrl:  keosRegistry.url,
+		Private:     a.clusterConfig.Spec.Private,
+		HelmPrivate: a.clusterConfig.Spec.PrivateHelmRepo,
+	}
+
+	chartsList := infra.getProviderCharts(&a.clusterConfig.Spec, a.keosCluster.Spec)
+	capiClustersNamespace := "cluster-" + a.keosCluster.Metadata.Name
+
+	ctx.Status.Start("Generating secrets file 📝🗝️")
+	defer ctx.Status.End(false)
+
+	err = commons.EnsureSecretsFile(a.keosCluster.Spec, a.vaultPassword, a.clusterCredentials)
+	if err != nil {
+		return errors.Wrap(err, "failed to ensure the secrets file")
+	}
+
+	err = commons.RewriteDescriptorFile(a.descriptorPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to rewrite the descriptor file")
+	}
+
+	ctx.Status.End(true) // End Generating secrets file
+
+	ctx.Status.Start("Importing the workload cluster kubeconfig 📥")
+	defer ctx.Status.End(false)
+
+	kubeconfig, err := os.ReadFile(a.adoptKubeconfig)
+	if err != nil {
+		return errors.Wrap(err, "failed to read the workload cluster kubeconfig")
+	}
+
+	err = commons.WriteFile(n, kubeconfigPath, string(kubeconfig))
+	if err != nil {
+		return errors.Wrap(err, "failed to copy the workload cluster kubeconfig")
+	}
+
+	c = "kubectl --kubeconfig " + kubeconfigPath + " get nodes"
+	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to the workload cluster")
+	}
+
+	// Other operations over the workload cluster must wait until the adoption ends
+	lock, err := acquireClusterLock(n, ctx.Logger, kubeconfigPath, a.keosCluster.Metadata.Name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := lock.release(); err != nil {
+			ctx.Logger.Warn(err.Error())
+		}
+	}()
+
+	localKubeconfigPath := getLocalPath(a.keosCluster, workKubeconfigPath)
+	err = os.MkdirAll(filepath.Dir(localKubeconfigPath), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(localKubeconfigPath, kubeconfig, 0600)
+	if err != nil {
+		return errors.Wrap(err, "failed to save the workload cluster kubeconfig")
+	}
+
+	ctx.Status.End(true) // End Importing the workload cluster kubeconfig
+
+	ctx.Status.Start("Installing CAPx in workload cluster 🎖️")
+	defer ctx.Status.End(false)
+
+	err = provider.deployCertManager(n, keosRegistry.url, kubeconfigPath, privateParams, chartsList)
+	if err != nil {
+		return err
+	}
+
+	certManagerVersion := getChartVersion(a.clusterConfig.Spec.Charts, "cert-manager")
+	if certManagerVersion == "" {
+		return errors.New("Cert manager helm chart version cannot be found ")
+	}
+	err = setClusterctlConfig(n, map[string]interface{}{
+		"cert-manager": map[string]string{"version": certManagerVersion},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to set cert-manager version in clusterctl config")
+	}
+
+	err = provider.installCAPXWorker(n, a.keosCluster, kubeconfigPath)
+	if err != nil {
+		return err
+	}
+
+	// The cluster infrastructure has not been created by Cluster API, so it must be left untouched
+	c = "kubectl --kubeconfig " + kubeconfigPath + " create ns " + commons.ShellQuote(capiClustersNamespace)
+	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to create cluster's Namespace in workload cluster")
+	}
+	c = "kubectl --kubeconfig " + kubeconfigPath + " annotate ns " + commons.ShellQuote(capiClustersNamespace) + " " + externallyManagedAnnotation + "=external"
+	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to annotate cluster's Namespace in workload cluster")
+	}
+
+	ctx.Status.End(true) // End Installing CAPx in workload cluster
+
+	ctx.Status.Start("Configuring Flux in workload cluster 🧭")
+	defer ctx.Status.End(false)
+
+	err = configureFlux(n, kubeconfigPath, privateParams, helmRegistry, a.keosCluster.Spec, chartsList)
+	if err != nil {
+		return errors.Wrap(err, "failed to install Flux in workload cluster")
+	}
+	ctx.Status.End(true) // End Configuring Flux in workload cluster
+
+	ctx.Status.Start("Installing StorageClass in workload cluster 💾")
+	defer ctx.Status.End(false)
+
+	err = infra.configureStorageClass(n, kubeconfigPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to configure StorageClass in workload cluster")
+	}
+	ctx.Status.End(true) // End Installing StorageClass in workload cluster
+
+	ctx.Status.Start("Generating the KEOS descriptor 📝")
+	defer ctx.Status.End(false)
+
+	err = createKEOSDescriptor(a.keosCluster, getStorageClassName(a.keosCluster.Spec.StorageClass))
+	if err != nil {
+		return err
+	}
+
+	err = override_vars(ctx, providerParams, a.keosCluster.Spec.Networks, infra, a.clusterConfig.Spec, getLocalPath(a.keosCluster, overrideVarsPath))
+	if err != nil {
+		return err
+	}
+
+	ctx.Status.End(true) // End Generating KEOS descriptor
+
+	return nil
+}
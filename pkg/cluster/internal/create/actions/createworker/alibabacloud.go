@@ -0,0 +1,239 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/exec"
+)
+
+type AlibabaCloudBuilder struct {
+	capxProvider     string
+	capxVersion      string
+	capxImageVersion string
+	capxManaged      bool
+	capxName         string
+	capxEnvVars      []string
+	scParameters     commons.SCParameters
+	scProvisioner    string
+	csiNamespace     string
+	accessKeyID      string
+	accessKeySecret  string
+	region           string
+}
+
+var alibabacloudCharts = ChartsDictionary{
+	Charts: map[string]map[string]map[string]commons.ChartEntry{
+		"28": {
+			"managed": {},
+			"unmanaged": {
+				"cluster-autoscaler": {Repository: "https://kubernetes.github.io/autoscaler", Version: "9.34.1", Namespace: "kube-system", Pull: false, Reconcile: false},
+				"tigera-operator":    {Repository: "https://docs.projectcalico.org/charts", Version: "v3.28.2", Namespace: "tigera-operator", Pull: true, Reconcile: true},
+			},
+		},
+		"29": {
+			"managed": {},
+			"unmanaged": {
+				"cluster-autoscaler": {Repository: "https://kubernetes.github.io/autoscaler", Version: "9.35.0", Namespace: "kube-system", Pull: false, Reconcile: false},
+				"tigera-operator":    {Repository: "https://docs.projectcalico.org/charts", Version: "v3.28.2", Namespace: "tigera-operator", Pull: true, Reconcile: true},
+			},
+		},
+		"30": {
+			"managed": {},
+			"unmanaged": {
+				"cluster-autoscaler": {Repository: "https://kubernetes.github.io/autoscaler", Version: "9.37.0", Namespace: "kube-system", Pull: false, Reconcile: false},
+				"tigera-operator":    {Repository: "https://docs.projectcalico.org/charts", Version: "v3.28.2", Namespace: "tigera-operator", Pull: true, Reconcile: true},
+			},
+		},
+	},
+}
+
+func newAlibabaCloudBuilder() *AlibabaCloudBuilder {
+	return &AlibabaCloudBuilder{}
+}
+
+func (b *AlibabaCloudBuilder) setCapx(managed bool) {
+	b.capxProvider = "alibabacloud"
+	b.capxVersion = "v0.1.0"
+	b.capxImageVersion = "v0.1.0"
+	b.capxName = "capac"
+	b.capxManaged = managed
+	b.csiNamespace = "kube-system"
+}
+
+func (b *AlibabaCloudBuilder) setCapxEnvVars(p ProviderParams) {
+	b.accessKeyID = p.Credentials["AccessKeyID"]
+	b.accessKeySecret = p.Credentials["AccessKeySecret"]
+	b.region = p.Region
+	b.capxEnvVars = []string{
+		"ALIBABA_CLOUD_ACCESS_KEY_ID=" + b.accessKeyID,
+		"ALIBABA_CLOUD_ACCESS_KEY_SECRET=" + b.accessKeySecret,
+		"ALIBABA_CLOUD_REGION=" + b.region,
+	}
+	if p.GithubToken != "" {
+		b.capxEnvVars = append(b.capxEnvVars, "GITHUB_TOKEN="+p.GithubToken)
+	}
+}
+
+func (b *AlibabaCloudBuilder) setSC(p ProviderParams) {
+	if (p.StorageClass.Parameters != commons.SCParameters{}) {
+		b.scParameters = p.StorageClass.Parameters
+	}
+
+	b.scProvisioner = "diskplugin.csi.alibabacloud.com"
+
+	if b.scParameters.Type == "" {
+		b.scParameters.Type = "cloud_essd"
+	}
+	// ESSD disks are tiered by performance level
+	if b.scParameters.PerformanceLevel == "" && b.scParameters.Type == "cloud_essd" {
+		if p.StorageClass.Class == "premium" {
+			b.scParameters.PerformanceLevel = "PL2"
+		} else {
+			b.scParameters.PerformanceLevel = "PL1"
+		}
+	}
+
+	if p.StorageClass.EncryptionKey != "" {
+		b.scParameters.Encrypted = "true"
+		b.scParameters.KmsKeyId = p.StorageClass.EncryptionKey
+	}
+}
+
+func (b *AlibabaCloudBuilder) pullProviderCharts(n nodes.Node, clusterConfigSpec *commons.ClusterConfigSpec, keosSpec commons.KeosSpec, clusterCredentials commons.ClusterCredentials, clusterType string) error {
+	return pullGenericCharts(n, clusterConfigSpec, keosSpec, clusterCredentials, alibabacloudCharts, clusterType)
+}
+
+func (b *AlibabaCloudBuilder) getProviderCharts(clusterConfigSpec *commons.ClusterConfigSpec, keosSpec commons.KeosSpec, clusterType string) map[string]commons.ChartEntry {
+	return getGenericCharts(clusterConfigSpec, keosSpec, alibabacloudCharts, clusterType)
+}
+
+func (b *AlibabaCloudBuilder) getOverriddenCharts(charts *[]commons.Chart, clusterConfigSpec *commons.ClusterConfigSpec, clusterType string) []commons.Chart {
+	return getGenericOverriddenCharts(charts, clusterConfigSpec, alibabacloudCharts, clusterType)
+}
+
+func (b *AlibabaCloudBuilder) getProvider() Provider {
+	return Provider{
+		capxProvider:     b.capxProvider,
+		capxVersion:      b.capxVersion,
+		capxImageVersion: b.capxImageVersion,
+		capxManaged:      b.capxManaged,
+		capxName:         b.capxName,
+		capxEnvVars:      b.capxEnvVars,
+		scParameters:     b.scParameters,
+		scProvisioner:    b.scProvisioner,
+		csiNamespace:     b.csiNamespace,
+	}
+}
+
+func (b *AlibabaCloudBuilder) installCloudProvider(n nodes.Node, k string, privateParams PrivateParams) error {
+	var c string
+	var err error
+	var cmd exec.Cmd
+
+	// Create the RAM credentials secret used by the CSI driver
+	c = "kubectl --kubeconfig " + commons.ShellQuote(k) + " -n kube-system create secret generic alibabacloud-credentials" +
+		" " + commons.ShellQuote("--from-literal=id="+b.accessKeyID, "--from-literal=secret="+b.accessKeySecret)
+	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to create alibabacloud-credentials secret")
+	}
+
+	// Create the cloud provider config, which expects base64 encoded RAM credentials
+	cloudConfig, err := json.Marshal(map[string]map[string]string{
+		"Global": {
+			"accessKeyID":     base64.StdEncoding.EncodeToString([]byte(b.accessKeyID)),
+			"accessKeySecret": base64.StdEncoding.EncodeToString([]byte(b.accessKeySecret)),
+			"region":          b.region,
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal cloud provider config")
+	}
+	c = "kubectl --kubeconfig " + commons.ShellQuote(k) + " -n kube-system create secret generic cloud-config " + commons.ShellQuote("--from-literal=cloud-config.conf="+string(cloudConfig))
+	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to create cloud provider secret")
+	}
+
+	cloudProviderManifests, err := b.getCloudProviderTemplate(privateParams).render()
+	if err != nil {
+		return errors.Wrap(err, "failed to get cloud provider manifests")
+	}
+
+	// Deploy cloud provider
+	cmd = n.Command("kubectl", "--kubeconfig", k, "apply", "-f", "-")
+	if err = cmd.SetStdin(strings.NewReader(cloudProviderManifests)).Run(); err != nil {
+		return errors.Wrap(err, "failed to deploy alibaba-cloud-controller-manager")
+	}
+
+	return nil
+}
+
+func (b *AlibabaCloudBuilder) getCloudProviderTemplate(privateParams PrivateParams) manifestTemplate {
+	return manifestTemplate{b.capxProvider, "alibaba-cloud-controller-manager.tmpl", "", privateParams}
+}
+
+func (b *AlibabaCloudBuilder) installCSI(n nodes.Node, k string, privateParams PrivateParams, providerParams ProviderParams, chartsList map[string]commons.ChartEntry) error {
+	var err error
+	var cmd exec.Cmd
+
+	csiManifests, err := b.getCSITemplates(privateParams)[0].render()
+	if err != nil {
+		return errors.Wrap(err, "failed to get alibaba-cloud-csi-driver manifests")
+	}
+
+	// Deploy alibaba-cloud-csi-driver
+	cmd = n.Command("kubectl", "--kubeconfig", k, "apply", "-f", "-")
+	if err = cmd.SetStdin(strings.NewReader(csiManifests)).Run(); err != nil {
+		return errors.Wrap(err, "failed to deploy alibaba-cloud-csi-driver")
+	}
+
+	return nil
+}
+
+func (b *AlibabaCloudBuilder) getCSITemplates(privateParams PrivateParams) []manifestTemplate {
+	return []manifestTemplate{{b.capxProvider, "alibaba-cloud-csi-driver.tmpl", "", privateParams}}
+}
+
+func (b *AlibabaCloudBuilder) getRegistryCredentials(p ProviderParams, u string) (string, string, error) {
+	return "", "", errors.New("only generic registries are supported in alibabacloud clusters")
+}
+
+func (b *AlibabaCloudBuilder) configureStorageClass(n nodes.Node, k string) error {
+	return applyGenericStorageClass(n, k, getGenericStorageClass(b.scProvisioner, b.scParameters), nil)
+}
+
+func (b *AlibabaCloudBuilder) internalNginx(p ProviderParams, networks commons.Networks) (bool, error) {
+	return false, nil
+}
+
+func (b *AlibabaCloudBuilder) getOverrideVars(p ProviderParams, networks commons.Networks, clusterConfigSpec commons.ClusterConfigSpec) (map[string][]byte, error) {
+	var overrideVars = make(map[string][]byte)
+
+	return overrideVars, nil
+}
+
+func (b *AlibabaCloudBuilder) postInstallPhase(n nodes.Node, k string) error {
+	return ensureCorednsPdb(n)
+}
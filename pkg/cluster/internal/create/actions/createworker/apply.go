@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+// The manifests are applied server-side, so large objects are not limited by the size of the
+// last-applied-configuration annotation, and the ones previously applied client-side are taken over
+const applyFieldManager = "keos-installer"
+
+// manifestBatch holds the manifests applied together to a cluster, with a single kubectl exec of
+// a multi-document payload instead of an exec each
+type manifestBatch struct {
+	kubeconfig string
+	documents  []string
+}
+
+// newManifestBatch returns an empty batch for the cluster of the kubeconfig, or the local one if empty
+func newManifestBatch(k string) *manifestBatch {
+	return &manifestBatch{kubeconfig: k}
+}
+
+// add marshals the resources as documents of the batch
+func (b *manifestBatch) add(resources ...interface{}) error {
+	for _, resource := range resources {
+		resourceYAML, err := yaml.Marshal(resource)
+		if err != nil {
+			return err
+		}
+		b.documents = append(b.documents, string(resourceYAML))
+	}
+	return nil
+}
+
+// addManifest adds a rendered manifest, which may hold several documents
+func (b *manifestBatch) addManifest(manifest string) {
+	if strings.TrimSpace(manifest) != "" {
+		b.documents = append(b.documents, manifest)
+	}
+}
+
+func (b *manifestBatch) len() int {
+	return len(b.documents)
+}
+
+// String returns the multi-document payload of the batch
+func (b *manifestBatch) String() string {
+	var payload strings.Builder
+	for _, document := range b.documents {
+		payload.WriteString("---\n")
+		payload.WriteString(document)
+		if !strings.HasSuffix(document, "\n") {
+			payload.WriteString("\n")
+		}
+	}
+	return payload.String()
+}
+
+// apply applies the documents of the batch with a single server-side apply
+func (b *manifestBatch) apply(n nodes.Node) error {
+	if b.len() == 0 {
+		return nil
+	}
+	cmd := n.Command("kubectl", applyArgs(b.kubeconfig, "-")...)
+	return cmd.SetStdin(strings.NewReader(b.String())).Run()
+}
+
+// applyManifestFiles applies the manifest files of the node with a single server-side apply
+func applyManifestFiles(n nodes.Node, k string, timeout int, retries int, paths ...string) error {
+	c := "kubectl " + commons.ShellQuote(applyArgs(k, paths...)...)
+	_, err := commons.ExecuteCommand(n, c, timeout, retries)
+	return err
+}
+
+func applyArgs(k string, paths ...string) []string {
+	var args []string
+	if k != "" {
+		args = append(args, "--kubeconfig", k)
+	}
+	args = append(args, "apply", "--server-side", "--force-conflicts", "--field-manager="+applyFieldManager)
+	for _, path := range paths {
+		args = append(args, "-f", path)
+	}
+	return args
+}
+
+// waitForRollouts waits for the rollout of the workloads of a namespace in a single exec, as kubectl
+// rollout status only takes a workload at a time
+func waitForRollouts(n nodes.Node, k string, namespace string, kind string, names []string, timeout string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	kubectl := "kubectl"
+	if k != "" {
+		kubectl += " --kubeconfig " + commons.ShellQuote(k)
+	}
+	var rollouts []string
+	for _, name := range names {
+		rollouts = append(rollouts, kubectl+" -n "+commons.ShellQuote(namespace)+" rollout status "+commons.ShellQuote(kind, name)+" --timeout="+commons.ShellQuote(timeout))
+	}
+	_, err := commons.ExecuteCommand(n, strings.Join(rollouts, " && "), 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to wait for the "+strings.Join(names, ", ")+" "+kind)
+	}
+	return nil
+}
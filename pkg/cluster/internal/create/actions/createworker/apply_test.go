@@ -0,0 +1,260 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/exec"
+)
+
+// applyExecLatency is the round-trip of each exec in the benchmarks, as a kubectl exec in the
+// container costs far more than rendering the manifests
+const applyExecLatency = time.Millisecond
+
+var applyBenchNodeGroups = []int{1, 10, 50}
+
+func TestManifestBatch(t *testing.T) {
+	batch := newManifestBatch(kubeconfigPath)
+	node := &applyNode{record: true}
+	if err := batch.apply(node); err != nil {
+		t.Fatal(err)
+	}
+	if len(node.commands) != 0 {
+		t.Errorf("an empty batch runs %d commands", len(node.commands))
+	}
+
+	if err := batch.add(map[string]string{"kind": "ConfigMap"}, map[string]string{"kind": "Secret"}); err != nil {
+		t.Fatal(err)
+	}
+	batch.addManifest("kind: Namespace")
+	batch.addManifest("\n")
+	batch.addManifest("kind: ServiceAccount\n---\nkind: Role\n")
+	if err := batch.apply(node); err != nil {
+		t.Fatal(err)
+	}
+	if len(node.commands) != 1 {
+		t.Fatalf("the batch is applied with %d commands", len(node.commands))
+	}
+	want := "kubectl --kubeconfig " + kubeconfigPath + " apply --server-side --force-conflicts --field-manager=" + applyFieldManager + " -f -"
+	if node.commands[0] != want {
+		t.Errorf("the batch is applied with %q, want %q", node.commands[0], want)
+	}
+
+	var kinds []string
+	decoder := yaml.NewDecoder(strings.NewReader(node.stdins[0]))
+	for {
+		var document struct {
+			Kind string `yaml:"kind"`
+		}
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid payload: %v", err)
+		}
+		kinds = append(kinds, document.Kind)
+	}
+	if strings.Join(kinds, ",") != "ConfigMap,Secret,Namespace,ServiceAccount,Role" {
+		t.Errorf("the payload holds the documents %v", kinds)
+	}
+}
+
+func TestConfigureNodeSecurityExecs(t *testing.T) {
+	node := &applyNode{record: true}
+	workerNodes := applyWorkerNodes(10)
+	workerNodes[3].NodeSecurity = nil
+	if err := configureNodeSecurity(node, kubeconfigPath, PrivateParams{}, workerNodes); err != nil {
+		t.Fatal(err)
+	}
+	// The DaemonSets are applied together, and waited for in a single exec
+	if len(node.commands) != 2 {
+		t.Fatalf("the node security is configured with %d commands", len(node.commands))
+	}
+	if got := strings.Count(node.stdins[0], "kind: DaemonSet"); got != 9 {
+		t.Errorf("%d DaemonSets are applied", got)
+	}
+	if got := strings.Count(node.commands[1], "rollout status ds"); got != 9 {
+		t.Errorf("%d DaemonSets are waited for", got)
+	}
+}
+
+// BenchmarkConfigureNodeSecurity compares the apply of the node security DaemonSets of the node
+// groups with a single batch, with the previous apply and wait of a DaemonSet at a time
+func BenchmarkConfigureNodeSecurity(b *testing.B) {
+	for _, nodeGroups := range applyBenchNodeGroups {
+		workerNodes := applyWorkerNodes(nodeGroups)
+		b.Run("batched/node-groups-"+strconv.Itoa(nodeGroups), func(b *testing.B) {
+			node := &applyNode{latency: applyExecLatency}
+			for i := 0; i < b.N; i++ {
+				if err := configureNodeSecurity(node, kubeconfigPath, PrivateParams{}, workerNodes); err != nil {
+					b.Fatal(err)
+				}
+			}
+			node.report(b)
+		})
+		b.Run("per-node-group/node-groups-"+strconv.Itoa(nodeGroups), func(b *testing.B) {
+			node := &applyNode{latency: applyExecLatency}
+			for i := 0; i < b.N; i++ {
+				for _, wn := range workerNodes {
+					config := getNodeSecurityConfig(wn.Name, wn.Labels, *wn.NodeSecurity)
+					if err := deployNodeConfigs(node, kubeconfigPath, PrivateParams{}, config); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+			node.report(b)
+		})
+	}
+}
+
+// BenchmarkCreateClusterResourceSets applies the ClusterResourceSets of large manifests, which are
+// applied in a single exec whatever their number
+func BenchmarkCreateClusterResourceSets(b *testing.B) {
+	source := filepath.Join(b.TempDir(), "manifests.yaml")
+	manifest := strings.Repeat("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: large\ndata:\n  key: "+strings.Repeat("x", 1024)+"\n---\n", 256)
+	if err := os.WriteFile(source, []byte(manifest), 0644); err != nil {
+		b.Fatal(err)
+	}
+	keosCluster := commons.KeosCluster{}
+	keosCluster.Metadata.Name = "benchmark"
+
+	for _, count := range applyBenchNodeGroups {
+		var clusterResourceSets []commons.ClusterResourceSet
+		for i := 0; i < count; i++ {
+			clusterResourceSets = append(clusterResourceSets, commons.ClusterResourceSet{
+				Name:    "crs-" + strconv.Itoa(i),
+				Sources: []string{source},
+			})
+		}
+		b.Run("cluster-resource-sets-"+strconv.Itoa(count), func(b *testing.B) {
+			node := &applyNode{latency: applyExecLatency}
+			for i := 0; i < b.N; i++ {
+				if err := createClusterResourceSets(node, keosCluster, "cluster-benchmark", clusterResourceSets); err != nil {
+					b.Fatal(err)
+				}
+			}
+			node.report(b)
+		})
+	}
+}
+
+func applyWorkerNodes(count int) commons.WorkerNodes {
+	workerNodes := make(commons.WorkerNodes, count)
+	for i := range workerNodes {
+		workerNodes[i].Name = "workers-" + strconv.Itoa(i)
+		workerNodes[i].Labels = map[string]string{"node-group": workerNodes[i].Name}
+		workerNodes[i].NodeSecurity = &commons.NodeSecurity{
+			SELinux:       "enforcing",
+			Sysctls:       map[string]string{"vm.max_map_count": "262144", "net.core.somaxconn": "4096"},
+			KernelModules: []string{"br_netfilter", "overlay"},
+		}
+	}
+	return workerNodes
+}
+
+// applyNode is a node which counts the commands run in it, and whose commands succeed without output
+// after the latency of an exec. The commands and the manifests applied are recorded if record is set
+type applyNode struct {
+	latency  time.Duration
+	record   bool
+	execs    int
+	commands []string
+	stdins   []string
+}
+
+func (n *applyNode) Command(command string, args ...string) exec.Cmd {
+	return &applyCmd{node: n, command: strings.Join(append([]string{command}, args...), " ")}
+}
+
+func (n *applyNode) CommandContext(_ context.Context, command string, args ...string) exec.Cmd {
+	return n.Command(command, args...)
+}
+
+func (n *applyNode) String() string {
+	return "apply-control-plane"
+}
+
+func (n *applyNode) Role() (string, error) {
+	return "control-plane", nil
+}
+
+func (n *applyNode) IP() (string, string, error) {
+	return "", "", nil
+}
+
+func (n *applyNode) SerialLogs(io.Writer) error {
+	return nil
+}
+
+// report reports the execs run for each operation of the benchmark
+func (n *applyNode) report(b *testing.B) {
+	b.ReportMetric(float64(n.execs)/float64(b.N), "execs/op")
+}
+
+type applyCmd struct {
+	node    *applyNode
+	command string
+	stdin   io.Reader
+}
+
+func (c *applyCmd) Run() error {
+	time.Sleep(c.node.latency)
+	c.node.execs++
+	var stdin bytes.Buffer
+	if c.stdin != nil {
+		if _, err := stdin.ReadFrom(c.stdin); err != nil {
+			return err
+		}
+	}
+	if c.node.record {
+		c.node.commands = append(c.node.commands, strings.TrimPrefix(c.command, "sh -c "))
+		if c.stdin != nil {
+			c.node.stdins = append(c.node.stdins, stdin.String())
+		}
+	}
+	return nil
+}
+
+func (c *applyCmd) SetEnv(...string) exec.Cmd {
+	return c
+}
+
+func (c *applyCmd) SetStdin(r io.Reader) exec.Cmd {
+	c.stdin = r
+	return c
+}
+
+func (c *applyCmd) SetStdout(io.Writer) exec.Cmd {
+	return c
+}
+
+func (c *applyCmd) SetStderr(io.Writer) exec.Cmd {
+	return c
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+// recordOperation stores the operation over the workload cluster, with its result, in a
+// ConfigMap of the cluster, which is listed by the history command. The operations which fail
+// before the workload cluster is reachable are recorded in the local cluster
+func recordOperation(n nodes.Node, operation string, keosCluster commons.KeosCluster, descriptorPath string, started time.Time, operationErr error) error {
+	descriptor, err := os.ReadFile(descriptorPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to read the descriptor")
+	}
+	descriptorHash := sha256.Sum256(descriptor)
+
+	record := commons.OperationRecord{
+		Operation:        operation,
+		Cluster:          keosCluster.Metadata.Name,
+		User:             getOperatorIdentity(),
+		Started:          started.UTC().Format(time.RFC3339),
+		Finished:         time.Now().UTC().Format(time.RFC3339),
+		DescriptorSHA256: hex.EncodeToString(descriptorHash[:]),
+		Result:           "Succeeded",
+	}
+	if operationErr != nil {
+		record.Result = "Failed"
+		record.Error = operationErr.Error()
+	}
+	// The ConfigMap data is the record itself
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	data := map[string]string{}
+	if err = json.Unmarshal(recordJSON, &data); err != nil {
+		return err
+	}
+
+	// The operations started in the same second by different operators are told apart by the suffix
+	suffix := make([]byte, 4)
+	if _, err = rand.Read(suffix); err != nil {
+		return errors.Wrap(err, "failed to generate the name of the "+operation+" operation record")
+	}
+	configMap := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      "cloud-provisioner-" + operation + "-" + started.UTC().Format("20060102150405") + "-" + hex.EncodeToString(suffix),
+			"namespace": commons.OperationHistoryNamespace,
+			"labels": map[string]string{
+				commons.OperationHistoryLabel:        "true",
+				commons.OperationHistoryClusterLabel: keosCluster.Metadata.Name,
+			},
+		},
+		"data": data,
+	}
+	configMapYAML, err := yaml.Marshal(configMap)
+	if err != nil {
+		return err
+	}
+	if _, err = commons.ExecuteCommand(n, "test -f "+kubeconfigPath, 5, 3); err == nil {
+		cmd := n.Command("kubectl", "--kubeconfig", kubeconfigPath, "create", "-f", "-")
+		if err = cmd.SetStdin(strings.NewReader(string(configMapYAML))).Run(); err == nil {
+			return nil
+		}
+	}
+	cmd := n.Command("kubectl", "create", "-f", "-")
+	if err = cmd.SetStdin(strings.NewReader(string(configMapYAML))).Run(); err != nil {
+		return errors.Wrap(err, "failed to record the "+operation+" operation in the workload or the local cluster")
+	}
+	return nil
+}
@@ -24,7 +24,6 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ecr"
 	"gopkg.in/yaml.v3"
 	"sigs.k8s.io/kind/pkg/cluster/nodes"
@@ -33,6 +32,9 @@ import (
 	"sigs.k8s.io/kind/pkg/exec"
 )
 
+// eksDefaultStorageClass is the StorageClass EKS installs by default
+const eksDefaultStorageClass = "gp2"
+
 //go:embed files/aws/internal-ingress-nginx.yaml
 var awsInternalIngress []byte
 
@@ -73,19 +75,55 @@ func (b *AWSBuilder) setCapx(managed bool) {
 }
 
 func (b *AWSBuilder) setCapxEnvVars(p ProviderParams) {
-	awsCredentials := "[default]\naws_access_key_id = " + p.Credentials["AccessKey"] + "\naws_secret_access_key = " + p.Credentials["SecretKey"] + "\nregion = " + p.Region + "\n"
 	b.capxEnvVars = []string{
 		"AWS_REGION=" + p.Region,
 		"AWS_ACCESS_KEY_ID=" + p.Credentials["AccessKey"],
 		"AWS_SECRET_ACCESS_KEY=" + p.Credentials["SecretKey"],
-		"AWS_B64ENCODED_CREDENTIALS=" + base64.StdEncoding.EncodeToString([]byte(awsCredentials)),
+		"AWS_B64ENCODED_CREDENTIALS=" + base64.StdEncoding.EncodeToString([]byte(getCAPACredentials(p.Credentials, p.Region))),
 		"CAPA_EKS_IAM=true",
 	}
+	if p.Credentials["SessionToken"] != "" {
+		b.capxEnvVars = append(b.capxEnvVars, "AWS_SESSION_TOKEN="+p.Credentials["SessionToken"])
+	}
 	if p.GithubToken != "" {
 		b.capxEnvVars = append(b.capxEnvVars, "GITHUB_TOKEN="+p.GithubToken)
 	}
 }
 
+// getCAPACredentials returns the shared credentials file of CAPA
+func getCAPACredentials(credentials map[string]string, region string) string {
+	if credentials["ClusterRoleARN"] != "" {
+		// CAPA assumes the cluster role in the workload account with the provider credentials
+		return getAWSProviderProfiles(credentials, "source") +
+			"[default]\nrole_arn = " + credentials["ClusterRoleARN"] + "\nsource_profile = source\nregion = " + region + "\n"
+	}
+	return getAWSProviderProfiles(credentials, "default") + "region = " + region + "\n"
+}
+
+// getAWSProviderProfiles returns the profiles of the provider credentials in the format of the AWS
+// shared credentials file, the last one with the given name. Their role is assumed through a profile
+// instead of resolved to temporary keys, so the SDK of the providers renews it while they run
+func getAWSProviderProfiles(credentials map[string]string, name string) string {
+	if credentials["RoleARN"] == "" {
+		return "[" + name + "]\n" + getAWSProfileKeys(credentials)
+	}
+	profiles := "[" + name + "-keys]\n" + getAWSProfileKeys(credentials) +
+		"[" + name + "]\nrole_arn = " + credentials["RoleARN"] + "\nsource_profile = " + name + "-keys\n"
+	if credentials["ExternalID"] != "" {
+		profiles += "external_id = " + credentials["ExternalID"] + "\n"
+	}
+	return profiles
+}
+
+// getAWSProfileKeys returns the keys of the credentials in the format of the AWS shared credentials file
+func getAWSProfileKeys(credentials map[string]string) string {
+	keys := "aws_access_key_id = " + credentials["AccessKey"] + "\naws_secret_access_key = " + credentials["SecretKey"] + "\n"
+	if credentials["SessionToken"] != "" {
+		keys += "aws_session_token = " + credentials["SessionToken"] + "\n"
+	}
+	return keys
+}
+
 func (b *AWSBuilder) setSC(p ProviderParams) {
 	if (p.StorageClass.Parameters != commons.SCParameters{}) {
 		b.scParameters = p.StorageClass.Parameters
@@ -197,37 +235,22 @@ func (b *AWSBuilder) getProvider() Provider {
 }
 
 func (b *AWSBuilder) installCloudProvider(n nodes.Node, k string, privateParams PrivateParams) error {
-	var podsCidrBlock string
-	keosCluster := privateParams.KeosCluster
-	if keosCluster.Spec.Networks.PodsCidrBlock != "" {
-		podsCidrBlock = keosCluster.Spec.Networks.PodsCidrBlock
-	} else {
-		podsCidrBlock = "192.168.0.0/16"
-	}
-
 	cloudControllerManagerValuesFile := "/kind/aws-cloud-controller-manager-helm-values.yaml"
-	cloudControllerManagerHelmParams := cloudControllerHelmParams{
-		ClusterName: privateParams.KeosCluster.Metadata.Name,
-		Private:     privateParams.Private,
-		KeosRegUrl:  privateParams.KeosRegUrl,
-		PodsCidr:    podsCidrBlock,
-	}
 
 	// Generate the CCM helm values
-	cloudControllerManagerHelmValues, err := getManifest(b.capxProvider, "aws-cloud-controller-manager-helm-values.tmpl", majorVersion, cloudControllerManagerHelmParams)
+	cloudControllerManagerHelmValues, err := b.getCloudProviderTemplate(privateParams).render()
 	if err != nil {
 		return errors.Wrap(err, "failed to create cloud controller manager Helm chart values file")
 	}
-	c := "echo '" + cloudControllerManagerHelmValues + "' > " + cloudControllerManagerValuesFile
-	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	err = commons.WriteFile(n, cloudControllerManagerValuesFile, cloudControllerManagerHelmValues)
 	if err != nil {
 		return errors.Wrap(err, "failed to create cloud controller manager Helm chart values file")
 	}
 
-	c = "helm install aws-cloud-controller-manager /stratio/helm/aws-cloud-controller-manager" +
-		" --kubeconfig " + k +
+	c := "helm install aws-cloud-controller-manager /stratio/helm/aws-cloud-controller-manager" +
+		" --kubeconfig " + commons.ShellQuote(k) +
 		" --namespace kube-system" +
-		" --values " + cloudControllerManagerValuesFile
+		" --values " + commons.ShellQuote(cloudControllerManagerValuesFile)
 	_, err = commons.ExecuteCommand(n, c, 5, 3)
 	if err != nil {
 		return errors.Wrap(err, "failed to deploy aws-cloud-controller-manager Helm Chart")
@@ -236,6 +259,10 @@ func (b *AWSBuilder) installCloudProvider(n nodes.Node, k string, privateParams
 	return nil
 }
 
+func (b *AWSBuilder) getCloudProviderTemplate(privateParams PrivateParams) manifestTemplate {
+	return manifestTemplate{b.capxProvider, "aws-cloud-controller-manager-helm-values.tmpl", majorVersion, getCloudControllerHelmParams(privateParams)}
+}
+
 func (b *AWSBuilder) installCSI(n nodes.Node, k string, privateParams PrivateParams, providerParams ProviderParams, chartsList map[string]commons.ChartEntry) error {
 	csiName := "aws-ebs-csi-driver"
 	csiValuesFile := "/kind/" + csiName + "-helm-values.yaml"
@@ -243,27 +270,40 @@ func (b *AWSBuilder) installCSI(n nodes.Node, k string, privateParams PrivatePar
 	csiHelmReleaseParams := fluxHelmReleaseParams{
 		ChartRepoRef:   "keos",
 		ChartName:      csiName,
-		ChartNamespace: csiEntry.Namespace,
+		ChartNamespace: b.csiNamespace,
 		ChartVersion:   csiEntry.Version,
 	}
 	if !privateParams.HelmPrivate {
 		csiHelmReleaseParams.ChartRepoRef = csiName
 	}
 	// Generate the csiName-csi helm values
-	csiHelmValues, getManifestErr := getManifest(privateParams.KeosCluster.Spec.InfraProvider, csiName+"-helm-values.tmpl", majorVersion, privateParams)
+	csiHelmValues, getManifestErr := b.getCSITemplates(privateParams)[0].render()
 	if getManifestErr != nil {
 		return errors.Wrap(getManifestErr, "failed to generate "+csiName+"-csi helm values")
 	}
 
-	c := "echo '" + csiHelmValues + "' > " + csiValuesFile
-	_, err := commons.ExecuteCommand(n, c, 5, 3)
+	err := commons.WriteFile(n, csiValuesFile, csiHelmValues)
 	if err != nil {
 		return errors.Wrap(err, "failed to create "+csiName+" Helm chart values file")
 	}
-	if err := configureHelmRelease(n, kubeconfigPath, "flux2_helmrelease.tmpl", csiHelmReleaseParams, privateParams.KeosCluster.Spec.HelmRepository); err != nil {
+	if err := configureHelmRelease(n, k, "flux2_helmrelease.tmpl", csiHelmReleaseParams, privateParams.KeosCluster.Spec.HelmRepository); err != nil {
 		return err
 	}
-	return nil
+
+	// The StorageClass can't bind volumes until the driver is running in the nodes
+	c := "kubectl --kubeconfig " + commons.ShellQuote(k) + " -n " + commons.ShellQuote(b.csiNamespace) + " wait --for=condition=Ready helmrelease/" + commons.ShellQuote(csiName) + " --timeout=10m"
+	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to wait for the "+csiName+" HelmRelease")
+	}
+	if err = waitForRollouts(n, k, b.csiNamespace, "deployment", []string{"ebs-csi-controller"}, "5m"); err != nil {
+		return err
+	}
+	return waitForRollouts(n, k, b.csiNamespace, "ds", []string{"ebs-csi-node"}, "5m")
+}
+
+func (b *AWSBuilder) getCSITemplates(privateParams PrivateParams) []manifestTemplate {
+	return []manifestTemplate{{b.capxProvider, "aws-ebs-csi-driver-helm-values.tmpl", majorVersion, privateParams}}
 }
 
 func installLBController(n nodes.Node, k string, privateParams PrivateParams, p ProviderParams, chartsList map[string]commons.ChartEntry) error {
@@ -273,6 +313,10 @@ func installLBController(n nodes.Node, k string, privateParams PrivateParams, p
 	clusterName := p.ClusterName
 	roleName := clusterName + "-lb-controller-manager"
 	accountID := p.Credentials["AccountID"]
+	if clusterRoleARN := strings.Split(p.Credentials["ClusterRoleARN"], ":"); len(clusterRoleARN) > 4 {
+		// The role of the controller lives in the workload account
+		accountID = clusterRoleARN[4]
+	}
 
 	lbControllerManagerHelmParams := lbControllerHelmParams{
 		ClusterName: privateParams.KeosCluster.Metadata.Name,
@@ -296,8 +340,7 @@ func installLBController(n nodes.Node, k string, privateParams PrivateParams, p
 	if getManifestErr != nil {
 		return errors.Wrap(getManifestErr, "failed to generate "+lbControllerName+"-csi helm values")
 	}
-	c := "echo '" + lbControllerHelmValues + "' > " + lbControllerValuesFile
-	_, err := commons.ExecuteCommand(n, c, 5, 3)
+	err := commons.WriteFile(n, lbControllerValuesFile, lbControllerHelmValues)
 	if err != nil {
 		return errors.Wrap(err, "failed to create "+lbControllerName+" Helm chart values file")
 	}
@@ -307,55 +350,15 @@ func installLBController(n nodes.Node, k string, privateParams PrivateParams, p
 	return nil
 }
 
-func createCloudFormationStack(n nodes.Node, envVars []string) error {
-	var c string
-	var err error
-
-	eksConfigData := `
-apiVersion: bootstrap.aws.infrastructure.cluster.x-k8s.io/v1beta1
-kind: AWSIAMConfiguration
-spec:
-  bootstrapUser:
-    enable: false
-  eks:
-    enable: true
-    iamRoleCreation: false
-    defaultControlPlaneRole:
-        disable: false
-  controlPlane:
-    enableCSIPolicy: true
-  nodes:
-    extraPolicyAttachments:
-    - arn:aws:iam::aws:policy/service-role/AmazonEBSCSIDriverPolicy`
-
-	// Create the eks.config file in the container
-	eksConfigPath := "/kind/eks.config"
-	c = "echo \"" + eksConfigData + "\" > " + eksConfigPath
-
-	_, err = commons.ExecuteCommand(n, c, 5, 3)
-	if err != nil {
-		return errors.Wrap(err, "failed to create eks.config")
-	}
-
-	// Run clusterawsadm with the eks.config file previously created (this will create or update the CloudFormation stack in AWS)
-	c = "clusterawsadm bootstrap iam create-cloudformation-stack --config " + eksConfigPath
-
-	_, err = commons.ExecuteCommand(n, c, 5, 3, envVars)
-	if err != nil {
-		return errors.Wrap(err, "failed to run clusterawsadm")
-	}
-	return nil
-}
-
 func (b *AWSBuilder) internalNginx(p ProviderParams, networks commons.Networks) (bool, error) {
 	var err error
 	var ctx = context.TODO()
 
-	cfg, err := commons.AWSGetConfig(ctx, p.Credentials, p.Region)
+	client, err := commons.NewAWSClient(ctx, p.Credentials, p.Region, p.Credentials["NetworkRoleARN"])
 	if err != nil {
 		return false, err
 	}
-	svc := ec2.NewFromConfig(cfg)
+	svc := client.EC2()
 	if len(networks.Subnets) > 0 {
 		for _, s := range networks.Subnets {
 			isPrivate, err := commons.AWSIsPrivateSubnet(ctx, svc, &s.SubnetId)
@@ -377,12 +380,11 @@ func (b *AWSBuilder) getRegistryCredentials(p ProviderParams, u string) (string,
 	var ctx = context.Background()
 
 	region := strings.Split(u, ".")[3]
-	cfg, err := commons.AWSGetConfig(ctx, p.Credentials, region)
+	client, err := commons.NewAWSClient(ctx, p.Credentials, region, "")
 	if err != nil {
 		return "", "", err
 	}
-	svc := ecr.NewFromConfig(cfg)
-	token, err := svc.GetAuthorizationToken(ctx, &ecr.GetAuthorizationTokenInput{})
+	token, err := client.ECR().GetAuthorizationToken(ctx, &ecr.GetAuthorizationTokenInput{})
 	if err != nil {
 		return "", "", err
 	}
@@ -396,28 +398,9 @@ func (b *AWSBuilder) getRegistryCredentials(p ProviderParams, u string) (string,
 }
 
 func (b *AWSBuilder) configureStorageClass(n nodes.Node, k string) error {
-	var c string
 	var err error
 	var cmd exec.Cmd
 
-	if b.capxManaged {
-		// Remove annotation from default storage class
-		c = "kubectl --kubeconfig " + k + ` get sc -o jsonpath='{.items[?(@.metadata.annotations.storageclass\.kubernetes\.io/is-default-class=="true")].metadata.name}'`
-
-		output, err := commons.ExecuteCommand(n, c, 5, 3)
-		if err != nil {
-			return errors.Wrap(err, "failed to get default storage class")
-		}
-		if strings.TrimSpace(output) != "" && strings.TrimSpace(output) != "No resources found" {
-			c = "kubectl --kubeconfig " + k + " annotate sc " + strings.TrimSpace(output) + " " + defaultScAnnotation + "-"
-
-			_, err = commons.ExecuteCommand(n, c, 5, 3)
-			if err != nil {
-				return errors.Wrap(err, "failed to remove annotation from default storage class")
-			}
-		}
-	}
-
 	scTemplate.Parameters = b.scParameters
 	scTemplate.Provisioner = b.scProvisioner
 
@@ -471,7 +454,7 @@ func (b *AWSBuilder) getOverrideVars(p ProviderParams, networks commons.Networks
 func (b *AWSBuilder) postInstallPhase(n nodes.Node, k string) error {
 	var coreDNSPDBName = "coredns"
 
-	c := "kubectl --kubeconfig " + kubeconfigPath + " get pdb " + coreDNSPDBName + " -n kube-system"
+	c := "kubectl --kubeconfig " + kubeconfigPath + " get pdb " + commons.ShellQuote(coreDNSPDBName) + " -n kube-system"
 
 	_, err := commons.ExecuteCommand(n, c, 5, 3)
 	if err != nil {
@@ -494,3 +477,13 @@ func (b *AWSBuilder) postInstallPhase(n nodes.Node, k string) error {
 
 	return nil
 }
+
+// removeAWSNode removes the aws-node DaemonSet of the VPC CNI, so Calico is the CNI of the EKS nodes
+func removeAWSNode(n nodes.Node, k string) error {
+	c := "kubectl --kubeconfig " + commons.ShellQuote(k) + " -n kube-system delete daemonset aws-node --ignore-not-found"
+	_, err := commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete the aws-node DaemonSet")
+	}
+	return nil
+}
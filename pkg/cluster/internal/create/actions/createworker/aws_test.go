@@ -0,0 +1,72 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import "testing"
+
+func TestGetCAPACredentials(t *testing.T) {
+	keys := map[string]string{"AccessKey": "AKID", "SecretKey": "secret"}
+	with := func(values ...string) map[string]string {
+		credentials := map[string]string{}
+		for k, v := range keys {
+			credentials[k] = v
+		}
+		for i := 0; i < len(values); i += 2 {
+			credentials[values[i]] = values[i+1]
+		}
+		return credentials
+	}
+
+	tests := []struct {
+		name        string
+		credentials map[string]string
+		expected    string
+	}{
+		{
+			name:        "keys",
+			credentials: keys,
+			expected:    "[default]\naws_access_key_id = AKID\naws_secret_access_key = secret\nregion = eu-west-1\n",
+		},
+		{
+			name:        "cluster role",
+			credentials: with("ClusterRoleARN", "arn:aws:iam::111111111111:role/cluster"),
+			expected: "[source]\naws_access_key_id = AKID\naws_secret_access_key = secret\n" +
+				"[default]\nrole_arn = arn:aws:iam::111111111111:role/cluster\nsource_profile = source\nregion = eu-west-1\n",
+		},
+		{
+			name:        "role",
+			credentials: with("RoleARN", "arn:aws:iam::222222222222:role/provisioner", "ExternalID", "id"),
+			expected: "[default-keys]\naws_access_key_id = AKID\naws_secret_access_key = secret\n" +
+				"[default]\nrole_arn = arn:aws:iam::222222222222:role/provisioner\nsource_profile = default-keys\nexternal_id = id\nregion = eu-west-1\n",
+		},
+		{
+			name:        "role and cluster role",
+			credentials: with("RoleARN", "arn:aws:iam::222222222222:role/provisioner", "ClusterRoleARN", "arn:aws:iam::111111111111:role/cluster"),
+			expected: "[source-keys]\naws_access_key_id = AKID\naws_secret_access_key = secret\n" +
+				"[source]\nrole_arn = arn:aws:iam::222222222222:role/provisioner\nsource_profile = source-keys\n" +
+				"[default]\nrole_arn = arn:aws:iam::111111111111:role/cluster\nsource_profile = source\nregion = eu-west-1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if credentials := getCAPACredentials(tt.credentials, "eu-west-1"); credentials != tt.expected {
+				t.Errorf("expected the credentials\n%s\ngot\n%s", tt.expected, credentials)
+			}
+		})
+	}
+}
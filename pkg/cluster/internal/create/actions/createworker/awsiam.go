@@ -0,0 +1,229 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const awsIAMStackTimeout = 15 * time.Minute
+
+// awsIAMStackPollInterval is the interval between the checks of a stack with an operation in progress
+const awsIAMStackPollInterval = 10 * time.Second
+
+var awsIAMStackCapabilities = []types.Capability{types.CapabilityCapabilityIam, types.CapabilityCapabilityNamedIam}
+
+// ensureAWSIAMStack creates, or updates, the CloudFormation stack with the IAM roles, policies and
+// instance profiles of CAPA, and returns the reasons of the failed resources if it rolls back.
+// The stack is left as is when its template is already the desired one, and created again when its
+// creation rolled back
+func ensureAWSIAMStack(p ProviderParams, security commons.AWSSecurity, clusterConfig *commons.ClusterConfig) error {
+	var ctx = context.Background()
+	stackName := commons.GetAWSIAMStackName(security)
+
+	// The IAM resources may be managed from another account of the landing zone
+	client, err := commons.NewAWSClient(ctx, p.Credentials, p.Region, p.Credentials["IAMRoleARN"])
+	if err != nil {
+		return err
+	}
+	cfnClient := client.CloudFormation()
+	template, err := json.Marshal(commons.GetAWSIAMTemplate(clusterConfig, security))
+	if err != nil {
+		return err
+	}
+
+	stack, err := describeAWSStack(ctx, cfnClient, stackName)
+	if err != nil {
+		return err
+	}
+	// The operation in progress, of another operator or of a previous run, is waited for
+	if stack != nil && strings.HasSuffix(string(stack.StackStatus), "_IN_PROGRESS") {
+		stack, err = waitAWSStack(ctx, cfnClient, stackName)
+		if err != nil {
+			return err
+		}
+	}
+	describeInput := &cloudformation.DescribeStacksInput{StackName: aws.String(stackName)}
+	if stack != nil {
+		switch stack.StackStatus {
+		case types.StackStatusRollbackComplete:
+			// The stack whose creation rolled back cannot be updated, but only deleted and created again
+			_, err = cfnClient.DeleteStack(ctx, &cloudformation.DeleteStackInput{StackName: aws.String(stackName)})
+			if err != nil {
+				return errors.Wrap(err, "failed to delete the "+stackName+" stack, whose creation rolled back")
+			}
+			err = cloudformation.NewStackDeleteCompleteWaiter(cfnClient).Wait(ctx, describeInput, awsIAMStackTimeout)
+			if err != nil {
+				return getAWSStackError(ctx, cfnClient, stackName, err)
+			}
+			stack = nil
+		case types.StackStatusUpdateRollbackFailed:
+			return errors.New("the " + stackName + " stack is in " + string(stack.StackStatus) + ", its update rollback must be " +
+				"continued in CloudFormation, skipping the resources which cannot be rolled back, before creating the cluster")
+		case types.StackStatusRollbackFailed, types.StackStatusDeleteFailed:
+			return errors.New("the " + stackName + " stack is in " + string(stack.StackStatus) + ", it must be deleted in " +
+				"CloudFormation, retaining the resources which cannot be deleted, before creating the cluster")
+		}
+	}
+	if stack == nil {
+		_, err = cfnClient.CreateStack(ctx, &cloudformation.CreateStackInput{
+			StackName:    aws.String(stackName),
+			TemplateBody: aws.String(string(template)),
+			Capabilities: awsIAMStackCapabilities,
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to create the "+stackName+" stack")
+		}
+		err = cloudformation.NewStackCreateCompleteWaiter(cfnClient).Wait(ctx, describeInput, awsIAMStackTimeout)
+		if err != nil {
+			return getAWSStackError(ctx, cfnClient, stackName, err)
+		}
+		return nil
+	}
+
+	status := string(stack.StackStatus)
+	if strings.HasSuffix(status, "_COMPLETE") && !strings.Contains(status, "ROLLBACK") {
+		upToDate, err := isAWSStackTemplate(ctx, cfnClient, stackName, template)
+		if err != nil {
+			return err
+		}
+		if upToDate {
+			return nil
+		}
+	}
+	_, err = cfnClient.UpdateStack(ctx, &cloudformation.UpdateStackInput{
+		StackName:    aws.String(stackName),
+		TemplateBody: aws.String(string(template)),
+		Capabilities: awsIAMStackCapabilities,
+	})
+	if err != nil {
+		if isAWSValidationError(err, "No updates are to be performed") {
+			return nil
+		}
+		return errors.Wrap(err, "failed to update the "+stackName+" stack")
+	}
+	err = cloudformation.NewStackUpdateCompleteWaiter(cfnClient).Wait(ctx, describeInput, awsIAMStackTimeout)
+	if err != nil {
+		return getAWSStackError(ctx, cfnClient, stackName, err)
+	}
+	return nil
+}
+
+// isAWSValidationError returns whether the error is the ValidationError of CloudFormation with the
+// given message, which is the one returned for the stacks which do not exist or are up to date
+func isAWSValidationError(err error, message string) bool {
+	var apiErr smithy.APIError
+	return stderrors.As(err, &apiErr) && apiErr.ErrorCode() == "ValidationError" && strings.Contains(apiErr.ErrorMessage(), message)
+}
+
+// getAWSStackError returns the error of the waiter along with the reasons of the failed resources of
+// the stack
+func getAWSStackError(ctx context.Context, cfnClient *cloudformation.Client, stackName string, err error) error {
+	message := "failed to wait for the " + stackName + " stack"
+	reasons, failuresErr := getAWSStackFailures(ctx, cfnClient, stackName)
+	if failuresErr == nil && len(reasons) > 0 {
+		message += ": " + strings.Join(reasons, "; ")
+	}
+	return errors.Wrap(err, message)
+}
+
+// isAWSStackTemplate returns whether the current template of the stack is the given one, regardless
+// of the formatting and the order of the keys
+func isAWSStackTemplate(ctx context.Context, cfnClient *cloudformation.Client, stackName string, template []byte) (bool, error) {
+	var current, desired interface{}
+
+	output, err := cfnClient.GetTemplate(ctx, &cloudformation.GetTemplateInput{StackName: aws.String(stackName)})
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get the template of the "+stackName+" stack")
+	}
+	// The stacks created by clusterawsadm have a YAML template, which is always updated
+	if err = json.Unmarshal([]byte(aws.ToString(output.TemplateBody)), &current); err != nil {
+		return false, nil
+	}
+	if err = json.Unmarshal(template, &desired); err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(current, desired), nil
+}
+
+// waitAWSStack waits for the operation in progress in the stack, and returns the stack once it ends
+func waitAWSStack(ctx context.Context, cfnClient *cloudformation.Client, stackName string) (*types.Stack, error) {
+	deadline := time.Now().Add(awsIAMStackTimeout)
+	for {
+		stack, err := describeAWSStack(ctx, cfnClient, stackName)
+		if err != nil || stack == nil || !strings.HasSuffix(string(stack.StackStatus), "_IN_PROGRESS") {
+			return stack, err
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New("timed out waiting for the " + string(stack.StackStatus) + " operation of the " + stackName + " stack")
+		}
+		time.Sleep(awsIAMStackPollInterval)
+	}
+}
+
+// describeAWSStack returns the stack, or nil if it does not exist
+func describeAWSStack(ctx context.Context, cfnClient *cloudformation.Client, stackName string) (*types.Stack, error) {
+	output, err := cfnClient.DescribeStacks(ctx, &cloudformation.DescribeStacksInput{StackName: aws.String(stackName)})
+	if err != nil {
+		if isAWSValidationError(err, "does not exist") {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to describe the "+stackName+" stack")
+	}
+	if len(output.Stacks) == 0 {
+		return nil, nil
+	}
+	return &output.Stacks[0], nil
+}
+
+// getAWSStackFailures returns the reasons of the resources which failed in the last operation
+func getAWSStackFailures(ctx context.Context, cfnClient *cloudformation.Client, stackName string) ([]string, error) {
+	var reasons []string
+
+	// The events are sorted from the newest, up to the start of the operation
+	paginator := cloudformation.NewDescribeStackEventsPaginator(cfnClient, &cloudformation.DescribeStackEventsInput{StackName: aws.String(stackName)})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range output.StackEvents {
+			logicalResourceID := aws.ToString(event.LogicalResourceId)
+			status := string(event.ResourceStatus)
+			reason := aws.ToString(event.ResourceStatusReason)
+			if logicalResourceID == stackName && strings.HasSuffix(status, "_IN_PROGRESS") && reason == "User Initiated" {
+				return reasons, nil
+			}
+			if strings.HasSuffix(status, "_FAILED") && reason != "" && !strings.Contains(reason, "Resource creation cancelled") {
+				reasons = append(reasons, logicalResourceID+": "+reason)
+			}
+		}
+	}
+	return reasons, nil
+}
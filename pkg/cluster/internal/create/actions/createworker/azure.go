@@ -20,13 +20,8 @@ import (
 	"context"
 	_ "embed"
 	"encoding/base64"
-	"encoding/json"
-	"fmt"
-	"net/http"
-	"net/url"
 	"strings"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/msi/armmsi"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v4"
 	"gopkg.in/yaml.v3"
@@ -52,6 +47,7 @@ type AzureBuilder struct {
 	scParameters     commons.SCParameters
 	scProvisioner    string
 	csiNamespace     string
+	acrTokens        map[string]commons.RegistryToken
 }
 
 var azureCharts = ChartsDictionary{
@@ -178,38 +174,25 @@ func (b *AzureBuilder) getProvider() Provider {
 }
 
 func (b *AzureBuilder) installCloudProvider(n nodes.Node, k string, privateParams PrivateParams) error {
-	var podsCidrBlock string
 	keosCluster := privateParams.KeosCluster
-	if keosCluster.Spec.Networks.PodsCidrBlock != "" {
-		podsCidrBlock = keosCluster.Spec.Networks.PodsCidrBlock
-	} else {
-		podsCidrBlock = "192.168.0.0/16"
-	}
 
 	cloudControllerManagerValuesFile := "/kind/cloud-provider-" + keosCluster.Spec.InfraProvider + "-helm-values.yaml"
-	cloudControllerManagerHelmParams := cloudControllerHelmParams{
-		ClusterName: privateParams.KeosCluster.Metadata.Name,
-		Private:     privateParams.Private,
-		KeosRegUrl:  privateParams.KeosRegUrl,
-		PodsCidr:    podsCidrBlock,
-	}
 
 	// Generate the CCM helm values
-	cloudControllerManagerHelmValues, err := getManifest(b.capxProvider, "cloud-provider-"+keosCluster.Spec.InfraProvider+"-helm-values.tmpl", majorVersion, cloudControllerManagerHelmParams)
+	cloudControllerManagerHelmValues, err := b.getCloudProviderTemplate(privateParams).render()
 	if err != nil {
 		return errors.Wrap(err, "failed to create cloud controller manager Helm chart values file")
 	}
-	c := "echo '" + cloudControllerManagerHelmValues + "' > " + cloudControllerManagerValuesFile
-	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	err = commons.WriteFile(n, cloudControllerManagerValuesFile, cloudControllerManagerHelmValues)
 	if err != nil {
 		return errors.Wrap(err, "failed to create cloud controller manager Helm chart values file")
 	}
 
-	c = "helm install cloud-provider-azure /stratio/helm/cloud-provider-azure" +
-		" --kubeconfig " + k +
+	c := "helm install cloud-provider-azure /stratio/helm/cloud-provider-azure" +
+		" --kubeconfig " + commons.ShellQuote(k) +
 		" --namespace kube-system" +
 		" --set cloudControllerManager.replicas=1" +
-		" --values " + cloudControllerManagerValuesFile
+		" --values " + commons.ShellQuote(cloudControllerManagerValuesFile)
 	_, err = commons.ExecuteCommand(n, c, 5, 3)
 	if err != nil {
 		return errors.Wrap(err, "failed to deploy cloud-provider-azure Helm Chart")
@@ -218,6 +201,10 @@ func (b *AzureBuilder) installCloudProvider(n nodes.Node, k string, privateParam
 	return nil
 }
 
+func (b *AzureBuilder) getCloudProviderTemplate(privateParams PrivateParams) manifestTemplate {
+	return manifestTemplate{b.capxProvider, "cloud-provider-" + privateParams.KeosCluster.Spec.InfraProvider + "-helm-values.tmpl", majorVersion, getCloudControllerHelmParams(privateParams)}
+}
+
 func (b *AzureBuilder) installCSI(n nodes.Node, kubeconfigPath string, privateParams PrivateParams, providerParams ProviderParams, chartsList map[string]commons.ChartEntry) error {
 	var c string
 	var err error
@@ -285,20 +272,20 @@ func (b *AzureBuilder) installCSI(n nodes.Node, kubeconfigPath string, privatePa
 		if getManifestErr != nil {
 			return errors.Wrap(getManifestErr, "failed to generate azuredisk driver config")
 		}
-		c = "echo '" + azureDiskSecret + "' > " + azureDiskSecretFile
-		_, err = commons.ExecuteCommand(n, c, 5, 3)
+		err = commons.WriteFile(n, azureDiskSecretFile, azureDiskSecret)
 		if err != nil {
 			return errors.Wrap(err, "failed to create azuredisk driver config")
 		}
-		c = "kubectl --kubeconfig " + kubeconfigPath + " create secret generic azure-cloud-provider -n " +
-			azureDiskNamespace + " --from-file=cloud-config=/kind/azuredisk-azure.json"
+		c = "kubectl --kubeconfig " + commons.ShellQuote(kubeconfigPath) + " create secret generic azure-cloud-provider -n " +
+			commons.ShellQuote(azureDiskNamespace) + " --from-file=cloud-config=/kind/azuredisk-azure.json"
 		_, err = commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
 			return errors.Wrap(err, "failed to create azuredisk secret")
 		}
 	}
 
-	for _, csiName := range []string{"azuredisk-csi-driver", "azurefile-csi-driver"} {
+	for _, csiTemplate := range b.getCSITemplates(privateParams) {
+		csiName := strings.TrimSuffix(csiTemplate.name, "-helm-values.tmpl")
 		csiValuesFile := "/kind/" + csiName + "-helm-values.yaml"
 		csiEntry := chartsList[csiName]
 		csiHelmReleaseParams := fluxHelmReleaseParams{
@@ -311,12 +298,11 @@ func (b *AzureBuilder) installCSI(n nodes.Node, kubeconfigPath string, privatePa
 			csiHelmReleaseParams.ChartRepoRef = csiName
 		}
 		// Generate the csiName-csi helm values
-		csiHelmValues, getManifestErr := getManifest(privateParams.KeosCluster.Spec.InfraProvider, csiName+"-helm-values.tmpl", majorVersion, privateParams)
+		csiHelmValues, getManifestErr := csiTemplate.render()
 		if getManifestErr != nil {
 			return errors.Wrap(getManifestErr, "failed to generate "+csiName+"-csi helm values")
 		}
-		c = "echo '" + csiHelmValues + "' > " + csiValuesFile
-		_, err = commons.ExecuteCommand(n, c, 5, 3)
+		err = commons.WriteFile(n, csiValuesFile, csiHelmValues)
 		if err != nil {
 			return errors.Wrap(err, "failed to create "+csiName+" Helm chart values file")
 		}
@@ -328,67 +314,34 @@ func (b *AzureBuilder) installCSI(n nodes.Node, kubeconfigPath string, privatePa
 	return nil
 }
 
-func (b *AzureBuilder) getRegistryCredentials(p ProviderParams, u string) (string, string, error) {
-	var registryUser = "00000000-0000-0000-0000-000000000000"
-	var registryPass string
-	var ctx = context.Background()
-	var response map[string]interface{}
-
-	cfg, err := commons.AzureGetConfig(p.Credentials)
-	if err != nil {
-		return "", "", err
-	}
-	aadToken, err := cfg.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"https://management.azure.com/.default"}})
-	if err != nil {
-		return "", "", err
+func (b *AzureBuilder) getCSITemplates(privateParams PrivateParams) []manifestTemplate {
+	return []manifestTemplate{
+		{b.capxProvider, "azuredisk-csi-driver-helm-values.tmpl", majorVersion, privateParams},
+		{b.capxProvider, "azurefile-csi-driver-helm-values.tmpl", majorVersion, privateParams},
 	}
+}
+
+func (b *AzureBuilder) getRegistryCredentials(p ProviderParams, u string) (string, string, error) {
+	// The ACR tokens are reused by the steps of the provisioning until they are about to expire
 	acrService := strings.Split(u, "/")[0]
-	formData := url.Values{
-		"grant_type":   {"access_token"},
-		"service":      {acrService},
-		"tenant":       {p.Credentials["TenantID"]},
-		"access_token": {aadToken.Token},
+	if token, ok := b.acrTokens[acrService]; ok && !token.Expired() {
+		return token.User, token.Password, nil
 	}
-	jsonResponse, err := http.PostForm(fmt.Sprintf("https://%s/oauth2/exchange", acrService), formData)
+	token, err := commons.GetACRToken(context.Background(), p.Credentials, u)
 	if err != nil {
 		return "", "", err
-	} else if jsonResponse.StatusCode == http.StatusUnauthorized {
-		return "", "", errors.New("Failed to obtain the ACR token with the provided credentials, please check the roles assigned to the correspondent Azure AD app")
 	}
-	json.NewDecoder(jsonResponse.Body).Decode(&response)
-	if response["access_token"] != nil {
-		registryPass = response["access_token"].(string)
-	} else if response["refresh_token"] != nil {
-		registryPass = response["refresh_token"].(string)
-	} else {
-		return "", "", errors.New("Failed to obtain the ACR token with the provided credentials, please check the roles assigned to the correspondent Azure AD app")
+	if b.acrTokens == nil {
+		b.acrTokens = map[string]commons.RegistryToken{}
 	}
-	return registryUser, registryPass, nil
+	b.acrTokens[acrService] = token
+	return token.User, token.Password, nil
 }
 
 func (b *AzureBuilder) configureStorageClass(n nodes.Node, k string) error {
-	var c string
 	var err error
 	var cmd exec.Cmd
 
-	if b.capxManaged {
-		// Remove annotation from default storage class
-		c = "kubectl --kubeconfig " + k + ` get sc -o jsonpath='{.items[?(@.metadata.annotations.storageclass\.kubernetes\.io/is-default-class=="true")].metadata.name}'`
-		output, err := commons.ExecuteCommand(n, c, 5, 3)
-		if err != nil {
-			return errors.Wrap(err, "failed to get default storage class")
-		}
-
-		if strings.TrimSpace(output) != "" && strings.TrimSpace(output) != "No resources found" {
-			c = "kubectl --kubeconfig " + k + " annotate sc " + strings.TrimSpace(output) + " " + defaultScAnnotation + "-"
-
-			_, err = commons.ExecuteCommand(n, c, 5, 3)
-			if err != nil {
-				return errors.Wrap(err, "failed to remove annotation from default storage class")
-			}
-		}
-	}
-
 	if !b.capxManaged {
 		// Create Azure storage classes
 		cmd = n.Command("kubectl", "--kubeconfig", k, "apply", "-f", "-")
@@ -422,7 +375,7 @@ func (b *AzureBuilder) internalNginx(p ProviderParams, networks commons.Networks
 	if err != nil {
 		return false, err
 	}
-	networkClientFactory, err := armnetwork.NewClientFactory(p.Credentials["SubscriptionID"], cfg, nil)
+	networkClientFactory, err := armnetwork.NewClientFactory(commons.AzureNetworkSubscription(p.Credentials), cfg, nil)
 	if err != nil {
 		return false, err
 	}
@@ -496,7 +449,7 @@ func (b *AzureBuilder) postInstallPhase(n nodes.Node, k string) error {
 		}
 	}
 
-	c := "kubectl --kubeconfig " + kubeconfigPath + " get pdb " + coreDNSPDBName + " -n kube-system"
+	c := "kubectl --kubeconfig " + kubeconfigPath + " get pdb " + commons.ShellQuote(coreDNSPDBName) + " -n kube-system"
 	_, err := commons.ExecuteCommand(n, c, 5, 3)
 	if err != nil {
 		err = installCorednsPdb(n)
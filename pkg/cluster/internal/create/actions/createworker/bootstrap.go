@@ -0,0 +1,115 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+// The local cluster is marked once its providers are installed, so the following runs which
+// reuse it skip their installation
+const bootstrapMarkerName = "keos-bootstrap"
+
+// getBootstrapMarker returns the settings the providers of the local cluster are installed with,
+// which must match for them to be reused. The credentials are hashed, as they are not needed
+// but to tell whether they changed
+func getBootstrapMarker(provider Provider, keosSpec commons.KeosSpec) map[string]string {
+	var keosRegistryURL string
+	for _, registry := range keosSpec.DockerRegistries {
+		if registry.KeosRegistry {
+			keosRegistryURL = registry.URL
+		}
+	}
+	return map[string]string{
+		"infra_provider":     provider.capxProvider,
+		"capx_version":       provider.capxVersion,
+		"capx_image_version": provider.capxImageVersion,
+		"managed":            strconv.FormatBool(provider.capxManaged),
+		"bootstrap":          provider.capiBootstrap,
+		"control_plane":      provider.capiControlPlane,
+		"keos_registry":      keosRegistryURL,
+		"credentials":        hashCredentials(provider.capxEnvVars...),
+	}
+}
+
+// hashCredentials returns the hash the credentials are compared by
+func hashCredentials(credentials ...string) string {
+	hash := sha256.Sum256([]byte(strings.Join(credentials, "\n")))
+	return hex.EncodeToString(hash[:])
+}
+
+// isBootstrapReused returns whether the providers of the local cluster were installed by a
+// previous run, and fails if they were installed with other settings
+func isBootstrapReused(n nodes.Node, marker map[string]string) (bool, error) {
+	c := "kubectl -n kube-system get configmap " + bootstrapMarkerName + " -o jsonpath='{.data}' --ignore-not-found"
+	output, err := commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get the "+bootstrapMarkerName+" ConfigMap")
+	}
+	if strings.TrimSpace(output) == "" {
+		return false, nil
+	}
+	var installed map[string]string
+	if err = json.Unmarshal([]byte(output), &installed); err != nil {
+		return false, errors.Wrap(err, "failed to parse the "+bootstrapMarkerName+" ConfigMap")
+	}
+	for key, value := range marker {
+		if installed[key] != value {
+			return false, errors.New("the temporary cluster was initialized with a different " + key + ", please delete it or use a different --name")
+		}
+	}
+	return true, nil
+}
+
+// markBootstrap marks the providers of the local cluster as installed
+func markBootstrap(n nodes.Node, marker map[string]string) error {
+	batch := newManifestBatch("")
+	err := batch.add(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]string{"name": bootstrapMarkerName, "namespace": "kube-system"},
+		"data":       marker,
+	})
+	if err != nil {
+		return err
+	}
+	if err = batch.apply(n); err != nil {
+		return errors.Wrap(err, "failed to create the "+bootstrapMarkerName+" ConfigMap")
+	}
+	return nil
+}
+
+// resetBootstrap removes the state of the previous run from a reused local cluster: the cluster
+// operator, which is installed with the credentials of each cluster, and the pulled charts
+func resetBootstrap(n nodes.Node) error {
+	c := "if helm -n kube-system status cluster-operator > /dev/null 2>&1; then helm -n kube-system uninstall cluster-operator --wait; fi && " +
+		"kubectl -n kube-system delete secret keoscluster-registries --ignore-not-found && " +
+		"rm -rf /stratio/helm/* " + chartsPackagesPath
+	_, err := commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to reset the temporary cluster")
+	}
+	return nil
+}
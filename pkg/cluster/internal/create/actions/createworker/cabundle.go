@@ -0,0 +1,101 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const caBundleName = "keos-ca-bundle"
+
+// getRegistryHosts returns the hosts of the docker registries, whose certs.d directories trust the CA bundle
+func getRegistryHosts(registries []commons.DockerRegistry) []string {
+	var hosts []string
+	for _, registry := range registries {
+		host := strings.SplitN(strings.TrimPrefix(strings.TrimPrefix(registry.URL, "https://"), "http://"), "/", 2)[0]
+		if !commons.Contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// installLocalCABundle trusts the CA bundle in the local container, both in the system trust store,
+// used by helm and clusterctl, and in the containerd certs.d directories of the docker registries
+func installLocalCABundle(n nodes.Node, caBundle string, registries []commons.DockerRegistry) error {
+	paths := []string{"/usr/local/share/ca-certificates/" + caBundleName + ".crt"}
+	for _, host := range getRegistryHosts(registries) {
+		paths = append(paths, "/etc/containerd/certs.d/"+host+"/ca.crt")
+	}
+	for _, path := range paths {
+		cmd := n.Command("sh", "-c", "mkdir -p $(dirname "+commons.ShellQuote(path)+") && cat > "+commons.ShellQuote(path))
+		if err := cmd.SetStdin(strings.NewReader(caBundle)).Run(); err != nil {
+			return errors.Wrap(err, "failed to write "+path)
+		}
+	}
+	_, err := commons.ExecuteCommand(n, "update-ca-certificates", 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to update the CA certificates")
+	}
+	return nil
+}
+
+// distributeCABundle creates the keos-ca-bundle ConfigMap, for the webhooks to reference it, and
+// trusts it in the containerd certs.d directories of the docker registries in every node
+func distributeCABundle(n nodes.Node, k string, privateParams PrivateParams, caBundle string) error {
+	configMap, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]string{"name": caBundleName, "namespace": "kube-system"},
+		"data":       map[string]string{"ca.crt": caBundle},
+	})
+	if err != nil {
+		return err
+	}
+	cmd := n.Command("kubectl", "--kubeconfig", k, "apply", "-f", "-")
+	if err = cmd.SetStdin(strings.NewReader(string(configMap))).Run(); err != nil {
+		return errors.Wrap(err, "failed to create the "+caBundleName+" ConfigMap")
+	}
+
+	var script []string
+	for _, host := range getRegistryHosts(privateParams.KeosCluster.Spec.DockerRegistries) {
+		script = append(script, "mkdir -p /certs.d/"+commons.ShellQuote(host)+" && cp /ca/ca.crt /certs.d/"+commons.ShellQuote(host)+"/ca.crt")
+	}
+	if len(script) == 0 {
+		return nil
+	}
+	return deployNodeConfigs(n, k, privateParams, nodeConfig{
+		name: caBundleName,
+		initContainer: map[string]interface{}{
+			"name":    "ca-bundle",
+			"command": []string{"/bin/sh", "-c", strings.Join(script, " && ")},
+			"volumeMounts": []map[string]string{
+				{"name": "ca", "mountPath": "/ca"},
+				{"name": "certs-d", "mountPath": "/certs.d"},
+			},
+		},
+		volumes: []map[string]interface{}{
+			{"name": "ca", "configMap": map[string]string{"name": caBundleName}},
+			{"name": "certs-d", "hostPath": map[string]string{"path": "/etc/containerd/certs.d", "type": "DirectoryOrCreate"}},
+		},
+	})
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+// The Cluster is labeled once it exists so the ClusterResourceSets of its namespace are bound to it
+const clusterResourceSetLabel = "keos.stratio.com/cluster-resource-sets"
+
+var invalidConfigMapKey = regexp.MustCompile(`[^-._a-zA-Z0-9]`)
+
+// createClusterResourceSets creates a ConfigMap with the manifests of each ClusterResourceSet
+// and the ClusterResourceSet itself, bound to the cluster
+func createClusterResourceSets(n nodes.Node, keosCluster commons.KeosCluster, capiClustersNamespace string, clusterResourceSets []commons.ClusterResourceSet) error {
+	// The ConfigMaps and ClusterResourceSets are applied together in the management cluster
+	batch := newManifestBatch("")
+	for _, crs := range clusterResourceSets {
+		data := map[string]string{}
+		for _, source := range crs.Sources {
+			manifests, err := readClusterResourceSetSource(source)
+			if err != nil {
+				return errors.Wrap(err, "failed to read "+source)
+			}
+			for name, manifest := range manifests {
+				data[invalidConfigMapKey.ReplaceAllString(name, "-")] = manifest
+			}
+		}
+
+		strategy := crs.Strategy
+		if strategy == "" {
+			strategy = "ApplyOnce"
+		}
+		resources := []interface{}{
+			map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]string{"name": crs.Name, "namespace": capiClustersNamespace},
+				"data":       data,
+			},
+			map[string]interface{}{
+				"apiVersion": "addons.cluster.x-k8s.io/v1beta1",
+				"kind":       "ClusterResourceSet",
+				"metadata":   map[string]string{"name": crs.Name, "namespace": capiClustersNamespace},
+				"spec": map[string]interface{}{
+					"strategy": strategy,
+					"clusterSelector": map[string]interface{}{
+						"matchLabels": map[string]string{clusterResourceSetLabel: "true"},
+					},
+					"resources": []map[string]string{{"name": crs.Name, "kind": "ConfigMap"}},
+				},
+			},
+		}
+		if err := batch.add(resources...); err != nil {
+			return err
+		}
+	}
+	if err := batch.apply(n); err != nil {
+		return errors.Wrap(err, "failed to create the ClusterResourceSets")
+	}
+
+	c := "kubectl -n " + commons.ShellQuote(capiClustersNamespace) + " label cluster " + commons.ShellQuote(keosCluster.Metadata.Name) + " " + clusterResourceSetLabel + "=true"
+	_, err := commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to bind the ClusterResourceSets to the cluster")
+	}
+	return nil
+}
+
+// readClusterResourceSetSource returns the manifests of a file, a directory or an https URL, by name
+func readClusterResourceSetSource(source string) (map[string]string, error) {
+	manifests := map[string]string{}
+
+	if strings.HasPrefix(source, "https://") {
+		resp, err := http.Get(source)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, errors.New("download failed: " + resp.Status)
+		}
+		manifest, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		manifests[path.Base(resp.Request.URL.Path)] = string(manifest)
+		return manifests, nil
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{source}
+	if info.IsDir() {
+		files = nil
+		for _, pattern := range []string{"*.yaml", "*.yml"} {
+			matches, err := filepath.Glob(filepath.Join(source, pattern))
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, matches...)
+		}
+	}
+	for _, file := range files {
+		manifest, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		manifests[filepath.Base(file)] = string(manifest)
+	}
+	return manifests, nil
+}
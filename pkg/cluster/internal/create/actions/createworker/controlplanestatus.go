@@ -0,0 +1,222 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v3"
+	"google.golang.org/api/container/v1"
+	"google.golang.org/api/option"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const (
+	managedControlPlaneTimeout      = 25 * time.Minute
+	managedControlPlanePollInterval = 30 * time.Second
+)
+
+// managedControlPlaneStatus is the status of the managed control plane, as reported by the provider
+type managedControlPlaneStatus struct {
+	state  string
+	failed bool
+	reason string
+}
+
+// waitForManagedControlPlane waits for the initialization of the managed control plane, polling
+// the provider so that its failures are surfaced instead of waiting for the timeout, as CAPx
+// keeps retrying them
+func waitForManagedControlPlane(n nodes.Node, infraProvider string, p ProviderParams, capiClustersNamespace string) error {
+	var cloudStatus managedControlPlaneStatus
+	var cloudErr error
+
+	c := "kubectl -n " + commons.ShellQuote(capiClustersNamespace) + " get cluster " + commons.ShellQuote(p.ClusterName) +
+		" -o jsonpath='{.status.conditions[?(@.type==\"ControlPlaneInitialized\")].status}'"
+	for deadline := time.Now().Add(managedControlPlaneTimeout); time.Now().Before(deadline); time.Sleep(managedControlPlanePollInterval) {
+		initialized, err := commons.ExecuteCommand(n, c, 5, 3)
+		if err == nil && strings.TrimSpace(initialized) == "True" {
+			return nil
+		}
+
+		// The control plane may not exist in the provider yet, so the errors are only reported on timeout
+		cloudStatus, cloudErr = getManagedControlPlaneStatus(n, infraProvider, p, capiClustersNamespace)
+		if cloudErr != nil {
+			continue
+		}
+		if cloudStatus.failed {
+			return errors.New("the " + infraProvider + " control plane is in " + cloudStatus.state + " state: " + cloudStatus.reason)
+		}
+	}
+
+	message := "timed out waiting for the control plane initialization"
+	if cloudErr != nil {
+		message += ", failed to get its status from " + infraProvider + ": " + cloudErr.Error()
+	} else if cloudStatus.state != "" {
+		message += ", its status in " + infraProvider + " is " + cloudStatus.state
+		if cloudStatus.reason != "" {
+			message += ": " + cloudStatus.reason
+		}
+	}
+	c = "kubectl -n " + commons.ShellQuote(capiClustersNamespace) + " get cluster " + commons.ShellQuote(p.ClusterName) +
+		" -o jsonpath='{range .status.conditions[?(@.status==\"False\")]}{.type}: {.message}; {end}'"
+	if conditions, err := commons.ExecuteCommand(n, c, 5, 3); err == nil && strings.TrimSpace(conditions) != "" {
+		message += " (cluster conditions: " + strings.TrimSuffix(strings.TrimSpace(conditions), ";") + ")"
+	}
+	return errors.New(message)
+}
+
+// getManagedControlPlaneStatus returns the status of the managed control plane in the provider,
+// identified by the CAPx control plane of the cluster
+func getManagedControlPlaneStatus(n nodes.Node, infraProvider string, p ProviderParams, capiClustersNamespace string) (managedControlPlaneStatus, error) {
+	switch infraProvider {
+	case "aws":
+		eksClusterName, err := getControlPlaneField(n, "awsmanagedcontrolplane", capiClustersNamespace, "{.spec.eksClusterName}")
+		if err != nil {
+			return managedControlPlaneStatus{}, err
+		}
+		return getEKSStatus(p, eksClusterName)
+	case "azure":
+		resourceGroup, err := getControlPlaneField(n, "azuremanagedcontrolplane", capiClustersNamespace, "{.spec.resourceGroupName}")
+		if err != nil {
+			return managedControlPlaneStatus{}, err
+		}
+		return getAKSStatus(p, resourceGroup, p.ClusterName)
+	case "gcp":
+		gkeCluster, err := getControlPlaneField(n, "gcpmanagedcontrolplane", capiClustersNamespace, "projects/{.spec.project}/locations/{.spec.location}/clusters/{.spec.clusterName}")
+		if err != nil {
+			return managedControlPlaneStatus{}, err
+		}
+		return getGKEStatus(p, gkeCluster)
+	}
+	return managedControlPlaneStatus{}, errors.New("the control plane status is not supported in " + infraProvider + " clusters")
+}
+
+func getControlPlaneField(n nodes.Node, kind string, capiClustersNamespace string, jsonPath string) (string, error) {
+	c := "kubectl -n " + commons.ShellQuote(capiClustersNamespace) + " get " + commons.ShellQuote(kind) + " -o " + commons.ShellQuote("jsonpath={range .items[0]}"+jsonPath+"{end}")
+	field, err := commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get the "+kind)
+	}
+	field = strings.TrimSpace(field)
+	if field == "" || strings.HasSuffix(field, "/") {
+		return "", errors.New("the " + kind + " has not been initialized yet")
+	}
+	return field, nil
+}
+
+func getEKSStatus(p ProviderParams, eksClusterName string) (managedControlPlaneStatus, error) {
+	var ctx = context.Background()
+	var response struct {
+		Cluster struct {
+			Status string `json:"status"`
+			Health struct {
+				Issues []struct {
+					Code    string `json:"code"`
+					Message string `json:"message"`
+				} `json:"issues"`
+			} `json:"health"`
+		} `json:"cluster"`
+	}
+
+	client, err := commons.NewAWSClient(ctx, p.Credentials, p.Region, "")
+	if err != nil {
+		return managedControlPlaneStatus{}, err
+	}
+	req, err := http.NewRequest(http.MethodGet, client.Endpoint("eks")+"/clusters/"+url.PathEscape(eksClusterName), nil)
+	if err != nil {
+		return managedControlPlaneStatus{}, err
+	}
+	resp, err := client.Do(ctx, "eks", req)
+	if err != nil {
+		return managedControlPlaneStatus{}, errors.Wrap(err, "failed to describe the EKS cluster")
+	}
+	if err = json.Unmarshal(resp, &response); err != nil {
+		return managedControlPlaneStatus{}, err
+	}
+
+	var issues []string
+	for _, issue := range response.Cluster.Health.Issues {
+		issues = append(issues, issue.Code+": "+issue.Message)
+	}
+	return managedControlPlaneStatus{
+		state:  response.Cluster.Status,
+		failed: response.Cluster.Status == "FAILED",
+		reason: strings.Join(issues, ", "),
+	}, nil
+}
+
+func getAKSStatus(p ProviderParams, resourceGroup string, aksClusterName string) (managedControlPlaneStatus, error) {
+	var ctx = context.Background()
+
+	cfg, err := commons.AzureGetConfig(p.Credentials)
+	if err != nil {
+		return managedControlPlaneStatus{}, err
+	}
+	clientFactory, err := armcontainerservice.NewClientFactory(p.Credentials["SubscriptionID"], cfg, nil)
+	if err != nil {
+		return managedControlPlaneStatus{}, err
+	}
+	res, err := clientFactory.NewManagedClustersClient().Get(ctx, resourceGroup, aksClusterName, nil)
+	if err != nil {
+		return managedControlPlaneStatus{}, errors.Wrap(err, "failed to get the AKS cluster")
+	}
+	if res.Properties == nil || res.Properties.ProvisioningState == nil {
+		return managedControlPlaneStatus{}, nil
+	}
+
+	status := managedControlPlaneStatus{
+		state:  *res.Properties.ProvisioningState,
+		failed: *res.Properties.ProvisioningState == "Failed",
+	}
+	if res.Properties.PowerState != nil && res.Properties.PowerState.Code != nil {
+		status.reason = "power state " + string(*res.Properties.PowerState.Code)
+	}
+	return status, nil
+}
+
+func getGKEStatus(p ProviderParams, gkeCluster string) (managedControlPlaneStatus, error) {
+	var ctx = context.Background()
+
+	containerService, err := container.NewService(ctx, option.WithCredentialsJSON(getGCPCredentials(p)))
+	if err != nil {
+		return managedControlPlaneStatus{}, err
+	}
+	cluster, err := containerService.Projects.Locations.Clusters.Get(gkeCluster).Do()
+	if err != nil {
+		return managedControlPlaneStatus{}, errors.Wrap(err, "failed to get the GKE cluster")
+	}
+
+	reasons := []string{}
+	if cluster.StatusMessage != "" {
+		reasons = append(reasons, cluster.StatusMessage)
+	}
+	for _, condition := range cluster.Conditions {
+		reasons = append(reasons, condition.Code+": "+condition.Message)
+	}
+	return managedControlPlaneStatus{
+		state:  cluster.Status,
+		failed: cluster.Status == "ERROR",
+		reason: strings.Join(reasons, ", "),
+	}, nil
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"encoding/json"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const (
+	openCostChart         = "opencost"
+	openCostServiceKey    = "opencost-service-key"
+	openCostServiceKeyDir = "/var/secrets"
+)
+
+var openCostChartEntry = commons.ChartEntry{Repository: "https://opencost.github.io/opencost-helm-chart", Version: "1.42.0", Namespace: "opencost", Pull: true, Reconcile: false}
+
+// deployOpenCost deploys OpenCost, reading the prices of the provider with the cluster credentials.
+// They are mounted from a Secret, as the Helm values are kept in ConfigMaps
+func deployOpenCost(n nodes.Node, k string, privateParams PrivateParams, providerParams ProviderParams, costAllocation commons.CostAllocation, chartsList map[string]commons.ChartEntry) error {
+	openCostEntry := chartsList[openCostChart]
+
+	c := "kubectl --kubeconfig " + commons.ShellQuote(k) + " create namespace " + commons.ShellQuote(openCostEntry.Namespace)
+	_, err := commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the "+openCostEntry.Namespace+" namespace")
+	}
+
+	// GCP prices are public, so no service key is required
+	var serviceKey map[string]interface{}
+	switch privateParams.KeosCluster.Spec.InfraProvider {
+	case "aws":
+		serviceKey = map[string]interface{}{
+			"aws_access_key_id":     providerParams.Credentials["AccessKey"],
+			"aws_secret_access_key": providerParams.Credentials["SecretKey"],
+		}
+	case "azure":
+		serviceKey = map[string]interface{}{
+			"subscriptionId": providerParams.Credentials["SubscriptionID"],
+			"serviceKey": map[string]string{
+				"appId":    providerParams.Credentials["ClientID"],
+				"password": providerParams.Credentials["ClientSecret"],
+				"tenant":   providerParams.Credentials["TenantID"],
+			},
+		}
+	}
+
+	exporter := map[string]interface{}{"defaultClusterId": providerParams.ClusterName}
+	helmValues := map[string]interface{}{}
+	if serviceKey != nil {
+		serviceKeyJSON, err := json.Marshal(serviceKey)
+		if err != nil {
+			return err
+		}
+		secret, err := yaml.Marshal(map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata":   map[string]string{"name": openCostServiceKey, "namespace": openCostEntry.Namespace},
+			"stringData": map[string]string{"service-key.json": string(serviceKeyJSON)},
+		})
+		if err != nil {
+			return err
+		}
+		cmd := n.Command("kubectl", "--kubeconfig", k, "apply", "-f", "-")
+		if err = cmd.SetStdin(strings.NewReader(string(secret))).Run(); err != nil {
+			return errors.Wrap(err, "failed to create the "+openCostServiceKey+" secret")
+		}
+		helmValues["extraVolumes"] = []map[string]interface{}{
+			{"name": "service-key", "secret": map[string]string{"secretName": openCostServiceKey}},
+		}
+		exporter["extraVolumeMounts"] = []map[string]interface{}{
+			{"name": "service-key", "mountPath": openCostServiceKeyDir, "readOnly": true},
+		}
+	}
+
+	ui := map[string]interface{}{"enabled": costAllocation.UI}
+	if privateParams.Private {
+		exporter["image"] = map[string]string{"registry": privateParams.KeosRegUrl}
+		ui["image"] = map[string]string{"registry": privateParams.KeosRegUrl}
+	}
+	helmValues["opencost"] = map[string]interface{}{
+		"exporter": exporter,
+		"ui":       ui,
+		"prometheus": map[string]interface{}{
+			"internal": map[string]bool{"enabled": false},
+			"external": map[string]interface{}{"enabled": true, "url": costAllocation.PrometheusURL},
+		},
+	}
+	helmValuesYAML, err := yaml.Marshal(helmValues)
+	if err != nil {
+		return err
+	}
+	err = commons.WriteFile(n, "/kind/"+openCostChart+"-helm-values.yaml", string(helmValuesYAML))
+	if err != nil {
+		return errors.Wrap(err, "failed to create "+openCostChart+" Helm chart values file")
+	}
+
+	openCostHelmReleaseParams := fluxHelmReleaseParams{
+		ChartRepoRef:   "keos",
+		ChartName:      openCostChart,
+		ChartNamespace: openCostEntry.Namespace,
+		ChartVersion:   openCostEntry.Version,
+	}
+	if !privateParams.HelmPrivate {
+		openCostHelmReleaseParams.ChartRepoRef = openCostChart
+	}
+	return configureHelmRelease(n, k, "flux2_helmrelease.tmpl", openCostHelmReleaseParams, privateParams.KeosCluster.Spec.HelmRepository)
+}
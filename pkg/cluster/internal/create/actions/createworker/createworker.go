@@ -22,8 +22,10 @@ import (
 	"context"
 	_ "embed"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"sigs.k8s.io/kind/pkg/cluster/internal/create/actions"
 	"sigs.k8s.io/kind/pkg/commons"
@@ -39,6 +41,9 @@ type action struct {
 	keosCluster        commons.KeosCluster
 	clusterCredentials commons.ClusterCredentials
 	clusterConfig      *commons.ClusterConfig
+	templatesDir       string
+	operationID        string
+	reporter           *operationReporter
 }
 
 type KeosRegistry struct {
@@ -65,9 +70,12 @@ const (
 	kubeconfigPath           = "/kind/worker-cluster.kubeconfig"
 	workKubeconfigPath       = ".kube/config"
 	CAPILocalRepository      = "/root/.cluster-api/local-repository"
+	clusterctlConfigPath     = "/root/.cluster-api/clusterctl.yaml"
 	cloudProviderBackupPath  = "/kind/backup/objects"
 	localBackupPath          = "backup"
+	overrideVarsPath         = "override_vars"
 	manifestsPath            = "/kind/manifests"
+	chartsPackagesPath       = "/kind/charts"
 	cniDefaultFile           = "/kind/manifests/default-cni.yaml"
 	storageDefaultPath       = "/kind/manifests/default-storage.yaml"
 	infraGCPVersion          = "v1.6.1"
@@ -82,6 +90,9 @@ var PathsToBackupLocally = []string{
 
 var majorVersion = ""
 
+// templatesDir holds the user templates overriding the embedded ones
+var templatesDir = ""
+
 //go:embed files/common/allow-all-egress_netpol.yaml
 var allowCommonEgressNetPol string
 
@@ -92,7 +103,7 @@ var rbacInternalLoadBalancing string
 var rbacAWSNode string
 
 // NewAction returns a new action for installing default CAPI
-func NewAction(vaultPassword string, descriptorPath string, moveManagement bool, avoidCreation bool, keosCluster commons.KeosCluster, clusterCredentials commons.ClusterCredentials, clusterConfig *commons.ClusterConfig) actions.Action {
+func NewAction(vaultPassword string, descriptorPath string, moveManagement bool, avoidCreation bool, keosCluster commons.KeosCluster, clusterCredentials commons.ClusterCredentials, clusterConfig *commons.ClusterConfig, templatesDir string, operationID string) actions.Action {
 	return &action{
 		vaultPassword:      vaultPassword,
 		descriptorPath:     descriptorPath,
@@ -101,16 +112,56 @@ func NewAction(vaultPassword string, descriptorPath string, moveManagement bool,
 		keosCluster:        keosCluster,
 		clusterCredentials: clusterCredentials,
 		clusterConfig:      clusterConfig,
+		templatesDir:       templatesDir,
+		operationID:        operationID,
 	}
 }
 
 // Execute runs the action
-func (a *action) Execute(ctx *actions.ActionContext) error {
+func (a *action) Execute(ctx *actions.ActionContext) (err error) {
+	commons.SetCommandLogger(ctx.Logger)
+	commons.SetCommandSecrets(append(commons.GetCredentialsSecrets(a.clusterCredentials), a.vaultPassword)...)
+	if a.operationID != "" {
+		n, err := ctx.GetNode()
+		if err != nil {
+			return err
+		}
+		a.reporter, err = startOperationReporter(n, ctx.Logger, a.operationID, a.keosCluster.Metadata.Name)
+		if err != nil {
+			return err
+		}
+		defer func() { a.reporter.finish(err) }()
+	}
+	started := time.Now()
+	// The runs which only deploy the management in the local cluster are recorded as bootstraps
+	operation := "bootstrap"
+	if !a.avoidCreation {
+		operation = "create"
+		if a.keosCluster.Spec.TTL != "" {
+			if err := setClusterExpiration(&a.keosCluster, started); err != nil {
+				return err
+			}
+		}
+	}
+	err = a.provision(ctx)
+
+	// The operation is recorded in the workload cluster, whatever its result
+	n, nodeErr := ctx.GetNode()
+	if nodeErr == nil {
+		if recordErr := recordOperation(n, operation, a.keosCluster, a.descriptorPath, started, err); recordErr != nil {
+			ctx.Logger.Warn(recordErr.Error())
+		}
+	}
+	return err
+}
+
+func (a *action) provision(ctx *actions.ActionContext) error {
 	var c string
 	var err error
 	var keosRegistry KeosRegistry
 	var helmRegistry HelmRegistry
 	majorVersion = strings.Split(a.keosCluster.Spec.K8SVersion, ".")[1]
+	templatesDir = a.templatesDir
 
 	// Get the target node
 	n, err := ctx.GetNode()
@@ -119,18 +170,94 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 	}
 
 	providerParams := ProviderParams{
-		ClusterName:  a.keosCluster.Metadata.Name,
-		Region:       a.keosCluster.Spec.Region,
-		Managed:      a.keosCluster.Spec.ControlPlane.Managed,
-		Credentials:  a.clusterCredentials.ProviderCredentials,
-		GithubToken:  a.clusterCredentials.GithubToken,
-		StorageClass: a.keosCluster.Spec.StorageClass,
+		ClusterName:        a.keosCluster.Metadata.Name,
+		Region:             a.keosCluster.Spec.Region,
+		Managed:            a.keosCluster.Spec.ControlPlane.Managed,
+		Credentials:        a.clusterCredentials.ProviderCredentials,
+		GithubToken:        a.clusterCredentials.GithubToken,
+		StorageClass:       a.keosCluster.Spec.StorageClass,
+		CAPXConfig:         a.clusterConfig.Spec.CAPXConfig,
+		ControlPlaneFlavor: a.keosCluster.Spec.ControlPlane.Flavor,
+		IBMCloud:           a.keosCluster.Spec.ControlPlane.IBMCloud,
+	}
+
+	// Worker groups deployed as machine pools and ClusterResourceSets need their feature gates
+	hasMachinePools := false
+	hasMachineDeployments := false
+	for _, wn := range a.keosCluster.Spec.WorkerNodes {
+		if wn.MachinePool {
+			hasMachinePools = true
+		} else {
+			hasMachineDeployments = true
+		}
+	}
+	if hasMachinePools && providerParams.CAPXConfig.FeatureGates.MachinePool == nil {
+		machinePool := true
+		providerParams.CAPXConfig.FeatureGates.MachinePool = &machinePool
+	}
+	if len(a.clusterConfig.Spec.ClusterResourceSets) > 0 && providerParams.CAPXConfig.FeatureGates.ClusterResourceSet == nil {
+		clusterResourceSet := true
+		providerParams.CAPXConfig.FeatureGates.ClusterResourceSet = &clusterResourceSet
 	}
 
 	providerBuilder := getBuilder(a.keosCluster.Spec.InfraProvider)
 	infra := newInfra(providerBuilder)
 	provider := infra.buildProvider(providerParams)
 
+	// The providers of a reused local cluster are already installed, only the state of its previous run is reset
+	bootstrapMarker := getBootstrapMarker(provider, a.keosCluster.Spec)
+	bootstrapReused, err := isBootstrapReused(n, bootstrapMarker)
+	if err != nil {
+		return err
+	}
+	if bootstrapReused {
+		ctx.Status.Start("Resetting the reused temporary cluster ♻️")
+		defer ctx.Status.End(false)
+
+		err = resetBootstrap(n)
+		if err != nil {
+			return err
+		}
+
+		ctx.Status.End(true) // End Resetting the reused temporary cluster
+	}
+
+	// Other operations over the workload cluster must wait until the provisioning ends. The lock is held
+	// in the management cluster shared by the operators: the local one if it is kept, or otherwise the
+	// workload cluster the management is moved to, once it exists
+	var lock *clusterLock
+	defer func() {
+		if lock == nil {
+			return
+		}
+		if err := lock.release(); err != nil {
+			ctx.Logger.Warn(err.Error())
+		}
+	}()
+	if a.moveManagement || a.avoidCreation {
+		lock, err = acquireClusterLock(n, ctx.Logger, "", a.keosCluster.Metadata.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	var caBundle []byte
+	if a.clusterConfig.Spec.CABundle != "" {
+		ctx.Status.Start("Trusting the CA bundle 🔏")
+		defer ctx.Status.End(false)
+
+		caBundle, err = os.ReadFile(a.clusterConfig.Spec.CABundle)
+		if err != nil {
+			return errors.Wrap(err, "failed to read the CA bundle")
+		}
+		err = installLocalCABundle(n, string(caBundle), a.keosCluster.Spec.DockerRegistries)
+		if err != nil {
+			return errors.Wrap(err, "failed to trust the CA bundle in the local container")
+		}
+
+		ctx.Status.End(true) // End Trusting the CA bundle
+	}
+
 	ctx.Status.Start("Pulling initial Helm Charts 🧭")
 
 	err = loginHelmRepo(n, a.keosCluster, a.clusterCredentials, &helmRegistry, infra, providerParams)
@@ -168,6 +295,19 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 	isMachinePool := a.keosCluster.Spec.InfraProvider != "aws" && a.keosCluster.Spec.ControlPlane.Managed
 	gcpGKEEnabled := a.keosCluster.Spec.InfraProvider == "gcp" && a.keosCluster.Spec.ControlPlane.Managed
 
+	// The machines are given the instance profiles of the IAM stack, which are named after it when it
+	// is not the default one
+	if a.keosCluster.Spec.InfraProvider == "aws" && a.keosCluster.Spec.Security.AWS.CreateIAM {
+		if suffix := commons.GetAWSIAMSuffix(a.keosCluster.Spec.Security.AWS); suffix != commons.AWSIAMSuffix {
+			if a.keosCluster.Spec.Security.ControlPlaneIdentity == "" {
+				a.keosCluster.Spec.Security.ControlPlaneIdentity = "control-plane" + suffix
+			}
+			if a.keosCluster.Spec.Security.NodesIdentity == "" {
+				a.keosCluster.Spec.Security.NodesIdentity = "nodes" + suffix
+			}
+		}
+	}
+
 	var privateParams PrivateParams
 	if a.clusterConfig != nil {
 		privateParams = PrivateParams{
@@ -184,7 +324,7 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 		}
 	}
 
-	if privateParams.Private {
+	if privateParams.Private && !bootstrapReused {
 		ctx.Status.Start("Installing Private CNI 🎖️")
 		defer ctx.Status.End(false)
 
@@ -193,7 +333,7 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 		if err != nil {
 			return err
 		}
-		c = `sed -i 's|docker.io|` + keosRegistry.url + `|g' /kind/manifests/default-cni.yaml`
+		c = "sed -i " + commons.ShellQuote("s|docker.io|"+keosRegistry.url+"|g") + " /kind/manifests/default-cni.yaml"
 
 		_, err = commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
@@ -248,118 +388,124 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 		keosRegistry.pass = a.clusterCredentials.KeosRegistryCredentials["Pass"]
 	}
 
-	// Create docker-registry secret for keos cluster
+	// Create docker-registry secret for keos cluster, which is refreshed in a reused local cluster
 	c = "kubectl -n kube-system create secret docker-registry regcred" +
-		" --docker-server=" + strings.Split(keosRegistry.url, "/")[0] +
-		" --docker-username=" + keosRegistry.user +
-		" --docker-password=" + keosRegistry.pass
+		" --docker-server=" + commons.ShellQuote(strings.Split(keosRegistry.url, "/")[0]) +
+		" " + commons.ShellQuote("--docker-username="+keosRegistry.user, "--docker-password="+keosRegistry.pass) +
+		" --dry-run=client -o yaml | kubectl apply -f -"
 
 	_, err = commons.ExecuteCommand(n, c, 5, 3)
 	if err != nil {
 		return errors.Wrap(err, "failed to create docker-registry secret")
 	}
 
-	if provider.capxVersion != provider.capxImageVersion {
+	if !bootstrapReused {
+		if provider.capxVersion != provider.capxImageVersion {
 
-		infraComponents := CAPILocalRepository + "/infrastructure-" + provider.capxProvider + "/" + provider.capxVersion + "/infrastructure-components.yaml"
+			infraComponents := CAPILocalRepository + "/infrastructure-" + provider.capxProvider + "/" + provider.capxVersion + "/infrastructure-components.yaml"
 
-		// Create provider-system namespace
-		c = "kubectl create namespace " + provider.capxName + "-system"
+			// Create provider-system namespace
+			c = "kubectl create namespace " + commons.ShellQuote(provider.capxName) + "-system"
 
-		_, err = commons.ExecuteCommand(n, c, 5, 3)
-		if err != nil {
-			return errors.Wrap(err, "failed to create "+provider.capxName+"-system namespace")
-		}
+			_, err = commons.ExecuteCommand(n, c, 5, 3)
+			if err != nil {
+				return errors.Wrap(err, "failed to create "+provider.capxName+"-system namespace")
+			}
 
-		// Create docker-registry secret in provider-system namespace
-		c = "kubectl create secret docker-registry regcred" +
-			" --docker-server=" + strings.Split(keosRegistry.url, "/")[0] +
-			" --docker-username=" + keosRegistry.user +
-			" --docker-password=" + keosRegistry.pass +
-			" --namespace=" + provider.capxName + "-system"
+			// Create docker-registry secret in provider-system namespace
+			c = "kubectl create secret docker-registry regcred" +
+				" --docker-server=" + commons.ShellQuote(strings.Split(keosRegistry.url, "/")[0]) +
+				" " + commons.ShellQuote("--docker-username="+keosRegistry.user, "--docker-password="+keosRegistry.pass) +
+				" --namespace=" + commons.ShellQuote(provider.capxName) + "-system"
 
-		_, err = commons.ExecuteCommand(n, c, 5, 3)
-		if err != nil {
-			return errors.Wrap(err, "failed to create docker-registry secret")
+			_, err = commons.ExecuteCommand(n, c, 5, 3)
+			if err != nil {
+				return errors.Wrap(err, "failed to create docker-registry secret")
+			}
+
+			// Add imagePullSecrets to infrastructure-components.yaml
+			c = "sed -i '/containers:/i\\      imagePullSecrets:\\n      - name: regcred' " + commons.ShellQuote(infraComponents)
+
+			_, err = commons.ExecuteCommand(n, c, 5, 3)
+			if err != nil {
+				return errors.Wrap(err, "failed to add imagePullSecrets to infrastructure-components.yaml")
+			}
 		}
 
-		// Add imagePullSecrets to infrastructure-components.yaml
-		c = "sed -i '/containers:/i\\      imagePullSecrets:\\n      - name: regcred' " + infraComponents
+		certManagerVersion := getChartVersion(a.clusterConfig.Spec.Charts, "cert-manager")
+		if certManagerVersion == "" {
+			return errors.New("Cert manager helm chart version cannot be found ")
+		}
+		err = provider.deployCertManager(n, keosRegistry.url, "", privateParams, make(map[string]commons.ChartEntry))
+		if err != nil {
+			return err
+		}
 
-		_, err = commons.ExecuteCommand(n, c, 5, 3)
+		err = setClusterctlConfig(n, map[string]interface{}{
+			"cert-manager": map[string]string{"version": certManagerVersion},
+		})
 		if err != nil {
-			return errors.Wrap(err, "failed to add imagePullSecrets to infrastructure-components.yaml")
+			return errors.Wrap(err, "failed to set cert-manager version in clusterctl config")
 		}
-	}
 
-	certManagerVersion := getChartVersion(a.clusterConfig.Spec.Charts, "cert-manager")
-	if certManagerVersion == "" {
-		return errors.New("Cert manager helm chart version cannot be found ")
-	}
-	err = provider.deployCertManager(n, keosRegistry.url, "", privateParams, make(map[string]commons.ChartEntry))
-	if err != nil {
-		return err
-	}
+		if privateParams.Private {
 
-	c = "echo \"cert-manager:\" >> /root/.cluster-api/clusterctl.yaml && " +
-		"echo \"  version: " + certManagerVersion + "\" >> /root/.cluster-api/clusterctl.yaml "
+			gcpVersion := infraGCPVersion
+			if gcpGKEEnabled {
+				gcpVersion = provider.capxImageVersion
+			}
 
-	_, err = commons.ExecuteCommand(n, c, 5, 3)
-	if err != nil {
-		return errors.Wrap(err, "failed to set cert-manager version in clusterctl config")
-	}
+			err = setClusterctlConfig(n, map[string]interface{}{
+				"images": map[string]map[string]string{
+					"cluster-api":           {"repository": keosRegistry.url + "/cluster-api"},
+					"bootstrap-kubeadm":     {"repository": keosRegistry.url + "/cluster-api"},
+					"control-plane-kubeadm": {"repository": keosRegistry.url + "/cluster-api"},
+					"infrastructure-aws":    {"repository": keosRegistry.url + "/cluster-api-aws", "tag": infraAWSVersion},
+					"infrastructure-gcp":    {"repository": keosRegistry.url + "/cluster-api-gcp", "tag": gcpVersion},
+					"infrastructure-azure":  {"repository": keosRegistry.url + "/cluster-api-azure"},
+					"cert-manager":          {"repository": keosRegistry.url + "/cert-manager"},
+				},
+			})
+			if err != nil {
+				return errors.Wrap(err, "failed to add private image registry clusterctl config")
+			}
 
-	if privateParams.Private {
+			c = `sed -i 's/@sha256:[[:alnum:]_-].*$//g' /root/.cluster-api/local-repository/infrastructure-gcp/` + infraGCPVersion + `/infrastructure-components.yaml`
+			_, err = commons.ExecuteCommand(n, c, 5, 3)
+			if err != nil {
+				return err
+			}
+		} else if gcpGKEEnabled {
+			err = setClusterctlConfig(n, map[string]interface{}{
+				"images": map[string]map[string]string{
+					"infrastructure-gcp": {"repository": keosRegistry.url + "/cluster-api-gcp", "tag": provider.capxImageVersion},
+				},
+			})
+			if err != nil {
+				return errors.Wrap(err, "failed to overwrite image registry clusterctl config")
+			}
+		}
 
-		gcpVersion := infraGCPVersion
-		if gcpGKEEnabled {
-			gcpVersion = provider.capxImageVersion
-		}
-
-		c = "echo \"images:\" >> /root/.cluster-api/clusterctl.yaml && " +
-			"echo \"  cluster-api:\" >> /root/.cluster-api/clusterctl.yaml && " +
-			"echo \"    repository: " + keosRegistry.url + "/cluster-api\" >> /root/.cluster-api/clusterctl.yaml && " +
-			"echo \"  bootstrap-kubeadm:\" >> /root/.cluster-api/clusterctl.yaml && " +
-			"echo \"    repository: " + keosRegistry.url + "/cluster-api\" >> /root/.cluster-api/clusterctl.yaml && " +
-			"echo \"  control-plane-kubeadm:\" >> /root/.cluster-api/clusterctl.yaml && " +
-			"echo \"    repository: " + keosRegistry.url + "/cluster-api\" >> /root/.cluster-api/clusterctl.yaml && " +
-			"echo \"  infrastructure-aws:\" >> /root/.cluster-api/clusterctl.yaml && " +
-			"echo \"    repository: " + keosRegistry.url + "/cluster-api-aws\" >> /root/.cluster-api/clusterctl.yaml && " +
-			"echo \"    tag: " + infraAWSVersion + "\" >> /root/.cluster-api/clusterctl.yaml && " +
-			"echo \"  infrastructure-gcp:\" >> /root/.cluster-api/clusterctl.yaml && " +
-			"echo \"    repository: " + keosRegistry.url + "/cluster-api-gcp\" >> /root/.cluster-api/clusterctl.yaml && " +
-			"echo \"    tag: " + gcpVersion + "\" >> /root/.cluster-api/clusterctl.yaml && " +
-			"echo \"  infrastructure-azure:\" >> /root/.cluster-api/clusterctl.yaml && " +
-			"echo \"    repository: " + keosRegistry.url + "/cluster-api-azure\" >> /root/.cluster-api/clusterctl.yaml && " +
-			"echo \"  cert-manager:\" >> /root/.cluster-api/clusterctl.yaml && " +
-			"echo \"    repository: " + keosRegistry.url + "/cert-manager\" >> /root/.cluster-api/clusterctl.yaml "
-		_, err = commons.ExecuteCommand(n, c, 5, 3)
-		if err != nil {
-			return errors.Wrap(err, "failed to add private image registry clusterctl config")
+		// The providers of the ClusterConfig prevail over the ones of the flavor
+		capiProviders := append(getCAPIFlavorProviderEntries(a.keosCluster.Spec.ControlPlane.Flavor), a.clusterConfig.Spec.CAPIProviders...)
+		if len(capiProviders) > 0 {
+			err = configureCAPIProviders(n, capiProviders)
+			if err != nil {
+				return err
+			}
 		}
 
-		c = `sed -i 's/@sha256:[[:alnum:]_-].*$//g' /root/.cluster-api/local-repository/infrastructure-gcp/` + infraGCPVersion + `/infrastructure-components.yaml`
-		_, err = commons.ExecuteCommand(n, c, 5, 3)
+		err = provider.installCAPXLocal(n)
 		if err != nil {
 			return err
 		}
-	} else if gcpGKEEnabled {
-		c = "echo \"images:\" >> /root/.cluster-api/clusterctl.yaml && " +
-			"echo \"  infrastructure-gcp:\" >> /root/.cluster-api/clusterctl.yaml && " +
-			"echo \"    repository: " + keosRegistry.url + "/cluster-api-gcp\" >> /root/.cluster-api/clusterctl.yaml && " +
-			"echo \"    tag: " + provider.capxImageVersion + "\" >> /root/.cluster-api/clusterctl.yaml "
 
-		_, err = commons.ExecuteCommand(n, c, 5, 3)
+		err = markBootstrap(n, bootstrapMarker)
 		if err != nil {
-			return errors.Wrap(err, "failed to overwrite image registry clusterctl config")
+			return err
 		}
 	}
 
-	err = provider.installCAPXLocal(n)
-	if err != nil {
-		return err
-	}
-
 	capiClustersNamespace := "cluster-" + a.keosCluster.Metadata.Name
 
 	ctx.Status.End(true) // End Installing CAPx
@@ -380,7 +526,7 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 	defer ctx.Status.End(true) // End Generating secrets file
 
 	// Create namespace for CAPI clusters (it must exists)
-	c = "kubectl create ns " + capiClustersNamespace
+	c = "kubectl create ns " + commons.ShellQuote(capiClustersNamespace)
 
 	_, err = commons.ExecuteCommand(n, c, 5, 3)
 	if err != nil {
@@ -389,9 +535,7 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 
 	// Create the allow-all-egress network policy file in the container
 	allowCommonEgressNetPolPath := "/kind/allow-all-egress_netpol.yaml"
-	c = "echo \"" + allowCommonEgressNetPol + "\" > " + allowCommonEgressNetPolPath
-
-	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	err = commons.WriteFile(n, allowCommonEgressNetPolPath, allowCommonEgressNetPol)
 	if err != nil {
 		return errors.Wrap(err, "failed to write the allow-all-egress network policy")
 	}
@@ -411,7 +555,7 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 			ctx.Status.Start("[CAPA] Ensuring IAM security 👮")
 			defer ctx.Status.End(false)
 
-			err = createCloudFormationStack(n, provider.capxEnvVars)
+			err = ensureAWSIAMStack(providerParams, a.keosCluster.Spec.Security.AWS, a.clusterConfig)
 			if err != nil {
 				return errors.Wrap(err, "failed to create the IAM security")
 			}
@@ -421,31 +565,36 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 		ctx.Status.Start("Creating the workload cluster 💥")
 		defer ctx.Status.End(false)
 
+		// Apply cluster manifests
+		clusterManifests := []string{manifestsPath + "/keoscluster.yaml"}
 		if a.clusterConfig != nil {
-			// Apply cluster manifests
-			c = "kubectl apply -f " + manifestsPath + "/clusterconfig.yaml"
-			_, err = commons.ExecuteCommand(n, c, 5, 5)
-			if err != nil {
-				return errors.Wrap(err, "failed to apply clusterconfig manifests")
-			}
+			clusterManifests = append([]string{manifestsPath + "/clusterconfig.yaml"}, clusterManifests...)
 		}
-
-		// Apply cluster manifests
-		c = "kubectl apply -f " + manifestsPath + "/keoscluster.yaml"
-		_, err = commons.ExecuteCommand(n, c, 10, 5)
+		err = applyManifestFiles(n, "", 10, 5, clusterManifests...)
 		if err != nil {
 			return errors.Wrap(err, "failed to apply keoscluster manifests")
 		}
 
-		c = "kubectl -n " + capiClustersNamespace + " get cluster " + a.keosCluster.Metadata.Name
+		c = "kubectl -n " + commons.ShellQuote(capiClustersNamespace) + " get cluster " + commons.ShellQuote(a.keosCluster.Metadata.Name)
 		_, err = commons.ExecuteCommand(n, c, 25, 5)
 		if err != nil {
 			return errors.Wrap(err, "failed to wait for cluster")
 		}
 
+		if len(a.clusterConfig.Spec.ClusterResourceSets) > 0 {
+			err = createClusterResourceSets(n, a.keosCluster, capiClustersNamespace, a.clusterConfig.Spec.ClusterResourceSets)
+			if err != nil {
+				return err
+			}
+		}
+
 		// Wait for the control plane initialization
-		c = "kubectl -n " + capiClustersNamespace + " wait --for=condition=ControlPlaneInitialized --timeout=25m cluster " + a.keosCluster.Metadata.Name
-		_, err = commons.ExecuteCommand(n, c, 5, 3)
+		if a.keosCluster.Spec.ControlPlane.Managed {
+			err = waitForManagedControlPlane(n, a.keosCluster.Spec.InfraProvider, providerParams, capiClustersNamespace)
+		} else {
+			c = "kubectl -n " + commons.ShellQuote(capiClustersNamespace) + " wait --for=condition=ControlPlaneInitialized --timeout=25m cluster " + commons.ShellQuote(a.keosCluster.Metadata.Name)
+			_, err = commons.ExecuteCommand(n, c, 5, 3)
+		}
 		if err != nil {
 			return errors.Wrap(err, "failed to create the workload cluster")
 		}
@@ -456,34 +605,69 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 		defer ctx.Status.End(false)
 
 		// Get the workload cluster kubeconfig
-		c = "clusterctl -n " + capiClustersNamespace + " get kubeconfig " + a.keosCluster.Metadata.Name + " | tee " + kubeconfigPath
+		c = "clusterctl -n " + commons.ShellQuote(capiClustersNamespace) + " get kubeconfig " + commons.ShellQuote(a.keosCluster.Metadata.Name) + " | tee " + kubeconfigPath
 		kubeconfig, err := commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil || kubeconfig == "" {
 			return errors.Wrap(err, "failed to get workload cluster kubeconfig")
 		}
 
 		// Create worker-kubeconfig secret for keos cluster
-		c = "kubectl -n " + capiClustersNamespace + " create secret generic worker-kubeconfig --from-file " + kubeconfigPath
+		c = "kubectl -n " + commons.ShellQuote(capiClustersNamespace) + " create secret generic worker-kubeconfig --from-file " + kubeconfigPath
 		_, err = commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
 			return errors.Wrap(err, "failed to create worker-kubeconfig secret")
 		}
 
-		workKubeconfigBasePath := strings.Split(workKubeconfigPath, "/")[0]
+		localKubeconfigPath := getLocalPath(a.keosCluster, workKubeconfigPath)
+		workKubeconfigBasePath := filepath.Dir(localKubeconfigPath)
 		_, err = os.Stat(workKubeconfigBasePath)
 		if err != nil {
-			err := os.Mkdir(workKubeconfigBasePath, os.ModePerm)
+			err := os.MkdirAll(workKubeconfigBasePath, os.ModePerm)
 			if err != nil {
 				return err
 			}
 		}
-		err = os.WriteFile(workKubeconfigPath, []byte(kubeconfig), 0600)
+		err = os.WriteFile(localKubeconfigPath, []byte(kubeconfig), 0600)
 		if err != nil {
 			return errors.Wrap(err, "failed to save the workload cluster kubeconfig")
 		}
 
 		ctx.Status.End(true) // End Saving the workload cluster kubeconfig
 
+		if lock == nil {
+			lock, err = acquireClusterLock(n, ctx.Logger, kubeconfigPath, a.keosCluster.Metadata.Name)
+			if err != nil {
+				return err
+			}
+			if a.reporter != nil {
+				a.reporter.setKubeconfig(kubeconfigPath)
+			}
+		}
+
+		if a.keosCluster.Spec.ControlPlane.APIServer.CreateRecord {
+			ctx.Status.Start("Creating the API server DNS record 🌐")
+			defer ctx.Status.End(false)
+
+			c = "kubectl -n " + commons.ShellQuote(capiClustersNamespace) + " get cluster " + commons.ShellQuote(a.keosCluster.Metadata.Name) + " -o jsonpath='{.spec.controlPlaneEndpoint.host}'"
+			endpoint, err := commons.ExecuteCommand(n, c, 5, 3)
+			if err != nil || endpoint == "" {
+				return errors.Wrap(err, "failed to get the control plane endpoint")
+			}
+			err = createAPIServerDNSRecord(a.keosCluster.Spec.InfraProvider, providerParams, a.keosCluster.Spec.ControlPlane.APIServer, endpoint)
+			if err != nil {
+				return errors.Wrap(err, "failed to create the API server DNS record")
+			}
+
+			// Use the DNS name in the local kubeconfig
+			kubeconfig = strings.Replace(kubeconfig, "server: https://"+endpoint+":", "server: https://"+a.keosCluster.Spec.ControlPlane.APIServer.DNSName+":", -1)
+			err = os.WriteFile(localKubeconfigPath, []byte(kubeconfig), 0600)
+			if err != nil {
+				return errors.Wrap(err, "failed to save the workload cluster kubeconfig")
+			}
+
+			ctx.Status.End(true) // End Creating the API server DNS record
+		}
+
 		// Install unmanaged cluster addons
 		if !a.keosCluster.Spec.ControlPlane.Managed {
 
@@ -498,11 +682,18 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 			ctx.Status.End(true) // End Installing cloud-provider in workload cluster
 		}
 
-		if !a.keosCluster.Spec.ControlPlane.Managed || a.keosCluster.Spec.InfraProvider == "aws" {
+		if installsCalico(a.keosCluster.Spec) {
 			ctx.Status.Start("Installing Calico in workload cluster 🔌")
 			defer ctx.Status.End(false)
 
-			isNetPolEngine := gcpGKEEnabled || awsEKSEnabled
+			isNetPolEngine := isCalicoNetPolEngine(a.keosCluster.Spec)
+
+			if awsEKSEnabled && a.keosCluster.Spec.ControlPlane.AWS.CalicoCNI {
+				err = removeAWSNode(n, kubeconfigPath)
+				if err != nil {
+					return errors.Wrap(err, "failed to remove the VPC CNI from workload cluster")
+				}
+			}
 
 			err = installCalico(n, kubeconfigPath, privateParams, isNetPolEngine, false)
 
@@ -527,26 +718,27 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 			if err != nil {
 				return errors.Wrap(err, "failed to wait for capa-controller-manager")
 			}
-			// Patch aws-node clusterrole with the required permissions
-			// https://github.com/aws/amazon-vpc-cni-k8s?tab=readme-ov-file#annotate_pod_ip-v193
-			rbacAWSNodePath := "/kind/aws-node_rbac.yaml"
+			if !a.keosCluster.Spec.ControlPlane.AWS.CalicoCNI {
+				// Patch aws-node clusterrole with the required permissions
+				// https://github.com/aws/amazon-vpc-cni-k8s?tab=readme-ov-file#annotate_pod_ip-v193
+				rbacAWSNodePath := "/kind/aws-node_rbac.yaml"
 
-			// Deploy Kubernetes additional RBAC aws node
-			c = "echo \"" + rbacAWSNode + "\" > " + rbacAWSNodePath
-			_, err = commons.ExecuteCommand(n, c, 5, 3)
-			if err != nil {
-				return errors.Wrap(err, "failed to write the kubernetes additional RBAC aws node")
-			}
-			c = "kubectl --kubeconfig " + kubeconfigPath + " apply -f " + rbacAWSNodePath
-			_, err = commons.ExecuteCommand(n, c, 5, 3)
-			if err != nil {
-				return errors.Wrap(err, "failed to apply the kubernetes additional RBAC aws node")
+				// Deploy Kubernetes additional RBAC aws node
+				err = commons.WriteFile(n, rbacAWSNodePath, rbacAWSNode)
+				if err != nil {
+					return errors.Wrap(err, "failed to write the kubernetes additional RBAC aws node")
+				}
+				c = "kubectl --kubeconfig " + kubeconfigPath + " apply -f " + commons.ShellQuote(rbacAWSNodePath)
+				_, err = commons.ExecuteCommand(n, c, 5, 3)
+				if err != nil {
+					return errors.Wrap(err, "failed to apply the kubernetes additional RBAC aws node")
+				}
 			}
 		}
 
 		if isMachinePool {
 			// Wait for all the machine pools to be ready
-			c = "kubectl -n " + capiClustersNamespace + " wait --for=condition=Ready --timeout=15m --all mp"
+			c = "kubectl -n " + commons.ShellQuote(capiClustersNamespace) + " wait --for=condition=Ready --timeout=15m --all mp"
 			_, err = commons.ExecuteCommand(n, c, 5, 3)
 			if err != nil {
 				return errors.Wrap(err, "failed to create the worker Cluster")
@@ -558,28 +750,145 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 				return errors.Wrap(err, "failed to wait for container metrics to be available")
 			}
 		} else {
-			// Wait for all the machine deployments to be ready
-			c = "kubectl -n " + capiClustersNamespace + " wait --for=condition=Ready --timeout=15m --all md"
+			if hasMachineDeployments {
+				// Wait for all the machine deployments to be ready
+				c = "kubectl -n " + commons.ShellQuote(capiClustersNamespace) + " wait --for=condition=Ready --timeout=15m --all md"
 
-			_, err = commons.ExecuteCommand(n, c, 5, 3)
-			if err != nil {
-				return errors.Wrap(err, "failed to create the worker Cluster")
+				_, err = commons.ExecuteCommand(n, c, 5, 3)
+				if err != nil {
+					return errors.Wrap(err, "failed to create the worker Cluster")
+				}
+			}
+			if hasMachinePools {
+				// Wait for the worker groups deployed as machine pools to be ready
+				c = "kubectl -n " + commons.ShellQuote(capiClustersNamespace) + " wait --for=condition=Ready --timeout=15m --all mp"
+
+				_, err = commons.ExecuteCommand(n, c, 5, 3)
+				if err != nil {
+					return errors.Wrap(err, "failed to create the worker Cluster")
+				}
 			}
 		}
 
 		if !a.keosCluster.Spec.ControlPlane.Managed && *a.keosCluster.Spec.ControlPlane.HighlyAvailable {
 			// Wait for all control planes to be ready
-			c = "kubectl -n " + capiClustersNamespace +
+			c = "kubectl -n " + commons.ShellQuote(capiClustersNamespace) +
 				" wait --for=jsonpath=\"{.status.readyReplicas}\"=3" +
-				" --timeout 10m kubeadmcontrolplanes " + a.keosCluster.Metadata.Name + "-control-plane"
+				" --timeout 10m kubeadmcontrolplanes " + commons.ShellQuote(a.keosCluster.Metadata.Name) + "-control-plane"
 			_, err = commons.ExecuteCommand(n, c, 5, 3)
 			if err != nil {
 				return errors.Wrap(err, "failed to create the worker Cluster")
 			}
 		}
 
+		if a.keosCluster.Spec.Profile == commons.DevProfile {
+			// The single node of the dev profile runs the workloads too
+			c = "kubectl --kubeconfig " + kubeconfigPath + " taint nodes -l node-role.kubernetes.io/control-plane node-role.kubernetes.io/control-plane:NoSchedule-"
+			_, err = commons.ExecuteCommand(n, c, 5, 3)
+			if err != nil {
+				return errors.Wrap(err, "failed to allow the workloads in the control plane node")
+			}
+		}
+
 		ctx.Status.End(true) // End Preparing nodes in workload cluster
 
+		if len(caBundle) > 0 {
+			ctx.Status.Start("Distributing the CA bundle in workload cluster 🔏")
+			defer ctx.Status.End(false)
+
+			err = distributeCABundle(n, kubeconfigPath, privateParams, string(caBundle))
+			if err != nil {
+				return errors.Wrap(err, "failed to distribute the CA bundle in workload cluster")
+			}
+
+			ctx.Status.End(true) // End Distributing the CA bundle in workload cluster
+		}
+
+		if len(a.clusterConfig.Spec.Namespaces) > 0 {
+			ctx.Status.Start("Creating namespaces in workload cluster 🏘️")
+			defer ctx.Status.End(false)
+
+			err = createNamespaces(n, kubeconfigPath, a.clusterConfig.Spec.Namespaces)
+			if err != nil {
+				return errors.Wrap(err, "failed to create the namespaces in workload cluster")
+			}
+
+			ctx.Status.End(true) // End Creating namespaces in workload cluster
+		}
+
+		if a.clusterConfig.Spec.RegistryLogin != nil {
+			ctx.Status.Start("Configuring the keos registry login in workload cluster 🪪")
+			defer ctx.Status.End(false)
+
+			err = configureRegistryLogin(n, kubeconfigPath, keosRegistry, *a.clusterConfig.Spec.RegistryLogin)
+			if err != nil {
+				return errors.Wrap(err, "failed to configure the keos registry login in workload cluster")
+			}
+
+			ctx.Status.End(true) // End Configuring the keos registry login in workload cluster
+		}
+
+		if len(a.clusterConfig.Spec.RegistryMirrors) > 0 {
+			if a.keosCluster.Spec.ControlPlane.Managed {
+				ctx.Logger.Warn("The registry mirrors are only set in the local container, the nodes of managed clusters are not configurable")
+			} else {
+				ctx.Status.Start("Configuring registry mirrors in workload cluster 🪞")
+				defer ctx.Status.End(false)
+
+				err = configureContainerdMirrors(n, kubeconfigPath, privateParams, a.clusterConfig.Spec.RegistryMirrors)
+				if err != nil {
+					return errors.Wrap(err, "failed to configure the registry mirrors in workload cluster")
+				}
+
+				ctx.Status.End(true) // End Configuring registry mirrors in workload cluster
+			}
+		}
+
+		if a.clusterConfig.Spec.SystemBaseline != nil {
+			ctx.Status.Start("Configuring system baseline in workload cluster ⚖️")
+			defer ctx.Status.End(false)
+
+			if a.clusterConfig.Spec.SystemBaseline.PriorityClasses {
+				err = createPriorityClasses(n, kubeconfigPath)
+				if err != nil {
+					return errors.Wrap(err, "failed to create the PriorityClasses in workload cluster")
+				}
+			}
+			// The kubelets of managed clusters are configured by the provider, which already reserves resources
+			if !a.keosCluster.Spec.ControlPlane.Managed {
+				err = reserveKubeletResources(n, kubeconfigPath, privateParams, *a.clusterConfig.Spec.SystemBaseline)
+				if err != nil {
+					return errors.Wrap(err, "failed to reserve the system resources in workload cluster")
+				}
+			}
+
+			ctx.Status.End(true) // End Configuring system baseline in workload cluster
+		}
+
+		if hasNodeSecurity(a.keosCluster.Spec.WorkerNodes) {
+			ctx.Status.Start("Configuring node security in node groups 🛡️")
+			defer ctx.Status.End(false)
+
+			err = configureNodeSecurity(n, kubeconfigPath, privateParams, a.keosCluster.Spec.WorkerNodes)
+			if err != nil {
+				return errors.Wrap(err, "failed to configure the node security of node groups")
+			}
+
+			ctx.Status.End(true) // End Configuring node security
+		}
+
+		if len(a.clusterConfig.Spec.PreloadImages) > 0 {
+			ctx.Status.Start("Preloading images in workload cluster 📥")
+			defer ctx.Status.End(false)
+
+			err = preloadImages(n, kubeconfigPath, a.clusterConfig.Spec.PreloadImages)
+			if err != nil {
+				return errors.Wrap(err, "failed to preload the images in workload cluster")
+			}
+
+			ctx.Status.End(true) // End Preloading images in workload cluster
+		}
+
 		if gcpGKEEnabled {
 			ctx.Status.Start("Enabling CoreDNS as DNS server 📡")
 			defer ctx.Status.End(false)
@@ -591,19 +900,17 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 			if err != nil {
 				return errors.Wrap(err, "failed to get CoreDNS file")
 			}
-			c = "echo '" + coreDNSConfigmap + "' > " + coreDNSTemplate
-			_, err = commons.ExecuteCommand(n, c, 3, 5)
+			err = commons.WriteFile(n, coreDNSTemplate, coreDNSConfigmap)
 			if err != nil {
 				return errors.Wrap(err, "failed to create CoreDNS configmap file")
 			}
-			c = "kubectl --kubeconfig " + kubeconfigPath + " apply -f " + coreDNSTemplate
+			c = "kubectl --kubeconfig " + kubeconfigPath + " apply -f " + commons.ShellQuote(coreDNSTemplate)
 			_, err = commons.ExecuteCommand(n, c, 3, 5)
 			if err != nil {
 				return errors.Wrap(err, "failed to apply CoreDNS configmap")
 			}
 
-			c := "echo '" + gcpCoreDNSTemplate + "' > " + GKECoreDNSDeploymentPath
-			_, err = commons.ExecuteCommand(n, c, 3, 5)
+			err = commons.WriteFile(n, GKECoreDNSDeploymentPath, gcpCoreDNSTemplate)
 			if err != nil {
 				return errors.Wrap(err, "failed to create CoreDNS deployment and RBAC file")
 			}
@@ -681,15 +988,18 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 		}
 		ctx.Status.End(true) // End Installing Flux in workload cluster
 
-		ctx.Status.Start("Enabling workload cluster's self-healing 🏥")
-		defer ctx.Status.End(false)
+		// The single node of the dev profile cannot be remediated
+		if a.keosCluster.Spec.Profile != commons.DevProfile {
+			ctx.Status.Start("Enabling workload cluster's self-healing 🏥")
+			defer ctx.Status.End(false)
 
-		err = enableSelfHealing(n, a.keosCluster, capiClustersNamespace, a.clusterConfig)
-		if err != nil {
-			return errors.Wrap(err, "failed to enable workload cluster's self-healing")
-		}
+			err = enableSelfHealing(n, a.keosCluster, capiClustersNamespace, a.clusterConfig)
+			if err != nil {
+				return errors.Wrap(err, "failed to enable workload cluster's self-healing")
+			}
 
-		ctx.Status.End(true) // End Enabling workload cluster's self-healing
+			ctx.Status.End(true) // End Enabling workload cluster's self-healing
+		}
 
 		//// <<<<<<< HEAD
 		ctx.Status.Start("Configuring Network Policy Engine in workload cluster 🚧")
@@ -697,14 +1007,14 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 
 		if !a.keosCluster.Spec.ControlPlane.Managed || a.keosCluster.Spec.InfraProvider == "aws" {
 			// Allow egress in tigera-operator namespace
-			c = "kubectl --kubeconfig " + kubeconfigPath + " -n tigera-operator apply -f " + allowCommonEgressNetPolPath
+			c = "kubectl --kubeconfig " + kubeconfigPath + " -n tigera-operator apply -f " + commons.ShellQuote(allowCommonEgressNetPolPath)
 			_, err = commons.ExecuteCommand(n, c, 5, 3)
 			if err != nil {
 				return errors.Wrap(err, "failed to apply tigera-operator egress NetworkPolicy")
 			}
 
 			// Allow egress in calico-system namespace
-			c = "kubectl --kubeconfig " + kubeconfigPath + " -n calico-system apply -f " + allowCommonEgressNetPolPath
+			c = "kubectl --kubeconfig " + kubeconfigPath + " -n calico-system apply -f " + commons.ShellQuote(allowCommonEgressNetPolPath)
 			_, err = commons.ExecuteCommand(n, c, 5, 3)
 			if err != nil {
 				return errors.Wrap(err, "failed to apply calico-system egress NetworkPolicy")
@@ -712,7 +1022,7 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 		}
 
 		// Allow egress in CAPX's Namespace
-		c = "kubectl --kubeconfig " + kubeconfigPath + " -n " + provider.capxName + "-system apply -f " + allowCommonEgressNetPolPath
+		c = "kubectl --kubeconfig " + kubeconfigPath + " -n " + commons.ShellQuote(provider.capxName) + "-system apply -f " + commons.ShellQuote(allowCommonEgressNetPolPath)
 		_, err = commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
 			return errors.Wrap(err, "failed to apply CAPX's NetworkPolicy in workload cluster")
@@ -731,7 +1041,7 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 		// Allow egress in CAPI's Namespaces
 		for _, deployment := range capiDeployments {
 			if !provider.capxManaged || (provider.capxManaged && !allowedNamePattern.MatchString(deployment.name)) {
-				c = "kubectl --kubeconfig " + kubeconfigPath + " -n " + deployment.namespace + " apply -f " + allowCommonEgressNetPolPath
+				c = "kubectl --kubeconfig " + kubeconfigPath + " -n " + commons.ShellQuote(deployment.namespace) + " apply -f " + commons.ShellQuote(allowCommonEgressNetPolPath)
 				_, err = commons.ExecuteCommand(n, c, 5, 3)
 				if err != nil {
 					return errors.Wrap(err, "failed to apply CAPI's egress NetworkPolicy in namespace "+deployment.namespace)
@@ -740,7 +1050,7 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 		}
 
 		// Allow egress in cert-manager Namespace
-		c = "kubectl --kubeconfig " + kubeconfigPath + " -n cert-manager apply -f " + allowCommonEgressNetPolPath
+		c = "kubectl --kubeconfig " + kubeconfigPath + " -n cert-manager apply -f " + commons.ShellQuote(allowCommonEgressNetPolPath)
 		_, err = commons.ExecuteCommand(n, c, 5, 3)
 
 		if err != nil {
@@ -753,7 +1063,7 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 		allowCAPXEgressIMDSGNetPolPath := "/kind/allow-egress-imds_gnetpol.yaml"
 
 		// Allow egress in kube-system Namespace
-		c = "kubectl --kubeconfig " + kubeconfigPath + " -n kube-system apply -f " + allowCommonEgressNetPolPath
+		c = "kubectl --kubeconfig " + kubeconfigPath + " -n kube-system apply -f " + commons.ShellQuote(allowCommonEgressNetPolPath)
 		_, err = commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
 			return errors.Wrap(err, "failed to apply kube-system egress NetworkPolicy")
@@ -763,8 +1073,7 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 			return err
 		}
 
-		c = "echo \"" + denyEgressIMDSGNetPol + "\" > " + denyallEgressIMDSGNetPolPath
-		_, err = commons.ExecuteCommand(n, c, 5, 3)
+		err = commons.WriteFile(n, denyallEgressIMDSGNetPolPath, denyEgressIMDSGNetPol)
 		if err != nil {
 			return errors.Wrap(err, "failed to write the deny-all-traffic-to-aws-imds global network policy")
 		}
@@ -773,21 +1082,20 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 			return err
 		}
 
-		c = "echo \"" + allowEgressIMDSGNetPol + "\" > " + allowCAPXEgressIMDSGNetPolPath
-		_, err = commons.ExecuteCommand(n, c, 5, 3)
+		err = commons.WriteFile(n, allowCAPXEgressIMDSGNetPolPath, allowEgressIMDSGNetPol)
 		if err != nil {
 			return errors.Wrap(err, "failed to write the allow-traffic-to-aws-imds-capa global network policy")
 		}
 
 		// Deny CAPA egress to AWS IMDS
-		c = "kubectl --kubeconfig " + kubeconfigPath + " apply -f " + denyallEgressIMDSGNetPolPath
+		c = "kubectl --kubeconfig " + kubeconfigPath + " apply -f " + commons.ShellQuote(denyallEgressIMDSGNetPolPath)
 		_, err = commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
 			return errors.Wrap(err, "failed to apply deny IMDS traffic GlobalNetworkPolicy")
 		}
 
 		// Allow CAPA egress to AWS IMDS
-		c = "kubectl --kubeconfig " + kubeconfigPath + " apply -f " + allowCAPXEgressIMDSGNetPolPath
+		c = "kubectl --kubeconfig " + kubeconfigPath + " apply -f " + commons.ShellQuote(allowCAPXEgressIMDSGNetPolPath)
 		_, err = commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
 			return errors.Wrap(err, "failed to apply allow CAPX as egress GlobalNetworkPolicy")
@@ -818,7 +1126,96 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 		}
 		ctx.Status.End(true) // End Installing StorageClass in workload cluster
 
-		if a.keosCluster.Spec.DeployAutoscaler && !isMachinePool {
+		if awsEKSEnabled && a.keosCluster.Spec.ControlPlane.AWS.RemoveGP2StorageClass {
+			ctx.Status.Start("Removing the gp2 StorageClass from workload cluster 🗑️")
+			defer ctx.Status.End(false)
+
+			c = "kubectl --kubeconfig " + kubeconfigPath + " delete sc " + eksDefaultStorageClass + " --ignore-not-found"
+			_, err = commons.ExecuteCommand(n, c, 5, 3)
+			if err != nil {
+				return errors.Wrap(err, "failed to remove the gp2 StorageClass from workload cluster")
+			}
+			ctx.Status.End(true) // End Removing the gp2 StorageClass from workload cluster
+		}
+
+		if a.clusterConfig.Spec.RegistryCache != nil {
+			ctx.Status.Start("Installing the registry cache in workload cluster 🗃️")
+			defer ctx.Status.End(false)
+
+			err = deployRegistryCache(n, kubeconfigPath, privateParams, keosRegistry, *a.clusterConfig.Spec.RegistryCache, chartsList)
+			if err != nil {
+				return errors.Wrap(err, "failed to install the registry cache in workload cluster")
+			}
+			ctx.Status.End(true) // End Installing the registry cache in workload cluster
+		}
+
+		if a.clusterConfig.Spec.PolicyEngine != nil {
+			ctx.Status.Start("Installing " + a.clusterConfig.Spec.PolicyEngine.Engine + " policies in workload cluster 👮")
+			defer ctx.Status.End(false)
+
+			err = deployPolicyEngine(n, kubeconfigPath, privateParams, *a.clusterConfig.Spec.PolicyEngine, chartsList)
+			if err != nil {
+				return errors.Wrap(err, "failed to install the policy engine in workload cluster")
+			}
+			ctx.Status.End(true) // End Installing policies in workload cluster
+		}
+
+		if a.clusterConfig.Spec.ExternalSecrets != nil {
+			ctx.Status.Start("Installing external-secrets in workload cluster 🔑")
+			defer ctx.Status.End(false)
+
+			err = deployExternalSecrets(n, kubeconfigPath, privateParams, providerParams, *a.clusterConfig.Spec.ExternalSecrets, chartsList)
+			if err != nil {
+				return errors.Wrap(err, "failed to install external-secrets in workload cluster")
+			}
+			ctx.Status.End(true) // End Installing external-secrets in workload cluster
+		}
+
+		if a.clusterConfig.Spec.CostAllocation != nil {
+			ctx.Status.Start("Installing OpenCost in workload cluster 💰")
+			defer ctx.Status.End(false)
+
+			err = deployOpenCost(n, kubeconfigPath, privateParams, providerParams, *a.clusterConfig.Spec.CostAllocation, chartsList)
+			if err != nil {
+				return errors.Wrap(err, "failed to install OpenCost in workload cluster")
+			}
+			ctx.Status.End(true) // End Installing OpenCost in workload cluster
+		}
+
+		if hasGPUNodes(a.keosCluster.Spec) {
+			ctx.Status.Start("Installing NVIDIA GPU Operator in workload cluster 🎮")
+			defer ctx.Status.End(false)
+
+			err = deployGPUOperator(n, kubeconfigPath, privateParams, a.keosCluster.Spec, chartsList)
+			if err != nil {
+				return errors.Wrap(err, "failed to install NVIDIA GPU Operator in workload cluster")
+			}
+			ctx.Status.End(true) // End Installing NVIDIA GPU Operator in workload cluster
+		}
+
+		if a.clusterConfig.Spec.SpotTermination != nil {
+			ctx.Status.Start("Installing spot termination handler in workload cluster 🪂")
+			defer ctx.Status.End(false)
+
+			err = deploySpotTermination(n, kubeconfigPath, privateParams, *a.clusterConfig.Spec.SpotTermination, chartsList)
+			if err != nil {
+				return errors.Wrap(err, "failed to install spot termination handler in workload cluster")
+			}
+			ctx.Status.End(true) // End Installing spot termination handler in workload cluster
+		}
+
+		if a.clusterConfig.Spec.Interconnect != nil {
+			ctx.Status.Start("Joining workload cluster to Submariner broker 🌉")
+			defer ctx.Status.End(false)
+
+			err = deployInterconnect(n, kubeconfigPath, privateParams, *a.clusterConfig.Spec.Interconnect, chartsList)
+			if err != nil {
+				return errors.Wrap(err, "failed to join workload cluster to Submariner broker")
+			}
+			ctx.Status.End(true) // End Joining workload cluster to Submariner broker
+		}
+
+		if deploysAutoscaler(a.keosCluster.Spec) {
 			ctx.Status.Start("Installing cluster-autoscaler in workload cluster 🗚")
 			defer ctx.Status.End(false)
 
@@ -841,7 +1238,7 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 		ctx.Status.End(true) // Installing keos cluster operator in workload cluster
 
 		// Apply custom CoreDNS configuration
-		if len(a.keosCluster.Spec.Dns.Forwarders) > 0 && (!awsEKSEnabled || !gcpGKEEnabled) {
+		if customizesCoreDNS(a.keosCluster.Spec) {
 			ctx.Status.Start("Customizing CoreDNS configuration 🪡")
 			defer ctx.Status.End(false)
 
@@ -853,6 +1250,18 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 			ctx.Status.End(true) // End Customizing CoreDNS configuration
 		}
 
+		if a.clusterConfig.Spec.NodeLocalDNS != nil {
+			ctx.Status.Start("Installing NodeLocal DNSCache in workload cluster 🗃️")
+			defer ctx.Status.End(false)
+
+			err = deployNodeLocalDNS(n, kubeconfigPath, privateParams, *a.clusterConfig.Spec.NodeLocalDNS)
+			if err != nil {
+				return errors.Wrap(err, "failed to install NodeLocal DNSCache in workload cluster")
+			}
+
+			ctx.Status.End(true) // End Installing NodeLocal DNSCache in workload cluster
+		}
+
 		if provider.capxProvider == "gcp" {
 			// XXX Ref kubernetes/kubernetes#86793 Starting from v1.18, gcp cloud-controller-manager requires RBAC to patch,update service/status (in-tree)
 			ctx.Status.Start("Creating Kubernetes RBAC for internal loadbalancing 🔐")
@@ -867,13 +1276,12 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 				rbacInternalLoadBalancingPath := "/kind/internalloadbalancing_rbac.yaml"
 
 				// Deploy Kubernetes RBAC internal loadbalancing
-				c = "echo \"" + rbacInternalLoadBalancing + "\" > " + rbacInternalLoadBalancingPath
-				_, err = commons.ExecuteCommand(n, c, 5, 3)
+				err = commons.WriteFile(n, rbacInternalLoadBalancingPath, rbacInternalLoadBalancing)
 				if err != nil {
 					return errors.Wrap(err, "failed to write the kubernetes RBAC internal loadbalancing")
 				}
 
-				c = "kubectl --kubeconfig " + kubeconfigPath + " apply -f " + rbacInternalLoadBalancingPath
+				c = "kubectl --kubeconfig " + kubeconfigPath + " apply -f " + commons.ShellQuote(rbacInternalLoadBalancingPath)
 				_, err = commons.ExecuteCommand(n, c, 5, 3)
 				if err != nil {
 					return errors.Wrap(err, "failed to the kubernetes RBAC internal loadbalancing")
@@ -893,12 +1301,69 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 			ctx.Status.End(true) // End Installing AWS LB controller in workload cluster
 		}
 
+		if hasInfraNodes(a.keosCluster.Spec.WorkerNodes) {
+			ctx.Status.Start("Pinning system components to infra nodes 📌")
+			defer ctx.Status.End(false)
+
+			err = pinSystemComponents(n, kubeconfigPath, a.keosCluster, chartsList)
+			if err != nil {
+				return errors.Wrap(err, "failed to pin system components to infra nodes")
+			}
+			ctx.Status.End(true) // End Pinning system components to infra nodes
+		}
+
+		if a.clusterConfig.Spec.AddonsPDB != nil {
+			ctx.Status.Start("Creating PodDisruptionBudgets for the addons 🛟")
+			defer ctx.Status.End(false)
+
+			err = createAddonPDBs(n, kubeconfigPath, *a.clusterConfig.Spec.AddonsPDB, chartsList)
+			if err != nil {
+				return errors.Wrap(err, "failed to create PodDisruptionBudgets for the addons")
+			}
+			ctx.Status.End(true) // End Creating PodDisruptionBudgets for the addons
+		}
+
+		ctx.Status.Start("Generating the image inventory 📋")
+		defer ctx.Status.End(false)
+
+		err = createImageInventory(n, a.keosCluster, kubeconfigPath)
+		if err != nil {
+			return errors.Wrap(err, "failed to generate the image inventory")
+		}
+
+		ctx.Status.End(true) // End Generating the image inventory
+
+		if issuer := a.keosCluster.Spec.ControlPlane.ServiceAccountIssuer; issuer != nil {
+			ctx.Status.Start("Exporting the service account issuer discovery documents 🔑")
+			defer ctx.Status.End(false)
+
+			err = exportServiceAccountIssuerDiscovery(n, a.keosCluster, *issuer)
+			if err != nil {
+				return errors.Wrap(err, "failed to export the service account issuer discovery documents")
+			}
+
+			ctx.Status.End(true) // End Exporting the service account issuer discovery documents
+		}
+
+		if a.clusterConfig.Spec.ManifestsExport != nil {
+			ctx.Status.Start("Exporting the Cluster API manifests 📤")
+			defer ctx.Status.End(false)
+
+			err = exportCAPIManifests(n, a.keosCluster, capiClustersNamespace, *a.clusterConfig.Spec.ManifestsExport)
+			if err != nil {
+				return errors.Wrap(err, "failed to export the Cluster API manifests")
+			}
+
+			ctx.Status.End(true) // End Exporting the Cluster API manifests
+		}
+
 		// Create cloud-provisioner Objects backup
 		ctx.Status.Start("Creating cloud-provisioner Objects backup 🗄️")
 		defer ctx.Status.End(false)
 
-		if _, err := os.Stat(localBackupPath); os.IsNotExist(err) {
-			if err := os.MkdirAll(localBackupPath, 0755); err != nil {
+		clusterBackupPath := getLocalPath(a.keosCluster, localBackupPath)
+		if _, err := os.Stat(clusterBackupPath); os.IsNotExist(err) {
+			if err := os.MkdirAll(clusterBackupPath, 0755); err != nil {
 				return errors.Wrap(err, "failed to create local backup directory")
 			}
 		}
@@ -909,7 +1374,7 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 			return errors.Wrap(err, "failed to create cloud-provisioner backup directory")
 		}
 
-		c = "clusterctl move -n " + capiClustersNamespace + " --to-directory " + cloudProviderBackupPath
+		c = "clusterctl move -n " + commons.ShellQuote(capiClustersNamespace) + " --to-directory " + cloudProviderBackupPath
 		_, err = commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
 			return errors.Wrap(err, "failed to backup cloud-provisioner Objects")
@@ -917,7 +1382,7 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 
 		for _, path := range PathsToBackupLocally {
 			raw := bytes.Buffer{}
-			cmd := exec.CommandContext(context.Background(), "sh", "-c", "docker cp "+n.String()+":"+path+" "+localBackupPath)
+			cmd := exec.CommandContext(context.Background(), "sh", "-c", "docker cp "+commons.ShellQuote(n.String())+":"+commons.ShellQuote(path)+" "+commons.ShellQuote(clusterBackupPath))
 			if err := cmd.SetStdout(&raw).Run(); err != nil {
 				return errors.Wrap(err, "failed to copy "+path+" to local host")
 			}
@@ -936,10 +1401,10 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 			}
 
 			// Create namespace, if not exists, for CAPI clusters in worker cluster
-			c = "kubectl --kubeconfig " + kubeconfigPath + " get ns " + capiClustersNamespace
+			c = "kubectl --kubeconfig " + kubeconfigPath + " get ns " + commons.ShellQuote(capiClustersNamespace)
 			_, err = commons.ExecuteCommand(n, c, 5, 3)
 			if err != nil {
-				c = "kubectl --kubeconfig " + kubeconfigPath + " create ns " + capiClustersNamespace
+				c = "kubectl --kubeconfig " + kubeconfigPath + " create ns " + commons.ShellQuote(capiClustersNamespace)
 				_, err = commons.ExecuteCommand(n, c, 5, 3)
 				if err != nil {
 					return errors.Wrap(err, "failed to create manifests Namespace")
@@ -947,7 +1412,7 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 			}
 
 			// Pivot management role to worker cluster
-			c = "clusterctl move -n " + capiClustersNamespace + " --to-kubeconfig " + kubeconfigPath
+			c = "clusterctl move -n " + commons.ShellQuote(capiClustersNamespace) + " --to-kubeconfig " + kubeconfigPath
 			_, err = commons.ExecuteCommand(n, c, 5, 3)
 			if err != nil {
 				return errors.Wrap(err, "failed to pivot management role to worker cluster")
@@ -962,21 +1427,21 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 
 			if a.clusterConfig != nil {
 
-				c = "kubectl -n " + capiClustersNamespace + " patch clusterconfig " + a.clusterConfig.Metadata.Name + " -p '{\"metadata\":{\"ownerReferences\":null,\"finalizers\":null}}' --type=merge"
+				c = "kubectl -n " + commons.ShellQuote(capiClustersNamespace) + " patch clusterconfig " + commons.ShellQuote(a.clusterConfig.Metadata.Name) + " -p '{\"metadata\":{\"ownerReferences\":null,\"finalizers\":null}}' --type=merge"
 				_, err = commons.ExecuteCommand(n, c, 5, 3)
 				if err != nil {
 					return errors.Wrap(err, "failed to remove clusterconfig ownerReferences and finalizers")
 				}
 
 				// Move clusterConfig to workload cluster
-				c = "kubectl -n " + capiClustersNamespace + " get clusterconfig " + a.clusterConfig.Metadata.Name + " -o json | kubectl apply --kubeconfig " + kubeconfigPath + " -f-"
+				c = "kubectl -n " + commons.ShellQuote(capiClustersNamespace) + " get clusterconfig " + commons.ShellQuote(a.clusterConfig.Metadata.Name) + " -o json | kubectl apply --kubeconfig " + kubeconfigPath + " -f-"
 				_, err = commons.ExecuteCommand(n, c, 5, 3)
 				if err != nil {
 					return errors.Wrap(err, "failed to move clusterconfig to workload cluster")
 				}
 
 				// Delete clusterconfig in management cluster
-				c = "kubectl -n " + capiClustersNamespace + " delete clusterconfig " + a.clusterConfig.Metadata.Name
+				c = "kubectl -n " + commons.ShellQuote(capiClustersNamespace) + " delete clusterconfig " + commons.ShellQuote(a.clusterConfig.Metadata.Name)
 				_, err = commons.ExecuteCommand(n, c, 5, 3)
 				if err != nil {
 					return errors.Wrap(err, "failed to delete clusterconfig in management cluster")
@@ -985,20 +1450,20 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 			}
 
 			// Move keoscluster to workload cluster
-			c = "kubectl -n " + capiClustersNamespace + " get keoscluster " + a.keosCluster.Metadata.Name + " -o json | jq 'del(.status)' | kubectl apply --kubeconfig " + kubeconfigPath + " -f-"
+			c = "kubectl -n " + commons.ShellQuote(capiClustersNamespace) + " get keoscluster " + commons.ShellQuote(a.keosCluster.Metadata.Name) + " -o json | jq 'del(.status)' | kubectl apply --kubeconfig " + kubeconfigPath + " -f-"
 			_, err = commons.ExecuteCommand(n, c, 5, 3)
 			if err != nil {
 				return errors.Wrap(err, "failed to move keoscluster to workload cluster")
 			}
 
-			c = "kubectl -n " + capiClustersNamespace + " patch keoscluster " + a.keosCluster.Metadata.Name + " -p '{\"metadata\":{\"finalizers\":null}}' --type=merge"
+			c = "kubectl -n " + commons.ShellQuote(capiClustersNamespace) + " patch keoscluster " + commons.ShellQuote(a.keosCluster.Metadata.Name) + " -p '{\"metadata\":{\"finalizers\":null}}' --type=merge"
 			_, err = commons.ExecuteCommand(n, c, 5, 3)
 			if err != nil {
 				return errors.Wrap(err, "failed to scale keoscluster deployment to 1")
 			}
 
 			// Delete keoscluster in management cluster
-			c = "kubectl -n " + capiClustersNamespace + " delete keoscluster " + a.keosCluster.Metadata.Name
+			c = "kubectl -n " + commons.ShellQuote(capiClustersNamespace) + " delete keoscluster " + commons.ShellQuote(a.keosCluster.Metadata.Name)
 			_, err = commons.ExecuteCommand(n, c, 5, 3)
 			if err != nil {
 				return errors.Wrap(err, "failed to delete keoscluster in management cluster")
@@ -1012,6 +1477,62 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 			ctx.Status.End(true) // End Moving the cluster-operator
 		}
 
+		if a.clusterConfig.Spec.ManagementAccess != nil {
+			ctx.Status.Start("Granting access to the workload cluster in the management cluster 🔑")
+			defer ctx.Status.End(false)
+
+			// The Role is created in the cluster which manages the workload cluster
+			managementKubeconfig := kubeconfigPath
+			if a.moveManagement {
+				managementKubeconfig = ""
+			}
+			err = configureManagementAccess(n, managementKubeconfig, a.keosCluster, capiClustersNamespace, *a.clusterConfig.Spec.ManagementAccess)
+			if err != nil {
+				return errors.Wrap(err, "failed to grant access to the workload cluster in the management cluster")
+			}
+
+			ctx.Status.End(true) // End Granting access to the workload cluster in the management cluster
+		}
+
+		if len(a.clusterConfig.Spec.AccessKubeconfigs) > 0 {
+			ctx.Status.Start("Generating the " + strings.Join(a.clusterConfig.Spec.AccessKubeconfigs, " and ") + " kubeconfigs 🎫")
+			defer ctx.Status.End(false)
+
+			err = createAccessKubeconfigs(n, kubeconfigPath, getLocalPath(a.keosCluster, workKubeconfigPath), a.clusterConfig.Spec.AccessKubeconfigs)
+			if err != nil {
+				return errors.Wrap(err, "failed to generate the kubeconfigs of the workload cluster")
+			}
+
+			ctx.Status.End(true) // End Generating the kubeconfigs
+		}
+
+		if a.keosCluster.Spec.TTL != "" {
+			ctx.Status.Start("Scheduling the expiration of the workload cluster ⏳")
+			defer ctx.Status.End(false)
+
+			err = deployClusterTTL(n, privateParams, a.keosCluster, capiClustersNamespace)
+			if err != nil {
+				return errors.Wrap(err, "failed to schedule the expiration of the workload cluster")
+			}
+
+			ctx.Status.End(true) // End Scheduling the expiration of the workload cluster
+		}
+
+		if a.clusterConfig.Spec.OSPatching != nil {
+			ctx.Status.Start("Enrolling the nodes in the OS patching 🩹")
+			defer ctx.Status.End(false)
+
+			if a.keosCluster.Spec.InfraProvider == "aws" && !a.keosCluster.Spec.Security.AWS.CreateIAM {
+				ctx.Logger.Warn("The IAM role of the nodes must have the " + commons.AWSSSMNodePolicy + " policy to be patched")
+			}
+			err = enrollOSPatching(a.keosCluster.Spec.InfraProvider, providerParams, *a.clusterConfig.Spec.OSPatching)
+			if err != nil {
+				return errors.Wrap(err, "failed to enroll the nodes in the OS patching")
+			}
+
+			ctx.Status.End(true) // End Enrolling the nodes in the OS patching
+		}
+
 		ctx.Status.Start("Executing post-install steps 🎖️")
 		defer ctx.Status.End(false)
 
@@ -1026,17 +1547,50 @@ func (a *action) Execute(ctx *actions.ActionContext) error {
 	ctx.Status.Start("Generating the KEOS descriptor 📝")
 	defer ctx.Status.End(false)
 
-	err = createKEOSDescriptor(a.keosCluster, scName)
+	err = createKEOSDescriptor(a.keosCluster, getStorageClassName(a.keosCluster.Spec.StorageClass))
 	if err != nil {
 		return err
 	}
 
-	err = override_vars(ctx, providerParams, a.keosCluster.Spec.Networks, infra, a.clusterConfig.Spec)
+	err = override_vars(ctx, providerParams, a.keosCluster.Spec.Networks, infra, a.clusterConfig.Spec, getLocalPath(a.keosCluster, overrideVarsPath))
 	if err != nil {
 		return err
 	}
 
 	ctx.Status.End(true) // End Generating KEOS descriptor
 
+	if a.keosCluster.Spec.Keos.VerifyHandoff && !a.avoidCreation {
+		ctx.Status.Start("Verifying the keos prerequisites 🩺")
+		defer ctx.Status.End(false)
+
+		checks := verifyKeosHandoff(n, a.keosCluster, kubeconfigPath)
+
+		ctx.Status.End(true) // End Verifying the keos prerequisites
+
+		ready := true
+		for _, check := range checks {
+			if check.err != nil {
+				ready = false
+				ctx.Logger.V(0).Infof(" ✗ %s: %v", check.name, check.err)
+			} else {
+				ctx.Logger.V(0).Infof(" ✓ %s", check.name)
+			}
+		}
+		if ready {
+			ctx.Logger.V(0).Info("The cluster is ready for the keos installation: go")
+		} else {
+			ctx.Logger.Warn("The cluster is not ready for the keos installation: no-go")
+		}
+	}
+
 	return nil
 }
+
+// getLocalPath returns the local path of a cluster artifact, keeping the ones
+// of a DR cluster apart from the ones of the cluster it is paired with
+func getLocalPath(keosCluster commons.KeosCluster, path string) string {
+	if keosCluster.Spec.DR != nil && keosCluster.Spec.DR.Secondary {
+		return filepath.Join(keosCluster.Metadata.Name, path)
+	}
+	return path
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/exec"
+)
+
+type DigitalOceanBuilder struct {
+	capxProvider     string
+	capxVersion      string
+	capxImageVersion string
+	capxManaged      bool
+	capxName         string
+	capxEnvVars      []string
+	scParameters     commons.SCParameters
+	scProvisioner    string
+	csiNamespace     string
+	token            string
+}
+
+var digitaloceanCharts = ChartsDictionary{
+	Charts: map[string]map[string]map[string]commons.ChartEntry{
+		"28": {
+			"managed": {},
+			"unmanaged": {
+				"cluster-autoscaler": {Repository: "https://kubernetes.github.io/autoscaler", Version: "9.34.1", Namespace: "kube-system", Pull: false, Reconcile: false},
+				"tigera-operator":    {Repository: "https://docs.projectcalico.org/charts", Version: "v3.28.2", Namespace: "tigera-operator", Pull: true, Reconcile: true},
+			},
+		},
+		"29": {
+			"managed": {},
+			"unmanaged": {
+				"cluster-autoscaler": {Repository: "https://kubernetes.github.io/autoscaler", Version: "9.35.0", Namespace: "kube-system", Pull: false, Reconcile: false},
+				"tigera-operator":    {Repository: "https://docs.projectcalico.org/charts", Version: "v3.28.2", Namespace: "tigera-operator", Pull: true, Reconcile: true},
+			},
+		},
+		"30": {
+			"managed": {},
+			"unmanaged": {
+				"cluster-autoscaler": {Repository: "https://kubernetes.github.io/autoscaler", Version: "9.37.0", Namespace: "kube-system", Pull: false, Reconcile: false},
+				"tigera-operator":    {Repository: "https://docs.projectcalico.org/charts", Version: "v3.28.2", Namespace: "tigera-operator", Pull: true, Reconcile: true},
+			},
+		},
+	},
+}
+
+func newDigitalOceanBuilder() *DigitalOceanBuilder {
+	return &DigitalOceanBuilder{}
+}
+
+func (b *DigitalOceanBuilder) setCapx(managed bool) {
+	b.capxProvider = "digitalocean"
+	b.capxVersion = "v1.6.0"
+	b.capxImageVersion = "v1.6.0"
+	b.capxName = "capdo"
+	b.capxManaged = managed
+	b.csiNamespace = "kube-system"
+}
+
+func (b *DigitalOceanBuilder) setCapxEnvVars(p ProviderParams) {
+	b.token = p.Credentials["Token"]
+	b.capxEnvVars = []string{
+		"DIGITALOCEAN_ACCESS_TOKEN=" + b.token,
+		"DO_B64ENCODED_CREDENTIALS=" + base64.StdEncoding.EncodeToString([]byte(b.token)),
+	}
+	if p.GithubToken != "" {
+		b.capxEnvVars = append(b.capxEnvVars, "GITHUB_TOKEN="+p.GithubToken)
+	}
+}
+
+func (b *DigitalOceanBuilder) setSC(p ProviderParams) {
+	// DigitalOcean offers a single (SSD based) volume type, so both
+	// standard and premium classes are provisioned as DO volumes
+	b.scProvisioner = "dobs.csi.digitalocean.com"
+
+	if p.StorageClass.Parameters.FsType != "" {
+		b.scParameters.FsType = p.StorageClass.Parameters.FsType
+	}
+}
+
+func (b *DigitalOceanBuilder) pullProviderCharts(n nodes.Node, clusterConfigSpec *commons.ClusterConfigSpec, keosSpec commons.KeosSpec, clusterCredentials commons.ClusterCredentials, clusterType string) error {
+	return pullGenericCharts(n, clusterConfigSpec, keosSpec, clusterCredentials, digitaloceanCharts, clusterType)
+}
+
+func (b *DigitalOceanBuilder) getProviderCharts(clusterConfigSpec *commons.ClusterConfigSpec, keosSpec commons.KeosSpec, clusterType string) map[string]commons.ChartEntry {
+	return getGenericCharts(clusterConfigSpec, keosSpec, digitaloceanCharts, clusterType)
+}
+
+func (b *DigitalOceanBuilder) getOverriddenCharts(charts *[]commons.Chart, clusterConfigSpec *commons.ClusterConfigSpec, clusterType string) []commons.Chart {
+	return getGenericOverriddenCharts(charts, clusterConfigSpec, digitaloceanCharts, clusterType)
+}
+
+func (b *DigitalOceanBuilder) getProvider() Provider {
+	return Provider{
+		capxProvider:     b.capxProvider,
+		capxVersion:      b.capxVersion,
+		capxImageVersion: b.capxImageVersion,
+		capxManaged:      b.capxManaged,
+		capxName:         b.capxName,
+		capxEnvVars:      b.capxEnvVars,
+		scParameters:     b.scParameters,
+		scProvisioner:    b.scProvisioner,
+		csiNamespace:     b.csiNamespace,
+	}
+}
+
+func (b *DigitalOceanBuilder) installCloudProvider(n nodes.Node, k string, privateParams PrivateParams) error {
+	var c string
+	var err error
+	var cmd exec.Cmd
+
+	// Create the digitalocean secret, shared by the CCM and the CSI driver
+	c = "kubectl --kubeconfig " + commons.ShellQuote(k) + " -n kube-system create secret generic digitalocean" +
+		" " + commons.ShellQuote("--from-literal=access-token="+b.token)
+	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to create digitalocean secret")
+	}
+
+	cloudProviderManifests, err := b.getCloudProviderTemplate(privateParams).render()
+	if err != nil {
+		return errors.Wrap(err, "failed to get cloud provider manifests")
+	}
+
+	// Deploy cloud provider
+	cmd = n.Command("kubectl", "--kubeconfig", k, "apply", "-f", "-")
+	if err = cmd.SetStdin(strings.NewReader(cloudProviderManifests)).Run(); err != nil {
+		return errors.Wrap(err, "failed to deploy digitalocean-cloud-controller-manager")
+	}
+
+	return nil
+}
+
+func (b *DigitalOceanBuilder) getCloudProviderTemplate(privateParams PrivateParams) manifestTemplate {
+	return manifestTemplate{b.capxProvider, "digitalocean-cloud-controller-manager.tmpl", "", privateParams}
+}
+
+func (b *DigitalOceanBuilder) installCSI(n nodes.Node, k string, privateParams PrivateParams, providerParams ProviderParams, chartsList map[string]commons.ChartEntry) error {
+	var err error
+	var cmd exec.Cmd
+
+	csiManifests, err := b.getCSITemplates(privateParams)[0].render()
+	if err != nil {
+		return errors.Wrap(err, "failed to get csi-digitalocean manifests")
+	}
+
+	// Deploy csi-digitalocean
+	cmd = n.Command("kubectl", "--kubeconfig", k, "apply", "-f", "-")
+	if err = cmd.SetStdin(strings.NewReader(csiManifests)).Run(); err != nil {
+		return errors.Wrap(err, "failed to deploy csi-digitalocean")
+	}
+
+	return nil
+}
+
+func (b *DigitalOceanBuilder) getCSITemplates(privateParams PrivateParams) []manifestTemplate {
+	return []manifestTemplate{{b.capxProvider, "csi-digitalocean.tmpl", "", privateParams}}
+}
+
+func (b *DigitalOceanBuilder) getRegistryCredentials(p ProviderParams, u string) (string, string, error) {
+	return "", "", errors.New("only generic registries are supported in digitalocean clusters")
+}
+
+func (b *DigitalOceanBuilder) configureStorageClass(n nodes.Node, k string) error {
+	return applyGenericStorageClass(n, k, getGenericStorageClass(b.scProvisioner, b.scParameters), nil)
+}
+
+func (b *DigitalOceanBuilder) internalNginx(p ProviderParams, networks commons.Networks) (bool, error) {
+	return false, nil
+}
+
+func (b *DigitalOceanBuilder) getOverrideVars(p ProviderParams, networks commons.Networks, clusterConfigSpec commons.ClusterConfigSpec) (map[string][]byte, error) {
+	var overrideVars = make(map[string][]byte)
+
+	return overrideVars, nil
+}
+
+func (b *DigitalOceanBuilder) postInstallPhase(n nodes.Node, k string) error {
+	return ensureCorednsPdb(n)
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"google.golang.org/api/dns/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const apiServerRecordTTL = 300
+
+// createAPIServerDNSRecord points the custom DNS name of the API server to the
+// control plane endpoint, in the DNS service of the provider
+func createAPIServerDNSRecord(infraProvider string, p ProviderParams, apiServer commons.APIServer, endpoint string) error {
+	recordType := "CNAME"
+	if net.ParseIP(endpoint) != nil {
+		recordType = "A"
+	}
+
+	switch infraProvider {
+	case "aws":
+		return createRoute53Record(p, apiServer, recordType, endpoint)
+	case "azure":
+		return createAzureDNSRecord(p, apiServer, recordType, endpoint)
+	case "gcp":
+		return createCloudDNSRecord(p, apiServer, recordType, endpoint)
+	}
+	return errors.New("DNS records are not supported in " + infraProvider + " clusters")
+}
+
+func createRoute53Record(p ProviderParams, apiServer commons.APIServer, recordType string, endpoint string) error {
+	var ctx = context.Background()
+
+	client, err := commons.NewAWSClient(ctx, p.Credentials, p.Region, "")
+	if err != nil {
+		return err
+	}
+
+	body := `<?xml version="1.0" encoding="UTF-8"?>
+<ChangeResourceRecordSetsRequest xmlns="https://route53.amazonaws.com/doc/2013-04-01/">
+  <ChangeBatch>
+    <Changes>
+      <Change>
+        <Action>UPSERT</Action>
+        <ResourceRecordSet>
+          <Name>` + apiServer.DNSName + `</Name>
+          <Type>` + recordType + `</Type>
+          <TTL>` + strconv.Itoa(apiServerRecordTTL) + `</TTL>
+          <ResourceRecords>
+            <ResourceRecord>
+              <Value>` + endpoint + `</Value>
+            </ResourceRecord>
+          </ResourceRecords>
+        </ResourceRecordSet>
+      </Change>
+    </Changes>
+  </ChangeBatch>
+</ChangeResourceRecordSetsRequest>`
+
+	hostedZone := strings.TrimPrefix(apiServer.HostedZone, "/hostedzone/")
+	req, err := http.NewRequest(http.MethodPost, client.Endpoint("route53")+"/2013-04-01/hostedzone/"+hostedZone+"/rrset/", strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/xml")
+	if _, err = client.Do(ctx, "route53", req); err != nil {
+		return errors.Wrap(err, "failed to create the Route53 record")
+	}
+	return nil
+}
+
+func createAzureDNSRecord(p ProviderParams, apiServer commons.APIServer, recordType string, endpoint string) error {
+	var ctx = context.Background()
+
+	cfg, err := commons.AzureGetConfig(p.Credentials)
+	if err != nil {
+		return err
+	}
+	token, err := cfg.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"https://management.azure.com/.default"}})
+	if err != nil {
+		return errors.Wrap(err, "failed to get Azure token")
+	}
+
+	properties := map[string]interface{}{"TTL": apiServerRecordTTL}
+	if recordType == "A" {
+		properties["ARecords"] = []map[string]string{{"ipv4Address": endpoint}}
+	} else {
+		properties["CNAMERecord"] = map[string]string{"cname": endpoint}
+	}
+	body, err := json.Marshal(map[string]interface{}{"properties": properties})
+	if err != nil {
+		return err
+	}
+
+	recordName := strings.TrimSuffix(strings.TrimSuffix(apiServer.DNSName, "."), "."+strings.TrimSuffix(apiServer.HostedZone, "."))
+	url := "https://management.azure.com/subscriptions/" + p.Credentials["SubscriptionID"] +
+		"/resourceGroups/" + apiServer.ResourceGroup +
+		"/providers/Microsoft.Network/dnsZones/" + apiServer.HostedZone +
+		"/" + recordType + "/" + recordName + "?api-version=2018-05-01"
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+
+	return doDNSRequest(req, "Azure DNS")
+}
+
+func createCloudDNSRecord(p ProviderParams, apiServer commons.APIServer, recordType string, endpoint string) error {
+	var ctx = context.Background()
+
+	dnsService, err := dns.NewService(ctx, option.WithCredentialsJSON(getGCPCredentials(p)))
+	if err != nil {
+		return err
+	}
+
+	if recordType == "CNAME" {
+		endpoint = strings.TrimSuffix(endpoint, ".") + "."
+	}
+	recordSet := &dns.ResourceRecordSet{
+		Name:    strings.TrimSuffix(apiServer.DNSName, ".") + ".",
+		Type:    recordType,
+		Ttl:     apiServerRecordTTL,
+		Rrdatas: []string{endpoint},
+	}
+
+	projectID := p.Credentials["ProjectID"]
+	_, err = dnsService.ResourceRecordSets.Create(projectID, apiServer.HostedZone, recordSet).Do()
+	if gErr, ok := err.(*googleapi.Error); ok && gErr.Code == http.StatusConflict {
+		_, err = dnsService.ResourceRecordSets.Patch(projectID, apiServer.HostedZone, recordSet.Name, recordType, recordSet).Do()
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to create the Cloud DNS record")
+	}
+	return nil
+}
+
+func doDNSRequest(req *http.Request, service string) error {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to "+service)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New(service + " request failed: " + resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"encoding/json"
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/exec"
+)
+
+type EquinixBuilder struct {
+	capxProvider     string
+	capxVersion      string
+	capxImageVersion string
+	capxManaged      bool
+	capxName         string
+	capxEnvVars      []string
+	scParameters     commons.SCParameters
+	scProvisioner    string
+	csiNamespace     string
+	apiKey           string
+	projectID        string
+	metro            string
+}
+
+var equinixCharts = ChartsDictionary{
+	Charts: map[string]map[string]map[string]commons.ChartEntry{
+		"28": {
+			"managed": {},
+			"unmanaged": {
+				"cluster-autoscaler": {Repository: "https://kubernetes.github.io/autoscaler", Version: "9.34.1", Namespace: "kube-system", Pull: false, Reconcile: false},
+				"tigera-operator":    {Repository: "https://docs.projectcalico.org/charts", Version: "v3.28.2", Namespace: "tigera-operator", Pull: true, Reconcile: true},
+			},
+		},
+		"29": {
+			"managed": {},
+			"unmanaged": {
+				"cluster-autoscaler": {Repository: "https://kubernetes.github.io/autoscaler", Version: "9.35.0", Namespace: "kube-system", Pull: false, Reconcile: false},
+				"tigera-operator":    {Repository: "https://docs.projectcalico.org/charts", Version: "v3.28.2", Namespace: "tigera-operator", Pull: true, Reconcile: true},
+			},
+		},
+		"30": {
+			"managed": {},
+			"unmanaged": {
+				"cluster-autoscaler": {Repository: "https://kubernetes.github.io/autoscaler", Version: "9.37.0", Namespace: "kube-system", Pull: false, Reconcile: false},
+				"tigera-operator":    {Repository: "https://docs.projectcalico.org/charts", Version: "v3.28.2", Namespace: "tigera-operator", Pull: true, Reconcile: true},
+			},
+		},
+	},
+}
+
+func newEquinixBuilder() *EquinixBuilder {
+	return &EquinixBuilder{}
+}
+
+func (b *EquinixBuilder) setCapx(managed bool) {
+	b.capxProvider = "packet"
+	b.capxVersion = "v0.9.0"
+	b.capxImageVersion = "v0.9.0"
+	b.capxName = "capp"
+	b.capxManaged = managed
+	b.csiNamespace = "local-path-storage"
+}
+
+func (b *EquinixBuilder) setCapxEnvVars(p ProviderParams) {
+	b.apiKey = p.Credentials["ApiKey"]
+	b.projectID = p.Credentials["ProjectID"]
+	// The servers are placed by metro, as the facilities are deprecated
+	b.metro = p.Region
+	b.capxEnvVars = []string{
+		"PACKET_API_KEY=" + b.apiKey,
+		"PROJECT_ID=" + b.projectID,
+		"METRO=" + b.metro,
+	}
+	if p.GithubToken != "" {
+		b.capxEnvVars = append(b.capxEnvVars, "GITHUB_TOKEN="+p.GithubToken)
+	}
+}
+
+func (b *EquinixBuilder) setSC(p ProviderParams) {
+	// Equinix Metal does not provide block storage, so volumes are
+	// provisioned on the local disks of the servers, as directories
+	// which take no parameters
+	b.scProvisioner = "rancher.io/local-path"
+}
+
+func (b *EquinixBuilder) pullProviderCharts(n nodes.Node, clusterConfigSpec *commons.ClusterConfigSpec, keosSpec commons.KeosSpec, clusterCredentials commons.ClusterCredentials, clusterType string) error {
+	return pullGenericCharts(n, clusterConfigSpec, keosSpec, clusterCredentials, equinixCharts, clusterType)
+}
+
+func (b *EquinixBuilder) getProviderCharts(clusterConfigSpec *commons.ClusterConfigSpec, keosSpec commons.KeosSpec, clusterType string) map[string]commons.ChartEntry {
+	return getGenericCharts(clusterConfigSpec, keosSpec, equinixCharts, clusterType)
+}
+
+func (b *EquinixBuilder) getOverriddenCharts(charts *[]commons.Chart, clusterConfigSpec *commons.ClusterConfigSpec, clusterType string) []commons.Chart {
+	return getGenericOverriddenCharts(charts, clusterConfigSpec, equinixCharts, clusterType)
+}
+
+func (b *EquinixBuilder) getProvider() Provider {
+	return Provider{
+		capxProvider:     b.capxProvider,
+		capxVersion:      b.capxVersion,
+		capxImageVersion: b.capxImageVersion,
+		capxManaged:      b.capxManaged,
+		capxName:         b.capxName,
+		capxEnvVars:      b.capxEnvVars,
+		scParameters:     b.scParameters,
+		scProvisioner:    b.scProvisioner,
+		csiNamespace:     b.csiNamespace,
+	}
+}
+
+func (b *EquinixBuilder) installCloudProvider(n nodes.Node, k string, privateParams PrivateParams) error {
+	var c string
+	var err error
+	var cmd exec.Cmd
+
+	// Create the cloud provider secret
+	cloudConfig, err := json.Marshal(map[string]string{
+		"apiKey":    b.apiKey,
+		"projectID": b.projectID,
+		"metro":     b.metro,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal cloud provider config")
+	}
+	c = "kubectl --kubeconfig " + commons.ShellQuote(k) + " -n kube-system create secret generic metal-cloud-config " + commons.ShellQuote("--from-literal=cloud-sa.json="+string(cloudConfig))
+	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to create cloud provider secret")
+	}
+
+	cloudProviderManifests, err := b.getCloudProviderTemplate(privateParams).render()
+	if err != nil {
+		return errors.Wrap(err, "failed to get cloud provider manifests")
+	}
+
+	// Deploy cloud provider
+	cmd = n.Command("kubectl", "--kubeconfig", k, "apply", "-f", "-")
+	if err = cmd.SetStdin(strings.NewReader(cloudProviderManifests)).Run(); err != nil {
+		return errors.Wrap(err, "failed to deploy cloud-provider-equinix-metal")
+	}
+
+	return nil
+}
+
+func (b *EquinixBuilder) getCloudProviderTemplate(privateParams PrivateParams) manifestTemplate {
+	return manifestTemplate{b.capxProvider, "cloud-provider-equinix-metal.tmpl", "", privateParams}
+}
+
+func (b *EquinixBuilder) installCSI(n nodes.Node, k string, privateParams PrivateParams, providerParams ProviderParams, chartsList map[string]commons.ChartEntry) error {
+	var err error
+	var cmd exec.Cmd
+
+	localPathManifests, err := b.getCSITemplates(privateParams)[0].render()
+	if err != nil {
+		return errors.Wrap(err, "failed to get local-path-provisioner manifests")
+	}
+
+	// Deploy local-path-provisioner
+	cmd = n.Command("kubectl", "--kubeconfig", k, "apply", "-f", "-")
+	if err = cmd.SetStdin(strings.NewReader(localPathManifests)).Run(); err != nil {
+		return errors.Wrap(err, "failed to deploy local-path-provisioner")
+	}
+
+	return nil
+}
+
+func (b *EquinixBuilder) getCSITemplates(privateParams PrivateParams) []manifestTemplate {
+	return []manifestTemplate{{b.capxProvider, "local-path-provisioner.tmpl", "", privateParams}}
+}
+
+func (b *EquinixBuilder) getRegistryCredentials(p ProviderParams, u string) (string, string, error) {
+	return "", "", errors.New("only generic registries are supported in equinix clusters")
+}
+
+func (b *EquinixBuilder) configureStorageClass(n nodes.Node, k string) error {
+	storageClass := getGenericStorageClass(b.scProvisioner, b.scParameters)
+	// Local volumes cannot be expanded
+	storageClass.AllowVolumeExpansion = false
+	return applyGenericStorageClass(n, k, storageClass, nil)
+}
+
+func (b *EquinixBuilder) internalNginx(p ProviderParams, networks commons.Networks) (bool, error) {
+	return false, nil
+}
+
+func (b *EquinixBuilder) getOverrideVars(p ProviderParams, networks commons.Networks, clusterConfigSpec commons.ClusterConfigSpec) (map[string][]byte, error) {
+	var overrideVars = make(map[string][]byte)
+
+	return overrideVars, nil
+}
+
+func (b *EquinixBuilder) postInstallPhase(n nodes.Node, k string) error {
+	return ensureCorednsPdb(n)
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/exec"
+)
+
+// Metadata set by the API server, which must not be re-applied
+var serverSideMetadata = []string{"uid", "resourceVersion", "generation", "creationTimestamp", "managedFields", "ownerReferences"}
+
+// exportCAPIManifests writes the Cluster API objects of the cluster, as rendered by the
+// cluster operator, so they can be stored in Git and re-applied by Flux
+func exportCAPIManifests(n nodes.Node, keosCluster commons.KeosCluster, capiClustersNamespace string, manifestsExport commons.ManifestsExport) error {
+	var manifests bytes.Buffer
+
+	c := "kubectl api-resources --namespaced --verbs=list -o name"
+	output, err := commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to list the Cluster API resources")
+	}
+
+	for _, resource := range strings.Fields(output) {
+		if !strings.HasSuffix(resource, "cluster.x-k8s.io") {
+			continue
+		}
+		var list struct {
+			Items []map[string]interface{} `json:"items"`
+		}
+		raw := bytes.Buffer{}
+		cmd := n.Command("kubectl", "-n", capiClustersNamespace, "get", resource, "-o", "json")
+		if err := cmd.SetStdout(&raw).Run(); err != nil {
+			return errors.Wrap(err, "failed to get "+resource)
+		}
+		if err := json.Unmarshal(raw.Bytes(), &list); err != nil {
+			return errors.Wrap(err, "failed to parse "+resource)
+		}
+		for _, item := range list.Items {
+			delete(item, "status")
+			if metadata, ok := item["metadata"].(map[string]interface{}); ok {
+				for _, field := range serverSideMetadata {
+					delete(metadata, field)
+				}
+				if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+					delete(annotations, "kubectl.kubernetes.io/last-applied-configuration")
+				}
+			}
+			manifest, err := yaml.Marshal(item)
+			if err != nil {
+				return err
+			}
+			manifests.WriteString("---\n")
+			manifests.Write(manifest)
+		}
+	}
+
+	if manifestsExport.GitBranch != "" {
+		if err := exec.Command("git", "-C", manifestsExport.Path, "checkout", "-B", manifestsExport.GitBranch).Run(); err != nil {
+			return errors.Wrap(err, "failed to checkout the "+manifestsExport.GitBranch+" branch")
+		}
+	}
+
+	manifestsFilename := filepath.Join(manifestsExport.Path, keosCluster.Metadata.Name+".yaml")
+	if err := os.MkdirAll(manifestsExport.Path, os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.WriteFile(manifestsFilename, manifests.Bytes(), 0644); err != nil {
+		return errors.Wrap(err, "failed to write the Cluster API manifests")
+	}
+
+	if manifestsExport.GitBranch != "" {
+		if err := exec.Command("git", "-C", manifestsExport.Path, "add", keosCluster.Metadata.Name+".yaml").Run(); err != nil {
+			return errors.Wrap(err, "failed to add the Cluster API manifests to git")
+		}
+		// Nothing to commit if the manifests have not changed
+		if err := exec.Command("git", "-C", manifestsExport.Path, "diff", "--cached", "--quiet").Run(); err == nil {
+			return nil
+		}
+		if err := exec.Command("git", "-C", manifestsExport.Path, "commit", "-m", "Update "+keosCluster.Metadata.Name+" cluster manifests").Run(); err != nil {
+			return errors.Wrap(err, "failed to commit the Cluster API manifests")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const (
+	externalSecretsChart       = "external-secrets"
+	externalSecretsStoreName   = "keos-secret-store"
+	externalSecretsStorePath   = "/kind/external-secrets-store.yaml"
+	externalSecretsImageSuffix = "/external-secrets/external-secrets"
+)
+
+var externalSecretsChartEntry = commons.ChartEntry{Repository: "https://charts.external-secrets.io", Version: "0.9.11", Namespace: "external-secrets", Pull: true, Reconcile: false}
+
+// deployExternalSecrets deploys external-secrets with a ClusterSecretStore of the secrets service of the
+// provider, authenticated with the identity of the nodes
+func deployExternalSecrets(n nodes.Node, k string, privateParams PrivateParams, providerParams ProviderParams, externalSecrets commons.ExternalSecrets, chartsList map[string]commons.ChartEntry) error {
+	externalSecretsEntry := chartsList[externalSecretsChart]
+
+	c := "kubectl --kubeconfig " + commons.ShellQuote(k) + " create namespace " + commons.ShellQuote(externalSecretsEntry.Namespace)
+	_, err := commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the "+externalSecretsEntry.Namespace+" namespace")
+	}
+
+	helmValues := map[string]interface{}{"installCRDs": true}
+	if privateParams.Private {
+		image := map[string]string{"repository": privateParams.KeosRegUrl + externalSecretsImageSuffix}
+		helmValues["image"] = image
+		helmValues["webhook"] = map[string]interface{}{"image": image}
+		helmValues["certController"] = map[string]interface{}{"image": image}
+	}
+	helmValuesYAML, err := yaml.Marshal(helmValues)
+	if err != nil {
+		return err
+	}
+	err = commons.WriteFile(n, "/kind/"+externalSecretsChart+"-helm-values.yaml", string(helmValuesYAML))
+	if err != nil {
+		return errors.Wrap(err, "failed to create "+externalSecretsChart+" Helm chart values file")
+	}
+
+	externalSecretsHelmReleaseParams := fluxHelmReleaseParams{
+		ChartRepoRef:   "keos",
+		ChartName:      externalSecretsChart,
+		ChartNamespace: externalSecretsEntry.Namespace,
+		ChartVersion:   externalSecretsEntry.Version,
+	}
+	if !privateParams.HelmPrivate {
+		externalSecretsHelmReleaseParams.ChartRepoRef = externalSecretsChart
+	}
+	if err := configureHelmRelease(n, k, "flux2_helmrelease.tmpl", externalSecretsHelmReleaseParams, privateParams.KeosCluster.Spec.HelmRepository); err != nil {
+		return err
+	}
+
+	var provider map[string]interface{}
+	switch privateParams.KeosCluster.Spec.InfraProvider {
+	case "aws":
+		provider = map[string]interface{}{
+			"aws": map[string]string{"service": "SecretsManager", "region": providerParams.Region},
+		}
+	case "azure":
+		azurekv := map[string]string{"authType": "ManagedIdentity", "vaultUrl": externalSecrets.VaultURL}
+		if externalSecrets.IdentityID != "" {
+			azurekv["identityId"] = externalSecrets.IdentityID
+		}
+		provider = map[string]interface{}{"azurekv": azurekv}
+	case "gcp":
+		provider = map[string]interface{}{
+			"gcpsm": map[string]string{"projectID": providerParams.Credentials["ProjectID"]},
+		}
+	}
+	secretStore, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion": "external-secrets.io/v1beta1",
+		"kind":       "ClusterSecretStore",
+		"metadata":   map[string]string{"name": externalSecretsStoreName},
+		"spec":       map[string]interface{}{"provider": provider},
+	})
+	if err != nil {
+		return err
+	}
+	err = commons.WriteFile(n, externalSecretsStorePath, string(secretStore))
+	if err != nil {
+		return errors.Wrap(err, "failed to write the ClusterSecretStore")
+	}
+	// The webhook may not be serving yet
+	c = "kubectl --kubeconfig " + commons.ShellQuote(k) + " apply -f " + externalSecretsStorePath
+	_, err = commons.ExecuteCommand(n, c, 10, 5)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the ClusterSecretStore")
+	}
+	return nil
+}
@@ -24,7 +24,6 @@ import (
 	"net/url"
 	"strings"
 
-	"golang.org/x/oauth2/google"
 	"google.golang.org/api/compute/v1"
 	"google.golang.org/api/option"
 	"gopkg.in/yaml.v3"
@@ -47,6 +46,7 @@ type GCPBuilder struct {
 	scParameters     commons.SCParameters
 	scProvisioner    string
 	csiNamespace     string
+	garToken         commons.RegistryToken
 }
 
 var googleCharts = ChartsDictionary{
@@ -97,6 +97,20 @@ func (b *GCPBuilder) setCapx(managed bool) {
 }
 
 func (b *GCPBuilder) setCapxEnvVars(p ProviderParams) {
+	b.capxEnvVars = []string{
+		"GCP_B64ENCODED_CREDENTIALS=" + b64.StdEncoding.EncodeToString(getGCPCredentials(p)),
+	}
+	if p.Managed {
+		b.capxEnvVars = append(b.capxEnvVars, "EXP_MACHINE_POOL=true")
+		b.capxEnvVars = append(b.capxEnvVars, "EXP_CAPG_GKE=true")
+	}
+	if p.GithubToken != "" {
+		b.capxEnvVars = append(b.capxEnvVars, "GITHUB_TOKEN="+p.GithubToken)
+	}
+}
+
+// getGCPCredentials returns the service account credentials file
+func getGCPCredentials(p ProviderParams) []byte {
 	data := map[string]interface{}{
 		"type":                        "service_account",
 		"project_id":                  p.Credentials["ProjectID"],
@@ -110,16 +124,7 @@ func (b *GCPBuilder) setCapxEnvVars(p ProviderParams) {
 		"client_x509_cert_url":        "https://www.googleapis.com/robot/v1/metadata/x509/" + url.QueryEscape(p.Credentials["ClientEmail"]),
 	}
 	jsonData, _ := json.Marshal(data)
-	b.capxEnvVars = []string{
-		"GCP_B64ENCODED_CREDENTIALS=" + b64.StdEncoding.EncodeToString([]byte(jsonData)),
-	}
-	if p.Managed {
-		b.capxEnvVars = append(b.capxEnvVars, "EXP_MACHINE_POOL=true")
-		b.capxEnvVars = append(b.capxEnvVars, "EXP_CAPG_GKE=true")
-	}
-	if p.GithubToken != "" {
-		b.capxEnvVars = append(b.capxEnvVars, "GITHUB_TOKEN="+p.GithubToken)
-	}
+	return jsonData
 }
 
 func (b *GCPBuilder) setSC(p ProviderParams) {
@@ -130,7 +135,11 @@ func (b *GCPBuilder) setSC(p ProviderParams) {
 	b.scProvisioner = "pd.csi.storage.gke.io"
 
 	if b.scParameters.Type == "" {
-		b.scParameters.Type = "pd-ssd"
+		if p.StorageClass.Class == "standard" {
+			b.scParameters.Type = "pd-standard"
+		} else {
+			b.scParameters.Type = "pd-ssd"
+		}
 	}
 
 	if p.StorageClass.EncryptionKey != "" {
@@ -174,37 +183,22 @@ func (b *GCPBuilder) getProvider() Provider {
 }
 
 func (b *GCPBuilder) installCloudProvider(n nodes.Node, k string, privateParams PrivateParams) error {
-	var podsCidrBlock string
-	keosCluster := privateParams.KeosCluster
-	if keosCluster.Spec.Networks.PodsCidrBlock != "" {
-		podsCidrBlock = keosCluster.Spec.Networks.PodsCidrBlock
-	} else {
-		podsCidrBlock = "192.168.0.0/16"
-	}
-
 	cloudControllerManagerValuesFile := "/kind/gcp-cloud-controller-manager-helm-values.yaml"
-	cloudControllerManagerHelmParams := cloudControllerHelmParams{
-		ClusterName: privateParams.KeosCluster.Metadata.Name,
-		Private:     privateParams.Private,
-		KeosRegUrl:  privateParams.KeosRegUrl,
-		PodsCidr:    podsCidrBlock,
-	}
 
 	// Generate the CCM helm values
-	cloudControllerManagerHelmValues, err := getManifest(b.capxProvider, "gcp-cloud-controller-manager-helm-values.tmpl", majorVersion, cloudControllerManagerHelmParams)
+	cloudControllerManagerHelmValues, err := b.getCloudProviderTemplate(privateParams).render()
 	if err != nil {
 		return errors.Wrap(err, "failed to create cloud controller manager Helm chart values file")
 	}
-	c := "echo '" + cloudControllerManagerHelmValues + "' > " + cloudControllerManagerValuesFile
-	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	err = commons.WriteFile(n, cloudControllerManagerValuesFile, cloudControllerManagerHelmValues)
 	if err != nil {
 		return errors.Wrap(err, "failed to create cloud controller manager Helm chart values file")
 	}
 
-	c = "helm install gcp-cloud-controller-manager /stratio/helm/gcp-cloud-controller-manager" +
-		" --kubeconfig " + k +
+	c := "helm install gcp-cloud-controller-manager /stratio/helm/gcp-cloud-controller-manager" +
+		" --kubeconfig " + commons.ShellQuote(k) +
 		" --namespace kube-system" +
-		" --values " + cloudControllerManagerValuesFile
+		" --values " + commons.ShellQuote(cloudControllerManagerValuesFile)
 	_, err = commons.ExecuteCommand(n, c, 5, 3)
 	if err != nil {
 		return errors.Wrap(err, "failed to deploy gcp-cloud-controller-manager Helm Chart")
@@ -213,6 +207,10 @@ func (b *GCPBuilder) installCloudProvider(n nodes.Node, k string, privateParams
 	return nil
 }
 
+func (b *GCPBuilder) getCloudProviderTemplate(privateParams PrivateParams) manifestTemplate {
+	return manifestTemplate{b.capxProvider, "gcp-cloud-controller-manager-helm-values.tmpl", majorVersion, getCloudControllerHelmParams(privateParams)}
+}
+
 func (b *GCPBuilder) installCSI(n nodes.Node, k string, privateParams PrivateParams, providerParams ProviderParams, charstList map[string]commons.ChartEntry) error {
 	var c string
 	var err error
@@ -220,13 +218,13 @@ func (b *GCPBuilder) installCSI(n nodes.Node, k string, privateParams PrivatePar
 
 	// Create CSI secret in CSI namespace
 	secret, _ := b64.StdEncoding.DecodeString(strings.Split(b.capxEnvVars[0], "GCP_B64ENCODED_CREDENTIALS=")[1])
-	c = "kubectl --kubeconfig " + k + " -n " + b.csiNamespace + " create secret generic cloud-sa --from-literal=cloud-sa.json='" + string(secret) + "'"
+	c = "kubectl --kubeconfig " + commons.ShellQuote(k) + " -n " + commons.ShellQuote(b.csiNamespace) + " create secret generic cloud-sa " + commons.ShellQuote("--from-literal=cloud-sa.json="+string(secret))
 	_, err = commons.ExecuteCommand(n, c, 5, 3)
 	if err != nil {
 		return errors.Wrap(err, "failed to create CSI secret in CSI namespace")
 	}
 
-	csiManifests, err := getManifest(privateParams.KeosCluster.Spec.InfraProvider, "gcp-compute-persistent-disk-csi-driver.tmpl", majorVersion, privateParams)
+	csiManifests, err := b.getCSITemplates(privateParams)[0].render()
 	if err != nil {
 		return errors.Wrap(err, "failed to get CSI driver manifests")
 	}
@@ -240,43 +238,28 @@ func (b *GCPBuilder) installCSI(n nodes.Node, k string, privateParams PrivatePar
 	return nil
 }
 
+func (b *GCPBuilder) getCSITemplates(privateParams PrivateParams) []manifestTemplate {
+	return []manifestTemplate{{b.capxProvider, "gcp-compute-persistent-disk-csi-driver.tmpl", majorVersion, privateParams}}
+}
+
 func (b *GCPBuilder) getRegistryCredentials(p ProviderParams, u string) (string, string, error) {
-	var registryUser = "oauth2accesstoken"
-	var ctx = context.Background()
-	scope := "https://www.googleapis.com/auth/cloud-platform"
-	key, _ := b64.StdEncoding.DecodeString(strings.Split(b.capxEnvVars[0], "GCP_B64ENCODED_CREDENTIALS=")[1])
-	creds, err := google.CredentialsFromJSON(ctx, key, scope)
-	if err != nil {
-		return "", "", err
+	// The access tokens of the service account are valid for any registry of the project, so the
+	// same one is reused by the steps of the provisioning until it is about to expire
+	if b.garToken.Password != "" && !b.garToken.Expired() {
+		return b.garToken.User, b.garToken.Password, nil
 	}
-	token, err := creds.TokenSource.Token()
+	token, err := commons.GetGARToken(context.Background(), getGCPCredentials(p))
 	if err != nil {
 		return "", "", err
 	}
-	return registryUser, token.AccessToken, nil
+	b.garToken = token
+	return token.User, token.Password, nil
 }
 
 func (b *GCPBuilder) configureStorageClass(n nodes.Node, k string) error {
-	var c string
 	var err error
 	var cmd exec.Cmd
 
-	if b.capxManaged {
-		// Remove annotation from default storage class
-		c = "kubectl --kubeconfig " + k + ` get sc -o jsonpath='{.items[?(@.metadata.annotations.storageclass\.kubernetes\.io/is-default-class=="true")].metadata.name}'`
-		output, err := commons.ExecuteCommand(n, c, 5, 3)
-		if err != nil {
-			return errors.Wrap(err, "failed to get default storage class")
-		}
-		if strings.TrimSpace(output) != "" && strings.TrimSpace(output) != "No resources found" {
-			c = "kubectl --kubeconfig " + k + " annotate sc " + strings.TrimSpace(output) + " " + defaultScAnnotation + "-"
-			_, err = commons.ExecuteCommand(n, c, 5, 3)
-			if err != nil {
-				return errors.Wrap(err, "failed to remove annotation from default storage class")
-			}
-		}
-	}
-
 	scTemplate.Parameters = b.scParameters
 	scTemplate.Provisioner = b.scProvisioner
 
@@ -305,7 +288,7 @@ func (b *GCPBuilder) internalNginx(p ProviderParams, networks commons.Networks)
 	}
 	if len(networks.Subnets) > 0 {
 		for _, s := range networks.Subnets {
-			publicSubnetID, _ := GCPFilterPublicSubnet(computeService, p.Credentials["ProjectID"], p.Region, s.SubnetId)
+			publicSubnetID, _ := GCPFilterPublicSubnet(computeService, commons.GCPNetworkProject(p.Credentials), p.Region, s.SubnetId)
 			if len(publicSubnetID) > 0 {
 				return false, nil
 			}
@@ -343,7 +326,7 @@ func (b *GCPBuilder) getOverrideVars(p ProviderParams, networks commons.Networks
 func (b *GCPBuilder) postInstallPhase(n nodes.Node, k string) error {
 	var coreDNSPDBName = "coredns"
 
-	c := "kubectl --kubeconfig " + kubeconfigPath + " get pdb " + coreDNSPDBName + " -n kube-system"
+	c := "kubectl --kubeconfig " + kubeconfigPath + " get pdb " + commons.ShellQuote(coreDNSPDBName) + " -n kube-system"
 	_, err := commons.ExecuteCommand(n, c, 5, 3)
 	if err != nil {
 		err = installCorednsPdb(n)
@@ -0,0 +1,204 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/kind/pkg/commons"
+)
+
+// The golden files are regenerated, once the changes of the templates are reviewed, with:
+//
+//	go test ./pkg/cluster/internal/create/actions/createworker -run TestGoldenTemplates -update
+var updateGolden = flag.Bool("update", false, "update the golden files with the rendered templates")
+
+const (
+	goldenDescriptorsDir = "testdata/descriptors"
+	goldenDir            = "testdata/golden"
+)
+
+// goldenCredentials are the credentials the templates of the provider are rendered with, as the
+// descriptors do not hold them
+var goldenCredentials = map[string]map[string]string{
+	"nutanix": {"Endpoint": "prism-central.golden.example.com:9440"},
+}
+
+// TestGoldenTemplates renders the keos.yaml and the templates of each descriptor of testdata/descriptors,
+// and compares them with the golden files of testdata/golden/<descriptor>, so the changes of the
+// rendered manifests are caught without provisioning any cluster
+func TestGoldenTemplates(t *testing.T) {
+	defer func(v string) { majorVersion = v }(majorVersion)
+	// The descriptors are not completed with the local config of the user
+	t.Setenv("HOME", t.TempDir())
+
+	descriptors, err := filepath.Glob(filepath.Join(goldenDescriptorsDir, "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(descriptors) == 0 {
+		t.Fatalf("no descriptors found in %s", goldenDescriptorsDir)
+	}
+
+	for _, descriptorPath := range descriptors {
+		descriptorPath := descriptorPath
+		name := strings.TrimSuffix(filepath.Base(descriptorPath), ".yaml")
+		t.Run(name, func(t *testing.T) {
+			keosCluster, clusterConfig, err := commons.GetClusterDescriptor(descriptorPath)
+			if err != nil {
+				t.Fatalf("failed to parse the descriptor: %v", err)
+			}
+			majorVersion = strings.Split(keosCluster.Spec.K8SVersion, ".")[1]
+
+			rendered := map[string]string{}
+			keosYAMLData, err := getKEOSDescriptor(*keosCluster, scName)
+			if err != nil {
+				t.Fatalf("failed to render keos.yaml: %v", err)
+			}
+			rendered["keos.yaml"] = string(keosYAMLData)
+			for _, tpl := range goldenTemplates(*keosCluster, clusterConfig) {
+				manifest, err := tpl.render()
+				if err != nil {
+					t.Errorf("failed to render %s: %v", tpl.name, err)
+					continue
+				}
+				rendered[strings.TrimSuffix(tpl.name, ".tmpl")+".yaml"] = manifest
+			}
+
+			compareGolden(t, filepath.Join(goldenDir, name), rendered)
+		})
+	}
+}
+
+// goldenTemplates returns the templates rendered in the provisioning of the cluster, as returned
+// by the functions the installation steps render them with
+func goldenTemplates(keosCluster commons.KeosCluster, clusterConfig *commons.ClusterConfig) []manifestTemplate {
+	spec := keosCluster.Spec
+	privateParams := PrivateParams{
+		KeosCluster: keosCluster,
+		Private:     clusterConfig.Spec.Private,
+		HelmPrivate: clusterConfig.Spec.PrivateHelmRepo,
+	}
+	for _, registry := range spec.DockerRegistries {
+		if registry.KeosRegistry {
+			privateParams.KeosRegUrl = registry.URL
+		}
+	}
+	infra := newInfra(getBuilder(spec.InfraProvider))
+	infra.buildProvider(ProviderParams{
+		ClusterName:  keosCluster.Metadata.Name,
+		Region:       spec.Region,
+		Managed:      spec.ControlPlane.Managed,
+		Credentials:  goldenCredentials[spec.InfraProvider],
+		StorageClass: spec.StorageClass,
+		IBMCloud:     spec.ControlPlane.IBMCloud,
+	})
+
+	var templates []manifestTemplate
+	if !spec.ControlPlane.Managed {
+		templates = append(templates, infra.getCloudProviderTemplate(privateParams))
+		templates = append(templates, infra.getCSITemplates(privateParams)...)
+	}
+	if installsCalico(spec) {
+		templates = append(templates, getCalicoTemplate(privateParams, isCalicoNetPolEngine(spec)))
+	}
+	if deploysAutoscaler(spec) {
+		templates = append(templates, getClusterAutoscalerTemplate(privateParams))
+	}
+	if customizesCoreDNS(spec) {
+		templates = append(templates, getCoreDNSPatchTemplate(spec))
+	}
+	return append(templates, getCAPXPDBTemplate(spec), getCAPIPDBTemplate(spec))
+}
+
+// compareGolden compares the rendered files with the ones of the golden directory, which must hold
+// the same files. The directory is replaced with the rendered files when -update is set
+func compareGolden(t *testing.T, dir string, rendered map[string]string) {
+	t.Helper()
+	if *updateGolden {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for name, content := range rendered {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read the golden files, run the test with -update to generate them: %v", err)
+	}
+	golden := map[string]bool{}
+	for _, entry := range entries {
+		golden[entry.Name()] = true
+		if _, ok := rendered[entry.Name()]; !ok {
+			t.Errorf("%s is no longer rendered, run the test with -update to remove it", entry.Name())
+		}
+	}
+
+	var names []string
+	for name := range rendered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !golden[name] {
+			t.Errorf("%s has no golden file, run the test with -update to generate it", name)
+			continue
+		}
+		want, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := goldenDiff(string(want), rendered[name]); diff != "" {
+			t.Errorf("%s differs from its golden file, run the test with -update if the change is expected:\n%s", name, diff)
+		}
+	}
+}
+
+// goldenDiff returns the first line which differs between the golden and the rendered contents
+func goldenDiff(want string, got string) string {
+	if want == got {
+		return ""
+	}
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	for i := 0; ; i++ {
+		var wantLine, gotLine string
+		if i < len(wantLines) {
+			wantLine = wantLines[i]
+		}
+		if i < len(gotLines) {
+			gotLine = gotLines[i]
+		}
+		if wantLine != gotLine || i >= len(wantLines) || i >= len(gotLines) {
+			return "line " + strconv.Itoa(i+1) + ":\n- " + wantLine + "\n+ " + gotLine
+		}
+	}
+}
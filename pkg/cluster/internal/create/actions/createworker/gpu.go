@@ -0,0 +1,154 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const (
+	gpuOperatorChart     = "gpu-operator"
+	gpuSharingConfigMap  = "keos-gpu-sharing"
+	gpuDevicePluginLabel = "nvidia.com/device-plugin.config"
+	gpuMIGLabel          = "nvidia.com/mig.config"
+)
+
+var gpuOperatorChartEntry = commons.ChartEntry{Repository: "https://helm.ngc.nvidia.com/nvidia", Version: "v23.9.1", Namespace: "gpu-operator", Pull: true, Reconcile: false}
+
+// The images of the GPU Operator components, which are pulled from the keos registry in private clusters
+var gpuOperatorComponents = []string{
+	"operator", "driver", "toolkit", "devicePlugin", "dcgm", "dcgmExporter", "gfd", "migManager", "validator", "nodeStatusExporter",
+}
+
+func hasGPUNodes(keosSpec commons.KeosSpec) bool {
+	for _, wn := range keosSpec.WorkerNodes {
+		if wn.GPU != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// getGPUNodeLabels returns the labels selecting the sharing config of the nodes of a GPU node group
+func getGPUNodeLabels(nodeGroup string, gpu commons.GPU) map[string]string {
+	labels := map[string]string{}
+	switch gpu.Sharing {
+	case "time-slicing":
+		labels[gpuDevicePluginLabel] = nodeGroup
+	case "mig":
+		labels[gpuDevicePluginLabel] = nodeGroup
+		labels[gpuMIGLabel] = gpu.MIGProfile
+	}
+	return labels
+}
+
+// deployGPUOperator deploys the NVIDIA GPU Operator, with the device plugin sharing config of each
+// GPU node group
+func deployGPUOperator(n nodes.Node, k string, privateParams PrivateParams, keosSpec commons.KeosSpec, chartsList map[string]commons.ChartEntry) error {
+	gpuOperatorEntry := chartsList[gpuOperatorChart]
+
+	c := "kubectl --kubeconfig " + commons.ShellQuote(k) + " create namespace " + commons.ShellQuote(gpuOperatorEntry.Namespace)
+	_, err := commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the "+gpuOperatorEntry.Namespace+" namespace")
+	}
+
+	sharingConfigs := map[string]string{}
+	migStrategy := "single"
+	for _, wn := range keosSpec.WorkerNodes {
+		if wn.GPU == nil || wn.GPU.Sharing == "" {
+			continue
+		}
+		var sharingConfig map[string]interface{}
+		if wn.GPU.Sharing == "mig" {
+			migStrategy = "mixed"
+			sharingConfig = map[string]interface{}{
+				"version": "v1",
+				"flags":   map[string]string{"migStrategy": "mixed"},
+			}
+		} else {
+			sharingConfig = map[string]interface{}{
+				"version": "v1",
+				"sharing": map[string]interface{}{
+					"timeSlicing": map[string]interface{}{
+						"resources": []map[string]interface{}{{"name": "nvidia.com/gpu", "replicas": wn.GPU.Replicas}},
+					},
+				},
+			}
+		}
+		sharingConfigYAML, err := yaml.Marshal(sharingConfig)
+		if err != nil {
+			return err
+		}
+		sharingConfigs[wn.Name] = string(sharingConfigYAML)
+	}
+	configMap, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]string{"name": gpuSharingConfigMap, "namespace": gpuOperatorEntry.Namespace},
+		"data":       sharingConfigs,
+	})
+	if err != nil {
+		return err
+	}
+	cmd := n.Command("kubectl", "--kubeconfig", k, "apply", "-f", "-")
+	if err = cmd.SetStdin(strings.NewReader(string(configMap))).Run(); err != nil {
+		return errors.Wrap(err, "failed to create the "+gpuSharingConfigMap+" ConfigMap")
+	}
+
+	helmValues := map[string]interface{}{
+		"mig":          map[string]string{"strategy": migStrategy},
+		"devicePlugin": map[string]interface{}{"config": map[string]string{"name": gpuSharingConfigMap}},
+	}
+	if privateParams.Private {
+		for _, component := range gpuOperatorComponents {
+			componentValues, ok := helmValues[component].(map[string]interface{})
+			if !ok {
+				componentValues = map[string]interface{}{}
+			}
+			componentValues["repository"] = privateParams.KeosRegUrl + "/nvidia"
+			helmValues[component] = componentValues
+		}
+		helmValues["node-feature-discovery"] = map[string]interface{}{
+			"image": map[string]string{"repository": privateParams.KeosRegUrl + "/nfd/node-feature-discovery"},
+		}
+	}
+	helmValuesYAML, err := yaml.Marshal(helmValues)
+	if err != nil {
+		return err
+	}
+	err = commons.WriteFile(n, "/kind/"+gpuOperatorChart+"-helm-values.yaml", string(helmValuesYAML))
+	if err != nil {
+		return errors.Wrap(err, "failed to create "+gpuOperatorChart+" Helm chart values file")
+	}
+
+	gpuOperatorHelmReleaseParams := fluxHelmReleaseParams{
+		ChartRepoRef:   "keos",
+		ChartName:      gpuOperatorChart,
+		ChartNamespace: gpuOperatorEntry.Namespace,
+		ChartVersion:   gpuOperatorEntry.Version,
+	}
+	if !privateParams.HelmPrivate {
+		gpuOperatorHelmReleaseParams.ChartRepoRef = gpuOperatorChart
+	}
+	return configureHelmRelease(n, k, "flux2_helmrelease.tmpl", gpuOperatorHelmReleaseParams, privateParams.KeosCluster.Spec.HelmRepository)
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"net"
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+type handoffCheck struct {
+	name string
+	err  error
+}
+
+// verifyKeosHandoff checks the workload cluster meets the prerequisites of the keos installation
+func verifyKeosHandoff(n nodes.Node, keosCluster commons.KeosCluster, k string) []handoffCheck {
+	var checks []handoffCheck
+
+	c := "kubectl --kubeconfig " + commons.ShellQuote(k) + " wait --for=condition=Ready nodes --all --timeout=1m"
+	_, err := commons.ExecuteCommand(n, c, 5, 3)
+	checks = append(checks, handoffCheck{name: "All the nodes are ready", err: err})
+
+	storageClassName := getStorageClassName(keosCluster.Spec.StorageClass)
+	if isDefaultStorageClass(keosCluster.Spec.StorageClass) {
+		c = "kubectl --kubeconfig " + commons.ShellQuote(k) + ` get sc -o jsonpath='{.items[?(@.metadata.annotations.storageclass\.kubernetes\.io/is-default-class=="true")].metadata.name}'`
+		output, err := commons.ExecuteCommand(n, c, 5, 3)
+		if err == nil && strings.TrimSpace(output) != storageClassName {
+			err = errors.New("the default StorageClass is \"" + strings.TrimSpace(output) + "\" instead of \"" + storageClassName + "\"")
+		}
+		checks = append(checks, handoffCheck{name: "The " + storageClassName + " StorageClass is the default one", err: err})
+	} else {
+		c = "kubectl --kubeconfig " + commons.ShellQuote(k) + " get sc " + commons.ShellQuote(storageClassName)
+		_, err = commons.ExecuteCommand(n, c, 5, 3)
+		checks = append(checks, handoffCheck{name: "The " + storageClassName + " StorageClass exists", err: err})
+	}
+
+	// The cluster operator image is pulled from the keos registry
+	c = "kubectl --kubeconfig " + commons.ShellQuote(k) + " -n kube-system rollout status deploy keoscluster-controller-manager --timeout=1m"
+	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	checks = append(checks, handoffCheck{name: "Images can be pulled from the keos registry", err: err})
+
+	// Both CoreDNS and kube-dns pods use this label
+	c = "kubectl --kubeconfig " + commons.ShellQuote(k) + " -n kube-system wait --for=condition=Ready pods -l k8s-app=kube-dns --timeout=1m"
+	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	checks = append(checks, handoffCheck{name: "The cluster DNS is ready", err: err})
+
+	if dnsName := keosCluster.Spec.ControlPlane.APIServer.DNSName; dnsName != "" {
+		_, err = net.LookupHost(dnsName)
+		checks = append(checks, handoffCheck{name: "The API server DNS name " + dnsName + " resolves", err: err})
+	}
+
+	return checks
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+type HetznerBuilder struct {
+	capxProvider     string
+	capxVersion      string
+	capxImageVersion string
+	capxManaged      bool
+	capxName         string
+	capxEnvVars      []string
+	scParameters     commons.SCParameters
+	scProvisioner    string
+	csiNamespace     string
+	token            string
+}
+
+var hetznerCharts = ChartsDictionary{
+	Charts: map[string]map[string]map[string]commons.ChartEntry{
+		"28": {
+			"managed": {},
+			"unmanaged": {
+				"hcloud-cloud-controller-manager": {Repository: "https://charts.hetzner.cloud", Version: "1.20.0", Namespace: "kube-system", Pull: true},
+				"hcloud-csi":                      {Repository: "https://charts.hetzner.cloud", Version: "2.9.0", Namespace: "kube-system", Pull: false, Reconcile: true},
+				"cluster-autoscaler":              {Repository: "https://kubernetes.github.io/autoscaler", Version: "9.34.1", Namespace: "kube-system", Pull: false, Reconcile: false},
+				"tigera-operator":                 {Repository: "https://docs.projectcalico.org/charts", Version: "v3.28.2", Namespace: "tigera-operator", Pull: true, Reconcile: true},
+			},
+		},
+		"29": {
+			"managed": {},
+			"unmanaged": {
+				"hcloud-cloud-controller-manager": {Repository: "https://charts.hetzner.cloud", Version: "1.20.0", Namespace: "kube-system", Pull: true},
+				"hcloud-csi":                      {Repository: "https://charts.hetzner.cloud", Version: "2.9.0", Namespace: "kube-system", Pull: false, Reconcile: true},
+				"cluster-autoscaler":              {Repository: "https://kubernetes.github.io/autoscaler", Version: "9.35.0", Namespace: "kube-system", Pull: false, Reconcile: false},
+				"tigera-operator":                 {Repository: "https://docs.projectcalico.org/charts", Version: "v3.28.2", Namespace: "tigera-operator", Pull: true, Reconcile: true},
+			},
+		},
+		"30": {
+			"managed": {},
+			"unmanaged": {
+				"hcloud-cloud-controller-manager": {Repository: "https://charts.hetzner.cloud", Version: "1.20.0", Namespace: "kube-system", Pull: true},
+				"hcloud-csi":                      {Repository: "https://charts.hetzner.cloud", Version: "2.9.0", Namespace: "kube-system", Pull: false, Reconcile: true},
+				"cluster-autoscaler":              {Repository: "https://kubernetes.github.io/autoscaler", Version: "9.37.0", Namespace: "kube-system", Pull: false, Reconcile: false},
+				"tigera-operator":                 {Repository: "https://docs.projectcalico.org/charts", Version: "v3.28.2", Namespace: "tigera-operator", Pull: true, Reconcile: true},
+			},
+		},
+	},
+}
+
+func newHetznerBuilder() *HetznerBuilder {
+	return &HetznerBuilder{}
+}
+
+func (b *HetznerBuilder) setCapx(managed bool) {
+	b.capxProvider = "hetzner"
+	b.capxVersion = "v1.0.0"
+	b.capxImageVersion = "v1.0.0"
+	b.capxName = "caph"
+	b.capxManaged = managed
+	b.csiNamespace = "kube-system"
+}
+
+func (b *HetznerBuilder) setCapxEnvVars(p ProviderParams) {
+	b.token = p.Credentials["Token"]
+	b.capxEnvVars = []string{
+		"HCLOUD_TOKEN=" + b.token,
+	}
+	if p.GithubToken != "" {
+		b.capxEnvVars = append(b.capxEnvVars, "GITHUB_TOKEN="+p.GithubToken)
+	}
+}
+
+func (b *HetznerBuilder) setSC(p ProviderParams) {
+	// Hetzner Cloud offers a single (SSD based) volume type, so both
+	// standard and premium classes are provisioned as hcloud volumes
+	b.scProvisioner = "csi.hetzner.cloud"
+
+	if p.StorageClass.Parameters.FsType != "" {
+		b.scParameters.FsType = p.StorageClass.Parameters.FsType
+	}
+}
+
+func (b *HetznerBuilder) pullProviderCharts(n nodes.Node, clusterConfigSpec *commons.ClusterConfigSpec, keosSpec commons.KeosSpec, clusterCredentials commons.ClusterCredentials, clusterType string) error {
+	return pullGenericCharts(n, clusterConfigSpec, keosSpec, clusterCredentials, hetznerCharts, clusterType)
+}
+
+func (b *HetznerBuilder) getProviderCharts(clusterConfigSpec *commons.ClusterConfigSpec, keosSpec commons.KeosSpec, clusterType string) map[string]commons.ChartEntry {
+	return getGenericCharts(clusterConfigSpec, keosSpec, hetznerCharts, clusterType)
+}
+
+func (b *HetznerBuilder) getOverriddenCharts(charts *[]commons.Chart, clusterConfigSpec *commons.ClusterConfigSpec, clusterType string) []commons.Chart {
+	return getGenericOverriddenCharts(charts, clusterConfigSpec, hetznerCharts, clusterType)
+}
+
+func (b *HetznerBuilder) getProvider() Provider {
+	return Provider{
+		capxProvider:     b.capxProvider,
+		capxVersion:      b.capxVersion,
+		capxImageVersion: b.capxImageVersion,
+		capxManaged:      b.capxManaged,
+		capxName:         b.capxName,
+		capxEnvVars:      b.capxEnvVars,
+		scParameters:     b.scParameters,
+		scProvisioner:    b.scProvisioner,
+		csiNamespace:     b.csiNamespace,
+	}
+}
+
+func (b *HetznerBuilder) installCloudProvider(n nodes.Node, k string, privateParams PrivateParams) error {
+	// Create the hcloud secret, shared by the CCM and the CSI driver
+	c := "kubectl --kubeconfig " + commons.ShellQuote(k) + " -n kube-system create secret generic hcloud" +
+		" " + commons.ShellQuote("--from-literal=token="+b.token)
+	_, err := commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to create hcloud secret")
+	}
+
+	cloudControllerManagerValuesFile := "/kind/hcloud-cloud-controller-manager-helm-values.yaml"
+
+	// Generate the CCM helm values
+	cloudControllerManagerHelmValues, err := b.getCloudProviderTemplate(privateParams).render()
+	if err != nil {
+		return errors.Wrap(err, "failed to create cloud controller manager Helm chart values file")
+	}
+	err = commons.WriteFile(n, cloudControllerManagerValuesFile, cloudControllerManagerHelmValues)
+	if err != nil {
+		return errors.Wrap(err, "failed to create cloud controller manager Helm chart values file")
+	}
+
+	c = "helm install hcloud-cloud-controller-manager /stratio/helm/hcloud-cloud-controller-manager" +
+		" --kubeconfig " + commons.ShellQuote(k) +
+		" --namespace kube-system" +
+		" --values " + commons.ShellQuote(cloudControllerManagerValuesFile)
+	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to deploy hcloud-cloud-controller-manager Helm Chart")
+	}
+
+	return nil
+}
+
+func (b *HetznerBuilder) getCloudProviderTemplate(privateParams PrivateParams) manifestTemplate {
+	return manifestTemplate{b.capxProvider, "hcloud-cloud-controller-manager-helm-values.tmpl", majorVersion, getCloudControllerHelmParams(privateParams)}
+}
+
+func (b *HetznerBuilder) installCSI(n nodes.Node, k string, privateParams PrivateParams, providerParams ProviderParams, chartsList map[string]commons.ChartEntry) error {
+	csiName := "hcloud-csi"
+	csiValuesFile := "/kind/" + csiName + "-helm-values.yaml"
+	csiEntry := chartsList[csiName]
+	csiHelmReleaseParams := fluxHelmReleaseParams{
+		ChartRepoRef:   "keos",
+		ChartName:      csiName,
+		ChartNamespace: csiEntry.Namespace,
+		ChartVersion:   csiEntry.Version,
+	}
+	if !privateParams.HelmPrivate {
+		csiHelmReleaseParams.ChartRepoRef = csiName
+	}
+	// Generate the hcloud-csi helm values
+	csiHelmValues, getManifestErr := b.getCSITemplates(privateParams)[0].render()
+	if getManifestErr != nil {
+		return errors.Wrap(getManifestErr, "failed to generate "+csiName+" helm values")
+	}
+
+	err := commons.WriteFile(n, csiValuesFile, csiHelmValues)
+	if err != nil {
+		return errors.Wrap(err, "failed to create "+csiName+" Helm chart values file")
+	}
+	if err := configureHelmRelease(n, kubeconfigPath, "flux2_helmrelease.tmpl", csiHelmReleaseParams, privateParams.KeosCluster.Spec.HelmRepository); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (b *HetznerBuilder) getCSITemplates(privateParams PrivateParams) []manifestTemplate {
+	return []manifestTemplate{{b.capxProvider, "hcloud-csi-helm-values.tmpl", majorVersion, privateParams}}
+}
+
+func (b *HetznerBuilder) getRegistryCredentials(p ProviderParams, u string) (string, string, error) {
+	return "", "", errors.New("only generic registries are supported in hetzner clusters")
+}
+
+func (b *HetznerBuilder) configureStorageClass(n nodes.Node, k string) error {
+	return applyGenericStorageClass(n, k, getGenericStorageClass(b.scProvisioner, b.scParameters), nil)
+}
+
+func (b *HetznerBuilder) internalNginx(p ProviderParams, networks commons.Networks) (bool, error) {
+	return false, nil
+}
+
+func (b *HetznerBuilder) getOverrideVars(p ProviderParams, networks commons.Networks, clusterConfigSpec commons.ClusterConfigSpec) (map[string][]byte, error) {
+	var overrideVars = make(map[string][]byte)
+
+	return overrideVars, nil
+}
+
+func (b *HetznerBuilder) postInstallPhase(n nodes.Node, k string) error {
+	return ensureCorednsPdb(n)
+}
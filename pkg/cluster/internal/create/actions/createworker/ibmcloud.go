@@ -0,0 +1,266 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/exec"
+)
+
+type IBMCloudBuilder struct {
+	capxProvider     string
+	capxVersion      string
+	capxImageVersion string
+	capxManaged      bool
+	capxName         string
+	capxEnvVars      []string
+	scParameters     commons.SCParameters
+	scProvisioner    string
+	csiNamespace     string
+	apiKey           string
+}
+
+type ibmCloudProviderParams struct {
+	ClusterName       string
+	Private           bool
+	KeosRegUrl        string
+	Region            string
+	Target            string
+	ServiceInstanceID string
+}
+
+var ibmcloudCharts = ChartsDictionary{
+	Charts: map[string]map[string]map[string]commons.ChartEntry{
+		"28": {
+			"managed": {},
+			"unmanaged": {
+				"cluster-autoscaler": {Repository: "https://kubernetes.github.io/autoscaler", Version: "9.34.1", Namespace: "kube-system", Pull: false, Reconcile: false},
+				"tigera-operator":    {Repository: "https://docs.projectcalico.org/charts", Version: "v3.28.2", Namespace: "tigera-operator", Pull: true, Reconcile: true},
+			},
+		},
+		"29": {
+			"managed": {},
+			"unmanaged": {
+				"cluster-autoscaler": {Repository: "https://kubernetes.github.io/autoscaler", Version: "9.35.0", Namespace: "kube-system", Pull: false, Reconcile: false},
+				"tigera-operator":    {Repository: "https://docs.projectcalico.org/charts", Version: "v3.28.2", Namespace: "tigera-operator", Pull: true, Reconcile: true},
+			},
+		},
+		"30": {
+			"managed": {},
+			"unmanaged": {
+				"cluster-autoscaler": {Repository: "https://kubernetes.github.io/autoscaler", Version: "9.37.0", Namespace: "kube-system", Pull: false, Reconcile: false},
+				"tigera-operator":    {Repository: "https://docs.projectcalico.org/charts", Version: "v3.28.2", Namespace: "tigera-operator", Pull: true, Reconcile: true},
+			},
+		},
+	},
+}
+
+func newIBMCloudBuilder() *IBMCloudBuilder {
+	return &IBMCloudBuilder{}
+}
+
+func (b *IBMCloudBuilder) setCapx(managed bool) {
+	b.capxProvider = "ibmcloud"
+	b.capxVersion = "v0.8.0"
+	b.capxImageVersion = "v0.8.0"
+	b.capxName = "capibm"
+	b.capxManaged = managed
+	b.csiNamespace = "kube-system"
+}
+
+func (b *IBMCloudBuilder) setCapxEnvVars(p ProviderParams) {
+	b.apiKey = p.Credentials["ApiKey"]
+	b.capxEnvVars = []string{
+		"IBMCLOUD_API_KEY=" + b.apiKey,
+	}
+	if p.IBMCloud.Target == "powervs" {
+		b.capxEnvVars = append(b.capxEnvVars, "PROVIDER_ID_FORMAT=v2")
+	}
+	if p.GithubToken != "" {
+		b.capxEnvVars = append(b.capxEnvVars, "GITHUB_TOKEN="+p.GithubToken)
+	}
+}
+
+func (b *IBMCloudBuilder) setSC(p ProviderParams) {
+	if (p.StorageClass.Parameters != commons.SCParameters{}) {
+		b.scParameters = p.StorageClass.Parameters
+	}
+
+	if p.IBMCloud.Target == "powervs" {
+		b.scProvisioner = "powervs.csi.ibm.com"
+
+		if b.scParameters.Type == "" {
+			if p.StorageClass.Class == "premium" {
+				b.scParameters.Type = "tier1"
+			} else {
+				b.scParameters.Type = "tier3"
+			}
+		}
+		return
+	}
+
+	b.scProvisioner = "vpc.block.csi.ibm.io"
+
+	if b.scParameters.Profile == "" {
+		if p.StorageClass.Class == "premium" {
+			b.scParameters.Profile = "10iops-tier"
+		} else {
+			b.scParameters.Profile = "general-purpose"
+		}
+	}
+
+	if p.StorageClass.EncryptionKey != "" {
+		b.scParameters.Encrypted = "true"
+		b.scParameters.EncryptionKey = p.StorageClass.EncryptionKey
+	}
+}
+
+func (b *IBMCloudBuilder) pullProviderCharts(n nodes.Node, clusterConfigSpec *commons.ClusterConfigSpec, keosSpec commons.KeosSpec, clusterCredentials commons.ClusterCredentials, clusterType string) error {
+	return pullGenericCharts(n, clusterConfigSpec, keosSpec, clusterCredentials, ibmcloudCharts, clusterType)
+}
+
+func (b *IBMCloudBuilder) getProviderCharts(clusterConfigSpec *commons.ClusterConfigSpec, keosSpec commons.KeosSpec, clusterType string) map[string]commons.ChartEntry {
+	return getGenericCharts(clusterConfigSpec, keosSpec, ibmcloudCharts, clusterType)
+}
+
+func (b *IBMCloudBuilder) getOverriddenCharts(charts *[]commons.Chart, clusterConfigSpec *commons.ClusterConfigSpec, clusterType string) []commons.Chart {
+	return getGenericOverriddenCharts(charts, clusterConfigSpec, ibmcloudCharts, clusterType)
+}
+
+func (b *IBMCloudBuilder) getProvider() Provider {
+	return Provider{
+		capxProvider:     b.capxProvider,
+		capxVersion:      b.capxVersion,
+		capxImageVersion: b.capxImageVersion,
+		capxManaged:      b.capxManaged,
+		capxName:         b.capxName,
+		capxEnvVars:      b.capxEnvVars,
+		scParameters:     b.scParameters,
+		scProvisioner:    b.scProvisioner,
+		csiNamespace:     b.csiNamespace,
+	}
+}
+
+func (b *IBMCloudBuilder) installCloudProvider(n nodes.Node, k string, privateParams PrivateParams) error {
+	var c string
+	var err error
+	var cmd exec.Cmd
+
+	// Create the ibmcloud-api-key secret, shared by the CCM and the CSI driver
+	c = "kubectl --kubeconfig " + commons.ShellQuote(k) + " -n kube-system create secret generic ibmcloud-api-key" +
+		" " + commons.ShellQuote("--from-literal=ibmcloud_api_key="+b.apiKey)
+	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to create ibmcloud-api-key secret")
+	}
+
+	cloudProviderManifests, err := b.getCloudProviderTemplate(privateParams).render()
+	if err != nil {
+		return errors.Wrap(err, "failed to get cloud provider manifests")
+	}
+
+	// Deploy cloud provider
+	cmd = n.Command("kubectl", "--kubeconfig", k, "apply", "-f", "-")
+	if err = cmd.SetStdin(strings.NewReader(cloudProviderManifests)).Run(); err != nil {
+		return errors.Wrap(err, "failed to deploy ibm-cloud-controller-manager")
+	}
+
+	return nil
+}
+
+func (b *IBMCloudBuilder) getCloudProviderTemplate(privateParams PrivateParams) manifestTemplate {
+	keosCluster := privateParams.KeosCluster
+	return manifestTemplate{b.capxProvider, "ibm-cloud-controller-manager.tmpl", "", ibmCloudProviderParams{
+		ClusterName:       keosCluster.Metadata.Name,
+		Private:           privateParams.Private,
+		KeosRegUrl:        privateParams.KeosRegUrl,
+		Region:            keosCluster.Spec.Region,
+		Target:            keosCluster.Spec.ControlPlane.IBMCloud.Target,
+		ServiceInstanceID: keosCluster.Spec.ControlPlane.IBMCloud.ServiceInstanceID,
+	}}
+}
+
+func (b *IBMCloudBuilder) installCSI(n nodes.Node, k string, privateParams PrivateParams, providerParams ProviderParams, chartsList map[string]commons.ChartEntry) error {
+	var err error
+	var cmd exec.Cmd
+
+	csiName := "ibm-vpc-block-csi-driver"
+	if providerParams.IBMCloud.Target == "powervs" {
+		csiName = "ibm-powervs-block-csi-driver"
+	} else {
+		// The VPC block CSI driver reads its credentials from the storage-secret-store secret
+		slclient := "[VPC]\n" +
+			"  iam_client_id = \"bx\"\n" +
+			"  iam_client_secret = \"bx\"\n" +
+			"  g2_token_exchange_endpoint_url = \"https://iam.cloud.ibm.com\"\n" +
+			"  g2_riaas_endpoint_url = \"https://" + providerParams.Region + ".iaas.cloud.ibm.com\"\n" +
+			"  g2_api_key = \"" + b.apiKey + "\"\n" +
+			"  provider_type = \"g2\"\n"
+		c := "kubectl --kubeconfig " + commons.ShellQuote(k) + " -n " + commons.ShellQuote(b.csiNamespace) + " create secret generic storage-secret-store " + commons.ShellQuote("--from-literal=slclient.toml="+slclient)
+		_, err = commons.ExecuteCommand(n, c, 5, 3)
+		if err != nil {
+			return errors.Wrap(err, "failed to create storage-secret-store secret")
+		}
+	}
+
+	csiManifests, err := b.getCSITemplates(privateParams)[0].render()
+	if err != nil {
+		return errors.Wrap(err, "failed to get "+csiName+" manifests")
+	}
+
+	// Deploy the CSI driver
+	cmd = n.Command("kubectl", "--kubeconfig", k, "apply", "-f", "-")
+	if err = cmd.SetStdin(strings.NewReader(csiManifests)).Run(); err != nil {
+		return errors.Wrap(err, "failed to deploy "+csiName)
+	}
+
+	return nil
+}
+
+func (b *IBMCloudBuilder) getCSITemplates(privateParams PrivateParams) []manifestTemplate {
+	csiName := "ibm-vpc-block-csi-driver"
+	if privateParams.KeosCluster.Spec.ControlPlane.IBMCloud.Target == "powervs" {
+		csiName = "ibm-powervs-block-csi-driver"
+	}
+	return []manifestTemplate{{b.capxProvider, csiName + ".tmpl", "", privateParams}}
+}
+
+func (b *IBMCloudBuilder) getRegistryCredentials(p ProviderParams, u string) (string, string, error) {
+	return "", "", errors.New("only generic registries are supported in ibmcloud clusters")
+}
+
+func (b *IBMCloudBuilder) configureStorageClass(n nodes.Node, k string) error {
+	return applyGenericStorageClass(n, k, getGenericStorageClass(b.scProvisioner, b.scParameters), nil)
+}
+
+func (b *IBMCloudBuilder) internalNginx(p ProviderParams, networks commons.Networks) (bool, error) {
+	return false, nil
+}
+
+func (b *IBMCloudBuilder) getOverrideVars(p ProviderParams, networks commons.Networks, clusterConfigSpec commons.ClusterConfigSpec) (map[string][]byte, error) {
+	var overrideVars = make(map[string][]byte)
+
+	return overrideVars, nil
+}
+
+func (b *IBMCloudBuilder) postInstallPhase(n nodes.Node, k string) error {
+	return ensureCorednsPdb(n)
+}
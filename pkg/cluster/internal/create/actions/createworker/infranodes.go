@@ -0,0 +1,124 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const (
+	// The nodes of the infra node groups are labeled and tainted, so only the system components run in them
+	infraNodeLabel = "keos.stratio.com/infra"
+	infraNodeTaint = infraNodeLabel + "=true:NoSchedule"
+)
+
+// The Deployments which must keep running in the control plane nodes
+var controlPlaneNodeLabels = []string{"node-role.kubernetes.io/control-plane", "node-role.kubernetes.io/master"}
+
+func hasInfraNodes(workerNodes commons.WorkerNodes) bool {
+	for _, wn := range workerNodes {
+		if wn.Infra {
+			return true
+		}
+	}
+	return false
+}
+
+// pinSystemComponents schedules the Deployments of the addons in the infra node groups. Only the ones
+// installed with Helm are pinned, as the addons of the managed clusters are reconciled by the provider,
+// and CoreDNS in the unmanaged clusters
+func pinSystemComponents(n nodes.Node, k string, keosCluster commons.KeosCluster, chartsList map[string]commons.ChartEntry) error {
+	namespaces := getAddonNamespaces(chartsList)
+
+	infraToleration := map[string]interface{}{"key": infraNodeLabel, "operator": "Equal", "value": "true", "effect": "NoSchedule"}
+	batch := newManifestBatch(k)
+	pinned := map[string][]string{}
+	for _, namespace := range namespaces {
+		deployments, err := getNamespaceDeployments(n, k, namespace)
+		if err != nil {
+			return err
+		}
+		for _, deployment := range deployments {
+			name := deployment.Metadata.Name
+			coreDNS := namespace == "kube-system" && name == "coredns" && !keosCluster.Spec.ControlPlane.Managed
+			if !deployment.isHelmManaged() && !coreDNS {
+				continue
+			}
+			podSpec := deployment.Spec.Template.Spec
+			nodeSelector := map[string]string{infraNodeLabel: "true"}
+			controlPlane := false
+			for key, value := range podSpec.NodeSelector {
+				controlPlane = controlPlane || commons.Contains(controlPlaneNodeLabels, key)
+				nodeSelector[key] = value
+			}
+			if controlPlane {
+				continue
+			}
+			tolerations := append([]map[string]interface{}{}, podSpec.Tolerations...)
+			tolerated := false
+			for _, toleration := range tolerations {
+				tolerated = tolerated || (toleration["key"] == infraNodeLabel || (toleration["key"] == nil && toleration["operator"] == "Exists"))
+			}
+			if !tolerated {
+				tolerations = append(tolerations, infraToleration)
+			}
+
+			// The pod spec is applied server-side, so only the scheduling fields are owned by the provisioner
+			err = batch.add(map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]string{"name": name, "namespace": namespace},
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"nodeSelector": nodeSelector,
+							"tolerations":  tolerations,
+						},
+					},
+				},
+			})
+			if err != nil {
+				return err
+			}
+			pinned[namespace] = append(pinned[namespace], name)
+		}
+	}
+
+	if err := batch.apply(n); err != nil {
+		return errors.Wrap(err, "failed to pin the Deployments to the infra nodes")
+	}
+	for _, namespace := range namespaces {
+		if err := waitForRollouts(n, k, namespace, "deployment", pinned[namespace], "5m"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// infraNodeTaints returns the taints of an infra node group, which keep the user workloads out of it
+func infraNodeTaints(taints []string) []string {
+	for _, taint := range taints {
+		if strings.HasPrefix(taint, infraNodeLabel+"=") {
+			return taints
+		}
+	}
+	return append(append([]string{}, taints...), infraNodeTaint)
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const (
+	submarinerBrokerChart     = "submariner-k8s-broker"
+	submarinerOperatorChart   = "submariner-operator"
+	submarinerBrokerNamespace = "submariner-k8s-broker"
+	submarinerBrokerClient    = "submariner-k8s-broker-client"
+	submarinerBrokerToken     = "submariner-k8s-broker-client-keos-token"
+	// The nodes of the gateway node group are labeled so the gateways only run in them
+	submarinerGatewayLabel = "submariner.io/gateway"
+)
+
+var interconnectChartEntries = map[string]commons.ChartEntry{
+	submarinerBrokerChart:   {Repository: "https://submariner-io.github.io/submariner-charts/charts", Version: "0.18.2", Namespace: submarinerBrokerNamespace, Pull: true, Reconcile: false},
+	submarinerOperatorChart: {Repository: "https://submariner-io.github.io/submariner-charts/charts", Version: "0.18.2", Namespace: "submariner-operator", Pull: true, Reconcile: false},
+}
+
+// submarinerBrokerInfo is written by the cluster hosting the broker, and read by the clusters joining it
+type submarinerBrokerInfo struct {
+	// Server is the API server of the broker cluster, without scheme
+	Server    string `json:"server"`
+	Namespace string `json:"namespace"`
+	Token     string `json:"token"`
+	// CA is the base64 encoded CA of the broker API server
+	CA string `json:"ca"`
+	// PSK is the IPsec pre-shared key of the tunnels between the clusters
+	PSK string `json:"psk"`
+}
+
+// getInterconnectCharts returns the Submariner charts deployed in the cluster
+func getInterconnectCharts(interconnect commons.Interconnect) []string {
+	if interconnect.Broker {
+		return []string{submarinerBrokerChart, submarinerOperatorChart}
+	}
+	return []string{submarinerOperatorChart}
+}
+
+// deployInterconnect joins the cluster to the Submariner broker, deploying it first if the cluster hosts it
+func deployInterconnect(n nodes.Node, k string, privateParams PrivateParams, interconnect commons.Interconnect, chartsList map[string]commons.ChartEntry) error {
+	var brokerInfo submarinerBrokerInfo
+	var err error
+	if interconnect.Broker {
+		brokerInfo, err = deploySubmarinerBroker(n, k, privateParams, interconnect, chartsList)
+		if err != nil {
+			return err
+		}
+	} else {
+		brokerInfoJSON, err := os.ReadFile(interconnect.BrokerInfo)
+		if err != nil {
+			return errors.Wrap(err, "failed to read the broker information")
+		}
+		if err = json.Unmarshal(brokerInfoJSON, &brokerInfo); err != nil {
+			return errors.Wrap(err, "failed to parse the broker information")
+		}
+	}
+
+	clusterID := interconnect.ClusterID
+	if clusterID == "" {
+		clusterID = privateParams.KeosCluster.Metadata.Name
+	}
+	// The broker credentials and the PSK are read from a Secret
+	secretValues := map[string]interface{}{
+		"ipsec": map[string]string{"psk": brokerInfo.PSK},
+		"broker": map[string]interface{}{
+			"server":    brokerInfo.Server,
+			"namespace": brokerInfo.Namespace,
+			"token":     brokerInfo.Token,
+			"ca":        brokerInfo.CA,
+			"globalnet": true,
+		},
+	}
+	helmValues := map[string]interface{}{
+		"submariner": map[string]interface{}{
+			"clusterId":        clusterID,
+			"globalCidr":       interconnect.GlobalCIDR,
+			"natEnabled":       true,
+			"serviceDiscovery": true,
+			"cableDriver":      "libreswan",
+		},
+		"serviceAccounts": map[string]interface{}{
+			"globalnet":         map[string]bool{"create": true},
+			"lighthouseAgent":   map[string]bool{"create": true},
+			"lighthouseCoreDns": map[string]bool{"create": true},
+		},
+	}
+	if privateParams.Private {
+		helmValues["operator"] = map[string]interface{}{"image": map[string]string{"repository": privateParams.KeosRegUrl + "/submariner/submariner-operator"}}
+		helmValues["submariner"].(map[string]interface{})["images"] = map[string]string{"repository": privateParams.KeosRegUrl + "/submariner"}
+	}
+	return deploySubmarinerChart(n, k, privateParams, submarinerOperatorChart, helmValues, secretValues, chartsList)
+}
+
+// deploySubmarinerBroker deploys the broker, and writes the information to join it, with a
+// new PSK for the tunnels between the clusters
+func deploySubmarinerBroker(n nodes.Node, k string, privateParams PrivateParams, interconnect commons.Interconnect, chartsList map[string]commons.ChartEntry) (submarinerBrokerInfo, error) {
+	brokerInfo := submarinerBrokerInfo{Namespace: submarinerBrokerNamespace}
+
+	helmValues := map[string]interface{}{
+		"submariner": map[string]bool{"serviceDiscovery": true},
+	}
+	err := deploySubmarinerChart(n, k, privateParams, submarinerBrokerChart, helmValues, nil, chartsList)
+	if err != nil {
+		return brokerInfo, err
+	}
+
+	// The clusters join the broker with a long-lived token of its client ServiceAccount
+	tokenSecret, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"type":       "kubernetes.io/service-account-token",
+		"metadata": map[string]interface{}{
+			"name":        submarinerBrokerToken,
+			"namespace":   submarinerBrokerNamespace,
+			"annotations": map[string]string{"kubernetes.io/service-account.name": submarinerBrokerClient},
+		},
+	})
+	if err != nil {
+		return brokerInfo, err
+	}
+	cmd := n.Command("kubectl", "--kubeconfig", k, "apply", "-f", "-")
+	if err = cmd.SetStdin(strings.NewReader(string(tokenSecret))).Run(); err != nil {
+		return brokerInfo, errors.Wrap(err, "failed to create the "+submarinerBrokerToken+" secret")
+	}
+	// The token is populated by the token controller
+	var token string
+	for i := 0; i < 30 && token == ""; i++ {
+		if i > 0 {
+			time.Sleep(2 * time.Second)
+		}
+		c := "kubectl --kubeconfig " + commons.ShellQuote(k) + " -n " + submarinerBrokerNamespace + " get secret " + submarinerBrokerToken + " -o jsonpath='{.data.token}'"
+		token, err = commons.ExecuteCommand(n, c, 5, 3)
+		if err != nil {
+			return brokerInfo, errors.Wrap(err, "failed to get the broker token")
+		}
+		token = strings.TrimSpace(token)
+	}
+	if token == "" {
+		return brokerInfo, errors.New("the broker token has not been populated")
+	}
+	tokenBytes, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return brokerInfo, errors.Wrap(err, "failed to decode the broker token")
+	}
+	brokerInfo.Token = string(tokenBytes)
+
+	c := "kubectl --kubeconfig " + commons.ShellQuote(k) + " -n " + submarinerBrokerNamespace + " get secret " + submarinerBrokerToken + " -o jsonpath='{.data.ca\\.crt}'"
+	brokerInfo.CA, err = commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return brokerInfo, errors.Wrap(err, "failed to get the broker CA")
+	}
+	brokerInfo.CA = strings.TrimSpace(brokerInfo.CA)
+
+	c = "kubectl --kubeconfig " + commons.ShellQuote(k) + " config view --raw --minify -o jsonpath='{.clusters[0].cluster.server}'"
+	server, err := commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return brokerInfo, errors.Wrap(err, "failed to get the broker API server")
+	}
+	brokerInfo.Server = strings.TrimPrefix(strings.TrimSpace(server), "https://")
+
+	psk := make([]byte, 48)
+	if _, err = rand.Read(psk); err != nil {
+		return brokerInfo, errors.Wrap(err, "failed to generate the IPsec PSK")
+	}
+	brokerInfo.PSK = base64.StdEncoding.EncodeToString(psk)
+
+	brokerInfoJSON, err := json.MarshalIndent(brokerInfo, "", "  ")
+	if err != nil {
+		return brokerInfo, err
+	}
+	if err = os.WriteFile(interconnect.BrokerInfo, brokerInfoJSON, 0600); err != nil {
+		return brokerInfo, errors.Wrap(err, "failed to write the broker information")
+	}
+	return brokerInfo, nil
+}
+
+// deploySubmarinerChart deploys a Submariner chart, whose secret values are kept in a Secret
+func deploySubmarinerChart(n nodes.Node, k string, privateParams PrivateParams, chart string, helmValues map[string]interface{}, secretValues map[string]interface{}, chartsList map[string]commons.ChartEntry) error {
+	chartEntry := chartsList[chart]
+
+	c := "kubectl --kubeconfig " + commons.ShellQuote(k) + " create namespace " + commons.ShellQuote(chartEntry.Namespace)
+	_, err := commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the "+chartEntry.Namespace+" namespace")
+	}
+
+	if secretValues != nil {
+		secretValuesYAML, err := yaml.Marshal(secretValues)
+		if err != nil {
+			return err
+		}
+		secret, err := yaml.Marshal(map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata":   map[string]string{"name": "00-" + chart + "-helm-chart-secret-values", "namespace": chartEntry.Namespace},
+			"stringData": map[string]string{"values.yaml": string(secretValuesYAML)},
+		})
+		if err != nil {
+			return err
+		}
+		cmd := n.Command("kubectl", "--kubeconfig", k, "apply", "-f", "-")
+		if err = cmd.SetStdin(strings.NewReader(string(secret))).Run(); err != nil {
+			return errors.Wrap(err, "failed to create the "+chart+" secret values")
+		}
+	}
+
+	helmValuesYAML, err := yaml.Marshal(helmValues)
+	if err != nil {
+		return err
+	}
+	err = commons.WriteFile(n, "/kind/"+chart+"-helm-values.yaml", string(helmValuesYAML))
+	if err != nil {
+		return errors.Wrap(err, "failed to create "+chart+" Helm chart values file")
+	}
+
+	helmReleaseParams := fluxHelmReleaseParams{
+		ChartRepoRef:   "keos",
+		ChartName:      chart,
+		ChartNamespace: chartEntry.Namespace,
+		ChartVersion:   chartEntry.Version,
+		SecretValues:   secretValues != nil,
+	}
+	if !privateParams.HelmPrivate {
+		helmReleaseParams.ChartRepoRef = chart
+	}
+	return configureHelmRelease(n, k, "flux2_helmrelease.tmpl", helmReleaseParams, privateParams.KeosCluster.Spec.HelmRepository)
+}
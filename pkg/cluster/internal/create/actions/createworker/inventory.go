@@ -0,0 +1,129 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+type ImageInventory struct {
+	ClusterID string           `yaml:"cluster_id"`
+	Generated string           `yaml:"generated"`
+	Images    []InventoryImage `yaml:"images"`
+}
+
+type InventoryImage struct {
+	Image      string   `yaml:"image"`
+	Digest     string   `yaml:"digest,omitempty"`
+	Namespaces []string `yaml:"namespaces"`
+}
+
+type containerStatus struct {
+	Image   string `json:"image"`
+	ImageID string `json:"imageID"`
+}
+
+// createImageInventory writes the list of container images (with their digests)
+// running in the workload cluster, so they can be scanned for compliance
+func createImageInventory(n nodes.Node, keosCluster commons.KeosCluster, kubeconfigPath string) error {
+	var podList struct {
+		Items []struct {
+			Metadata struct {
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+			Status struct {
+				InitContainerStatuses []containerStatus `json:"initContainerStatuses"`
+				ContainerStatuses     []containerStatus `json:"containerStatuses"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+
+	c := "kubectl --kubeconfig " + commons.ShellQuote(kubeconfigPath) + " get pods -A -o json 2>/dev/null"
+	output, err := commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to list the workload cluster pods")
+	}
+	err = json.Unmarshal([]byte(output), &podList)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse the workload cluster pods")
+	}
+
+	images := map[string]*InventoryImage{}
+	for _, pod := range podList.Items {
+		for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+			digest := ""
+			if i := strings.LastIndex(status.ImageID, "@"); i != -1 {
+				digest = status.ImageID[i+1:]
+			} else if strings.HasPrefix(status.ImageID, "sha256:") {
+				digest = status.ImageID
+			}
+			key := status.Image + "@" + digest
+			if _, ok := images[key]; !ok {
+				images[key] = &InventoryImage{Image: status.Image, Digest: digest}
+			}
+			if !commons.Contains(images[key].Namespaces, pod.Metadata.Namespace) {
+				images[key].Namespaces = append(images[key].Namespaces, pod.Metadata.Namespace)
+			}
+		}
+	}
+
+	inventory := ImageInventory{
+		ClusterID: keosCluster.Metadata.Name,
+		Generated: time.Now().UTC().Format(time.RFC3339),
+	}
+	for _, image := range images {
+		sort.Strings(image.Namespaces)
+		inventory.Images = append(inventory.Images, *image)
+	}
+	sort.Slice(inventory.Images, func(i, j int) bool {
+		if inventory.Images[i].Image == inventory.Images[j].Image {
+			return inventory.Images[i].Digest < inventory.Images[j].Digest
+		}
+		return inventory.Images[i].Image < inventory.Images[j].Image
+	})
+
+	inventoryYAMLData, err := yaml.Marshal(inventory)
+	if err != nil {
+		return err
+	}
+
+	// Rotate images.yaml
+	inventoryFilename := getLocalPath(keosCluster, "images.yaml")
+
+	if _, err := os.Stat(inventoryFilename); err == nil {
+		timestamp := time.Now().Format("2006-01-02@15:04:05")
+		if err := os.Rename(inventoryFilename, inventoryFilename+"."+timestamp+"~"); err != nil {
+			return err
+		}
+	}
+
+	// Write file to disk
+	if err := os.MkdirAll(filepath.Dir(inventoryFilename), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(inventoryFilename, inventoryYAMLData, 0644)
+}
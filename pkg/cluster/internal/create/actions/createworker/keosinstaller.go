@@ -27,24 +27,26 @@ import (
 
 type KEOSDescriptor struct {
 	DockerRegistry struct {
-		AuthRequired bool   `yaml:"auth_required"`
-		Type         string `yaml:"type"`
-		URL          string `yaml:"url"`
+		AuthRequired   bool   `yaml:"auth_required"`
+		Type           string `yaml:"type"`
+		URL            string `yaml:"url"`
+		CredentialsRef string `yaml:"credentials_ref,omitempty"`
 	} `yaml:"docker_registry"`
 	HelmRepository struct {
-		AuthRequired bool   `yaml:"auth_required"`
-		URL          string `yaml:"url"`
-		Type         string `yaml:"type,omitempty"`
-		User         string `yaml:"user,omitempty"`
-		Pass         string `yaml:"pass,omitempty"`
+		AuthRequired   bool   `yaml:"auth_required"`
+		URL            string `yaml:"url"`
+		Type           string `yaml:"type,omitempty"`
+		User           string `yaml:"user,omitempty"`
+		Pass           string `yaml:"pass,omitempty"`
+		CredentialsRef string `yaml:"credentials_ref,omitempty"`
 	} `yaml:"helm_repository"`
 	AWS struct {
 		Enabled bool `yaml:"enabled"`
 		EKS     bool `yaml:"eks"`
 	} `yaml:"aws,omitempty"`
 	Azure struct {
-		Enabled       bool   `yaml:"enabled"`
-		AKS           bool   `yaml:"aks"`
+		Enabled bool `yaml:"enabled"`
+		AKS     bool `yaml:"aks"`
 	} `yaml:"azure,omitempty"`
 	GCP struct {
 		Enabled bool `yaml:"enabled"`
@@ -57,7 +59,22 @@ type KEOSDescriptor struct {
 			Pool  string `yaml:"pool,omitempty"`
 		} `yaml:"calico,omitempty"`
 		ClusterID string `yaml:"cluster_id"`
-		Dns       struct {
+		DR        struct {
+			Role       string `yaml:"role"`
+			Peer       string `yaml:"peer"`
+			PeerRegion string `yaml:"peer_region"`
+			Velero     struct {
+				BackupLocation  VeleroLocation `yaml:"backup_location"`
+				RestoreLocation VeleroLocation `yaml:"restore_location"`
+			} `yaml:"velero,omitempty"`
+			DnsFailover struct {
+				Hostname        string `yaml:"hostname"`
+				PeerDomain      string `yaml:"peer_domain"`
+				HealthCheckPath string `yaml:"health_check_path,omitempty"`
+				TTL             int    `yaml:"ttl,omitempty"`
+			} `yaml:"dns_failover,omitempty"`
+		} `yaml:"dr,omitempty"`
+		Dns struct {
 			ExternalDns struct {
 				Enabled *bool `yaml:"enabled,omitempty"`
 			} `yaml:"external_dns,omitempty"`
@@ -66,6 +83,7 @@ type KEOSDescriptor struct {
 		Domain          string `yaml:"domain,omitempty"`
 		ExternalDomain  string `yaml:"external_domain,omitempty"`
 		Flavour         string `yaml:"flavour,omitempty"`
+		Version         string `yaml:"version,omitempty"`
 		K8sInstallation bool   `yaml:"k8s_installation"`
 		Storage         struct {
 			DefaultStorageClass string   `yaml:"default_storage_class,omitempty"`
@@ -80,6 +98,11 @@ type KEOSDescriptor struct {
 	}
 }
 
+type VeleroLocation struct {
+	Bucket string `yaml:"bucket"`
+	Region string `yaml:"region"`
+}
+
 type EFSConfig struct {
 	ID          string `yaml:"id"`
 	Name        string `yaml:"name"`
@@ -87,9 +110,41 @@ type EFSConfig struct {
 }
 
 func createKEOSDescriptor(keosCluster commons.KeosCluster, storageClass string) error {
+	keosYAMLData, err := getKEOSDescriptor(keosCluster, storageClass)
+	if err != nil {
+		return err
+	}
+
+	// Rotate keos.yaml
+	keosFilename := getLocalPath(keosCluster, "keos.yaml")
+
+	if _, err := os.Stat(keosFilename); err == nil {
+		timestamp := time.Now().Format("2006-01-02@15:04:05")
+		backupKeosFilename := keosFilename + "." + timestamp + "~"
+		originalKeosFilePath := filepath.Join(".", keosFilename)
+		backupKeosFilePath := filepath.Join(".", backupKeosFilename)
+
+		if err := os.Rename(originalKeosFilePath, backupKeosFilePath); err != nil {
+			return err
+		}
+	}
+
+	// Write file to disk
+	if err := os.MkdirAll(filepath.Dir(keosFilename), os.ModePerm); err != nil {
+		return err
+	}
+	err = os.WriteFile(keosFilename, []byte(keosYAMLData), 0644)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// getKEOSDescriptor renders the keos.yaml of the cluster, validated against the schema of its keos release
+func getKEOSDescriptor(keosCluster commons.KeosCluster, storageClass string) ([]byte, error) {
 
 	var keosDescriptor KEOSDescriptor
-	var err error
 
 	// External registry
 	for _, registry := range keosCluster.Spec.DockerRegistries {
@@ -97,6 +152,10 @@ func createKEOSDescriptor(keosCluster commons.KeosCluster, storageClass string)
 			keosDescriptor.DockerRegistry.URL = registry.URL
 			keosDescriptor.DockerRegistry.AuthRequired = registry.AuthRequired
 			keosDescriptor.DockerRegistry.Type = registry.Type
+			// The credentials are kept in the secrets file
+			if registry.AuthRequired && registry.Type == "generic" {
+				keosDescriptor.DockerRegistry.CredentialsRef = "secrets.docker_registry"
+			}
 		}
 	}
 
@@ -104,6 +163,9 @@ func createKEOSDescriptor(keosCluster commons.KeosCluster, storageClass string)
 	keosDescriptor.HelmRepository.URL = keosCluster.Spec.HelmRepository.URL
 	keosDescriptor.HelmRepository.AuthRequired = keosCluster.Spec.HelmRepository.AuthRequired
 	keosDescriptor.HelmRepository.Type = keosCluster.Spec.HelmRepository.Type
+	if keosCluster.Spec.HelmRepository.AuthRequired && keosCluster.Spec.HelmRepository.Type == "generic" {
+		keosDescriptor.HelmRepository.CredentialsRef = "secrets.helm_repository"
+	}
 
 	// AWS
 	if keosCluster.Spec.InfraProvider == "aws" {
@@ -125,11 +187,12 @@ func createKEOSDescriptor(keosCluster commons.KeosCluster, storageClass string)
 
 	// Keos
 	keosDescriptor.Keos.ClusterID = keosCluster.Metadata.Name
-	keosDescriptor.Keos.Domain = "cluster.local"
+	keosDescriptor.Keos.Domain = keosCluster.Spec.Networks.ClusterDomain
 	if keosCluster.Spec.ExternalDomain != "" {
 		keosDescriptor.Keos.ExternalDomain = keosCluster.Spec.ExternalDomain
 	}
 	keosDescriptor.Keos.Flavour = keosCluster.Spec.Keos.Flavour
+	keosDescriptor.Keos.Version = keosCluster.Spec.Keos.Version
 
 	// Keos - Calico
 	if !keosCluster.Spec.ControlPlane.Managed {
@@ -171,6 +234,26 @@ func createKEOSDescriptor(keosCluster commons.KeosCluster, storageClass string)
 		keosDescriptor.Keos.Storage.Providers = []string{"custom"}
 	}
 
+	// Keos - DR
+	if dr := keosCluster.Spec.DR; dr != nil {
+		keosDescriptor.Keos.DR.Role = "primary"
+		if dr.Secondary {
+			keosDescriptor.Keos.DR.Role = "secondary"
+		}
+		keosDescriptor.Keos.DR.Peer = dr.Name
+		keosDescriptor.Keos.DR.PeerRegion = dr.Region
+		if dr.Velero.Enabled {
+			keosDescriptor.Keos.DR.Velero.BackupLocation = VeleroLocation{Bucket: dr.Velero.Bucket, Region: keosCluster.Spec.Region}
+			keosDescriptor.Keos.DR.Velero.RestoreLocation = VeleroLocation{Bucket: dr.Velero.DRBucket, Region: dr.Region}
+		}
+		if dr.DNSFailover.Enabled {
+			keosDescriptor.Keos.DR.DnsFailover.Hostname = dr.DNSFailover.Hostname
+			keosDescriptor.Keos.DR.DnsFailover.PeerDomain = dr.ExternalDomain
+			keosDescriptor.Keos.DR.DnsFailover.HealthCheckPath = dr.DNSFailover.HealthCheckPath
+			keosDescriptor.Keos.DR.DnsFailover.TTL = dr.DNSFailover.TTL
+		}
+	}
+
 	// Keos - External dns
 	if !keosCluster.Spec.Dns.ManageZone {
 		keosDescriptor.Keos.Dns.ExternalDns.Enabled = &keosCluster.Spec.Dns.ManageZone
@@ -178,28 +261,10 @@ func createKEOSDescriptor(keosCluster commons.KeosCluster, storageClass string)
 
 	keosYAMLData, err := yaml.Marshal(keosDescriptor)
 	if err != nil {
-		return err
-	}
-
-	// Rotate keos.yaml
-	keosFilename := "keos.yaml"
-
-	if _, err := os.Stat(keosFilename); err == nil {
-		timestamp := time.Now().Format("2006-01-02@15:04:05")
-		backupKeosFilename := keosFilename + "." + timestamp + "~"
-		originalKeosFilePath := filepath.Join(".", keosFilename)
-		backupKeosFilePath := filepath.Join(".", backupKeosFilename)
-
-		if err := os.Rename(originalKeosFilePath, backupKeosFilePath); err != nil {
-			return err
-		}
+		return nil, err
 	}
-
-	// Write file to disk
-	err = os.WriteFile("keos.yaml", []byte(keosYAMLData), 0644)
-	if err != nil {
-		return err
+	if err := validateKEOSDescriptor(keosYAMLData, keosCluster.Spec.Keos.Version); err != nil {
+		return nil, err
 	}
-
-	return nil
+	return keosYAMLData, nil
 }
@@ -0,0 +1,189 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+//go:embed files/keos/keos-v1.schema.json
+var keosSchemaV1 []byte
+
+// The keos.yaml schema of each keos release, the latest one is used when the version is not set
+var keosSchemas = map[string][]byte{
+	"1.0": keosSchemaV1,
+	"1.1": keosSchemaV1,
+}
+
+// jsonSchema is the subset of JSON Schema used by the keos installer schemas
+type jsonSchema struct {
+	Ref                  string                 `json:"$ref"`
+	Type                 interface{}            `json:"type"`
+	Required             []string               `json:"required"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	Enum                 []interface{}          `json:"enum"`
+	Pattern              string                 `json:"pattern"`
+	MinLength            *int                   `json:"minLength"`
+	Minimum              *float64               `json:"minimum"`
+	Definitions          map[string]*jsonSchema `json:"definitions"`
+}
+
+// validateKEOSDescriptor validates the keos.yaml against the schema of the keos release, reporting
+// every invalid field
+func validateKEOSDescriptor(keosYAMLData []byte, keosVersion string) error {
+	release := ""
+	if keosVersion != "" {
+		release = strings.Join(strings.Split(keosVersion, ".")[:2], ".")
+	} else {
+		for r := range keosSchemas {
+			if r > release {
+				release = r
+			}
+		}
+	}
+	schemaData, ok := keosSchemas[release]
+	if !ok {
+		return errors.New("there is no keos.yaml schema for keos " + keosVersion)
+	}
+	var schema jsonSchema
+	if err := json.Unmarshal(schemaData, &schema); err != nil {
+		return errors.Wrap(err, "failed to parse the keos.yaml schema")
+	}
+	var keosDescriptor interface{}
+	if err := yaml.Unmarshal(keosYAMLData, &keosDescriptor); err != nil {
+		return err
+	}
+
+	fieldErrors := validateJSONSchema(&schema, &schema, keosDescriptor, "")
+	if len(fieldErrors) > 0 {
+		return errors.New("keos.yaml is invalid for keos " + release + ":\n  " + strings.Join(fieldErrors, "\n  "))
+	}
+	return nil
+}
+
+func validateJSONSchema(root *jsonSchema, schema *jsonSchema, value interface{}, path string) []string {
+	var fieldErrors []string
+	if schema.Ref != "" {
+		schema = root.Definitions[strings.TrimPrefix(schema.Ref, "#/definitions/")]
+	}
+	field := strings.TrimPrefix(path, ".")
+	if field == "" {
+		field = "(root)"
+	}
+
+	if types := schemaTypes(schema.Type); len(types) > 0 && !commons.Contains(types, jsonType(value)) {
+		return []string{field + ": Invalid type: expected " + strings.Join(types, " or ") + ", got " + jsonType(value)}
+	}
+	if len(schema.Enum) > 0 {
+		valid := false
+		for _, e := range schema.Enum {
+			if fmt.Sprint(e) == fmt.Sprint(value) {
+				valid = true
+			}
+		}
+		if !valid {
+			fieldErrors = append(fieldErrors, fmt.Sprintf("%s: Unsupported value: %q, supported values: %v", field, fmt.Sprint(value), schema.Enum))
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		if schema.MinLength != nil && len(v) < *schema.MinLength {
+			fieldErrors = append(fieldErrors, field+": Required value")
+		}
+		if schema.Pattern != "" && !regexp.MustCompile(schema.Pattern).MatchString(v) {
+			fieldErrors = append(fieldErrors, fmt.Sprintf("%s: Invalid value: %q: must match '%s'", field, v, schema.Pattern))
+		}
+	case int:
+		if schema.Minimum != nil && float64(v) < *schema.Minimum {
+			fieldErrors = append(fieldErrors, fmt.Sprintf("%s: Invalid value: %d: must be greater than or equal to %v", field, v, *schema.Minimum))
+		}
+	case []interface{}:
+		if schema.Items != nil {
+			for i, item := range v {
+				fieldErrors = append(fieldErrors, validateJSONSchema(root, schema.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case map[string]interface{}:
+		for _, required := range schema.Required {
+			if _, ok := v[required]; !ok {
+				fieldErrors = append(fieldErrors, strings.TrimPrefix(path+"."+required, ".")+": Required value")
+			}
+		}
+		var keys []string
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			propertySchema, ok := schema.Properties[key]
+			if !ok {
+				if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+					fieldErrors = append(fieldErrors, strings.TrimPrefix(path+"."+key, ".")+": Forbidden: field not supported")
+				}
+				continue
+			}
+			fieldErrors = append(fieldErrors, validateJSONSchema(root, propertySchema, v[key], path+"."+key)...)
+		}
+	}
+	return fieldErrors
+}
+
+func schemaTypes(schemaType interface{}) []string {
+	switch t := schemaType.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var types []string
+		for _, st := range t {
+			types = append(types, fmt.Sprint(st))
+		}
+		return types
+	}
+	return nil
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case int, int64, uint64:
+		return "integer"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+const (
+	clusterLockNamespace = "kube-system"
+	// The lock is renewed while the operation runs, so it expires soon after a crash
+	clusterLockDuration      = 5 * time.Minute
+	clusterLockRenewInterval = time.Minute
+	leaseTimeFormat          = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// clusterLock is a Lease held in the management cluster, so that concurrent operations over the
+// same workload cluster fail fast
+type clusterLock struct {
+	n          nodes.Node
+	logger     log.Logger
+	kubeconfig string
+	name       string
+	holder     string
+	stop       chan struct{}
+}
+
+// acquireClusterLock creates the Lease of the cluster, or takes it over if it has expired, and
+// renews it until released
+func acquireClusterLock(n nodes.Node, logger log.Logger, kubeconfig string, clusterName string) (*clusterLock, error) {
+	lock := &clusterLock{
+		n:          n,
+		logger:     logger,
+		kubeconfig: kubeconfig,
+		name:       "cloud-provisioner-" + clusterName,
+		holder:     getOperatorIdentity(),
+		stop:       make(chan struct{}),
+	}
+
+	// The creation is atomic, so only one of the concurrent operations holds the lock
+	leaseYAML, err := lock.getLease("")
+	if err != nil {
+		return nil, err
+	}
+	_, stderr, err := lock.kubectl(leaseYAML, "create", "-f", "-")
+	if err == nil {
+		go lock.renew()
+		return lock, nil
+	}
+	if !strings.Contains(stderr, "(AlreadyExists)") {
+		return nil, errors.Wrap(err, "failed to create the lock of the cluster: "+stderr)
+	}
+
+	output, stderr, err := lock.kubectl("", "-n", clusterLockNamespace, "get", "lease", lock.name, "-o",
+		"jsonpath={.spec.holderIdentity}|{.spec.acquireTime}|{.spec.renewTime}|{.spec.leaseDurationSeconds}|{.metadata.resourceVersion}")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the lock of the cluster: "+stderr)
+	}
+	fields := strings.Split(strings.TrimSpace(output), "|")
+	if len(fields) != 5 {
+		return nil, errors.New("invalid lock of the cluster: " + output)
+	}
+	renewTime, _ := time.Parse(leaseTimeFormat, fields[2])
+	duration, _ := strconv.Atoi(fields[3])
+	if time.Since(renewTime) < time.Duration(duration)*time.Second {
+		return nil, errors.New("the cluster is locked by " + fields[0] + " since " + fields[1] +
+			", delete the " + clusterLockNamespace + "/" + lock.name + " Lease if the operation is no longer running")
+	}
+
+	// The expired lock is replaced at the version it was read, so if another operation renews or takes
+	// it over in the meantime, the replacement fails with a conflict
+	leaseYAML, err = lock.getLease(fields[4])
+	if err != nil {
+		return nil, err
+	}
+	_, stderr, err = lock.kubectl(leaseYAML, "replace", "-f", "-")
+	if err != nil {
+		if strings.Contains(stderr, "(Conflict)") {
+			return nil, errors.New("the expired lock of the cluster was taken over by another operation, delete the " +
+				clusterLockNamespace + "/" + lock.name + " Lease if the operation is no longer running")
+		}
+		return nil, errors.Wrap(err, "failed to take over the expired lock of the cluster: "+stderr)
+	}
+	go lock.renew()
+	return lock, nil
+}
+
+// getLease returns the Lease held by the operation, at the resource version it replaces if any
+func (l *clusterLock) getLease(resourceVersion string) (string, error) {
+	metadata := map[string]string{"name": l.name, "namespace": clusterLockNamespace}
+	if resourceVersion != "" {
+		metadata["resourceVersion"] = resourceVersion
+	}
+	now := time.Now().UTC().Format(leaseTimeFormat)
+	lease := map[string]interface{}{
+		"apiVersion": "coordination.k8s.io/v1",
+		"kind":       "Lease",
+		"metadata":   metadata,
+		"spec": map[string]interface{}{
+			"holderIdentity":       l.holder,
+			"leaseDurationSeconds": int(clusterLockDuration.Seconds()),
+			"acquireTime":          now,
+			"renewTime":            now,
+		},
+	}
+	leaseYAML, err := yaml.Marshal(lease)
+	if err != nil {
+		return "", err
+	}
+	return string(leaseYAML), nil
+}
+
+// kubectl runs a kubectl command against the cluster holding the lock, and returns its output and
+// its error output, which tells the reason of the API errors
+func (l *clusterLock) kubectl(stdin string, args ...string) (string, string, error) {
+	if l.kubeconfig != "" {
+		args = append([]string{"--kubeconfig", l.kubeconfig}, args...)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := l.n.Command("kubectl", args...).SetStdout(&stdout).SetStderr(&stderr)
+	if stdin != "" {
+		cmd = cmd.SetStdin(strings.NewReader(stdin))
+	}
+	err := cmd.Run()
+	return stdout.String(), strings.TrimSpace(stderr.String()), err
+}
+
+// renew renews the lock as long as it is held by the operation. The patch is only applied if the
+// holder has not changed, so the lock is never renewed once another operation has taken it over
+func (l *clusterLock) renew() {
+	ticker := time.NewTicker(clusterLockRenewInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			patch, err := json.Marshal([]map[string]string{
+				{"op": "test", "path": "/spec/holderIdentity", "value": l.holder},
+				{"op": "replace", "path": "/spec/renewTime", "value": time.Now().UTC().Format(leaseTimeFormat)},
+			})
+			if err != nil {
+				l.logger.Warn("failed to renew the lock of the cluster: " + err.Error())
+				continue
+			}
+			_, stderr, err := l.kubectl("", "-n", clusterLockNamespace, "patch", "lease", l.name, "--type=json", "-p", string(patch))
+			if err != nil {
+				l.logger.Warn("failed to renew the lock of the cluster, other operations may take it over once it expires: " + stderr)
+			}
+		}
+	}
+}
+
+// release stops renewing the lock and deletes it
+func (l *clusterLock) release() error {
+	close(l.stop)
+	_, stderr, err := l.kubectl("", "-n", clusterLockNamespace, "delete", "lease", l.name, "--ignore-not-found")
+	if err != nil {
+		return errors.Wrap(err, "failed to release the lock of the cluster: "+stderr)
+	}
+	return nil
+}
+
+// getOperatorIdentity returns the user and host running the operation
+func getOperatorIdentity() string {
+	username := "unknown"
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+	hostname, _ := os.Hostname()
+	return username + "@" + hostname
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/exec"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+const sharedKubeconfig = "/kind/shared.kubeconfig"
+
+func TestClusterLock(t *testing.T) {
+	// Each operator has its own local cluster, and reaches the shared management cluster with its kubeconfig
+	shared := &leaseCluster{}
+	first := &leaseNode{local: &leaseCluster{}, clusters: map[string]*leaseCluster{sharedKubeconfig: shared}}
+	second := &leaseNode{local: &leaseCluster{}, clusters: map[string]*leaseCluster{sharedKubeconfig: shared}}
+
+	lock, err := acquireClusterLock(first, log.NoopLogger{}, sharedKubeconfig, "cluster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := acquireClusterLock(second, log.NoopLogger{}, sharedKubeconfig, "cluster"); err == nil || !strings.Contains(err.Error(), "the cluster is locked by") {
+		t.Fatalf("the second operator acquires the locked cluster: %v", err)
+	}
+	other, err := acquireClusterLock(second, log.NoopLogger{}, sharedKubeconfig, "other")
+	if err != nil {
+		t.Fatalf("the lock of a cluster blocks another cluster: %v", err)
+	}
+	if err := other.release(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lock.release(); err != nil {
+		t.Fatal(err)
+	}
+	lock, err = acquireClusterLock(second, log.NoopLogger{}, sharedKubeconfig, "cluster")
+	if err != nil {
+		t.Fatalf("the released lock cannot be acquired: %v", err)
+	}
+	if err := lock.release(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClusterLockExpired(t *testing.T) {
+	shared := &leaseCluster{}
+	first := &leaseNode{local: &leaseCluster{}, clusters: map[string]*leaseCluster{sharedKubeconfig: shared}}
+	second := &leaseNode{local: &leaseCluster{}, clusters: map[string]*leaseCluster{sharedKubeconfig: shared}}
+
+	// The first operator crashes without releasing the lock, which expires
+	if _, err := acquireClusterLock(first, log.NoopLogger{}, sharedKubeconfig, "cluster"); err != nil {
+		t.Fatal(err)
+	}
+	lease := shared.leases["cloud-provisioner-cluster"]
+	lease.Spec.RenewTime = time.Now().Add(-2 * clusterLockDuration).UTC().Format(leaseTimeFormat)
+
+	lock, err := acquireClusterLock(second, log.NoopLogger{}, sharedKubeconfig, "cluster")
+	if err != nil {
+		t.Fatalf("the expired lock is not taken over: %v", err)
+	}
+	if lease := shared.leases["cloud-provisioner-cluster"]; lease.Spec.HolderIdentity != lock.holder || lease.ResourceVersion != 2 {
+		t.Errorf("the expired lock is taken over as %+v", lease)
+	}
+	if err := lock.release(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// leaseCluster holds the Leases of a cluster, with their resource versions
+type leaseCluster struct {
+	leases map[string]*fakeLease
+}
+
+type fakeLease struct {
+	ResourceVersion int
+	Spec            struct {
+		HolderIdentity       string `yaml:"holderIdentity"`
+		LeaseDurationSeconds int    `yaml:"leaseDurationSeconds"`
+		AcquireTime          string `yaml:"acquireTime"`
+		RenewTime            string `yaml:"renewTime"`
+	} `yaml:"spec"`
+}
+
+// leaseNode is a local node whose kubectl commands manage the Leases of its local cluster, or of the
+// cluster of the kubeconfig they are given
+type leaseNode struct {
+	applyNode
+	local    *leaseCluster
+	clusters map[string]*leaseCluster
+}
+
+func (n *leaseNode) Command(command string, args ...string) exec.Cmd {
+	return &leaseCmd{node: n, args: args}
+}
+
+func (n *leaseNode) CommandContext(_ context.Context, command string, args ...string) exec.Cmd {
+	return n.Command(command, args...)
+}
+
+type leaseCmd struct {
+	node   *leaseNode
+	args   []string
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func (c *leaseCmd) Run() error {
+	cluster := c.node.local
+	args := c.args
+	if len(args) > 1 && args[0] == "--kubeconfig" {
+		cluster = c.node.clusters[args[1]]
+		args = args[2:]
+	}
+	if cluster.leases == nil {
+		cluster.leases = map[string]*fakeLease{}
+	}
+	fail := func(reason string) error {
+		_, _ = io.WriteString(c.stderr, "Error from server ("+reason+")")
+		return errors.New("exit status 1")
+	}
+
+	switch args[0] {
+	case "create", "replace":
+		var manifest bytes.Buffer
+		if _, err := manifest.ReadFrom(c.stdin); err != nil {
+			return err
+		}
+		var lease struct {
+			Metadata struct {
+				Name            string `yaml:"name"`
+				ResourceVersion string `yaml:"resourceVersion"`
+			} `yaml:"metadata"`
+			fakeLease `yaml:",inline"`
+		}
+		if err := yaml.Unmarshal(manifest.Bytes(), &lease); err != nil {
+			return err
+		}
+		current, exists := cluster.leases[lease.Metadata.Name]
+		if args[0] == "create" && exists {
+			return fail("AlreadyExists")
+		}
+		if args[0] == "replace" {
+			if !exists {
+				return fail("NotFound")
+			}
+			if lease.Metadata.ResourceVersion != strconv.Itoa(current.ResourceVersion) {
+				return fail("Conflict")
+			}
+			lease.ResourceVersion = current.ResourceVersion
+		}
+		lease.ResourceVersion++
+		cluster.leases[lease.Metadata.Name] = &lease.fakeLease
+	case "-n":
+		// -n <namespace> get|patch|delete lease <name> ...
+		name := args[4]
+		lease, exists := cluster.leases[name]
+		switch args[2] {
+		case "get":
+			if !exists {
+				return fail("NotFound")
+			}
+			_, _ = io.WriteString(c.stdout, lease.Spec.HolderIdentity+"|"+lease.Spec.AcquireTime+"|"+lease.Spec.RenewTime+"|"+
+				strconv.Itoa(lease.Spec.LeaseDurationSeconds)+"|"+strconv.Itoa(lease.ResourceVersion))
+		case "patch":
+			if !exists {
+				return fail("NotFound")
+			}
+			lease.Spec.RenewTime = time.Now().UTC().Format(leaseTimeFormat)
+			lease.ResourceVersion++
+		case "delete":
+			delete(cluster.leases, name)
+		}
+	}
+	return nil
+}
+
+func (c *leaseCmd) SetEnv(...string) exec.Cmd {
+	return c
+}
+
+func (c *leaseCmd) SetStdin(r io.Reader) exec.Cmd {
+	c.stdin = r
+	return c
+}
+
+func (c *leaseCmd) SetStdout(w io.Writer) exec.Cmd {
+	c.stdout = w
+	return c
+}
+
+func (c *leaseCmd) SetStderr(w io.Writer) exec.Cmd {
+	c.stderr = w
+	return c
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const (
+	managementAccessName = "keos-cluster-access"
+	// The namespaces of the clusters are labeled, so they can be told apart in the management cluster
+	clusterNamespaceLabel = "keos.stratio.com/cluster"
+)
+
+// The API groups of the objects of the clusters in their namespace of the management cluster
+var managementAccessAPIGroups = []string{
+	"cluster.x-k8s.io",
+	"infrastructure.cluster.x-k8s.io",
+	"controlplane.cluster.x-k8s.io",
+	"bootstrap.cluster.x-k8s.io",
+	"addons.cluster.x-k8s.io",
+	"installer.stratio.com",
+}
+
+// configureManagementAccess restricts the users and groups of the cluster to its namespace of the
+// management cluster, with a Role over the Cluster API and keos objects and, unless they are read-only,
+// the Secrets of its kubeconfigs
+func configureManagementAccess(n nodes.Node, k string, keosCluster commons.KeosCluster, namespace string, access commons.ManagementAccess) error {
+	verbs := []string{"*"}
+	if access.ReadOnly {
+		verbs = []string{"get", "list", "watch"}
+	}
+	rules := []map[string]interface{}{{
+		"apiGroups": managementAccessAPIGroups,
+		"resources": []string{"*"},
+		"verbs":     verbs,
+	}}
+	if !access.ReadOnly {
+		rules = append(rules, map[string]interface{}{
+			"apiGroups": []string{""},
+			"resources": []string{"secrets"},
+			"verbs":     []string{"get", "list", "watch"},
+		})
+	}
+
+	var subjects []map[string]string
+	for _, group := range access.Groups {
+		subjects = append(subjects, map[string]string{"apiGroup": "rbac.authorization.k8s.io", "kind": "Group", "name": group})
+	}
+	for _, user := range access.Users {
+		subjects = append(subjects, map[string]string{"apiGroup": "rbac.authorization.k8s.io", "kind": "User", "name": user})
+	}
+
+	metadata := map[string]string{"name": managementAccessName, "namespace": namespace}
+	batch := newManifestBatch(k)
+	err := batch.add(
+		map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Namespace",
+			"metadata": map[string]interface{}{
+				"name":   namespace,
+				"labels": map[string]string{clusterNamespaceLabel: keosCluster.Metadata.Name},
+			},
+		},
+		map[string]interface{}{
+			"apiVersion": "rbac.authorization.k8s.io/v1",
+			"kind":       "Role",
+			"metadata":   metadata,
+			"rules":      rules,
+		},
+		map[string]interface{}{
+			"apiVersion": "rbac.authorization.k8s.io/v1",
+			"kind":       "RoleBinding",
+			"metadata":   metadata,
+			"roleRef":    map[string]string{"apiGroup": "rbac.authorization.k8s.io", "kind": "Role", "name": managementAccessName},
+			"subjects":   subjects,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if err = batch.apply(n); err != nil {
+		return errors.Wrap(err, "failed to create the "+managementAccessName+" Role of "+namespace)
+	}
+	return nil
+}
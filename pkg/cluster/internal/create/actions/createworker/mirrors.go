@@ -0,0 +1,79 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+)
+
+const (
+	registryMirrorName = "keos-registry-mirror"
+	registryImage      = "registry"
+	registryImageTag   = "2.8.1"
+)
+
+// GetContainerdMirrorsPatch returns the containerd config patch setting the registry mirrors
+func GetContainerdMirrorsPatch(registryMirrors []commons.RegistryMirror) []string {
+	var patches []string
+	for _, mirror := range registryMirrors {
+		patches = append(patches, "[plugins.\"io.containerd.grpc.v1.cri\".registry.mirrors.\""+mirror.Registry+"\"]\n"+
+			"  endpoint = [\""+strings.Join(mirror.Endpoints, "\", \"")+"\"]\n")
+	}
+	return patches
+}
+
+func getRegistryImage(privateParams PrivateParams) string {
+	if privateParams.Private {
+		return privateParams.KeosRegUrl + "/" + registryImage
+	}
+	return registryImage
+}
+
+// configureContainerdMirrors writes the hosts.toml of the mirrored registries in every node of the
+// workload cluster, as containerd reads them on each pull
+func configureContainerdMirrors(n nodes.Node, k string, privateParams PrivateParams, registryMirrors []commons.RegistryMirror) error {
+	var script []string
+	var env []map[string]string
+	for i, mirror := range registryMirrors {
+		server := "https://" + mirror.Registry
+		if mirror.Registry == "docker.io" {
+			server = "https://registry-1.docker.io"
+		}
+		hostsTOML := "server = \"" + server + "\"\n"
+		for _, endpoint := range mirror.Endpoints {
+			hostsTOML += "\n[host.\"" + endpoint + "\"]\n  capabilities = [\"pull\", \"resolve\"]\n"
+		}
+		envName := "HOSTS_TOML_" + strconv.Itoa(i)
+		env = append(env, map[string]string{"name": envName, "value": hostsTOML})
+		script = append(script, "mkdir -p /certs.d/"+commons.ShellQuote(mirror.Registry)+" && printf '%s' \"$HOSTS_TOML_"+strconv.Itoa(i)+"\" > /certs.d/"+commons.ShellQuote(mirror.Registry)+"/hosts.toml")
+	}
+
+	return deployNodeConfigs(n, k, privateParams, nodeConfig{
+		name: registryMirrorName,
+		initContainer: map[string]interface{}{
+			"name":         "hosts",
+			"command":      []string{"/bin/sh", "-c", strings.Join(script, " && ")},
+			"env":          env,
+			"volumeMounts": []map[string]string{{"name": "certs-d", "mountPath": "/certs.d"}},
+		},
+		volumes: []map[string]interface{}{{"name": "certs-d", "hostPath": map[string]string{"path": "/etc/containerd/certs.d", "type": "DirectoryOrCreate"}}},
+	})
+}
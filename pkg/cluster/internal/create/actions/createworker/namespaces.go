@@ -0,0 +1,98 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const tenantResourceName = "keos-tenant"
+
+// createNamespaces creates the namespaces of the tenants with their ResourceQuota, LimitRange
+// and the RoleBindings of their teams
+func createNamespaces(n nodes.Node, k string, namespaces []commons.TenantNamespace) error {
+	for _, ns := range namespaces {
+		resources := []interface{}{
+			map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Namespace",
+				"metadata":   map[string]interface{}{"name": ns.Name, "labels": ns.Labels},
+			},
+		}
+		if len(ns.ResourceQuota) > 0 {
+			resources = append(resources, map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ResourceQuota",
+				"metadata":   map[string]string{"name": tenantResourceName, "namespace": ns.Name},
+				"spec":       map[string]interface{}{"hard": ns.ResourceQuota},
+			})
+		}
+		if ns.LimitRange != nil {
+			limit := map[string]interface{}{"type": "Container"}
+			if len(ns.LimitRange.DefaultRequest) > 0 {
+				limit["defaultRequest"] = ns.LimitRange.DefaultRequest
+			}
+			if len(ns.LimitRange.Default) > 0 {
+				limit["default"] = ns.LimitRange.Default
+			}
+			if len(ns.LimitRange.Max) > 0 {
+				limit["max"] = ns.LimitRange.Max
+			}
+			resources = append(resources, map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "LimitRange",
+				"metadata":   map[string]string{"name": tenantResourceName, "namespace": ns.Name},
+				"spec":       map[string]interface{}{"limits": []interface{}{limit}},
+			})
+		}
+		for _, rb := range ns.RoleBindings {
+			var subjects []map[string]string
+			for _, group := range rb.Groups {
+				subjects = append(subjects, map[string]string{"apiGroup": "rbac.authorization.k8s.io", "kind": "Group", "name": group})
+			}
+			for _, user := range rb.Users {
+				subjects = append(subjects, map[string]string{"apiGroup": "rbac.authorization.k8s.io", "kind": "User", "name": user})
+			}
+			resources = append(resources, map[string]interface{}{
+				"apiVersion": "rbac.authorization.k8s.io/v1",
+				"kind":       "RoleBinding",
+				"metadata":   map[string]string{"name": tenantResourceName + "-" + rb.ClusterRole, "namespace": ns.Name},
+				"roleRef":    map[string]string{"apiGroup": "rbac.authorization.k8s.io", "kind": "ClusterRole", "name": rb.ClusterRole},
+				"subjects":   subjects,
+			})
+		}
+
+		var manifests []string
+		for _, resource := range resources {
+			resourceYAML, err := yaml.Marshal(resource)
+			if err != nil {
+				return err
+			}
+			manifests = append(manifests, string(resourceYAML))
+		}
+		cmd := n.Command("kubectl", "--kubeconfig", k, "apply", "-f", "-")
+		if err := cmd.SetStdin(strings.NewReader(strings.Join(manifests, "---\n"))).Run(); err != nil {
+			return errors.Wrap(err, "failed to create the "+ns.Name+" namespace")
+		}
+	}
+	return nil
+}
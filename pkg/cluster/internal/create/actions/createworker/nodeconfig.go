@@ -0,0 +1,82 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+// nodeConfig configures the nodes of the workload cluster with the init container of a DaemonSet,
+// which keeps running so the nodes joining later are configured too
+type nodeConfig struct {
+	name          string
+	nodeSelector  map[string]string
+	hostPID       bool
+	initContainer map[string]interface{}
+	volumes       []map[string]interface{}
+}
+
+// deployNodeConfigs deploys the DaemonSets of the node configs with a single apply, and waits for
+// all of them in a single exec, so the node groups do not add a round-trip each
+func deployNodeConfigs(n nodes.Node, k string, privateParams PrivateParams, configs ...nodeConfig) error {
+	// The registry image is used as it ships a shell
+	image := getRegistryImage(privateParams) + ":" + registryImageTag
+	batch := newManifestBatch(k)
+	var names []string
+	for _, config := range configs {
+		config.initContainer["image"] = image
+		labels := map[string]string{"app": config.name}
+		podSpec := map[string]interface{}{
+			"hostPID":        config.hostPID,
+			"initContainers": []map[string]interface{}{config.initContainer},
+			"containers": []map[string]interface{}{{
+				"name":      "pause",
+				"image":     image,
+				"command":   []string{"/bin/sh", "-c", "trap : TERM INT; sleep infinity & wait"},
+				"resources": map[string]interface{}{"requests": map[string]string{"cpu": "1m", "memory": "8Mi"}},
+			}},
+			"volumes":     config.volumes,
+			"tolerations": []map[string]string{{"operator": "Exists"}},
+		}
+		if len(config.nodeSelector) > 0 {
+			podSpec["nodeSelector"] = config.nodeSelector
+		}
+		daemonSet := map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "DaemonSet",
+			"metadata":   map[string]interface{}{"name": config.name, "namespace": "kube-system"},
+			"spec": map[string]interface{}{
+				"selector": map[string]interface{}{"matchLabels": labels},
+				"template": map[string]interface{}{
+					"metadata": map[string]interface{}{"labels": labels},
+					"spec":     podSpec,
+				},
+			},
+		}
+		if err := batch.add(daemonSet); err != nil {
+			return err
+		}
+		names = append(names, config.name)
+	}
+	if err := batch.apply(n); err != nil {
+		return errors.Wrap(err, "failed to create the "+strings.Join(names, ", ")+" DaemonSets")
+	}
+	return waitForRollouts(n, k, "kube-system", "ds", names, "5m")
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const nodeLocalDNSDefaultIP = "169.254.20.10"
+
+type nodeLocalDNSParams struct {
+	Private    bool
+	KeosRegUrl string
+	LocalIP    string
+	DNSServer  string
+	// ClusterDomain is forwarded to CoreDNS along with the reverse zones
+	ClusterDomain string
+}
+
+// deployNodeLocalDNS deploys the NodeLocal DNSCache. It listens on the kube-dns Service IP too, so
+// the pods keep the kubelet clusterDNS and every query goes through CoreDNS and its custom configuration
+func deployNodeLocalDNS(n nodes.Node, k string, privateParams PrivateParams, nodeLocalDNS commons.NodeLocalDNS) error {
+	params := nodeLocalDNSParams{
+		Private:       privateParams.Private,
+		KeosRegUrl:    privateParams.KeosRegUrl,
+		LocalIP:       nodeLocalDNS.LocalIP,
+		ClusterDomain: privateParams.KeosCluster.Spec.Networks.ClusterDomain,
+	}
+	if params.LocalIP == "" {
+		params.LocalIP = nodeLocalDNSDefaultIP
+	}
+
+	c := "kubectl --kubeconfig " + commons.ShellQuote(k) + " -n kube-system get svc kube-dns -o jsonpath='{.spec.clusterIP}'"
+	dnsServer, err := commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the kube-dns Service IP")
+	}
+	params.DNSServer = strings.TrimSpace(dnsServer)
+
+	nodeLocalDNSManifest, err := getManifest("common", "nodelocaldns.tmpl", "", params)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the NodeLocal DNSCache manifest")
+	}
+	cmd := n.Command("kubectl", "--kubeconfig", k, "apply", "-f", "-")
+	if err = cmd.SetStdin(strings.NewReader(nodeLocalDNSManifest)).Run(); err != nil {
+		return errors.Wrap(err, "failed to apply the NodeLocal DNSCache manifest")
+	}
+
+	c = "kubectl --kubeconfig " + commons.ShellQuote(k) + " -n kube-system rollout status ds node-local-dns --timeout=5m"
+	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to wait for the NodeLocal DNSCache DaemonSet")
+	}
+	return nil
+}
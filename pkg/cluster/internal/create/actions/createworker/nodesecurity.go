@@ -0,0 +1,86 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+)
+
+const nodeSecurityName = "keos-node-security"
+
+// The settings are persisted in the node, so they are kept across reboots
+var nodeSecurityScript = strings.Join([]string{
+	`if [ -n "$KERNEL_MODULES" ]; then for m in $KERNEL_MODULES; do modprobe $m; done; printf '%s\n' $KERNEL_MODULES > /etc/modules-load.d/keos.conf; fi`,
+	`if [ -n "$SYSCTLS" ]; then printf '%s' "$SYSCTLS" > /etc/sysctl.d/90-keos.conf && sysctl -p /etc/sysctl.d/90-keos.conf; fi`,
+	`if [ -n "$SELINUX" ] && [ -f /etc/selinux/config ]; then sed -i "s/^SELINUX=.*/SELINUX=$SELINUX/" /etc/selinux/config; if [ "$SELINUX" = enforcing ]; then setenforce 1; else setenforce 0 || true; fi; fi`,
+}, " && ")
+
+func hasNodeSecurity(workerNodes commons.WorkerNodes) bool {
+	for _, wn := range workerNodes {
+		if wn.NodeSecurity != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// configureNodeSecurity sets the kernel modules, sysctls and SELinux mode of the nodes of the node
+// groups with a node security config, which are selected by their labels
+func configureNodeSecurity(n nodes.Node, k string, privateParams PrivateParams, workerNodes commons.WorkerNodes) error {
+	var configs []nodeConfig
+	for _, wn := range workerNodes {
+		if wn.NodeSecurity != nil {
+			configs = append(configs, getNodeSecurityConfig(wn.Name, wn.Labels, *wn.NodeSecurity))
+		}
+	}
+	return deployNodeConfigs(n, k, privateParams, configs...)
+}
+
+func getNodeSecurityConfig(nodeGroup string, nodeLabels map[string]string, nodeSecurity commons.NodeSecurity) nodeConfig {
+	var keys []string
+	for key := range nodeSecurity.Sysctls {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	sysctls := ""
+	for _, key := range keys {
+		sysctls += key + " = " + nodeSecurity.Sysctls[key] + "\n"
+	}
+	env := []map[string]string{
+		{"name": "KERNEL_MODULES", "value": strings.Join(nodeSecurity.KernelModules, " ")},
+		{"name": "SYSCTLS", "value": sysctls},
+		{"name": "SELINUX", "value": nodeSecurity.SELinux},
+	}
+
+	return nodeConfig{
+		name:         nodeSecurityName + "-" + nodeGroup,
+		nodeSelector: nodeLabels,
+		hostPID:      true,
+		initContainer: map[string]interface{}{
+			"name":            "node-security",
+			"command":         []string{"chroot", "/host", "sh", "-c", nodeSecurityScript},
+			"env":             env,
+			"securityContext": map[string]bool{"privileged": true},
+			"volumeMounts":    []map[string]string{{"name": "host", "mountPath": "/host"}},
+		},
+		volumes: []map[string]interface{}{{"name": "host", "hostPath": map[string]string{"path": "/"}}},
+	}
+}
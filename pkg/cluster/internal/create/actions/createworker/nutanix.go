@@ -0,0 +1,290 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+type NutanixBuilder struct {
+	capxProvider     string
+	capxVersion      string
+	capxImageVersion string
+	capxManaged      bool
+	capxName         string
+	capxEnvVars      []string
+	scParameters     commons.SCParameters
+	scProvisioner    string
+	csiNamespace     string
+	endpoint         string
+	port             string
+	username         string
+	password         string
+}
+
+type nutanixCloudControllerHelmParams struct {
+	ClusterName string
+	Private     bool
+	KeosRegUrl  string
+	Endpoint    string
+	Port        string
+}
+
+const nutanixDefaultPort = "9440"
+
+var nutanixCharts = ChartsDictionary{
+	Charts: map[string]map[string]map[string]commons.ChartEntry{
+		"28": {
+			"managed": {},
+			"unmanaged": {
+				"nutanix-cloud-provider": {Repository: "https://nutanix.github.io/helm", Version: "0.4.1", Namespace: "kube-system", Pull: true},
+				"nutanix-csi-storage":    {Repository: "https://nutanix.github.io/helm", Version: "3.0.0", Namespace: "ntnx-system", Pull: false, Reconcile: true},
+				"cluster-autoscaler":     {Repository: "https://kubernetes.github.io/autoscaler", Version: "9.34.1", Namespace: "kube-system", Pull: false, Reconcile: false},
+				"tigera-operator":        {Repository: "https://docs.projectcalico.org/charts", Version: "v3.28.2", Namespace: "tigera-operator", Pull: true, Reconcile: true},
+			},
+		},
+		"29": {
+			"managed": {},
+			"unmanaged": {
+				"nutanix-cloud-provider": {Repository: "https://nutanix.github.io/helm", Version: "0.4.1", Namespace: "kube-system", Pull: true},
+				"nutanix-csi-storage":    {Repository: "https://nutanix.github.io/helm", Version: "3.0.0", Namespace: "ntnx-system", Pull: false, Reconcile: true},
+				"cluster-autoscaler":     {Repository: "https://kubernetes.github.io/autoscaler", Version: "9.35.0", Namespace: "kube-system", Pull: false, Reconcile: false},
+				"tigera-operator":        {Repository: "https://docs.projectcalico.org/charts", Version: "v3.28.2", Namespace: "tigera-operator", Pull: true, Reconcile: true},
+			},
+		},
+		"30": {
+			"managed": {},
+			"unmanaged": {
+				"nutanix-cloud-provider": {Repository: "https://nutanix.github.io/helm", Version: "0.4.1", Namespace: "kube-system", Pull: true},
+				"nutanix-csi-storage":    {Repository: "https://nutanix.github.io/helm", Version: "3.0.0", Namespace: "ntnx-system", Pull: false, Reconcile: true},
+				"cluster-autoscaler":     {Repository: "https://kubernetes.github.io/autoscaler", Version: "9.37.0", Namespace: "kube-system", Pull: false, Reconcile: false},
+				"tigera-operator":        {Repository: "https://docs.projectcalico.org/charts", Version: "v3.28.2", Namespace: "tigera-operator", Pull: true, Reconcile: true},
+			},
+		},
+	},
+}
+
+func newNutanixBuilder() *NutanixBuilder {
+	return &NutanixBuilder{}
+}
+
+func (b *NutanixBuilder) setCapx(managed bool) {
+	b.capxProvider = "nutanix"
+	b.capxVersion = "v1.4.0"
+	b.capxImageVersion = "v1.4.0"
+	b.capxName = "capx"
+	b.capxManaged = managed
+	b.csiNamespace = "ntnx-system"
+}
+
+func (b *NutanixBuilder) setCapxEnvVars(p ProviderParams) {
+	host, port, err := net.SplitHostPort(p.Credentials["Endpoint"])
+	if err != nil {
+		host, port = p.Credentials["Endpoint"], nutanixDefaultPort
+	}
+	b.endpoint = host
+	b.port = port
+	b.username = p.Credentials["Username"]
+	b.password = p.Credentials["Password"]
+	b.capxEnvVars = []string{
+		"NUTANIX_ENDPOINT=" + b.endpoint,
+		"NUTANIX_PORT=" + b.port,
+		"NUTANIX_USER=" + b.username,
+		"NUTANIX_PASSWORD=" + b.password,
+		"NUTANIX_INSECURE=false",
+	}
+	if p.GithubToken != "" {
+		b.capxEnvVars = append(b.capxEnvVars, "GITHUB_TOKEN="+p.GithubToken)
+	}
+}
+
+func (b *NutanixBuilder) setSC(p ProviderParams) {
+	if (p.StorageClass.Parameters != commons.SCParameters{}) {
+		b.scParameters = p.StorageClass.Parameters
+	}
+
+	b.scProvisioner = "csi.nutanix.com"
+
+	if b.scParameters.StorageType == "" {
+		b.scParameters.StorageType = "NutanixVolumes"
+	}
+	// Premium volumes are pinned to the SSD tier of the storage container
+	if b.scParameters.FlashMode == "" && p.StorageClass.Class == "premium" {
+		b.scParameters.FlashMode = "ENABLED"
+	}
+}
+
+func (b *NutanixBuilder) pullProviderCharts(n nodes.Node, clusterConfigSpec *commons.ClusterConfigSpec, keosSpec commons.KeosSpec, clusterCredentials commons.ClusterCredentials, clusterType string) error {
+	return pullGenericCharts(n, clusterConfigSpec, keosSpec, clusterCredentials, nutanixCharts, clusterType)
+}
+
+func (b *NutanixBuilder) getProviderCharts(clusterConfigSpec *commons.ClusterConfigSpec, keosSpec commons.KeosSpec, clusterType string) map[string]commons.ChartEntry {
+	return getGenericCharts(clusterConfigSpec, keosSpec, nutanixCharts, clusterType)
+}
+
+func (b *NutanixBuilder) getOverriddenCharts(charts *[]commons.Chart, clusterConfigSpec *commons.ClusterConfigSpec, clusterType string) []commons.Chart {
+	return getGenericOverriddenCharts(charts, clusterConfigSpec, nutanixCharts, clusterType)
+}
+
+func (b *NutanixBuilder) getProvider() Provider {
+	return Provider{
+		capxProvider:     b.capxProvider,
+		capxVersion:      b.capxVersion,
+		capxImageVersion: b.capxImageVersion,
+		capxManaged:      b.capxManaged,
+		capxName:         b.capxName,
+		capxEnvVars:      b.capxEnvVars,
+		scParameters:     b.scParameters,
+		scProvisioner:    b.scProvisioner,
+		csiNamespace:     b.csiNamespace,
+	}
+}
+
+func (b *NutanixBuilder) installCloudProvider(n nodes.Node, k string, privateParams PrivateParams) error {
+	// Create the Prism Central credentials secret
+	credentials, err := json.Marshal([]map[string]interface{}{{
+		"type": "basic_auth",
+		"data": map[string]interface{}{
+			"prismCentral": map[string]string{
+				"username": b.username,
+				"password": b.password,
+			},
+		},
+	}})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal Prism Central credentials")
+	}
+	c := "kubectl --kubeconfig " + commons.ShellQuote(k) + " -n kube-system create secret generic nutanix-creds " + commons.ShellQuote("--from-literal=credentials="+string(credentials))
+	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to create nutanix-creds secret")
+	}
+
+	cloudControllerManagerValuesFile := "/kind/nutanix-cloud-provider-helm-values.yaml"
+
+	// Generate the CCM helm values
+	cloudControllerManagerHelmValues, err := b.getCloudProviderTemplate(privateParams).render()
+	if err != nil {
+		return errors.Wrap(err, "failed to create cloud controller manager Helm chart values file")
+	}
+	err = commons.WriteFile(n, cloudControllerManagerValuesFile, cloudControllerManagerHelmValues)
+	if err != nil {
+		return errors.Wrap(err, "failed to create cloud controller manager Helm chart values file")
+	}
+
+	c = "helm install nutanix-cloud-provider /stratio/helm/nutanix-cloud-provider" +
+		" --kubeconfig " + commons.ShellQuote(k) +
+		" --namespace kube-system" +
+		" --values " + commons.ShellQuote(cloudControllerManagerValuesFile)
+	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to deploy nutanix-cloud-provider Helm Chart")
+	}
+
+	return nil
+}
+
+func (b *NutanixBuilder) getCloudProviderTemplate(privateParams PrivateParams) manifestTemplate {
+	return manifestTemplate{b.capxProvider, "nutanix-cloud-provider-helm-values.tmpl", majorVersion, nutanixCloudControllerHelmParams{
+		ClusterName: privateParams.KeosCluster.Metadata.Name,
+		Private:     privateParams.Private,
+		KeosRegUrl:  privateParams.KeosRegUrl,
+		Endpoint:    b.endpoint,
+		Port:        b.port,
+	}}
+}
+
+func (b *NutanixBuilder) installCSI(n nodes.Node, k string, privateParams PrivateParams, providerParams ProviderParams, chartsList map[string]commons.ChartEntry) error {
+	csiName := "nutanix-csi-storage"
+	csiValuesFile := "/kind/" + csiName + "-helm-values.yaml"
+	csiEntry := chartsList[csiName]
+	csiHelmReleaseParams := fluxHelmReleaseParams{
+		ChartRepoRef:   "keos",
+		ChartName:      csiName,
+		ChartNamespace: csiEntry.Namespace,
+		ChartVersion:   csiEntry.Version,
+	}
+	if !privateParams.HelmPrivate {
+		csiHelmReleaseParams.ChartRepoRef = csiName
+	}
+
+	// Create the CSI namespace and the Prism Central secret used by the CSI driver and the storage class
+	c := "kubectl --kubeconfig " + commons.ShellQuote(k) + " create namespace " + commons.ShellQuote(b.csiNamespace)
+	_, err := commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to create "+b.csiNamespace+" namespace")
+	}
+	key := strings.Join([]string{b.endpoint, b.port, b.username, b.password}, ":")
+	c = "kubectl --kubeconfig " + commons.ShellQuote(k) + " -n " + commons.ShellQuote(b.csiNamespace) + " create secret generic ntnx-pc-secret " + commons.ShellQuote("--from-literal=key="+key)
+	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to create ntnx-pc-secret secret")
+	}
+
+	// Generate the nutanix-csi-storage helm values
+	csiHelmValues, getManifestErr := b.getCSITemplates(privateParams)[0].render()
+	if getManifestErr != nil {
+		return errors.Wrap(getManifestErr, "failed to generate "+csiName+" helm values")
+	}
+
+	err = commons.WriteFile(n, csiValuesFile, csiHelmValues)
+	if err != nil {
+		return errors.Wrap(err, "failed to create "+csiName+" Helm chart values file")
+	}
+	if err := configureHelmRelease(n, kubeconfigPath, "flux2_helmrelease.tmpl", csiHelmReleaseParams, privateParams.KeosCluster.Spec.HelmRepository); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (b *NutanixBuilder) getCSITemplates(privateParams PrivateParams) []manifestTemplate {
+	return []manifestTemplate{{b.capxProvider, "nutanix-csi-storage-helm-values.tmpl", majorVersion, privateParams}}
+}
+
+func (b *NutanixBuilder) getRegistryCredentials(p ProviderParams, u string) (string, string, error) {
+	return "", "", errors.New("only generic registries are supported in nutanix clusters")
+}
+
+func (b *NutanixBuilder) configureStorageClass(n nodes.Node, k string) error {
+	// The CSI driver authenticates against Prism Central with the secret referenced in the storage class
+	secretParameters := map[string]string{}
+	for _, secretRef := range []string{"provisioner", "node-publish", "controller-expand"} {
+		secretParameters["csi.storage.k8s.io/"+secretRef+"-secret-name"] = "ntnx-pc-secret"
+		secretParameters["csi.storage.k8s.io/"+secretRef+"-secret-namespace"] = b.csiNamespace
+	}
+	return applyGenericStorageClass(n, k, getGenericStorageClass(b.scProvisioner, b.scParameters), secretParameters)
+}
+
+func (b *NutanixBuilder) internalNginx(p ProviderParams, networks commons.Networks) (bool, error) {
+	return false, nil
+}
+
+func (b *NutanixBuilder) getOverrideVars(p ProviderParams, networks commons.Networks, clusterConfigSpec commons.ClusterConfigSpec) (map[string][]byte, error) {
+	var overrideVars = make(map[string][]byte)
+
+	return overrideVars, nil
+}
+
+func (b *NutanixBuilder) postInstallPhase(n nodes.Node, k string) error {
+	return ensureCorednsPdb(n)
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+// operationReporter reports the asynchronous operation run by the process, with the tail of its
+// log, to the management cluster where the follow command reads it: the local cluster, until the
+// workload cluster holds the lock of the operation
+type operationReporter struct {
+	n           nodes.Node
+	logger      log.Logger
+	operation   commons.Operation
+	clusterName string
+	mu          sync.Mutex
+	kubeconfig  string
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+// startOperationReporter reports the operation as running until it finishes
+func startOperationReporter(n nodes.Node, logger log.Logger, operationID string, clusterName string) (*operationReporter, error) {
+	operation, err := commons.GetOperation(operationID)
+	if err != nil {
+		return nil, err
+	}
+	r := &operationReporter{
+		n:           n,
+		logger:      logger,
+		operation:   operation,
+		clusterName: clusterName,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go r.run()
+	return r, nil
+}
+
+func (r *operationReporter) run() {
+	defer close(r.done)
+	ticker := time.NewTicker(commons.OperationReportInterval)
+	defer ticker.Stop()
+	for {
+		if err := r.report(commons.OperationRunning, nil); err != nil {
+			r.logger.Warn("failed to report the operation to the management cluster: " + err.Error())
+		}
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// setKubeconfig reports the operation to the cluster of the kubeconfig from now on
+func (r *operationReporter) setKubeconfig(kubeconfig string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.kubeconfig = kubeconfig
+}
+
+// report applies the ConfigMap with the status of the operation
+func (r *operationReporter) report(status string, operationErr error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	operationStatus, err := r.operation.GetStatus(status, operationErr)
+	if err != nil {
+		return err
+	}
+	configMapYAML, err := r.operation.GetStatusManifest(r.clusterName, operationStatus)
+	if err != nil {
+		return err
+	}
+	// The log is too large for the last-applied annotation of a client-side apply
+	args := []string{"apply", "--server-side", "--force-conflicts", "-f", "-"}
+	if r.kubeconfig != "" {
+		args = append([]string{"--kubeconfig", r.kubeconfig}, args...)
+	}
+	var stderr bytes.Buffer
+	err = r.n.Command("kubectl", args...).SetStdin(strings.NewReader(configMapYAML)).SetStderr(&stderr).Run()
+	if err != nil {
+		return errors.Wrap(err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// finish stops reporting the operation as running, and reports its result
+func (r *operationReporter) finish(operationErr error) {
+	close(r.stop)
+	<-r.done
+	status := commons.OperationSucceeded
+	if operationErr != nil {
+		status = commons.OperationFailed
+	}
+	if err := r.report(status, operationErr); err != nil {
+		r.logger.Warn("failed to report the result of the operation to the management cluster: " + err.Error())
+	}
+}
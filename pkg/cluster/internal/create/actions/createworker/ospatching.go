@@ -0,0 +1,246 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const osPatchingDefaultDuration = 3
+
+// enrollOSPatching creates the weekly maintenance window which patches the nodes of the cluster.
+// The nodes are targeted by the ownership tag of CAPx, so the nodes created afterwards are enrolled too
+func enrollOSPatching(infraProvider string, p ProviderParams, osPatching commons.OSPatching) error {
+	if osPatching.Duration == 0 {
+		osPatching.Duration = osPatchingDefaultDuration
+	}
+	switch infraProvider {
+	case "aws":
+		return enrollSSMPatchManager(p, osPatching)
+	case "azure":
+		return enrollAzureUpdateManager(p, osPatching)
+	}
+	return errors.New("OS patching is not supported in " + infraProvider + " clusters")
+}
+
+func enrollSSMPatchManager(p ProviderParams, osPatching commons.OSPatching) error {
+	var ctx = context.Background()
+	var windows struct {
+		WindowIdentities []struct {
+			WindowId string
+		}
+	}
+	var window struct {
+		WindowId string
+	}
+	var target struct {
+		WindowTargetId string
+	}
+
+	client, err := commons.NewAWSClient(ctx, p.Credentials, p.Region, "")
+	if err != nil {
+		return err
+	}
+	ssmRequest := func(action string, body interface{}, response interface{}) error {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Post(ctx, "ssm", "application/x-amz-json-1.1", "AmazonSSM."+action, string(jsonBody))
+		if err != nil {
+			return errors.Wrap(err, "failed to run "+action)
+		}
+		return json.Unmarshal(resp, response)
+	}
+
+	windowName := "keos-" + p.ClusterName + "-patching"
+	err = ssmRequest("DescribeMaintenanceWindows", map[string]interface{}{
+		"Filters": []map[string]interface{}{{"Key": "Name", "Values": []string{windowName}}},
+	}, &windows)
+	if err != nil {
+		return err
+	}
+	if len(windows.WindowIdentities) > 0 {
+		return nil
+	}
+
+	hour, minute, _ := strings.Cut(osPatching.Time, ":")
+	err = ssmRequest("CreateMaintenanceWindow", map[string]interface{}{
+		"Name":                     windowName,
+		"Schedule":                 "cron(" + minute + " " + hour + " ? * " + strings.ToUpper(osPatching.Day[:3]) + " *)",
+		"ScheduleTimezone":         "UTC",
+		"Duration":                 osPatching.Duration,
+		"Cutoff":                   1,
+		"AllowUnassociatedTargets": false,
+		"Tags":                     []map[string]string{{"Key": "keos.stratio.com/cluster", "Value": p.ClusterName}},
+	}, &window)
+	if err != nil {
+		return err
+	}
+
+	err = ssmRequest("RegisterTargetWithMaintenanceWindow", map[string]interface{}{
+		"WindowId":     window.WindowId,
+		"Name":         "keos-nodes",
+		"ResourceType": "INSTANCE",
+		"Targets": []map[string]interface{}{
+			{"Key": "tag:sigs.k8s.io/cluster-api-provider-aws/cluster/" + p.ClusterName, "Values": []string{"owned"}},
+		},
+	}, &target)
+	if err != nil {
+		return err
+	}
+
+	rebootOption := "NoReboot"
+	if osPatching.Reboot {
+		rebootOption = "RebootIfNeeded"
+	}
+	// The nodes are patched one by one, so the workloads can be rescheduled
+	return ssmRequest("RegisterTaskWithMaintenanceWindow", map[string]interface{}{
+		"WindowId":       window.WindowId,
+		"Name":           "keos-patch-baseline",
+		"TaskArn":        "AWS-RunPatchBaseline",
+		"TaskType":       "RUN_COMMAND",
+		"Priority":       1,
+		"MaxConcurrency": "1",
+		"MaxErrors":      "1",
+		"Targets":        []map[string]interface{}{{"Key": "WindowTargetIds", "Values": []string{target.WindowTargetId}}},
+		"TaskInvocationParameters": map[string]interface{}{
+			"RunCommand": map[string]interface{}{
+				"Parameters": map[string][]string{"Operation": {"Install"}, "RebootOption": {rebootOption}},
+			},
+		},
+	}, &map[string]interface{}{})
+}
+
+func enrollAzureUpdateManager(p ProviderParams, osPatching commons.OSPatching) error {
+	var ctx = context.Background()
+	var vms struct {
+		Value []struct {
+			ID string `json:"id"`
+		} `json:"value"`
+	}
+
+	cfg, err := commons.AzureGetConfig(p.Credentials)
+	if err != nil {
+		return err
+	}
+	token, err := cfg.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"https://management.azure.com/.default"}})
+	if err != nil {
+		return errors.Wrap(err, "failed to get Azure token")
+	}
+	azureRequest := func(method string, resourceURL string, body interface{}) ([]byte, error) {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequest(method, resourceURL, bytes.NewReader(jsonBody))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token.Token)
+		req.Header.Set("Content-Type", "application/json")
+		return commons.DoCloudRequest(req)
+	}
+
+	subscriptionURL := "https://management.azure.com/subscriptions/" + p.Credentials["SubscriptionID"]
+	// The resource group of the cluster is named after it
+	configurationID := "/subscriptions/" + p.Credentials["SubscriptionID"] + "/resourceGroups/" + p.ClusterName +
+		"/providers/Microsoft.Maintenance/maintenanceConfigurations/keos-" + p.ClusterName + "-patching"
+	ownershipTag := "sigs.k8s.io_cluster-api-provider-azure_cluster_" + p.ClusterName
+
+	rebootSetting := "NeverReboot"
+	if osPatching.Reboot {
+		rebootSetting = "IfRequired"
+	}
+	_, err = azureRequest(http.MethodPut, "https://management.azure.com"+configurationID+"?api-version=2023-04-01", map[string]interface{}{
+		"location": p.Region,
+		"properties": map[string]interface{}{
+			"maintenanceScope":    "InGuestPatch",
+			"extensionProperties": map[string]string{"InGuestPatchMode": "User"},
+			"maintenanceWindow": map[string]string{
+				"startDateTime": "2024-01-01 " + osPatching.Time,
+				"duration":      "0" + strconv.Itoa(osPatching.Duration) + ":00",
+				"timeZone":      "UTC",
+				"recurEvery":    "Week " + osPatching.Day,
+			},
+			"installPatches": map[string]interface{}{
+				"rebootSetting":   rebootSetting,
+				"linuxParameters": map[string][]string{"classificationsToInclude": {"Critical", "Security"}},
+			},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to create the maintenance configuration")
+	}
+
+	// The dynamic scope enrolls the nodes created afterwards
+	_, err = azureRequest(http.MethodPut, subscriptionURL+"/providers/Microsoft.Maintenance/configurationAssignments/keos-"+p.ClusterName+"-patching?api-version=2023-04-01", map[string]interface{}{
+		"properties": map[string]interface{}{
+			"maintenanceConfigurationId": configurationID,
+			"filter": map[string]interface{}{
+				"resourceTypes":  []string{"Microsoft.Compute/virtualMachines"},
+				"resourceGroups": []string{p.ClusterName},
+				"osTypes":        []string{"Linux"},
+				"tagSettings": map[string]interface{}{
+					"tags":           map[string][]string{ownershipTag: {"owned"}},
+					"filterOperator": "All",
+				},
+			},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to assign the maintenance configuration")
+	}
+
+	// The scheduled patching requires the platform patch mode in the virtual machines
+	filter := "resourceType eq 'Microsoft.Compute/virtualMachines' and tagName eq '" + ownershipTag + "' and tagValue eq 'owned'"
+	resp, err := azureRequest(http.MethodGet, subscriptionURL+"/resources?api-version=2021-04-01&$filter="+url.QueryEscape(filter), nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to list the virtual machines")
+	}
+	if err = json.Unmarshal(resp, &vms); err != nil {
+		return err
+	}
+	for _, vm := range vms.Value {
+		_, err = azureRequest(http.MethodPatch, "https://management.azure.com"+vm.ID+"?api-version=2023-03-01", map[string]interface{}{
+			"properties": map[string]interface{}{
+				"osProfile": map[string]interface{}{
+					"linuxConfiguration": map[string]interface{}{
+						"patchSettings": map[string]interface{}{
+							"patchMode":                   "AutomaticByPlatform",
+							"automaticByPlatformSettings": map[string]bool{"bypassPlatformSafetyChecksOnUserSchedule": true},
+						},
+					},
+				},
+			},
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to set the patch mode of "+vm.ID)
+		}
+	}
+	return nil
+}
@@ -26,13 +26,12 @@ import (
 	"sigs.k8s.io/kind/pkg/errors"
 )
 
-func override_vars(ctx *actions.ActionContext, p ProviderParams, networks commons.Networks, infra *Infra, clusterConfigSpec commons.ClusterConfigSpec) error {
+func override_vars(ctx *actions.ActionContext, p ProviderParams, networks commons.Networks, infra *Infra, clusterConfigSpec commons.ClusterConfigSpec, overrideVarsDir string) error {
 
 	override_vars, err := infra.getOverrideVars(p, networks, clusterConfigSpec)
 	if err != nil {
 		return err
 	}
-	overrideVarsDir := "override_vars"
 
 	if len(override_vars) > 0 {
 		ctx.Status.Start("Rotating and generating override_vars structure ⚒️")
@@ -0,0 +1,136 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const (
+	kyvernoChart         = "kyverno"
+	kyvernoPoliciesChart = "kyverno-policies"
+	gatekeeperChart      = "gatekeeper"
+	policiesPath         = "/kind/policies"
+)
+
+var policyEngineChartEntries = map[string]commons.ChartEntry{
+	kyvernoChart:         {Repository: "https://kyverno.github.io/kyverno", Version: "3.1.4", Namespace: "kyverno", Pull: true, Reconcile: false},
+	kyvernoPoliciesChart: {Repository: "https://kyverno.github.io/kyverno", Version: "3.1.4", Namespace: "kyverno", Pull: true, Reconcile: false},
+	gatekeeperChart:      {Repository: "https://open-policy-agent.github.io/gatekeeper/charts", Version: "3.14.0", Namespace: "gatekeeper-system", Pull: true, Reconcile: false},
+}
+
+// getPolicyEngineCharts returns the charts of the policy engine, along with the Pod Security Standards
+// policies of Kyverno when no policy bundle is set
+func getPolicyEngineCharts(policyEngine commons.PolicyEngine) []string {
+	if policyEngine.Engine == gatekeeperChart {
+		return []string{gatekeeperChart}
+	}
+	if len(policyEngine.Policies) == 0 {
+		return []string{kyvernoChart, kyvernoPoliciesChart}
+	}
+	return []string{kyvernoChart}
+}
+
+// deployPolicyEngine deploys Kyverno or Gatekeeper and applies the baseline policy bundle
+func deployPolicyEngine(n nodes.Node, k string, privateParams PrivateParams, policyEngine commons.PolicyEngine, chartsList map[string]commons.ChartEntry) error {
+	charts := getPolicyEngineCharts(policyEngine)
+
+	c := "kubectl --kubeconfig " + commons.ShellQuote(k) + " create namespace " + commons.ShellQuote(chartsList[charts[0]].Namespace)
+	_, err := commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the "+chartsList[charts[0]].Namespace+" namespace")
+	}
+
+	for _, chart := range charts {
+		helmValues := map[string]interface{}{}
+		switch chart {
+		case kyvernoChart:
+			if privateParams.Private {
+				helmValues["global"] = map[string]interface{}{"image": map[string]string{"registry": privateParams.KeosRegUrl}}
+			}
+		case kyvernoPoliciesChart:
+			helmValues["podSecurityStandard"] = "baseline"
+			helmValues["validationFailureAction"] = "Audit"
+		case gatekeeperChart:
+			if privateParams.Private {
+				helmValues["image"] = map[string]string{
+					"repository":    privateParams.KeosRegUrl + "/openpolicyagent/gatekeeper",
+					"crdRepository": privateParams.KeosRegUrl + "/openpolicyagent/gatekeeper-crds",
+				}
+			}
+		}
+		helmValuesYAML, err := yaml.Marshal(helmValues)
+		if err != nil {
+			return err
+		}
+		err = commons.WriteFile(n, "/kind/"+chart+"-helm-values.yaml", string(helmValuesYAML))
+		if err != nil {
+			return errors.Wrap(err, "failed to create "+chart+" Helm chart values file")
+		}
+
+		chartEntry := chartsList[chart]
+		helmReleaseParams := fluxHelmReleaseParams{
+			ChartRepoRef:   "keos",
+			ChartName:      chart,
+			ChartNamespace: chartEntry.Namespace,
+			ChartVersion:   chartEntry.Version,
+		}
+		if !privateParams.HelmPrivate {
+			helmReleaseParams.ChartRepoRef = chart
+		}
+		if err := configureHelmRelease(n, k, "flux2_helmrelease.tmpl", helmReleaseParams, privateParams.KeosCluster.Spec.HelmRepository); err != nil {
+			return err
+		}
+	}
+
+	c = "mkdir -p " + policiesPath
+	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the policies directory")
+	}
+	for _, source := range policyEngine.Policies {
+		manifests, err := readClusterResourceSetSource(source)
+		if err != nil {
+			return errors.Wrap(err, "failed to read "+source)
+		}
+		var names []string
+		for name := range manifests {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			policyPath := policiesPath + "/" + invalidConfigMapKey.ReplaceAllString(name, "-")
+			cmd := n.Command("sh", "-c", "cat > "+commons.ShellQuote(policyPath))
+			if err = cmd.SetStdin(strings.NewReader(manifests[name])).Run(); err != nil {
+				return errors.Wrap(err, "failed to write the "+name+" policies")
+			}
+			// The constraints of Gatekeeper can only be applied once their templates are established
+			c = "kubectl --kubeconfig " + commons.ShellQuote(k) + " apply -f " + commons.ShellQuote(policyPath)
+			_, err = commons.ExecuteCommand(n, c, 10, 5)
+			if err != nil {
+				return errors.Wrap(err, "failed to apply the "+name+" policies")
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const (
+	imagePreloadName    = "keos-image-preload"
+	imagePreloadTimeout = 15 * time.Minute
+)
+
+// preloadImages pulls the images in every node of the workload cluster with a short-lived
+// DaemonSet, whose containers are only created to have the images pulled
+func preloadImages(n nodes.Node, kubeconfigPath string, images []string) error {
+	var containers []map[string]interface{}
+	for i, image := range images {
+		containers = append(containers, map[string]interface{}{
+			"name":            "image-" + strconv.Itoa(i),
+			"image":           image,
+			"imagePullPolicy": "IfNotPresent",
+			"command":         []string{"/bin/sh", "-c", "exit 0"},
+			"resources":       map[string]interface{}{"requests": map[string]string{"cpu": "1m", "memory": "1Mi"}},
+		})
+	}
+	labels := map[string]string{"app": imagePreloadName}
+	daemonSet := map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "DaemonSet",
+		"metadata":   map[string]interface{}{"name": imagePreloadName, "namespace": "kube-system"},
+		"spec": map[string]interface{}{
+			"selector": map[string]interface{}{"matchLabels": labels},
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": labels},
+				"spec": map[string]interface{}{
+					"containers":  containers,
+					"tolerations": []map[string]string{{"operator": "Exists"}},
+				},
+			},
+		},
+	}
+	daemonSetYAML, err := yaml.Marshal(daemonSet)
+	if err != nil {
+		return err
+	}
+	cmd := n.Command("kubectl", "--kubeconfig", kubeconfigPath, "apply", "-f", "-")
+	if err = cmd.SetStdin(strings.NewReader(string(daemonSetYAML))).Run(); err != nil {
+		return errors.Wrap(err, "failed to create the "+imagePreloadName+" DaemonSet")
+	}
+
+	// The images are pulled once all the containers have an image ID, whether they run or not
+	c := "kubectl --kubeconfig " + commons.ShellQuote(kubeconfigPath) + " -n kube-system get ds " + imagePreloadName + " -o jsonpath='{.status.desiredNumberScheduled}' && echo && " +
+		"kubectl --kubeconfig " + commons.ShellQuote(kubeconfigPath) + " -n kube-system get pods -l app=" + imagePreloadName +
+		" -o jsonpath='{range .items[*]}{range .status.containerStatuses[*]}{.imageID}{\" \"}{end}{\"\\n\"}{end}'"
+	pulled := false
+	for deadline := time.Now().Add(imagePreloadTimeout); time.Now().Before(deadline); time.Sleep(10 * time.Second) {
+		output, err := commons.ExecuteCommand(n, c, 5, 3)
+		if err != nil {
+			return errors.Wrap(err, "failed to get the "+imagePreloadName+" pods")
+		}
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		desired, _ := strconv.Atoi(strings.TrimSpace(lines[0]))
+		pods := 0
+		for _, line := range lines[1:] {
+			if len(strings.Fields(line)) == len(images) {
+				pods++
+			}
+		}
+		if desired > 0 && pods == desired {
+			pulled = true
+			break
+		}
+	}
+
+	c = "kubectl --kubeconfig " + commons.ShellQuote(kubeconfigPath) + " -n kube-system delete ds " + imagePreloadName
+	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete the "+imagePreloadName+" DaemonSet")
+	}
+	if !pulled {
+		return errors.New("timed out waiting for the images to be pulled")
+	}
+	return nil
+}
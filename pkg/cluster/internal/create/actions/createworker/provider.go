@@ -23,9 +23,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"time"
 
@@ -62,13 +64,20 @@ const (
 	CAPIControlPlaneProvider = "kubeadm"
 	CAPIVersion              = "v1.7.4"
 
-	scName = "keos"
+	// The RKE2 and K3s providers compatible with CAPIVersion
+	CAPIRKE2Version = "v0.5.0"
+	CAPIK3sVersion  = "v0.2.1"
+
+	scName                   = "keos"
+	storageClassOverrideFile = "storageclass.yaml"
 
 	postInstallAnnotation = "cluster-autoscaler.kubernetes.io/safe-to-evict-local-volumes"
 	corednsPdbPath        = "/kind/coredns_pdb.yaml"
 
 	machineHealthCheckWorkerNodePath       = "/kind/manifests/machinehealthcheckworkernode.yaml"
 	machineHealthCheckControlPlaneNodePath = "/kind/manifests/machinehealthcheckcontrolplane.yaml"
+	defaultNodeStartupTimeout              = "300s"
+	defaultUnhealthyTimeout                = "180s"
 	defaultScAnnotation                    = "storageclass.kubernetes.io/is-default-class"
 )
 
@@ -94,7 +103,9 @@ type PBuilder interface {
 	getProviderCharts(clusterConfigSpec *commons.ClusterConfigSpec, keosSpec commons.KeosSpec, clusterType string) map[string]commons.ChartEntry
 	getOverriddenCharts(charts *[]commons.Chart, clusterConfigSpec *commons.ClusterConfigSpec, clusterType string) []commons.Chart
 	installCloudProvider(n nodes.Node, k string, privateParams PrivateParams) error
+	getCloudProviderTemplate(privateParams PrivateParams) manifestTemplate
 	installCSI(n nodes.Node, k string, privateParams PrivateParams, providerParams ProviderParams, chartsList map[string]commons.ChartEntry) error
+	getCSITemplates(privateParams PrivateParams) []manifestTemplate
 	getProvider() Provider
 	configureStorageClass(n nodes.Node, k string) error
 	internalNginx(p ProviderParams, networks commons.Networks) (bool, error)
@@ -110,6 +121,10 @@ type Provider struct {
 	capxManaged      bool
 	capxName         string
 	capxEnvVars      []string
+	capxExtraArgs    []string
+	// bootstrap and control plane providers, as provider:version
+	capiBootstrap    string
+	capiControlPlane string
 	scParameters     commons.SCParameters
 	scProvisioner    string
 	csiNamespace     string
@@ -123,7 +138,8 @@ type Node struct {
 }
 
 type Infra struct {
-	builder PBuilder
+	builder      PBuilder
+	storageClass commons.StorageClass
 }
 
 type ProviderParams struct {
@@ -133,6 +149,10 @@ type ProviderParams struct {
 	Credentials  map[string]string
 	GithubToken  string
 	StorageClass commons.StorageClass
+	IBMCloud     commons.IBMCloudCP
+	CAPXConfig   commons.CAPXConfig
+	// ControlPlaneFlavor is the kubeadm, rke2 or k3s flavor of unmanaged clusters
+	ControlPlaneFlavor string
 }
 
 type DefaultStorageClass struct {
@@ -188,6 +208,9 @@ type fluxHelmReleaseParams struct {
 	ChartNamespace string
 	ChartRepoRef   string
 	ChartVersion   string
+	// SecretValues also reads the values of the 00-<chart>-helm-chart-secret-values Secret, so the
+	// credentials of the chart are not kept in ConfigMaps
+	SecretValues bool
 }
 
 var scTemplate = DefaultStorageClass{
@@ -253,6 +276,30 @@ func getBuilder(builderType string) PBuilder {
 	if builderType == "azure" {
 		return newAzureBuilder()
 	}
+
+	if builderType == "equinix" {
+		return newEquinixBuilder()
+	}
+
+	if builderType == "hetzner" {
+		return newHetznerBuilder()
+	}
+
+	if builderType == "nutanix" {
+		return newNutanixBuilder()
+	}
+
+	if builderType == "digitalocean" {
+		return newDigitalOceanBuilder()
+	}
+
+	if builderType == "ibmcloud" {
+		return newIBMCloudBuilder()
+	}
+
+	if builderType == "alibabacloud" {
+		return newAlibabaCloudBuilder()
+	}
 	return nil
 }
 
@@ -266,7 +313,81 @@ func (i *Infra) buildProvider(p ProviderParams) Provider {
 	i.builder.setCapx(p.Managed)
 	i.builder.setCapxEnvVars(p)
 	i.builder.setSC(p)
-	return i.builder.getProvider()
+	i.storageClass = p.StorageClass
+	scTemplate.Metadata.Name = getStorageClassName(p.StorageClass)
+	scTemplate.Metadata.Annotations = nil
+	if isDefaultStorageClass(p.StorageClass) {
+		scTemplate.Metadata.Annotations = map[string]string{defaultScAnnotation: "true"}
+	}
+	provider := i.builder.getProvider()
+	provider.capxEnvVars = overrideEnvVars(provider.capxEnvVars, getFeatureGatesVariables(p.CAPXConfig.FeatureGates))
+	provider.capxEnvVars = overrideEnvVars(provider.capxEnvVars, p.CAPXConfig.Variables)
+	provider.capxExtraArgs = p.CAPXConfig.ExtraArgs
+	provider.capiBootstrap, provider.capiControlPlane = getCAPIFlavorProviders(p.ControlPlaneFlavor)
+	return provider
+}
+
+// getCAPIFlavorProviders returns the bootstrap and control plane providers of the flavor
+func getCAPIFlavorProviders(flavor string) (string, string) {
+	switch flavor {
+	case "rke2":
+		return "rke2:" + CAPIRKE2Version, "rke2:" + CAPIRKE2Version
+	case "k3s":
+		return "k3s:" + CAPIK3sVersion, "k3s:" + CAPIK3sVersion
+	}
+	return CAPIBootstrapProvider + ":" + CAPIVersion, CAPIControlPlaneProvider + ":" + CAPIVersion
+}
+
+// getCAPIFlavorProviderEntries returns the clusterctl config entries of the providers of the flavor
+// which clusterctl does not know about
+func getCAPIFlavorProviderEntries(flavor string) []commons.CAPIProvider {
+	if flavor != "k3s" {
+		return nil
+	}
+	releaseURL := "https://github.com/k3s-io/cluster-api-k3s/releases/" + CAPIK3sVersion
+	return []commons.CAPIProvider{
+		{Name: "k3s", Type: "BootstrapProvider", URL: releaseURL + "/bootstrap-components.yaml"},
+		{Name: "k3s", Type: "ControlPlaneProvider", URL: releaseURL + "/control-plane-components.yaml"},
+	}
+}
+
+// getFeatureGatesVariables returns the clusterctl variables enabling or disabling the feature gates set
+func getFeatureGatesVariables(featureGates commons.CAPIFeatureGates) map[string]string {
+	variables := map[string]string{}
+	for name, enabled := range map[string]*bool{
+		"EXP_MACHINE_POOL":                 featureGates.MachinePool,
+		"EXP_CLUSTER_RESOURCE_SET":         featureGates.ClusterResourceSet,
+		"CLUSTER_TOPOLOGY":                 featureGates.ClusterTopology,
+		"EXP_MACHINE_SET_PREFLIGHT_CHECKS": featureGates.MachineSetPreflightChecks,
+	} {
+		if enabled != nil {
+			variables[name] = strconv.FormatBool(*enabled)
+		}
+	}
+	return variables
+}
+
+// overrideEnvVars sets the given variables, keeping the position of the existing ones
+func overrideEnvVars(envVars []string, variables map[string]string) []string {
+	overridden := append([]string{}, envVars...)
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		found := false
+		for i, envVar := range overridden {
+			if strings.HasPrefix(envVar, name+"=") {
+				overridden[i] = name + "=" + variables[name]
+				found = true
+			}
+		}
+		if !found {
+			overridden = append(overridden, name+"="+variables[name])
+		}
+	}
+	return overridden
 }
 
 func (i *Infra) getProviderCharts(clusterConfigSpec *commons.ClusterConfigSpec, keosSpec commons.KeosSpec) map[string]commons.ChartEntry {
@@ -343,9 +464,37 @@ func getGenericCharts(clusterConfigSpec *commons.ClusterConfigSpec, keosSpec com
 		if !reflect.DeepEqual(chart, commons.ChartEntry{}) {
 
 			chart.Version = overrideChart.Version
+			chart.Digest = overrideChart.Digest
 			chartsToInstall[overrideChart.Name] = chart
 		}
 	}
+	if clusterConfigSpec.RegistryCache != nil {
+		chartsToInstall[registryCacheChart] = registryCacheChartEntry
+	}
+	if clusterConfigSpec.ExternalSecrets != nil {
+		chartsToInstall[externalSecretsChart] = externalSecretsChartEntry
+	}
+	if clusterConfigSpec.PolicyEngine != nil {
+		for _, chart := range getPolicyEngineCharts(*clusterConfigSpec.PolicyEngine) {
+			chartsToInstall[chart] = policyEngineChartEntries[chart]
+		}
+	}
+	if hasGPUNodes(keosSpec) {
+		chartsToInstall[gpuOperatorChart] = gpuOperatorChartEntry
+	}
+	if clusterConfigSpec.CostAllocation != nil {
+		chartsToInstall[openCostChart] = openCostChartEntry
+	}
+	if clusterConfigSpec.SpotTermination != nil {
+		if chart := getSpotTerminationChart(keosSpec.InfraProvider); chart != "" {
+			chartsToInstall[chart] = spotTerminationChartEntries[chart]
+		}
+	}
+	if clusterConfigSpec.Interconnect != nil {
+		for _, chart := range getInterconnectCharts(*clusterConfigSpec.Interconnect) {
+			chartsToInstall[chart] = interconnectChartEntries[chart]
+		}
+	}
 	if clusterConfigSpec.PrivateHelmRepo {
 		for name, entry := range chartsToInstall {
 			entry.Repository = keosSpec.HelmRepository.URL
@@ -360,18 +509,161 @@ func pullGenericCharts(n nodes.Node, clusterConfigSpec *commons.ClusterConfigSpe
 	return pullCharts(n, chartsToInstall, keosSpec, clusterCredentials)
 }
 
+// getGenericOverriddenCharts appends the charts pulled from the dictionary, with the versions of the
+// cluster config
+func getGenericOverriddenCharts(charts *[]commons.Chart, clusterConfigSpec *commons.ClusterConfigSpec, chartDictionary ChartsDictionary, clusterType string) []commons.Chart {
+	providerCharts := *ConvertToChart(chartDictionary.Charts[majorVersion][clusterType])
+	for _, ovChart := range clusterConfigSpec.Charts {
+		for i := range providerCharts {
+			if providerCharts[i].Name == ovChart.Name {
+				providerCharts[i].Version = ovChart.Version
+			}
+		}
+	}
+	*charts = append(*charts, providerCharts...)
+	return *charts
+}
+
+// getGenericStorageClass returns the default StorageClass of the provisioner, without modifying the
+// template shared by the providers
+func getGenericStorageClass(provisioner string, parameters commons.SCParameters) DefaultStorageClass {
+	storageClass := scTemplate
+	storageClass.Provisioner = provisioner
+	storageClass.Parameters = parameters
+	return storageClass
+}
+
+// applyGenericStorageClass applies the StorageClass along with the extra parameters which are not
+// set in the descriptor, such as the references to the secrets of the CSI driver
+func applyGenericStorageClass(n nodes.Node, k string, storageClass DefaultStorageClass, extraParameters map[string]string) error {
+	scBytes, err := yaml.Marshal(storageClass)
+	if err != nil {
+		return err
+	}
+	if len(extraParameters) > 0 {
+		var sc map[string]interface{}
+		if err = yaml.Unmarshal(scBytes, &sc); err != nil {
+			return err
+		}
+		scParams, _ := sc["parameters"].(map[string]interface{})
+		if scParams == nil {
+			scParams = map[string]interface{}{}
+			sc["parameters"] = scParams
+		}
+		for name, value := range extraParameters {
+			scParams[name] = value
+		}
+		if scBytes, err = yaml.Marshal(sc); err != nil {
+			return err
+		}
+	}
+	manifest := strings.Replace(string(scBytes), "fsType", "csi.storage.k8s.io/fstype", -1)
+
+	cmd := n.Command("kubectl", "--kubeconfig", k, "apply", "-f", "-")
+	if err = cmd.SetStdin(strings.NewReader(manifest)).Run(); err != nil {
+		return errors.Wrap(err, "failed to create default storage class")
+	}
+	return nil
+}
+
+// ensureCorednsPdb installs the PodDisruptionBudget of CoreDNS, unless the cluster already has one
+func ensureCorednsPdb(n nodes.Node) error {
+	c := "kubectl --kubeconfig " + kubeconfigPath + " get pdb coredns -n kube-system"
+	_, err := commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		err = installCorednsPdb(n)
+		if err != nil {
+			return errors.Wrap(err, "failed to add core dns PDB")
+		}
+	}
+	return nil
+}
+
+// getCloudControllerHelmParams returns the params of the Helm values of the cloud controller managers
+func getCloudControllerHelmParams(privateParams PrivateParams) cloudControllerHelmParams {
+	podsCidrBlock := privateParams.KeosCluster.Spec.Networks.PodsCidrBlock
+	if podsCidrBlock == "" {
+		podsCidrBlock = "192.168.0.0/16"
+	}
+	return cloudControllerHelmParams{
+		ClusterName: privateParams.KeosCluster.Metadata.Name,
+		Private:     privateParams.Private,
+		KeosRegUrl:  privateParams.KeosRegUrl,
+		PodsCidr:    podsCidrBlock,
+	}
+}
+
 func (i *Infra) installCloudProvider(n nodes.Node, k string, privateParams PrivateParams) error {
 	return i.builder.installCloudProvider(n, k, privateParams)
 }
 
+func (i *Infra) getCloudProviderTemplate(privateParams PrivateParams) manifestTemplate {
+	return i.builder.getCloudProviderTemplate(privateParams)
+}
+
 func (i *Infra) installCSI(n nodes.Node, k string, privateParams PrivateParams, providerParams ProviderParams, chartsList map[string]commons.ChartEntry) error {
 	return i.builder.installCSI(n, k, privateParams, providerParams, chartsList)
 }
 
+func (i *Infra) getCSITemplates(privateParams PrivateParams) []manifestTemplate {
+	return i.builder.getCSITemplates(privateParams)
+}
+
 func (i *Infra) configureStorageClass(n nodes.Node, k string) error {
+	// A user StorageClass manifest replaces the one of the provider
+	if overridePath := getTemplateOverride(filepath.Join(i.builder.getProvider().capxProvider, storageClassOverrideFile)); overridePath != "" {
+		storageClass, err := os.ReadFile(overridePath)
+		if err != nil {
+			return err
+		}
+		cmd := n.Command("kubectl", "--kubeconfig", k, "apply", "-f", "-")
+		if err = cmd.SetStdin(bytes.NewReader(storageClass)).Run(); err != nil {
+			return errors.Wrap(err, "failed to create default storage class")
+		}
+		return nil
+	}
+	if isDefaultStorageClass(i.storageClass) {
+		if err := unsetDefaultStorageClasses(n, k, getStorageClassName(i.storageClass)); err != nil {
+			return err
+		}
+	}
 	return i.builder.configureStorageClass(n, k)
 }
 
+// getStorageClassName returns the name of the StorageClass of the descriptor
+func getStorageClassName(storageClass commons.StorageClass) string {
+	if storageClass.Name != "" {
+		return storageClass.Name
+	}
+	return scName
+}
+
+// isDefaultStorageClass returns whether the StorageClass of the descriptor is the default one, as it is unless disabled
+func isDefaultStorageClass(storageClass commons.StorageClass) bool {
+	return storageClass.Default == nil || *storageClass.Default
+}
+
+// unsetDefaultStorageClasses removes the default annotation from the StorageClasses other than the
+// one given, like the gp2 of EKS, so the cluster is not left with several default ones
+func unsetDefaultStorageClasses(n nodes.Node, k string, name string) error {
+	c := "kubectl --kubeconfig " + commons.ShellQuote(k) + ` get sc -o jsonpath='{range .items[?(@.metadata.annotations.storageclass\.kubernetes\.io/is-default-class=="true")]}{.metadata.name}{"\n"}{end}'`
+	output, err := commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to get default storage class")
+	}
+	for _, defaultSC := range strings.Fields(output) {
+		if defaultSC == name {
+			continue
+		}
+		c = "kubectl --kubeconfig " + commons.ShellQuote(k) + " annotate sc " + commons.ShellQuote(defaultSC) + " " + defaultScAnnotation + "-"
+		_, err = commons.ExecuteCommand(n, c, 5, 3)
+		if err != nil {
+			return errors.Wrap(err, "failed to remove annotation from default storage class "+defaultSC)
+		}
+	}
+	return nil
+}
+
 func (i *Infra) internalNginx(p ProviderParams, networks commons.Networks) (bool, error) {
 	return i.builder.internalNginx(p, networks)
 }
@@ -443,17 +735,16 @@ func (p *Provider) deployCertManager(n nodes.Node, keosRegistryUrl string, kubec
 		return errors.Wrap(err, "failed to generate cert-manager helm values")
 	}
 
-	c := "echo '" + certManagerHelmValues + "' > " + certManagerValuesFile
-	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	err = commons.WriteFile(n, certManagerValuesFile, certManagerHelmValues)
 	if err != nil {
 		return errors.Wrap(err, "failed to create cert-manager Helm chart values file")
 	}
-	c = "helm install --wait cert-manager /stratio/helm/cert-manager" +
+	c := "helm install --wait cert-manager /stratio/helm/cert-manager" +
 		" --namespace=cert-manager" +
 		" --create-namespace" +
-		" --values " + certManagerValuesFile
+		" --values " + commons.ShellQuote(certManagerValuesFile)
 	if kubeconfigPath != "" {
-		c = c + " --kubeconfig " + kubeconfigPath
+		c = c + " --kubeconfig " + commons.ShellQuote(kubeconfigPath)
 	}
 	_, err = commons.ExecuteCommand(n, c, 5, 3)
 	if err != nil {
@@ -491,15 +782,12 @@ func (p *Provider) deployClusterOperator(n nodes.Node, privateParams PrivatePara
 		if err != nil {
 			return errors.Wrap(err, "failed to create aws config file")
 		}
-		c = "echo [default] > ~/.aws/config && " +
-			"echo region = " + keosCluster.Spec.Region + " >>  ~/.aws/config"
-		_, err = commons.ExecuteCommand(n, c, 5, 3)
+		err = commons.WriteFile(n, "~/.aws/config", "[default]\nregion = "+keosCluster.Spec.Region+"\n")
 		if err != nil {
 			return errors.Wrap(err, "failed to create aws config file")
 		}
-		awsCredentials := "[default]\naws_access_key_id = " + clusterCredentials.ProviderCredentials["AccessKey"] + "\naws_secret_access_key = " + clusterCredentials.ProviderCredentials["SecretKey"] + "\n"
-		c = "echo '" + awsCredentials + "' > ~/.aws/credentials"
-		_, err = commons.ExecuteCommand(n, c, 5, 3)
+		awsCredentials := getAWSProviderProfiles(clusterCredentials.ProviderCredentials, "default")
+		err = commons.WriteFile(n, "~/.aws/credentials", awsCredentials)
 		if err != nil {
 			return errors.Wrap(err, "failed to create aws credentials file")
 		}
@@ -511,9 +799,9 @@ func (p *Provider) deployClusterOperator(n nodes.Node, privateParams PrivatePara
 		if err != nil {
 			return errors.Wrap(err, "failed to marshal docker registries credentials")
 		}
-		c = "kubectl -n kube-system create secret generic keoscluster-registries --from-literal=credentials='" + string(jsonDockerRegistriesCredentials) + "'"
+		c = "kubectl -n kube-system create secret generic keoscluster-registries " + commons.ShellQuote("--from-literal=credentials="+string(jsonDockerRegistriesCredentials))
 		if kubeconfigPath != "" {
-			c = c + " --kubeconfig " + kubeconfigPath
+			c = c + " --kubeconfig " + commons.ShellQuote(kubeconfigPath)
 		}
 		_, err = commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
@@ -525,9 +813,7 @@ func (p *Provider) deployClusterOperator(n nodes.Node, privateParams PrivatePara
 		// Clean keoscluster file
 		keosCluster.Spec.Credentials = commons.Credentials{}
 		keosCluster.Spec.StorageClass = commons.StorageClass{}
-		keosCluster.Spec.Security.AWS = struct {
-			CreateIAM bool "yaml:\"create_iam\" validate:\"boolean\""
-		}{}
+		keosCluster.Spec.Security.AWS = commons.AWSSecurity{}
 		if keosCluster.Spec.InfraProvider != "azure" || (keosCluster.Spec.InfraProvider == "azure" && !keosCluster.Spec.ControlPlane.Managed) {
 			keosCluster.Spec.ControlPlane.Azure = commons.AzureCP{}
 		}
@@ -542,14 +828,93 @@ func (p *Provider) deployClusterOperator(n nodes.Node, privateParams PrivatePara
 			keosCluster.Spec.ControlPlane.HighlyAvailable = nil
 		}
 		keosCluster.Spec.Keos = commons.Keos{}
-
-		clusterConfigYAML, err := yaml.Marshal(clusterConfig)
+		keosCluster.Spec.DR = nil
+		// The TTL is enforced with the expiration annotation
+		keosCluster.Spec.TTL = ""
+		// The node security, the GPU Operator, the spot termination, the interconnect gateways and the infra nodes are set by the provisioner
+		keosCluster.Spec.WorkerNodes = append(commons.WorkerNodes{}, keosCluster.Spec.WorkerNodes...)
+		for i, wn := range keosCluster.Spec.WorkerNodes {
+			keosCluster.Spec.WorkerNodes[i].NodeSecurity = nil
+			labels := map[string]string{}
+			for k, v := range wn.Labels {
+				labels[k] = v
+			}
+			// The GPU nodes are labeled with their sharing config
+			if wn.GPU != nil {
+				for k, v := range getGPUNodeLabels(wn.Name, *wn.GPU) {
+					labels[k] = v
+				}
+				keosCluster.Spec.WorkerNodes[i].Labels = labels
+				keosCluster.Spec.WorkerNodes[i].GPU = nil
+			}
+			if wn.Spot && clusterConfig.Spec.SpotTermination != nil {
+				labels[spotNodeLabel] = "true"
+				keosCluster.Spec.WorkerNodes[i].Labels = labels
+			}
+			// The infra node groups only run the system components
+			if wn.Infra {
+				labels[infraNodeLabel] = "true"
+				keosCluster.Spec.WorkerNodes[i].Labels = labels
+				keosCluster.Spec.WorkerNodes[i].Taints = infraNodeTaints(wn.Taints)
+				keosCluster.Spec.WorkerNodes[i].Infra = false
+			}
+			if interconnect := clusterConfig.Spec.Interconnect; interconnect != nil && wn.Name == interconnect.GatewayNodeGroup {
+				labels[submarinerGatewayLabel] = "true"
+				keosCluster.Spec.WorkerNodes[i].Labels = labels
+			}
+		}
+		// The custom DNS name must be valid for the API server certificate
+		apiServer := keosCluster.Spec.ControlPlane.APIServer
+		if apiServer.DNSName != "" && !commons.Contains(apiServer.CertSANs, apiServer.DNSName) {
+			keosCluster.Spec.ControlPlane.APIServer.CertSANs = append(append([]string{}, apiServer.CertSANs...), apiServer.DNSName)
+		}
+		// The discovery documents are only exported by the provisioner
+		if issuer := keosCluster.Spec.ControlPlane.ServiceAccountIssuer; issuer != nil {
+			issuerCopy := *issuer
+			issuerCopy.DiscoveryPath = ""
+			keosCluster.Spec.ControlPlane.ServiceAccountIssuer = &issuerCopy
+		}
+		// The networks of the GKE API server are its master authorized networks
+		if keosCluster.Spec.InfraProvider == "gcp" && keosCluster.Spec.ControlPlane.Managed && len(apiServer.AllowedCIDRs) > 0 {
+			var cidrBlocks []commons.CIDRBlock
+			for _, cidr := range apiServer.AllowedCIDRs {
+				cidrBlocks = append(cidrBlocks, commons.CIDRBlock{CIDRBlock: cidr})
+			}
+			keosCluster.Spec.ControlPlane.Gcp.MasterAuthorizedNetworksConfig.CIDRBlocks = cidrBlocks
+			keosCluster.Spec.ControlPlane.APIServer.AllowedCIDRs = nil
+		}
+
+		// The clusterctl providers, their values, the ClusterResourceSets and the manifests export are only used during the bootstrap
+		clusterConfigCopy := *clusterConfig
+		clusterConfigCopy.Spec.CAPIProviders = nil
+		clusterConfigCopy.Spec.ManifestsExport = nil
+		clusterConfigCopy.Spec.CAPXConfig = commons.CAPXConfig{}
+		clusterConfigCopy.Spec.ClusterResourceSets = nil
+		clusterConfigCopy.Spec.NamingPolicy = nil
+		clusterConfigCopy.Spec.Notifications = nil
+		clusterConfigCopy.Spec.PreloadImages = nil
+		clusterConfigCopy.Spec.RegistryCache = nil
+		clusterConfigCopy.Spec.RegistryMirrors = nil
+		clusterConfigCopy.Spec.NodeLocalDNS = nil
+		clusterConfigCopy.Spec.SystemBaseline = nil
+		clusterConfigCopy.Spec.ExternalSecrets = nil
+		clusterConfigCopy.Spec.PolicyEngine = nil
+		clusterConfigCopy.Spec.Namespaces = nil
+		clusterConfigCopy.Spec.CABundle = ""
+		clusterConfigCopy.Spec.OSPatching = nil
+		clusterConfigCopy.Spec.CostAllocation = nil
+		clusterConfigCopy.Spec.SpotTermination = nil
+		clusterConfigCopy.Spec.Interconnect = nil
+		clusterConfigCopy.Spec.AddonsPDB = nil
+		clusterConfigCopy.Spec.RegistryLogin = nil
+		clusterConfigCopy.Spec.ManagementAccess = nil
+		clusterConfigCopy.Spec.AccessKubeconfigs = nil
+		clusterConfigYAML, err := yaml.Marshal(clusterConfigCopy)
 		if err != nil {
 			return err
 		}
 		// Write keoscluster file
-		c = "echo '" + string(clusterConfigYAML) + "' > " + manifestsPath + "/clusterconfig.yaml"
-		_, err = commons.ExecuteCommand(n, c, 5, 3)
+		err = commons.WriteFile(n, manifestsPath+"/clusterconfig.yaml", string(clusterConfigYAML))
 		if err != nil {
 			return errors.Wrap(err, "failed to write the keoscluster file")
 		}
@@ -560,8 +925,7 @@ func (p *Provider) deployClusterOperator(n nodes.Node, privateParams PrivatePara
 			return err
 		}
 		// Write keoscluster file
-		c = "echo '" + string(keosClusterYAML) + "' > " + manifestsPath + "/keoscluster.yaml"
-		_, err = commons.ExecuteCommand(n, c, 5, 3)
+		err = commons.WriteFile(n, manifestsPath+"/keoscluster.yaml", string(keosClusterYAML))
 		if err != nil {
 			return errors.Wrap(err, "failed to write the keoscluster file")
 		}
@@ -575,7 +939,7 @@ func (p *Provider) deployClusterOperator(n nodes.Node, privateParams PrivatePara
 
 		if firstInstallation {
 			// Pull cluster-operator helm chart
-			c = "helm pull " + stratio_helm_repo + "/cluster-operator --version " + chartVersion +
+			c = "helm pull " + commons.ShellQuote(stratio_helm_repo) + "/cluster-operator --version " + commons.ShellQuote(chartVersion) +
 				" --untar --untardir /stratio/helm"
 			_, err = commons.ExecuteCommand(n, c, 5, 3)
 			if err != nil {
@@ -585,25 +949,25 @@ func (p *Provider) deployClusterOperator(n nodes.Node, privateParams PrivatePara
 		// Deploy cluster-operator chart
 		c = "helm install --wait cluster-operator /stratio/helm/cluster-operator" +
 			" --namespace kube-system" +
-			" --set provider=" + keosCluster.Spec.InfraProvider +
-			" --set app.containers.controllerManager.image.registry=" + keosRegistry.url +
+			" --set provider=" + commons.ShellQuote(keosCluster.Spec.InfraProvider) +
+			" --set app.containers.controllerManager.image.registry=" + commons.ShellQuote(keosRegistry.url) +
 			" --set app.containers.controllerManager.image.repository=stratio/cluster-operator" +
 			" --set app.containers.controllerManager.imagePullSecrets.enabled=true"
 		if clusterOperatorImage != "" {
-			c += " --set app.containers.controllerManager.image.tag=" + clusterOperatorImage
+			c += " --set app.containers.controllerManager.image.tag=" + commons.ShellQuote(clusterOperatorImage)
 		}
 		if privateParams.Private {
-			c += " --set app.containers.kubeRbacProxy.image=" + keosRegistry.url + "/stratio/kube-rbac-proxy:v0.13.1"
+			c += " --set app.containers.kubeRbacProxy.image=" + commons.ShellQuote(keosRegistry.url) + "/stratio/kube-rbac-proxy:v0.13.1"
 		}
 		if keosCluster.Spec.InfraProvider == "azure" {
-			c += " --set secrets.azure.clientIDBase64=" + strings.Split(p.capxEnvVars[1], "AZURE_CLIENT_ID_B64=")[1] +
-				" --set secrets.azure.clientSecretBase64=" + strings.Split(p.capxEnvVars[0], "AZURE_CLIENT_SECRET_B64=")[1] +
-				" --set secrets.azure.subscriptionIDBase64=" + strings.Split(p.capxEnvVars[2], "AZURE_SUBSCRIPTION_ID_B64=")[1] +
-				" --set secrets.azure.tenantIDBase64=" + strings.Split(p.capxEnvVars[3], "AZURE_TENANT_ID_B64=")[1]
+			c += " --set secrets.azure.clientIDBase64=" + commons.ShellQuote(strings.Split(p.capxEnvVars[1], "AZURE_CLIENT_ID_B64=")[1]) +
+				" --set secrets.azure.clientSecretBase64=" + commons.ShellQuote(strings.Split(p.capxEnvVars[0], "AZURE_CLIENT_SECRET_B64=")[1]) +
+				" --set secrets.azure.subscriptionIDBase64=" + commons.ShellQuote(strings.Split(p.capxEnvVars[2], "AZURE_SUBSCRIPTION_ID_B64=")[1]) +
+				" --set secrets.azure.tenantIDBase64=" + commons.ShellQuote(strings.Split(p.capxEnvVars[3], "AZURE_TENANT_ID_B64=")[1])
 		} else if keosCluster.Spec.InfraProvider == "gcp" {
-			c += " --set secrets.common.credentialsBase64=" + strings.Split(p.capxEnvVars[0], "GCP_B64ENCODED_CREDENTIALS=")[1]
+			c += " --set secrets.common.credentialsBase64=" + commons.ShellQuote(strings.Split(p.capxEnvVars[0], "GCP_B64ENCODED_CREDENTIALS=")[1])
 		} else if keosCluster.Spec.InfraProvider == "aws" {
-			c += " --set secrets.common.credentialsBase64=" + strings.Split(p.capxEnvVars[3], "AWS_B64ENCODED_CREDENTIALS=")[1]
+			c += " --set secrets.common.credentialsBase64=" + commons.ShellQuote(strings.Split(p.capxEnvVars[3], "AWS_B64ENCODED_CREDENTIALS=")[1])
 		}
 		_, err = commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
@@ -613,14 +977,14 @@ func (p *Provider) deployClusterOperator(n nodes.Node, privateParams PrivatePara
 		helmValuesClusterOperatorFile := "/kind/cluster-operator-helm-values.yaml"
 		c = "helm get values cluster-operator" +
 			" --namespace kube-system --all > " +
-			helmValuesClusterOperatorFile
+			commons.ShellQuote(helmValuesClusterOperatorFile)
 		_, err = commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
 			return errors.Wrap(err, "failed to create cluster-operator helm values file")
 		}
 
 		// Read the YAML file
-		c = "cat " + helmValuesClusterOperatorFile
+		c = "cat " + commons.ShellQuote(helmValuesClusterOperatorFile)
 		helmValuesClusterOperatorData, err := commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil || helmValuesClusterOperatorData == "" {
 			return errors.Wrap(err, "failed to read HelmRelease values file")
@@ -649,8 +1013,7 @@ func (p *Provider) deployClusterOperator(n nodes.Node, privateParams PrivatePara
 			return errors.Wrap(err, "failed to marshal updated HelmRelease values content")
 		}
 		// Write the updated YAML data back to the file
-		c = "echo '" + string(updatedHelmValuesClusterOperatorData) + "' > " + helmValuesClusterOperatorFile
-		_, err = commons.ExecuteCommand(n, c, 5, 3)
+		err = commons.WriteFile(n, helmValuesClusterOperatorFile, string(updatedHelmValuesClusterOperatorData))
 		if err != nil {
 			return errors.Wrap(err, "failed to write updated HelmRelease values file")
 		}
@@ -680,43 +1043,58 @@ func (p *Provider) deployClusterOperator(n nodes.Node, privateParams PrivatePara
 	return nil
 }
 
-func installCalico(n nodes.Node, k string, privateParams PrivateParams, isNetPolEngine bool, dryRun bool) error {
-	var c string
-	var cmd exec.Cmd
-	var err error
-	keosCluster := privateParams.KeosCluster
+// installsCalico returns whether Calico is installed in the workload cluster, as the CNI of the
+// unmanaged clusters or along with the VPC CNI of EKS
+func installsCalico(keosSpec commons.KeosSpec) bool {
+	return !keosSpec.ControlPlane.Managed || keosSpec.InfraProvider == "aws"
+}
 
-	calicoTemplate := "/kind/tigera-operator-helm-values.yaml"
+// isCalicoNetPolEngine returns whether Calico is only the Network Policy engine of the CNI of the
+// managed control plane
+func isCalicoNetPolEngine(keosSpec commons.KeosSpec) bool {
+	if !keosSpec.ControlPlane.Managed {
+		return false
+	}
+	return keosSpec.InfraProvider == "gcp" || (keosSpec.InfraProvider == "aws" && !keosSpec.ControlPlane.AWS.CalicoCNI)
+}
 
-	calicoHelmParams := calicoHelmParams{
-		Spec:           keosCluster.Spec,
+func getCalicoTemplate(privateParams PrivateParams, isNetPolEngine bool) manifestTemplate {
+	return manifestTemplate{"common", "tigera-operator-helm-values.tmpl", majorVersion, calicoHelmParams{
+		Spec:           privateParams.KeosCluster.Spec,
 		KeosRegUrl:     privateParams.KeosRegUrl,
 		Private:        privateParams.Private,
 		IsNetPolEngine: isNetPolEngine,
 		Annotations: map[string]string{
 			postInstallAnnotation: "var-lib-calico",
 		},
-	}
+	}}
+}
+
+func installCalico(n nodes.Node, k string, privateParams PrivateParams, isNetPolEngine bool, dryRun bool) error {
+	var c string
+	var cmd exec.Cmd
+	var err error
+
+	calicoTemplate := "/kind/tigera-operator-helm-values.yaml"
 
 	// Generate the calico helm values
-	calicoHelmValues, err := getManifest("common", "tigera-operator-helm-values.tmpl", majorVersion, calicoHelmParams)
+	calicoHelmValues, err := getCalicoTemplate(privateParams, isNetPolEngine).render()
 
 	if err != nil {
 		return errors.Wrap(err, "failed to generate calico helm values")
 	}
 
-	c = "echo '" + calicoHelmValues + "' > " + calicoTemplate
-	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	err = commons.WriteFile(n, calicoTemplate, calicoHelmValues)
 	if err != nil {
 		return errors.Wrap(err, "failed to create Calico Helm chart values file")
 	}
 
 	if !dryRun {
 		c = "helm install tigera-operator /stratio/helm/tigera-operator" +
-			" --kubeconfig " + k +
+			" --kubeconfig " + commons.ShellQuote(k) +
 			" --namespace tigera-operator" +
 			" --create-namespace" +
-			" --values " + calicoTemplate
+			" --values " + commons.ShellQuote(calicoTemplate)
 		_, err = commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
 			return errors.Wrap(err, "failed to deploy Calico Helm Chart")
@@ -738,6 +1116,17 @@ func installCalico(n nodes.Node, k string, privateParams PrivateParams, isNetPol
 	return nil
 }
 
+// deploysAutoscaler returns whether cluster-autoscaler is deployed, as the machine pools of the
+// managed control planes other than EKS are scaled by the cloud provider
+func deploysAutoscaler(keosSpec commons.KeosSpec) bool {
+	isMachinePool := keosSpec.InfraProvider != "aws" && keosSpec.ControlPlane.Managed
+	return keosSpec.DeployAutoscaler && !isMachinePool
+}
+
+func getClusterAutoscalerTemplate(privateParams PrivateParams) manifestTemplate {
+	return manifestTemplate{"common", "cluster-autoscaler-helm-values.tmpl", majorVersion, privateParams}
+}
+
 func deployClusterAutoscaler(n nodes.Node, chartsList map[string]commons.ChartEntry, privateParams PrivateParams, capiClustersNamespace string, moveManagement bool) error {
 	helmValuesCAFile := "/kind/cluster-autoscaler-helm-values.yaml"
 	clusterAutoscalerEntry := chartsList["cluster-autoscaler"]
@@ -751,12 +1140,11 @@ func deployClusterAutoscaler(n nodes.Node, chartsList map[string]commons.ChartEn
 		clusterAutoscalerHelmReleaseParams.ChartRepoRef = "cluster-autoscaler"
 	}
 
-	helmValuesCA, err := getManifest("common", "cluster-autoscaler-helm-values.tmpl", majorVersion, privateParams)
+	helmValuesCA, err := getClusterAutoscalerTemplate(privateParams).render()
 	if err != nil {
 		return errors.Wrap(err, "failed to get CA helm values")
 	}
-	c := "echo '" + helmValuesCA + "' > " + helmValuesCAFile
-	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	err = commons.WriteFile(n, helmValuesCAFile, helmValuesCA)
 	if err != nil {
 		return errors.Wrap(err, "failed to create CA helm values file")
 	}
@@ -772,20 +1160,19 @@ func deployClusterAutoscaler(n nodes.Node, chartsList map[string]commons.ChartEn
 			return errors.Wrap(err, "failed to get CA RBAC file")
 		}
 
-		c = "echo '" + autoscalerRBAC + "' > " + autoscalerRBACPath
-		_, err = commons.ExecuteCommand(n, c, 5, 3)
+		err = commons.WriteFile(n, autoscalerRBACPath, autoscalerRBAC)
 		if err != nil {
 			return errors.Wrap(err, "failed to create CA RBAC file")
 		}
 
 		// Create namespace for CAPI clusters (it must exists) in worker cluster
-		c = "kubectl --kubeconfig " + kubeconfigPath + " create ns " + capiClustersNamespace
+		c := "kubectl --kubeconfig " + kubeconfigPath + " create ns " + commons.ShellQuote(capiClustersNamespace)
 		_, err = commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
 			return errors.Wrap(err, "failed to create manifests Namespace")
 		}
 
-		c = "kubectl --kubeconfig " + kubeconfigPath + " apply -f " + autoscalerRBACPath
+		c = "kubectl --kubeconfig " + kubeconfigPath + " apply -f " + commons.ShellQuote(autoscalerRBACPath)
 		_, err = commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
 			return errors.Wrap(err, "failed to apply CA RBAC")
@@ -810,13 +1197,12 @@ func configureFlux(n nodes.Node, k string, privateParams PrivateParams, helmRepo
 	// Make flux work after capz-nmi deployment in Azure
 	if keosClusterSpec.InfraProvider == "azure" {
 		azureFlux2PodIdentityExceptionPath := "/kind/flux2_azurepodidentityexception.yaml"
-		c := "echo \"" + azureFlux2PodIdentityException + "\" > " + azureFlux2PodIdentityExceptionPath
-		_, err := commons.ExecuteCommand(n, c, 5, 3)
+		err := commons.WriteFile(n, azureFlux2PodIdentityExceptionPath, azureFlux2PodIdentityException)
 		if err != nil {
 			return errors.Wrap(err, "failed to write the flux2 azure pod identity exception")
 		}
 		// Apply HelmRepository
-		c = "kubectl --kubeconfig " + k + " apply -f " + azureFlux2PodIdentityExceptionPath
+		c = "kubectl --kubeconfig " + commons.ShellQuote(k) + " apply -f " + commons.ShellQuote(azureFlux2PodIdentityExceptionPath)
 		_, err = commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
 			return errors.Wrap(err, "failed to deploy Flux2 azure pod identity exception")
@@ -830,17 +1216,16 @@ func configureFlux(n nodes.Node, k string, privateParams PrivateParams, helmRepo
 		return errors.Wrap(err, "failed to generate flux helm values")
 	}
 
-	c = "echo '" + fluxHelmValues + "' > " + fluxTemplate
-	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	err = commons.WriteFile(n, fluxTemplate, fluxHelmValues)
 	if err != nil {
 		return errors.Wrap(err, "failed to create Flux Helm chart values file")
 	}
 
 	c = "helm install flux2 /stratio/helm/flux2" +
-		" --kubeconfig " + k +
+		" --kubeconfig " + commons.ShellQuote(k) +
 		" --namespace kube-system" +
 		" --create-namespace" +
-		" --values " + fluxTemplate
+		" --values " + commons.ShellQuote(fluxTemplate)
 	_, err = commons.ExecuteCommand(n, c, 5, 3)
 	if err != nil {
 		return errors.Wrap(err, "failed to deploy Flux Helm Chart")
@@ -933,14 +1318,13 @@ func configureHelmRepository(n nodes.Node, k string, templatePath string, params
 
 	// Write HelmRepository manifest to file
 	fluxHelmRepositoryTemplate := "/kind/" + params.ChartName + "_helmrepository.yaml"
-	c := "echo '" + fluxHelmRepository + "' > " + fluxHelmRepositoryTemplate
-	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	err = commons.WriteFile(n, fluxHelmRepositoryTemplate, fluxHelmRepository)
 	if err != nil {
 		return errors.Wrap(err, "failed to create "+params.ChartName+" Flux HelmRepository file")
 	}
 
 	// Apply HelmRepository
-	c = "kubectl --kubeconfig " + k + " apply -f " + fluxHelmRepositoryTemplate
+	c := "kubectl --kubeconfig " + commons.ShellQuote(k) + " apply -f " + commons.ShellQuote(fluxHelmRepositoryTemplate)
 	_, err = commons.ExecuteCommand(n, c, 5, 3)
 	if err != nil {
 		return errors.Wrap(err, "failed to deploy "+params.ChartName+" Flux HelmRepository")
@@ -953,9 +1337,9 @@ func configureHelmRelease(n nodes.Node, k string, templatePath string, params fl
 
 	// Create default HelmRelease configmap
 	c := "kubectl --kubeconfig " + kubeconfigPath + " " +
-		"-n " + params.ChartNamespace + " create configmap " +
-		"00-" + params.ChartName + "-helm-chart-default-values " +
-		"--from-file=values.yaml=" + valuesFile
+		"-n " + commons.ShellQuote(params.ChartNamespace) + " create configmap " +
+		"00-" + commons.ShellQuote(params.ChartName) + "-helm-chart-default-values " +
+		"--from-file=values.yaml=" + commons.ShellQuote(valuesFile)
 	_, err := commons.ExecuteCommand(n, c, 5, 3)
 	if err != nil {
 		return errors.Wrap(err, "failed to deploy "+params.ChartName+" HelmRelease default configuration map")
@@ -963,8 +1347,8 @@ func configureHelmRelease(n nodes.Node, k string, templatePath string, params fl
 
 	// Create override HelmRelease configmap
 	c = "kubectl --kubeconfig " + kubeconfigPath + " " +
-		"-n " + params.ChartNamespace + " create configmap " +
-		"01-" + params.ChartName + "-helm-chart-override-values " +
+		"-n " + commons.ShellQuote(params.ChartNamespace) + " create configmap " +
+		"01-" + commons.ShellQuote(params.ChartName) + "-helm-chart-override-values " +
 		"--from-literal=values.yaml=\"\""
 	_, err = commons.ExecuteCommand(n, c, 5, 3)
 	if err != nil {
@@ -983,6 +1367,7 @@ func configureHelmRelease(n nodes.Node, k string, templatePath string, params fl
 		HelmReleaseInterval       string
 		HelmReleaseRetries        int
 		HelmReleaseSourceInterval string
+		SecretValues              bool
 	}{
 		ChartName:                 params.ChartName,
 		ChartNamespace:            params.ChartNamespace,
@@ -991,6 +1376,7 @@ func configureHelmRelease(n nodes.Node, k string, templatePath string, params fl
 		HelmReleaseInterval:       defaultHelmReleaseInterval,
 		HelmReleaseRetries:        defaultHelmReleaseRetries,
 		HelmReleaseSourceInterval: defaultHelmReleaseSourceInterval,
+		SecretValues:              params.SecretValues,
 	}
 
 	if completedfluxHelmReleaseParams.ChartRepoRef == "keos" {
@@ -1013,21 +1399,20 @@ func configureHelmRelease(n nodes.Node, k string, templatePath string, params fl
 
 	// Write HelmHelmRelease manifest to file
 	fluxHelmHelmReleaseTemplate := "/kind/" + params.ChartName + "_helmrelease.yaml"
-	c = "echo '" + fluxHelmHelmRelease + "' > " + fluxHelmHelmReleaseTemplate
-	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	err = commons.WriteFile(n, fluxHelmHelmReleaseTemplate, fluxHelmHelmRelease)
 	if err != nil {
 		return errors.Wrap(err, "failed to create "+params.ChartName+" Flux HelmHelmRelease file")
 	}
 
 	// Apply HelmHelmRelease
-	c = "kubectl --kubeconfig " + k + " apply -f " + fluxHelmHelmReleaseTemplate
+	c = "kubectl --kubeconfig " + commons.ShellQuote(k) + " apply -f " + commons.ShellQuote(fluxHelmHelmReleaseTemplate)
 	_, err = commons.ExecuteCommand(n, c, 5, 3)
 	if err != nil {
 		return errors.Wrap(err, "failed to deploy "+params.ChartName+" Flux HelmHelmRelease")
 	}
 	// Wait for HelmRelease to become ready
 	c = "kubectl --kubeconfig " + kubeconfigPath + " " +
-		"-n " + params.ChartNamespace + " wait helmrelease/" + params.ChartName +
+		"-n " + commons.ShellQuote(params.ChartNamespace) + " wait helmrelease/" + commons.ShellQuote(params.ChartName) +
 		" --for=condition=ready --timeout=5m"
 	_, err = commons.ExecuteCommand(n, c, 5, 3)
 	if err != nil {
@@ -1036,32 +1421,46 @@ func configureHelmRelease(n nodes.Node, k string, templatePath string, params fl
 	return nil
 }
 
+// customizesCoreDNS returns whether the CoreDNS configuration is patched with the forwarders and
+// stub domains of the descriptor. GKE clusters get it along with the CoreDNS deployment
+func customizesCoreDNS(keosSpec commons.KeosSpec) bool {
+	customDNS := len(keosSpec.Dns.Forwarders) > 0 || len(keosSpec.Dns.StubDomains) > 0
+	return customDNS && !(keosSpec.InfraProvider == "gcp" && keosSpec.ControlPlane.Managed)
+}
+
+// getCoreDNSPatchTemplate returns the patch of the CoreDNS configuration, which AKS reads from the
+// coredns-custom ConfigMap
+func getCoreDNSPatchTemplate(keosSpec commons.KeosSpec) manifestTemplate {
+	coreDNSSuffix := ""
+	if keosSpec.InfraProvider == "azure" && keosSpec.ControlPlane.Managed {
+		coreDNSSuffix = "-aks"
+	}
+	return manifestTemplate{keosSpec.InfraProvider, "coredns-patch_configmap" + coreDNSSuffix + ".tmpl", "", keosSpec}
+}
+
 func customCoreDNS(n nodes.Node, keosCluster commons.KeosCluster) error {
 	var c string
 	var err error
 
 	coreDNSPatchFile := "coredns"
 	coreDNSTemplate := "/kind/coredns-configmap.yaml"
-	coreDNSSuffix := ""
 
 	if keosCluster.Spec.InfraProvider == "azure" && keosCluster.Spec.ControlPlane.Managed {
 		coreDNSPatchFile = "coredns-custom"
-		coreDNSSuffix = "-aks"
 	}
 
-	coreDNSConfigmap, err := getManifest(keosCluster.Spec.InfraProvider, "coredns_configmap"+coreDNSSuffix+".tmpl", "", keosCluster.Spec)
+	coreDNSConfigmap, err := getCoreDNSPatchTemplate(keosCluster.Spec).render()
 	if err != nil {
 		return errors.Wrap(err, "failed to get CoreDNS file")
 	}
 
-	c = "echo '" + coreDNSConfigmap + "' > " + coreDNSTemplate
-	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	err = commons.WriteFile(n, coreDNSTemplate, coreDNSConfigmap)
 	if err != nil {
 		return errors.Wrap(err, "failed to create CoreDNS configmap file")
 	}
 
 	// Patch configmap
-	c = "kubectl --kubeconfig " + kubeconfigPath + " -n kube-system patch cm " + coreDNSPatchFile + " --patch-file " + coreDNSTemplate
+	c = "kubectl --kubeconfig " + kubeconfigPath + " -n kube-system patch cm " + commons.ShellQuote(coreDNSPatchFile) + " --patch-file " + commons.ShellQuote(coreDNSTemplate)
 	_, err = commons.ExecuteCommand(n, c, 5, 3)
 	if err != nil {
 		return errors.Wrap(err, "failed to customize coreDNS patching ConfigMap")
@@ -1084,6 +1483,16 @@ func customCoreDNS(n nodes.Node, keosCluster commons.KeosCluster) error {
 	return nil
 }
 
+// getCAPXPDBTemplate returns the PodDisruptionBudgets of the CAPX controllers
+func getCAPXPDBTemplate(keosSpec commons.KeosSpec) manifestTemplate {
+	return manifestTemplate{"common", "capx_pdb.tmpl", "", keosSpec}
+}
+
+// getCAPIPDBTemplate returns the PodDisruptionBudgets of the CAPI controllers
+func getCAPIPDBTemplate(keosSpec commons.KeosSpec) manifestTemplate {
+	return manifestTemplate{"common", "capi_pdb.tmpl", "", keosSpec}
+}
+
 // installCAPXWorker installs CAPX in the worker cluster
 func (p *Provider) installCAPXWorker(n nodes.Node, keosCluster commons.KeosCluster, kubeconfigPath string) error {
 	var c string
@@ -1093,7 +1502,7 @@ func (p *Provider) installCAPXWorker(n nodes.Node, keosCluster commons.KeosClust
 
 	if p.capxProvider == "azure" {
 		// Create capx namespace
-		c = "kubectl --kubeconfig " + kubeconfigPath + " create namespace " + p.capxName + "-system"
+		c = "kubectl --kubeconfig " + commons.ShellQuote(kubeconfigPath) + " create namespace " + commons.ShellQuote(p.capxName) + "-system"
 		_, err = commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
 			return errors.Wrap(err, "failed to create CAPx namespace")
@@ -1103,9 +1512,8 @@ func (p *Provider) installCAPXWorker(n nodes.Node, keosCluster commons.KeosClust
 		namespace := p.capxName + "-system"
 		clientSecret, _ := base64.StdEncoding.DecodeString(strings.Split(p.capxEnvVars[0], "AZURE_CLIENT_SECRET_B64=")[1])
 
-		c := fmt.Sprintf(
-			"kubectl --kubeconfig %s -n %s create secret generic cluster-identity-secret --from-literal=clientSecret='%s'",
-			kubeconfigPath, namespace, clientSecret)
+		c := "kubectl --kubeconfig " + commons.ShellQuote(kubeconfigPath) + " -n " + commons.ShellQuote(namespace) +
+			" create secret generic cluster-identity-secret " + commons.ShellQuote("--from-literal=clientSecret="+string(clientSecret))
 		_, err = commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
 			return errors.Wrap(err, "failed to create CAPx secret")
@@ -1113,16 +1521,21 @@ func (p *Provider) installCAPXWorker(n nodes.Node, keosCluster commons.KeosClust
 	}
 
 	// Install CAPX in worker cluster
-	c = "clusterctl --kubeconfig " + kubeconfigPath + " init --wait-providers" +
+	c = "clusterctl --kubeconfig " + commons.ShellQuote(kubeconfigPath) + " init --wait-providers" +
 		" --core " + CAPICoreProvider + ":" + CAPIVersion +
-		" --bootstrap " + CAPIBootstrapProvider + ":" + CAPIVersion +
-		" --control-plane " + CAPIControlPlaneProvider + ":" + CAPIVersion +
-		" --infrastructure " + p.capxProvider + ":" + p.capxVersion
+		" --bootstrap " + commons.ShellQuote(p.capiBootstrap) +
+		" --control-plane " + commons.ShellQuote(p.capiControlPlane) +
+		" --infrastructure " + commons.ShellQuote(p.capxProvider) + ":" + commons.ShellQuote(p.capxVersion)
 	_, err = commons.ExecuteCommand(n, c, 5, 3, p.capxEnvVars)
 	if err != nil {
 		return errors.Wrap(err, "failed to install CAPX in workload cluster")
 	}
 
+	err = p.configureCAPXArgs(n, kubeconfigPath)
+	if err != nil {
+		return err
+	}
+
 	// GKE by default limits the consumption of this priority class using ResourceQuota
 	if p.capxProvider == "gcp" && p.capxManaged {
 		resourceQuotaPath := "/kind/resourceQuota.yaml"
@@ -1135,12 +1548,11 @@ func (p *Provider) installCAPXWorker(n nodes.Node, keosCluster commons.KeosClust
 			if err != nil {
 				return errors.Wrap(err, "failed to get ResourceQuota template")
 			}
-			c = "echo '" + resourceQuota + "' > " + resourceQuotaPath
-			_, err = commons.ExecuteCommand(n, c, 5, 3)
+			err = commons.WriteFile(n, resourceQuotaPath, resourceQuota)
 			if err != nil {
 				return errors.Wrap(err, "failed to save ResourceQuota manifest")
 			}
-			c = "kubectl --kubeconfig " + kubeconfigPath + " apply -f " + resourceQuotaPath
+			c = "kubectl --kubeconfig " + commons.ShellQuote(kubeconfigPath) + " apply -f " + commons.ShellQuote(resourceQuotaPath)
 			_, err = commons.ExecuteCommand(n, c, 5, 3)
 			if err != nil {
 				return errors.Wrap(err, "failed to apply ResourceQuota manifest")
@@ -1149,19 +1561,19 @@ func (p *Provider) installCAPXWorker(n nodes.Node, keosCluster commons.KeosClust
 	}
 
 	// Manually assign PriorityClass to capx service
-	c = "kubectl --kubeconfig " + kubeconfigPath + " -n " + p.capxName + "-system get deploy " + p.capxName + "-controller-manager -o jsonpath='{.spec.template.spec.priorityClassName}'"
+	c = "kubectl --kubeconfig " + commons.ShellQuote(kubeconfigPath) + " -n " + commons.ShellQuote(p.capxName) + "-system get deploy " + commons.ShellQuote(p.capxName) + "-controller-manager -o jsonpath='{.spec.template.spec.priorityClassName}'"
 	priorityClassName, err := commons.ExecuteCommand(n, c, 5, 3)
 	if err != nil {
 		return errors.Wrap(err, "failed to get priorityClass for "+p.capxName+"-controller-manager")
 	}
 
 	if priorityClassName != "system-node-critical" {
-		c = "kubectl --kubeconfig " + kubeconfigPath + " -n " + p.capxName + "-system patch deploy " + p.capxName + "-controller-manager -p '{\"spec\": {\"template\": {\"spec\": {\"priorityClassName\": \"system-node-critical\"}}}}' --type=merge"
+		c = "kubectl --kubeconfig " + commons.ShellQuote(kubeconfigPath) + " -n " + commons.ShellQuote(p.capxName) + "-system patch deploy " + commons.ShellQuote(p.capxName) + "-controller-manager -p '{\"spec\": {\"template\": {\"spec\": {\"priorityClassName\": \"system-node-critical\"}}}}' --type=merge"
 		_, err = commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
 			return errors.Wrap(err, "failed to assigned priorityClass to "+p.capxName+"-controller-manager")
 		}
-		c = "kubectl --kubeconfig " + kubeconfigPath + " -n " + p.capxName + "-system rollout status deploy " + p.capxName + "-controller-manager --timeout 60s"
+		c = "kubectl --kubeconfig " + commons.ShellQuote(kubeconfigPath) + " -n " + commons.ShellQuote(p.capxName) + "-system rollout status deploy " + commons.ShellQuote(p.capxName) + "-controller-manager --timeout 60s"
 		_, err = commons.ExecuteCommand(n, c, 30, 3)
 		if err != nil {
 			return errors.Wrap(err, "failed to check rollout status for "+p.capxName+"-controller-manager")
@@ -1169,30 +1581,29 @@ func (p *Provider) installCAPXWorker(n nodes.Node, keosCluster commons.KeosClust
 	}
 
 	// Scale CAPX to 2 replicas
-	c = "kubectl --kubeconfig " + kubeconfigPath + " -n " + p.capxName + "-system scale --replicas 2 deploy " + p.capxName + "-controller-manager"
+	c = "kubectl --kubeconfig " + commons.ShellQuote(kubeconfigPath) + " -n " + commons.ShellQuote(p.capxName) + "-system scale --replicas 2 deploy " + commons.ShellQuote(p.capxName) + "-controller-manager"
 	_, err = commons.ExecuteCommand(n, c, 5, 3)
 	if err != nil {
 		return errors.Wrap(err, "failed to scale CAPX in workload cluster")
 	}
-	c = "kubectl --kubeconfig " + kubeconfigPath + " -n " + p.capxName + "-system rollout status deploy " + p.capxName + "-controller-manager --timeout 60s"
+	c = "kubectl --kubeconfig " + commons.ShellQuote(kubeconfigPath) + " -n " + commons.ShellQuote(p.capxName) + "-system rollout status deploy " + commons.ShellQuote(p.capxName) + "-controller-manager --timeout 60s"
 	_, err = commons.ExecuteCommand(n, c, 5, 3)
 	if err != nil {
 		return errors.Wrap(err, "failed to check rollout status for "+p.capxName+"-controller-manager")
 	}
 
 	// Define PodDisruptionBudget for capx services
-	capxPDB, err := getManifest("common", "capx_pdb.tmpl", "", keosCluster.Spec)
+	capxPDB, err := getCAPXPDBTemplate(keosCluster.Spec).render()
 	if err != nil {
 		return errors.Wrap(err, "failed to get PodDisruptionBudget file")
 	}
 
-	c = "echo '" + capxPDB + "' > " + capxPDBPath
-	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	err = commons.WriteFile(n, capxPDBPath, capxPDB)
 	if err != nil {
 		return errors.Wrap(err, "failed to create PodDisruptionBudget file")
 	}
 
-	c = "kubectl --kubeconfig " + kubeconfigPath + " apply -f " + capxPDBPath
+	c = "kubectl --kubeconfig " + commons.ShellQuote(kubeconfigPath) + " apply -f " + commons.ShellQuote(capxPDBPath)
 	_, err = commons.ExecuteCommand(n, c, 5, 3)
 	if err != nil {
 		return errors.Wrap(err, "failed to apply "+p.capxName+" PodDisruptionBudget")
@@ -1228,7 +1639,7 @@ func (p *Provider) configCAPIWorker(n nodes.Node, keosCluster commons.KeosCluste
 	// Manually assign PriorityClass to capi services
 	for _, deployment := range capiDeployments {
 		if !p.capxManaged || (p.capxManaged && !allowedNamePattern.MatchString(deployment.name)) {
-			c = "kubectl --kubeconfig " + kubeconfigPath + " -n " + deployment.namespace + " patch deploy " + deployment.name + " -p '{\"spec\": {\"template\": {\"spec\": {\"priorityClassName\": \"system-node-critical\"}}}}' --type=merge"
+			c = "kubectl --kubeconfig " + commons.ShellQuote(kubeconfigPath) + " -n " + commons.ShellQuote(deployment.namespace) + " patch deploy " + commons.ShellQuote(deployment.name) + " -p '{\"spec\": {\"template\": {\"spec\": {\"priorityClassName\": \"system-node-critical\"}}}}' --type=merge"
 			_, err = commons.ExecuteCommand(n, c, 5, 3)
 			if err != nil {
 				return errors.Wrap(err, "failed to assigned priorityClass to "+deployment.name)
@@ -1238,12 +1649,12 @@ func (p *Provider) configCAPIWorker(n nodes.Node, keosCluster commons.KeosCluste
 
 	// Manually assign PriorityClass to nmi
 	if p.capxProvider == "azure" {
-		c = "kubectl --kubeconfig " + kubeconfigPath + " -n " + p.capxName + "-system patch ds capz-nmi -p '{\"spec\": {\"template\": {\"spec\": {\"priorityClassName\": \"system-node-critical\"}}}}' --type=merge"
+		c = "kubectl --kubeconfig " + commons.ShellQuote(kubeconfigPath) + " -n " + commons.ShellQuote(p.capxName) + "-system patch ds capz-nmi -p '{\"spec\": {\"template\": {\"spec\": {\"priorityClassName\": \"system-node-critical\"}}}}' --type=merge"
 		_, err = commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
 			return errors.Wrap(err, "failed to assigned priorityClass to nmi")
 		}
-		c = "kubectl --kubeconfig " + kubeconfigPath + " -n " + p.capxName + "-system rollout status ds capz-nmi --timeout 90s"
+		c = "kubectl --kubeconfig " + commons.ShellQuote(kubeconfigPath) + " -n " + commons.ShellQuote(p.capxName) + "-system rollout status ds capz-nmi --timeout 90s"
 		_, err = commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
 			return errors.Wrap(err, "failed to check rollout status for nmi")
@@ -1251,7 +1662,7 @@ func (p *Provider) configCAPIWorker(n nodes.Node, keosCluster commons.KeosCluste
 	}
 
 	// Scale number of replicas to 2 for capi service
-	c = "kubectl --kubeconfig " + kubeconfigPath + " -n capi-system scale deploy capi-controller-manager --replicas 2"
+	c = "kubectl --kubeconfig " + commons.ShellQuote(kubeconfigPath) + " -n capi-system scale deploy capi-controller-manager --replicas 2"
 	_, err = commons.ExecuteCommand(n, c, 5, 3)
 	if err != nil {
 		return errors.Wrap(err, "failed to scale the CAPI Deployment")
@@ -1264,12 +1675,12 @@ func (p *Provider) configCAPIWorker(n nodes.Node, keosCluster commons.KeosCluste
 	// Scale number of required replicas for capi kubeadm services
 	for _, deployment := range capiDeployments {
 		if deployment.name != "capi-controller-manager" {
-			c = "kubectl --kubeconfig " + kubeconfigPath + " -n " + deployment.namespace + " scale --replicas " + strconv.Itoa(capiKubeadmReplicas) + " deploy " + deployment.name
+			c = "kubectl --kubeconfig " + commons.ShellQuote(kubeconfigPath) + " -n " + commons.ShellQuote(deployment.namespace) + " scale --replicas " + strconv.Itoa(capiKubeadmReplicas) + " deploy " + commons.ShellQuote(deployment.name)
 			_, err = commons.ExecuteCommand(n, c, 5, 3)
 			if err != nil {
 				return errors.Wrap(err, "failed to scale the "+deployment.name+" deployment")
 			}
-			c = "kubectl --kubeconfig " + kubeconfigPath + " -n " + deployment.namespace + " rollout status deploy " + deployment.name + " --timeout 60s"
+			c = "kubectl --kubeconfig " + commons.ShellQuote(kubeconfigPath) + " -n " + commons.ShellQuote(deployment.namespace) + " rollout status deploy " + commons.ShellQuote(deployment.name) + " --timeout 60s"
 			_, err = commons.ExecuteCommand(n, c, 5, 3)
 			if err != nil {
 				return errors.Wrap(err, "failed to check rollout status for "+deployment.name)
@@ -1278,17 +1689,16 @@ func (p *Provider) configCAPIWorker(n nodes.Node, keosCluster commons.KeosCluste
 	}
 
 	// Define PodDisruptionBudget for capi services
-	capiPDB, err := getManifest("common", "capi_pdb.tmpl", "", keosCluster.Spec)
+	capiPDB, err := getCAPIPDBTemplate(keosCluster.Spec).render()
 	if err != nil {
 		return errors.Wrap(err, "failed to get PodDisruptionBudget file")
 	}
-	c = "echo '" + capiPDB + "' > " + capiPDBPath
-	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	err = commons.WriteFile(n, capiPDBPath, capiPDB)
 	if err != nil {
 		return errors.Wrap(err, "failed to create PodDisruptionBudget file")
 	}
 
-	c = "kubectl --kubeconfig " + kubeconfigPath + " apply -f " + capiPDBPath
+	c = "kubectl --kubeconfig " + commons.ShellQuote(kubeconfigPath) + " apply -f " + commons.ShellQuote(capiPDBPath)
 	_, err = commons.ExecuteCommand(n, c, 5, 3)
 
 	if err != nil {
@@ -1298,6 +1708,133 @@ func (p *Provider) configCAPIWorker(n nodes.Node, keosCluster commons.KeosCluste
 	return nil
 }
 
+// configureCAPIProviders overrides the providers of the clusterctl config with the ones in the
+// ClusterConfig. The components served from https mirrors are downloaded into the local repository,
+// as clusterctl only knows how to fetch them from GitHub, GitLab or the local filesystem
+func configureCAPIProviders(n nodes.Node, capiProviders []commons.CAPIProvider) error {
+	var c string
+	var err error
+
+	clusterctlConfig, err := getClusterctlConfig(n)
+	if err != nil {
+		return err
+	}
+	providers, _ := clusterctlConfig["providers"].([]interface{})
+
+	for _, capiProvider := range capiProviders {
+		url := capiProvider.URL
+		if strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "https://github.com/") {
+			urlPath := url[:strings.LastIndex(url, "/")]
+			version := urlPath[strings.LastIndex(urlPath, "/")+1:]
+			componentsFile := url[strings.LastIndex(url, "/")+1:]
+			providerPath := CAPILocalRepository + "/" + getCAPIProviderLabel(capiProvider) + "/" + version
+
+			c = "mkdir -p " + commons.ShellQuote(providerPath) +
+				" && curl -fsSL " + commons.ShellQuote(url) + " -o " + commons.ShellQuote(providerPath) + "/" + commons.ShellQuote(componentsFile) +
+				" && curl -fsSL " + commons.ShellQuote(urlPath) + "/metadata.yaml -o " + commons.ShellQuote(providerPath) + "/metadata.yaml"
+			_, err = commons.ExecuteCommand(n, c, 5, 3)
+			if err != nil {
+				return errors.Wrap(err, "failed to download "+capiProvider.Name+" provider components from "+urlPath)
+			}
+			url = providerPath + "/" + componentsFile
+		}
+		if capiProvider.SHA256 != "" {
+			err = verifyChecksum(n, url, capiProvider.SHA256)
+			if err != nil {
+				return errors.Wrap(err, "failed to verify "+capiProvider.Name+" provider components")
+			}
+		}
+
+		// Replace the provider if it is already defined
+		var filteredProviders []interface{}
+		for _, provider := range providers {
+			if p, ok := provider.(map[string]interface{}); ok && p["name"] == capiProvider.Name && p["type"] == capiProvider.Type {
+				continue
+			}
+			filteredProviders = append(filteredProviders, provider)
+		}
+		providers = append(filteredProviders, map[string]interface{}{
+			"name": capiProvider.Name,
+			"url":  url,
+			"type": capiProvider.Type,
+		})
+	}
+	clusterctlConfig["providers"] = providers
+
+	return writeClusterctlConfig(n, clusterctlConfig)
+}
+
+// getClusterctlConfig returns the clusterctl config of the node
+func getClusterctlConfig(n nodes.Node) (map[string]interface{}, error) {
+	var clusterctlConfig map[string]interface{}
+
+	c := "cat " + clusterctlConfigPath
+	clusterctlConfigRaw, err := commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read clusterctl config")
+	}
+	err = yaml.Unmarshal([]byte(clusterctlConfigRaw), &clusterctlConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse clusterctl config")
+	}
+	if clusterctlConfig == nil {
+		clusterctlConfig = map[string]interface{}{}
+	}
+	return clusterctlConfig, nil
+}
+
+func writeClusterctlConfig(n nodes.Node, clusterctlConfig map[string]interface{}) error {
+	clusterctlConfigYAML, err := yaml.Marshal(clusterctlConfig)
+	if err != nil {
+		return err
+	}
+	err = commons.WriteFile(n, clusterctlConfigPath, string(clusterctlConfigYAML))
+	if err != nil {
+		return errors.Wrap(err, "failed to write clusterctl config")
+	}
+	return nil
+}
+
+// setClusterctlConfig sets the values of the clusterctl config, replacing the ones already set
+func setClusterctlConfig(n nodes.Node, values map[string]interface{}) error {
+	clusterctlConfig, err := getClusterctlConfig(n)
+	if err != nil {
+		return err
+	}
+	for key, value := range values {
+		clusterctlConfig[key] = value
+	}
+	return writeClusterctlConfig(n, clusterctlConfig)
+}
+
+// verifyChecksum checks the sha256 checksum of a file in the node
+func verifyChecksum(n nodes.Node, path string, sha256 string) error {
+	c := "sha256sum " + commons.ShellQuote(path) + " | cut -d ' ' -f 1"
+	output, err := commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to compute the checksum of "+path)
+	}
+	checksum := strings.TrimSpace(output)
+	if checksum != strings.ToLower(sha256) {
+		return errors.New("checksum mismatch for " + path + ": expected sha256 " + sha256 + ", got " + checksum)
+	}
+	return nil
+}
+
+// getCAPIProviderLabel returns the directory name of the provider in a clusterctl local repository
+func getCAPIProviderLabel(capiProvider commons.CAPIProvider) string {
+	switch capiProvider.Type {
+	case "CoreProvider":
+		return "cluster-api"
+	case "BootstrapProvider":
+		return "bootstrap-" + capiProvider.Name
+	case "ControlPlaneProvider":
+		return "control-plane-" + capiProvider.Name
+	default:
+		return "infrastructure-" + capiProvider.Name
+	}
+}
+
 // installCAPXLocal installs CAPX in the local cluster
 func (p *Provider) installCAPXLocal(n nodes.Node) error {
 	var c string
@@ -1305,7 +1842,7 @@ func (p *Provider) installCAPXLocal(n nodes.Node) error {
 
 	if p.capxProvider == "azure" {
 		// Create capx namespace
-		c = "kubectl create namespace " + p.capxName + "-system"
+		c = "kubectl create namespace " + commons.ShellQuote(p.capxName) + "-system"
 		_, err = commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
 			return errors.Wrap(err, "failed to create CAPx namespace")
@@ -1315,10 +1852,8 @@ func (p *Provider) installCAPXLocal(n nodes.Node) error {
 		namespace := p.capxName + "-system"
 		clientSecret, _ := base64.StdEncoding.DecodeString(strings.Split(p.capxEnvVars[0], "AZURE_CLIENT_SECRET_B64=")[1])
 
-		c := fmt.Sprintf(
-			"kubectl -n %s create secret generic cluster-identity-secret "+
-				"--from-literal=clientSecret='%s' ",
-			namespace, clientSecret)
+		c := "kubectl -n " + commons.ShellQuote(namespace) + " create secret generic cluster-identity-secret " +
+			commons.ShellQuote("--from-literal=clientSecret="+string(clientSecret))
 		_, err = commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
 			return errors.Wrap(err, "failed to create CAPx secret")
@@ -1327,66 +1862,107 @@ func (p *Provider) installCAPXLocal(n nodes.Node) error {
 
 	c = "clusterctl init --wait-providers" +
 		" --core " + CAPICoreProvider + ":" + CAPIVersion +
-		" --bootstrap " + CAPIBootstrapProvider + ":" + CAPIVersion +
-		" --control-plane " + CAPIControlPlaneProvider + ":" + CAPIVersion +
-		" --infrastructure " + p.capxProvider + ":" + p.capxVersion
+		" --bootstrap " + commons.ShellQuote(p.capiBootstrap) +
+		" --control-plane " + commons.ShellQuote(p.capiControlPlane) +
+		" --infrastructure " + commons.ShellQuote(p.capxProvider) + ":" + commons.ShellQuote(p.capxVersion)
 	_, err = commons.ExecuteCommand(n, c, 5, 3, p.capxEnvVars)
 	if err != nil {
 		return errors.Wrap(err, "failed to install CAPX in local cluster")
 	}
 
+	return p.configureCAPXArgs(n, "")
+}
+
+// configureCAPXArgs appends the extra args of the descriptor to the infrastructure provider manager
+func (p *Provider) configureCAPXArgs(n nodes.Node, kubeconfigPath string) error {
+	if len(p.capxExtraArgs) == 0 {
+		return nil
+	}
+
+	var patch []map[string]string
+	for _, arg := range p.capxExtraArgs {
+		patch = append(patch, map[string]string{"op": "add", "path": "/spec/template/spec/containers/0/args/-", "value": arg})
+	}
+	patchJSON, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+
+	kubectl := "kubectl"
+	if kubeconfigPath != "" {
+		kubectl = kubectl + " --kubeconfig " + commons.ShellQuote(kubeconfigPath)
+	}
+	c := kubectl + " -n " + commons.ShellQuote(p.capxName) + "-system patch deploy " + commons.ShellQuote(p.capxName) + "-controller-manager --type=json -p " + commons.ShellQuote(string(patchJSON))
+	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to set the extra args of "+p.capxName+"-controller-manager")
+	}
+	c = kubectl + " -n " + commons.ShellQuote(p.capxName) + "-system rollout status deploy " + commons.ShellQuote(p.capxName) + "-controller-manager --timeout 60s"
+	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to check rollout status for "+p.capxName+"-controller-manager")
+	}
 	return nil
 }
 
 func enableSelfHealing(n nodes.Node, keosCluster commons.KeosCluster, namespace string, clusterConfig *commons.ClusterConfig) error {
-	var c string
 	var err error
+	var mhcManifests []string
 
 	if !keosCluster.Spec.ControlPlane.Managed {
 		machineRole := "-control-plane-node"
 		controlplane_maxunhealty := 34
+		var controlplaneConfig commons.ControlplaneConfig
 		if clusterConfig != nil {
-			if clusterConfig.Spec.ControlplaneConfig.MaxUnhealthy != nil {
-				controlplane_maxunhealty = *clusterConfig.Spec.ControlplaneConfig.MaxUnhealthy
+			controlplaneConfig = clusterConfig.Spec.ControlplaneConfig
+			if controlplaneConfig.MaxUnhealthy != nil {
+				controlplane_maxunhealty = *controlplaneConfig.MaxUnhealthy
 			}
 		}
 
-		err = generateMHCManifest(n, keosCluster.Metadata.Name, namespace, machineHealthCheckControlPlaneNodePath, machineRole, controlplane_maxunhealty)
+		err = generateMHCManifest(n, keosCluster.Metadata.Name, namespace, machineHealthCheckControlPlaneNodePath, machineRole, controlplane_maxunhealty, controlplaneConfig.NodeStartupTimeout, controlplaneConfig.UnhealthyConditions)
 		if err != nil {
 			return errors.Wrap(err, "failed to create the MachineHealthCheck manifest")
 		}
-		c = "kubectl -n " + namespace + " apply -f " + machineHealthCheckControlPlaneNodePath
-		_, err = commons.ExecuteCommand(n, c, 5, 3)
-		if err != nil {
-			return errors.Wrap(err, "failed to apply the MachineHealthCheck manifest")
-		}
+		mhcManifests = append(mhcManifests, machineHealthCheckControlPlaneNodePath)
 	}
 
 	machineRole := "-worker-node"
 	workernode_maxunhealty := 34
+	var workersConfig commons.WorkersConfig
 	if clusterConfig != nil {
-		if clusterConfig.Spec.WorkersConfig.MaxUnhealthy != nil {
-			workernode_maxunhealty = *clusterConfig.Spec.WorkersConfig.MaxUnhealthy
+		workersConfig = clusterConfig.Spec.WorkersConfig
+		if workersConfig.MaxUnhealthy != nil {
+			workernode_maxunhealty = *workersConfig.MaxUnhealthy
 		}
 	}
-	err = generateMHCManifest(n, keosCluster.Metadata.Name, namespace, machineHealthCheckWorkerNodePath, machineRole, workernode_maxunhealty)
+	err = generateMHCManifest(n, keosCluster.Metadata.Name, namespace, machineHealthCheckWorkerNodePath, machineRole, workernode_maxunhealty, workersConfig.NodeStartupTimeout, workersConfig.UnhealthyConditions)
 	if err != nil {
 		return errors.Wrap(err, "failed to create the MachineHealthCheck manifest")
 	}
-	c = "kubectl -n " + namespace + " apply -f " + machineHealthCheckWorkerNodePath
-	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	mhcManifests = append(mhcManifests, machineHealthCheckWorkerNodePath)
+	err = applyManifestFiles(n, "", 5, 3, mhcManifests...)
 	if err != nil {
-		return errors.Wrap(err, "failed to apply the MachineHealthCheck manifest")
+		return errors.Wrap(err, "failed to apply the MachineHealthCheck manifests")
 	}
 
 	return nil
 }
 
-func generateMHCManifest(n nodes.Node, clusterID string, namespace string, manifestPath string, machineRole string, maxunhealthy int) error {
-	var c string
+func generateMHCManifest(n nodes.Node, clusterID string, namespace string, manifestPath string, machineRole string, maxunhealthy int, nodeStartupTimeout string, unhealthyConditions []commons.UnhealthyCondition) error {
 	var err error
 	var maxUnhealthy = strconv.Itoa(maxunhealthy) + "%"
 
+	if nodeStartupTimeout == "" {
+		nodeStartupTimeout = defaultNodeStartupTimeout
+	}
+	if len(unhealthyConditions) == 0 {
+		unhealthyConditions = []commons.UnhealthyCondition{
+			{Type: "Ready", Status: "Unknown", Timeout: defaultUnhealthyTimeout},
+			{Type: "Ready", Status: "False", Timeout: defaultUnhealthyTimeout},
+		}
+	}
+
 	var machineHealthCheck = `
 apiVersion: cluster.x-k8s.io/v1beta1
 kind: MachineHealthCheck
@@ -1395,21 +1971,20 @@ metadata:
   namespace: ` + namespace + `
 spec:
   clusterName: ` + clusterID + `
-  nodeStartupTimeout: 300s
+  nodeStartupTimeout: ` + nodeStartupTimeout + `
   maxUnhealthy: ` + maxUnhealthy + `
   selector:
     matchLabels:
       keos.stratio.com/machine-role: ` + clusterID + machineRole + `
-  unhealthyConditions:
-    - type: Ready
-      status: Unknown
-      timeout: 180s
-    - type: Ready
-      status: 'False'
-      timeout: 180s`
-
-	c = "echo \"" + machineHealthCheck + "\" > " + manifestPath
-	_, err = commons.ExecuteCommand(n, c, 5, 3)
+  unhealthyConditions:`
+	for _, condition := range unhealthyConditions {
+		machineHealthCheck += `
+    - type: ` + condition.Type + `
+      status: '` + condition.Status + `'
+      timeout: ` + condition.Timeout
+	}
+
+	err = commons.WriteFile(n, manifestPath, machineHealthCheck)
 	if err != nil {
 		return errors.Wrap(err, "failed to write the MachineHealthCheck manifest")
 	}
@@ -1424,7 +1999,14 @@ func getManifest(parentPath string, name string, majorVersion string, params int
 	}
 
 	var tpl bytes.Buffer
-	t, err := template.New("").ParseFS(ctel, templatePath)
+	var t *template.Template
+	var err error
+	// User templates take precedence over the embedded ones
+	if overridePath := getTemplateOverride(strings.TrimPrefix(templatePath, "templates")); overridePath != "" {
+		t, err = template.New("").ParseFiles(overridePath)
+	} else {
+		t, err = template.New("").ParseFS(ctel, templatePath)
+	}
 	if err != nil {
 		return "", err
 	}
@@ -1436,8 +2018,33 @@ func getManifest(parentPath string, name string, majorVersion string, params int
 	return tpl.String(), nil
 }
 
+// manifestTemplate is a template rendered in the provisioning of the cluster, along with the params
+// it is rendered with, so the rendered manifests can be checked without provisioning any cluster
+type manifestTemplate struct {
+	parentPath string
+	name       string
+	version    string
+	params     interface{}
+}
+
+func (t manifestTemplate) render() (string, error) {
+	return getManifest(t.parentPath, t.name, t.version, t.params)
+}
+
+// getTemplateOverride returns the path of the user version of a template, if any
+func getTemplateOverride(path string) string {
+	if templatesDir == "" {
+		return ""
+	}
+	overridePath := filepath.Join(templatesDir, path)
+	if _, err := os.Stat(overridePath); err != nil {
+		return ""
+	}
+	return overridePath
+}
+
 func patchDeploy(n nodes.Node, k string, ns string, deployName string, patch string) error {
-	c := "kubectl --kubeconfig " + k + " patch deploy -n " + ns + " " + deployName + " -p '" + patch + "'"
+	c := "kubectl --kubeconfig " + commons.ShellQuote(k) + " patch deploy -n " + commons.ShellQuote(ns) + " " + commons.ShellQuote(deployName) + " -p " + commons.ShellQuote(patch)
 	_, err := commons.ExecuteCommand(n, c, 5, 3)
 	if err != nil {
 		return err
@@ -1446,7 +2053,7 @@ func patchDeploy(n nodes.Node, k string, ns string, deployName string, patch str
 }
 
 func rolloutStatus(n nodes.Node, k string, ns string, deployName string) error {
-	c := "kubectl --kubeconfig " + k + " rollout status deploy -n " + ns + " " + deployName + " --timeout=5m"
+	c := "kubectl --kubeconfig " + commons.ShellQuote(k) + " rollout status deploy -n " + commons.ShellQuote(ns) + " " + commons.ShellQuote(deployName) + " --timeout=5m"
 	_, err := commons.ExecuteCommand(n, c, 5, 3)
 	return err
 }
@@ -1460,13 +2067,12 @@ func installCorednsPdb(n nodes.Node) error {
 		return err
 	}
 
-	c := "echo \"" + corednsPDB + "\" > " + corednsPdbPath
-	_, err = commons.ExecuteCommand(n, c, 5, 3)
+	err = commons.WriteFile(n, corednsPdbPath, corednsPDB)
 	if err != nil {
 		return errors.Wrap(err, "failed to create coredns PodDisruptionBudget file")
 	}
 
-	c = "kubectl --kubeconfig " + kubeconfigPath + " apply -f " + corednsPdbPath
+	c := "kubectl --kubeconfig " + kubeconfigPath + " apply -f " + corednsPdbPath
 	_, err = commons.ExecuteCommand(n, c, 5, 3)
 	if err != nil {
 		return errors.Wrap(err, "failed to apply coredns PodDisruptionBudget")
@@ -1484,14 +2090,21 @@ func pullCharts(n nodes.Node, charts map[string]commons.ChartEntry, keosSpec com
 		if chart.Pull {
 			var c string
 			if strings.HasPrefix(chart.Repository, "oci://") {
-				c = "helm pull " + chart.Repository + "/" + name + " --version " + chart.Version + " --untar --untardir /stratio/helm"
+				c = "helm pull " + commons.ShellQuote(chart.Repository) + "/" + commons.ShellQuote(name) + " --version " + commons.ShellQuote(chart.Version)
+			} else {
+				c = "helm pull " + commons.ShellQuote(name) + " --version " + commons.ShellQuote(chart.Version) + " --repo " + commons.ShellQuote(chart.Repository)
+			}
+			// Charts with a digest are verified before being extracted
+			chartPackage := chartsPackagesPath + "/" + name + "-" + chart.Version + ".tgz"
+			if chart.Digest != "" {
+				c = "mkdir -p " + chartsPackagesPath + " && " + c + " --destination " + chartsPackagesPath
 			} else {
-				c = "helm pull " + name + " --version " + chart.Version + " --repo " + chart.Repository + " --untar --untardir /stratio/helm"
+				c = c + " --untar --untardir /stratio/helm"
 			}
 			// Add authentication if required
 			if chart.Repository == keosSpec.HelmRepository.URL && keosSpec.HelmRepository.AuthRequired {
 				if keosSpec.HelmRepository.AuthRequired {
-					c = c + " --username " + clusterCredentials.HelmRepositoryCredentials["User"] + " --password " + clusterCredentials.HelmRepositoryCredentials["Pass"]
+					c = c + " " + commons.ShellQuote("--username", clusterCredentials.HelmRepositoryCredentials["User"], "--password", clusterCredentials.HelmRepositoryCredentials["Pass"])
 				}
 			}
 			// Execute the command
@@ -1499,6 +2112,17 @@ func pullCharts(n nodes.Node, charts map[string]commons.ChartEntry, keosSpec com
 			if err != nil {
 				return errors.Wrap(err, "failed to pull the helm chart: "+fmt.Sprint(chart))
 			}
+			if chart.Digest != "" {
+				err = verifyChecksum(n, chartPackage, chart.Digest)
+				if err != nil {
+					return errors.Wrap(err, "failed to verify the helm chart: "+name)
+				}
+				c = "tar -xzf " + commons.ShellQuote(chartPackage) + " -C /stratio/helm"
+				_, err = commons.ExecuteCommand(n, c, 5, 3)
+				if err != nil {
+					return errors.Wrap(err, "failed to extract the helm chart: "+name)
+				}
+			}
 		}
 	}
 	return nil
@@ -1526,7 +2150,7 @@ func loginHelmRepo(n nodes.Node, keosCluster commons.KeosCluster, clusterCredent
 		stratio_helm_repo = helmRepoCreds.URL
 		urlLogin := strings.Split(strings.Split(keosCluster.Spec.HelmRepository.URL, "//")[1], "/")[0]
 
-		c := "helm registry login " + urlLogin + " --username " + helmRepoCreds.User + " --password " + helmRepoCreds.Pass
+		c := "helm registry login " + commons.ShellQuote(urlLogin) + " " + commons.ShellQuote("--username", helmRepoCreds.User, "--password", helmRepoCreds.Pass)
 		_, err := commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
 			return errors.Wrap(err, "failed to add and authenticate to helm repository: "+helmRepoCreds.URL)
@@ -1535,14 +2159,14 @@ func loginHelmRepo(n nodes.Node, keosCluster commons.KeosCluster, clusterCredent
 		helmRepository.user = clusterCredentials.HelmRepositoryCredentials["User"]
 		helmRepository.pass = clusterCredentials.HelmRepositoryCredentials["Pass"]
 		stratio_helm_repo = "stratio-helm-repo"
-		c := "helm repo add " + stratio_helm_repo + " " + helmRepoCreds.URL + " --username " + helmRepoCreds.User + " --password " + helmRepoCreds.Pass
+		c := "helm repo add " + commons.ShellQuote(stratio_helm_repo) + " " + commons.ShellQuote(helmRepoCreds.URL) + " " + commons.ShellQuote("--username", helmRepoCreds.User, "--password", helmRepoCreds.Pass)
 		_, err := commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
 			return errors.Wrap(err, "failed to add and authenticate to helm repository: "+helmRepository.url)
 		}
 	} else {
 		stratio_helm_repo = "stratio-helm-repo"
-		c := "helm repo add " + stratio_helm_repo + " " + helmRepoCreds.URL
+		c := "helm repo add " + commons.ShellQuote(stratio_helm_repo) + " " + commons.ShellQuote(helmRepoCreds.URL)
 		_, err := commons.ExecuteCommand(n, c, 5, 3)
 		if err != nil {
 			return errors.Wrap(err, "failed to add helm repository: "+helmRepoCreds.URL)
@@ -0,0 +1,691 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/exec"
+)
+
+// providerConformance describes a provider to the conformance suite. Every builder must pass the
+// suite, so a new provider is contributed along with its Test<Provider>BuilderConformance
+type providerConformance struct {
+	infraProvider string
+	// capxProvider is the name of the provider in Cluster API, and of its templates, when it is not
+	// the infra provider
+	capxProvider string
+	newBuilder   func() PBuilder
+	credentials  map[string]string
+	// managed is whether the provider supports managed control planes
+	managed bool
+	// cloudProviderTemplate is the template of the cloud provider, rendered with the
+	// cloudControllerHelmParams unless cloudProviderParams returns others
+	cloudProviderTemplate string
+	cloudProviderParams   func(privateParams PrivateParams, helmParams cloudControllerHelmParams) interface{}
+	// csiTemplates are the templates rendered with the PrivateParams
+	csiTemplates []string
+	// singleVolumeType is whether the provider offers a single volume type, so the storage class may
+	// have no parameters and the premium class is the standard one
+	singleVolumeType bool
+	// unencrypted is whether the volumes cannot be encrypted with a key of the descriptor
+	unencrypted bool
+	// localVolumes is whether the volumes are the disks of the nodes, which cannot be expanded
+	localVolumes bool
+}
+
+// templatesPath returns the directory of the templates of the provider
+func (pc providerConformance) templatesPath() string {
+	if pc.capxProvider != "" {
+		return pc.capxProvider
+	}
+	return pc.infraProvider
+}
+
+// conformanceDescriptor is a descriptor of the matrix the builders are checked against
+type conformanceDescriptor struct {
+	name         string
+	majorVersion string
+	// templatesVersion is the majorVersion, or empty if the templates of the provider are not versioned
+	templatesVersion string
+	keosCluster      commons.KeosCluster
+	private          bool
+}
+
+const conformanceRegistry = "conformance.registry.example/keos"
+
+var conformanceEnvVar = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*=`)
+
+func TestAWSBuilderConformance(t *testing.T) {
+	runProviderConformance(t, providerConformance{
+		infraProvider: "aws",
+		newBuilder:    func() PBuilder { return newAWSBuilder() },
+		credentials: map[string]string{
+			"AccessKey": "AKIACONFORMANCE",
+			"SecretKey": "conformance-secret-key",
+		},
+		managed:               true,
+		cloudProviderTemplate: "aws-cloud-controller-manager-helm-values.tmpl",
+		csiTemplates:          []string{"aws-ebs-csi-driver-helm-values.tmpl"},
+	})
+}
+
+func TestGCPBuilderConformance(t *testing.T) {
+	runProviderConformance(t, providerConformance{
+		infraProvider: "gcp",
+		newBuilder:    func() PBuilder { return newGCPBuilder() },
+		credentials: map[string]string{
+			"ProjectID":    "conformance-project",
+			"PrivateKeyID": "conformance-private-key-id",
+			"PrivateKey":   "conformance-private-key",
+			"ClientEmail":  "conformance@conformance-project.iam.gserviceaccount.com",
+			"ClientID":     "conformance-client-id",
+		},
+		managed:               true,
+		cloudProviderTemplate: "gcp-cloud-controller-manager-helm-values.tmpl",
+		csiTemplates:          []string{"gcp-compute-persistent-disk-csi-driver.tmpl"},
+	})
+}
+
+func TestAzureBuilderConformance(t *testing.T) {
+	runProviderConformance(t, providerConformance{
+		infraProvider: "azure",
+		newBuilder:    func() PBuilder { return newAzureBuilder() },
+		credentials: map[string]string{
+			"ClientID":       "conformance-client-id",
+			"ClientSecret":   "conformance-client-secret",
+			"SubscriptionID": "conformance-subscription-id",
+			"TenantID":       "conformance-tenant-id",
+		},
+		managed:               true,
+		cloudProviderTemplate: "cloud-provider-azure-helm-values.tmpl",
+		csiTemplates:          []string{"azuredisk-csi-driver-helm-values.tmpl", "azurefile-csi-driver-helm-values.tmpl"},
+	})
+}
+
+func TestEquinixBuilderConformance(t *testing.T) {
+	runProviderConformance(t, providerConformance{
+		infraProvider: "equinix",
+		capxProvider:  "packet",
+		newBuilder:    func() PBuilder { return newEquinixBuilder() },
+		credentials: map[string]string{
+			"ApiKey":    "conformance-api-key",
+			"ProjectID": "conformance-project-id",
+		},
+		cloudProviderTemplate: "cloud-provider-equinix-metal.tmpl",
+		cloudProviderParams: func(privateParams PrivateParams, _ cloudControllerHelmParams) interface{} {
+			return privateParams
+		},
+		csiTemplates:     []string{"local-path-provisioner.tmpl"},
+		singleVolumeType: true,
+		unencrypted:      true,
+		localVolumes:     true,
+	})
+}
+
+func TestHetznerBuilderConformance(t *testing.T) {
+	runProviderConformance(t, providerConformance{
+		infraProvider: "hetzner",
+		newBuilder:    func() PBuilder { return newHetznerBuilder() },
+		credentials: map[string]string{
+			"Token": "conformance-token",
+		},
+		cloudProviderTemplate: "hcloud-cloud-controller-manager-helm-values.tmpl",
+		csiTemplates:          []string{"hcloud-csi-helm-values.tmpl"},
+		singleVolumeType:      true,
+		unencrypted:           true,
+	})
+}
+
+func TestNutanixBuilderConformance(t *testing.T) {
+	runProviderConformance(t, providerConformance{
+		infraProvider: "nutanix",
+		newBuilder:    func() PBuilder { return newNutanixBuilder() },
+		credentials: map[string]string{
+			"Endpoint": "prism-central.conformance.example:9440",
+			"Username": "conformance-username",
+			"Password": "conformance-password",
+		},
+		cloudProviderTemplate: "nutanix-cloud-provider-helm-values.tmpl",
+		cloudProviderParams: func(_ PrivateParams, helmParams cloudControllerHelmParams) interface{} {
+			return nutanixCloudControllerHelmParams{
+				ClusterName: helmParams.ClusterName,
+				Private:     helmParams.Private,
+				KeosRegUrl:  helmParams.KeosRegUrl,
+				Endpoint:    "prism-central.conformance.example",
+				Port:        nutanixDefaultPort,
+			}
+		},
+		csiTemplates: []string{"nutanix-csi-storage-helm-values.tmpl"},
+		unencrypted:  true,
+	})
+}
+
+func TestDigitalOceanBuilderConformance(t *testing.T) {
+	runProviderConformance(t, providerConformance{
+		infraProvider: "digitalocean",
+		newBuilder:    func() PBuilder { return newDigitalOceanBuilder() },
+		credentials: map[string]string{
+			"Token": "conformance-token",
+		},
+		cloudProviderTemplate: "digitalocean-cloud-controller-manager.tmpl",
+		cloudProviderParams: func(privateParams PrivateParams, _ cloudControllerHelmParams) interface{} {
+			return privateParams
+		},
+		csiTemplates:     []string{"csi-digitalocean.tmpl"},
+		singleVolumeType: true,
+		unencrypted:      true,
+	})
+}
+
+func TestIBMCloudBuilderConformance(t *testing.T) {
+	runProviderConformance(t, providerConformance{
+		infraProvider: "ibmcloud",
+		newBuilder:    func() PBuilder { return newIBMCloudBuilder() },
+		credentials: map[string]string{
+			"ApiKey": "conformance-api-key",
+		},
+		cloudProviderTemplate: "ibm-cloud-controller-manager.tmpl",
+		cloudProviderParams: func(privateParams PrivateParams, helmParams cloudControllerHelmParams) interface{} {
+			return ibmCloudProviderParams{
+				ClusterName: helmParams.ClusterName,
+				Private:     helmParams.Private,
+				KeosRegUrl:  helmParams.KeosRegUrl,
+				Region:      privateParams.KeosCluster.Spec.Region,
+				Target:      "vpc",
+			}
+		},
+		csiTemplates: []string{"ibm-vpc-block-csi-driver.tmpl", "ibm-powervs-block-csi-driver.tmpl"},
+	})
+}
+
+func TestAlibabaCloudBuilderConformance(t *testing.T) {
+	runProviderConformance(t, providerConformance{
+		infraProvider: "alibabacloud",
+		newBuilder:    func() PBuilder { return newAlibabaCloudBuilder() },
+		credentials: map[string]string{
+			"AccessKeyID":     "conformance-access-key-id",
+			"AccessKeySecret": "conformance-access-key-secret",
+		},
+		cloudProviderTemplate: "alibaba-cloud-controller-manager.tmpl",
+		cloudProviderParams: func(privateParams PrivateParams, _ cloudControllerHelmParams) interface{} {
+			return privateParams
+		},
+		csiTemplates: []string{"alibaba-cloud-csi-driver.tmpl"},
+	})
+}
+
+// runProviderConformance runs the conformance suite for the provider. The charts and the templates
+// depend on the package majorVersion, so the descriptors are not run in parallel
+func runProviderConformance(t *testing.T, pc providerConformance) {
+	t.Helper()
+	defer func(v string) { majorVersion = v }(majorVersion)
+
+	for _, d := range conformanceDescriptors(t, pc) {
+		d := d
+		t.Run(d.name, func(t *testing.T) {
+			majorVersion = d.majorVersion
+			t.Run("provider", func(t *testing.T) { testConformanceProvider(t, pc, d) })
+			t.Run("storage class", func(t *testing.T) { testConformanceStorageClass(t, pc, d) })
+			t.Run("templates", func(t *testing.T) { testConformanceTemplates(t, pc, d) })
+			t.Run("charts", func(t *testing.T) { testConformanceCharts(t, pc, d) })
+		})
+	}
+	if !pc.singleVolumeType {
+		t.Run("premium storage class", func(t *testing.T) { testConformancePremiumStorageClass(t, pc) })
+	}
+	t.Run("env vars without leaks", func(t *testing.T) { testConformanceEnvVarLeaks(t, pc) })
+}
+
+// conformanceDescriptors returns the matrix of the supported Kubernetes versions, the control plane
+// types, the registries and the storage classes
+func conformanceDescriptors(t *testing.T, pc providerConformance) []conformanceDescriptor {
+	t.Helper()
+	entries, err := fs.ReadDir(ctel, path.Join("templates", pc.templatesPath()))
+	if err != nil {
+		t.Fatalf("failed to read the templates of %s: %v", pc.infraProvider, err)
+	}
+	var versions []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			versions = append(versions, entry.Name())
+		}
+	}
+	// The templates which are not versioned are checked against every version of the common charts
+	versionedTemplates := len(versions) > 0
+	if !versionedTemplates {
+		for version := range commonsCharts.Charts {
+			versions = append(versions, version)
+		}
+		sort.Strings(versions)
+	}
+	if len(versions) == 0 {
+		t.Fatalf("no versions found for %s", pc.infraProvider)
+	}
+
+	clusterTypes := []string{"unmanaged"}
+	if pc.managed {
+		clusterTypes = append(clusterTypes, "managed")
+	}
+	storageClasses := map[string]commons.StorageClass{
+		"standard":  {Class: "standard"},
+		"premium":   {Class: "premium"},
+		"encrypted": {EncryptionKey: "conformance-encryption-key"},
+	}
+	if pc.unencrypted {
+		delete(storageClasses, "encrypted")
+	}
+	var scNames []string
+	for name := range storageClasses {
+		scNames = append(scNames, name)
+	}
+	sort.Strings(scNames)
+
+	var descriptors []conformanceDescriptor
+	for _, version := range versions {
+		for _, clusterType := range clusterTypes {
+			for _, private := range []bool{false, true} {
+				for _, scName := range scNames {
+					registry := "public"
+					if private {
+						registry = "private"
+					}
+					keosCluster := commons.KeosCluster{
+						APIVersion: "installer.stratio.com/v1beta1",
+						Kind:       "KeosCluster",
+						Metadata:   commons.Metadata{Name: "conformance"},
+					}
+					keosCluster.Spec.InfraProvider = pc.infraProvider
+					keosCluster.Spec.K8SVersion = "v1." + version + ".0"
+					keosCluster.Spec.Region = "conformance-region"
+					keosCluster.Spec.ControlPlane.Managed = clusterType == "managed"
+					keosCluster.Spec.StorageClass = storageClasses[scName]
+					descriptor := conformanceDescriptor{
+						name:         "1." + version + "/" + clusterType + "/" + registry + "/" + scName,
+						majorVersion: version,
+						keosCluster:  keosCluster,
+						private:      private,
+					}
+					if versionedTemplates {
+						descriptor.templatesVersion = version
+					}
+					descriptors = append(descriptors, descriptor)
+				}
+			}
+		}
+	}
+	return descriptors
+}
+
+func conformanceProviderParams(keosCluster commons.KeosCluster, credentials map[string]string) ProviderParams {
+	return ProviderParams{
+		ClusterName:  keosCluster.Metadata.Name,
+		Region:       keosCluster.Spec.Region,
+		Managed:      keosCluster.Spec.ControlPlane.Managed,
+		Credentials:  credentials,
+		StorageClass: keosCluster.Spec.StorageClass,
+	}
+}
+
+func testConformanceProvider(t *testing.T, pc providerConformance, d conformanceDescriptor) {
+	provider := newInfra(pc.newBuilder()).buildProvider(conformanceProviderParams(d.keosCluster, pc.credentials))
+
+	if provider.capxProvider != pc.templatesPath() {
+		t.Errorf("expected the %s capx provider, got %q", pc.templatesPath(), provider.capxProvider)
+	}
+	for name, value := range map[string]string{
+		"capx name":          provider.capxName,
+		"capx version":       provider.capxVersion,
+		"capx image version": provider.capxImageVersion,
+		"CAPI bootstrap":     provider.capiBootstrap,
+		"CAPI control plane": provider.capiControlPlane,
+		"SC provisioner":     provider.scProvisioner,
+		"CSI namespace":      provider.csiNamespace,
+	} {
+		if value == "" {
+			t.Errorf("the %s is not set", name)
+		}
+	}
+	if provider.capxManaged != d.keosCluster.Spec.ControlPlane.Managed {
+		t.Errorf("expected managed %t, got %t", d.keosCluster.Spec.ControlPlane.Managed, provider.capxManaged)
+	}
+
+	names := map[string]bool{}
+	for _, envVar := range provider.capxEnvVars {
+		if !conformanceEnvVar.MatchString(envVar) {
+			t.Errorf("invalid env var %q", strings.SplitN(envVar, "=", 2)[0])
+			continue
+		}
+		name := strings.SplitN(envVar, "=", 2)[0]
+		if names[name] {
+			t.Errorf("duplicated env var %s", name)
+		}
+		names[name] = true
+	}
+}
+
+func testConformanceStorageClass(t *testing.T, pc providerConformance, d conformanceDescriptor) {
+	provider, storageClass := conformanceStorageClass(t, pc, d.keosCluster)
+
+	if storageClass.Provisioner != provider.scProvisioner {
+		t.Errorf("expected the %s provisioner, got %q", provider.scProvisioner, storageClass.Provisioner)
+	}
+	if !storageClass.AllowVolumeExpansion && !pc.localVolumes {
+		t.Errorf("the volume expansion is not allowed")
+	}
+	if storageClass.AllowVolumeExpansion && pc.localVolumes {
+		t.Errorf("the expansion of the local volumes is allowed")
+	}
+	if storageClass.VolumeBindingMode != "WaitForFirstConsumer" {
+		t.Errorf("expected the WaitForFirstConsumer binding mode, got %q", storageClass.VolumeBindingMode)
+	}
+	if len(storageClass.Parameters) == 0 && !pc.singleVolumeType {
+		t.Errorf("the storage class has no parameters")
+	}
+	if len(storageClass.Parameters) > 0 && pc.localVolumes {
+		t.Errorf("the local volumes take no parameters, got %v", storageClass.Parameters)
+	}
+	if _, ok := storageClass.Parameters["fsType"]; ok {
+		t.Errorf("the fsType parameter is not prefixed with csi.storage.k8s.io")
+	}
+	if key := d.keosCluster.Spec.StorageClass.EncryptionKey; key != "" {
+		encrypted := false
+		for _, value := range storageClass.Parameters {
+			encrypted = encrypted || value == key
+		}
+		if !encrypted {
+			t.Errorf("the encryption key is not set in the parameters %v", storageClass.Parameters)
+		}
+	}
+}
+
+func testConformancePremiumStorageClass(t *testing.T, pc providerConformance) {
+	keosCluster := commons.KeosCluster{Metadata: commons.Metadata{Name: "conformance"}}
+	keosCluster.Spec.InfraProvider = pc.infraProvider
+	keosCluster.Spec.StorageClass = commons.StorageClass{Class: "standard"}
+	_, standard := conformanceStorageClass(t, pc, keosCluster)
+	keosCluster.Spec.StorageClass = commons.StorageClass{Class: "premium"}
+	_, premium := conformanceStorageClass(t, pc, keosCluster)
+
+	if reflect.DeepEqual(standard.Parameters, premium.Parameters) {
+		t.Errorf("the premium class has the parameters of the standard one: %v", premium.Parameters)
+	}
+}
+
+type conformanceStorageClassManifest struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name        string            `yaml:"name"`
+		Annotations map[string]string `yaml:"annotations"`
+	} `yaml:"metadata"`
+	AllowVolumeExpansion bool              `yaml:"allowVolumeExpansion"`
+	Provisioner          string            `yaml:"provisioner"`
+	Parameters           map[string]string `yaml:"parameters"`
+	VolumeBindingMode    string            `yaml:"volumeBindingMode"`
+}
+
+// conformanceStorageClass configures the storage classes of the descriptor and returns the default one,
+// which must be the only default one applied
+func conformanceStorageClass(t *testing.T, pc providerConformance, keosCluster commons.KeosCluster) (Provider, conformanceStorageClassManifest) {
+	t.Helper()
+	infra := newInfra(pc.newBuilder())
+	provider := infra.buildProvider(conformanceProviderParams(keosCluster, pc.credentials))
+	node := &conformanceNode{}
+	if err := infra.configureStorageClass(node, kubeconfigPath); err != nil {
+		t.Fatalf("failed to configure the storage class: %v", err)
+	}
+
+	var defaults []conformanceStorageClassManifest
+	for _, stdin := range node.stdins {
+		decoder := yaml.NewDecoder(strings.NewReader(stdin))
+		for {
+			var manifest conformanceStorageClassManifest
+			err := decoder.Decode(&manifest)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatalf("invalid storage class manifest: %v", err)
+			}
+			if manifest.Kind == "StorageClass" && manifest.Metadata.Annotations[defaultScAnnotation] == "true" {
+				defaults = append(defaults, manifest)
+			}
+		}
+	}
+	if len(defaults) != 1 {
+		t.Fatalf("expected one default storage class, got %d", len(defaults))
+	}
+	if defaults[0].Metadata.Name != scName {
+		t.Errorf("expected the %s default storage class, got %q", scName, defaults[0].Metadata.Name)
+	}
+	return provider, defaults[0]
+}
+
+func testConformanceTemplates(t *testing.T, pc providerConformance, d conformanceDescriptor) {
+	privateParams := PrivateParams{KeosCluster: d.keosCluster, Private: d.private, HelmPrivate: d.private}
+	if d.private {
+		privateParams.KeosRegUrl = conformanceRegistry
+	}
+
+	// The images of the cloud provider and the CSI drivers are pulled from the private registry
+	cloudProviderParams := cloudControllerHelmParams{
+		ClusterName: d.keosCluster.Metadata.Name,
+		Private:     privateParams.Private,
+		KeosRegUrl:  privateParams.KeosRegUrl,
+		PodsCidr:    "192.168.0.0/16",
+	}
+	var params interface{} = cloudProviderParams
+	if pc.cloudProviderParams != nil {
+		params = pc.cloudProviderParams(privateParams, cloudProviderParams)
+	}
+	// The external cloud provider is only deployed in the versions with its chart, unless it is deployed
+	// with manifests
+	charts := newInfra(pc.newBuilder()).getProviderCharts(&commons.ClusterConfigSpec{}, d.keosCluster.Spec)
+	cloudProviderChart := strings.TrimSuffix(pc.cloudProviderTemplate, "-helm-values.tmpl")
+	if _, ok := charts[cloudProviderChart]; ok || cloudProviderChart == pc.cloudProviderTemplate {
+		rendered := conformanceRender(t, pc.templatesPath(), pc.cloudProviderTemplate, d.templatesVersion, params)
+		if d.private && !strings.Contains(rendered, conformanceRegistry) {
+			t.Errorf("%s does not use the private registry", pc.cloudProviderTemplate)
+		}
+	}
+	for _, csiTemplate := range pc.csiTemplates {
+		rendered := conformanceRender(t, pc.templatesPath(), csiTemplate, d.templatesVersion, privateParams)
+		if d.private && !strings.Contains(rendered, conformanceRegistry) {
+			t.Errorf("%s does not use the private registry", csiTemplate)
+		}
+	}
+
+	// The unversioned templates of the provider and the PDBs are rendered with the spec
+	entries, err := fs.ReadDir(ctel, path.Join("templates", pc.templatesPath()))
+	if err != nil {
+		t.Fatalf("failed to read the templates of %s: %v", pc.infraProvider, err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && entry.Name() != pc.cloudProviderTemplate && !commons.Contains(pc.csiTemplates, entry.Name()) {
+			conformanceRender(t, pc.templatesPath(), entry.Name(), "", d.keosCluster.Spec)
+		}
+	}
+	for _, pdbTemplate := range []string{"capx_pdb.tmpl", "capi_pdb.tmpl"} {
+		conformanceRender(t, "common", pdbTemplate, "", d.keosCluster.Spec)
+	}
+}
+
+// conformanceRender renders the template, which must be valid and not empty YAML
+func conformanceRender(t *testing.T, parentPath string, name string, version string, params interface{}) string {
+	t.Helper()
+	rendered, err := getManifest(parentPath, name, version, params)
+	if err != nil {
+		t.Errorf("failed to render %s: %v", name, err)
+		return ""
+	}
+	decoder := yaml.NewDecoder(strings.NewReader(rendered))
+	documents := 0
+	for {
+		var document interface{}
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Errorf("%s is not valid YAML: %v", name, err)
+			return rendered
+		}
+		if document != nil {
+			documents++
+		}
+	}
+	if documents == 0 {
+		t.Errorf("%s is empty", name)
+	}
+	return rendered
+}
+
+func testConformanceCharts(t *testing.T, pc providerConformance, d conformanceDescriptor) {
+	charts := newInfra(pc.newBuilder()).getProviderCharts(&commons.ClusterConfigSpec{}, d.keosCluster.Spec)
+	if len(charts) == 0 {
+		t.Fatalf("no charts for the %s clusters", d.name)
+	}
+	for name, chart := range charts {
+		if chart.Repository == "" || chart.Version == "" || chart.Namespace == "" {
+			t.Errorf("the %s chart is incomplete: %+v", name, chart)
+		}
+	}
+}
+
+// testConformanceEnvVarLeaks checks that the env vars are only generated from the given params: the
+// process environment is not modified, the credentials are not mutated, and the credentials of a
+// build are not found in another one, even when the builder is reused
+func testConformanceEnvVarLeaks(t *testing.T, pc providerConformance) {
+	keosCluster := commons.KeosCluster{Metadata: commons.Metadata{Name: "conformance"}}
+	keosCluster.Spec.InfraProvider = pc.infraProvider
+	keosCluster.Spec.Region = "conformance-region"
+
+	credentials := map[string]string{}
+	otherCredentials := map[string]string{}
+	for name, value := range pc.credentials {
+		credentials[name] = value
+		otherCredentials[name] = "other-" + value
+	}
+	environ := os.Environ()
+
+	infra := newInfra(pc.newBuilder())
+	other := infra.buildProvider(conformanceProviderParams(keosCluster, otherCredentials))
+	provider := infra.buildProvider(conformanceProviderParams(keosCluster, credentials))
+	fresh := newInfra(pc.newBuilder()).buildProvider(conformanceProviderParams(keosCluster, credentials))
+
+	if !reflect.DeepEqual(environ, os.Environ()) {
+		t.Errorf("the process environment was modified")
+	}
+	if !reflect.DeepEqual(credentials, pc.credentials) {
+		t.Errorf("the credentials were mutated")
+	}
+	if !reflect.DeepEqual(provider.capxEnvVars, fresh.capxEnvVars) {
+		t.Errorf("the env vars of a reused builder differ from the ones of a new builder")
+	}
+	for name, value := range otherCredentials {
+		encoded := base64.StdEncoding.EncodeToString([]byte(value))
+		for _, envVar := range provider.capxEnvVars {
+			if strings.Contains(envVar, value) || strings.Contains(envVar, encoded) {
+				t.Errorf("the %s of another build leaked into %s", name, strings.SplitN(envVar, "=", 2)[0])
+			}
+		}
+	}
+	for _, envVars := range [][]string{other.capxEnvVars, provider.capxEnvVars} {
+		for _, envVar := range envVars {
+			if strings.HasPrefix(envVar, "GITHUB_TOKEN=") {
+				t.Errorf("GITHUB_TOKEN is set without a token")
+			}
+		}
+	}
+}
+
+// conformanceNode is a node which records the manifests applied to it, and whose commands succeed
+// without output
+type conformanceNode struct {
+	stdins []string
+}
+
+func (n *conformanceNode) Command(string, ...string) exec.Cmd {
+	return &conformanceCmd{node: n}
+}
+
+func (n *conformanceNode) CommandContext(context.Context, string, ...string) exec.Cmd {
+	return &conformanceCmd{node: n}
+}
+
+func (n *conformanceNode) String() string {
+	return "conformance-control-plane"
+}
+
+func (n *conformanceNode) Role() (string, error) {
+	return "control-plane", nil
+}
+
+func (n *conformanceNode) IP() (string, string, error) {
+	return "", "", nil
+}
+
+func (n *conformanceNode) SerialLogs(io.Writer) error {
+	return nil
+}
+
+type conformanceCmd struct {
+	node  *conformanceNode
+	stdin io.Reader
+}
+
+func (c *conformanceCmd) Run() error {
+	if c.stdin != nil {
+		var stdin bytes.Buffer
+		if _, err := stdin.ReadFrom(c.stdin); err != nil {
+			return err
+		}
+		c.node.stdins = append(c.node.stdins, stdin.String())
+	}
+	return nil
+}
+
+func (c *conformanceCmd) SetEnv(...string) exec.Cmd {
+	return c
+}
+
+func (c *conformanceCmd) SetStdin(r io.Reader) exec.Cmd {
+	c.stdin = r
+	return c
+}
+
+func (c *conformanceCmd) SetStdout(io.Writer) exec.Cmd {
+	return c
+}
+
+func (c *conformanceCmd) SetStderr(io.Writer) exec.Cmd {
+	return c
+}
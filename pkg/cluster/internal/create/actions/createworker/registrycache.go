@@ -0,0 +1,88 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const (
+	registryCacheChart       = "docker-registry"
+	registryCacheDefaultSize = "50Gi"
+	registryCacheDefaultPort = 30500
+)
+
+var registryCacheChartEntry = commons.ChartEntry{Repository: "https://helm.twun.io", Version: "2.2.3", Namespace: "kube-system", Pull: true, Reconcile: false}
+
+// deployRegistryCache deploys a pull-through cache of the upstream registry, backed by the default
+// StorageClass, and sets it as the containerd mirror of the upstream registry in every node
+func deployRegistryCache(n nodes.Node, k string, privateParams PrivateParams, keosRegistry KeosRegistry, registryCache commons.RegistryCache, chartsList map[string]commons.ChartEntry) error {
+	upstream := registryCache.Upstream
+	if upstream == "" {
+		upstream = keosRegistry.url
+	}
+	upstreamHost := strings.SplitN(strings.TrimPrefix(strings.TrimPrefix(upstream, "https://"), "http://"), "/", 2)[0]
+	storageSize := registryCache.StorageSize
+	if storageSize == "" {
+		storageSize = registryCacheDefaultSize
+	}
+	nodePort := registryCache.NodePort
+	if nodePort == 0 {
+		nodePort = registryCacheDefaultPort
+	}
+	proxy := map[string]interface{}{"enabled": true, "remoteurl": "https://" + upstreamHost}
+	if upstream == keosRegistry.url && keosRegistry.user != "" {
+		proxy["username"] = keosRegistry.user
+		proxy["password"] = keosRegistry.pass
+	}
+	helmValues, err := yaml.Marshal(map[string]interface{}{
+		"image":       map[string]string{"repository": getRegistryImage(privateParams), "tag": registryImageTag},
+		"persistence": map[string]interface{}{"enabled": true, "size": storageSize},
+		"service":     map[string]interface{}{"type": "NodePort", "nodePort": nodePort},
+		"proxy":       proxy,
+	})
+	if err != nil {
+		return err
+	}
+	err = commons.WriteFile(n, "/kind/"+registryCacheChart+"-helm-values.yaml", string(helmValues))
+	if err != nil {
+		return errors.Wrap(err, "failed to create "+registryCacheChart+" Helm chart values file")
+	}
+
+	registryCacheEntry := chartsList[registryCacheChart]
+	registryCacheHelmReleaseParams := fluxHelmReleaseParams{
+		ChartRepoRef:   "keos",
+		ChartName:      registryCacheChart,
+		ChartNamespace: registryCacheEntry.Namespace,
+		ChartVersion:   registryCacheEntry.Version,
+	}
+	if !privateParams.HelmPrivate {
+		registryCacheHelmReleaseParams.ChartRepoRef = registryCacheChart
+	}
+	if err := configureHelmRelease(n, k, "flux2_helmrelease.tmpl", registryCacheHelmReleaseParams, privateParams.KeosCluster.Spec.HelmRepository); err != nil {
+		return err
+	}
+
+	registryMirrors := []commons.RegistryMirror{{Registry: upstreamHost, Endpoints: []string{"http://127.0.0.1:" + strconv.Itoa(nodePort)}}}
+	return configureContainerdMirrors(n, k, privateParams, registryMirrors)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const registryLoginSecret = "keos-registry-credentials"
+
+// configureRegistryLogin creates the pull secret of the keos registry in kube-system and the namespaces
+// of the descriptor, which are created if missing, and sets it as the image pull secret of their
+// default ServiceAccount
+func configureRegistryLogin(n nodes.Node, k string, keosRegistry KeosRegistry, registryLogin commons.RegistryLogin) error {
+	server := strings.Split(keosRegistry.url, "/")[0]
+	dockerConfig, err := json.Marshal(map[string]interface{}{
+		"auths": map[string]interface{}{
+			server: map[string]string{
+				"username": keosRegistry.user,
+				"password": keosRegistry.pass,
+				"auth":     base64.StdEncoding.EncodeToString([]byte(keosRegistry.user + ":" + keosRegistry.pass)),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	namespaces := []string{"kube-system"}
+	for _, namespace := range registryLogin.Namespaces {
+		if !commons.Contains(namespaces, namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	batch := newManifestBatch(k)
+	var patches []string
+	for _, namespace := range namespaces {
+		if namespace != "kube-system" {
+			err = batch.add(map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Namespace",
+				"metadata":   map[string]string{"name": namespace},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		err = batch.add(map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"type":       "kubernetes.io/dockerconfigjson",
+			"metadata":   map[string]string{"name": registryLoginSecret, "namespace": namespace},
+			"stringData": map[string]string{".dockerconfigjson": string(dockerConfig)},
+		})
+		if err != nil {
+			return err
+		}
+		// The pull secrets of the ServiceAccount are merged by name, so the existing ones are kept
+		patches = append(patches, "kubectl --kubeconfig "+commons.ShellQuote(k)+" -n "+commons.ShellQuote(namespace)+" patch serviceaccount default "+
+			"-p '{\"imagePullSecrets\": [{\"name\": \""+registryLoginSecret+"\"}]}'")
+	}
+	if err = batch.apply(n); err != nil {
+		return errors.Wrap(err, "failed to create the "+registryLoginSecret+" secrets")
+	}
+	_, err = commons.ExecuteCommand(n, strings.Join(patches, " && "), 5, 3)
+	if err != nil {
+		return errors.Wrap(err, "failed to set the image pull secret of the default ServiceAccounts")
+	}
+	return nil
+}
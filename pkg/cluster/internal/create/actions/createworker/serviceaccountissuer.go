@@ -0,0 +1,68 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+// The discovery documents, relative to the issuer URL, as served by the API server
+var serviceAccountIssuerDocuments = []string{"/.well-known/openid-configuration", "/openid/v1/jwks"}
+
+// exportServiceAccountIssuerDiscovery writes the discovery documents of the service account issuer
+// of the workload cluster, with the layout of the issuer URL they must be uploaded to
+func exportServiceAccountIssuerDiscovery(n nodes.Node, keosCluster commons.KeosCluster, issuer commons.ServiceAccountIssuer) error {
+	discoveryPath := issuer.DiscoveryPath
+	if discoveryPath == "" {
+		discoveryPath = keosCluster.Metadata.Name + "-oidc"
+	}
+
+	for _, document := range serviceAccountIssuerDocuments {
+		raw := bytes.Buffer{}
+		cmd := n.Command("kubectl", "--kubeconfig", kubeconfigPath, "get", "--raw", document)
+		if err := cmd.SetStdout(&raw).Run(); err != nil {
+			return errors.Wrap(err, "failed to get "+document)
+		}
+		// The API server must have been configured with the issuer before it is trusted
+		if document == serviceAccountIssuerDocuments[0] {
+			var configuration struct {
+				Issuer string `json:"issuer"`
+			}
+			if err := json.Unmarshal(raw.Bytes(), &configuration); err != nil {
+				return errors.Wrap(err, "failed to parse "+document)
+			}
+			if configuration.Issuer != issuer.URL {
+				return errors.New("the service account issuer of the API server is " + configuration.Issuer + " instead of " + issuer.URL)
+			}
+		}
+		filename := filepath.Join(discoveryPath, filepath.FromSlash(document))
+		if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filename, raw.Bytes(), 0644); err != nil {
+			return errors.Wrap(err, "failed to write "+document)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"strings"
+	"testing"
+)
+
+// shellCommands are the commands whose command lines are checked when they are concatenated
+var shellCommands = []string{"kubectl", "clusterctl", "helm", "sed", "mkdir", "cat", "cp", "rm", "test", "chmod", "tar", "curl", "sha256sum", "docker"}
+
+// TestShellQuotedCommands checks the command lines run through a shell only concatenate literals,
+// constants and commons.ShellQuote, so the names and paths of the descriptor are never split or
+// expanded by the shell
+func TestShellQuotedCommands(t *testing.T) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The constants of the other files of the package are not resolved by the parser
+	constants := map[string]bool{}
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok == token.CONST {
+					for _, spec := range decl.Specs {
+						for _, name := range spec.(*ast.ValueSpec).Names {
+							constants[name.Name] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	isQuoted := func(operand ast.Expr) bool {
+		switch operand := operand.(type) {
+		case *ast.BasicLit:
+			return true
+		case *ast.Ident:
+			// c and kubectl are the command lines, and their prefixes, being built
+			if operand.Name == "c" || operand.Name == "kubectl" {
+				return true
+			}
+			if operand.Obj == nil {
+				return constants[operand.Name]
+			}
+			return operand.Obj.Kind == ast.Con
+		case *ast.CallExpr:
+			if fun, ok := operand.Fun.(*ast.SelectorExpr); ok {
+				return fun.Sel.Name == "ShellQuote" || fun.Sel.Name == "Itoa"
+			}
+		}
+		return false
+	}
+	check := func(command ast.Expr, isCommand bool) {
+		var operands []ast.Expr
+		var flatten func(e ast.Expr)
+		flatten = func(e ast.Expr) {
+			switch e := e.(type) {
+			case *ast.BinaryExpr:
+				if e.Op == token.ADD {
+					flatten(e.X)
+					flatten(e.Y)
+					return
+				}
+			case *ast.ParenExpr:
+				flatten(e.X)
+				return
+			}
+			operands = append(operands, e)
+		}
+		flatten(command)
+		switch first := operands[0].(type) {
+		case *ast.BasicLit:
+			for _, name := range shellCommands {
+				isCommand = isCommand || strings.HasPrefix(strings.Trim(first.Value, "\"`"), name+" ")
+			}
+		case *ast.Ident:
+			isCommand = isCommand || first.Name == "kubectl"
+		}
+		if !isCommand {
+			return
+		}
+		for _, operand := range operands {
+			if !isQuoted(operand) {
+				t.Errorf("%s: the command line is not quoted with commons.ShellQuote", fset.Position(operand.Pos()))
+			}
+		}
+	}
+
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			ast.Inspect(file, func(node ast.Node) bool {
+				switch node := node.(type) {
+				case *ast.AssignStmt:
+					if name, ok := node.Lhs[0].(*ast.Ident); ok && name.Name == "c" && len(node.Rhs) == 1 {
+						check(node.Rhs[0], true)
+						return false
+					}
+				case *ast.CallExpr:
+					// The commands joined with && are checked where each one is built
+					if fun, ok := node.Fun.(*ast.SelectorExpr); ok && fun.Sel.Name == "ExecuteCommand" && len(node.Args) > 1 {
+						if join, ok := node.Args[1].(*ast.CallExpr); !ok || !isJoinedCommands(join) {
+							check(node.Args[1], true)
+						}
+					}
+				case *ast.BinaryExpr:
+					if node.Op == token.ADD {
+						check(node, false)
+						return false
+					}
+				}
+				return true
+			})
+		}
+	}
+}
+
+// isJoinedCommands returns whether the call joins a list of commands with &&
+func isJoinedCommands(call *ast.CallExpr) bool {
+	fun, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || fun.Sel.Name != "Join" || len(call.Args) != 2 {
+		return false
+	}
+	separator, ok := call.Args[1].(*ast.BasicLit)
+	return ok && separator.Value == `" && "`
+}
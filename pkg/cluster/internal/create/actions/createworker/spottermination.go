@@ -0,0 +1,134 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const (
+	awsNodeTerminationHandlerChart = "aws-node-termination-handler"
+	aksNodeTerminationHandlerChart = "aks-node-termination-handler"
+	aksNodeTerminationHandlerImage = "paskalmaksim/aks-node-termination-handler:v1.1.5"
+	spotShutdownName               = "keos-spot-shutdown"
+	// The spot nodes are labeled so the termination handlers only run in them
+	spotNodeLabel = "keos.stratio.com/spot"
+)
+
+var spotTerminationChartEntries = map[string]commons.ChartEntry{
+	awsNodeTerminationHandlerChart: {Repository: "https://aws.github.io/eks-charts", Version: "0.21.0", Namespace: "kube-system", Pull: true, Reconcile: false},
+	aksNodeTerminationHandlerChart: {Repository: "https://maksim-paskal.github.io/aks-node-termination-handler", Version: "1.1.5", Namespace: "kube-system", Pull: true, Reconcile: false},
+}
+
+// GCP preemptions are notified 30 seconds before the instances are stopped, which the kubelet
+// spends terminating the pods once the shutdown is inhibited
+var spotShutdownGracePeriod = "# " + spotShutdownName + "-begin\n" +
+	"shutdownGracePeriod: 30s\n" +
+	"shutdownGracePeriodCriticalPods: 10s\n" +
+	"# " + spotShutdownName + "-end"
+
+var spotShutdownScript = "f=/var/lib/kubelet/config.yaml && l=/etc/systemd/logind.conf.d/90-keos.conf && mkdir -p /etc/systemd/logind.conf.d && " +
+	"printf '[Login]\\nInhibitDelayMaxSec=30\\n' > $l.keos && " +
+	"if cmp -s $l $l.keos; then rm $l.keos; else mv $l.keos $l && systemctl restart systemd-logind; fi && " +
+	"sed -e '/^# " + spotShutdownName + "-begin/,/^# " + spotShutdownName + "-end/d' -e '/^shutdownGracePeriod/d' $f > $f.keos && " +
+	"printf '%s\\n' \"$SHUTDOWN\" >> $f.keos && " +
+	"if cmp -s $f $f.keos; then rm $f.keos; else mv $f.keos $f && systemctl restart kubelet; fi"
+
+// getSpotTerminationChart returns the chart of the termination handler of the provider, if any
+func getSpotTerminationChart(infraProvider string) string {
+	switch infraProvider {
+	case "aws":
+		return awsNodeTerminationHandlerChart
+	case "azure":
+		return aksNodeTerminationHandlerChart
+	}
+	return ""
+}
+
+// deploySpotTermination drains the spot nodes gracefully on preemption. The termination handlers
+// of aws and azure cordon and drain the nodes on the interruption notices of the instance metadata,
+// while the gcp nodes rely on the kubelet graceful node shutdown, as preemptions stop the instances
+func deploySpotTermination(n nodes.Node, k string, privateParams PrivateParams, spotTermination commons.SpotTermination, chartsList map[string]commons.ChartEntry) error {
+	infraProvider := privateParams.KeosCluster.Spec.InfraProvider
+	nodeSelector := map[string]string{spotNodeLabel: "true"}
+	tolerations := []map[string]string{{"operator": "Exists"}}
+
+	var helmValues map[string]interface{}
+	switch infraProvider {
+	case "aws":
+		helmValues = map[string]interface{}{
+			"enableSpotInterruptionDraining": true,
+			"enableScheduledEventDraining":   true,
+			"enableRebalanceMonitoring":      spotTermination.RebalanceDraining,
+			"enableRebalanceDraining":        spotTermination.RebalanceDraining,
+			"daemonsetNodeSelector":          nodeSelector,
+			"daemonsetTolerations":           tolerations,
+		}
+		if privateParams.Private {
+			helmValues["image"] = map[string]string{"repository": privateParams.KeosRegUrl + "/aws-ec2/aws-node-termination-handler"}
+		}
+	case "azure":
+		helmValues = map[string]interface{}{
+			"nodeSelector": nodeSelector,
+			"tolerations":  tolerations,
+		}
+		if privateParams.Private {
+			helmValues["image"] = privateParams.KeosRegUrl + "/" + aksNodeTerminationHandlerImage
+		}
+	case "gcp":
+		return deployNodeConfigs(n, k, privateParams, nodeConfig{
+			name:         spotShutdownName,
+			nodeSelector: nodeSelector,
+			hostPID:      true,
+			initContainer: map[string]interface{}{
+				"name":            "kubelet-config",
+				"command":         []string{"chroot", "/host", "sh", "-c", spotShutdownScript},
+				"env":             []map[string]string{{"name": "SHUTDOWN", "value": spotShutdownGracePeriod}},
+				"securityContext": map[string]bool{"privileged": true},
+				"volumeMounts":    []map[string]string{{"name": "host", "mountPath": "/host"}},
+			},
+			volumes: []map[string]interface{}{{"name": "host", "hostPath": map[string]string{"path": "/"}}},
+		})
+	default:
+		return errors.New("spot termination is not supported in " + infraProvider + " clusters")
+	}
+
+	chart := getSpotTerminationChart(infraProvider)
+	chartEntry := chartsList[chart]
+	helmValuesYAML, err := yaml.Marshal(helmValues)
+	if err != nil {
+		return err
+	}
+	err = commons.WriteFile(n, "/kind/"+chart+"-helm-values.yaml", string(helmValuesYAML))
+	if err != nil {
+		return errors.Wrap(err, "failed to create "+chart+" Helm chart values file")
+	}
+
+	helmReleaseParams := fluxHelmReleaseParams{
+		ChartRepoRef:   "keos",
+		ChartName:      chart,
+		ChartNamespace: chartEntry.Namespace,
+		ChartVersion:   chartEntry.Version,
+	}
+	if !privateParams.HelmPrivate {
+		helmReleaseParams.ChartRepoRef = chart
+	}
+	return configureHelmRelease(n, k, "flux2_helmrelease.tmpl", helmReleaseParams, privateParams.KeosCluster.Spec.HelmRepository)
+}
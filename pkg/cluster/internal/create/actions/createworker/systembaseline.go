@@ -0,0 +1,114 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const kubeletReservedName = "keos-kubelet-reserved"
+
+// The standard PriorityClasses, from the most to the least critical workloads
+var priorityClasses = []struct {
+	name        string
+	value       int
+	description string
+}{
+	{"keos-critical", 1000000, "Platform services the cluster cannot work without"},
+	{"keos-high", 100000, "Platform services"},
+	{"keos-medium", 10000, "Business workloads"},
+	{"keos-low", 1000, "Batch and best-effort workloads"},
+}
+
+var defaultKubeReserved = map[string]string{"cpu": "100m", "memory": "512Mi", "ephemeral-storage": "1Gi"}
+var defaultSystemReserved = map[string]string{"cpu": "100m", "memory": "256Mi", "ephemeral-storage": "1Gi"}
+
+// createPriorityClasses creates the standard PriorityClasses in the workload cluster
+func createPriorityClasses(n nodes.Node, k string) error {
+	var resources []string
+	for _, pc := range priorityClasses {
+		priorityClass, err := yaml.Marshal(map[string]interface{}{
+			"apiVersion":  "scheduling.k8s.io/v1",
+			"kind":        "PriorityClass",
+			"metadata":    map[string]string{"name": pc.name},
+			"value":       pc.value,
+			"description": pc.description,
+		})
+		if err != nil {
+			return err
+		}
+		resources = append(resources, string(priorityClass))
+	}
+	cmd := n.Command("kubectl", "--kubeconfig", k, "apply", "-f", "-")
+	if err := cmd.SetStdin(strings.NewReader(strings.Join(resources, "---\n"))).Run(); err != nil {
+		return errors.Wrap(err, "failed to create the PriorityClasses")
+	}
+	return nil
+}
+
+// reserveKubeletResources sets the kube-reserved and system-reserved resources in the kubelet config of
+// every node, restarting the kubelet only when the config changes
+func reserveKubeletResources(n nodes.Node, k string, privateParams PrivateParams, systemBaseline commons.SystemBaseline) error {
+	kubeReserved := systemBaseline.KubeReserved
+	if len(kubeReserved) == 0 {
+		kubeReserved = defaultKubeReserved
+	}
+	systemReserved := systemBaseline.SystemReserved
+	if len(systemReserved) == 0 {
+		systemReserved = defaultSystemReserved
+	}
+	reserved := "# " + kubeletReservedName + "-begin\n" +
+		reservedResources("kubeReserved", kubeReserved) +
+		reservedResources("systemReserved", systemReserved) +
+		"# " + kubeletReservedName + "-end"
+	script := "f=/var/lib/kubelet/config.yaml && " +
+		"sed '/^# " + kubeletReservedName + "-begin/,/^# " + kubeletReservedName + "-end/d' $f > $f.keos && " +
+		"printf '%s\\n' \"$RESERVED\" >> $f.keos && " +
+		"if cmp -s $f $f.keos; then rm $f.keos; else mv $f.keos $f && systemctl restart kubelet; fi"
+
+	return deployNodeConfigs(n, k, privateParams, nodeConfig{
+		name:    kubeletReservedName,
+		hostPID: true,
+		initContainer: map[string]interface{}{
+			"name":            "kubelet-config",
+			"command":         []string{"chroot", "/host", "sh", "-c", script},
+			"env":             []map[string]string{{"name": "RESERVED", "value": reserved}},
+			"securityContext": map[string]bool{"privileged": true},
+			"volumeMounts":    []map[string]string{{"name": "host", "mountPath": "/host"}},
+		},
+		volumes: []map[string]interface{}{{"name": "host", "hostPath": map[string]string{"path": "/"}}},
+	})
+}
+
+func reservedResources(field string, resources map[string]string) string {
+	var names []string
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	reserved := field + ":\n"
+	for _, name := range names {
+		reserved += "  " + name + ": \"" + resources[name] + "\"\n"
+	}
+	return reserved
+}
@@ -0,0 +1,289 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+// The upstream releases of the infrastructure providers, whose components are the reference the
+// templates selecting the provider objects are checked against
+var capxReleaseURLs = map[string]string{
+	"aws":   "https://github.com/kubernetes-sigs/cluster-api-provider-aws/releases/download/",
+	"azure": "https://github.com/kubernetes-sigs/cluster-api-provider-azure/releases/download/",
+	"gcp":   "https://github.com/kubernetes-sigs/cluster-api-provider-gcp/releases/download/",
+}
+
+// capxComponents are the objects of the infrastructure components of a provider release which
+// the templates and the provisioner depend on
+type capxComponents struct {
+	// served versions of the CRDs, by kind
+	crds map[string][]string
+	// controllers, by namespace/name
+	deployments map[string]capxDeployment
+}
+
+type capxDeployment struct {
+	namespace string
+	labels    map[string]string
+	images    map[string]string
+	args      map[string][]string
+}
+
+// capxObject holds the fields of the component objects which are compared
+type capxObject struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Names struct {
+			Kind string `yaml:"kind"`
+		} `yaml:"names"`
+		Versions []struct {
+			Name   string `yaml:"name"`
+			Served bool   `yaml:"served"`
+		} `yaml:"versions"`
+		Selector struct {
+			MatchLabels map[string]string `yaml:"matchLabels"`
+		} `yaml:"selector"`
+		Template struct {
+			Metadata struct {
+				Labels map[string]string `yaml:"labels"`
+			} `yaml:"metadata"`
+			Spec struct {
+				Containers []struct {
+					Name  string   `yaml:"name"`
+					Image string   `yaml:"image"`
+					Args  []string `yaml:"args"`
+				} `yaml:"containers"`
+			} `yaml:"spec"`
+		} `yaml:"template"`
+	} `yaml:"spec"`
+}
+
+// DiffCAPXTemplates reports the drift of the provider version to upgrade to: the embedded templates
+// whose selectors no longer match its components, and the differences of its CRDs and controllers
+// with the components of the version the provisioner installs
+func DiffCAPXTemplates(infraProvider string, capxVersion string) ([]string, error) {
+	releaseURL, ok := capxReleaseURLs[infraProvider]
+	if !ok {
+		return nil, errors.New("the templates of " + infraProvider + " have no upstream reference, only aws, azure and gcp are supported")
+	}
+	builder := getBuilder(infraProvider)
+	builder.setCapx(false)
+	provider := builder.getProvider()
+
+	current, err := getCAPXComponents(releaseURL + provider.capxVersion + "/infrastructure-components.yaml")
+	if err != nil {
+		return nil, err
+	}
+	target, err := getCAPXComponents(releaseURL + capxVersion + "/infrastructure-components.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []string
+	capxPDB, err := getManifest("common", "capx_pdb.tmpl", "", commons.KeosSpec{InfraProvider: infraProvider})
+	if err != nil {
+		return nil, err
+	}
+	selectorDrifts, err := diffTemplateSelectors("common/capx_pdb.tmpl", capxPDB, target)
+	if err != nil {
+		return nil, err
+	}
+	drifts = append(drifts, selectorDrifts...)
+	drifts = append(drifts, diffCAPXComponents(provider.capxVersion, capxVersion, current, target)...)
+	return drifts, nil
+}
+
+func getCAPXComponents(url string) (capxComponents, error) {
+	components := capxComponents{crds: map[string][]string{}, deployments: map[string]capxDeployment{}}
+
+	client := &http.Client{Timeout: time.Minute}
+	resp, err := client.Get(url)
+	if err != nil {
+		return components, errors.Wrap(err, "failed to download "+url)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return components, errors.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+
+	decoder := yaml.NewDecoder(resp.Body)
+	for {
+		var object capxObject
+		err := decoder.Decode(&object)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return components, errors.Wrap(err, "failed to parse "+url)
+		}
+		switch object.Kind {
+		case "CustomResourceDefinition":
+			var versions []string
+			for _, version := range object.Spec.Versions {
+				if version.Served {
+					versions = append(versions, version.Name)
+				}
+			}
+			components.crds[object.Spec.Names.Kind] = versions
+		case "Deployment":
+			deployment := capxDeployment{
+				namespace: object.Metadata.Namespace,
+				labels:    object.Spec.Template.Metadata.Labels,
+				images:    map[string]string{},
+				args:      map[string][]string{},
+			}
+			for _, container := range object.Spec.Template.Spec.Containers {
+				deployment.images[container.Name] = container.Image
+				deployment.args[container.Name] = container.Args
+			}
+			components.deployments[object.Metadata.Namespace+"/"+object.Metadata.Name] = deployment
+		}
+	}
+	return components, nil
+}
+
+// diffTemplateSelectors reports the PodDisruptionBudgets of the rendered template which select
+// none of the controllers of the components
+func diffTemplateSelectors(template string, manifest string, components capxComponents) ([]string, error) {
+	var drifts []string
+	decoder := yaml.NewDecoder(strings.NewReader(manifest))
+	for {
+		var object capxObject
+		err := decoder.Decode(&object)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse "+template)
+		}
+		if object.Kind != "PodDisruptionBudget" {
+			continue
+		}
+		selected := false
+		for _, deployment := range components.deployments {
+			if deployment.namespace == object.Metadata.Namespace && matchLabels(deployment.labels, object.Spec.Selector.MatchLabels) {
+				selected = true
+			}
+		}
+		if !selected {
+			drifts = append(drifts, template+": the PodDisruptionBudget "+object.Metadata.Namespace+"/"+object.Metadata.Name+" selects no controller")
+		}
+	}
+	return drifts, nil
+}
+
+// diffCAPXComponents reports the CRDs and controllers added, removed or changed by the target version
+func diffCAPXComponents(currentVersion string, targetVersion string, current capxComponents, target capxComponents) []string {
+	var drifts []string
+	for _, kind := range sortedKeys(current.crds, target.crds) {
+		currentVersions, inCurrent := current.crds[kind]
+		targetVersions, inTarget := target.crds[kind]
+		switch {
+		case !inTarget:
+			drifts = append(drifts, "CRD "+kind+": removed in "+targetVersion)
+		case !inCurrent:
+			drifts = append(drifts, "CRD "+kind+": added in "+targetVersion)
+		case strings.Join(currentVersions, ",") != strings.Join(targetVersions, ","):
+			drifts = append(drifts, "CRD "+kind+": serves "+strings.Join(targetVersions, ", ")+" instead of "+strings.Join(currentVersions, ", "))
+		}
+	}
+
+	for _, name := range sortedKeys(current.deployments, target.deployments) {
+		currentDeployment, inCurrent := current.deployments[name]
+		targetDeployment, inTarget := target.deployments[name]
+		switch {
+		case !inTarget:
+			drifts = append(drifts, "Deployment "+name+": removed in "+targetVersion)
+			continue
+		case !inCurrent:
+			drifts = append(drifts, "Deployment "+name+": added in "+targetVersion)
+			continue
+		}
+		for _, container := range sortedKeys(currentDeployment.images, targetDeployment.images) {
+			currentImage, inCurrent := currentDeployment.images[container]
+			targetImage, inTarget := targetDeployment.images[container]
+			switch {
+			case !inTarget:
+				drifts = append(drifts, "Deployment "+name+": container "+container+" removed in "+targetVersion)
+				continue
+			case !inCurrent:
+				drifts = append(drifts, "Deployment "+name+": container "+container+" added in "+targetVersion)
+				continue
+			}
+			// The image tags change with every release, only the repositories are compared
+			if imageRepository(currentImage) != imageRepository(targetImage) {
+				drifts = append(drifts, "Deployment "+name+": container "+container+" image "+targetImage+" instead of "+currentImage)
+			}
+			for _, arg := range targetDeployment.args[container] {
+				if !commons.Contains(currentDeployment.args[container], arg) {
+					drifts = append(drifts, "Deployment "+name+": container "+container+" arg "+arg+" added in "+targetVersion)
+				}
+			}
+			for _, arg := range currentDeployment.args[container] {
+				if !commons.Contains(targetDeployment.args[container], arg) {
+					drifts = append(drifts, "Deployment "+name+": container "+container+" arg "+arg+" removed since "+currentVersion)
+				}
+			}
+		}
+	}
+	return drifts
+}
+
+// imageRepository returns the image without its tag
+func imageRepository(image string) string {
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i]
+	}
+	return image
+}
+
+func matchLabels(labels map[string]string, selector map[string]string) bool {
+	for key, value := range selector {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return len(selector) > 0
+}
+
+// sortedKeys returns the keys of both maps, sorted
+func sortedKeys[V any](a map[string]V, b map[string]V) []string {
+	var keys []string
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
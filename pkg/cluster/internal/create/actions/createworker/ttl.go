@@ -0,0 +1,166 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package createworker
+
+import (
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const (
+	expiresAtAnnotation = "keos.stratio.com/expires-at"
+	clusterTTLName      = "cloud-provisioner-ttl"
+	kubectlImage        = "alpine/k8s"
+	kubectlImageTag     = "1.30.5"
+)
+
+// setClusterExpiration annotates the keoscluster with the time its TTL expires
+func setClusterExpiration(keosCluster *commons.KeosCluster, started time.Time) error {
+	ttl, err := time.ParseDuration(keosCluster.Spec.TTL)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse the TTL of the cluster")
+	}
+	if keosCluster.Metadata.Annotations == nil {
+		keosCluster.Metadata.Annotations = map[string]string{}
+	}
+	keosCluster.Metadata.Annotations[expiresAtAnnotation] = started.Add(ttl).UTC().Format(time.RFC3339)
+	return nil
+}
+
+// deployClusterTTL creates the CronJob which deletes the keoscluster once the expiration of its
+// annotation is reached, so the cluster-operator destroys the workload cluster. The annotation is
+// read on each run, so editing it extends the lifetime of the cluster. The CronJob runs in the local
+// cluster, which keeps the management of the workload cluster, since a self-managed cluster would
+// be deleted from its own nodes. The image is pinned, and pulled from the keos registry in the
+// private clusters, as the local cluster has no other source of images then
+func deployClusterTTL(n nodes.Node, privateParams PrivateParams, keosCluster commons.KeosCluster, namespace string) error {
+	image := kubectlImage + ":" + kubectlImageTag
+	if privateParams.Private {
+		image = privateParams.KeosRegUrl + "/" + image
+	}
+	// The deletion is recorded in the local cluster, as the workload cluster is being destroyed
+	record := "now=$(date -u +%Y-%m-%dT%H:%M:%SZ); " +
+		"record=cloud-provisioner-delete-$(date -u +%Y%m%d%H%M%S)-$(od -An -N4 -tx1 /dev/urandom | tr -d ' \\n'); " +
+		"kubectl -n " + commons.OperationHistoryNamespace + " create configmap \"$record\" --dry-run=client -o yaml" +
+		" --from-literal=operation=delete --from-literal=cluster=" + keosCluster.Metadata.Name +
+		" --from-literal=user=system:serviceaccount:" + namespace + ":" + clusterTTLName +
+		" --from-literal=started=\"$now\" --from-literal=finished=\"$now\" --from-literal=result=Succeeded" +
+		" | kubectl label --local -f - -o yaml " + commons.OperationHistoryLabel + "=true " +
+		commons.OperationHistoryClusterLabel + "=" + keosCluster.Metadata.Name +
+		" | kubectl create -f -; "
+	// The failures to read the annotation or to delete the keoscluster fail the Job, so they are
+	// reported instead of being retried silently on the next run. The expiration is compared as
+	// text, as the dates in UTC sort as their RFC 3339 text does, so it must keep that format
+	script := "set -eo pipefail; " +
+		"expires=$(kubectl -n " + namespace + " get keoscluster " + keosCluster.Metadata.Name +
+		" -o jsonpath='{.metadata.annotations." + strings.ReplaceAll(expiresAtAnnotation, ".", "\\.") + "}'); " +
+		"if [ -z \"$expires\" ]; then exit 0; fi; " +
+		"if [[ ! \"$expires\" =~ ^[0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9]{2}:[0-9]{2}:[0-9]{2}Z$ ]]; then " +
+		"echo \"the " + expiresAtAnnotation + " annotation is not a date in UTC, as 2006-01-02T15:04:05Z: $expires\"; exit 1; fi; " +
+		"if [[ ! \"$(date -u +%Y-%m-%dT%H:%M:%SZ)\" < \"$expires\" ]]; then " +
+		"kubectl -n " + namespace + " delete keoscluster " + keosCluster.Metadata.Name + " --wait=false; " + record + "fi"
+
+	metadata := map[string]string{"name": clusterTTLName, "namespace": namespace}
+	historyMetadata := map[string]string{"name": clusterTTLName + "-" + keosCluster.Metadata.Name, "namespace": commons.OperationHistoryNamespace}
+	resources := []map[string]interface{}{
+		{
+			"apiVersion": "v1",
+			"kind":       "ServiceAccount",
+			"metadata":   metadata,
+		},
+		{
+			"apiVersion": "rbac.authorization.k8s.io/v1",
+			"kind":       "Role",
+			"metadata":   metadata,
+			"rules": []map[string]interface{}{{
+				"apiGroups":     []string{"installer.stratio.com"},
+				"resources":     []string{"keosclusters"},
+				"resourceNames": []string{keosCluster.Metadata.Name},
+				"verbs":         []string{"get", "delete"},
+			}},
+		},
+		{
+			"apiVersion": "rbac.authorization.k8s.io/v1",
+			"kind":       "RoleBinding",
+			"metadata":   metadata,
+			"roleRef":    map[string]string{"apiGroup": "rbac.authorization.k8s.io", "kind": "Role", "name": clusterTTLName},
+			"subjects":   []map[string]string{{"kind": "ServiceAccount", "name": clusterTTLName, "namespace": namespace}},
+		},
+		{
+			"apiVersion": "rbac.authorization.k8s.io/v1",
+			"kind":       "Role",
+			"metadata":   historyMetadata,
+			"rules": []map[string]interface{}{{
+				"apiGroups": []string{""},
+				"resources": []string{"configmaps"},
+				"verbs":     []string{"create"},
+			}},
+		},
+		{
+			"apiVersion": "rbac.authorization.k8s.io/v1",
+			"kind":       "RoleBinding",
+			"metadata":   historyMetadata,
+			"roleRef":    map[string]string{"apiGroup": "rbac.authorization.k8s.io", "kind": "Role", "name": historyMetadata["name"]},
+			"subjects":   []map[string]string{{"kind": "ServiceAccount", "name": clusterTTLName, "namespace": namespace}},
+		},
+		{
+			"apiVersion": "batch/v1",
+			"kind":       "CronJob",
+			"metadata":   metadata,
+			"spec": map[string]interface{}{
+				"schedule":                   "*/15 * * * *",
+				"concurrencyPolicy":          "Forbid",
+				"successfulJobsHistoryLimit": 1,
+				"jobTemplate": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"backoffLimit": 1,
+						"template": map[string]interface{}{
+							"spec": map[string]interface{}{
+								"serviceAccountName": clusterTTLName,
+								"restartPolicy":      "Never",
+								"containers": []map[string]interface{}{{
+									"name":    "ttl",
+									"image":   image,
+									"command": []string{"/bin/bash", "-c", script},
+								}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	var manifests []string
+	for _, resource := range resources {
+		manifest, err := yaml.Marshal(resource)
+		if err != nil {
+			return err
+		}
+		manifests = append(manifests, string(manifest))
+	}
+	cmd := n.Command("kubectl", "apply", "-f", "-")
+	if err := cmd.SetStdin(strings.NewReader(strings.Join(manifests, "---\n"))).Run(); err != nil {
+		return errors.Wrap(err, "failed to create the "+clusterTTLName+" CronJob")
+	}
+	return nil
+}
@@ -66,9 +66,16 @@ type ClusterOptions struct {
 	ClusterConfig        *commons.ClusterConfig
 	ClusterCredentials   commons.ClusterCredentials
 	DockerRegUrl         string
+	AdoptKubeconfig      string
+	TemplatesDir         string
+	MetricsPushgateway   string
+	Bootstrap            commons.BootstrapConfig
+	OperationID          string
 
 	// Force local container delete before creating the cluster if it already exists
 	ForceDelete bool
+	// Reuse the local container if it already exists, which is kept after the provisioning
+	ReuseBootstrap bool
 	// NodeImage overrides the nodes' images in Config if non-zero
 	NodeImage      string
 	Retain         bool
@@ -82,7 +89,7 @@ type ClusterOptions struct {
 }
 
 // Cluster creates a cluster
-func Cluster(logger log.Logger, p providers.Provider, opts *ClusterOptions) error {
+func Cluster(logger log.Logger, p providers.Provider, opts *ClusterOptions) (err error) {
 	// validate provider first
 	if err := validateProvider(p); err != nil {
 		return err
@@ -94,8 +101,12 @@ func Cluster(logger log.Logger, p providers.Provider, opts *ClusterOptions) erro
 	}
 
 	// Check if the cluster name already exists
+	reused := false
 	if err := alreadyExists(p, opts.Config.Name); err != nil {
-		if opts.ForceDelete {
+		if opts.ReuseBootstrap {
+			// Stratio: the providers of the local cluster are kept warm between runs
+			reused = true
+		} else if opts.ForceDelete {
 			// Delete current cluster container
 			_ = delete.Cluster(nil, p, opts.Config.Name, "")
 		} else {
@@ -117,24 +128,53 @@ func Cluster(logger log.Logger, p providers.Provider, opts *ClusterOptions) erro
 	// setup a status object to show progress to the user
 	status := cli.StatusForLogger(logger)
 
-	// we're going to start creating now, tell the user
-	logger.V(0).Infof("Creating temporary cluster %q ...\n", opts.Config.Name)
+	// optionally notify the outcome of the provisioning
+	if opts.ClusterConfig != nil && opts.ClusterConfig.Spec.Notifications != nil {
+		started := time.Now()
+		defer func() {
+			if notifyErr := notifyProvisioning(*opts.ClusterConfig.Spec.Notifications, opts.KeosCluster, time.Since(started), err); notifyErr != nil {
+				logger.Warnf("failed to send the provisioning notification: %v", notifyErr)
+			}
+		}()
+	}
 
-	// Create node containers implementing defined config Nodes
-	if err := p.Provision(status, opts.Config, opts.DockerRegUrl, opts.UseLocalStratioImage); err != nil {
-		// In case of errors nodes are deleted (except if retain is explicitly set)
-		if !opts.Retain {
-			_ = delete.Cluster(logger, p, opts.Config.Name, opts.KubeconfigPath)
-		}
-		return err
+	// optionally report the duration and outcome of the provisioning
+	if opts.MetricsPushgateway != "" {
+		metrics := newProvisioningMetrics(opts.KeosCluster.Metadata.Name, opts.KeosCluster.Spec.InfraProvider)
+		status.SetPhaseObserver(metrics.observePhase)
+		defer func() {
+			if pushErr := metrics.push(opts.MetricsPushgateway, err == nil); pushErr != nil {
+				logger.Warnf("failed to push the provisioning metrics: %v", pushErr)
+			}
+		}()
 	}
 
-	// TODO(bentheelder): make this controllable from the command line?
-	actionsToRun := []actions.Action{
-		loadbalancer.NewAction(), // setup external loadbalancer
-		configaction.NewAction(), // setup kubeadm config
+	// the local cluster is kept if it is reused, as well as if retain is explicitly set
+	retain := opts.Retain || opts.ReuseBootstrap
+
+	var actionsToRun []actions.Action
+	if reused {
+		logger.V(0).Infof("Reusing temporary cluster %q ...\n", opts.Config.Name)
+	} else {
+		// we're going to start creating now, tell the user
+		logger.V(0).Infof("Creating temporary cluster %q ...\n", opts.Config.Name)
+
+		// Create node containers implementing defined config Nodes
+		if err := p.Provision(status, opts.Config, opts.DockerRegUrl, opts.UseLocalStratioImage); err != nil {
+			// In case of errors nodes are deleted (except if retain is explicitly set)
+			if !retain {
+				_ = delete.Cluster(logger, p, opts.Config.Name, opts.KubeconfigPath)
+			}
+			return err
+		}
+
+		// TODO(bentheelder): make this controllable from the command line?
+		actionsToRun = append(actionsToRun,
+			loadbalancer.NewAction(), // setup external loadbalancer
+			configaction.NewAction(), // setup kubeadm config
+		)
 	}
-	if !opts.StopBeforeSettingUpKubernetes {
+	if !opts.StopBeforeSettingUpKubernetes && !reused {
 		actionsToRun = append(actionsToRun,
 			kubeadminit.NewAction(opts.Config), // run kubeadm init
 		)
@@ -150,18 +190,25 @@ func Cluster(logger log.Logger, p providers.Provider, opts *ClusterOptions) erro
 			kubeadmjoin.NewAction(),                   // run kubeadm join
 			waitforready.NewAction(opts.WaitForReady), // wait for cluster readiness
 		)
-
+	}
+	if !opts.StopBeforeSettingUpKubernetes {
 		// add Stratio step
-		actionsToRun = append(actionsToRun,
-			createworker.NewAction(opts.VaultPassword, opts.DescriptorPath, opts.MoveManagement, opts.AvoidCreation, opts.KeosCluster, opts.ClusterCredentials, opts.ClusterConfig), // create worker k8s cluster
-		)
+		if opts.AdoptKubeconfig != "" {
+			actionsToRun = append(actionsToRun,
+				createworker.NewAdoptAction(opts.VaultPassword, opts.DescriptorPath, opts.AdoptKubeconfig, opts.KeosCluster, opts.ClusterCredentials, opts.ClusterConfig), // adopt existing worker k8s cluster
+			)
+		} else {
+			actionsToRun = append(actionsToRun,
+				createworker.NewAction(opts.VaultPassword, opts.DescriptorPath, opts.MoveManagement, opts.AvoidCreation, opts.KeosCluster, opts.ClusterCredentials, opts.ClusterConfig, opts.TemplatesDir, opts.OperationID), // create worker k8s cluster
+			)
+		}
 	}
 
 	// run all actions
 	actionsContext := actions.NewActionContext(logger, status, p, opts.Config)
 	for _, action := range actionsToRun {
 		if err := action.Execute(actionsContext); err != nil {
-			if !opts.Retain {
+			if !retain {
 				_ = delete.Cluster(logger, p, opts.Config.Name, opts.KubeconfigPath)
 			}
 			return err
@@ -176,7 +223,6 @@ func Cluster(logger log.Logger, p providers.Provider, opts *ClusterOptions) erro
 	// try exporting kubeconfig with backoff for locking failures
 	// TODO: factor out into a public errors API w/ backoff handling?
 	// for now this is easier than coming up with a good API
-	var err error
 	for _, b := range []time.Duration{0, time.Millisecond, time.Millisecond * 50, time.Millisecond * 100} {
 		time.Sleep(b)
 		if err = kubeconfig.Export(p, opts.Config.Name, opts.KubeconfigPath, true); err == nil {
@@ -188,7 +234,7 @@ func Cluster(logger log.Logger, p providers.Provider, opts *ClusterOptions) erro
 	}
 
 	// add Stratio action: delete the local cluster
-	if !opts.Retain {
+	if !retain {
 		actionsContext.Status.Start("Cleaning up temporary cluster 🧹")
 		defer actionsContext.Status.End(false)
 		_ = delete.Cluster(logger, p, opts.Config.Name, opts.KubeconfigPath)
@@ -261,6 +307,26 @@ func fixupOptions(opts *ClusterOptions) error {
 		opts.Config.Name = opts.NameOverride
 	}
 
+	// Stratio: tune the local container with the bootstrap settings of the user config, the
+	// image flag takes precedence over its node image
+	if opts.NodeImage == "" {
+		opts.NodeImage = opts.Bootstrap.NodeImage
+	}
+	if opts.Bootstrap.APIServerPort != 0 {
+		opts.Config.Networking.APIServerPort = opts.Bootstrap.APIServerPort
+	}
+	for i := range opts.Config.Nodes {
+		for _, mount := range opts.Bootstrap.ExtraMounts {
+			opts.Config.Nodes[i].ExtraMounts = append(opts.Config.Nodes[i].ExtraMounts, config.Mount{
+				HostPath:      mount.HostPath,
+				ContainerPath: mount.ContainerPath,
+				Readonly:      mount.ReadOnly,
+			})
+		}
+		opts.Config.Nodes[i].CPUs = opts.Bootstrap.Resources.CPUs
+		opts.Config.Nodes[i].Memory = opts.Bootstrap.Resources.Memory
+	}
+
 	// if NodeImage was set, override the image on all nodes
 	if opts.NodeImage != "" {
 		// Apply image override to all the Nodes defined in Config
@@ -271,6 +337,12 @@ func fixupOptions(opts *ClusterOptions) error {
 		}
 	}
 
+	// Stratio: pull the images of the local container through the registry mirrors
+	if opts.ClusterConfig != nil {
+		opts.Config.ContainerdConfigPatches = append(opts.Config.ContainerdConfigPatches,
+			createworker.GetContainerdMirrorsPatch(opts.ClusterConfig.Spec.RegistryMirrors)...)
+	}
+
 	// default config fields (important for usage as a library, where the config
 	// may be constructed in memory rather than from disk)
 	config.SetDefaultsCluster(opts.Config)
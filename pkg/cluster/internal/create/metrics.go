@@ -0,0 +1,89 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package create
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const metricsJob = "cloud-provisioner"
+
+// provisioningMetrics collects the duration and outcome of the provisioning phases,
+// to be pushed to a Prometheus Pushgateway
+type provisioningMetrics struct {
+	cluster  string
+	provider string
+	started  time.Time
+	phases   bytes.Buffer
+}
+
+func newProvisioningMetrics(cluster string, provider string) *provisioningMetrics {
+	m := &provisioningMetrics{
+		cluster:  cluster,
+		provider: provider,
+		started:  time.Now(),
+	}
+	m.phases.WriteString("# TYPE cloud_provisioner_phase_duration_seconds gauge\n")
+	return m
+}
+
+func (m *provisioningMetrics) observePhase(phase string, duration time.Duration, success bool) {
+	// The phases end with emojis, which are left out of the label
+	phase = strings.TrimRightFunc(phase, func(r rune) bool {
+		return r > unicode.MaxASCII || unicode.IsSpace(r)
+	})
+	fmt.Fprintf(&m.phases, "cloud_provisioner_phase_duration_seconds{provider=%q,phase=%q,success=\"%t\"} %f\n",
+		m.provider, phase, success, duration.Seconds())
+}
+
+// push replaces the metrics of the cluster in the Pushgateway
+func (m *provisioningMetrics) push(pushgatewayURL string, success bool) error {
+	successValue := 0
+	if success {
+		successValue = 1
+	}
+	metrics := bytes.Buffer{}
+	metrics.Write(m.phases.Bytes())
+	fmt.Fprintf(&metrics, "# TYPE cloud_provisioner_duration_seconds gauge\ncloud_provisioner_duration_seconds{provider=%q} %f\n",
+		m.provider, time.Since(m.started).Seconds())
+	fmt.Fprintf(&metrics, "# TYPE cloud_provisioner_success gauge\ncloud_provisioner_success{provider=%q} %d\n",
+		m.provider, successValue)
+
+	pushURL := strings.TrimSuffix(pushgatewayURL, "/") + "/metrics/job/" + metricsJob + "/cluster/" + url.PathEscape(m.cluster)
+	req, err := http.NewRequest(http.MethodPut, pushURL, &metrics)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to the Pushgateway")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("Pushgateway request failed: " + resp.Status)
+	}
+	return nil
+}
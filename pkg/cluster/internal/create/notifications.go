@@ -0,0 +1,66 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package create
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+// notifyProvisioning posts the outcome of the provisioning to the webhook, with a "text"
+// summary so it can be used as a Slack incoming webhook
+func notifyProvisioning(notifications commons.Notifications, keosCluster commons.KeosCluster, duration time.Duration, provisioningErr error) error {
+	event := "success"
+	text := "Cluster " + keosCluster.Metadata.Name + " (" + keosCluster.Spec.InfraProvider + ") has been provisioned in " + duration.Round(time.Second).String()
+	if provisioningErr != nil {
+		event = "failure"
+		text = "Cluster " + keosCluster.Metadata.Name + " (" + keosCluster.Spec.InfraProvider + ") provisioning failed after " + duration.Round(time.Second).String() + ": " + provisioningErr.Error()
+	}
+	if len(notifications.Events) > 0 && !commons.Contains(notifications.Events, event) {
+		return nil
+	}
+
+	payload := map[string]string{
+		"text":     text,
+		"event":    event,
+		"cluster":  keosCluster.Metadata.Name,
+		"provider": keosCluster.Spec.InfraProvider,
+		"duration": duration.Round(time.Second).String(),
+	}
+	if provisioningErr != nil {
+		payload["error"] = provisioningErr.Error()
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.Post(notifications.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to the notifications webhook")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("notifications webhook request failed: " + resp.Status)
+	}
+	return nil
+}
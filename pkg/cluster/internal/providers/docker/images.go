@@ -108,7 +108,11 @@ func ensureStratioImageFiles(logger log.Logger) (dir string, err error) {
 
 // buildStratioImage builds the stratio image
 func buildStratioImage(logger log.Logger, image string, path string) error {
-	cmd := exec.Command("docker", "build", "--tag="+image, path)
+	// The checksums of the tools are taken from the environment, if set
+	cmd := exec.Command("docker", "build", "--tag="+image,
+		"--build-arg", "CLUSTERCTL_SHA256",
+		"--build-arg", "CLUSTERAWSADM_SHA256",
+		path)
 	if err := cmd.Run(); err != nil {
 		return errors.Wrapf(err, "failed to build image %q", image)
 	}
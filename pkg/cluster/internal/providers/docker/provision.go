@@ -240,6 +240,14 @@ func runArgsForNode(node *config.Node, clusterIPFamily config.ClusterIPFamily, n
 	}
 	args = append(args, mappingArgs...)
 
+	// Stratio: limit the resources of the local container
+	if node.CPUs != "" {
+		args = append(args, "--cpus", node.CPUs)
+	}
+	if node.Memory != "" {
+		args = append(args, "--memory", node.Memory)
+	}
+
 	switch node.Role {
 	case config.ControlPlaneRole:
 		args = append(args, "-e", "KUBECONFIG=/etc/kubernetes/admin.conf")
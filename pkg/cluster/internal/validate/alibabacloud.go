@@ -0,0 +1,251 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const alibabaCloudECSURL = "https://ecs.aliyuncs.com/"
+
+var AlibabaCloudVolumes = []string{"cloud_essd", "cloud_essd_entry", "cloud_auto", "cloud_ssd", "cloud_efficiency"}
+
+type alibabaCloudZone struct {
+	ZoneId                 string `json:"ZoneId"`
+	AvailableInstanceTypes struct {
+		InstanceTypes []string `json:"InstanceTypes"`
+	} `json:"AvailableInstanceTypes"`
+	AvailableDiskCategories struct {
+		DiskCategories []string `json:"DiskCategories"`
+	} `json:"AvailableDiskCategories"`
+}
+
+func validateAlibabaCloud(spec commons.KeosSpec, providerSecrets map[string]string) error {
+	var err error
+
+	if spec.ControlPlane.Managed {
+		return errors.New("spec.control_plane: Invalid value: \"managed\": managed control planes are not supported in alibabacloud clusters")
+	}
+
+	regions, err := getAlibabaCloudRegions(providerSecrets)
+	if err != nil {
+		return err
+	}
+	if !commons.Contains(regions, spec.Region) {
+		return errors.New("spec.region: " + spec.Region + " region does not exist, regions: " + fmt.Sprint(regions))
+	}
+
+	zones, err := getAlibabaCloudZones(providerSecrets, spec.Region)
+	if err != nil {
+		return err
+	}
+
+	if err = validateAlibabaCloudNetworks(spec.Networks); err != nil {
+		return err
+	}
+
+	if (spec.StorageClass != commons.StorageClass{}) {
+		if err = validateAlibabaCloudStorageClass(spec.StorageClass, zones); err != nil {
+			return errors.Wrap(err, "spec.storageclass: Invalid value")
+		}
+	}
+
+	if err = validateGenericRepositories(spec); err != nil {
+		return err
+	}
+	if err = validateNoVolumes(spec); err != nil {
+		return err
+	}
+
+	if len(alibabaCloudZonesWithInstanceType(zones, spec.ControlPlane.Size)) == 0 {
+		return errors.New("spec.control_plane.size: " + spec.ControlPlane.Size + " instance type is not available in region " + spec.Region)
+	}
+
+	for _, wn := range spec.WorkerNodes {
+		typeZones := alibabaCloudZonesWithInstanceType(zones, wn.Size)
+		if wn.AZ != "" {
+			if !commons.Contains(typeZones, wn.AZ) {
+				return errors.New("spec.worker_nodes." + wn.Name + ": " + wn.Size + " instance type is not available in zone " + wn.AZ + ", zones: " + fmt.Sprint(typeZones))
+			}
+		} else if wn.ZoneDistribution != "unbalanced" && len(typeZones) < 3 {
+			return errors.New("spec.worker_nodes." + wn.Name + ": " + wn.Size + " instance type is available in less than 3 zones of region " + spec.Region)
+		} else if len(typeZones) == 0 {
+			return errors.New("spec.worker_nodes." + wn.Name + ".size: " + wn.Size + " instance type is not available in region " + spec.Region)
+		}
+	}
+
+	return nil
+}
+
+func validateAlibabaCloudNetworks(networks commons.Networks) error {
+	if networks.ResourceGroup != "" || len(networks.PodsSubnets) > 0 {
+		return errors.New("spec.networks: Invalid value: \"resource_group\" and \"pods_subnets\" are not supported in alibabacloud clusters")
+	}
+	if len(networks.Subnets) > 0 && networks.VPCID == "" {
+		return errors.New("spec.networks.vpc_id: Required value: \"vpc_id\" is required when \"subnets\" are set")
+	}
+	return nil
+}
+
+func validateAlibabaCloudStorageClass(sc commons.StorageClass, zones []alibabaCloudZone) error {
+	if sc.EFS != (commons.EFS{}) {
+		return errors.New("\"efs\" is not supported in alibabacloud clusters")
+	}
+	if sc.EncryptionKey != "" && sc.Parameters.KmsKeyId != "" {
+		return errors.New("\"encryptionKey\" and \"parameters.kmsKeyId\" cannot be set at the same time")
+	}
+	supported := commons.SCParameters{
+		Type:             sc.Parameters.Type,
+		FsType:           sc.Parameters.FsType,
+		Encrypted:        sc.Parameters.Encrypted,
+		KmsKeyId:         sc.Parameters.KmsKeyId,
+		PerformanceLevel: sc.Parameters.PerformanceLevel,
+	}
+	if sc.Parameters != supported {
+		return errors.New("only \"type\", \"fsType\", \"encrypted\", \"kmsKeyId\" and \"performanceLevel\" parameters are supported in alibabacloud clusters")
+	}
+	if sc.Parameters.Type != "" && !commons.Contains(AlibabaCloudVolumes, sc.Parameters.Type) {
+		return errors.New("unsupported type " + sc.Parameters.Type + ", supported types: " + strings.Join(AlibabaCloudVolumes, ", "))
+	}
+	if sc.Parameters.PerformanceLevel != "" && sc.Parameters.Type != "" && sc.Parameters.Type != "cloud_essd" {
+		return errors.New("\"performanceLevel\" is only supported with cloud_essd disks")
+	}
+
+	diskType := sc.Parameters.Type
+	if diskType == "" {
+		diskType = "cloud_essd"
+	}
+	for _, zone := range zones {
+		if commons.Contains(zone.AvailableDiskCategories.DiskCategories, diskType) {
+			return nil
+		}
+	}
+	return errors.New(diskType + " disks are not available in the region")
+}
+
+func alibabaCloudZonesWithInstanceType(zones []alibabaCloudZone, instanceType string) []string {
+	var zoneIds []string
+	for _, zone := range zones {
+		if commons.Contains(zone.AvailableInstanceTypes.InstanceTypes, instanceType) {
+			zoneIds = append(zoneIds, zone.ZoneId)
+		}
+	}
+	return zoneIds
+}
+
+func getAlibabaCloudRegions(providerSecrets map[string]string) ([]string, error) {
+	var regions []string
+	var response struct {
+		Regions struct {
+			Region []struct {
+				RegionId string `json:"RegionId"`
+			} `json:"Region"`
+		} `json:"Regions"`
+	}
+
+	err := alibabaCloudECSRequest(providerSecrets, map[string]string{"Action": "DescribeRegions"}, &response)
+	if err != nil {
+		return nil, err
+	}
+	for _, region := range response.Regions.Region {
+		regions = append(regions, region.RegionId)
+	}
+	return regions, nil
+}
+
+func getAlibabaCloudZones(providerSecrets map[string]string, region string) ([]alibabaCloudZone, error) {
+	var response struct {
+		Zones struct {
+			Zone []alibabaCloudZone `json:"Zone"`
+		} `json:"Zones"`
+	}
+
+	err := alibabaCloudECSRequest(providerSecrets, map[string]string{"Action": "DescribeZones", "RegionId": region}, &response)
+	if err != nil {
+		return nil, err
+	}
+	return response.Zones.Zone, nil
+}
+
+// alibabaCloudECSRequest calls the ECS RPC API signing the request with the
+// RAM credentials (signature version 1.0)
+func alibabaCloudECSRequest(providerSecrets map[string]string, params map[string]string, out interface{}) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	query := map[string]string{
+		"Format":           "JSON",
+		"Version":          "2014-05-26",
+		"AccessKeyId":      providerSecrets["AccessKeyID"],
+		"SignatureMethod":  "HMAC-SHA1",
+		"SignatureVersion": "1.0",
+		"SignatureNonce":   hex.EncodeToString(nonce),
+		"Timestamp":        time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+	}
+	for k, v := range params {
+		query[k] = v
+	}
+
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var canonicalized []string
+	for _, k := range keys {
+		canonicalized = append(canonicalized, alibabaCloudPercentEncode(k)+"="+alibabaCloudPercentEncode(query[k]))
+	}
+	canonicalizedQuery := strings.Join(canonicalized, "&")
+
+	mac := hmac.New(sha1.New, []byte(providerSecrets["AccessKeySecret"]+"&"))
+	mac.Write([]byte("GET&" + alibabaCloudPercentEncode("/") + "&" + alibabaCloudPercentEncode(canonicalizedQuery)))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	resp, err := http.Get(alibabaCloudECSURL + "?" + canonicalizedQuery + "&Signature=" + alibabaCloudPercentEncode(signature))
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to the Alibaba Cloud API")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("Alibaba Cloud API request " + params["Action"] + " failed: " + resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func alibabaCloudPercentEncode(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.ReplaceAll(s, "+", "%20")
+	s = strings.ReplaceAll(s, "*", "%2A")
+	return strings.ReplaceAll(s, "%7E", "~")
+}
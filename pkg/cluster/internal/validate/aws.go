@@ -43,17 +43,26 @@ var AWSVolumes = []string{"io1", "io2", "gp2", "gp3", "sc1", "st1", "standard",
 var isAWSNodeImage = regexp.MustCompile(`^ami-\w+$`).MatchString
 var AWSNodeImageFormat = "ami-[IMAGE_ID]"
 
+var isCloudFormationStackName = regexp.MustCompile(`^[a-zA-Z][-a-zA-Z0-9]*$`).MatchString
+var AWSEdgeZoneTypes = []string{"local-zone", "wavelength-zone"}
+
 func validateAWS(spec commons.KeosSpec, providerSecrets map[string]string) error {
 	var err error
 	var ctx = context.TODO()
 	deviceRegex := regexp.MustCompile(commons.DeviceNameRegex)
 
-	cfg, err := commons.AWSGetConfig(ctx, providerSecrets, spec.Region)
+	// The credentials of an instance profile are only available in the host, so the providers of the
+	// clusters could not renew them once the provisioning ends
+	if providerSecrets["AccessKey"] == "" {
+		return errors.New("credentials: Invalid value: \"access_key\": the access keys are required, with a role_arn to assume a role with them")
+	}
+
+	client, err := commons.NewAWSClient(ctx, providerSecrets, spec.Region, providerSecrets["ClusterRoleARN"])
 	if err != nil {
 		return err
 	}
 
-	regions, err := getAWSRegions(cfg)
+	regions, err := getAWSRegions(client)
 	if err != nil {
 		return err
 	}
@@ -61,7 +70,7 @@ func validateAWS(spec commons.KeosSpec, providerSecrets map[string]string) error
 		return errors.New("spec.region: " + spec.Region + " region does not exist")
 	}
 
-	azs, err := getAWSAzs(ctx, cfg, spec.Region)
+	azs, err := getAWSAzs(ctx, client, spec.Region)
 	if err != nil {
 		return err
 	}
@@ -73,7 +82,12 @@ func validateAWS(spec commons.KeosSpec, providerSecrets map[string]string) error
 	}
 
 	if !reflect.ValueOf(spec.Networks).IsZero() {
-		if err = validateAWSNetwork(ctx, cfg, spec); err != nil {
+		// The networks may be shared from another account of the landing zone
+		networkClient, err := commons.NewAWSClient(ctx, providerSecrets, spec.Region, providerSecrets["NetworkRoleARN"])
+		if err != nil {
+			return err
+		}
+		if err = validateAWSNetwork(ctx, networkClient, spec); err != nil {
 			return errors.Wrap(err, "spec.networks: Invalid value")
 		}
 	}
@@ -93,13 +107,44 @@ func validateAWS(spec commons.KeosSpec, providerSecrets map[string]string) error
 		}
 	}
 
+	if !spec.ControlPlane.Managed {
+		if spec.ControlPlane.AWS.RemoveGP2StorageClass || spec.ControlPlane.AWS.CalicoCNI {
+			return errors.New("spec.control_plane.aws: Invalid value: \"remove_gp2_storage_class\" and \"calico_cni\" are only supported in EKS clusters")
+		}
+	}
+	if spec.ControlPlane.AWS.RemoveGP2StorageClass && spec.StorageClass.Name == "gp2" {
+		return errors.New("spec.control_plane.aws: Invalid value: \"remove_gp2_storage_class\": the gp2 StorageClass is the one of the descriptor")
+	}
+
+	if stackName := spec.Security.AWS.IAMStackName; stackName != "" {
+		if !spec.Security.AWS.CreateIAM {
+			return errors.New("spec.security.aws: Invalid value: \"iam_stack_name\": the IAM stack is only created with \"create_iam\"")
+		}
+		if !isCloudFormationStackName(stackName) || len(stackName) > 128 {
+			return errors.New("spec.security.aws.iam_stack_name: Invalid value: \"" + stackName + "\": must start with a letter and contain only alphanumeric characters and hyphens, with a maximum of 128 characters")
+		}
+		// The IAM resources of the stack are named after it
+		resources := commons.GetAWSIAMTemplate(nil, spec.Security.AWS)["Resources"].(map[string]interface{})
+		names := make([]string, 0, len(resources))
+		for name := range resources {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			properties := resources[name].(map[string]interface{})["Properties"].(map[string]interface{})
+			if roleName, ok := properties["RoleName"].(string); ok && len(roleName) > commons.AWSIAMRoleNameMaxLength {
+				return errors.New("spec.security.aws.iam_stack_name: Invalid value: \"" + stackName + "\": the IAM role " + roleName + " is named after it, and exceeds the maximum of " + strconv.Itoa(commons.AWSIAMRoleNameMaxLength) + " characters")
+			}
+		}
+	}
+
 	if !spec.ControlPlane.Managed {
 		if spec.ControlPlane.NodeImage != "" {
 			if !isAWSNodeImage(spec.ControlPlane.NodeImage) {
 				return errors.New("spec.control_plane: Invalid value: \"node_image\": must have the format " + AWSNodeImageFormat)
 			}
 		}
-		if err := validateAWSInstanceType(cfg, spec.ControlPlane.Size); err != nil {
+		if err := validateAWSInstanceType(client, spec.ControlPlane.Size); err != nil {
 			return errors.New("spec.control_plane.size: " + spec.ControlPlane.Size + " does not exists in AWS instance types")
 		}
 		if err := validateVolumeType(spec.ControlPlane.RootVolume.Type, AWSVolumes); err != nil {
@@ -166,6 +211,9 @@ func validateAWS(spec commons.KeosSpec, providerSecrets map[string]string) error
 		}
 	}
 
+	if err = validateWorkersAZs(spec.WorkerNodes, azs); err != nil {
+		return errors.Wrap(err, "invalid AZ in this region")
+	}
 	for _, wn := range spec.WorkerNodes {
 		if wn.NodeImage != "" {
 			if !isAWSNodeImage(wn.NodeImage) {
@@ -173,16 +221,19 @@ func validateAWS(spec commons.KeosSpec, providerSecrets map[string]string) error
 			}
 		}
 		if wn.AZ != "" {
-			if len(azs) > 0 {
-				if !commons.Contains(azs, wn.AZ) {
-					return errors.New(wn.AZ + " does not exist in this region, azs: " + fmt.Sprint(azs))
-				}
+			if err := validateAWSEdgeLocation(ctx, client, spec, wn.AZ, wn.Size); err != nil {
+				return errors.Wrap(err, "spec.worker_nodes."+wn.Name+": Invalid value")
 			}
 		}
 		if wn.Size != "" {
-			if err := validateAWSInstanceType(cfg, wn.Size); err != nil {
+			if err := validateAWSInstanceType(client, wn.Size); err != nil {
 				return errors.New("spec.worker_nodes." + wn.Name + ".size: " + wn.Size + " does not exists in AWS instance types")
 			}
+			if wn.NodeImage != "" {
+				if err := validateAWSNodeImageArchitecture(ctx, client, wn.NodeImage, wn.Size); err != nil {
+					return errors.Wrap(err, "spec.worker_nodes."+wn.Name+": Invalid value: \"node_image\"")
+				}
+			}
 		}
 		if err := validateVolumeType(wn.RootVolume.Type, AWSVolumes); err != nil {
 			return errors.Wrap(err, "spec.worker_nodes."+wn.Name+".root_volume: Invalid value: \"type\"")
@@ -230,14 +281,14 @@ func validateAWS(spec commons.KeosSpec, providerSecrets map[string]string) error
 	return nil
 }
 
-func validateAWSNetwork(ctx context.Context, cfg aws.Config, spec commons.KeosSpec) error {
+func validateAWSNetwork(ctx context.Context, client *commons.AWSClient, spec commons.KeosSpec) error {
 	var err error
 
 	if spec.Networks.VPCID != "" {
 		if spec.Networks.VPCCIDRBlock != "" {
 			return errors.New("\"vpc_id\" and \"vpc_cidr\" are mutually exclusive")
 		}
-		vpcs, _ := getAWSVPCs(cfg)
+		vpcs, _ := getAWSVPCs(client)
 		if len(vpcs) > 0 && !commons.Contains(vpcs, spec.Networks.VPCID) {
 			return errors.New("\"vpc_id\": " + spec.Networks.VPCID + " does not exist")
 		}
@@ -249,10 +300,10 @@ func validateAWSNetwork(ctx context.Context, cfg aws.Config, spec commons.KeosSp
 					return errors.New("\"subnet_id\": is required")
 				}
 			}
-			if err = validateAWSAZs(ctx, cfg, spec); err != nil {
+			if err = validateAWSAZs(ctx, client, spec); err != nil {
 				return err
 			}
-			subnets, _ := getAWSSubnets(spec.Networks.VPCID, cfg)
+			subnets, _ := getAWSSubnets(spec.Networks.VPCID, client)
 			if len(subnets) > 0 {
 				for _, subnet := range spec.Networks.Subnets {
 					if !commons.Contains(subnets, subnet.SubnetId) {
@@ -271,7 +322,7 @@ func validateAWSNetwork(ctx context.Context, cfg aws.Config, spec commons.KeosSp
 	}
 	if spec.Networks.VPCCIDRBlock != "" {
 		const cidrSizeMin = 256
-		_, ipv4Net, err := net.ParseCIDR(spec.Networks.VPCCIDRBlock)
+		ipv4Net, err := parseIPv4CIDR(spec.Networks.VPCCIDRBlock)
 		if err != nil {
 			return errors.New("\"vpc_cidr\": CIDR block must be a valid IPv4 CIDR block")
 		}
@@ -305,7 +356,7 @@ func validateAWSPodsNetwork(podsNetwork string) error {
 		Mask: net.IPv4Mask(255, 255, 0, 0),
 	}
 
-	_, ipv4Net, err := net.ParseCIDR(podsNetwork)
+	ipv4Net, err := parseIPv4CIDR(podsNetwork)
 	if err != nil {
 		return errors.New("\"pods_cidr\": CIDR block must be a valid IPv4 CIDR block")
 	}
@@ -322,13 +373,11 @@ func validateAWSPodsNetwork(podsNetwork string) error {
 	return nil
 }
 
-func getAWSRegions(config aws.Config) ([]string, error) {
+func getAWSRegions(awsClient *commons.AWSClient) ([]string, error) {
 	regions := []string{}
 
-	// Use a default region to authenticate
-	config.Region = *aws.String("eu-west-1")
-
-	client := ec2.NewFromConfig(config)
+	// Use the global region of the partition to authenticate
+	client := awsClient.Global().EC2()
 
 	// Describe regions
 	describeRegionsOpts := &ec2.DescribeRegionsInput{}
@@ -345,10 +394,10 @@ func getAWSRegions(config aws.Config) ([]string, error) {
 	return regions, nil
 }
 
-func getAWSVPCs(config aws.Config) ([]string, error) {
+func getAWSVPCs(awsClient *commons.AWSClient) ([]string, error) {
 	vpcs := []string{}
 
-	client := ec2.NewFromConfig(config)
+	client := awsClient.EC2()
 	DescribeVpcOpts := &ec2.DescribeVpcsInput{}
 	output, err := client.DescribeVpcs(context.Background(), DescribeVpcOpts)
 	if err != nil {
@@ -360,10 +409,10 @@ func getAWSVPCs(config aws.Config) ([]string, error) {
 	return vpcs, nil
 }
 
-func getAWSSubnets(vpcId string, config aws.Config) ([]string, error) {
+func getAWSSubnets(vpcId string, awsClient *commons.AWSClient) ([]string, error) {
 	subnets := []string{}
 
-	client := ec2.NewFromConfig(config)
+	client := awsClient.EC2()
 	vpc_id_filterName := "vpc-id"
 	DescribeSubnetOpts := &ec2.DescribeSubnetsInput{
 		Filters: []types.Filter{
@@ -467,9 +516,9 @@ func validateAWSStorageClass(sc commons.StorageClass, wn commons.WorkerNodes) er
 	return nil
 }
 
-func validateAWSInstanceType(cfg aws.Config, instanceType string) error {
+func validateAWSInstanceType(awsClient *commons.AWSClient, instanceType string) error {
 
-	client := ec2.NewFromConfig(cfg)
+	client := awsClient.EC2()
 
 	// Call DescribeInstanceTypes API to get details about the instance type
 	diti := &ec2.DescribeInstanceTypesInput{
@@ -484,6 +533,38 @@ func validateAWSInstanceType(cfg aws.Config, instanceType string) error {
 	return nil
 }
 
+// validateAWSNodeImageArchitecture checks the AMI is built for the architecture of the instance type,
+// as the Graviton instance types only run arm64 images
+func validateAWSNodeImageArchitecture(ctx context.Context, client *commons.AWSClient, nodeImage string, instanceType string) error {
+	svc := client.EC2()
+	dito, err := svc.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{
+		InstanceTypes: []types.InstanceType{types.InstanceType(instanceType)},
+	})
+	if err != nil {
+		return err
+	}
+	dio, err := svc.DescribeImages(ctx, &ec2.DescribeImagesInput{
+		ImageIds: []string{nodeImage},
+	})
+	if err != nil {
+		return err
+	}
+	if len(dito.InstanceTypes) == 0 || len(dio.Images) == 0 {
+		return errors.New(nodeImage + " does not exist in the region")
+	}
+	var architectures []string
+	if processorInfo := dito.InstanceTypes[0].ProcessorInfo; processorInfo != nil {
+		for _, architecture := range processorInfo.SupportedArchitectures {
+			architectures = append(architectures, string(architecture))
+		}
+	}
+	imageArchitecture := string(dio.Images[0].Architecture)
+	if !slices.Contains(architectures, imageArchitecture) {
+		return errors.New(nodeImage + " is a " + imageArchitecture + " image, but " + instanceType + " supports " + strings.Join(architectures, ", "))
+	}
+	return nil
+}
+
 func validateAWSLabel(l string) error {
 	var isLabel = regexp.MustCompile(`^([\w\.\/-]+=[\w\.\/-]+)(\s?,\s?[\w\.\/-]+=[\w\.\/-]+)*$`).MatchString
 	if !isLabel(l) {
@@ -492,11 +573,11 @@ func validateAWSLabel(l string) error {
 	return nil
 }
 
-func validateAWSAZs(ctx context.Context, cfg aws.Config, spec commons.KeosSpec) error {
+func validateAWSAZs(ctx context.Context, client *commons.AWSClient, spec commons.KeosSpec) error {
 	var err error
 	var azs []string
 
-	svc := ec2.NewFromConfig(cfg)
+	svc := client.EC2()
 	if spec.Networks.VPCID != "" {
 		if len(spec.Networks.Subnets) > 0 {
 			azs, err = commons.AWSGetPrivateAZs(ctx, svc, spec.Networks.Subnets)
@@ -516,20 +597,82 @@ func validateAWSAZs(ctx context.Context, cfg aws.Config, spec commons.KeosSpec)
 			return errors.New("insufficient Availability Zones in region " + spec.Region + ". Must have at least 3")
 		}
 	}
-	for _, node := range spec.WorkerNodes {
-		if node.ZoneDistribution == "unbalanced" && node.AZ != "" {
-			if !slices.Contains(azs, node.AZ) {
-				return errors.New("worker node " + node.Name + " whose AZ is defined in " + node.AZ + " must match with the AZs associated to the defined subnets in descriptor")
-			}
+	// The AZ of the worker nodes must have a subnet, the region AZs are checked otherwise
+	if err = validateWorkersAZs(spec.WorkerNodes, azs); err != nil {
+		return errors.Wrap(err, "invalid AZ for the subnets")
+	}
+
+	return nil
+}
+
+// validateAWSEdgeLocation checks the placement of a node group in a Local/Wavelength Zone.
+// Regular Availability Zones are ignored.
+func validateAWSEdgeLocation(ctx context.Context, client *commons.AWSClient, spec commons.KeosSpec, az string, instanceType string) error {
+	svc := client.EC2()
+
+	dazo, err := svc.DescribeAvailabilityZones(ctx, &ec2.DescribeAvailabilityZonesInput{
+		ZoneNames: []string{az},
+	})
+	if err != nil {
+		return err
+	}
+	if len(dazo.AvailabilityZones) == 0 {
+		return errors.New("\"az\": " + az + " does not exist or is not enabled in region " + spec.Region)
+	}
+	zone := dazo.AvailabilityZones[0]
+	zoneType := aws.ToString(zone.ZoneType)
+	if !slices.Contains(AWSEdgeZoneTypes, zoneType) {
+		return nil
+	}
+
+	if spec.ControlPlane.Managed {
+		return errors.New("\"az\": Local and Wavelength Zones are only supported in unmanaged clusters")
+	}
+	if spec.Networks.VPCID == "" {
+		return errors.New("\"az\": a custom network (\"networks.vpc_id\") with a subnet in " + az + " is required for Local and Wavelength Zones")
+	}
+
+	// A subnet in the zone must be provided in the descriptor
+	var subnetIds []string
+	for _, s := range spec.Networks.Subnets {
+		subnetIds = append(subnetIds, s.SubnetId)
+	}
+	dso, err := svc.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{SubnetIds: subnetIds})
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, subnet := range dso.Subnets {
+		if aws.ToString(subnet.AvailabilityZone) == az {
+			found = true
+			break
 		}
 	}
+	if !found {
+		return errors.New("\"az\": no subnet in \"networks.subnets\" belongs to zone " + az)
+	}
+
+	// Local and Wavelength Zones offer a reduced set of instance types
+	ditoo, err := svc.DescribeInstanceTypeOfferings(ctx, &ec2.DescribeInstanceTypeOfferingsInput{
+		LocationType: types.LocationTypeAvailabilityZone,
+		Filters: []types.Filter{
+			{Name: aws.String("location"), Values: []string{az}},
+			{Name: aws.String("instance-type"), Values: []string{instanceType}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if len(ditoo.InstanceTypeOfferings) == 0 {
+		return errors.New("\"size\": " + instanceType + " is not offered in zone " + az)
+	}
 
 	return nil
 }
 
-func getAWSAzs(ctx context.Context, cfg aws.Config, region string) ([]string, error) {
+func getAWSAzs(ctx context.Context, client *commons.AWSClient, region string) ([]string, error) {
 	var azs []string
-	svc := ec2.NewFromConfig(cfg)
+	svc := client.EC2()
 	result, err := svc.DescribeAvailabilityZones(ctx, &ec2.DescribeAvailabilityZonesInput{})
 	if err != nil {
 		return nil, err
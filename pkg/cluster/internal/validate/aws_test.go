@@ -0,0 +1,65 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"fmt"
+	"testing"
+	"testing/quick"
+)
+
+// TestValidateAWSPodsNetworkProperties checks the EKS pods CIDR blocks are accepted if and only if
+// they are between a /16 and a /28, within 100.64.0.0/10 or 198.19.0.0/16
+func TestValidateAWSPodsNetworkProperties(t *testing.T) {
+	ranges := []struct {
+		network uint32
+		prefix  uint8
+	}{
+		{100<<24 | 64<<16, 10},
+		{198<<24 | 19<<16, 16},
+	}
+	property := func(ip [4]byte, prefix uint8, inRange bool) bool {
+		prefix %= 33
+		if inRange {
+			// Half of the blocks are moved into one of the ranges, which random blocks are rarely in
+			r := ranges[int(ip[3])%len(ranges)]
+			address := r.network | ipv4Uint(ip)&^prefixMask(r.prefix)
+			ip = [4]byte{byte(address >> 24), byte(address >> 16), byte(address >> 8), byte(address)}
+		}
+		start := ipv4Uint(ip) & prefixMask(prefix)
+		end := start | ^prefixMask(prefix)
+		block := fmt.Sprintf("%d.%d.%d.%d/%d", ip[0], ip[1], ip[2], ip[3], prefix)
+
+		valid := prefix >= 16 && prefix <= 28
+		within := false
+		for _, r := range ranges {
+			if start&prefixMask(r.prefix) == r.network && end&prefixMask(r.prefix) == r.network {
+				within = true
+			}
+		}
+		return (validateAWSPodsNetwork(block) == nil) == (valid && within)
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 1000}); err != nil {
+		t.Error(err)
+	}
+
+	for _, block := range []string{"::ffff:100.64.0.0/112", "fd00::/112", "100.64.0.0"} {
+		if validateAWSPodsNetwork(block) == nil {
+			t.Errorf("%s is accepted as the pods CIDR block", block)
+		}
+	}
+}
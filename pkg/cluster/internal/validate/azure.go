@@ -20,12 +20,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"net"
+	"net/http"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v5"
@@ -72,14 +73,10 @@ func validateAzure(spec commons.KeosSpec, providerSecrets map[string]string, clu
 		return err
 	}
 
+	if err = validateWorkersAZs(spec.WorkerNodes, azs); err != nil {
+		return errors.Wrap(err, "invalid AZ in this region")
+	}
 	for _, wn := range spec.WorkerNodes {
-		if wn.AZ != "" {
-			if len(azs) > 0 {
-				if !commons.Contains(azs, wn.AZ) {
-					return errors.New(wn.AZ + " does not exist in this region, azs: " + fmt.Sprint(azs))
-				}
-			}
-		}
 		if wn.Size != "" {
 			if err := validateAzureInstanceType(creds, wn.Size, providerSecrets["SubscriptionID"], spec.Region); err != nil {
 				return errors.New("spec.worker_nodes." + wn.Name + ".size: " + wn.Size + " does not exist as a Azure instance types in region " + spec.Region)
@@ -93,10 +90,13 @@ func validateAzure(spec commons.KeosSpec, providerSecrets map[string]string, clu
 		}
 	}
 	if !reflect.ValueOf(spec.Networks).IsZero() {
-		if err = validateAzureNetwork(spec.Networks, spec, creds, providerSecrets["SubscriptionID"], clusterName); err != nil {
+		if err = validateAzureNetwork(spec.Networks, spec, creds, commons.AzureNetworkSubscription(providerSecrets), clusterName); err != nil {
 			return errors.Wrap(err, "spec.networks: Invalid value")
 		}
 	}
+	if err = validateAzurePermissions(spec, creds, providerSecrets); err != nil {
+		return err
+	}
 	if !isAzureIdentity(spec.Security.ControlPlaneIdentity) {
 		return errors.New("spec.security: Invalid value: \"control_plane_identity\": is required and have the format " + AzureIdentityFormat)
 	}
@@ -210,6 +210,84 @@ func validateAzureCredentials(secrets map[string]string) (*azidentity.ClientSecr
 	return creds, nil
 }
 
+// validateAzurePermissions checks that the service principal is allowed to create the cluster
+// in its subscription and to use the networks in theirs
+func validateAzurePermissions(spec commons.KeosSpec, creds *azidentity.ClientSecretCredential, secrets map[string]string) error {
+	clusterActions := []string{"Microsoft.Resources/subscriptions/resourceGroups/write", "Microsoft.Compute/virtualMachines/write"}
+	if spec.ControlPlane.Managed {
+		clusterActions = []string{"Microsoft.Resources/subscriptions/resourceGroups/write", "Microsoft.ContainerService/managedClusters/write"}
+	}
+	if err := validateAzureScopeActions(creds, "/subscriptions/"+secrets["SubscriptionID"], clusterActions); err != nil {
+		return errors.Wrap(err, "credentials: Invalid value: \"subscription_id\"")
+	}
+
+	if spec.Networks.VPCID != "" && spec.Networks.ResourceGroup != "" {
+		networkScope := "/subscriptions/" + commons.AzureNetworkSubscription(secrets) + "/resourceGroups/" + spec.Networks.ResourceGroup
+		networkActions := []string{"Microsoft.Network/virtualNetworks/read", "Microsoft.Network/virtualNetworks/subnets/join/action"}
+		if err := validateAzureScopeActions(creds, networkScope, networkActions); err != nil {
+			return errors.Wrap(err, "credentials: Invalid value: \"network_subscription_id\"")
+		}
+	}
+	return nil
+}
+
+// validateAzureScopeActions checks the effective permissions of the role assignments at the given scope
+func validateAzureScopeActions(creds *azidentity.ClientSecretCredential, scope string, actions []string) error {
+	var permissions struct {
+		Value []struct {
+			Actions    []string `json:"actions"`
+			NotActions []string `json:"notActions"`
+		} `json:"value"`
+	}
+	var ctx = context.Background()
+
+	token, err := creds.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"https://management.azure.com/.default"}})
+	if err != nil {
+		return errors.Wrap(err, "failed to get Azure token")
+	}
+	req, err := http.NewRequest(http.MethodGet, "https://management.azure.com"+scope+"/providers/Microsoft.Authorization/permissions?api-version=2022-04-01", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to Azure")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("failed to get the permissions in " + scope + ": " + resp.Status)
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&permissions); err != nil {
+		return err
+	}
+
+	for _, action := range actions {
+		allowed := false
+		for _, p := range permissions.Value {
+			if matchAzureActions(p.Actions, action) && !matchAzureActions(p.NotActions, action) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return errors.New("the service principal is not allowed to " + action + " in " + scope)
+		}
+	}
+	return nil
+}
+
+// matchAzureActions reports if the action matches any of the patterns, where * is a wildcard
+func matchAzureActions(patterns []string, action string) bool {
+	for _, pattern := range patterns {
+		expr := "(?i)^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+		if matched, _ := regexp.MatchString(expr, action); matched {
+			return true
+		}
+	}
+	return false
+}
+
 func validateAzureStorageClass(sc commons.StorageClass, wn commons.WorkerNodes) error {
 	var err error
 	var isKeyValid = regexp.MustCompile(`(?i)^\/subscriptions\/[\w-]+\/resourceGroups\/[\w\.-]+\/providers\/Microsoft\.Compute\/diskEncryptionSets\/[\w\.-]+$`).MatchString
@@ -319,9 +397,9 @@ func validateAzureNetwork(network commons.Networks, spec commons.KeosSpec, creds
 	}
 	if spec.ControlPlane.Managed && network.VPCCIDRBlock != "" {
 		const cidrSizeMin = 256
-		_, ipv4Net, err := net.ParseCIDR(network.VPCCIDRBlock)
+		ipv4Net, err := parseIPv4CIDR(network.VPCCIDRBlock)
 		if err != nil {
-			return errors.New("\"pods_cidr\": CIDR block must be a valid IPv4 CIDR block")
+			return errors.New("\"vpc_cidr\": CIDR block must be a valid IPv4 CIDR block")
 		}
 		cidrSize := cidr.AddressCount(ipv4Net)
 		if cidrSize < cidrSizeMin {
@@ -17,11 +17,14 @@ limitations under the License.
 package validate
 
 import (
+	"crypto/x509"
 	"fmt"
+	"os"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/exp/slices"
 	"sigs.k8s.io/kind/pkg/commons"
@@ -35,12 +38,22 @@ const (
 
 var k8sVersionSupported = []string{"1.28", "1.29", "1.30"}
 
+// The cluster names are limited by the names of the GKE clusters and of the resources CAPG names
+// after them
+var clusterNameMaxLength = map[string]int{
+	"gcp": 40,
+}
+
 func validateCommon(spec commons.KeosSpec, clusterConfigSpec commons.ClusterConfigSpec) error {
 	var err error
 	if err = validateK8SVersion(spec.K8SVersion); err != nil {
 		return err
 	}
-	if err = validateWorkers(spec.WorkerNodes); err != nil {
+	if spec.Profile == commons.DevProfile {
+		if err = validateDevProfile(spec); err != nil {
+			return err
+		}
+	} else if err = validateWorkers(spec.WorkerNodes); err != nil {
 		return err
 	}
 	if err = validateVolumes(spec); err != nil {
@@ -49,6 +62,212 @@ func validateCommon(spec commons.KeosSpec, clusterConfigSpec commons.ClusterConf
 	if err = validateClusterConfig(spec, clusterConfigSpec); err != nil {
 		return err
 	}
+	if err = validateTTL(spec); err != nil {
+		return err
+	}
+	if err = validateAPIServer(spec); err != nil {
+		return err
+	}
+	if err = validateServiceAccountIssuer(spec); err != nil {
+		return err
+	}
+	if err = validateControlPlaneFlavor(spec, clusterConfigSpec); err != nil {
+		return err
+	}
+	if err = validateDNS(spec); err != nil {
+		return err
+	}
+	if err = validateStorageClassName(spec); err != nil {
+		return err
+	}
+	if err = validateMachinePools(spec, clusterConfigSpec); err != nil {
+		return err
+	}
+	if err = validateKeos(spec); err != nil {
+		return err
+	}
+	if err = validatePodsNetwork(spec); err != nil {
+		return err
+	}
+	if spec.ControlPlane.Managed && spec.Networks.ClusterDomain != "" && spec.Networks.ClusterDomain != commons.DefaultClusterDomain {
+		return errors.New("spec.networks.cluster_domain: Invalid value: \"" + spec.Networks.ClusterDomain + "\": managed clusters only support " + commons.DefaultClusterDomain)
+	}
+	return nil
+}
+
+// validatePodsNetwork checks the pods CIDR block, which is rendered as is in the Calico pool and the
+// cluster CIDR of the cloud controller managers
+func validatePodsNetwork(spec commons.KeosSpec) error {
+	if spec.Networks.PodsCidrBlock == "" {
+		return nil
+	}
+	ipNet, err := parseIPv4CIDR(spec.Networks.PodsCidrBlock)
+	if err != nil {
+		return errors.New("\"pods_cidr\": CIDR block must be a valid IPv4 CIDR block")
+	}
+	if ipNet.String() != spec.Networks.PodsCidrBlock {
+		return errors.New("\"pods_cidr\": CIDR block must start at the network address, " + ipNet.String())
+	}
+	return nil
+}
+
+// validateDevProfile checks that the cluster is a single unmanaged node
+func validateDevProfile(spec commons.KeosSpec) error {
+	if spec.ControlPlane.Managed {
+		return errors.New("spec.profile: Invalid value: \"dev\": it is not supported in managed clusters")
+	}
+	if _, ok := commons.DevProfileSizes[spec.InfraProvider]; !ok {
+		return errors.New("spec.profile: Invalid value: \"dev\": it is only supported in aws, azure and gcp clusters")
+	}
+	if *spec.ControlPlane.HighlyAvailable {
+		return errors.New("spec.control_plane.highly_available: Invalid value: the dev profile has a single control plane node")
+	}
+	if len(spec.WorkerNodes) > 0 {
+		return errors.New("spec.worker_nodes: Invalid value: the workloads of the dev profile run in the control plane node")
+	}
+	return nil
+}
+
+func validateMachinePools(spec commons.KeosSpec, clusterConfigSpec commons.ClusterConfigSpec) error {
+	for _, wn := range spec.WorkerNodes {
+		if !wn.MachinePool {
+			continue
+		}
+		if !commons.Contains([]string{"aws", "azure", "gcp"}, spec.InfraProvider) {
+			return errors.New("spec.worker_nodes." + wn.Name + ".machine_pool: Invalid value: machine pools are not supported in " + spec.InfraProvider + " clusters")
+		}
+		machinePool := clusterConfigSpec.CAPXConfig.FeatureGates.MachinePool
+		if machinePool != nil && !*machinePool {
+			return errors.New("spec.worker_nodes." + wn.Name + ".machine_pool: Invalid value: it requires the machine_pool feature gate")
+		}
+	}
+	return nil
+}
+
+func validateAPIServer(spec commons.KeosSpec) error {
+	apiServer := spec.ControlPlane.APIServer
+	if reflect.DeepEqual(apiServer, commons.APIServer{}) {
+		return nil
+	}
+	if len(apiServer.AllowedCIDRs) > 0 {
+		if !slices.Contains([]string{"aws", "azure", "gcp"}, spec.InfraProvider) {
+			return errors.New("spec.control_plane.api_server.allowed_cidrs: Invalid value: it is only supported in aws, azure and gcp clusters")
+		}
+		if spec.InfraProvider == "gcp" && spec.ControlPlane.Managed && len(spec.ControlPlane.Gcp.MasterAuthorizedNetworksConfig.CIDRBlocks) > 0 {
+			return errors.New("spec.control_plane.api_server.allowed_cidrs: Invalid value: it is mutually exclusive with spec.control_plane.gcp.master_authorized_networks_config.cidr_blocks")
+		}
+	}
+	// Only the networks of the API server of managed clusters can be restricted
+	if spec.ControlPlane.Managed && !reflect.DeepEqual(apiServer, commons.APIServer{AllowedCIDRs: apiServer.AllowedCIDRs}) {
+		return errors.New("spec.control_plane.api_server: Invalid value: only allowed_cidrs is supported in managed clusters")
+	}
+	if apiServer.CreateRecord {
+		if apiServer.DNSName == "" {
+			return errors.New("spec.control_plane.api_server.dns_name: Required value: it is required to create the DNS record")
+		}
+		if !slices.Contains([]string{"aws", "azure", "gcp"}, spec.InfraProvider) {
+			return errors.New("spec.control_plane.api_server.create_record: Invalid value: DNS records can only be created in aws, azure and gcp clusters")
+		}
+		if spec.InfraProvider == "azure" && apiServer.ResourceGroup == "" {
+			return errors.New("spec.control_plane.api_server.resource_group: Required value: the resource group of the DNS zone is required in azure clusters")
+		}
+	}
+	return nil
+}
+
+// validateServiceAccountIssuer checks the issuer of the service account tokens, which is rendered in
+// the kube-apiserver args of unmanaged clusters
+func validateServiceAccountIssuer(spec commons.KeosSpec) error {
+	issuer := spec.ControlPlane.ServiceAccountIssuer
+	if issuer == nil {
+		return nil
+	}
+	if spec.ControlPlane.Managed {
+		return errors.New("spec.control_plane.service_account_issuer: Invalid value: it is not supported in managed clusters")
+	}
+	// The discovery document and the keys are served under the issuer path
+	if strings.ContainsAny(issuer.URL, "?#") || strings.HasSuffix(issuer.URL, "/") {
+		return errors.New("spec.control_plane.service_account_issuer.url: Invalid value: \"" + issuer.URL + "\": it cannot have a trailing slash, a query or a fragment")
+	}
+	if issuer.MaxTokenExpiration != "" {
+		maxTokenExpiration, err := time.ParseDuration(issuer.MaxTokenExpiration)
+		if err != nil {
+			return errors.New("spec.control_plane.service_account_issuer.max_token_expiration: Invalid value: \"" + issuer.MaxTokenExpiration + "\": it must be a duration")
+		}
+		if maxTokenExpiration < time.Hour {
+			return errors.New("spec.control_plane.service_account_issuer.max_token_expiration: Invalid value: \"" + issuer.MaxTokenExpiration + "\": it must be at least 1h")
+		}
+	}
+	return nil
+}
+
+// validateControlPlaneFlavor rejects the node settings which rely on the kubeadm paths of the
+// containerd and kubelet configuration, as RKE2 and K3s embed their own
+func validateControlPlaneFlavor(spec commons.KeosSpec, clusterConfigSpec commons.ClusterConfigSpec) error {
+	flavor := spec.ControlPlane.Flavor
+	if flavor == "" || flavor == "kubeadm" {
+		return nil
+	}
+	if spec.ControlPlane.Managed {
+		return errors.New("spec.control_plane.flavor: Invalid value: it is not supported in managed clusters")
+	}
+	if !slices.Contains([]string{"aws", "azure", "gcp"}, spec.InfraProvider) {
+		return errors.New("spec.control_plane.flavor: Invalid value: " + flavor + " is only supported in aws, azure and gcp clusters")
+	}
+	if clusterConfigSpec.Private {
+		return errors.New("spec.control_plane.flavor: Invalid value: " + flavor + " images cannot be pulled from the private registry")
+	}
+	if len(clusterConfigSpec.RegistryMirrors) > 0 || clusterConfigSpec.RegistryCache != nil {
+		return errors.New("spec.control_plane.flavor: Invalid value: the containerd mirrors can only be set in kubeadm clusters")
+	}
+	if systemBaseline := clusterConfigSpec.SystemBaseline; systemBaseline != nil {
+		if len(systemBaseline.KubeReserved) > 0 || len(systemBaseline.SystemReserved) > 0 {
+			return errors.New("spec.control_plane.flavor: Invalid value: the kubelet reservations can only be set in kubeadm clusters")
+		}
+	}
+	return nil
+}
+
+func validateTTL(spec commons.KeosSpec) error {
+	if spec.TTL == "" {
+		return nil
+	}
+	ttl, err := time.ParseDuration(spec.TTL)
+	if err != nil {
+		return errors.New("spec.ttl: Invalid value: \"" + spec.TTL + "\": must be a duration (e.g. 72h)")
+	}
+	if ttl < time.Hour {
+		return errors.New("spec.ttl: Invalid value: \"" + spec.TTL + "\": must be at least 1h")
+	}
+	return nil
+}
+
+func validateDNS(spec commons.KeosSpec) error {
+	if len(spec.Dns.Forwarders) == 0 && len(spec.Dns.StubDomains) == 0 {
+		return nil
+	}
+	if !slices.Contains([]string{"aws", "azure", "gcp"}, spec.InfraProvider) {
+		return errors.New("spec.dns: Invalid value: the CoreDNS configuration can only be customized in aws, azure and gcp clusters")
+	}
+	for i, stubDomain := range spec.Dns.StubDomains {
+		for j := range spec.Dns.StubDomains[:i] {
+			if spec.Dns.StubDomains[j].Domain == stubDomain.Domain {
+				return errors.New("spec.dns.stub_domains[" + strconv.Itoa(i) + "].domain: Duplicate value: " + stubDomain.Domain)
+			}
+		}
+	}
+	return nil
+}
+
+func validateStorageClassName(spec commons.KeosSpec) error {
+	name := spec.StorageClass.Name
+	if name == "" {
+		return nil
+	}
+	regex := regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+	if !regex.MatchString(name) || len(name) > 253 {
+		return errors.New("spec.storageclass.name: Invalid value: \"" + name + "\": must be a lowercase RFC 1123 subdomain of no more than 253 characters")
+	}
 	return nil
 }
 
@@ -57,6 +276,148 @@ func validateClusterConfig(spec commons.KeosSpec, clusterConfigSpec commons.Clus
 		if clusterConfigSpec.ControlplaneConfig.MaxUnhealthy != nil {
 			return errors.New("spec: Invalid value: \"controlplane_config.max_unhealthy\" in clusterConfig: This field cannot be set with managed cluster")
 		}
+		if clusterConfigSpec.ControlplaneConfig.NodeStartupTimeout != "" || len(clusterConfigSpec.ControlplaneConfig.UnhealthyConditions) > 0 {
+			return errors.New("spec: Invalid value: \"controlplane_config\" in clusterConfig: MachineHealthCheck settings cannot be set with managed cluster")
+		}
+	}
+	if err := validateMachineHealthCheck("controlplane_config", clusterConfigSpec.ControlplaneConfig.NodeStartupTimeout, clusterConfigSpec.ControlplaneConfig.UnhealthyConditions); err != nil {
+		return err
+	}
+	if err := validateMachineHealthCheck("workers_config", clusterConfigSpec.WorkersConfig.NodeStartupTimeout, clusterConfigSpec.WorkersConfig.UnhealthyConditions); err != nil {
+		return err
+	}
+	for i, capiProvider := range clusterConfigSpec.CAPIProviders {
+		if !strings.HasPrefix(capiProvider.URL, "https://") && !strings.HasPrefix(capiProvider.URL, "/") {
+			return errors.New("spec.capi_providers[" + strconv.Itoa(i) + "]: Invalid value: \"url\": must be an https URL or an absolute path inside the bootstrap image")
+		}
+		if !strings.HasSuffix(capiProvider.URL, ".yaml") {
+			return errors.New("spec.capi_providers[" + strconv.Itoa(i) + "]: Invalid value: \"url\": must point to the provider components file")
+		}
+		if capiProvider.SHA256 != "" && strings.HasPrefix(capiProvider.URL, "https://github.com/") {
+			return errors.New("spec.capi_providers[" + strconv.Itoa(i) + "]: Invalid value: \"sha256\": can only be verified for mirrors and local paths")
+		}
+	}
+	machinePool := clusterConfigSpec.CAPXConfig.FeatureGates.MachinePool
+	if machinePool != nil && !*machinePool && spec.ControlPlane.Managed && spec.InfraProvider != "aws" {
+		return errors.New("spec.capx_config.feature_gates.machine_pool: Invalid value: it is required by " + spec.InfraProvider + " managed clusters")
+	}
+	if err := validateClusterResourceSets(clusterConfigSpec); err != nil {
+		return err
+	}
+	if clusterConfigSpec.CABundle != "" {
+		caBundle, err := os.ReadFile(clusterConfigSpec.CABundle)
+		if err != nil {
+			return errors.Wrap(err, "spec.ca_bundle: Invalid value: failed to read the CA bundle")
+		}
+		if !x509.NewCertPool().AppendCertsFromPEM(caBundle) {
+			return errors.New("spec.ca_bundle: Invalid value: \"" + clusterConfigSpec.CABundle + "\": must contain PEM encoded certificates")
+		}
+	}
+	for i, ns := range clusterConfigSpec.Namespaces {
+		for j := range clusterConfigSpec.Namespaces[:i] {
+			if clusterConfigSpec.Namespaces[j].Name == ns.Name {
+				return errors.New("spec.namespaces[" + strconv.Itoa(i) + "].name: Duplicate value: " + ns.Name)
+			}
+		}
+		if ns.Name == "kube-system" || ns.Name == "default" || strings.HasPrefix(ns.Name, "kube-") {
+			return errors.New("spec.namespaces[" + strconv.Itoa(i) + "].name: Invalid value: \"" + ns.Name + "\": system namespaces cannot be declared")
+		}
+	}
+	if policyEngine := clusterConfigSpec.PolicyEngine; policyEngine != nil {
+		if policyEngine.Engine == "gatekeeper" && len(policyEngine.Policies) == 0 {
+			return errors.New("spec.policy_engine.policies: Required value: Gatekeeper has no default policy bundle")
+		}
+		for i, source := range policyEngine.Policies {
+			if strings.HasPrefix(source, "http://") {
+				return errors.New("spec.policy_engine.policies[" + strconv.Itoa(i) + "]: Invalid value: only https URLs are supported")
+			}
+		}
+	}
+	if externalSecrets := clusterConfigSpec.ExternalSecrets; externalSecrets != nil {
+		if !slices.Contains([]string{"aws", "azure", "gcp"}, spec.InfraProvider) {
+			return errors.New("spec.external_secrets: Invalid value: secret stores are only supported in aws, azure and gcp clusters")
+		}
+		if spec.InfraProvider == "azure" && externalSecrets.VaultURL == "" {
+			return errors.New("spec.external_secrets.vault_url: Required value: the Key Vault is required in azure clusters")
+		}
+	}
+	if systemBaseline := clusterConfigSpec.SystemBaseline; systemBaseline != nil && spec.ControlPlane.Managed {
+		if len(systemBaseline.KubeReserved) > 0 || len(systemBaseline.SystemReserved) > 0 {
+			return errors.New("spec.system_baseline: Invalid value: the kubelet reservations can only be set in unmanaged clusters")
+		}
+	}
+	if clusterConfigSpec.NodeLocalDNS != nil {
+		if spec.InfraProvider == "gcp" && spec.ControlPlane.Managed {
+			return errors.New("spec.node_local_dns: Invalid value: it is not supported in gcp managed clusters, which offer it as a GKE addon")
+		}
+		if localIP := clusterConfigSpec.NodeLocalDNS.LocalIP; localIP != "" && !strings.HasPrefix(localIP, "169.254.") {
+			return errors.New("spec.node_local_dns.local_ip: Invalid value: \"" + localIP + "\": must be a link-local address")
+		}
+	}
+	if clusterConfigSpec.OSPatching != nil {
+		if spec.ControlPlane.Managed {
+			return errors.New("spec.os_patching: Invalid value: the nodes of managed clusters are patched with the node images of the provider")
+		}
+		if spec.InfraProvider == "gcp" {
+			return errors.New("spec.os_patching: Invalid value: gcp nodes are patched with OS Config patch deployments, targeting the instances with the capg-cluster-<name> label")
+		}
+		if !slices.Contains([]string{"aws", "azure"}, spec.InfraProvider) {
+			return errors.New("spec.os_patching: Invalid value: it is only supported in aws and azure clusters")
+		}
+	}
+	if clusterConfigSpec.CostAllocation != nil && !slices.Contains([]string{"aws", "azure", "gcp"}, spec.InfraProvider) {
+		return errors.New("spec.cost_allocation: Invalid value: the cloud costs are only supported in aws, azure and gcp clusters")
+	}
+	if clusterConfigSpec.SpotTermination != nil {
+		if !slices.Contains([]string{"aws", "azure", "gcp"}, spec.InfraProvider) {
+			return errors.New("spec.spot_termination: Invalid value: it is only supported in aws, azure and gcp clusters")
+		}
+		if spec.InfraProvider == "gcp" && spec.ControlPlane.Managed {
+			return errors.New("spec.spot_termination: Invalid value: it is not supported in gcp managed clusters, whose spot nodes are already shut down gracefully")
+		}
+		if !hasSpotWorkers(spec.WorkerNodes) {
+			return errors.New("spec.spot_termination: Invalid value: there are no spot worker nodes")
+		}
+	}
+	if clusterConfigSpec.RegistryLogin != nil {
+		for _, registry := range spec.DockerRegistries {
+			if !registry.KeosRegistry {
+				continue
+			}
+			if registry.Type != "generic" {
+				return errors.New("spec.registry_login: Invalid value: the nodes pull from " + registry.Type + " registries with their cloud identity")
+			}
+			if !registry.AuthRequired {
+				return errors.New("spec.registry_login: Invalid value: the keos registry does not require authentication")
+			}
+		}
+	}
+	if addonsPDB := clusterConfigSpec.AddonsPDB; addonsPDB != nil && addonsPDB.MaxUnavailable != "" {
+		// The pods of the addons could never be evicted with 0
+		if !regexp.MustCompile(`^\d+%?$`).MatchString(addonsPDB.MaxUnavailable) || strings.TrimSuffix(addonsPDB.MaxUnavailable, "%") == "0" {
+			return errors.New("spec.addons_pdb.max_unavailable: Invalid value: \"" + addonsPDB.MaxUnavailable + "\": it must be a positive number or percentage")
+		}
+	}
+	if interconnect := clusterConfigSpec.Interconnect; interconnect != nil {
+		if !slices.Contains([]string{"aws", "azure", "gcp"}, spec.InfraProvider) {
+			return errors.New("spec.interconnect: Invalid value: it is only supported in aws, azure and gcp clusters")
+		}
+		if !hasWorkerNodeGroup(spec.WorkerNodes, interconnect.GatewayNodeGroup) {
+			return errors.New("spec.interconnect.gateway_node_group: Invalid value: \"" + interconnect.GatewayNodeGroup + "\": it is not a worker node group")
+		}
+		// The clusters joining the broker read the information written by the one hosting it. The
+		// DR cluster is created once the cluster has deployed or joined the broker
+		if !interconnect.Broker && (spec.DR == nil || !spec.DR.Secondary) {
+			if _, err := os.Stat(interconnect.BrokerInfo); err != nil {
+				return errors.New("spec.interconnect.broker_info: Invalid value: \"" + interconnect.BrokerInfo + "\": the broker information cannot be read")
+			}
+		}
+	}
+	if access := clusterConfigSpec.ManagementAccess; access != nil && len(access.Groups) == 0 && len(access.Users) == 0 {
+		return errors.New("spec.management_access: Invalid value: at least a group or a user is required")
+	}
+	if clusterConfigSpec.RegistryCache != nil && spec.ControlPlane.Managed {
+		return errors.New("spec.registry_cache: Invalid value: the containerd mirrors can only be set in unmanaged clusters")
 	}
 	for i, chart := range clusterConfigSpec.Charts {
 		for j, chartCheck := range clusterConfigSpec.Charts {
@@ -70,10 +431,102 @@ func validateClusterConfig(spec commons.KeosSpec, clusterConfigSpec commons.Clus
 	return nil
 }
 
+func validateClusterResourceSets(clusterConfigSpec commons.ClusterConfigSpec) error {
+	var names []string
+	for _, crs := range clusterConfigSpec.ClusterResourceSets {
+		if commons.Contains(names, crs.Name) {
+			return errors.New("spec.cluster_resource_sets." + crs.Name + ": Invalid value: \"name\": is duplicated")
+		}
+		names = append(names, crs.Name)
+		for _, source := range crs.Sources {
+			if strings.HasPrefix(source, "https://") {
+				continue
+			}
+			if _, err := os.Stat(source); err != nil {
+				return errors.New("spec.cluster_resource_sets." + crs.Name + ": Invalid value: \"sources\": " + source + " must be an existing path or an https URL")
+			}
+		}
+	}
+	clusterResourceSet := clusterConfigSpec.CAPXConfig.FeatureGates.ClusterResourceSet
+	if len(names) > 0 && clusterResourceSet != nil && !*clusterResourceSet {
+		return errors.New("spec.cluster_resource_sets: Invalid value: it requires the cluster_resource_set feature gate")
+	}
+	return nil
+}
+
+// validateClusterName checks the name is valid in the names of the namespace of the cluster
+// objects, cluster-<name>, and of the provider resources named after it
+func validateClusterName(keosCluster commons.KeosCluster) error {
+	name := keosCluster.Metadata.Name
+	maxLength := 63 - len("cluster-")
+	if length, ok := clusterNameMaxLength[keosCluster.Spec.InfraProvider]; ok {
+		maxLength = length
+	}
+	regex := regexp.MustCompile(`^[a-z]([-a-z0-9]*[a-z0-9])?$`)
+	if !regex.MatchString(name) {
+		return errors.New("metadata.name: Invalid value: \"" + name + "\": must consist of lower case alphanumeric characters or '-', start with an alphabetic character and end with an alphanumeric character")
+	}
+	if len(name) > maxLength {
+		return errors.New("metadata.name: Invalid value: \"" + name + "\": must be no more than " + strconv.Itoa(maxLength) + " characters long in " + keosCluster.Spec.InfraProvider + " clusters")
+	}
+	return nil
+}
+
+func validateNamingPolicy(keosCluster commons.KeosCluster, namingPolicy *commons.NamingPolicy) error {
+	if namingPolicy == nil {
+		return nil
+	}
+	var namingRegex *regexp.Regexp
+	if namingPolicy.Regex != "" {
+		var err error
+		if namingRegex, err = regexp.Compile(namingPolicy.Regex); err != nil {
+			return errors.Wrap(err, "spec.naming_policy: Invalid value: \"regex\"")
+		}
+	}
+	validateName := func(field string, name string) error {
+		if !strings.HasPrefix(name, namingPolicy.Prefix) {
+			return errors.New(field + ": Invalid value: \"" + name + "\": must start with the naming policy prefix " + namingPolicy.Prefix)
+		}
+		if namingRegex != nil && !namingRegex.MatchString(name) {
+			return errors.New(field + ": Invalid value: \"" + name + "\": must match the naming policy regex " + namingPolicy.Regex)
+		}
+		return nil
+	}
+
+	if err := validateName("metadata.name", keosCluster.Metadata.Name); err != nil {
+		return err
+	}
+	if keosCluster.Spec.DR != nil {
+		if err := validateName("spec.dr.name", keosCluster.Spec.DR.Name); err != nil {
+			return err
+		}
+	}
+	for _, wn := range keosCluster.Spec.WorkerNodes {
+		if err := validateName("spec.worker_nodes.name", wn.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateMachineHealthCheck(field string, nodeStartupTimeout string, unhealthyConditions []commons.UnhealthyCondition) error {
+	if nodeStartupTimeout != "" {
+		if _, err := time.ParseDuration(nodeStartupTimeout); err != nil {
+			return errors.New("spec: Invalid value: \"" + field + ".node_startup_timeout\" in clusterConfig: " + nodeStartupTimeout + " is not a valid duration")
+		}
+	}
+	for _, condition := range unhealthyConditions {
+		if _, err := time.ParseDuration(condition.Timeout); err != nil {
+			return errors.New("spec: Invalid value: \"" + field + ".unhealthy_conditions.timeout\" in clusterConfig: " + condition.Timeout + " is not a valid duration")
+		}
+	}
+	return nil
+}
+
 func validateK8SVersion(v string) error {
-	var isVersion = regexp.MustCompile(`^v\d.\d{2}.\d{1,2}(-gke.\d{3,4})?$`).MatchString
+	var isVersion = regexp.MustCompile(`^v\d\.\d{2}\.\d{1,2}(-gke\.\d{3,4})?$`).MatchString
 	if !isVersion(v) {
-		return errors.New("spec: Invalid value: \"k8s_version\": regex used for validation is '^v\\d.\\d{2}.\\d{1,2}(-gke.\\d{3,4})?$'")
+		return errors.New("spec: Invalid value: \"k8s_version\": regex used for validation is '^v\\d\\.\\d{2}\\.\\d{1,2}(-gke\\.\\d{3,4})?$'")
 	}
 	K8sVersionMM := strings.Split(v, ".")
 	k8sVersion := strings.Join(K8sVersionMM[:2], ".")
@@ -96,6 +549,29 @@ func validateWorkers(wn commons.WorkerNodes) error {
 	if err := validateWorkersType(wn); err != nil {
 		return err
 	}
+	if err := validateWorkersNodeSecurity(wn); err != nil {
+		return err
+	}
+	if err := validateWorkersInfra(wn); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateWorkersInfra rejects the infra node groups whose nodes can be taken away, as the system
+// components are only scheduled in them
+func validateWorkersInfra(wns commons.WorkerNodes) error {
+	for _, wn := range wns {
+		if !wn.Infra {
+			continue
+		}
+		if wn.Spot {
+			return errors.New("spec.worker_nodes." + wn.Name + ".infra: Invalid value: infra node groups cannot be spot")
+		}
+		if wn.NodeGroupMinSize != nil && *wn.NodeGroupMinSize == 0 {
+			return errors.New("spec.worker_nodes." + wn.Name + ".infra: Invalid value: infra node groups cannot be scaled to 0")
+		}
+	}
 	return nil
 }
 
@@ -202,6 +678,31 @@ func validateWorkersType(wns commons.WorkerNodes) error {
 	return nil
 }
 
+func validateWorkersNodeSecurity(wns commons.WorkerNodes) error {
+	sysctlRegex := regexp.MustCompile(`^[a-z0-9_]+([./][a-z0-9_-]+)+$`)
+	moduleRegex := regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	for _, wn := range wns {
+		if wn.NodeSecurity == nil {
+			continue
+		}
+		// The nodes of the group are selected by its labels
+		if len(wn.Labels) == 0 {
+			return errors.New("spec.worker_nodes." + wn.Name + ".node_security: Invalid value: the node group must have labels to select its nodes")
+		}
+		for key, value := range wn.NodeSecurity.Sysctls {
+			if !sysctlRegex.MatchString(key) || strings.ContainsAny(value, "\n'") {
+				return errors.New("spec.worker_nodes." + wn.Name + ".node_security.sysctls: Invalid value: \"" + key + "\"")
+			}
+		}
+		for _, module := range wn.NodeSecurity.KernelModules {
+			if !moduleRegex.MatchString(module) {
+				return errors.New("spec.worker_nodes." + wn.Name + ".node_security.kernel_modules: Invalid value: \"" + module + "\"")
+			}
+		}
+	}
+	return nil
+}
+
 func validateVolumes(spec commons.KeosSpec) error {
 
 	if spec.ControlPlane.Managed {
@@ -269,3 +770,71 @@ func validateVolumeType(t string, supportedTypes []string) error {
 	}
 	return nil
 }
+
+// validateNoVolumes checks that no volumes are set, for providers whose machines
+// come with their own local disks
+func validateNoVolumes(spec commons.KeosSpec) error {
+	if !reflect.DeepEqual(spec.ControlPlane.RootVolume, commons.RootVolume{}) ||
+		!reflect.DeepEqual(spec.ControlPlane.CRIVolume, commons.CustomVolume{}) ||
+		!reflect.DeepEqual(spec.ControlPlane.ETCDVolume, commons.CustomVolume{}) ||
+		len(spec.ControlPlane.ExtraVolumes) > 0 {
+		return errors.New("spec.control_plane: Invalid value: volumes are not supported in " + spec.InfraProvider + " clusters")
+	}
+	for _, wn := range spec.WorkerNodes {
+		if !reflect.DeepEqual(wn.RootVolume, commons.RootVolume{}) ||
+			!reflect.DeepEqual(wn.CRIVolume, commons.CustomVolume{}) ||
+			len(wn.ExtraVolumes) > 0 {
+			return errors.New("spec.worker_nodes." + wn.Name + ": Invalid value: volumes are not supported in " + spec.InfraProvider + " clusters")
+		}
+	}
+	return nil
+}
+
+// validateGenericRepositories checks that only generic docker registries and
+// helm repositories are used, for providers without a registry service
+func validateGenericRepositories(spec commons.KeosSpec) error {
+	for i, dr := range spec.DockerRegistries {
+		if dr.Type != "generic" {
+			return errors.New("spec.docker_registries[" + strconv.Itoa(i) + "]: Invalid value: \"type\": only 'generic' is supported in " + spec.InfraProvider + " clusters")
+		}
+	}
+	if spec.HelmRepository.Type != "generic" {
+		return errors.New("spec.helm_repository: Invalid value: \"type\": only 'generic' is supported in " + spec.InfraProvider + " clusters")
+	}
+	return nil
+}
+
+// validateDR checks the "dr" block against the cluster it is paired with
+func validateDR(keosCluster commons.KeosCluster, clusterConfigSpec commons.ClusterConfigSpec) error {
+	dr := keosCluster.Spec.DR
+	if dr == nil || dr.Secondary {
+		return nil
+	}
+	if dr.Name == keosCluster.Metadata.Name {
+		return errors.New("spec.dr.name: Invalid value: \"" + dr.Name + "\": must be different from the cluster name")
+	}
+	if dr.Region == keosCluster.Spec.Region {
+		return errors.New("spec.dr.region: Invalid value: \"" + dr.Region + "\": must be different from the cluster region")
+	}
+	if dr.Velero.Enabled && dr.Velero.Bucket == dr.Velero.DRBucket {
+		return errors.New("spec.dr.velero.dr_bucket: Invalid value: \"" + dr.Velero.DRBucket + "\": must be different from the cluster bucket")
+	}
+	if interconnect := clusterConfigSpec.Interconnect; interconnect != nil {
+		// The global IPs of the exported services must be unique among the joined clusters
+		if dr.Interconnect.GlobalCIDR == "" {
+			return errors.New("spec.dr.interconnect.global_cidr: Required value: the dr cluster joins the interconnect of the cluster")
+		}
+		drGlobalNet, err := parseIPv4CIDR(dr.Interconnect.GlobalCIDR)
+		if err != nil {
+			return errors.New("spec.dr.interconnect.global_cidr: Invalid value: \"" + dr.Interconnect.GlobalCIDR + "\": " + err.Error())
+		}
+		globalNet, err := parseIPv4CIDR(interconnect.GlobalCIDR)
+		if err == nil && (globalNet.Contains(drGlobalNet.IP) || drGlobalNet.Contains(globalNet.IP)) {
+			return errors.New("spec.dr.interconnect.global_cidr: Invalid value: \"" + dr.Interconnect.GlobalCIDR + "\": must not overlap the global CIDR of the cluster")
+		}
+		if interconnect.ClusterID == dr.Name {
+			return errors.New("spec.interconnect.cluster_id: Invalid value: \"" + interconnect.ClusterID + "\": must be different from the dr cluster name")
+		}
+	}
+	return nil
+}
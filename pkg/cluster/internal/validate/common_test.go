@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"sigs.k8s.io/kind/pkg/commons"
+)
+
+// FuzzValidateK8SVersion checks the accepted versions are the supported ones, and can be split in
+// the major, minor and patch versions the templates are selected with
+func FuzzValidateK8SVersion(f *testing.F) {
+	for _, seed := range []string{"v1.30.4", "v1.28.13-gke.1234", "v1.27.1", "v1x30x4", "1.30.4", "v1.30", ""} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, version string) {
+		if err := validateK8SVersion(version); err != nil {
+			return
+		}
+		parts := strings.Split(version, ".")
+		if len(parts) != 3 && len(parts) != 4 {
+			t.Fatalf("%s is accepted without a major, minor and patch version", version)
+		}
+		if !commons.Contains(k8sVersionSupported, strings.TrimPrefix(parts[0], "v")+"."+parts[1]) {
+			t.Fatalf("%s is accepted but unsupported", version)
+		}
+	})
+}
+
+func TestValidatePodsNetworkProperties(t *testing.T) {
+	property := func(ip [4]byte, prefix uint8) bool {
+		prefix %= 40
+		block := fmt.Sprintf("%d.%d.%d.%d/%d", ip[0], ip[1], ip[2], ip[3], prefix)
+		var spec commons.KeosSpec
+		spec.Networks.PodsCidrBlock = block
+
+		// Only the blocks starting at their network address are valid
+		valid := prefix <= 32 && ipv4Uint(ip)&^prefixMask(prefix) == 0
+		return (validatePodsNetwork(spec) == nil) == valid
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+
+	var spec commons.KeosSpec
+	for _, block := range []string{"::ffff:10.0.0.0/104", "fd00::/64", "10.0.0.0"} {
+		spec.Networks.PodsCidrBlock = block
+		if validatePodsNetwork(spec) == nil {
+			t.Errorf("%s is accepted as the pods CIDR block", block)
+		}
+	}
+}
+
+// TestValidateWorkersQuantityProperties checks the quantity of a single node group, which is spread
+// across 3 AZs unless it is placed in a single one
+func TestValidateWorkersQuantityProperties(t *testing.T) {
+	property := func(quantity uint8, maxSize uint8, placed bool) bool {
+		q := int(quantity % 32)
+		workerNodes := make(commons.WorkerNodes, 1)
+		workerNodes[0].Name = "workers"
+		workerNodes[0].Quantity = &q
+		workerNodes[0].NodeGroupMaxSize = int(maxSize % 32)
+		if placed {
+			workerNodes[0].AZ = "zone-a"
+		}
+
+		valid := workerNodes[0].NodeGroupMaxSize == 0 || workerNodes[0].NodeGroupMaxSize >= q
+		if placed {
+			valid = valid && q >= 1
+		} else {
+			valid = valid && q > 0 && q%3 == 0
+		}
+		return (validateWorkersQuantity(workerNodes) == nil) == valid
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func ipv4Uint(ip [4]byte) uint32 {
+	return uint32(ip[0])<<24 | uint32(ip[1])<<16 | uint32(ip[2])<<8 | uint32(ip[3])
+}
+
+func prefixMask(prefix uint8) uint32 {
+	if prefix == 0 {
+		return 0
+	}
+	return ^uint32(0) << (32 - prefix)
+}
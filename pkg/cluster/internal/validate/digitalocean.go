@@ -0,0 +1,168 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const digitalOceanAPIURL = "https://api.digitalocean.com/v2"
+
+func validateDigitalOcean(spec commons.KeosSpec, providerSecrets map[string]string) error {
+	var err error
+
+	if spec.ControlPlane.Managed {
+		return errors.New("spec.control_plane: Invalid value: \"managed\": managed control planes are not supported in digitalocean clusters")
+	}
+
+	regions, err := getDigitalOceanRegions(providerSecrets)
+	if err != nil {
+		return err
+	}
+	if !commons.Contains(regions, spec.Region) {
+		return errors.New("spec.region: " + spec.Region + " region does not exist or is not available, regions: " + fmt.Sprint(regions))
+	}
+
+	sizes, err := getDigitalOceanSizes(providerSecrets, spec.Region)
+	if err != nil {
+		return err
+	}
+
+	if err = validateDigitalOceanNetworks(spec, providerSecrets); err != nil {
+		return err
+	}
+
+	if (spec.StorageClass != commons.StorageClass{}) {
+		if spec.StorageClass.EncryptionKey != "" || spec.StorageClass.EFS != (commons.EFS{}) ||
+			spec.StorageClass.Parameters != (commons.SCParameters{FsType: spec.StorageClass.Parameters.FsType}) {
+			return errors.New("spec.storageclass: Invalid value: only \"class\" and \"parameters.fsType\" are supported in digitalocean clusters")
+		}
+	}
+
+	if err = validateGenericRepositories(spec); err != nil {
+		return err
+	}
+	if err = validateNoVolumes(spec); err != nil {
+		return err
+	}
+
+	if !commons.Contains(sizes, spec.ControlPlane.Size) {
+		return errors.New("spec.control_plane.size: " + spec.ControlPlane.Size + " droplet size is not available in region " + spec.Region)
+	}
+
+	for _, wn := range spec.WorkerNodes {
+		if !commons.Contains(sizes, wn.Size) {
+			return errors.New("spec.worker_nodes." + wn.Name + ".size: " + wn.Size + " droplet size is not available in region " + spec.Region)
+		}
+		if wn.AZ != "" {
+			return errors.New("spec.worker_nodes." + wn.Name + ".az: Invalid value: availability zones are not supported in digitalocean clusters")
+		}
+		if wn.Spot {
+			return errors.New("spec.worker_nodes." + wn.Name + ".spot: Invalid value: spot instances are not supported in digitalocean clusters")
+		}
+	}
+
+	return nil
+}
+
+func validateDigitalOceanNetworks(spec commons.KeosSpec, providerSecrets map[string]string) error {
+	networks := spec.Networks
+	if networks.VPCCIDRBlock != "" || networks.ResourceGroup != "" || len(networks.Subnets) > 0 || len(networks.PodsSubnets) > 0 {
+		return errors.New("spec.networks: Invalid value: only \"vpc_id\" and \"pods_cidr\" are supported in digitalocean clusters")
+	}
+	if networks.VPCID != "" {
+		var response struct {
+			VPC struct {
+				Region string `json:"region"`
+			} `json:"vpc"`
+		}
+		if err := digitalOceanGet(providerSecrets["Token"], "/vpcs/"+networks.VPCID, &response); err != nil {
+			return errors.Wrap(err, "spec.networks.vpc_id: Invalid value: \""+networks.VPCID+"\"")
+		}
+		if response.VPC.Region != spec.Region {
+			return errors.New("spec.networks.vpc_id: Invalid value: \"" + networks.VPCID + "\": VPC does not belong to region " + spec.Region)
+		}
+	}
+	return nil
+}
+
+func getDigitalOceanRegions(providerSecrets map[string]string) ([]string, error) {
+	var regions []string
+	var response struct {
+		Regions []struct {
+			Slug      string `json:"slug"`
+			Available bool   `json:"available"`
+		} `json:"regions"`
+	}
+
+	err := digitalOceanGet(providerSecrets["Token"], "/regions?per_page=200", &response)
+	if err != nil {
+		return nil, err
+	}
+	for _, region := range response.Regions {
+		if region.Available {
+			regions = append(regions, region.Slug)
+		}
+	}
+	return regions, nil
+}
+
+func getDigitalOceanSizes(providerSecrets map[string]string, region string) ([]string, error) {
+	var sizes []string
+	var response struct {
+		Sizes []struct {
+			Slug      string   `json:"slug"`
+			Available bool     `json:"available"`
+			Regions   []string `json:"regions"`
+		} `json:"sizes"`
+	}
+
+	err := digitalOceanGet(providerSecrets["Token"], "/sizes?per_page=200", &response)
+	if err != nil {
+		return nil, err
+	}
+	for _, size := range response.Sizes {
+		if size.Available && commons.Contains(size.Regions, region) {
+			sizes = append(sizes, size.Slug)
+		}
+	}
+	return sizes, nil
+}
+
+func digitalOceanGet(token string, path string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, digitalOceanAPIURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to the DigitalOcean API")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("DigitalOcean API request " + path + " failed: " + resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
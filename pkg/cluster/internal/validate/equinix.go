@@ -0,0 +1,136 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const equinixMetalAPIURL = "https://api.equinix.com/metal/v1"
+
+func validateEquinix(spec commons.KeosSpec, providerSecrets map[string]string) error {
+	var err error
+
+	if spec.ControlPlane.Managed {
+		return errors.New("spec.control_plane: Invalid value: \"managed\": managed control planes are not supported in equinix clusters")
+	}
+
+	metros, err := getEquinixMetros(providerSecrets)
+	if err != nil {
+		return err
+	}
+	if !commons.Contains(metros, spec.Region) {
+		return errors.New("spec.region: Invalid value: \"" + spec.Region + "\": must be the code of an Equinix Metal metro, as the facilities are not supported")
+	}
+
+	plans, err := getEquinixPlans(providerSecrets)
+	if err != nil {
+		return err
+	}
+
+	if !reflect.ValueOf(spec.Networks).IsZero() {
+		return errors.New("spec.networks: Invalid value: custom networks are not supported in equinix clusters")
+	}
+
+	// The local volumes are directories of the disks of the servers, which take no parameters
+	if spec.StorageClass.EncryptionKey != "" || spec.StorageClass.EFS != (commons.EFS{}) ||
+		spec.StorageClass.Parameters != (commons.SCParameters{}) {
+		return errors.New("spec.storageclass: Invalid value: \"parameters\": the encryption and the parameters of the volumes are not supported in equinix clusters")
+	}
+
+	if err = validateGenericRepositories(spec); err != nil {
+		return err
+	}
+	if err = validateNoVolumes(spec); err != nil {
+		return err
+	}
+
+	if !commons.Contains(plans, spec.ControlPlane.Size) {
+		return errors.New("spec.control_plane.size: " + spec.ControlPlane.Size + " does not exist as an Equinix Metal plan")
+	}
+
+	for _, wn := range spec.WorkerNodes {
+		if !commons.Contains(plans, wn.Size) {
+			return errors.New("spec.worker_nodes." + wn.Name + ".size: " + wn.Size + " does not exist as an Equinix Metal plan")
+		}
+		if wn.Spot {
+			return errors.New("spec.worker_nodes." + wn.Name + ".spot: Invalid value: spot instances are not supported in equinix clusters")
+		}
+	}
+
+	return nil
+}
+
+func getEquinixMetros(providerSecrets map[string]string) ([]string, error) {
+	var metros []string
+	var response struct {
+		Metros []struct {
+			Code string `json:"code"`
+		} `json:"metros"`
+	}
+
+	err := equinixMetalGet(providerSecrets["ApiKey"], "/locations/metros", &response)
+	if err != nil {
+		return nil, err
+	}
+	for _, metro := range response.Metros {
+		metros = append(metros, metro.Code)
+	}
+	return metros, nil
+}
+
+func getEquinixPlans(providerSecrets map[string]string) ([]string, error) {
+	var plans []string
+	var response struct {
+		Plans []struct {
+			Slug string `json:"slug"`
+		} `json:"plans"`
+	}
+
+	err := equinixMetalGet(providerSecrets["ApiKey"], "/projects/"+providerSecrets["ProjectID"]+"/plans", &response)
+	if err != nil {
+		return nil, err
+	}
+	for _, plan := range response.Plans {
+		plans = append(plans, plan.Slug)
+	}
+	return plans, nil
+}
+
+func equinixMetalGet(apiKey string, path string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, equinixMetalAPIURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Auth-Token", apiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to the Equinix Metal API")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("Equinix Metal API request " + path + " failed: " + resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -28,6 +28,7 @@ import (
 
 	b64 "encoding/base64"
 
+	"google.golang.org/api/cloudresourcemanager/v1"
 	"google.golang.org/api/compute/v1"
 	"google.golang.org/api/option"
 	"sigs.k8s.io/kind/pkg/commons"
@@ -62,11 +63,15 @@ func validateGCP(spec commons.KeosSpec, providerSecrets map[string]string) error
 	}
 
 	if !reflect.ValueOf(spec.Networks).IsZero() {
-		if err = validateGCPNetwork(spec.Networks, credentialsJson, spec.Region); err != nil {
+		if err = validateGCPNetwork(spec.Networks, credentialsJson, spec.Region, commons.GCPNetworkProject(providerSecrets)); err != nil {
 			return errors.Wrap(err, "spec.networks: Invalid value")
 		}
 	}
 
+	if err = validateGCPPermissions(spec, credentialsJson, providerSecrets); err != nil {
+		return err
+	}
+
 	for i, dr := range spec.DockerRegistries {
 		if dr.Type != "gar" && dr.Type != "gcr" && spec.ControlPlane.Managed {
 			return errors.New("spec.docker_registries[" + strconv.Itoa(i) + "]: Invalid value: \"type\": only 'gar' and 'gcr' are supported in gcp managed clusters")
@@ -116,14 +121,10 @@ func validateGCP(spec commons.KeosSpec, providerSecrets map[string]string) error
 		}
 	}
 
+	if err = validateWorkersAZs(spec.WorkerNodes, azs); err != nil {
+		return errors.Wrap(err, "invalid AZ in this region")
+	}
 	for _, wn := range spec.WorkerNodes {
-		if wn.AZ != "" {
-			if len(azs) > 0 {
-				if !commons.Contains(azs, wn.AZ) {
-					return errors.New(wn.AZ + " does not exist in this region, azs: " + fmt.Sprint(azs))
-				}
-			}
-		}
 		if wn.Size != "" {
 			if err := validateGCPInstanceType(wn.Size, credentialsJson, spec.Region, azs, wn.AZ); err != nil {
 				return errors.New("spec.worker_nodes." + wn.Name + ".size: " + wn.Size + " does not exist as a GCP instance types in region " + spec.Region)
@@ -256,9 +257,9 @@ func validateGCPLabel(l string) error {
 	return nil
 }
 
-func validateGCPNetwork(network commons.Networks, credentialsJson string, region string) error {
+func validateGCPNetwork(network commons.Networks, credentialsJson string, region string, project string) error {
 	if network.VPCID != "" {
-		vpcs, _ := getGoogleVPCs(credentialsJson)
+		vpcs, _ := getGoogleVPCs(credentialsJson, project)
 		if len(vpcs) > 0 && !commons.Contains(vpcs, network.VPCID) {
 			return errors.New("\"vpc_id\": " + network.VPCID + " does not exist")
 		}
@@ -268,7 +269,7 @@ func validateGCPNetwork(network commons.Networks, credentialsJson string, region
 		if network.Subnets[0].SubnetId == "" {
 			return errors.New("\"subnet_id\": required")
 		}
-		subnets, _ := getGoogleSubnets(credentialsJson, region, network.VPCID)
+		subnets, _ := getGoogleSubnets(credentialsJson, region, network.VPCID, project)
 		if !commons.Contains(subnets, network.Subnets[0].SubnetId) {
 			return errors.New("\"subnets\": " + network.Subnets[0].SubnetId + " does not belong to vpc with id: " + network.VPCID)
 		}
@@ -292,6 +293,55 @@ func validateGCPNetwork(network commons.Networks, credentialsJson string, region
 	return nil
 }
 
+// validateGCPPermissions checks the IAM bindings of the service account in the projects
+// of the cluster, the network and the node images
+func validateGCPPermissions(spec commons.KeosSpec, credentialsJson string, providerSecrets map[string]string) error {
+	projectPermissions := map[string][]string{}
+	addPermissions := func(project string, permissions ...string) {
+		for _, permission := range permissions {
+			if !commons.Contains(projectPermissions[project], permission) {
+				projectPermissions[project] = append(projectPermissions[project], permission)
+			}
+		}
+	}
+
+	if spec.ControlPlane.Managed {
+		addPermissions(providerSecrets["ProjectID"], "container.clusters.create")
+	} else {
+		addPermissions(providerSecrets["ProjectID"], "compute.instances.create")
+	}
+	if spec.Networks.VPCID != "" {
+		addPermissions(commons.GCPNetworkProject(providerSecrets), "compute.networks.get", "compute.subnetworks.use")
+	}
+	nodeImages := []string{spec.ControlPlane.NodeImage}
+	for _, wn := range spec.WorkerNodes {
+		nodeImages = append(nodeImages, wn.NodeImage)
+	}
+	for _, nodeImage := range nodeImages {
+		if isGCPNodeImage(nodeImage) {
+			addPermissions(strings.Split(nodeImage, "/")[1], "compute.images.useReadOnly")
+		}
+	}
+
+	ctx := context.Background()
+	crmService, err := cloudresourcemanager.NewService(ctx, option.WithCredentialsJSON([]byte(credentialsJson)))
+	if err != nil {
+		return err
+	}
+	for project, permissions := range projectPermissions {
+		response, err := crmService.Projects.TestIamPermissions(project, &cloudresourcemanager.TestIamPermissionsRequest{Permissions: permissions}).Do()
+		if err != nil {
+			return errors.Wrap(err, "failed to get the IAM permissions in the "+project+" project")
+		}
+		for _, permission := range permissions {
+			if !commons.Contains(response.Permissions, permission) {
+				return errors.New("credentials: Invalid value: the service account does not have the " + permission + " permission in the " + project + " project")
+			}
+		}
+	}
+	return nil
+}
+
 func getGCPRegions(credentialsJson string) ([]string, error) {
 	var regions_names []string
 	var ctx = context.Background()
@@ -324,16 +374,10 @@ func getGCPRegions(credentialsJson string) ([]string, error) {
 
 }
 
-func getGoogleVPCs(credentialsJson string) ([]string, error) {
+func getGoogleVPCs(credentialsJson string, project string) ([]string, error) {
 	var network_names []string
 	var ctx = context.Background()
 
-	gcpCreds := map[string]string{}
-	err := json.Unmarshal([]byte(credentialsJson), &gcpCreds)
-	if err != nil {
-		return []string{}, err
-	}
-
 	cfg := option.WithCredentialsJSON([]byte(credentialsJson))
 	computeService, err := compute.NewService(ctx, cfg)
 
@@ -341,7 +385,7 @@ func getGoogleVPCs(credentialsJson string) ([]string, error) {
 		return []string{}, err
 	}
 
-	networks, err := computeService.Networks.List(string(gcpCreds["project_id"])).Do()
+	networks, err := computeService.Networks.List(project).Do()
 	if err != nil {
 		return []string{}, err
 	}
@@ -354,16 +398,10 @@ func getGoogleVPCs(credentialsJson string) ([]string, error) {
 
 }
 
-func getGoogleSubnets(credentialsJson string, region string, vpcId string) ([]string, error) {
+func getGoogleSubnets(credentialsJson string, region string, vpcId string, project string) ([]string, error) {
 	var subnetwork_names []string
 	var ctx = context.Background()
 
-	gcpCreds := map[string]string{}
-	err := json.Unmarshal([]byte(credentialsJson), &gcpCreds)
-	if err != nil {
-		return []string{}, err
-	}
-
 	cfg := option.WithCredentialsJSON([]byte(credentialsJson))
 	computeService, err := compute.NewService(ctx, cfg)
 
@@ -371,7 +409,7 @@ func getGoogleSubnets(credentialsJson string, region string, vpcId string) ([]st
 		return []string{}, err
 	}
 
-	subnetworks, err := computeService.Subnetworks.List(string(gcpCreds["project_id"]), region).Do()
+	subnetworks, err := computeService.Subnetworks.List(project, region).Do()
 	if err != nil {
 		return []string{}, err
 	}
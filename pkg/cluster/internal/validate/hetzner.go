@@ -0,0 +1,141 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const hetznerCloudAPIURL = "https://api.hetzner.cloud/v1"
+
+func validateHetzner(spec commons.KeosSpec, providerSecrets map[string]string) error {
+	var err error
+
+	if spec.ControlPlane.Managed {
+		return errors.New("spec.control_plane: Invalid value: \"managed\": managed control planes are not supported in hetzner clusters")
+	}
+
+	locations, err := getHetznerLocations(providerSecrets)
+	if err != nil {
+		return err
+	}
+	if !commons.Contains(locations, spec.Region) {
+		return errors.New("spec.region: " + spec.Region + " location does not exist, locations: " + fmt.Sprint(locations))
+	}
+
+	serverTypes, err := getHetznerServerTypes(providerSecrets)
+	if err != nil {
+		return err
+	}
+
+	if !reflect.ValueOf(spec.Networks).IsZero() {
+		return errors.New("spec.networks: Invalid value: custom networks are not supported in hetzner clusters")
+	}
+
+	if (spec.StorageClass != commons.StorageClass{}) {
+		if spec.StorageClass.EncryptionKey != "" || spec.StorageClass.EFS != (commons.EFS{}) ||
+			spec.StorageClass.Parameters != (commons.SCParameters{FsType: spec.StorageClass.Parameters.FsType}) {
+			return errors.New("spec.storageclass: Invalid value: only \"class\" and \"parameters.fsType\" are supported in hetzner clusters")
+		}
+	}
+
+	if err = validateGenericRepositories(spec); err != nil {
+		return err
+	}
+	if err = validateNoVolumes(spec); err != nil {
+		return err
+	}
+
+	if !commons.Contains(serverTypes, spec.ControlPlane.Size) {
+		return errors.New("spec.control_plane.size: " + spec.ControlPlane.Size + " does not exist as a Hetzner Cloud server type")
+	}
+
+	for _, wn := range spec.WorkerNodes {
+		if !commons.Contains(serverTypes, wn.Size) {
+			return errors.New("spec.worker_nodes." + wn.Name + ".size: " + wn.Size + " does not exist as a Hetzner Cloud server type")
+		}
+		if wn.AZ != "" && !commons.Contains(locations, wn.AZ) {
+			return errors.New("spec.worker_nodes." + wn.Name + ".az: " + wn.AZ + " location does not exist, locations: " + fmt.Sprint(locations))
+		}
+		if wn.Spot {
+			return errors.New("spec.worker_nodes." + wn.Name + ".spot: Invalid value: spot instances are not supported in hetzner clusters")
+		}
+	}
+
+	return nil
+}
+
+func getHetznerLocations(providerSecrets map[string]string) ([]string, error) {
+	var locations []string
+	var response struct {
+		Locations []struct {
+			Name string `json:"name"`
+		} `json:"locations"`
+	}
+
+	err := hetznerCloudGet(providerSecrets["Token"], "/locations", &response)
+	if err != nil {
+		return nil, err
+	}
+	for _, location := range response.Locations {
+		locations = append(locations, location.Name)
+	}
+	return locations, nil
+}
+
+func getHetznerServerTypes(providerSecrets map[string]string) ([]string, error) {
+	var serverTypes []string
+	var response struct {
+		ServerTypes []struct {
+			Name string `json:"name"`
+		} `json:"server_types"`
+	}
+
+	err := hetznerCloudGet(providerSecrets["Token"], "/server_types?per_page=50", &response)
+	if err != nil {
+		return nil, err
+	}
+	for _, serverType := range response.ServerTypes {
+		serverTypes = append(serverTypes, serverType.Name)
+	}
+	return serverTypes, nil
+}
+
+func hetznerCloudGet(token string, path string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, hetznerCloudAPIURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to the Hetzner Cloud API")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("Hetzner Cloud API request " + path + " failed: " + resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,279 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const (
+	ibmCloudIAMURL                = "https://iam.cloud.ibm.com/identity/token"
+	ibmCloudResourceControllerURL = "https://resource-controller.cloud.ibm.com/v2"
+	ibmCloudVPCAPIVersion         = "2024-04-30"
+)
+
+var IBMCloudVPCProfiles = []string{"general-purpose", "5iops-tier", "10iops-tier", "custom"}
+var IBMCloudPowerVSTiers = []string{"tier0", "tier1", "tier3", "tier5k"}
+
+func validateIBMCloud(spec commons.KeosSpec, providerSecrets map[string]string) error {
+	var err error
+
+	if spec.ControlPlane.Managed {
+		return errors.New("spec.control_plane: Invalid value: \"managed\": managed control planes are not supported in ibmcloud clusters")
+	}
+
+	token, err := getIBMCloudToken(providerSecrets["ApiKey"])
+	if err != nil {
+		return err
+	}
+
+	if err = validateGenericRepositories(spec); err != nil {
+		return err
+	}
+	if err = validateNoVolumes(spec); err != nil {
+		return err
+	}
+
+	if spec.ControlPlane.NodeImage == "" {
+		return errors.New("spec.control_plane: Required value: \"node_image\"")
+	}
+	for _, wn := range spec.WorkerNodes {
+		if wn.NodeImage == "" {
+			return errors.New("spec.worker_nodes." + wn.Name + ": Required value: \"node_image\"")
+		}
+		if wn.Spot {
+			return errors.New("spec.worker_nodes." + wn.Name + ".spot: Invalid value: spot instances are not supported in ibmcloud clusters")
+		}
+	}
+
+	if spec.ControlPlane.IBMCloud.Target == "powervs" {
+		return validateIBMCloudPowerVS(spec, token)
+	}
+	return validateIBMCloudVPC(spec, token)
+}
+
+func validateIBMCloudVPC(spec commons.KeosSpec, token string) error {
+	var err error
+
+	regions, err := getIBMCloudVPCNames(token, "us-south", "/regions", "regions")
+	if err != nil {
+		return err
+	}
+	if !commons.Contains(regions, spec.Region) {
+		return errors.New("spec.region: " + spec.Region + " region does not exist, regions: " + fmt.Sprint(regions))
+	}
+
+	zones, err := getIBMCloudVPCNames(token, spec.Region, "/regions/"+spec.Region+"/zones", "zones")
+	if err != nil {
+		return err
+	}
+	profiles, err := getIBMCloudVPCNames(token, spec.Region, "/instance/profiles", "profiles")
+	if err != nil {
+		return err
+	}
+	images, err := getIBMCloudVPCNames(token, spec.Region, "/images?limit=100&visibility=private", "images")
+	if err != nil {
+		return err
+	}
+
+	if err = validateIBMCloudVPCNetworks(spec, token); err != nil {
+		return err
+	}
+
+	if (spec.StorageClass != commons.StorageClass{}) {
+		sc := spec.StorageClass
+		if sc.EFS != (commons.EFS{}) || sc.Parameters != (commons.SCParameters{FsType: sc.Parameters.FsType, Profile: sc.Parameters.Profile}) {
+			return errors.New("spec.storageclass: Invalid value: only \"class\", \"encryptionKey\", \"parameters.fsType\" and \"parameters.profile\" are supported in ibmcloud vpc clusters")
+		}
+		if sc.Parameters.Profile != "" && !commons.Contains(IBMCloudVPCProfiles, sc.Parameters.Profile) {
+			return errors.New("spec.storageclass.parameters.profile: Unsupported value: \"" + sc.Parameters.Profile + "\": supported values: " + strings.Join(IBMCloudVPCProfiles, ", "))
+		}
+		if sc.EncryptionKey != "" && !strings.HasPrefix(sc.EncryptionKey, "crn:") {
+			return errors.New("spec.storageclass.encryptionKey: Invalid value: must be the CRN of a Key Protect or Hyper Protect root key")
+		}
+	}
+
+	if !commons.Contains(profiles, spec.ControlPlane.Size) {
+		return errors.New("spec.control_plane.size: " + spec.ControlPlane.Size + " does not exist as an IBM Cloud VPC instance profile")
+	}
+	if !commons.Contains(images, spec.ControlPlane.NodeImage) {
+		return errors.New("spec.control_plane.node_image: " + spec.ControlPlane.NodeImage + " image does not exist in region " + spec.Region)
+	}
+	for _, wn := range spec.WorkerNodes {
+		if !commons.Contains(profiles, wn.Size) {
+			return errors.New("spec.worker_nodes." + wn.Name + ".size: " + wn.Size + " does not exist as an IBM Cloud VPC instance profile")
+		}
+		if !commons.Contains(images, wn.NodeImage) {
+			return errors.New("spec.worker_nodes." + wn.Name + ".node_image: " + wn.NodeImage + " image does not exist in region " + spec.Region)
+		}
+		if wn.AZ != "" && !commons.Contains(zones, wn.AZ) {
+			return errors.New("spec.worker_nodes." + wn.Name + ".az: " + wn.AZ + " zone does not exist in region " + spec.Region + ", zones: " + fmt.Sprint(zones))
+		}
+	}
+
+	return nil
+}
+
+func validateIBMCloudVPCNetworks(spec commons.KeosSpec, token string) error {
+	networks := spec.Networks
+	if networks.VPCCIDRBlock != "" || networks.ResourceGroup != "" || len(networks.PodsSubnets) > 0 {
+		return errors.New("spec.networks: Invalid value: only \"vpc_id\", \"subnets\" and \"pods_cidr\" are supported in ibmcloud vpc clusters")
+	}
+	if networks.VPCID == "" {
+		if len(networks.Subnets) > 0 {
+			return errors.New("spec.networks.vpc_id: Required value: \"vpc_id\" is required when \"subnets\" are set")
+		}
+		return nil
+	}
+	if err := ibmCloudGet(token, ibmCloudVPCURL(spec.Region, "/vpcs/"+networks.VPCID), nil); err != nil {
+		return errors.Wrap(err, "spec.networks.vpc_id: Invalid value: \""+networks.VPCID+"\"")
+	}
+	for _, subnet := range networks.Subnets {
+		var response struct {
+			VPC struct {
+				ID string `json:"id"`
+			} `json:"vpc"`
+		}
+		if err := ibmCloudGet(token, ibmCloudVPCURL(spec.Region, "/subnets/"+subnet.SubnetId), &response); err != nil {
+			return errors.Wrap(err, "spec.networks.subnets: Invalid value: \""+subnet.SubnetId+"\"")
+		}
+		if response.VPC.ID != networks.VPCID {
+			return errors.New("spec.networks.subnets: Invalid value: \"" + subnet.SubnetId + "\": subnet does not belong to VPC " + networks.VPCID)
+		}
+	}
+	return nil
+}
+
+func validateIBMCloudPowerVS(spec commons.KeosSpec, token string) error {
+	var response struct {
+		RegionID string `json:"region_id"`
+		State    string `json:"state"`
+	}
+
+	serviceInstanceID := spec.ControlPlane.IBMCloud.ServiceInstanceID
+	if err := ibmCloudGet(token, ibmCloudResourceControllerURL+"/resource_instances/"+serviceInstanceID, &response); err != nil {
+		return errors.Wrap(err, "spec.control_plane.ibmcloud.service_instance_id: Invalid value: \""+serviceInstanceID+"\"")
+	}
+	if response.State != "active" {
+		return errors.New("spec.control_plane.ibmcloud.service_instance_id: Invalid value: \"" + serviceInstanceID + "\": workspace is not active")
+	}
+	// PowerVS workspaces are created in a zone (e.g. dal10), which is used as region
+	if response.RegionID != spec.Region {
+		return errors.New("spec.region: Invalid value: \"" + spec.Region + "\": the PowerVS workspace is located in " + response.RegionID)
+	}
+
+	if !isZeroNetworks(spec.Networks) {
+		return errors.New("spec.networks: Invalid value: only \"pods_cidr\" is supported in ibmcloud powervs clusters")
+	}
+
+	if (spec.StorageClass != commons.StorageClass{}) {
+		sc := spec.StorageClass
+		if sc.EncryptionKey != "" || sc.EFS != (commons.EFS{}) || sc.Parameters != (commons.SCParameters{FsType: sc.Parameters.FsType, Type: sc.Parameters.Type}) {
+			return errors.New("spec.storageclass: Invalid value: only \"class\", \"parameters.fsType\" and \"parameters.type\" are supported in ibmcloud powervs clusters")
+		}
+		if sc.Parameters.Type != "" && !commons.Contains(IBMCloudPowerVSTiers, sc.Parameters.Type) {
+			return errors.New("spec.storageclass.parameters.type: Unsupported value: \"" + sc.Parameters.Type + "\": supported values: " + strings.Join(IBMCloudPowerVSTiers, ", "))
+		}
+	}
+
+	for _, wn := range spec.WorkerNodes {
+		if wn.AZ != "" {
+			return errors.New("spec.worker_nodes." + wn.Name + ".az: Invalid value: availability zones are not supported in ibmcloud powervs clusters")
+		}
+	}
+
+	return nil
+}
+
+func isZeroNetworks(networks commons.Networks) bool {
+	return networks.VPCID == "" && networks.VPCCIDRBlock == "" && networks.ResourceGroup == "" &&
+		len(networks.Subnets) == 0 && len(networks.PodsSubnets) == 0
+}
+
+func getIBMCloudToken(apiKey string) (string, error) {
+	var response struct {
+		AccessToken string `json:"access_token"`
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ibm:params:oauth:grant-type:apikey")
+	form.Set("apikey", apiKey)
+	resp, err := http.PostForm(ibmCloudIAMURL, form)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to connect to IBM Cloud IAM")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New("failed to authenticate against IBM Cloud IAM: " + resp.Status)
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", err
+	}
+	return response.AccessToken, nil
+}
+
+func getIBMCloudVPCNames(token string, region string, path string, field string) ([]string, error) {
+	var names []string
+	var response map[string][]struct {
+		Name string `json:"name"`
+	}
+
+	if err := ibmCloudGet(token, ibmCloudVPCURL(region, path), &response); err != nil {
+		return nil, err
+	}
+	for _, item := range response[field] {
+		names = append(names, item.Name)
+	}
+	return names, nil
+}
+
+func ibmCloudVPCURL(region string, path string) string {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return "https://" + region + ".iaas.cloud.ibm.com/v1" + path + separator + "version=" + ibmCloudVPCAPIVersion + "&generation=2"
+}
+
+func ibmCloudGet(token string, u string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to the IBM Cloud API")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("IBM Cloud API request failed: " + resp.Status)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
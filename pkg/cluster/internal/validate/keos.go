@@ -0,0 +1,65 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"strings"
+
+	"golang.org/x/exp/slices"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+type keosRelease struct {
+	k8sVersions []string
+	providers   []string
+}
+
+var keosFlavours = []string{"production", "development", "minimal"}
+
+// keosCompatibility holds the Kubernetes versions and providers supported by each keos release
+var keosCompatibility = map[string]keosRelease{
+	"1.0": {k8sVersions: []string{"1.28", "1.29"}, providers: []string{"aws", "azure", "gcp"}},
+	"1.1": {k8sVersions: []string{"1.29", "1.30"}, providers: []string{"aws", "azure", "gcp"}},
+}
+
+func validateKeos(spec commons.KeosSpec) error {
+	if spec.Keos.Flavour != "" && !slices.Contains(keosFlavours, spec.Keos.Flavour) {
+		return errors.New("spec.keos.flavour: Invalid value: \"" + spec.Keos.Flavour + "\": supported flavours: " + strings.Join(keosFlavours, ", "))
+	}
+	if spec.Keos.Version == "" {
+		return nil
+	}
+
+	release, ok := keosCompatibility[strings.Join(strings.Split(spec.Keos.Version, ".")[:2], ".")]
+	if !ok {
+		var versions []string
+		for version := range keosCompatibility {
+			versions = append(versions, version)
+		}
+		slices.Sort(versions)
+		return errors.New("spec.keos.version: Invalid value: \"" + spec.Keos.Version + "\": supported keos versions: " + strings.Join(versions, ", "))
+	}
+	k8sVersion := strings.TrimPrefix(strings.Join(strings.Split(spec.K8SVersion, ".")[:2], "."), "v")
+	if !slices.Contains(release.k8sVersions, k8sVersion) {
+		return errors.New("spec.keos.version: Invalid value: \"" + spec.Keos.Version + "\": kubernetes versions supported: " + strings.Join(release.k8sVersions, ", "))
+	}
+	if !slices.Contains(release.providers, spec.InfraProvider) {
+		return errors.New("spec.keos.version: Invalid value: \"" + spec.Keos.Version + "\": providers supported: " + strings.Join(release.providers, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"reflect"
+	"regexp"
+
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const nutanixDefaultPort = "9440"
+
+var isNutanixUUID = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`).MatchString
+
+func validateNutanix(spec commons.KeosSpec, providerSecrets map[string]string) error {
+	var err error
+
+	if spec.ControlPlane.Managed {
+		return errors.New("spec.control_plane: Invalid value: \"managed\": managed control planes are not supported in nutanix clusters")
+	}
+
+	// The region is the Prism Element cluster where the nodes are deployed
+	if !isNutanixUUID(spec.Region) {
+		return errors.New("spec.region: Invalid value: \"" + spec.Region + "\": must be the UUID of a Prism Element cluster")
+	}
+	if err = nutanixEntityExists(providerSecrets, "clusters", spec.Region); err != nil {
+		return errors.Wrap(err, "spec.region: Invalid value: \""+spec.Region+"\"")
+	}
+
+	if err = validateNutanixNetworks(spec.Networks, providerSecrets); err != nil {
+		return err
+	}
+
+	if (spec.StorageClass != commons.StorageClass{}) {
+		if err = validateNutanixStorageClass(spec.StorageClass); err != nil {
+			return errors.Wrap(err, "spec.storageclass: Invalid value")
+		}
+	} else {
+		return errors.New("spec.storageclass: Required value: \"parameters.storageContainer\" must be set in nutanix clusters")
+	}
+
+	if err = validateGenericRepositories(spec); err != nil {
+		return err
+	}
+
+	if err = validateNutanixVolumes(spec); err != nil {
+		return err
+	}
+
+	if spec.ControlPlane.NodeImage == "" {
+		return errors.New("spec.control_plane: Required value: \"node_image\"")
+	}
+	if err = nutanixImageExists(providerSecrets, spec.ControlPlane.NodeImage); err != nil {
+		return errors.Wrap(err, "spec.control_plane: Invalid value: \"node_image\"")
+	}
+
+	for _, wn := range spec.WorkerNodes {
+		if wn.NodeImage == "" {
+			return errors.New("spec.worker_nodes." + wn.Name + ": Required value: \"node_image\"")
+		}
+		if wn.NodeImage != spec.ControlPlane.NodeImage {
+			if err = nutanixImageExists(providerSecrets, wn.NodeImage); err != nil {
+				return errors.Wrap(err, "spec.worker_nodes."+wn.Name+": Invalid value: \"node_image\"")
+			}
+		}
+		if wn.AZ != "" && wn.AZ != spec.Region {
+			if !isNutanixUUID(wn.AZ) {
+				return errors.New("spec.worker_nodes." + wn.Name + ".az: Invalid value: \"" + wn.AZ + "\": must be the UUID of a Prism Element cluster")
+			}
+			if err = nutanixEntityExists(providerSecrets, "clusters", wn.AZ); err != nil {
+				return errors.Wrap(err, "spec.worker_nodes."+wn.Name+".az: Invalid value: \""+wn.AZ+"\"")
+			}
+		}
+		if wn.Spot {
+			return errors.New("spec.worker_nodes." + wn.Name + ".spot: Invalid value: spot instances are not supported in nutanix clusters")
+		}
+	}
+
+	return nil
+}
+
+func validateNutanixNetworks(networks commons.Networks, providerSecrets map[string]string) error {
+	if networks.VPCID != "" || networks.VPCCIDRBlock != "" || networks.ResourceGroup != "" || len(networks.PodsSubnets) > 0 {
+		return errors.New("spec.networks: Invalid value: only \"subnets\" and \"pods_cidr\" are supported in nutanix clusters")
+	}
+	if len(networks.Subnets) == 0 {
+		return errors.New("spec.networks.subnets: Required value: at least one subnet UUID must be set in nutanix clusters")
+	}
+	for _, subnet := range networks.Subnets {
+		if !isNutanixUUID(subnet.SubnetId) {
+			return errors.New("spec.networks.subnets: Invalid value: \"" + subnet.SubnetId + "\": must be a subnet UUID")
+		}
+		if err := nutanixEntityExists(providerSecrets, "subnets", subnet.SubnetId); err != nil {
+			return errors.Wrap(err, "spec.networks.subnets: Invalid value: \""+subnet.SubnetId+"\"")
+		}
+	}
+	return nil
+}
+
+func validateNutanixStorageClass(sc commons.StorageClass) error {
+	if sc.EncryptionKey != "" || sc.EFS != (commons.EFS{}) {
+		return errors.New("\"encryptionKey\" and \"efs\" are not supported in nutanix clusters")
+	}
+	supported := commons.SCParameters{
+		FsType:           sc.Parameters.FsType,
+		StorageContainer: sc.Parameters.StorageContainer,
+		StorageType:      sc.Parameters.StorageType,
+		FlashMode:        sc.Parameters.FlashMode,
+	}
+	if sc.Parameters != supported {
+		return errors.New("only \"fsType\", \"storageContainer\", \"storageType\" and \"flashMode\" parameters are supported in nutanix clusters")
+	}
+	if sc.Parameters.StorageContainer == "" {
+		return errors.New("\"parameters.storageContainer\" is required in nutanix clusters")
+	}
+	if sc.Class == "premium" && sc.Parameters.FlashMode == "DISABLED" {
+		return errors.New("\"parameters.flashMode\" cannot be disabled in premium storage classes")
+	}
+	return nil
+}
+
+func validateNutanixVolumes(spec commons.KeosSpec) error {
+	// Only the size of the system disk can be customized
+	if !reflect.DeepEqual(spec.ControlPlane.RootVolume, commons.RootVolume{Size: spec.ControlPlane.RootVolume.Size}) ||
+		!reflect.DeepEqual(spec.ControlPlane.CRIVolume, commons.CustomVolume{}) ||
+		!reflect.DeepEqual(spec.ControlPlane.ETCDVolume, commons.CustomVolume{}) ||
+		len(spec.ControlPlane.ExtraVolumes) > 0 {
+		return errors.New("spec.control_plane: Invalid value: only \"root_volume.size\" is supported in nutanix clusters")
+	}
+	for _, wn := range spec.WorkerNodes {
+		if !reflect.DeepEqual(wn.RootVolume, commons.RootVolume{Size: wn.RootVolume.Size}) ||
+			!reflect.DeepEqual(wn.CRIVolume, commons.CustomVolume{}) ||
+			len(wn.ExtraVolumes) > 0 {
+			return errors.New("spec.worker_nodes." + wn.Name + ": Invalid value: only \"root_volume.size\" is supported in nutanix clusters")
+		}
+	}
+	return nil
+}
+
+func nutanixEntityExists(providerSecrets map[string]string, kind string, uuid string) error {
+	return prismCentralRequest(providerSecrets, http.MethodGet, "/"+kind+"/"+uuid, nil, nil)
+}
+
+func nutanixImageExists(providerSecrets map[string]string, image string) error {
+	var response struct {
+		Entities []interface{} `json:"entities"`
+	}
+	filter := map[string]interface{}{
+		"kind":   "image",
+		"filter": "name==" + image,
+	}
+	if err := prismCentralRequest(providerSecrets, http.MethodPost, "/images/list", filter, &response); err != nil {
+		return err
+	}
+	if len(response.Entities) == 0 {
+		return errors.New("image " + image + " does not exist in Prism Central")
+	}
+	return nil
+}
+
+func prismCentralRequest(providerSecrets map[string]string, method string, path string, in interface{}, out interface{}) error {
+	host, port, err := net.SplitHostPort(providerSecrets["Endpoint"])
+	if err != nil {
+		host, port = providerSecrets["Endpoint"], nutanixDefaultPort
+	}
+
+	var body bytes.Buffer
+	if in != nil {
+		if err = json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, "https://"+net.JoinHostPort(host, port)+"/api/nutanix/v3"+path, &body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(providerSecrets["Username"], providerSecrets["Password"])
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to Prism Central")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("Prism Central request " + path + " failed: " + resp.Status)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
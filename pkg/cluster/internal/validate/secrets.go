@@ -43,22 +43,25 @@ func validateCredentials(params ValidateParams) (commons.ClusterCredentials, err
 		secrets = secretsFile.Secrets
 	}
 
-	creds.ProviderCredentials, err = validateProviderCredentials(secrets, params)
+	// The references to secret managers are resolved before validating the credentials
+	creds.SecretReferences = map[string]string{}
+
+	creds.ProviderCredentials, err = validateProviderCredentials(secrets, params, creds.SecretReferences)
 	if err != nil {
 		return commons.ClusterCredentials{}, err
 	}
 
-	creds.KeosRegistryCredentials, creds.DockerRegistriesCredentials, err = validateRegistryCredentials(secrets, params.KeosCluster.Spec)
+	creds.KeosRegistryCredentials, creds.DockerRegistriesCredentials, err = validateRegistryCredentials(secrets, params.KeosCluster.Spec, creds.SecretReferences)
 	if err != nil {
 		return commons.ClusterCredentials{}, err
 	}
 
-	creds.HelmRepositoryCredentials, err = validateHelmCredentials(secrets, params.KeosCluster.Spec)
+	creds.HelmRepositoryCredentials, err = validateHelmCredentials(secrets, params.KeosCluster.Spec, creds.SecretReferences)
 	if err != nil {
 		return commons.ClusterCredentials{}, err
 	}
 
-	creds.GithubToken, err = validateGithubToken(secrets, params.KeosCluster.Spec)
+	creds.GithubToken, err = validateGithubToken(secrets, params.KeosCluster.Spec, creds.SecretReferences)
 	if err != nil {
 		return commons.ClusterCredentials{}, err
 	}
@@ -66,7 +69,7 @@ func validateCredentials(params ValidateParams) (commons.ClusterCredentials, err
 	return creds, nil
 }
 
-func validateProviderCredentials(secrets interface{}, params ValidateParams) (map[string]string, error) {
+func validateProviderCredentials(secrets interface{}, params ValidateParams, references map[string]string) (map[string]string, error) {
 	infraProvider := params.KeosCluster.Spec.InfraProvider
 	credentialsProvider, err := reflections.GetField(secrets, strings.ToUpper(infraProvider))
 	if err != nil || reflect.DeepEqual(credentialsProvider, reflect.Zero(reflect.TypeOf(credentialsProvider)).Interface()) {
@@ -78,6 +81,10 @@ func validateProviderCredentials(secrets interface{}, params ValidateParams) (ma
 		credentialsProvider, _ = reflections.GetField(credentialsProvider, "Credentials")
 
 	}
+	credentialsProvider, err = commons.ResolveSecretReferences(credentialsProvider, "provider", references)
+	if err != nil {
+		return nil, err
+	}
 	err = validateStruct(credentialsProvider)
 	if err != nil {
 		return nil, err
@@ -87,7 +94,7 @@ func validateProviderCredentials(secrets interface{}, params ValidateParams) (ma
 	return resultCreds, nil
 }
 
-func validateRegistryCredentials(secrets commons.Secrets, spec commons.KeosSpec) (map[string]string, []map[string]interface{}, error) {
+func validateRegistryCredentials(secrets commons.Secrets, spec commons.KeosSpec, references map[string]string) (map[string]string, []map[string]interface{}, error) {
 	var dockerRegistries []commons.DockerRegistryCredentials
 	var resultKeosRegistry map[string]string
 	var resultDockerRegistries = []map[string]interface{}{}
@@ -118,7 +125,19 @@ func validateRegistryCredentials(secrets commons.Secrets, spec commons.KeosSpec)
 				// Check if there are valid credentials for the registry
 				if dockerRegistryCredential.URL == dockerRegistry.URL {
 					existCredentials = true
-					err := validateStruct(dockerRegistryCredential)
+					resolved, err := commons.ResolveSecretReferences(dockerRegistryCredential, "docker_registries."+dockerRegistry.URL, references)
+					if err != nil {
+						return nil, nil, err
+					}
+					dockerRegistryCredential = resolved.(commons.DockerRegistryCredentials)
+					if dockerRegistry.KeosRegistry {
+						for k, v := range references {
+							if strings.HasPrefix(k, "docker_registries."+dockerRegistry.URL+".") {
+								references["keos_registry."+strings.TrimPrefix(k, "docker_registries."+dockerRegistry.URL+".")] = v
+							}
+						}
+					}
+					err = validateStruct(dockerRegistryCredential)
 					if err != nil {
 						return nil, nil, errors.Wrap(err, "there aren't valid credentials for the registry: "+dockerRegistry.URL)
 					}
@@ -148,7 +167,7 @@ func validateRegistryCredentials(secrets commons.Secrets, spec commons.KeosSpec)
 	return resultKeosRegistry, resultDockerRegistries, nil
 }
 
-func validateHelmCredentials(secrets commons.Secrets, spec commons.KeosSpec) (map[string]string, error) {
+func validateHelmCredentials(secrets commons.Secrets, spec commons.KeosSpec, references map[string]string) (map[string]string, error) {
 	var helmRepository commons.HelmRepositoryCredentials
 	var resultHelmRepository map[string]string
 
@@ -165,7 +184,12 @@ func validateHelmCredentials(secrets commons.Secrets, spec commons.KeosSpec) (ma
 		existCredentials := false
 		if helmRepository.URL == spec.HelmRepository.URL {
 			existCredentials = true
-			err := validateStruct(helmRepository)
+			resolved, err := commons.ResolveSecretReferences(helmRepository, "helm_repository", references)
+			if err != nil {
+				return nil, err
+			}
+			helmRepository = resolved.(commons.HelmRepositoryCredentials)
+			err = validateStruct(helmRepository)
 			if err != nil {
 				return nil, errors.Wrap(err, "there aren't valid credentials for the repository: "+helmRepository.URL)
 			}
@@ -179,7 +203,7 @@ func validateHelmCredentials(secrets commons.Secrets, spec commons.KeosSpec) (ma
 	return resultHelmRepository, nil
 }
 
-func validateGithubToken(secrets commons.Secrets, spec commons.KeosSpec) (string, error) {
+func validateGithubToken(secrets commons.Secrets, spec commons.KeosSpec, references map[string]string) (string, error) {
 	var githubToken string
 	var isGithubToken = regexp.MustCompile(`^(github_pat_|ghp_)\w+$`).MatchString
 
@@ -191,6 +215,15 @@ func validateGithubToken(secrets commons.Secrets, spec commons.KeosSpec) (string
 		return "", nil
 	}
 
+	if commons.IsSecretReference(githubToken) {
+		references["github_token"] = githubToken
+		resolved, err := commons.ResolveSecretReference(githubToken)
+		if err != nil {
+			return "", err
+		}
+		githubToken = resolved
+	}
+
 	if isGithubToken(githubToken) {
 		return githubToken, nil
 	} else {
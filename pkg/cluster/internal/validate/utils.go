@@ -18,8 +18,10 @@ package validate
 
 import (
 	"fmt"
+	"net"
 	"reflect"
 
+	"sigs.k8s.io/kind/pkg/commons"
 	"sigs.k8s.io/kind/pkg/errors"
 )
 
@@ -72,3 +74,47 @@ func getFieldNames(s interface{}) []string {
 	}
 	return fieldNames
 }
+
+// parseIPv4CIDR parses the CIDR block, which must be an IPv4 one. IPv4-mapped IPv6 blocks
+// (e.g. ::ffff:10.0.0.0/104) are parsed as IPv4 addresses by net.ParseCIDR, but not their masks
+func parseIPv4CIDR(s string) (*net.IPNet, error) {
+	_, ipNet, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, err
+	}
+	if ipNet.IP.To4() == nil || len(ipNet.Mask) != net.IPv4len {
+		return nil, errors.New(s + " is not an IPv4 CIDR block")
+	}
+	return ipNet, nil
+}
+
+// validateWorkersAZs checks the worker nodes placed in a single AZ are placed in one of the given AZs
+func validateWorkersAZs(workerNodes commons.WorkerNodes, azs []string) error {
+	if len(azs) == 0 {
+		return nil
+	}
+	for _, wn := range workerNodes {
+		if wn.AZ != "" && !commons.Contains(azs, wn.AZ) {
+			return errors.New("spec.worker_nodes." + wn.Name + ": \"az\": " + wn.AZ + " is not one of " + fmt.Sprint(azs))
+		}
+	}
+	return nil
+}
+
+func hasSpotWorkers(workerNodes commons.WorkerNodes) bool {
+	for _, wn := range workerNodes {
+		if wn.Spot {
+			return true
+		}
+	}
+	return false
+}
+
+func hasWorkerNodeGroup(workerNodes commons.WorkerNodes, name string) bool {
+	for _, wn := range workerNodes {
+		if wn.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"net"
+	"testing"
+	"testing/quick"
+
+	"sigs.k8s.io/kind/pkg/commons"
+)
+
+func FuzzParseIPv4CIDR(f *testing.F) {
+	for _, seed := range []string{"10.0.0.0/16", "10.0.0.1/16", "0.0.0.0/0", "::/0", "::ffff:10.0.0.0/104", "10.0.0.0/33", "10.0.0.0"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		ipNet, err := parseIPv4CIDR(s)
+		if err != nil {
+			return
+		}
+		if ipNet.IP.To4() == nil || len(ipNet.Mask) != net.IPv4len {
+			t.Fatalf("%s is parsed as the non IPv4 block %s", s, ipNet)
+		}
+		// The parsed block is the same once printed
+		reparsed, err := parseIPv4CIDR(ipNet.String())
+		if err != nil || reparsed.String() != ipNet.String() {
+			t.Fatalf("%s is parsed as %s, which is parsed as %v (%v)", s, ipNet, reparsed, err)
+		}
+	})
+}
+
+// TestValidateWorkersAZsProperties checks the worker nodes are rejected if and only if any of their
+// AZs is not one of the given ones, which are not checked when unknown
+func TestValidateWorkersAZsProperties(t *testing.T) {
+	zones := []string{"", "zone-a", "zone-b", "zone-c", "zone-d"}
+	property := func(available uint8, placements []uint8) bool {
+		var azs []string
+		for i, zone := range zones[1:] {
+			if available&(1<<i) != 0 {
+				azs = append(azs, zone)
+			}
+		}
+		workerNodes := make(commons.WorkerNodes, len(placements))
+		valid := true
+		for i, placement := range placements {
+			workerNodes[i].Name = "workers"
+			workerNodes[i].AZ = zones[int(placement)%len(zones)]
+			if len(azs) > 0 && workerNodes[i].AZ != "" && !commons.Contains(azs, workerNodes[i].AZ) {
+				valid = false
+			}
+		}
+		return (validateWorkersAZs(workerNodes, azs) == nil) == valid
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -42,6 +42,15 @@ func Cluster(params *ValidateParams) (commons.ClusterCredentials, error) {
 	if err := validateCommon(params.KeosCluster.Spec, clusterConfigSpec); err != nil {
 		return commons.ClusterCredentials{}, err
 	}
+	if err := validateClusterName(params.KeosCluster); err != nil {
+		return commons.ClusterCredentials{}, err
+	}
+	if err := validateDR(params.KeosCluster, clusterConfigSpec); err != nil {
+		return commons.ClusterCredentials{}, err
+	}
+	if err := validateNamingPolicy(params.KeosCluster, clusterConfigSpec.NamingPolicy); err != nil {
+		return commons.ClusterCredentials{}, err
+	}
 
 	switch params.KeosCluster.Spec.InfraProvider {
 	case "aws":
@@ -50,6 +59,18 @@ func Cluster(params *ValidateParams) (commons.ClusterCredentials, error) {
 		err = validateGCP(params.KeosCluster.Spec, creds.ProviderCredentials)
 	case "azure":
 		err = validateAzure(params.KeosCluster.Spec, creds.ProviderCredentials, params.KeosCluster.Metadata.Name)
+	case "equinix":
+		err = validateEquinix(params.KeosCluster.Spec, creds.ProviderCredentials)
+	case "hetzner":
+		err = validateHetzner(params.KeosCluster.Spec, creds.ProviderCredentials)
+	case "nutanix":
+		err = validateNutanix(params.KeosCluster.Spec, creds.ProviderCredentials)
+	case "digitalocean":
+		err = validateDigitalOcean(params.KeosCluster.Spec, creds.ProviderCredentials)
+	case "ibmcloud":
+		err = validateIBMCloud(params.KeosCluster.Spec, creds.ProviderCredentials)
+	case "alibabacloud":
+		err = validateAlibabaCloud(params.KeosCluster.Spec, creds.ProviderCredentials)
 	}
 	if err != nil {
 		return commons.ClusterCredentials{}, err
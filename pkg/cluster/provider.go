@@ -32,6 +32,7 @@ import (
 	"sigs.k8s.io/kind/pkg/log"
 
 	internalcreate "sigs.k8s.io/kind/pkg/cluster/internal/create"
+	"sigs.k8s.io/kind/pkg/cluster/internal/create/actions/createworker"
 	internaldelete "sigs.k8s.io/kind/pkg/cluster/internal/delete"
 	"sigs.k8s.io/kind/pkg/cluster/internal/kubeconfig"
 	internalproviders "sigs.k8s.io/kind/pkg/cluster/internal/providers"
@@ -265,3 +266,9 @@ func (p *Provider) Validate(keosCluster commons.KeosCluster, clusterConfig *comm
 	}
 	return internalvalidate.Cluster(params)
 }
+
+// DiffCAPXTemplates reports the drift between the templates and the provider version the
+// provisioner installs, and the given version of the infrastructure provider
+func DiffCAPXTemplates(infraProvider string, capxVersion string) ([]string, error) {
+	return createworker.DiffCAPXTemplates(infraProvider, capxVersion)
+}
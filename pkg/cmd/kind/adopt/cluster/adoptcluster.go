@@ -0,0 +1,175 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cluster implements the `adopt cluster` command
+package cluster
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/kind/pkg/cluster"
+	"sigs.k8s.io/kind/pkg/commons"
+
+	"sigs.k8s.io/kind/pkg/cmd"
+	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/log"
+
+	"sigs.k8s.io/kind/pkg/internal/cli"
+	"sigs.k8s.io/kind/pkg/internal/runtime"
+)
+
+type flagpole struct {
+	Name                 string
+	ClusterKubeconfig    string
+	VaultPassword        string
+	DescriptorPath       string
+	ForceDelete          bool
+	UseLocalStratioImage bool
+}
+
+const clusterDefaultPath = "./cluster.yaml"
+const secretsDefaultPath = "./secrets.yml"
+
+// NewCommand returns a new cobra.Command for cluster adoption
+func NewCommand(logger log.Logger, streams cmd.IOStreams) *cobra.Command {
+	flags := &flagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "cluster",
+		Short: "Adopts an existing managed Kubernetes cluster",
+		Long:  "Adopts an existing EKS, AKS or GKE cluster, bringing it under management with a temporary local cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cli.OverrideDefaultName(cmd.Flags())
+			return runE(logger, flags)
+		},
+	}
+	cmd.Flags().StringVarP(
+		&flags.Name,
+		"name",
+		"n",
+		"",
+		"cluster name, overrides KIND_CLUSTER_NAME, config (default kind)",
+	)
+	cmd.Flags().StringVar(
+		&flags.ClusterKubeconfig,
+		"cluster-kubeconfig",
+		"",
+		"path to the kubeconfig of the existing cluster",
+	)
+	cmd.Flags().StringVarP(
+		&flags.VaultPassword,
+		"vault-password",
+		"p",
+		"",
+		"sets vault password to encrypt secrets",
+	)
+	cmd.Flags().StringVarP(
+		&flags.DescriptorPath,
+		"descriptor",
+		"d",
+		"",
+		"allows you to indicate the name of the descriptor located in current or other directory. Default: cluster.yaml",
+	)
+	cmd.Flags().BoolVar(
+		&flags.ForceDelete,
+		"delete-previous",
+		false,
+		"by setting this flag the local cluster container will be deleted",
+	)
+	cmd.Flags().BoolVar(
+		&flags.UseLocalStratioImage,
+		"use-local-stratio-image",
+		false,
+		"by setting this flag the the stratio image will not be build and the local one will be used",
+	)
+
+	return cmd
+}
+
+func runE(logger log.Logger, flags *flagpole) error {
+	var err error
+
+	if flags.ClusterKubeconfig == "" {
+		return errors.New("Flag --cluster-kubeconfig is required")
+	}
+	if _, err = os.Stat(flags.ClusterKubeconfig); err != nil {
+		return errors.Wrap(err, "failed to read the cluster kubeconfig")
+	}
+
+	if flags.DescriptorPath == "" {
+		flags.DescriptorPath = clusterDefaultPath
+	}
+
+	if flags.VaultPassword == "" {
+		flags.VaultPassword, err = cmd.SetPassword(secretsDefaultPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	keosCluster, clusterConfig, err := commons.GetClusterDescriptor(flags.DescriptorPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse cluster descriptor")
+	}
+
+	if !keosCluster.Spec.ControlPlane.Managed {
+		return errors.New("spec.control_plane.managed: Invalid value: only managed clusters (EKS, AKS, GKE) can be adopted")
+	}
+	if keosCluster.Spec.DR != nil {
+		return errors.New("spec.dr: Invalid value: dr clusters cannot be adopted")
+	}
+	if clusterConfig != nil && clusterConfig.Spec.Private {
+		return errors.New("spec.private_registry: Invalid value: private clusters cannot be adopted")
+	}
+
+	provider := cluster.NewProvider(
+		cluster.ProviderWithLogger(logger),
+		runtime.GetDefault(logger),
+	)
+
+	clusterCredentials, err := provider.Validate(
+		*keosCluster,
+		clusterConfig,
+		secretsDefaultPath,
+		flags.VaultPassword,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to validate cluster")
+	}
+
+	// adopt the cluster
+	if err = provider.Create(
+		flags.Name,
+		flags.VaultPassword,
+		flags.DescriptorPath,
+		false,
+		false,
+		flags.UseLocalStratioImage,
+		"",
+		clusterConfig,
+		*keosCluster,
+		clusterCredentials,
+		cluster.CreateWithAdoptKubeconfig(flags.ClusterKubeconfig),
+		cluster.CreateWithForceDelete(flags.ForceDelete),
+		cluster.CreateWithDisplaySalutation(true),
+	); err != nil {
+		return errors.Wrap(err, "failed to adopt cluster")
+	}
+
+	return nil
+}
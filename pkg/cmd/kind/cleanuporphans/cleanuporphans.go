@@ -0,0 +1,141 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cleanuporphans implements the `cleanup-orphans` command
+package cleanuporphans
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/kind/pkg/cluster"
+	"sigs.k8s.io/kind/pkg/cmd"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/internal/runtime"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+type flagpole struct {
+	DescriptorPath string
+	VaultPassword  string
+	Yes            bool
+}
+
+const clusterDefaultPath = "./cluster.yaml"
+const secretsDefaultPath = "./secrets.yml"
+
+// NewCommand returns a new cobra.Command for cleaning up the orphan resources of a cluster
+func NewCommand(logger log.Logger, streams cmd.IOStreams) *cobra.Command {
+	flags := &flagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "cleanup-orphans",
+		Short: "Deletes the cloud resources left behind by a cluster",
+		Long: "Finds the load balancers, volumes, security groups, NAT gateways and addresses tagged for the cluster " +
+			"of the descriptor by Cluster API or the cloud controllers, left behind by failed creations or deletions, " +
+			"and deletes them after confirmation. The cluster must have been deleted",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(logger, streams, flags)
+		},
+	}
+	cmd.Flags().StringVarP(
+		&flags.DescriptorPath,
+		"descriptor",
+		"d",
+		clusterDefaultPath,
+		"path to the cluster descriptor",
+	)
+	cmd.Flags().StringVarP(
+		&flags.VaultPassword,
+		"vault-password",
+		"p",
+		"",
+		"vault password of the secrets file",
+	)
+	cmd.Flags().BoolVarP(
+		&flags.Yes,
+		"yes",
+		"y",
+		false,
+		"delete the resources without confirmation",
+	)
+	return cmd
+}
+
+func runE(logger log.Logger, streams cmd.IOStreams, flags *flagpole) error {
+	var err error
+
+	if flags.VaultPassword == "" {
+		flags.VaultPassword, err = cmd.RequestPassword("Vault Password: ")
+		if err != nil {
+			return err
+		}
+	}
+
+	keosCluster, clusterConfig, err := commons.GetClusterDescriptor(flags.DescriptorPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse cluster descriptor")
+	}
+
+	provider := cluster.NewProvider(
+		cluster.ProviderWithLogger(logger),
+		runtime.GetDefault(logger),
+	)
+	clusterCredentials, err := provider.Validate(*keosCluster, clusterConfig, secretsDefaultPath, flags.VaultPassword)
+	if err != nil {
+		return errors.Wrap(err, "failed to validate cluster")
+	}
+
+	orphans, err := commons.FindOrphanResources(*keosCluster, clusterCredentials.ProviderCredentials)
+	if err != nil {
+		return errors.Wrap(err, "failed to find the orphan resources")
+	}
+	if len(orphans) == 0 {
+		logger.V(0).Info("There are no orphan resources of the cluster " + keosCluster.Metadata.Name)
+		return nil
+	}
+
+	for _, orphan := range orphans {
+		fmt.Fprintf(streams.Out, "%s\t%s\n", orphan.Type, orphan.ID)
+	}
+	if !flags.Yes {
+		fmt.Fprint(streams.Out, "Delete "+strconv.Itoa(len(orphans))+" resources? [y/N]: ")
+		answer, _ := bufio.NewReader(streams.In).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			return nil
+		}
+	}
+
+	// The resources may depend on each other, so the failures are reported but do not stop the cleanup
+	failed := 0
+	for _, orphan := range orphans {
+		if err := orphan.Delete(); err != nil {
+			logger.Warnf("failed to delete the %s %s: %v", orphan.Type, orphan.ID, err)
+			failed++
+			continue
+		}
+		logger.V(0).Info("Deleted the " + orphan.Type + " " + orphan.ID)
+	}
+	if failed > 0 {
+		return errors.New(strconv.Itoa(failed) + " resources could not be deleted, run the command again once their dependencies are deleted")
+	}
+	return nil
+}
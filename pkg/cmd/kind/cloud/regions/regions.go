@@ -0,0 +1,112 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package regions implements the `cloud regions` command
+package regions
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/kind/pkg/cmd"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+type flagpole struct {
+	InfraProvider string
+	SecretsPath   string
+	VaultPassword string
+}
+
+const secretsDefaultPath = "./secrets.yml"
+
+// NewCommand returns a new cobra.Command for listing the regions of a provider
+func NewCommand(logger log.Logger, streams cmd.IOStreams) *cobra.Command {
+	flags := &flagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "regions",
+		Short: "Lists the regions of the provider and their availability zones",
+		Long: "Lists the regions enabled for the credentials of the secrets file and their availability zones, " +
+			"to choose a region with as many zones as the control plane and the worker nodes are spread across",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(streams, flags)
+		},
+	}
+	cmd.Flags().StringVar(
+		&flags.InfraProvider,
+		"provider",
+		"",
+		"infrastructure provider of the regions: aws, azure or gcp",
+	)
+	cmd.Flags().StringVar(
+		&flags.SecretsPath,
+		"secrets",
+		secretsDefaultPath,
+		"path to the secrets file",
+	)
+	cmd.Flags().StringVarP(
+		&flags.VaultPassword,
+		"vault-password",
+		"p",
+		"",
+		"vault password of the secrets file",
+	)
+	_ = cmd.MarkFlagRequired("provider")
+	return cmd
+}
+
+func runE(streams cmd.IOStreams, flags *flagpole) error {
+	var err error
+
+	if _, err = os.Stat(flags.SecretsPath); err != nil {
+		return errors.Wrap(err, "failed to read the secrets file")
+	}
+
+	if flags.VaultPassword == "" {
+		flags.VaultPassword, err = cmd.RequestPassword("Vault Password: ")
+		if err != nil {
+			return err
+		}
+	}
+
+	secretsFile, err := commons.GetSecretsFile(flags.SecretsPath, flags.VaultPassword)
+	if err != nil {
+		return err
+	}
+	credentials, err := commons.GetSecretsProviderCredentials(secretsFile.Secrets, flags.InfraProvider)
+	if err != nil {
+		return err
+	}
+
+	regions, err := commons.ListCloudRegions(flags.InfraProvider, credentials)
+	if err != nil {
+		return errors.Wrap(err, "failed to list the regions")
+	}
+
+	w := tabwriter.NewWriter(streams.Out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REGION\tAZS\tZONES")
+	for _, region := range regions {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", region.Name, len(region.Zones), strings.Join(region.Zones, ","))
+	}
+	return w.Flush()
+}
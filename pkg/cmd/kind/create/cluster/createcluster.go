@@ -18,15 +18,13 @@ limitations under the License.
 package cluster
 
 import (
+	"bufio"
 	"fmt"
 	"io"
 	"os"
-
-	"syscall"
+	"strings"
 	"time"
 
-	term "golang.org/x/term"
-
 	"github.com/spf13/cobra"
 
 	"sigs.k8s.io/kind/pkg/cluster"
@@ -52,8 +50,13 @@ type flagpole struct {
 	MoveManagement       bool
 	AvoidCreation        bool
 	ForceDelete          bool
+	ReuseBootstrap       bool
 	ValidateOnly         bool
 	UseLocalStratioImage bool
+	TemplatesDir         string
+	MetricsPushgateway   string
+	Async                bool
+	OperationID          string
 }
 
 const clusterDefaultPath = "./cluster.yaml"
@@ -127,7 +130,7 @@ func NewCommand(logger log.Logger, streams cmd.IOStreams) *cobra.Command {
 		&flags.MoveManagement,
 		"keep-mgmt",
 		false,
-		"by setting this flag the cluster management will be kept in the kind",
+		"by setting this flag the cluster management will be kept in the kind, which also runs the CronJob that destroys the cluster once its ttl expires",
 	)
 	cmd.Flags().BoolVar(
 		&flags.AvoidCreation,
@@ -141,6 +144,12 @@ func NewCommand(logger log.Logger, streams cmd.IOStreams) *cobra.Command {
 		false,
 		"by setting this flag the local cluster container will be deleted",
 	)
+	cmd.Flags().BoolVar(
+		&flags.ReuseBootstrap,
+		"reuse-previous",
+		false,
+		"by setting this flag the local cluster container will be reused if it exists, and kept for the following runs",
+	)
 	cmd.Flags().BoolVar(
 		&flags.ValidateOnly,
 		"validate-only",
@@ -153,13 +162,50 @@ func NewCommand(logger log.Logger, streams cmd.IOStreams) *cobra.Command {
 		false,
 		"by setting this flag the the stratio image will not be build and the local one will be used",
 	)
+	cmd.Flags().StringVar(
+		&flags.TemplatesDir,
+		"templates-dir",
+		"",
+		"directory with templates overriding the embedded ones, using the same <provider>/[<k8s minor>/]<template> layout",
+	)
+	cmd.Flags().StringVar(
+		&flags.MetricsPushgateway,
+		"metrics-pushgateway",
+		"",
+		"URL of a Prometheus Pushgateway where the duration and outcome of the provisioning phases are pushed",
+	)
+	cmd.Flags().BoolVar(
+		&flags.Async,
+		"async",
+		false,
+		"by setting this flag the provisioning runs in the background, and the ID to follow it with is printed",
+	)
+	cmd.Flags().StringVar(
+		&flags.OperationID,
+		"operation-id",
+		"",
+		"ID of the asynchronous operation run by the process",
+	)
+	_ = cmd.Flags().MarkHidden("operation-id")
 
 	return cmd
 }
 
 func runE(logger log.Logger, streams cmd.IOStreams, flags *flagpole) error {
 
-	err := validateFlags(flags)
+	localConfig, err := commons.GetLocalConfig()
+	if err != nil {
+		return err
+	}
+	localConfig.SetProxyEnv()
+	if flags.TemplatesDir == "" {
+		flags.TemplatesDir = localConfig.TemplatesDir
+	}
+	if flags.MetricsPushgateway == "" {
+		flags.MetricsPushgateway = localConfig.MetricsPushgateway
+	}
+
+	err = validateFlags(flags)
 	if err != nil {
 		return err
 	}
@@ -168,8 +214,16 @@ func runE(logger log.Logger, streams cmd.IOStreams, flags *flagpole) error {
 		flags.DescriptorPath = clusterDefaultPath
 	}
 
+	// The process of an asynchronous operation is given the vault password in its stdin
+	if flags.VaultPassword == "" && flags.OperationID != "" {
+		password, err := bufio.NewReader(streams.In).ReadString('\n')
+		if err != nil {
+			return errors.Wrap(err, "failed to read the vault password of the operation")
+		}
+		flags.VaultPassword = strings.TrimSuffix(password, "\n")
+	}
 	if flags.VaultPassword == "" {
-		flags.VaultPassword, err = setPassword(secretsDefaultPath)
+		flags.VaultPassword, err = cmd.SetPassword(secretsDefaultPath)
 		if err != nil {
 			return err
 		}
@@ -195,6 +249,50 @@ func runE(logger log.Logger, streams cmd.IOStreams, flags *flagpole) error {
 		return errors.Wrap(err, "failed to validate cluster")
 	}
 
+	// The expired cluster is destroyed from the local cluster, since a self-managed cluster cannot
+	// destroy itself
+	if keosCluster.Spec.TTL != "" && !flags.MoveManagement {
+		return errors.New("Flag --keep-mgmt is required to set the ttl of the cluster")
+	}
+
+	// Validate the DR cluster before creating any of them
+	var drCluster *commons.KeosCluster
+	var drClusterConfig *commons.ClusterConfig
+	var drClusterCredentials commons.ClusterCredentials
+	if keosCluster.Spec.DR != nil {
+		if flags.Retain || flags.MoveManagement || flags.AvoidCreation {
+			return errors.New("Flags --retain, --avoid-creation, and --keep-mgmt cannot be used with a dr cluster")
+		}
+		// The operation is followed in the management cluster, and each cluster has its own
+		if flags.Async {
+			return errors.New("Flag --async cannot be used with a dr cluster")
+		}
+		drCluster, drClusterConfig = commons.GetDRClusterDescriptor(*keosCluster, clusterConfig)
+		drClusterCredentials, err = provider.Validate(
+			*drCluster,
+			drClusterConfig,
+			secretsDefaultPath,
+			flags.VaultPassword,
+		)
+		if err != nil {
+			return errors.Wrap(err, "failed to validate dr cluster")
+		}
+	}
+
+	// The resources of a previous run are expected when the local cluster is reused
+	if !flags.AvoidCreation && !flags.ReuseBootstrap {
+		err = checkClusterCollisions(*keosCluster, clusterCredentials)
+		if err != nil {
+			return err
+		}
+		if drCluster != nil {
+			err = checkClusterCollisions(*drCluster, drClusterCredentials)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	dockerRegUrl := ""
 	if clusterConfig != nil && clusterConfig.Spec.Private {
 		configFile, err := getConfigFile(keosCluster, clusterCredentials)
@@ -214,12 +312,35 @@ func runE(logger log.Logger, streams cmd.IOStreams, flags *flagpole) error {
 		return nil
 	}
 
+	// The descriptor is validated before the provisioning is started in the background
+	if flags.Async {
+		return startOperation(logger, streams, flags, keosCluster.Metadata.Name)
+	}
+
 	// handle config flag, we might need to read from stdin
 	withConfig, err := configOption(flags.Config, streams.In)
 	if err != nil {
 		return err
 	}
 
+	createOptions := []cluster.CreateOption{
+		withConfig,
+		cluster.CreateWithNodeImage(flags.ImageName),
+		cluster.CreateWithRetain(flags.Retain),
+		cluster.CreateWithMove(flags.MoveManagement),
+		cluster.CreateWithAvoidCreation(flags.AvoidCreation),
+		cluster.CreateWithForceDelete(flags.ForceDelete),
+		cluster.CreateWithReuseBootstrap(flags.ReuseBootstrap),
+		cluster.CreateWithWaitForReady(flags.Wait),
+		cluster.CreateWithKubeconfigPath(flags.Kubeconfig),
+		cluster.CreateWithDisplayUsage(true),
+		cluster.CreateWithDisplaySalutation(true),
+		cluster.CreateWithTemplatesDir(flags.TemplatesDir),
+		cluster.CreateWithMetricsPushgateway(flags.MetricsPushgateway),
+		cluster.CreateWithBootstrapConfig(localConfig.Bootstrap),
+		cluster.CreateWithOperationID(flags.OperationID),
+	}
+
 	// create the cluster
 	if err = provider.Create(
 		flags.Name,
@@ -232,20 +353,30 @@ func runE(logger log.Logger, streams cmd.IOStreams, flags *flagpole) error {
 		clusterConfig,
 		*keosCluster,
 		clusterCredentials,
-		withConfig,
-		cluster.CreateWithNodeImage(flags.ImageName),
-		cluster.CreateWithRetain(flags.Retain),
-		cluster.CreateWithMove(flags.MoveManagement),
-		cluster.CreateWithAvoidCreation(flags.AvoidCreation),
-		cluster.CreateWithForceDelete(flags.ForceDelete),
-		cluster.CreateWithWaitForReady(flags.Wait),
-		cluster.CreateWithKubeconfigPath(flags.Kubeconfig),
-		cluster.CreateWithDisplayUsage(true),
-		cluster.CreateWithDisplaySalutation(true),
+		createOptions...,
 	); err != nil {
 		return errors.Wrap(err, "failed to create cluster")
 	}
 
+	// create the DR cluster, the local cluster of the first one has already been deleted
+	if drCluster != nil {
+		if err = provider.Create(
+			flags.Name,
+			flags.VaultPassword,
+			flags.DescriptorPath,
+			flags.MoveManagement,
+			flags.AvoidCreation,
+			flags.UseLocalStratioImage,
+			dockerRegUrl,
+			drClusterConfig,
+			*drCluster,
+			drClusterCredentials,
+			createOptions...,
+		); err != nil {
+			return errors.Wrap(err, "failed to create dr cluster")
+		}
+	}
+
 	return nil
 }
 
@@ -264,35 +395,6 @@ func configOption(rawConfigFlag string, stdin io.Reader) (cluster.CreateOption,
 	return cluster.CreateWithRawConfig(raw), nil
 }
 
-func setPassword(secretsDefaultPath string) (string, error) {
-	firstPassword, err := requestPassword("Vault Password: ")
-	if err != nil {
-		return "", err
-	}
-
-	if _, err := os.Stat(secretsDefaultPath); os.IsNotExist(err) {
-		secondPassword, err := requestPassword("Rewrite Vault Password:")
-		if err != nil {
-			return "", err
-		}
-		if firstPassword != secondPassword {
-			return "", errors.New("The passwords do not match.")
-		}
-	}
-
-	return firstPassword, nil
-}
-
-func requestPassword(request string) (string, error) {
-	fmt.Print(request)
-	bytePassword, err := term.ReadPassword(int(syscall.Stdin))
-	if err != nil {
-		return "", err
-	}
-	fmt.Print("\n")
-	return string(bytePassword), nil
-}
-
 func validateFlags(flags *flagpole) error {
 	count := 0
 	if flags.AvoidCreation {
@@ -307,5 +409,25 @@ func validateFlags(flags *flagpole) error {
 	if count > 1 {
 		return errors.New("Flags --retain, --avoid-creation, and --keep-mgmt are mutually exclusive")
 	}
+	if flags.ReuseBootstrap && (flags.ForceDelete || flags.MoveManagement) {
+		return errors.New("Flag --reuse-previous cannot be used with --delete-previous or --keep-mgmt")
+	}
+	if flags.TemplatesDir != "" {
+		if info, err := os.Stat(flags.TemplatesDir); err != nil || !info.IsDir() {
+			return errors.New("Flag --templates-dir must be an existing directory")
+		}
+	}
+	return nil
+}
+
+// checkClusterCollisions fails if the provider already has a cluster with the name of the descriptor
+func checkClusterCollisions(keosCluster commons.KeosCluster, clusterCredentials commons.ClusterCredentials) error {
+	collisions, err := commons.FindClusterCollisions(keosCluster, clusterCredentials.ProviderCredentials)
+	if err != nil {
+		return errors.Wrap(err, "failed to look for an existing "+keosCluster.Metadata.Name+" cluster")
+	}
+	if len(collisions) > 0 {
+		return errors.New("a cluster named " + keosCluster.Metadata.Name + " already exists in " + keosCluster.Spec.InfraProvider + ", with the resources: " + strings.Join(collisions, ", "))
+	}
 	return nil
 }
@@ -0,0 +1,92 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/kind/pkg/cmd"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+// startOperation runs the provisioning in a detached process, whose output is written to the log
+// of the operation, and prints the ID of the operation to follow it with. The process reports the
+// operation to the management cluster, where it is followed
+func startOperation(logger log.Logger, streams cmd.IOStreams, flags *flagpole, clusterName string) error {
+	operation, err := commons.GetOperation(clusterName + "-" + time.Now().UTC().Format("20060102150405"))
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(operation.LogPath), 0700); err != nil {
+		return errors.Wrap(err, "failed to create the operations directory")
+	}
+	logFile, err := os.OpenFile(operation.LogPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the log of the operation")
+	}
+	defer logFile.Close()
+
+	executable, err := os.Executable()
+	if err != nil {
+		return errors.Wrap(err, "failed to get the cloud-provisioner executable")
+	}
+	// The process runs the same command, without the async flag, recording the operation
+	var args []string
+	for _, arg := range os.Args[1:] {
+		if arg != "--async" && !strings.HasPrefix(arg, "--async=") {
+			args = append(args, arg)
+		}
+	}
+	args = append(args, "--operation-id", operation.ID)
+
+	// The vault password cannot be prompted for, so it is written to the stdin of the process, which
+	// unlike its arguments and environment is not readable by other processes
+	stdin, passwordWriter, err := os.Pipe()
+	if err != nil {
+		return errors.Wrap(err, "failed to pass the vault password to the operation")
+	}
+	defer passwordWriter.Close()
+
+	process := exec.Command(executable, args...)
+	process.Stdin = stdin
+	process.Stdout = logFile
+	process.Stderr = logFile
+	process.SysProcAttr = commons.DetachedProcAttr()
+	err = process.Start()
+	stdin.Close()
+	if err != nil {
+		return errors.Wrap(err, "failed to start the operation")
+	}
+	if _, err = io.WriteString(passwordWriter, flags.VaultPassword+"\n"); err != nil {
+		return errors.Wrap(err, "failed to pass the vault password to the operation")
+	}
+	if err = process.Process.Release(); err != nil {
+		return err
+	}
+
+	logger.V(0).Infof("Provisioning started, follow it with: cloud-provisioner follow %s", operation.ID)
+	fmt.Fprintln(streams.Out, operation.ID)
+	return nil
+}
@@ -6,8 +6,6 @@ import (
 	"embed"
 	"encoding/base64"
 	"encoding/json"
-	"fmt"
-	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
@@ -15,9 +13,7 @@ import (
 	"strings"
 	"text/template"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/aws/aws-sdk-go-v2/service/ecr"
-	"golang.org/x/oauth2/google"
 	"sigs.k8s.io/kind/pkg/commons"
 	"sigs.k8s.io/kind/pkg/errors"
 )
@@ -123,17 +119,12 @@ func getECRCredentials(clusterCredentials commons.ClusterCredentials, keosRegUrl
 	var registryPass string
 	var ctx = context.Background()
 
-	credentials := map[string]string{
-		"AccessKey": clusterCredentials.ProviderCredentials["AccessKey"],
-		"SecretKey": clusterCredentials.ProviderCredentials["SecretKey"],
-	}
 	region := strings.Split(keosRegUrl, ".")[3]
-	cfg, err := commons.AWSGetConfig(ctx, credentials, region)
+	client, err := commons.NewAWSClient(ctx, clusterCredentials.ProviderCredentials, region, "")
 	if err != nil {
 		return "", "", err
 	}
-	svc := ecr.NewFromConfig(cfg)
-	token, err := svc.GetAuthorizationToken(ctx, &ecr.GetAuthorizationTokenInput{})
+	token, err := client.ECR().GetAuthorizationToken(ctx, &ecr.GetAuthorizationTokenInput{})
 	if err != nil {
 		return "", "", err
 	}
@@ -147,47 +138,14 @@ func getECRCredentials(clusterCredentials commons.ClusterCredentials, keosRegUrl
 }
 
 func getACRCredentials(clusterCredentials commons.ClusterCredentials, keosRegUrl string) (string, string, error) {
-	var registryUser = "00000000-0000-0000-0000-000000000000"
-	var registryPass string
-	var ctx = context.Background()
-	var response map[string]interface{}
-
-	cfg, err := commons.AzureGetConfig(clusterCredentials.ProviderCredentials)
+	token, err := commons.GetACRToken(context.Background(), clusterCredentials.ProviderCredentials, keosRegUrl)
 	if err != nil {
 		return "", "", err
 	}
-	aadToken, err := cfg.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"https://management.azure.com/.default"}})
-	if err != nil {
-		return "", "", err
-	}
-	acrService := strings.Split(keosRegUrl, "/")[0]
-	formData := url.Values{
-		"grant_type":   {"access_token"},
-		"service":      {acrService},
-		"tenant":       {clusterCredentials.ProviderCredentials["TenantID"]},
-		"access_token": {aadToken.Token},
-	}
-	jsonResponse, err := http.PostForm(fmt.Sprintf("https://%s/oauth2/exchange", acrService), formData)
-	if err != nil {
-		return "", "", err
-	} else if jsonResponse.StatusCode == http.StatusUnauthorized {
-		return "", "", errors.New("Failed to obtain the ACR token with the provided credentials, please check the roles assigned to the correspondent Azure AD app")
-	}
-	json.NewDecoder(jsonResponse.Body).Decode(&response)
-	if response["access_token"] != nil {
-		registryPass = response["access_token"].(string)
-	} else if response["refresh_token"] != nil {
-		registryPass = response["refresh_token"].(string)
-	} else {
-		return "", "", errors.New("Failed to obtain the ACR token with the provided credentials, please check the roles assigned to the correspondent Azure AD app")
-	}
-	return registryUser, registryPass, nil
+	return token.User, token.Password, nil
 }
 
 func getGARCredentials(clusterCredentials commons.ClusterCredentials, keosRegUrl string) (string, string, error) {
-	var registryUser = "oauth2accesstoken"
-	var ctx = context.Background()
-	scope := "https://www.googleapis.com/auth/cloud-platform"
 	data := map[string]interface{}{
 		"type":                        "service_account",
 		"project_id":                  clusterCredentials.ProviderCredentials["ProjectID"],
@@ -202,13 +160,9 @@ func getGARCredentials(clusterCredentials commons.ClusterCredentials, keosRegUrl
 	}
 	jsonData, _ := json.Marshal(data)
 
-	creds, err := google.CredentialsFromJSON(ctx, jsonData, scope)
-	if err != nil {
-		return "", "", err
-	}
-	token, err := creds.TokenSource.Token()
+	token, err := commons.GetGARToken(context.Background(), jsonData)
 	if err != nil {
 		return "", "", err
 	}
-	return registryUser, token.AccessToken, nil
+	return token.User, token.Password, nil
 }
@@ -0,0 +1,80 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package difftemplates implements the `diff-templates` command
+package difftemplates
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/kind/pkg/cluster"
+	"sigs.k8s.io/kind/pkg/cmd"
+	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+type flagpole struct {
+	InfraProvider string
+	CAPXVersion   string
+}
+
+// NewCommand returns a new cobra.Command for diffing the templates against an infrastructure provider version
+func NewCommand(logger log.Logger, streams cmd.IOStreams) *cobra.Command {
+	flags := &flagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "diff-templates",
+		Short: "Reports the drift of the templates with a version of the infrastructure provider",
+		Long: "Downloads the infrastructure components of the given version of the provider, and reports the " +
+			"embedded templates whose selectors no longer match them, and the CRDs and controllers which differ " +
+			"from the version the provisioner installs, to be reviewed before upgrading it",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(streams, flags)
+		},
+	}
+	cmd.Flags().StringVar(
+		&flags.InfraProvider,
+		"provider",
+		"",
+		"infrastructure provider of the templates: aws, azure or gcp",
+	)
+	cmd.Flags().StringVar(
+		&flags.CAPXVersion,
+		"capx-version",
+		"",
+		"version of the infrastructure provider to diff the templates against, e.g. v2.6.1",
+	)
+	_ = cmd.MarkFlagRequired("provider")
+	_ = cmd.MarkFlagRequired("capx-version")
+	return cmd
+}
+
+func runE(streams cmd.IOStreams, flags *flagpole) error {
+	drifts, err := cluster.DiffCAPXTemplates(flags.InfraProvider, flags.CAPXVersion)
+	if err != nil {
+		return errors.Wrap(err, "failed to diff the templates")
+	}
+	if len(drifts) == 0 {
+		fmt.Fprintln(streams.Out, "No drift found")
+		return nil
+	}
+	for _, drift := range drifts {
+		fmt.Fprintln(streams.Out, drift)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package follow implements the `follow` command
+package follow
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/kind/pkg/cmd"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/exec"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+type flagpole struct {
+	Kubeconfig string
+	Timeout    time.Duration
+}
+
+const kubeconfigDefaultPath = ".kube/config"
+
+// The operation is polled for new output at this interval
+const followInterval = 5 * time.Second
+
+// NewCommand returns a new cobra.Command for following an asynchronous operation
+func NewCommand(logger log.Logger, streams cmd.IOStreams) *cobra.Command {
+	flags := &flagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.ExactArgs(1),
+		Use:   "follow <operation-id>",
+		Short: "Streams the progress of an asynchronous operation",
+		Long: "Streams the output of an operation started with --async until it finishes, " +
+			"and fails if the operation failed. The operation is read from the management cluster: " +
+			"the workload cluster once it exists, or the local cluster with --keep-mgmt. kubectl must be installed",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(streams, flags, args[0])
+		},
+	}
+	cmd.Flags().StringVar(
+		&flags.Kubeconfig,
+		"kubeconfig",
+		kubeconfigDefaultPath,
+		"path to the kubeconfig of the management cluster",
+	)
+	cmd.Flags().DurationVar(
+		&flags.Timeout,
+		"timeout",
+		30*time.Minute,
+		"time to wait for the operation to be reported to the management cluster",
+	)
+	return cmd
+}
+
+func runE(streams cmd.IOStreams, flags *flagpole, id string) error {
+	operation := commons.Operation{ID: id}
+	started := time.Now()
+	var streamed int64
+	for {
+		status, err := getOperationStatus(flags.Kubeconfig, operation)
+		if err != nil {
+			return err
+		}
+		if status == nil {
+			// The workload cluster is reported to once it exists
+			if time.Since(started) > flags.Timeout {
+				return errors.Errorf("operation %q has not been reported to the management cluster, "+
+					"its log is in the %s directory of the host which started it", id, commons.OperationsPath)
+			}
+			time.Sleep(followInterval)
+			continue
+		}
+
+		// Only the tail of the log is reported, so the output streamed so far may be behind it
+		offset, err := strconv.ParseInt(status.LogOffset, 10, 64)
+		if err != nil {
+			return errors.Wrap(err, "failed to parse the log of the operation")
+		}
+		if streamed < offset {
+			if streamed > 0 {
+				fmt.Fprintf(streams.Out, "[%d bytes of output are not reported to the management cluster]\n", offset-streamed)
+			}
+			streamed = offset
+		}
+		if end := offset + int64(len(status.Log)); streamed < end {
+			if _, err = io.WriteString(streams.Out, status.Log[streamed-offset:]); err != nil {
+				return err
+			}
+			streamed = end
+		}
+
+		switch status.Status {
+		case commons.OperationSucceeded:
+			return nil
+		case commons.OperationFailed:
+			return errors.Errorf("operation %q failed: %s", id, status.Error)
+		}
+		updated, err := time.Parse(time.RFC3339, status.Updated)
+		if err != nil {
+			return errors.Wrap(err, "failed to parse the status of the operation")
+		}
+		if time.Since(updated) > commons.OperationReportTimeout {
+			return errors.Errorf("operation %q was interrupted before it finished, it has not been reported since %s", id, status.Updated)
+		}
+		time.Sleep(followInterval)
+	}
+}
+
+// getOperationStatus returns the status of the operation reported to the management cluster, or
+// nil if it has not been reported yet
+func getOperationStatus(kubeconfig string, operation commons.Operation) (*commons.OperationStatus, error) {
+	output, err := exec.Output(exec.Command("kubectl", "--kubeconfig", kubeconfig, "-n", commons.OperationHistoryNamespace,
+		"get", "configmap", operation.ConfigMapName(), "-o", "json", "--ignore-not-found"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the operation from the management cluster")
+	}
+	if strings.TrimSpace(string(output)) == "" {
+		return nil, nil
+	}
+	var configMap struct {
+		Data commons.OperationStatus `json:"data"`
+	}
+	if err = json.Unmarshal(output, &configMap); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the operation of the management cluster")
+	}
+	return &configMap.Data, nil
+}
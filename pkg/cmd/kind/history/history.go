@@ -0,0 +1,115 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package history implements the `history` command
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/kind/pkg/cmd"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/exec"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+type flagpole struct {
+	Kubeconfig string
+	Name       string
+}
+
+const kubeconfigDefaultPath = ".kube/config"
+
+// NewCommand returns a new cobra.Command for listing the operations over the workload clusters
+func NewCommand(logger log.Logger, streams cmd.IOStreams) *cobra.Command {
+	flags := &flagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "history",
+		Short: "Lists the operations recorded in a workload cluster",
+		Long: "Lists the bootstrap, create and adopt operations recorded in the workload cluster, with who ran them, " +
+			"when, the SHA-256 of the descriptor and their result. The operations which fail before the workload " +
+			"cluster is reachable, and the deletions of the expired clusters, are recorded in the local cluster, " +
+			"listed with its kubeconfig. kubectl must be installed",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(streams, flags)
+		},
+	}
+	cmd.Flags().StringVar(
+		&flags.Kubeconfig,
+		"kubeconfig",
+		kubeconfigDefaultPath,
+		"path to the kubeconfig of the workload cluster",
+	)
+	cmd.Flags().StringVarP(
+		&flags.Name,
+		"name",
+		"n",
+		"",
+		"only list the operations over this cluster",
+	)
+	return cmd
+}
+
+func runE(streams cmd.IOStreams, flags *flagpole) error {
+	var configMaps struct {
+		Items []struct {
+			Data commons.OperationRecord `json:"data"`
+		} `json:"items"`
+	}
+
+	selector := commons.OperationHistoryLabel + "=true"
+	if flags.Name != "" {
+		selector += "," + commons.OperationHistoryClusterLabel + "=" + flags.Name
+	}
+	output, err := exec.Output(exec.Command("kubectl", "--kubeconfig", flags.Kubeconfig,
+		"-n", commons.OperationHistoryNamespace, "get", "configmaps", "-l", selector, "-o", "json"))
+	if err != nil {
+		return errors.Wrap(err, "failed to get the operations of the workload cluster")
+	}
+	if err = json.Unmarshal(output, &configMaps); err != nil {
+		return errors.Wrap(err, "failed to parse the operations of the workload cluster")
+	}
+
+	var records []commons.OperationRecord
+	for _, item := range configMaps.Items {
+		records = append(records, item.Data)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Started < records[j].Started
+	})
+
+	w := tabwriter.NewWriter(streams.Out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STARTED\tFINISHED\tOPERATION\tCLUSTER\tUSER\tDESCRIPTOR\tRESULT")
+	for _, r := range records {
+		result := r.Result
+		if r.Error != "" {
+			result += ": " + r.Error
+		}
+		descriptorHash := r.DescriptorSHA256
+		if len(descriptorHash) > 12 {
+			descriptorHash = descriptorHash[:12]
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Started, r.Finished, r.Operation, r.Cluster, r.User, descriptorHash, result)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package importconfig implements the `import-config` command
+package importconfig
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"sigs.k8s.io/kind/pkg/cmd"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+type flagpole struct {
+	File   string
+	Output string
+}
+
+// NewCommand returns a new cobra.Command for converting a cluster config into a descriptor
+func NewCommand(logger log.Logger, streams cmd.IOStreams) *cobra.Command {
+	flags := &flagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "import-config",
+		Short: "Converts an eksctl, AKS or GKE cluster config into a cluster descriptor",
+		Long: "Converts an eksctl ClusterConfig, the JSON of `az aks show` or the output of " +
+			"`gcloud container clusters describe` into a managed cluster descriptor. " +
+			"The docker registries, the Helm repository, the external domain and the credentials must be completed afterwards",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(logger, streams, flags)
+		},
+	}
+	cmd.Flags().StringVarP(
+		&flags.File,
+		"file",
+		"f",
+		"",
+		"path to the eksctl, AKS or GKE cluster config",
+	)
+	cmd.Flags().StringVarP(
+		&flags.Output,
+		"output",
+		"o",
+		"",
+		"path to write the cluster descriptor, stdout when unset",
+	)
+	_ = cmd.MarkFlagRequired("file")
+	return cmd
+}
+
+func runE(logger log.Logger, streams cmd.IOStreams, flags *flagpole) error {
+	data, err := os.ReadFile(flags.File)
+	if err != nil {
+		return errors.Wrap(err, "failed to read the cluster config")
+	}
+
+	keosCluster, err := commons.ImportClusterConfig(data)
+	if err != nil {
+		return err
+	}
+	descriptor, err := yaml.Marshal(keosCluster)
+	if err != nil {
+		return err
+	}
+
+	if flags.Output == "" {
+		fmt.Fprint(streams.Out, string(descriptor))
+	} else if err := os.WriteFile(flags.Output, descriptor, 0644); err != nil {
+		return errors.Wrap(err, "failed to write the cluster descriptor")
+	}
+	logger.Warn("Complete the docker_registries, helm_repository and external_domain of the descriptor, and the credentials of its secrets, before creating the cluster")
+	return nil
+}
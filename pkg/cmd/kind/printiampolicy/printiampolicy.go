@@ -0,0 +1,73 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package printiampolicy implements the `print-iam-policy` command
+package printiampolicy
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/kind/pkg/cmd"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+type flagpole struct {
+	DescriptorPath string
+}
+
+const clusterDefaultPath = "./cluster.yaml"
+
+// NewCommand returns a new cobra.Command for printing the IAM policy of a descriptor
+func NewCommand(logger log.Logger, streams cmd.IOStreams) *cobra.Command {
+	flags := &flagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "print-iam-policy",
+		Short: "Prints the IAM policy needed by the credentials of a cluster",
+		Long: "Prints the least privilege policy needed to provision the cluster of the descriptor: " +
+			"an IAM policy document in aws, a custom role definition in azure and a custom role in gcp",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(streams, flags)
+		},
+	}
+	cmd.Flags().StringVarP(
+		&flags.DescriptorPath,
+		"descriptor",
+		"d",
+		clusterDefaultPath,
+		"path to the cluster descriptor",
+	)
+	return cmd
+}
+
+func runE(streams cmd.IOStreams, flags *flagpole) error {
+	keosCluster, _, err := commons.GetClusterDescriptor(flags.DescriptorPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse cluster descriptor")
+	}
+
+	policy, err := commons.GetIAMPolicy(*keosCluster)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(streams.Out, string(policy))
+	return nil
+}
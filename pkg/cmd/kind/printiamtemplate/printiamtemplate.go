@@ -0,0 +1,96 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package printiamtemplate implements the `print-iam-template` command
+package printiamtemplate
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"sigs.k8s.io/kind/pkg/cmd"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+type flagpole struct {
+	DescriptorPath string
+	Output         string
+}
+
+const clusterDefaultPath = "./cluster.yaml"
+
+// NewCommand returns a new cobra.Command for printing the CloudFormation IAM template of a descriptor
+func NewCommand(logger log.Logger, streams cmd.IOStreams) *cobra.Command {
+	flags := &flagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "print-iam-template",
+		Short: "Prints the CloudFormation template of the IAM stack of an aws cluster",
+		Long: "Prints, without applying it, the CloudFormation template of the IAM roles, policies and instance " +
+			"profiles which are created with spec.security.aws.create_iam, so the stack can be reviewed, " +
+			"or created beforehand, in the account",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(streams, flags)
+		},
+	}
+	cmd.Flags().StringVarP(
+		&flags.DescriptorPath,
+		"descriptor",
+		"d",
+		clusterDefaultPath,
+		"path to the cluster descriptor",
+	)
+	cmd.Flags().StringVarP(
+		&flags.Output,
+		"output",
+		"o",
+		"json",
+		"output format of the template: json or yaml",
+	)
+	return cmd
+}
+
+func runE(streams cmd.IOStreams, flags *flagpole) error {
+	keosCluster, clusterConfig, err := commons.GetClusterDescriptor(flags.DescriptorPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse cluster descriptor")
+	}
+	if keosCluster.Spec.InfraProvider != "aws" {
+		return errors.New("the IAM stack is only created in aws")
+	}
+
+	template := commons.GetAWSIAMTemplate(clusterConfig, keosCluster.Spec.Security.AWS)
+	var out []byte
+	switch flags.Output {
+	case "json":
+		out, err = json.MarshalIndent(template, "", "  ")
+	case "yaml":
+		out, err = yaml.Marshal(template)
+	default:
+		return errors.New("--output must be json or yaml")
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(streams.Out, string(out))
+	return nil
+}
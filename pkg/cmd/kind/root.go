@@ -24,13 +24,23 @@ import (
 	"github.com/spf13/cobra"
 
 	"sigs.k8s.io/kind/pkg/cmd"
+	"sigs.k8s.io/kind/pkg/cmd/kind/adopt"
 	"sigs.k8s.io/kind/pkg/cmd/kind/build"
+	"sigs.k8s.io/kind/pkg/cmd/kind/cleanuporphans"
+	"sigs.k8s.io/kind/pkg/cmd/kind/cloud"
 	"sigs.k8s.io/kind/pkg/cmd/kind/completion"
 	"sigs.k8s.io/kind/pkg/cmd/kind/create"
 	"sigs.k8s.io/kind/pkg/cmd/kind/delete"
+	"sigs.k8s.io/kind/pkg/cmd/kind/difftemplates"
 	"sigs.k8s.io/kind/pkg/cmd/kind/export"
+	"sigs.k8s.io/kind/pkg/cmd/kind/follow"
 	"sigs.k8s.io/kind/pkg/cmd/kind/get"
+	"sigs.k8s.io/kind/pkg/cmd/kind/history"
+	"sigs.k8s.io/kind/pkg/cmd/kind/importconfig"
 	"sigs.k8s.io/kind/pkg/cmd/kind/load"
+	"sigs.k8s.io/kind/pkg/cmd/kind/printiampolicy"
+	"sigs.k8s.io/kind/pkg/cmd/kind/printiamtemplate"
+	"sigs.k8s.io/kind/pkg/cmd/kind/rotate"
 	"sigs.k8s.io/kind/pkg/cmd/kind/version"
 	"sigs.k8s.io/kind/pkg/log"
 )
@@ -79,14 +89,24 @@ func NewCommand(logger log.Logger, streams cmd.IOStreams) *cobra.Command {
 		"silence all stderr output",
 	)
 	// add all top level subcommands
+	cmd.AddCommand(adopt.NewCommand(logger, streams))
 	cmd.AddCommand(build.NewCommand(logger, streams))
+	cmd.AddCommand(cleanuporphans.NewCommand(logger, streams))
+	cmd.AddCommand(cloud.NewCommand(logger, streams))
 	cmd.AddCommand(completion.NewCommand(logger, streams))
 	cmd.AddCommand(create.NewCommand(logger, streams))
 	cmd.AddCommand(delete.NewCommand(logger, streams))
+	cmd.AddCommand(difftemplates.NewCommand(logger, streams))
 	cmd.AddCommand(export.NewCommand(logger, streams))
+	cmd.AddCommand(follow.NewCommand(logger, streams))
 	cmd.AddCommand(get.NewCommand(logger, streams))
+	cmd.AddCommand(history.NewCommand(logger, streams))
+	cmd.AddCommand(importconfig.NewCommand(logger, streams))
 	cmd.AddCommand(version.NewCommand(logger, streams))
 	cmd.AddCommand(load.NewCommand(logger, streams))
+	cmd.AddCommand(printiampolicy.NewCommand(logger, streams))
+	cmd.AddCommand(printiamtemplate.NewCommand(logger, streams))
+	cmd.AddCommand(rotate.NewCommand(logger, streams))
 	return cmd
 }
 
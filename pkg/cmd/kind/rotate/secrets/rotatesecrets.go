@@ -0,0 +1,112 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package secrets implements the `rotate secrets` command
+package secrets
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/kind/pkg/cmd"
+	"sigs.k8s.io/kind/pkg/commons"
+	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+type flagpole struct {
+	SecretsPath      string
+	VaultPassword    string
+	NewVaultPassword string
+}
+
+const secretsDefaultPath = "./secrets.yml"
+
+// NewCommand returns a new cobra.Command for the vault password rotation
+func NewCommand(logger log.Logger, streams cmd.IOStreams) *cobra.Command {
+	flags := &flagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "secrets",
+		Short: "Rotates the vault password of the secrets file",
+		Long:  "Re-encrypts the secrets file with a new vault password",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(logger, flags)
+		},
+	}
+	cmd.Flags().StringVar(
+		&flags.SecretsPath,
+		"secrets",
+		secretsDefaultPath,
+		"path to the secrets file",
+	)
+	cmd.Flags().StringVarP(
+		&flags.VaultPassword,
+		"vault-password",
+		"p",
+		"",
+		"current vault password of the secrets file",
+	)
+	cmd.Flags().StringVar(
+		&flags.NewVaultPassword,
+		"new-vault-password",
+		"",
+		"new vault password to encrypt the secrets file",
+	)
+	return cmd
+}
+
+func runE(logger log.Logger, flags *flagpole) error {
+	var err error
+
+	if _, err = os.Stat(flags.SecretsPath); err != nil {
+		return errors.Wrap(err, "failed to read the secrets file")
+	}
+
+	if flags.VaultPassword == "" {
+		flags.VaultPassword, err = cmd.RequestPassword("Vault Password: ")
+		if err != nil {
+			return err
+		}
+	}
+
+	if flags.NewVaultPassword == "" {
+		flags.NewVaultPassword, err = cmd.RequestPassword("New Vault Password: ")
+		if err != nil {
+			return err
+		}
+		secondPassword, err := cmd.RequestPassword("Rewrite New Vault Password:")
+		if err != nil {
+			return err
+		}
+		if flags.NewVaultPassword != secondPassword {
+			return errors.New("The passwords do not match.")
+		}
+	}
+
+	if flags.NewVaultPassword == flags.VaultPassword {
+		return errors.New("The new vault password must be different from the current one")
+	}
+
+	err = commons.RotateSecretsPassword(flags.SecretsPath, flags.VaultPassword, flags.NewVaultPassword)
+	if err != nil {
+		return errors.Wrap(err, "failed to rotate the vault password")
+	}
+
+	logger.V(0).Info("The vault password of " + flags.SecretsPath + " has been rotated")
+	return nil
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"golang.org/x/term"
+
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+// SetPassword requests the vault password, which is requested twice when the secrets file does
+// not exist yet, since it is the password the file will be encrypted with
+func SetPassword(secretsPath string) (string, error) {
+	firstPassword, err := RequestPassword("Vault Password: ")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(secretsPath); os.IsNotExist(err) {
+		secondPassword, err := RequestPassword("Rewrite Vault Password:")
+		if err != nil {
+			return "", err
+		}
+		if firstPassword != secondPassword {
+			return "", errors.New("The passwords do not match.")
+		}
+	}
+
+	return firstPassword, nil
+}
+
+// RequestPassword prints the request and reads the password from the terminal, without echoing it
+func RequestPassword(request string) (string, error) {
+	fmt.Print(request)
+	bytePassword, err := term.ReadPassword(int(syscall.Stdin))
+	if err != nil {
+		return "", err
+	}
+	fmt.Print("\n")
+	return string(bytePassword), nil
+}
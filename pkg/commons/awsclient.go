@@ -0,0 +1,206 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commons
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const awsRetryMaxAttempts = 5
+
+// The global services have a single endpoint in each partition, signed in its global region
+var awsGlobalServices = []string{"route53", "iam"}
+
+// AWSClient is the single entry point to the AWS APIs. The clients of the SDK and the signed
+// requests to the APIs without one in the dependencies share its config, so the credentials, the
+// retries and the endpoints of the partition and of FIPS are resolved in one place
+type AWSClient struct {
+	Config aws.Config
+	fips   bool
+}
+
+// NewAWSClient returns the client of the region. The provider credentials are used if given, or the
+// default chain otherwise (environment, shared profiles and instance metadata), then the role of the
+// credentials and the given role are assumed in turn if set. An empty region falls back to the one
+// of the environment
+func NewAWSClient(ctx context.Context, secrets map[string]string, region string, roleARN string) (*AWSClient, error) {
+	var cfg aws.Config
+	var err error
+
+	fips := os.Getenv("AWS_USE_FIPS_ENDPOINT") == "true"
+	loadOptions := config.LoadOptions{Region: region, RetryMaxAttempts: awsRetryMaxAttempts}
+	if fips {
+		loadOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
+	}
+	if secrets["AccessKey"] != "" {
+		// The config of the given credentials is not loaded from the process environment nor the
+		// shared profiles, so the clients of different accounts do not leak into each other
+		cfg = aws.Config{
+			Region:           region,
+			RetryMaxAttempts: awsRetryMaxAttempts,
+			Credentials: aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider(
+				secrets["AccessKey"], secrets["SecretKey"], secrets["SessionToken"],
+			)),
+			ConfigSources: []interface{}{loadOptions},
+		}
+	} else {
+		cfg, err = config.LoadDefaultConfig(ctx, func(o *config.LoadOptions) error {
+			*o = loadOptions
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load the AWS config")
+		}
+	}
+	if secrets["RoleARN"] != "" {
+		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), secrets["RoleARN"], func(o *stscreds.AssumeRoleOptions) {
+			if secrets["ExternalID"] != "" {
+				o.ExternalID = aws.String(secrets["ExternalID"])
+			}
+		}))
+	}
+	if roleARN != "" {
+		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), roleARN))
+	}
+	return &AWSClient{Config: cfg, fips: fips}, nil
+}
+
+// WithRegion returns a copy of the client in the given region
+func (c *AWSClient) WithRegion(region string) *AWSClient {
+	client := *c
+	client.Config = c.Config.Copy()
+	client.Config.Region = region
+	return &client
+}
+
+// Global returns a copy of the client in the global region of its partition
+func (c *AWSClient) Global() *AWSClient {
+	return c.WithRegion(awsGlobalRegion(c.Config.Region))
+}
+
+func (c *AWSClient) CloudFormation() *cloudformation.Client {
+	return cloudformation.NewFromConfig(c.Config)
+}
+
+func (c *AWSClient) EC2() *ec2.Client {
+	return ec2.NewFromConfig(c.Config)
+}
+
+func (c *AWSClient) ECR() *ecr.Client {
+	return ecr.NewFromConfig(c.Config)
+}
+
+// Endpoint returns the endpoint of the service in the partition of the region
+func (c *AWSClient) Endpoint(service string) string {
+	suffix := awsDNSSuffix(c.Config.Region)
+	if Contains(awsGlobalServices, service) {
+		return "https://" + service + "." + suffix
+	}
+	if c.fips {
+		service += "-fips"
+	}
+	return "https://" + service + "." + c.Config.Region + "." + suffix
+}
+
+// Post posts the body to the API of the service, as the JSON and query APIs expect
+func (c *AWSClient) Post(ctx context.Context, service string, contentType string, target string, body string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, c.Endpoint(service)+"/", strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if target != "" {
+		req.Header.Set("X-Amz-Target", target)
+	}
+	return c.Do(ctx, service, req)
+}
+
+// Do signs the request to the API of the service with the credentials of the client and sends it
+func (c *AWSClient) Do(ctx context.Context, service string, req *http.Request) ([]byte, error) {
+	creds, err := c.Config.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to retrieve AWS credentials")
+	}
+	var body []byte
+	if req.Body != nil {
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+	}
+	region := c.Config.Region
+	if Contains(awsGlobalServices, service) {
+		region = awsGlobalRegion(region)
+	}
+	payloadHash := sha256.Sum256(body)
+	for attempt := 1; ; attempt++ {
+		req.Body = io.NopCloser(strings.NewReader(string(body)))
+		err = v4.NewSigner().SignHTTP(ctx, creds, req, hex.EncodeToString(payloadHash[:]), service, region, time.Now())
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to sign the "+service+" request")
+		}
+		resp, err := DoCloudRequest(req)
+		// The throttled and the server errors are retried, as the clients of the SDK do
+		if err == nil || attempt == awsRetryMaxAttempts || !isAWSRetryable(err) {
+			return resp, err
+		}
+		time.Sleep(time.Duration(attempt) * time.Second)
+	}
+}
+
+func isAWSRetryable(err error) bool {
+	for _, reason := range []string{"Throttling", "RequestLimitExceeded", "TooManyRequests", "request failed: 5"} {
+		if strings.Contains(err.Error(), reason) {
+			return true
+		}
+	}
+	return false
+}
+
+func awsDNSSuffix(region string) string {
+	if strings.HasPrefix(region, "cn-") {
+		return "amazonaws.com.cn"
+	}
+	return "amazonaws.com"
+}
+
+func awsGlobalRegion(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return "cn-northwest-1"
+	case strings.HasPrefix(region, "us-gov-"):
+		return "us-gov-west-1"
+	}
+	return "us-east-1"
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commons
+
+const (
+	// AWSIAMStackName is the default stack, the one of clusterawsadm, so the stacks created by previous
+	// versions are updated
+	AWSIAMStackName = "cluster-api-provider-aws-sigs-k8s-io"
+	// AWSIAMSuffix is the suffix of the names of the IAM resources of the default stack, as clusterawsadm names them
+	AWSIAMSuffix = ".cluster-api-provider-aws.sigs.k8s.io"
+	// AWSIAMRoleNameMaxLength is the maximum length of the names of the IAM roles
+	AWSIAMRoleNameMaxLength = 64
+	// AWSSSMNodePolicy is needed by the nodes to be patched, along with the SSM agent of the images
+	AWSSSMNodePolicy = "arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore"
+	awsCSIPolicy     = "arn:aws:iam::aws:policy/service-role/AmazonEBSCSIDriverPolicy"
+)
+
+var awsIAMControllersActions = []string{
+	"ec2:AllocateAddress", "ec2:AssociateRouteTable", "ec2:AttachInternetGateway", "ec2:AuthorizeSecurityGroupIngress",
+	"ec2:CreateInternetGateway", "ec2:CreateNatGateway", "ec2:CreateRoute", "ec2:CreateRouteTable", "ec2:CreateSecurityGroup",
+	"ec2:CreateSubnet", "ec2:CreateTags", "ec2:CreateVpc", "ec2:ModifyVpcAttribute", "ec2:DeleteInternetGateway",
+	"ec2:DeleteNatGateway", "ec2:DeleteRouteTable", "ec2:ReplaceRoute", "ec2:DeleteSecurityGroup", "ec2:DeleteSubnet",
+	"ec2:DeleteTags", "ec2:DeleteVpc", "ec2:Describe*", "ec2:CreateDhcpOptions", "ec2:AssociateDhcpOptions",
+	"ec2:DeleteDhcpOptions", "ec2:DetachInternetGateway", "ec2:DisassociateRouteTable", "ec2:DisassociateAddress",
+	"ec2:ModifyInstanceAttribute", "ec2:ModifyNetworkInterfaceAttribute", "ec2:ModifySubnetAttribute", "ec2:ReleaseAddress",
+	"ec2:RevokeSecurityGroupIngress", "ec2:RunInstances", "ec2:TerminateInstances", "ec2:CreateLaunchTemplate",
+	"ec2:CreateLaunchTemplateVersion", "ec2:DeleteLaunchTemplate", "ec2:DeleteLaunchTemplateVersions",
+	"ec2:ModifyInstanceMetadataOptions", "tag:GetResources", "elasticloadbalancing:*", "autoscaling:DescribeAutoScalingGroups",
+	"autoscaling:DescribeInstanceRefreshes",
+}
+var awsIAMControllersEKSActions = []string{
+	"ssm:GetParameter", "iam:ListOpenIDConnectProviders", "iam:GetOpenIDConnectProvider", "iam:CreateOpenIDConnectProvider",
+	"iam:AddClientIDToOpenIDConnectProvider", "iam:UpdateOpenIDConnectProviderThumbprint", "iam:DeleteOpenIDConnectProvider",
+	"iam:TagOpenIDConnectProvider", "iam:ListAttachedRolePolicies", "iam:GetRole", "iam:GetPolicy", "eks:*",
+	"kms:CreateGrant", "kms:DescribeKey",
+}
+var awsIAMControlPlaneActions = []string{
+	"autoscaling:DescribeAutoScalingGroups", "autoscaling:DescribeLaunchConfigurations", "autoscaling:DescribeTags",
+	"ec2:Describe*", "ec2:CreateSecurityGroup", "ec2:CreateTags", "ec2:CreateVolume", "ec2:ModifyInstanceAttribute",
+	"ec2:ModifyVolume", "ec2:AttachVolume", "ec2:AuthorizeSecurityGroupIngress", "ec2:CreateRoute", "ec2:DeleteRoute",
+	"ec2:DeleteSecurityGroup", "ec2:DeleteVolume", "ec2:DetachVolume", "ec2:RevokeSecurityGroupIngress",
+	"elasticloadbalancing:*", "iam:CreateServiceLinkedRole", "kms:DescribeKey",
+}
+var awsIAMNodesActions = []string{
+	"ec2:AssignIpv6Addresses", "ec2:DescribeInstances", "ec2:DescribeRegions", "ec2:CreateTags", "ec2:DescribeTags",
+	"ec2:DescribeNetworkInterfaces", "ec2:DescribeInstanceTypes", "ecr:GetAuthorizationToken",
+	"ecr:BatchCheckLayerAvailability", "ecr:GetDownloadUrlForLayer", "ecr:GetRepositoryPolicy", "ecr:DescribeRepositories",
+	"ecr:ListImages", "ecr:BatchGetImage", "ssm:UpdateInstanceInformation", "ssmmessages:*",
+	"s3:GetEncryptionConfiguration",
+}
+
+// GetAWSIAMStackName returns the name of the IAM stack of the descriptor
+func GetAWSIAMStackName(security AWSSecurity) string {
+	if security.IAMStackName != "" {
+		return security.IAMStackName
+	}
+	return AWSIAMStackName
+}
+
+// GetAWSIAMSuffix returns the suffix of the names of the IAM resources of the stack. The IAM names are
+// unique in the account, so the resources of a stack other than the default one are named after it
+func GetAWSIAMSuffix(security AWSSecurity) string {
+	stackName := GetAWSIAMStackName(security)
+	if stackName == AWSIAMStackName {
+		return AWSIAMSuffix
+	}
+	return "." + stackName
+}
+
+// GetAWSIAMTemplate returns the CloudFormation template of the IAM stack, with the resources that
+// clusterawsadm creates with EKS enabled, the default EKS control plane role and the CSI policy
+func GetAWSIAMTemplate(clusterConfig *ClusterConfig, security AWSSecurity) map[string]interface{} {
+	suffix := GetAWSIAMSuffix(security)
+	policy := func(name string, statements ...map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"Type": "AWS::IAM::ManagedPolicy",
+			"Properties": map[string]interface{}{
+				"ManagedPolicyName": name + suffix,
+				"Description":       "For the Kubernetes Cluster API Provider AWS " + name,
+				"PolicyDocument":    map[string]interface{}{"Version": "2012-10-17", "Statement": statements},
+			},
+		}
+	}
+	statement := func(actions []string, resource string) map[string]interface{} {
+		return map[string]interface{}{"Effect": "Allow", "Action": actions, "Resource": resource}
+	}
+	serviceLinkedRole := func(service string, role string) map[string]interface{} {
+		s := statement([]string{"iam:CreateServiceLinkedRole"}, "arn:*:iam::*:role/aws-service-role/"+service+"/"+role)
+		s["Condition"] = map[string]interface{}{"StringLike": map[string]string{"iam:AWSServiceName": service}}
+		return s
+	}
+	role := func(name string, service string, policies []interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"Type": "AWS::IAM::Role",
+			"Properties": map[string]interface{}{
+				"RoleName": name + suffix,
+				"AssumeRolePolicyDocument": map[string]interface{}{
+					"Version": "2012-10-17",
+					"Statement": []map[string]interface{}{{
+						"Effect":    "Allow",
+						"Principal": map[string][]string{"Service": {service}},
+						"Action":    []string{"sts:AssumeRole"},
+					}},
+				},
+				"ManagedPolicyArns": policies,
+			},
+		}
+	}
+	instanceProfile := func(name string, role string) map[string]interface{} {
+		return map[string]interface{}{
+			"Type": "AWS::IAM::InstanceProfile",
+			"Properties": map[string]interface{}{
+				"InstanceProfileName": name + suffix,
+				"Roles":               []interface{}{map[string]string{"Ref": role}},
+			},
+		}
+	}
+	ref := func(name string) map[string]string {
+		return map[string]string{"Ref": name}
+	}
+
+	nodesPolicies := []interface{}{
+		ref("AWSIAMManagedPolicyCloudProviderNodes"),
+		"arn:aws:iam::aws:policy/AmazonEKSWorkerNodePolicy",
+		"arn:aws:iam::aws:policy/AmazonEKS_CNI_Policy",
+		awsCSIPolicy,
+	}
+	if clusterConfig != nil && clusterConfig.Spec.OSPatching != nil {
+		nodesPolicies = append(nodesPolicies, AWSSSMNodePolicy)
+	}
+	resources := map[string]interface{}{
+		"AWSIAMManagedPolicyControllers": policy("controllers",
+			statement(awsIAMControllersActions, "*"),
+			statement([]string{
+				"autoscaling:CreateAutoScalingGroup", "autoscaling:UpdateAutoScalingGroup", "autoscaling:CreateOrUpdateTags",
+				"autoscaling:StartInstanceRefresh", "autoscaling:DeleteAutoScalingGroup", "autoscaling:DeleteTags",
+			}, "arn:*:autoscaling:*:*:autoScalingGroup:*:autoScalingGroupName/*"),
+			serviceLinkedRole("autoscaling.amazonaws.com", "AWSServiceRoleForAutoScaling"),
+			serviceLinkedRole("elasticloadbalancing.amazonaws.com", "AWSServiceRoleForElasticLoadBalancing"),
+			serviceLinkedRole("spot.amazonaws.com", "AWSServiceRoleForEC2Spot"),
+			statement([]string{"iam:PassRole"}, "arn:*:iam::*:role/*"+suffix),
+			statement([]string{"secretsmanager:CreateSecret", "secretsmanager:DeleteSecret", "secretsmanager:TagResource"},
+				"arn:*:secretsmanager:*:*:secret:aws.cluster.x-k8s.io/*"),
+		),
+		"AWSIAMManagedPolicyControllersEKS": policy("controllers-eks",
+			statement(awsIAMControllersEKSActions, "*"),
+			serviceLinkedRole("eks.amazonaws.com", "AWSServiceRoleForAmazonEKS"),
+			serviceLinkedRole("eks-nodegroup.amazonaws.com", "AWSServiceRoleForAmazonEKSNodegroup"),
+			serviceLinkedRole("eks-fargate.amazonaws.com", "AWSServiceRoleForAmazonEKSForFargate"),
+		),
+		"AWSIAMManagedPolicyCloudProviderControlPlane": policy("control-plane",
+			statement(awsIAMControlPlaneActions, "*"),
+		),
+		"AWSIAMManagedPolicyCloudProviderNodes": policy("nodes",
+			statement(awsIAMNodesActions, "*"),
+			statement([]string{"secretsmanager:DeleteSecret", "secretsmanager:GetSecretValue"},
+				"arn:*:secretsmanager:*:*:secret:aws.cluster.x-k8s.io/*"),
+		),
+		"AWSIAMRoleControlPlane": role("control-plane", "ec2.amazonaws.com", []interface{}{
+			ref("AWSIAMManagedPolicyCloudProviderControlPlane"),
+			ref("AWSIAMManagedPolicyCloudProviderNodes"),
+			ref("AWSIAMManagedPolicyControllers"),
+			ref("AWSIAMManagedPolicyControllersEKS"),
+			awsCSIPolicy,
+		}),
+		"AWSIAMRoleControllers": role("controllers", "ec2.amazonaws.com", []interface{}{
+			ref("AWSIAMManagedPolicyControllers"),
+			ref("AWSIAMManagedPolicyControllersEKS"),
+		}),
+		"AWSIAMRoleEKSControlPlane": role("eks-controlplane", "eks.amazonaws.com", []interface{}{
+			"arn:aws:iam::aws:policy/AmazonEKSClusterPolicy",
+		}),
+		"AWSIAMRoleNodes":                   role("nodes", "ec2.amazonaws.com", nodesPolicies),
+		"AWSIAMInstanceProfileControlPlane": instanceProfile("control-plane", "AWSIAMRoleControlPlane"),
+		"AWSIAMInstanceProfileControllers":  instanceProfile("controllers", "AWSIAMRoleControllers"),
+		"AWSIAMInstanceProfileNodes":        instanceProfile("nodes", "AWSIAMRoleNodes"),
+	}
+	return map[string]interface{}{
+		"AWSTemplateFormatVersion": "2010-09-09",
+		"Description":              "IAM resources of the Kubernetes Cluster API Provider AWS",
+		"Resources":                resources,
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commons
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestGetAWSIAMTemplateNames(t *testing.T) {
+	tests := []struct {
+		name      string
+		stackName string
+		suffix    string
+	}{
+		{name: "default stack", suffix: AWSIAMSuffix},
+		{name: "default stack name", stackName: AWSIAMStackName, suffix: AWSIAMSuffix},
+		{name: "custom stack", stackName: "team-a-iam", suffix: ".team-a-iam"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			security := AWSSecurity{CreateIAM: true, IAMStackName: tt.stackName}
+			if suffix := GetAWSIAMSuffix(security); suffix != tt.suffix {
+				t.Fatalf("expected the suffix %q, got %q", tt.suffix, suffix)
+			}
+
+			resources := GetAWSIAMTemplate(nil, security)["Resources"].(map[string]interface{})
+			names := 0
+			for id, resource := range resources {
+				properties := resource.(map[string]interface{})["Properties"].(map[string]interface{})
+				for _, key := range []string{"ManagedPolicyName", "RoleName", "InstanceProfileName"} {
+					name, ok := properties[key].(string)
+					if !ok {
+						continue
+					}
+					names++
+					if !strings.HasSuffix(name, tt.suffix) {
+						t.Errorf("the %s of %s is %s, without the suffix %s", key, id, name, tt.suffix)
+					}
+				}
+			}
+			if names != len(resources) {
+				t.Errorf("%d of the %d resources are named", names, len(resources))
+			}
+
+			// The controllers can only pass the roles of the stack to the machines
+			controllers, err := json.Marshal(resources["AWSIAMManagedPolicyControllers"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(controllers), `"arn:*:iam::*:role/*`+tt.suffix+`"`) {
+				t.Errorf("the controllers cannot pass the roles of the stack: %s", controllers)
+			}
+		})
+	}
+}
+
+// TestGetAWSIAMTemplateStacks checks the stacks of two teams in the same account do not share any
+// IAM name, as they are unique in the account
+func TestGetAWSIAMTemplateStacks(t *testing.T) {
+	names := map[string]string{}
+	for _, stackName := range []string{"", "team-a-iam", "team-b-iam"} {
+		resources := GetAWSIAMTemplate(nil, AWSSecurity{CreateIAM: true, IAMStackName: stackName})["Resources"].(map[string]interface{})
+		for _, resource := range resources {
+			properties := resource.(map[string]interface{})["Properties"].(map[string]interface{})
+			for _, key := range []string{"ManagedPolicyName", "RoleName", "InstanceProfileName"} {
+				name, ok := properties[key].(string)
+				if !ok {
+					continue
+				}
+				if other, exists := names[key+"/"+name]; exists {
+					t.Errorf("the %s %s is in the stacks %q and %q", key, name, other, stackName)
+				}
+				names[key+"/"+name] = stackName
+			}
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
@@ -39,6 +40,18 @@ var AWSVolumeType = "gp3"
 var AzureVMsVolumeType = "Standard_LRS"
 var GCPVMsVolumeType = "pd-ssd"
 
+const DevProfile = "dev"
+
+// DefaultClusterDomain is the DNS domain of the services unless the descriptor sets another one
+const DefaultClusterDomain = "cluster.local"
+
+// DevProfileSizes are the default instance types of the single node of the dev profile
+var DevProfileSizes = map[string]string{
+	"aws":   "t3.large",
+	"azure": "Standard_D2s_v3",
+	"gcp":   "e2-standard-2",
+}
+
 type Resource struct {
 	APIVersion string      `yaml:"apiVersion" validate:"required"`
 	Kind       string      `yaml:"kind" validate:"required"`
@@ -68,19 +81,249 @@ type Metadata struct {
 }
 
 type ClusterConfigSpec struct {
-	EKSLBController             bool               `yaml:"eks_lb_controller"`
-	Private                     bool               `yaml:"private_registry"`
-	ControlplaneConfig          ControlplaneConfig `yaml:"controlplane_config"`
-	WorkersConfig               WorkersConfig      `yaml:"workers_config"`
-	ClusterOperatorVersion      string             `yaml:"cluster_operator_version,omitempty"`
-	ClusterOperatorImageVersion string             `yaml:"cluster_operator_image_version,omitempty"`
-	PrivateHelmRepo             bool               `yaml:"private_helm_repo"`
-	Charts                      []Chart            `yaml:"charts,omitempty"`
+	EKSLBController             bool                 `yaml:"eks_lb_controller"`
+	Private                     bool                 `yaml:"private_registry"`
+	ControlplaneConfig          ControlplaneConfig   `yaml:"controlplane_config"`
+	WorkersConfig               WorkersConfig        `yaml:"workers_config"`
+	ClusterOperatorVersion      string               `yaml:"cluster_operator_version,omitempty"`
+	ClusterOperatorImageVersion string               `yaml:"cluster_operator_image_version,omitempty"`
+	PrivateHelmRepo             bool                 `yaml:"private_helm_repo"`
+	Charts                      []Chart              `yaml:"charts,omitempty"`
+	CAPIProviders               []CAPIProvider       `yaml:"capi_providers,omitempty" validate:"omitempty,dive"`
+	ManifestsExport             *ManifestsExport     `yaml:"manifests_export,omitempty"`
+	CAPXConfig                  CAPXConfig           `yaml:"capx_config,omitempty"`
+	ClusterResourceSets         []ClusterResourceSet `yaml:"cluster_resource_sets,omitempty" validate:"omitempty,dive"`
+	NamingPolicy                *NamingPolicy        `yaml:"naming_policy,omitempty"`
+	Notifications               *Notifications       `yaml:"notifications,omitempty"`
+	PreloadImages               []string             `yaml:"preload_images,omitempty" validate:"omitempty,dive,required"`
+	RegistryCache               *RegistryCache       `yaml:"registry_cache,omitempty"`
+	RegistryMirrors             []RegistryMirror     `yaml:"registry_mirrors,omitempty" validate:"omitempty,dive"`
+	NodeLocalDNS                *NodeLocalDNS        `yaml:"node_local_dns,omitempty"`
+	SystemBaseline              *SystemBaseline      `yaml:"system_baseline,omitempty"`
+	ExternalSecrets             *ExternalSecrets     `yaml:"external_secrets,omitempty"`
+	PolicyEngine                *PolicyEngine        `yaml:"policy_engine,omitempty"`
+	Namespaces                  []TenantNamespace    `yaml:"namespaces,omitempty" validate:"omitempty,dive"`
+	CABundle                    string               `yaml:"ca_bundle,omitempty" validate:"omitempty,file"`
+	OSPatching                  *OSPatching          `yaml:"os_patching,omitempty"`
+	CostAllocation              *CostAllocation      `yaml:"cost_allocation,omitempty"`
+	SpotTermination             *SpotTermination     `yaml:"spot_termination,omitempty"`
+	Interconnect                *Interconnect        `yaml:"interconnect,omitempty"`
+	AddonsPDB                   *AddonsPDB           `yaml:"addons_pdb,omitempty"`
+	RegistryLogin               *RegistryLogin       `yaml:"registry_login,omitempty"`
+	ManagementAccess            *ManagementAccess    `yaml:"management_access,omitempty"`
+	AccessKubeconfigs           []string             `yaml:"access_kubeconfigs,omitempty" validate:"omitempty,unique,dive,oneof='operator' 'viewer'"`
+}
+
+// ManagementAccess binds the users and groups of the team of the cluster to a Role of its namespace,
+// cluster-<name>, in the management cluster, so they can only see and manage their own clusters
+type ManagementAccess struct {
+	Groups []string `yaml:"groups,omitempty" validate:"omitempty,dive,required"`
+	Users  []string `yaml:"users,omitempty" validate:"omitempty,dive,required"`
+	// ReadOnly only allows them to get, list and watch the objects of the cluster
+	ReadOnly bool `yaml:"read_only,omitempty"`
+}
+
+// RegistryLogin sets the credentials of the keos registry as the image pull secret of the default
+// ServiceAccount of kube-system and the namespaces. Only the generic registries are supported, as the
+// nodes pull from the cloud registries with their own identity, while their tokens expire in hours
+type RegistryLogin struct {
+	Namespaces []string `yaml:"namespaces,omitempty" validate:"omitempty,dive,hostname_rfc1123"`
+}
+
+// AddonsPDB creates a PodDisruptionBudget for each addon Deployment without one, so the node rollouts
+// do not take down the cluster-critical components
+type AddonsPDB struct {
+	// MaxUnavailable is the number or percentage of pods of each addon evicted at a time, 1 if empty
+	MaxUnavailable string `yaml:"max_unavailable,omitempty"`
+	// Exclude are the addons, as namespace/deployment, without PodDisruptionBudget
+	Exclude []string `yaml:"exclude,omitempty" validate:"omitempty,dive,contains=/"`
+}
+
+// SpotTermination drains the spot nodes gracefully when they are preempted, with the
+// aws-node-termination-handler in aws, the aks-node-termination-handler in azure and the kubelet
+// graceful node shutdown in gcp
+type SpotTermination struct {
+	// RebalanceDraining also drains the aws spot nodes on EC2 rebalance recommendations, before
+	// their interruption notice
+	RebalanceDraining bool `yaml:"rebalance_draining,omitempty"`
+}
+
+// Interconnect joins the cluster to a Submariner broker, which connects the services exported by
+// the joined clusters through IPsec tunnels between their gateway nodes. One of the clusters hosts
+// the broker, and writes the information the rest of them join it with
+type Interconnect struct {
+	// ClusterID identifies the cluster among the joined ones, the cluster name if empty
+	ClusterID string `yaml:"cluster_id,omitempty" validate:"omitempty,hostname_rfc1123,max=63"`
+	// Broker deploys the broker in this cluster, and writes its information to BrokerInfo
+	Broker bool `yaml:"broker,omitempty"`
+	// BrokerInfo is the file with the broker API server, credentials and IPsec PSK
+	BrokerInfo string `yaml:"broker_info" validate:"required"`
+	// GatewayNodeGroup is the node group whose nodes terminate the tunnels to the other clusters
+	GatewayNodeGroup string `yaml:"gateway_node_group" validate:"required"`
+	// GlobalCIDR is the range the exported services are given global IPs from, so the clusters
+	// can have overlapping pods and services networks. It must be unique among the joined clusters
+	GlobalCIDR string `yaml:"global_cidr" validate:"required,cidrv4"`
+}
+
+// CostAllocation deploys OpenCost, which prices the workloads of each namespace with the pricing
+// API of the provider
+type CostAllocation struct {
+	// PrometheusURL is the Prometheus which scrapes the metrics of the cluster
+	PrometheusURL string `yaml:"prometheus_url" validate:"required,url"`
+	// UI deploys the OpenCost UI along with the exporter
+	UI bool `yaml:"ui,omitempty"`
+}
+
+// OSPatching enrolls the nodes in a weekly maintenance window of the patch service of the provider:
+// SSM Patch Manager in aws and Update Manager in azure
+type OSPatching struct {
+	Day string `yaml:"day" validate:"required,oneof=Monday Tuesday Wednesday Thursday Friday Saturday Sunday"`
+	// Time is the start of the window, in UTC (HH:MM)
+	Time string `yaml:"time" validate:"required,datetime=15:04"`
+	// Duration is the length of the window, in hours
+	Duration int `yaml:"duration,omitempty" validate:"omitempty,gte=2,lte=4"`
+	// Reboot restarts the nodes when the patches require it
+	Reboot bool `yaml:"reboot,omitempty"`
+}
+
+// TenantNamespace is a namespace with its quota, the default limits of its containers and the
+// bindings of its teams
+type TenantNamespace struct {
+	Name   string            `yaml:"name" validate:"required,hostname_rfc1123"`
+	Labels map[string]string `yaml:"labels,omitempty"`
+	// ResourceQuota is the hard limits of the namespace (e.g. requests.cpu: "10")
+	ResourceQuota map[string]string `yaml:"resource_quota,omitempty"`
+	LimitRange    *LimitRange       `yaml:"limit_range,omitempty"`
+	RoleBindings  []TeamRoleBinding `yaml:"role_bindings,omitempty" validate:"omitempty,dive"`
+}
+
+// LimitRange sets the default requests and limits of the containers of a namespace
+type LimitRange struct {
+	DefaultRequest map[string]string `yaml:"default_request,omitempty"`
+	Default        map[string]string `yaml:"default,omitempty"`
+	Max            map[string]string `yaml:"max,omitempty"`
+}
+
+// TeamRoleBinding binds a ClusterRole (e.g. admin, edit or view) in a namespace to the groups and
+// users of a team
+type TeamRoleBinding struct {
+	ClusterRole string   `yaml:"cluster_role" validate:"required"`
+	Groups      []string `yaml:"groups,omitempty" validate:"required_without=Users"`
+	Users       []string `yaml:"users,omitempty"`
+}
+
+// PolicyEngine deploys Kyverno or Gatekeeper with a baseline policy bundle
+type PolicyEngine struct {
+	Engine string `yaml:"engine" validate:"required,oneof='kyverno' 'gatekeeper'"`
+	// Policies are the files, directories or https URLs of the policy bundle. Kyverno defaults to the
+	// baseline Pod Security Standards in audit mode
+	Policies []string `yaml:"policies,omitempty" validate:"omitempty,dive,required"`
+}
+
+// ExternalSecrets deploys external-secrets with a ClusterSecretStore of AWS Secrets Manager,
+// Azure Key Vault or GCP Secret Manager, authenticated with the identity of the nodes
+type ExternalSecrets struct {
+	// VaultURL is the Azure Key Vault of the ClusterSecretStore
+	VaultURL string `yaml:"vault_url,omitempty" validate:"omitempty,url"`
+	// IdentityID is the client ID of the Azure managed identity, the one of the nodes when unset
+	IdentityID string `yaml:"identity_id,omitempty"`
+}
+
+// SystemBaseline protects the system components of the workload cluster from the saturation of the nodes
+type SystemBaseline struct {
+	// PriorityClasses creates the keos-critical, keos-high, keos-medium and keos-low PriorityClasses
+	PriorityClasses bool `yaml:"priority_classes,omitempty"`
+	// KubeReserved and SystemReserved are set in the kubelets, with the default reservations when empty
+	KubeReserved   map[string]string `yaml:"kube_reserved,omitempty" validate:"omitempty,dive,keys,oneof='cpu' 'memory' 'ephemeral-storage' 'pid',endkeys,required"`
+	SystemReserved map[string]string `yaml:"system_reserved,omitempty" validate:"omitempty,dive,keys,oneof='cpu' 'memory' 'ephemeral-storage' 'pid',endkeys,required"`
+}
+
+// NodeLocalDNS deploys a DNS cache in every node of the workload cluster
+type NodeLocalDNS struct {
+	// LocalIP is the link-local address the cache listens on, 169.254.20.10 when unset
+	LocalIP string `yaml:"local_ip,omitempty" validate:"omitempty,ip_addr"`
+}
+
+// StubDomain resolves the names of a domain (e.g. a corporate one) through its own servers
+type StubDomain struct {
+	Domain  string   `yaml:"domain" validate:"required,fqdn"`
+	Servers []string `yaml:"servers" validate:"required,dive,ip_addr"`
+}
+
+// RegistryMirror sets the endpoints through which the images of a registry (e.g. docker.io) are pulled
+type RegistryMirror struct {
+	Registry  string   `yaml:"registry" validate:"required"`
+	Endpoints []string `yaml:"endpoints" validate:"required,dive,url"`
+}
+
+// RegistryCache deploys a pull-through registry cache in the workload cluster, set as the
+// containerd mirror of the upstream registry in the nodes
+type RegistryCache struct {
+	// Upstream is the registry mirrored by the cache, the keos registry when unset
+	Upstream    string `yaml:"upstream,omitempty"`
+	StorageSize string `yaml:"storage_size,omitempty"`
+	NodePort    int    `yaml:"node_port,omitempty" validate:"omitempty,gte=30000,lte=32767"`
+}
+
+// Notifications posts the outcome of the provisioning to a webhook (e.g. a Slack incoming webhook)
+type Notifications struct {
+	WebhookURL string `yaml:"webhook_url" validate:"required,url"`
+	// Events are the outcomes to be notified, all of them when empty
+	Events []string `yaml:"events,omitempty" validate:"omitempty,dive,oneof='success' 'failure'"`
+}
+
+// NamingPolicy enforces the corporate naming standards on the names of the cluster and its node groups,
+// which the cloud resources are named after
+type NamingPolicy struct {
+	Prefix string `yaml:"prefix,omitempty"`
+	Regex  string `yaml:"regex,omitempty"`
+}
+
+// ClusterResourceSet packages user manifests to be applied to the cluster once it is created
+type ClusterResourceSet struct {
+	Name string `yaml:"name" validate:"required"`
+	// Sources are paths to YAML files or directories of YAML files, or https URLs
+	Sources  []string `yaml:"sources" validate:"required,min=1"`
+	Strategy string   `yaml:"strategy,omitempty" validate:"omitempty,oneof='ApplyOnce' 'Reconcile'"`
+}
+
+// CAPXConfig sets the values used to install the Cluster API providers
+type CAPXConfig struct {
+	// Variables are passed to clusterctl, overriding the ones set for the provider (e.g. EXP_MACHINE_POOL)
+	Variables map[string]string `yaml:"variables,omitempty"`
+	// ExtraArgs are appended to the infrastructure provider manager args (e.g. concurrency, metrics binding)
+	ExtraArgs []string `yaml:"extra_args,omitempty" validate:"omitempty,dive,startswith=--"`
+	// FeatureGates of the Cluster API controllers, the defaults of the provider are kept if unset
+	FeatureGates CAPIFeatureGates `yaml:"feature_gates,omitempty"`
+}
+
+type CAPIFeatureGates struct {
+	MachinePool               *bool `yaml:"machine_pool,omitempty"`
+	ClusterResourceSet        *bool `yaml:"cluster_resource_set,omitempty"`
+	ClusterTopology           *bool `yaml:"cluster_topology,omitempty"`
+	MachineSetPreflightChecks *bool `yaml:"machine_set_preflight_checks,omitempty"`
+}
+
+// ManifestsExport sets where the rendered Cluster API manifests are written after the creation
+type ManifestsExport struct {
+	Path string `yaml:"path" validate:"required"`
+	// GitBranch commits the manifests to this branch, Path must be a git working tree
+	GitBranch string `yaml:"git_branch,omitempty"`
+}
+
+// CAPIProvider overrides the location clusterctl fetches the components of a provider from
+type CAPIProvider struct {
+	Name   string `yaml:"name" validate:"required"`
+	Type   string `yaml:"type" validate:"required,oneof='CoreProvider' 'BootstrapProvider' 'ControlPlaneProvider' 'InfrastructureProvider'"`
+	URL    string `yaml:"url" validate:"required"`
+	SHA256 string `yaml:"sha256,omitempty" validate:"omitempty,len=64,hexadecimal"`
 }
 
 type Chart struct {
 	Name    string `yaml:"name,omitempty"`
 	Version string `yaml:"version,omitempty"`
+	// Digest is the sha256 checksum of the chart package
+	Digest string `yaml:"digest,omitempty" validate:"omitempty,len=64,hexadecimal"`
 }
 
 type ChartEntry struct {
@@ -89,14 +332,25 @@ type ChartEntry struct {
 	Namespace  string
 	Pull       bool
 	Reconcile  bool
+	Digest     string
 }
 
 type ControlplaneConfig struct {
-	MaxUnhealthy *int `yaml:"max_unhealthy,omitempty" validate:"omitempty,numeric,gte=0,lte=100"`
+	MaxUnhealthy        *int                 `yaml:"max_unhealthy,omitempty" validate:"omitempty,numeric,gte=0,lte=100"`
+	NodeStartupTimeout  string               `yaml:"node_startup_timeout,omitempty"`
+	UnhealthyConditions []UnhealthyCondition `yaml:"unhealthy_conditions,omitempty" validate:"omitempty,dive"`
 }
 
 type WorkersConfig struct {
-	MaxUnhealthy *int `yaml:"max_unhealthy,omitempty" validate:"omitempty,numeric,gte=0,lte=100"`
+	MaxUnhealthy        *int                 `yaml:"max_unhealthy,omitempty" validate:"omitempty,numeric,gte=0,lte=100"`
+	NodeStartupTimeout  string               `yaml:"node_startup_timeout,omitempty"`
+	UnhealthyConditions []UnhealthyCondition `yaml:"unhealthy_conditions,omitempty" validate:"omitempty,dive"`
+}
+
+type UnhealthyCondition struct {
+	Type    string `yaml:"type" validate:"required"`
+	Status  string `yaml:"status" validate:"required,oneof=True False Unknown"`
+	Timeout string `yaml:"timeout" validate:"required"`
 }
 
 type ClusterConfigRef struct {
@@ -107,13 +361,17 @@ type ClusterConfigRef struct {
 type KeosSpec struct {
 	DeployAutoscaler bool `yaml:"deploy_autoscaler" validate:"boolean"`
 
+	Profile string `yaml:"profile,omitempty" validate:"omitempty,oneof=dev"`
+
+	TTL string `yaml:"ttl,omitempty"`
+
 	Bastion Bastion `yaml:"bastion,omitempty"`
 
 	StorageClass StorageClass `yaml:"storageclass,omitempty"`
 
 	Credentials Credentials `yaml:"credentials,omitempty"`
 
-	InfraProvider string `yaml:"infra_provider" validate:"required,oneof='aws' 'gcp' 'azure'"`
+	InfraProvider string `yaml:"infra_provider" validate:"required,oneof='aws' 'gcp' 'azure' 'equinix' 'hetzner' 'nutanix' 'digitalocean' 'ibmcloud' 'alibabacloud'"`
 
 	K8SVersion string `yaml:"k8s_version" validate:"required"`
 	Region     string `yaml:"region" validate:"required"`
@@ -121,8 +379,9 @@ type KeosSpec struct {
 	Networks Networks `yaml:"networks,omitempty"`
 
 	Dns struct {
-		ManageZone bool     `yaml:"manage_zone,omitempty" validate:"boolean"`
-		Forwarders []string `yaml:"forwarders,omitempty" validate:"omitempty,dive,ip_addr"`
+		ManageZone  bool         `yaml:"manage_zone,omitempty" validate:"boolean"`
+		Forwarders  []string     `yaml:"forwarders,omitempty" validate:"omitempty,dive,ip_addr"`
+		StubDomains []StubDomain `yaml:"stub_domains,omitempty" validate:"omitempty,dive"`
 	} `yaml:"dns,omitempty"`
 
 	DockerRegistries []DockerRegistry `yaml:"docker_registries" validate:"required,dive"`
@@ -135,15 +394,18 @@ type KeosSpec struct {
 
 	Keos Keos `yaml:"keos,omitempty"`
 
+	DR *DR `yaml:"dr,omitempty"`
+
 	ControlPlane ControlPlane `yaml:"control_plane" validate:"required,dive"`
 
-	WorkerNodes WorkerNodes `yaml:"worker_nodes" validate:"required,dive"`
+	WorkerNodes WorkerNodes `yaml:"worker_nodes" validate:"required_unless=Profile dev,dive"`
 
 	ClusterConfigRef ClusterConfigRef `yaml:"cluster_config_ref,omitempty" validate:"dive"`
 }
 
 type ControlPlane struct {
 	Managed         bool                `yaml:"managed" validate:"boolean"`
+	Flavor          string              `yaml:"flavor,omitempty" validate:"omitempty,oneof=kubeadm rke2 k3s"`
 	NodeImage       string              `yaml:"node_image,omitempty"`
 	HighlyAvailable *bool               `yaml:"highly_available,omitempty" validate:"boolean"`
 	Size            string              `yaml:"size,omitempty" validate:"required_if=Managed false"`
@@ -152,9 +414,42 @@ type ControlPlane struct {
 	AWS             AWSCP               `yaml:"aws,omitempty"`
 	Azure           AzureCP             `yaml:"azure,omitempty"`
 	Gcp             GCPCP               `yaml:"gcp,omitempty"`
-	CRIVolume       CustomVolume        `yaml:"cri_volume,omitempty"  validate:"dive"`
-	ETCDVolume      CustomVolume        `yaml:"etcd_volume,omitempty"  validate:"dive"`
-	ExtraVolumes    []ExtraVolume       `yaml:"extra_volumes,omitempty" validate:"dive"`
+	IBMCloud        IBMCloudCP          `yaml:"ibmcloud,omitempty"`
+	APIServer       APIServer           `yaml:"api_server,omitempty"`
+	// ServiceAccountIssuer is only supported in unmanaged clusters, managed ones have their own issuer
+	ServiceAccountIssuer *ServiceAccountIssuer `yaml:"service_account_issuer,omitempty"`
+	CRIVolume            CustomVolume          `yaml:"cri_volume,omitempty"  validate:"dive"`
+	ETCDVolume           CustomVolume          `yaml:"etcd_volume,omitempty"  validate:"dive"`
+	ExtraVolumes         []ExtraVolume         `yaml:"extra_volumes,omitempty" validate:"dive"`
+}
+
+// APIServer allows addressing the API server through a custom DNS name, and restricting the networks
+// it is reachable from
+type APIServer struct {
+	CertSANs     []string `yaml:"cert_sans,omitempty" validate:"omitempty,dive,required"`
+	DNSName      string   `yaml:"dns_name,omitempty" validate:"omitempty,fqdn"`
+	CreateRecord bool     `yaml:"create_record,omitempty" validate:"boolean"`
+	// HostedZone is the Route53 hosted zone ID, the Azure DNS zone or the Cloud DNS managed zone
+	HostedZone    string `yaml:"hosted_zone,omitempty" validate:"required_if=CreateRecord true"`
+	ResourceGroup string `yaml:"resource_group,omitempty"`
+	// AllowedCIDRs are the only networks the API server is reachable from: the EKS public access CIDRs,
+	// the AKS authorized IP ranges, the GKE master authorized networks or the security rules of the API
+	// server load balancer. They must include the network the cluster is provisioned from
+	AllowedCIDRs []string `yaml:"allowed_cidrs,omitempty" validate:"omitempty,dive,cidrv4"`
+}
+
+// ServiceAccountIssuer sets a stable issuer for the service account tokens, so the cloud IAM can
+// federate with the cluster (e.g. IAM roles for service accounts) through its discovery documents
+type ServiceAccountIssuer struct {
+	// URL is the public HTTPS endpoint serving the discovery documents, e.g. an S3 bucket
+	URL string `yaml:"url" validate:"required,url,startswith=https://"`
+	// Audiences accepted by the API server, the issuer URL if empty
+	Audiences []string `yaml:"audiences,omitempty" validate:"omitempty,dive,required"`
+	// MaxTokenExpiration caps the expiration of the projected service account tokens
+	MaxTokenExpiration string `yaml:"max_token_expiration,omitempty"`
+	// DiscoveryPath is the directory the discovery documents are written to after the creation, to be
+	// uploaded to the issuer URL. It defaults to <cluster name>-oidc
+	DiscoveryPath string `yaml:"discovery_path,omitempty"`
 }
 
 type GCPCP struct {
@@ -191,7 +486,10 @@ type CIDRBlock struct {
 }
 
 type Keos struct {
+	Version string `yaml:"version,omitempty" validate:"omitempty,semver"`
 	Flavour string `yaml:"flavour,omitempty"`
+	// VerifyHandoff checks the cluster meets the keos prerequisites once it is created
+	VerifyHandoff bool `yaml:"verify_handoff,omitempty"`
 }
 
 type Networks struct {
@@ -201,6 +499,8 @@ type Networks struct {
 	PodsSubnets   []Subnets `yaml:"pods_subnets,omitempty" validate:"dive"`
 	Subnets       []Subnets `yaml:"subnets,omitempty" validate:"dive"`
 	ResourceGroup string    `yaml:"resource_group,omitempty"`
+	// ClusterDomain is the DNS domain of the services, rendered in the kubeadm and kubelet config and CoreDNS
+	ClusterDomain string `yaml:"cluster_domain,omitempty" validate:"omitempty,hostname_rfc1123"`
 }
 
 type Subnets struct {
@@ -219,18 +519,32 @@ type AWSCP struct {
 		ControllerManager bool `yaml:"controller_manager" validate:"boolean"`
 		Scheduler         bool `yaml:"scheduler" validate:"boolean"`
 	} `yaml:"logging"`
+	// RemoveGP2StorageClass deletes the gp2 StorageClass EKS installs by default
+	RemoveGP2StorageClass bool `yaml:"remove_gp2_storage_class,omitempty" validate:"boolean"`
+	// CalicoCNI replaces the VPC CNI of EKS with Calico, removing the aws-node DaemonSet
+	CalicoCNI bool `yaml:"calico_cni,omitempty" validate:"boolean"`
 }
 
 type AzureCP struct {
 	Tier string `yaml:"tier" validate:"omitempty,oneof='Free' 'Paid'"`
 }
 
+type IBMCloudCP struct {
+	// +kubebuilder:default=vpc
+	Target            string `yaml:"target,omitempty" validate:"omitempty,oneof='vpc' 'powervs'"`
+	ServiceInstanceID string `yaml:"service_instance_id,omitempty" validate:"required_if=Target powervs"`
+}
+
 type Security struct {
-	ControlPlaneIdentity string `yaml:"control_plane_identity,omitempty"`
-	NodesIdentity        string `yaml:"nodes_identity,omitempty"`
-	AWS                  struct {
-		CreateIAM bool `yaml:"create_iam" validate:"boolean"`
-	} `yaml:"aws,omitempty"`
+	ControlPlaneIdentity string      `yaml:"control_plane_identity,omitempty"`
+	NodesIdentity        string      `yaml:"nodes_identity,omitempty"`
+	AWS                  AWSSecurity `yaml:"aws,omitempty"`
+}
+
+type AWSSecurity struct {
+	CreateIAM bool `yaml:"create_iam" validate:"boolean"`
+	// Name of the CloudFormation stack of the IAM resources, the one of clusterawsadm when unset
+	IAMStackName string `yaml:"iam_stack_name,omitempty"`
 }
 
 type WorkerNodes []struct {
@@ -242,6 +556,7 @@ type WorkerNodes []struct {
 	AZ               string            `yaml:"az,omitempty"`
 	SSHKey           string            `yaml:"ssh_key,omitempty"`
 	Spot             bool              `yaml:"spot,omitempty" validate:"boolean"`
+	MachinePool      bool              `yaml:"machine_pool,omitempty" validate:"boolean"`
 	Labels           map[string]string `yaml:"labels,omitempty"`
 	Taints           []string          `yaml:"taints,omitempty"`
 	NodeGroupMaxSize int               `yaml:"max_size,omitempty" validate:"omitempty,required_with=NodeGroupMinSize,numeric"`
@@ -249,6 +564,60 @@ type WorkerNodes []struct {
 	RootVolume       RootVolume        `yaml:"root_volume,omitempty"`
 	CRIVolume        CustomVolume      `yaml:"cri_volume,omitempty"  validate:"dive"`
 	ExtraVolumes     []ExtraVolume     `yaml:"extra_volumes,omitempty" validate:"dive"`
+	NodeSecurity     *NodeSecurity     `yaml:"node_security,omitempty"`
+	GPU              *GPU              `yaml:"gpu,omitempty"`
+	Infra            bool              `yaml:"infra,omitempty" validate:"boolean"`
+}
+
+// GPU installs the NVIDIA GPU Operator, sharing the GPUs of the node group with time-slicing or MIG
+type GPU struct {
+	Sharing string `yaml:"sharing,omitempty" validate:"omitempty,oneof='time-slicing' 'mig'"`
+	// Replicas is the number of pods sharing each GPU with time-slicing
+	Replicas int `yaml:"replicas,omitempty" validate:"required_if=Sharing time-slicing,omitempty,gte=2"`
+	// MIGProfile is the mig-parted profile of the nodes (e.g. all-1g.5gb)
+	MIGProfile string `yaml:"mig_profile,omitempty" validate:"required_if=Sharing mig"`
+}
+
+// NodeSecurity sets the kernel and security settings of the nodes of a node group
+type NodeSecurity struct {
+	SELinux       string            `yaml:"selinux,omitempty" validate:"omitempty,oneof='enforcing' 'permissive' 'disabled'"`
+	Sysctls       map[string]string `yaml:"sysctls,omitempty" validate:"omitempty,dive,keys,required,endkeys,required"`
+	KernelModules []string          `yaml:"kernel_modules,omitempty" validate:"omitempty,dive,required"`
+}
+
+// DR represents the disaster recovery cluster paired with the one in the descriptor
+type DR struct {
+	Name           string        `yaml:"name,omitempty" validate:"omitempty,min=3,max=100"`
+	Region         string        `yaml:"region" validate:"required"`
+	ExternalDomain string        `yaml:"external_domain,omitempty" validate:"omitempty,fqdn"`
+	NodeImage      string        `yaml:"node_image,omitempty"`
+	Networks       Networks      `yaml:"networks,omitempty"`
+	Velero         DRVelero      `yaml:"velero,omitempty"`
+	DNSFailover    DRDNSFailover `yaml:"dns_failover,omitempty"`
+	// Interconnect joins the DR cluster to the Submariner broker of the interconnect of the cluster
+	Interconnect DRInterconnect `yaml:"interconnect,omitempty"`
+	// Secondary is only set in the descriptor derived for the DR cluster
+	Secondary bool `yaml:"-"`
+}
+
+// DRInterconnect sets the settings of the DR cluster which must differ from the ones of the cluster
+// in the interconnect
+type DRInterconnect struct {
+	// GlobalCIDR is the range the services exported by the DR cluster are given global IPs from
+	GlobalCIDR string `yaml:"global_cidr,omitempty" validate:"omitempty,cidrv4"`
+}
+
+type DRVelero struct {
+	Enabled  bool   `yaml:"enabled" validate:"boolean"`
+	Bucket   string `yaml:"bucket,omitempty" validate:"required_if=Enabled true"`
+	DRBucket string `yaml:"dr_bucket,omitempty" validate:"required_if=Enabled true"`
+}
+
+type DRDNSFailover struct {
+	Enabled         bool   `yaml:"enabled" validate:"boolean"`
+	Hostname        string `yaml:"hostname,omitempty" validate:"required_if=Enabled true"`
+	HealthCheckPath string `yaml:"health_check_path,omitempty"`
+	TTL             int    `yaml:"ttl,omitempty" validate:"omitempty,numeric,gte=1"`
 }
 
 // Bastion represents the bastion VM
@@ -307,12 +676,20 @@ type ClusterCredentials struct {
 	DockerRegistriesCredentials []map[string]interface{}
 	HelmRepositoryCredentials   map[string]string
 	GithubToken                 string
+	// References to cloud secret managers of the resolved credentials, by section and field
+	SecretReferences map[string]string
 }
 
 type Credentials struct {
-	AWS              AWSCredentials              `yaml:"aws" validate:"excluded_with=AZURE GCP"`
-	AZURE            AzureCredentials            `yaml:"azure" validate:"excluded_with=AWS GCP"`
-	GCP              GCPCredentials              `yaml:"gcp" validate:"excluded_with=AWS AZURE"`
+	AWS              AWSCredentials              `yaml:"aws" validate:"excluded_with=AZURE GCP EQUINIX HETZNER NUTANIX DIGITALOCEAN IBMCLOUD ALIBABACLOUD"`
+	AZURE            AzureCredentials            `yaml:"azure" validate:"excluded_with=AWS GCP EQUINIX HETZNER NUTANIX DIGITALOCEAN IBMCLOUD ALIBABACLOUD"`
+	GCP              GCPCredentials              `yaml:"gcp" validate:"excluded_with=AWS AZURE EQUINIX HETZNER NUTANIX DIGITALOCEAN IBMCLOUD ALIBABACLOUD"`
+	EQUINIX          EquinixCredentials          `yaml:"equinix" validate:"excluded_with=AWS AZURE GCP HETZNER NUTANIX DIGITALOCEAN IBMCLOUD ALIBABACLOUD"`
+	HETZNER          HetznerCredentials          `yaml:"hetzner" validate:"excluded_with=AWS AZURE GCP EQUINIX NUTANIX DIGITALOCEAN IBMCLOUD ALIBABACLOUD"`
+	NUTANIX          NutanixCredentials          `yaml:"nutanix" validate:"excluded_with=AWS AZURE GCP EQUINIX HETZNER DIGITALOCEAN IBMCLOUD ALIBABACLOUD"`
+	DIGITALOCEAN     DigitalOceanCredentials     `yaml:"digitalocean" validate:"excluded_with=AWS AZURE GCP EQUINIX HETZNER NUTANIX IBMCLOUD ALIBABACLOUD"`
+	IBMCLOUD         IBMCloudCredentials         `yaml:"ibmcloud" validate:"excluded_with=AWS AZURE GCP EQUINIX HETZNER NUTANIX DIGITALOCEAN ALIBABACLOUD"`
+	ALIBABACLOUD     AlibabaCloudCredentials     `yaml:"alibabacloud" validate:"excluded_with=AWS AZURE GCP EQUINIX HETZNER NUTANIX DIGITALOCEAN IBMCLOUD"`
 	GithubToken      string                      `yaml:"github_token"`
 	DockerRegistries []DockerRegistryCredentials `yaml:"docker_registries"`
 	HelmRepository   HelmRepositoryCredentials   `yaml:"helm_repository"`
@@ -321,8 +698,17 @@ type Credentials struct {
 type AWSCredentials struct {
 	AccessKey string `yaml:"access_key"`
 	SecretKey string `yaml:"secret_key"`
-	Region    string `yaml:"region"`
-	AccountID string `yaml:"account_id"`
+	// Session token of temporary credentials, as the ones of SSO or an assumed role
+	SessionToken string `yaml:"session_token,omitempty"`
+	// Role assumed with the keys, which the providers renew while they run
+	RoleARN    string `yaml:"role_arn,omitempty" validate:"omitempty,startswith=arn:aws"`
+	ExternalID string `yaml:"external_id,omitempty" validate:"excluded_without=RoleARN"`
+	Region     string `yaml:"region"`
+	AccountID  string `yaml:"account_id"`
+	// Roles assumed in the accounts of a landing zone, the credentials account is used when unset
+	ClusterRoleARN string `yaml:"cluster_role_arn,omitempty" validate:"omitempty,startswith=arn:aws"`
+	NetworkRoleARN string `yaml:"network_role_arn,omitempty" validate:"omitempty,startswith=arn:aws"`
+	IAMRoleARN     string `yaml:"iam_role_arn,omitempty" validate:"omitempty,startswith=arn:aws"`
 }
 
 type AzureCredentials struct {
@@ -330,6 +716,8 @@ type AzureCredentials struct {
 	TenantID       string `yaml:"tenant_id"`
 	ClientID       string `yaml:"client_id"`
 	ClientSecret   string `yaml:"client_secret"`
+	// Subscription of the network resources, the cluster subscription is used when unset
+	NetworkSubscriptionID string `yaml:"network_subscription_id,omitempty"`
 }
 
 type GCPCredentials struct {
@@ -338,6 +726,36 @@ type GCPCredentials struct {
 	PrivateKey   string `yaml:"private_key"`
 	ClientEmail  string `yaml:"client_email"`
 	ClientID     string `yaml:"client_id"`
+	// Host project of the network, the cluster project is used when unset
+	NetworkProjectID string `yaml:"network_project_id,omitempty"`
+}
+
+type EquinixCredentials struct {
+	ApiKey    string `yaml:"api_key"`
+	ProjectID string `yaml:"project_id"`
+}
+
+type HetznerCredentials struct {
+	Token string `yaml:"token"`
+}
+
+type NutanixCredentials struct {
+	Endpoint string `yaml:"endpoint"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+type DigitalOceanCredentials struct {
+	Token string `yaml:"token"`
+}
+
+type IBMCloudCredentials struct {
+	ApiKey string `yaml:"api_key"`
+}
+
+type AlibabaCloudCredentials struct {
+	AccessKeyID     string `yaml:"access_key_id"`
+	AccessKeySecret string `yaml:"access_key_secret"`
 }
 
 type DockerRegistryCredentials struct {
@@ -381,6 +799,30 @@ type GCP struct {
 	Credentials GCPCredentials `yaml:"credentials"`
 }
 
+type EQUINIX struct {
+	Credentials EquinixCredentials `yaml:"credentials"`
+}
+
+type HETZNER struct {
+	Credentials HetznerCredentials `yaml:"credentials"`
+}
+
+type NUTANIX struct {
+	Credentials NutanixCredentials `yaml:"credentials"`
+}
+
+type DIGITALOCEAN struct {
+	Credentials DigitalOceanCredentials `yaml:"credentials"`
+}
+
+type IBMCLOUD struct {
+	Credentials IBMCloudCredentials `yaml:"credentials"`
+}
+
+type ALIBABACLOUD struct {
+	Credentials AlibabaCloudCredentials `yaml:"credentials"`
+}
+
 type SecretsFile struct {
 	Secrets Secrets `yaml:"secrets"`
 }
@@ -389,6 +831,12 @@ type Secrets struct {
 	AWS              AWS                         `yaml:"aws"`
 	AZURE            AZURE                       `yaml:"azure"`
 	GCP              GCP                         `yaml:"gcp"`
+	EQUINIX          EQUINIX                     `yaml:"equinix"`
+	HETZNER          HETZNER                     `yaml:"hetzner"`
+	NUTANIX          NUTANIX                     `yaml:"nutanix"`
+	DIGITALOCEAN     DIGITALOCEAN                `yaml:"digitalocean"`
+	IBMCLOUD         IBMCLOUD                    `yaml:"ibmcloud"`
+	ALIBABACLOUD     ALIBABACLOUD                `yaml:"alibabacloud"`
 	GithubToken      string                      `yaml:"github_token"`
 	DockerRegistry   DockerRegistryCredentials   `yaml:"docker_registry"`
 	DockerRegistries []DockerRegistryCredentials `yaml:"docker_registries"`
@@ -402,6 +850,10 @@ type EFS struct {
 }
 
 type StorageClass struct {
+	// Name is the name of the StorageClass, keos by default
+	Name string `yaml:"name,omitempty"`
+	// Default makes it the default StorageClass, removing the annotation from any other one
+	Default       *bool        `yaml:"default,omitempty"`
 	EFS           EFS          `yaml:"efs,omitempty"`
 	EncryptionKey string       `yaml:"encryptionKey,omitempty"`
 	Class         string       `yaml:"class,omitempty" validate:"omitempty,oneof='standard' 'premium'"`
@@ -445,6 +897,18 @@ type SCParameters struct {
 	ProvisionedIopsOnCreate       string `yaml:"provisioned-iops-on-create,omitempty"`
 	ProvisionedThroughputOnCreate string `yaml:"provisioned-throughput-on-create,omitempty"`
 	ReplicationType               string `yaml:"replication-type,omitempty"`
+
+	// Nutanix
+	StorageContainer string `yaml:"storageContainer,omitempty"`
+	StorageType      string `yaml:"storageType,omitempty" validate:"omitempty,oneof='NutanixVolumes'"`
+	FlashMode        string `yaml:"flashMode,omitempty" validate:"omitempty,oneof='ENABLED' 'DISABLED'"`
+
+	// IBM Cloud
+	Profile       string `yaml:"profile,omitempty"`
+	EncryptionKey string `yaml:"encryptionKey,omitempty"`
+
+	// Alibaba Cloud
+	PerformanceLevel string `yaml:"performanceLevel,omitempty" validate:"omitempty,oneof='PL0' 'PL1' 'PL2' 'PL3'"`
 }
 
 func (s ClusterConfigSpec) Init() ClusterConfigSpec {
@@ -486,6 +950,19 @@ func (s KeosSpec) Init() KeosSpec {
 	// Managed zones
 	s.Dns.ManageZone = true
 
+	// Networks
+	s.Networks.ClusterDomain = DefaultClusterDomain
+
+	return s
+}
+
+// InitDevProfile sets the default values of the dev profile, which the descriptor overrides
+func (s KeosSpec) InitDevProfile() KeosSpec {
+	s.ControlPlane.HighlyAvailable = ToPtr(false)
+	s.ControlPlane.Size = DevProfileSizes[s.InfraProvider]
+	s.ControlPlane.CRIVolume.Enabled = ToPtr(false)
+	s.ControlPlane.ETCDVolume.Enabled = ToPtr(false)
+	s.DeployAutoscaler = false
 	return s
 }
 
@@ -605,14 +1082,28 @@ func GetClusterDescriptor(descriptorPath string) (*KeosCluster, *ClusterConfig,
 		return nil, nil, err
 	}
 
+	localConfig, err := GetLocalConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
 	validate := validator.New()
 	validate.RegisterValidation("gte_param_if_exists", gteParamIfExists)
 	validate.RegisterValidation("lte_param_if_exists", lteParamIfExists)
 	validate.RegisterValidation("required_if_for_bool", requiredIfForBool)
 
-	descriptorManifests := strings.Split(string(descriptorRAW), "---\n")
+	descriptor, err := ExpandEnvVars(string(descriptorRAW))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	descriptorManifests := descriptorSeparator.Split(descriptor, -1)
 	for _, manifest := range descriptorManifests {
 		var resource Resource
+		manifest, err = extendManifest(manifest, filepath.Dir(descriptorPath), []string{descriptorPath})
+		if err != nil {
+			return nil, nil, err
+		}
 		err = yaml.Unmarshal([]byte(manifest), &resource)
 		if err != nil {
 			return nil, nil, err
@@ -625,11 +1116,18 @@ func GetClusterDescriptor(descriptorPath string) (*KeosCluster, *ClusterConfig,
 
 			switch resource.Kind {
 			case "KeosCluster":
-				keosCluster.Spec = new(KeosSpec).Init()
+				keosCluster.Spec = localConfig.SetDefaults(new(KeosSpec).Init())
 				err = yaml.Unmarshal([]byte(manifest), &keosCluster)
 				if err != nil {
 					return nil, nil, err
 				}
+				if keosCluster.Spec.Profile == DevProfile {
+					keosCluster.Spec = keosCluster.Spec.InitDevProfile()
+					err = yaml.Unmarshal([]byte(manifest), &keosCluster)
+					if err != nil {
+						return nil, nil, err
+					}
+				}
 				keosCluster.Spec = keosCluster.Spec.InitVolumes()
 				err = validate.Struct(keosCluster)
 				if err != nil {
@@ -637,6 +1135,9 @@ func GetClusterDescriptor(descriptorPath string) (*KeosCluster, *ClusterConfig,
 				}
 
 				keosCluster.Metadata.Namespace = "cluster-" + keosCluster.Metadata.Name
+				if keosCluster.Spec.DR != nil && keosCluster.Spec.DR.Name == "" {
+					keosCluster.Spec.DR.Name = keosCluster.Metadata.Name + "-dr"
+				}
 			case "ClusterConfig":
 				findClusterConfig = true
 				clusterConfig.Spec = new(ClusterConfigSpec).Init()
@@ -674,6 +1175,62 @@ func GetClusterDescriptor(descriptorPath string) (*KeosCluster, *ClusterConfig,
 	return &keosCluster, &clusterConfig, nil
 }
 
+// GetDRClusterDescriptor derives the descriptor of the DR cluster from the "dr" block of keosCluster
+func GetDRClusterDescriptor(keosCluster KeosCluster, clusterConfig *ClusterConfig) (*KeosCluster, *ClusterConfig) {
+	dr := *keosCluster.Spec.DR
+
+	drCluster := keosCluster
+	drCluster.Metadata.Name = dr.Name
+	drCluster.Metadata.Namespace = "cluster-" + dr.Name
+	drCluster.Spec.Region = dr.Region
+	drCluster.Spec.Networks = dr.Networks
+	if dr.ExternalDomain != "" {
+		drCluster.Spec.ExternalDomain = dr.ExternalDomain
+	}
+	if dr.NodeImage != "" {
+		drCluster.Spec.ControlPlane.NodeImage = dr.NodeImage
+	}
+
+	// Availability zones are bound to the region of the cluster
+	drCluster.Spec.WorkerNodes = make(WorkerNodes, len(keosCluster.Spec.WorkerNodes))
+	copy(drCluster.Spec.WorkerNodes, keosCluster.Spec.WorkerNodes)
+	for i := range drCluster.Spec.WorkerNodes {
+		drCluster.Spec.WorkerNodes[i].AZ = ""
+		if dr.NodeImage != "" {
+			drCluster.Spec.WorkerNodes[i].NodeImage = dr.NodeImage
+		}
+	}
+
+	// The DR cluster points back to the primary one
+	drCluster.Spec.DR = &DR{
+		Name:           keosCluster.Metadata.Name,
+		Region:         keosCluster.Spec.Region,
+		ExternalDomain: keosCluster.Spec.ExternalDomain,
+		Velero: DRVelero{
+			Enabled:  dr.Velero.Enabled,
+			Bucket:   dr.Velero.DRBucket,
+			DRBucket: dr.Velero.Bucket,
+		},
+		DNSFailover: dr.DNSFailover,
+		Secondary:   true,
+	}
+
+	drClusterConfig := *clusterConfig
+	drClusterConfig.Metadata.Namespace = drCluster.Metadata.Namespace
+
+	// The DR cluster joins the broker the cluster hosts or joins, which is deployed before the DR
+	// cluster is created, so the services of both clusters are connected across the regions
+	if interconnect := clusterConfig.Spec.Interconnect; interconnect != nil {
+		drInterconnect := *interconnect
+		drInterconnect.Broker = false
+		drInterconnect.ClusterID = ""
+		drInterconnect.GlobalCIDR = dr.Interconnect.GlobalCIDR
+		drClusterConfig.Spec.Interconnect = &drInterconnect
+	}
+
+	return &drCluster, &drClusterConfig
+}
+
 func DecryptFile(filePath string, vaultPassword string) (string, error) {
 	data, err := vault.DecryptFile(filePath, vaultPassword)
 
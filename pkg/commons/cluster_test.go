@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commons
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const fuzzKeosCluster = `apiVersion: installer.stratio.com/v1beta1
+kind: KeosCluster
+metadata:
+  name: fuzz
+spec:
+  infra_provider: aws
+  k8s_version: v1.30.4
+  region: eu-west-1
+  docker_registries:
+    - url: registry.example.com/keos
+      type: generic
+      keos_registry: true
+  helm_repository:
+    url: https://charts.example.com
+  external_domain: fuzz.example.com
+  control_plane:
+    managed: false
+    size: m6i.large
+  worker_nodes:
+    - name: workers
+      quantity: 3
+      size: m6i.xlarge
+`
+
+const fuzzClusterConfig = `apiVersion: installer.stratio.com/v1beta1
+kind: ClusterConfig
+metadata:
+  name: fuzz-config
+spec:
+  private_registry: true
+  workers_config:
+    max_unhealthy: 34
+`
+
+// FuzzGetClusterDescriptor parses arbitrary descriptors, which must be either rejected with an
+// error or parsed into a complete KeosCluster and ClusterConfig, and never panic
+func FuzzGetClusterDescriptor(f *testing.F) {
+	f.Add(fuzzKeosCluster)
+	f.Add(fuzzKeosCluster + "---\n" + fuzzClusterConfig)
+	f.Add(strings.Replace(fuzzKeosCluster, "infra_provider: aws", "infra_provider: gcp", 1))
+	f.Add(strings.Replace(fuzzKeosCluster, "managed: false", "managed: true", 1))
+	f.Add(strings.Replace(fuzzKeosCluster, "  region: eu-west-1\n", "  region: eu-west-1\n  profile: dev\n", 1))
+	f.Add(strings.Replace(fuzzKeosCluster, "  region: eu-west-1\n", "  region: eu-west-1\n  dr:\n    region: eu-central-1\n", 1))
+	f.Add(strings.Replace(fuzzKeosCluster, "eu-west-1", "${FUZZ_REGION:-eu-west-1}", 1))
+	f.Add(strings.Replace(fuzzKeosCluster, "quantity: 3", "quantity: -1", 1))
+	f.Add("extends: base.yaml\nkind: KeosCluster\n")
+	f.Add("---\n---\n")
+	f.Add("kind: [")
+
+	// The descriptors are not completed with the local config of the user
+	f.Setenv("HOME", f.TempDir())
+	f.Fuzz(func(t *testing.T, descriptor string) {
+		descriptorPath := filepath.Join(t.TempDir(), "cluster.yaml")
+		if err := os.WriteFile(descriptorPath, []byte(descriptor), 0644); err != nil {
+			t.Fatal(err)
+		}
+		keosCluster, clusterConfig, err := GetClusterDescriptor(descriptorPath)
+		if err != nil {
+			return
+		}
+		if keosCluster == nil || clusterConfig == nil {
+			t.Fatalf("no error returned for an incomplete descriptor")
+		}
+		if keosCluster.Metadata.Namespace != "cluster-"+keosCluster.Metadata.Name {
+			t.Errorf("the namespace of the cluster is %q", keosCluster.Metadata.Namespace)
+		}
+		if clusterConfig.Metadata.Namespace != keosCluster.Metadata.Namespace {
+			t.Errorf("the ClusterConfig namespace %q differs from the cluster one", clusterConfig.Metadata.Namespace)
+		}
+		if keosCluster.Spec.ControlPlane.HighlyAvailable == nil {
+			t.Errorf("the control plane defaults are not set")
+		}
+		for _, wn := range keosCluster.Spec.WorkerNodes {
+			if wn.Quantity == nil || *wn.Quantity < 0 {
+				t.Errorf("the worker nodes %q have an invalid quantity", wn.Name)
+			}
+		}
+		if keosCluster.Spec.DR != nil && keosCluster.Spec.DR.Name == "" {
+			t.Errorf("the DR cluster has no name")
+		}
+	})
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commons
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+// FindClusterCollisions returns the resources of the provider which belong to a cluster with the
+// name of the descriptor, as the creation of another one would fail half-way or take them over
+func FindClusterCollisions(keosCluster KeosCluster, credentials map[string]string) ([]string, error) {
+	switch keosCluster.Spec.InfraProvider {
+	case "aws":
+		return findAWSCollisions(keosCluster.Metadata.Name, keosCluster.Spec.Region, credentials)
+	case "azure":
+		return findAzureCollisions(keosCluster.Metadata.Name, credentials)
+	case "gcp":
+		return findGCPCollisions(keosCluster.Metadata.Name, keosCluster.Spec.Region, credentials)
+	}
+	return nil, nil
+}
+
+// isNotFound returns whether the request failed as the resource does not exist
+func isNotFound(err error) bool {
+	return strings.Contains(err.Error(), "request failed: 404")
+}
+
+func findAWSCollisions(clusterName string, region string, credentials map[string]string) ([]string, error) {
+	var ctx = context.Background()
+	var collisions []string
+
+	client, err := NewAWSClient(ctx, credentials, region, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// The EKS clusters are unique by name in the region, whoever created them
+	req, err := http.NewRequest(http.MethodGet, client.Endpoint("eks")+"/clusters/"+url.PathEscape(clusterName), nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = client.Do(ctx, "eks", req); err == nil {
+		collisions = append(collisions, "EKS cluster "+clusterName)
+	} else if !isNotFound(err) {
+		return nil, errors.Wrap(err, "failed to describe the EKS cluster")
+	}
+
+	for _, tagKey := range []string{"sigs.k8s.io/cluster-api-provider-aws/cluster/" + clusterName, "kubernetes.io/cluster/" + clusterName} {
+		var response struct {
+			PaginationToken        string
+			ResourceTagMappingList []struct {
+				ResourceARN string
+			}
+		}
+		for {
+			request := map[string]interface{}{
+				"TagFilters": []map[string]interface{}{{"Key": tagKey, "Values": []string{"owned"}}},
+			}
+			if response.PaginationToken != "" {
+				request["PaginationToken"] = response.PaginationToken
+			}
+			body, err := json.Marshal(request)
+			if err != nil {
+				return nil, err
+			}
+			resp, err := client.Post(ctx, "tagging", "application/x-amz-json-1.1", "ResourceGroupsTaggingAPI_20170126.GetResources", string(body))
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to get the resources of the cluster")
+			}
+			response.PaginationToken = ""
+			if err = json.Unmarshal(resp, &response); err != nil {
+				return nil, err
+			}
+			for _, resource := range response.ResourceTagMappingList {
+				if !Contains(collisions, resource.ResourceARN) {
+					collisions = append(collisions, resource.ResourceARN)
+				}
+			}
+			if response.PaginationToken == "" {
+				break
+			}
+		}
+	}
+	return collisions, nil
+}
+
+func findAzureCollisions(clusterName string, credentials map[string]string) ([]string, error) {
+	var ctx = context.Background()
+	var collisions []string
+	var resources struct {
+		Value []struct {
+			ID string `json:"id"`
+		} `json:"value"`
+	}
+
+	cfg, err := AzureGetConfig(credentials)
+	if err != nil {
+		return nil, err
+	}
+	token, err := cfg.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"https://management.azure.com/.default"}})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get Azure token")
+	}
+
+	// The resource group named after the cluster may be the one of a custom network, so only the
+	// resources tagged for the cluster are looked for
+	subscriptionURL := "https://management.azure.com/subscriptions/" + credentials["SubscriptionID"]
+	tagFilters := []string{
+		"tagName eq 'sigs.k8s.io_cluster-api-provider-azure_cluster_" + clusterName + "' and tagValue eq 'owned'",
+		"tagName eq 'kubernetes-cluster-name' and tagValue eq '" + clusterName + "'",
+	}
+	for _, filter := range tagFilters {
+		req, err := http.NewRequest(http.MethodGet, subscriptionURL+"/resources?api-version=2021-04-01&$filter="+url.QueryEscape(filter), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token.Token)
+		resp, err := DoCloudRequest(req)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list the resources of the cluster")
+		}
+		if err = json.Unmarshal(resp, &resources); err != nil {
+			return nil, err
+		}
+		for _, resource := range resources.Value {
+			if !Contains(collisions, resource.ID) {
+				collisions = append(collisions, resource.ID)
+			}
+		}
+	}
+	return collisions, nil
+}
+
+func findGCPCollisions(clusterName string, region string, credentials map[string]string) ([]string, error) {
+	var ctx = context.Background()
+	var collisions []string
+	project := credentials["ProjectID"]
+
+	creds, err := google.CredentialsFromJSON(ctx, gcpCredentialsJSON(credentials), "https://www.googleapis.com/auth/cloud-platform")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse the GCP credentials")
+	}
+	token, err := creds.TokenSource.Token()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get GCP token")
+	}
+
+	// The GKE clusters are unique by name in the location, whoever created them
+	clusterURL := "https://container.googleapis.com/v1/projects/" + project + "/locations/" + region + "/clusters/" + clusterName
+	req, err := http.NewRequest(http.MethodGet, clusterURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	if _, err = DoCloudRequest(req); err == nil {
+		collisions = append(collisions, "GKE cluster "+clusterName)
+	} else if !isNotFound(err) {
+		return nil, errors.Wrap(err, "failed to get the GKE cluster")
+	}
+
+	computeService, err := compute.NewService(ctx, option.WithCredentialsJSON(gcpCredentialsJSON(credentials)))
+	if err != nil {
+		return nil, err
+	}
+	filter := "labels.capg-cluster-" + clusterName + "=owned"
+	instances, err := computeService.Instances.AggregatedList(project).Filter(filter).Context(ctx).Do()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the instances")
+	}
+	for _, scoped := range instances.Items {
+		for _, instance := range scoped.Instances {
+			collisions = append(collisions, "instance "+instance.Name)
+		}
+	}
+	forwardingRules, err := computeService.ForwardingRules.List(project, region).Filter(filter).Context(ctx).Do()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the forwarding rules")
+	}
+	for _, rule := range forwardingRules.Items {
+		collisions = append(collisions, "forwarding rule "+rule.Name)
+	}
+	return collisions, nil
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commons
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+// MaxCommandOutputSize is the size of the tail of the output kept from each command, so that the
+// commands printing tens of MB, such as some clusterctl and helm invocations, do not balloon the memory
+const MaxCommandOutputSize = 8 << 20
+
+// commandOutputLevel is the verbosity of the streamed output of the commands
+const commandOutputLevel log.Level = 3
+
+// lineOverlap is the tail of each chunk of a line without breaks which is inspected again along with
+// the next chunk, so that the conditions split by the chunks are found
+const lineOverlap = 4 << 10
+
+// redactedSecret replaces the secrets in the streamed output of the commands
+const redactedSecret = "[REDACTED]"
+
+// readsSecrets matches the commands whose output is the content of the secrets, which is not streamed
+var readsSecrets = regexp.MustCompile(`\bget\s+secrets?\b`)
+
+var (
+	commandLogger   log.Logger = log.NoopLogger{}
+	commandSecrets  []string
+	commandLoggerMu sync.RWMutex
+)
+
+// SetCommandLogger sets the logger which the output lines of the commands are streamed to, with
+// their timestamp, so that they are interleaved with the progress of the steps
+func SetCommandLogger(logger log.Logger) {
+	commandLoggerMu.Lock()
+	defer commandLoggerMu.Unlock()
+	commandLogger = logger
+}
+
+func getCommandLogger() log.Logger {
+	commandLoggerMu.RLock()
+	defer commandLoggerMu.RUnlock()
+	return commandLogger
+}
+
+// SetCommandSecrets sets the secrets which are redacted from the streamed output of the commands,
+// and from the output in their errors
+func SetCommandSecrets(secrets ...string) {
+	commandLoggerMu.Lock()
+	defer commandLoggerMu.Unlock()
+	commandSecrets = nil
+	for _, secret := range secrets {
+		if secret != "" {
+			commandSecrets = append(commandSecrets, secret)
+		}
+	}
+	// The longest secrets are redacted first, so the secrets which contain others are redacted whole
+	sort.Slice(commandSecrets, func(i, j int) bool { return len(commandSecrets[i]) > len(commandSecrets[j]) })
+}
+
+// GetCredentialsSecrets returns the values of the credentials whose field names a secret, such as
+// a password, a token or a key
+func GetCredentialsSecrets(credentials ClusterCredentials) []string {
+	secrets := []string{credentials.GithubToken}
+	isSecret := func(field string) bool {
+		field = strings.ToLower(field)
+		return strings.Contains(field, "secret") || strings.Contains(field, "pass") ||
+			strings.Contains(field, "token") || strings.Contains(field, "key")
+	}
+	maps := []map[string]string{credentials.ProviderCredentials, credentials.KeosRegistryCredentials, credentials.HelmRepositoryCredentials}
+	for _, registry := range credentials.DockerRegistriesCredentials {
+		fields := map[string]string{}
+		for field, value := range registry {
+			if value, ok := value.(string); ok {
+				fields[field] = value
+			}
+		}
+		maps = append(maps, fields)
+	}
+	for _, fields := range maps {
+		for field, value := range fields {
+			if isSecret(field) {
+				secrets = append(secrets, value)
+			}
+		}
+	}
+	return secrets
+}
+
+func redactSecrets(output string) string {
+	commandLoggerMu.RLock()
+	defer commandLoggerMu.RUnlock()
+	for _, secret := range commandSecrets {
+		output = strings.ReplaceAll(output, secret, redactedSecret)
+	}
+	return output
+}
+
+// commandOutput is the writer of the output of a command. It keeps the tail of the output, up to
+// its limit, and inspects and streams the output line by line as it is written, so that the
+// conditions are found in the whole output
+type commandOutput struct {
+	limit int
+	// tail grows up to the limit, and is then overwritten as a ring whose oldest byte is at start
+	tail      []byte
+	start     int
+	truncated int
+	line      []byte
+	// logged is the size of the start of the line which was already streamed along with the
+	// previous chunk
+	logged   int
+	patterns []*regexp.Regexp
+	matched  bool
+	failed   bool
+	logger   log.InfoLogger
+}
+
+func newCommandOutput(limit int, patterns []*regexp.Regexp) *commandOutput {
+	return &commandOutput{
+		limit:    limit,
+		patterns: patterns,
+		logger:   getCommandLogger().V(commandOutputLevel),
+	}
+}
+
+func (o *commandOutput) Write(p []byte) (int, error) {
+	o.keep(p)
+
+	o.line = append(o.line, p...)
+	for {
+		i := bytes.IndexByte(o.line, '\n')
+		if i < 0 {
+			break
+		}
+		o.inspect(string(o.line[:i]))
+		o.line = o.line[i+1:]
+		o.logged = 0
+	}
+	// A line without breaks is inspected in chunks, which overlap so that the conditions are not
+	// missed at their boundaries
+	if len(o.line) > o.limit {
+		o.inspect(string(o.line))
+		overlap := lineOverlap
+		if overlap > o.limit/2 {
+			overlap = o.limit / 2
+		}
+		o.line = append([]byte(nil), o.line[len(o.line)-overlap:]...)
+		o.logged = overlap
+	}
+	return len(p), nil
+}
+
+// keep adds the bytes to the tail, overwriting the oldest ones once it is full, so each write costs
+// its own size whatever the size of the tail
+func (o *commandOutput) keep(p []byte) {
+	if len(p) >= o.limit {
+		o.truncated += len(o.tail) + len(p) - o.limit
+		o.tail = append(o.tail[:0], p[len(p)-o.limit:]...)
+		o.start = 0
+		return
+	}
+	if free := o.limit - len(o.tail); free > 0 {
+		if free > len(p) {
+			free = len(p)
+		}
+		o.tail = append(o.tail, p[:free]...)
+		p = p[free:]
+	}
+	o.truncated += len(p)
+	for len(p) > 0 {
+		n := copy(o.tail[o.start:], p)
+		p = p[n:]
+		o.start = (o.start + n) % o.limit
+	}
+}
+
+func (o *commandOutput) inspect(line string) {
+	for _, pattern := range o.patterns {
+		if pattern.MatchString(line) {
+			o.matched = true
+		}
+	}
+	if strings.Contains(line, "Error:") || strings.Contains(line, "Error from server") {
+		o.failed = true
+	}
+	if o.logger.Enabled() && len(line) > o.logged {
+		o.logger.Info(time.Now().Format("15:04:05.000") + " " + redactSecrets(line[o.logged:]))
+	}
+}
+
+// flush inspects the last line, once the command has finished
+func (o *commandOutput) flush() {
+	if len(o.line) > o.logged {
+		o.inspect(string(o.line))
+	}
+	o.line = nil
+	o.logged = 0
+	if o.truncated > 0 && o.logger.Enabled() {
+		o.logger.Info(time.Now().Format("15:04:05.000") + " [" + strconv.Itoa(o.truncated) + " bytes of the output truncated]")
+	}
+}
+
+// String returns the tail of the output, whose start is lost once the output exceeds the limit
+func (o *commandOutput) String() string {
+	return string(o.tail[o.start:]) + string(o.tail[:o.start])
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commons
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCommandOutput(t *testing.T) {
+	const limit = 64
+	tests := []struct {
+		name    string
+		writes  []string
+		matched bool
+		failed  bool
+	}{
+		{
+			name:   "lines",
+			writes: []string{"first line\nsecond ", "line\n"},
+		},
+		{
+			name:   "error in a line",
+			writes: []string{"first line\n", "Error: failed\n"},
+			failed: true,
+		},
+		{
+			name:    "condition split by the writes",
+			writes:  []string{"the request timed ", "out waiting for the condition\n"},
+			matched: true,
+		},
+		{
+			name:   "error split by the chunks of a long line",
+			writes: []string{strings.Repeat("x", limit) + "Err", "or: failed" + strings.Repeat("x", limit)},
+			failed: true,
+		},
+		{
+			name:    "condition split by the chunks of a long line",
+			writes:  []string{strings.Repeat("x", limit-10) + "timed out wai", "ting" + strings.Repeat("x", 2*limit)},
+			matched: true,
+		},
+		{
+			name:   "tail wrapped by the writes",
+			writes: []string{strings.Repeat("a", 50), strings.Repeat("b", 30), "c\n", strings.Repeat("d", 40), strings.Repeat("e", limit+1), "f\n", strings.Repeat("g", 63)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newCommandOutput(limit, []*regexp.Regexp{regexp.MustCompile("timed out waiting")})
+			var written string
+			for _, w := range tt.writes {
+				if _, err := o.Write([]byte(w)); err != nil {
+					t.Fatal(err)
+				}
+				written += w
+			}
+			o.flush()
+
+			if o.matched != tt.matched {
+				t.Errorf("expected matched %t, got %t", tt.matched, o.matched)
+			}
+			if o.failed != tt.failed {
+				t.Errorf("expected failed %t, got %t", tt.failed, o.failed)
+			}
+			want := written
+			if len(want) > limit {
+				want = want[len(want)-limit:]
+			}
+			if o.String() != want {
+				t.Errorf("expected the tail %q, got %q", want, o.String())
+			}
+			if o.truncated != len(written)-len(want) {
+				t.Errorf("expected %d truncated bytes, got %d", len(written)-len(want), o.truncated)
+			}
+		})
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	defer SetCommandSecrets()
+	SetCommandSecrets(GetCredentialsSecrets(ClusterCredentials{
+		ProviderCredentials:         map[string]string{"AccessKey": "AKIA", "SecretKey": "s3cr3t", "Region": "eu-west-1"},
+		KeosRegistryCredentials:     map[string]string{"User": "keos", "Pass": "s3cr3t-pass"},
+		DockerRegistriesCredentials: []map[string]interface{}{{"url": "registry.example.com", "user": "docker", "pass": "d0cker"}},
+		GithubToken:                 "ghp_token",
+	})...)
+
+	output := "eu-west-1 keos docker registry.example.com AKIA s3cr3t s3cr3t-pass d0cker ghp_token"
+	want := "eu-west-1 keos docker registry.example.com [REDACTED] [REDACTED] [REDACTED] [REDACTED] [REDACTED]"
+	if redacted := redactSecrets(output); redacted != want {
+		t.Errorf("expected %q, got %q", want, redacted)
+	}
+}
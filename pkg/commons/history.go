@@ -0,0 +1,38 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commons
+
+// OperationHistoryNamespace is the namespace of the workload cluster where its operations are recorded
+const OperationHistoryNamespace = "kube-system"
+
+// OperationHistoryLabel labels the ConfigMaps which record the operations over the workload cluster
+const OperationHistoryLabel = "keos.stratio.com/operation-history"
+
+// OperationHistoryClusterLabel labels the records with the name of the workload cluster
+const OperationHistoryClusterLabel = "keos.stratio.com/cluster"
+
+// OperationRecord is an operation over a workload cluster, stored as the data of a ConfigMap
+type OperationRecord struct {
+	Operation        string `json:"operation"`
+	Cluster          string `json:"cluster"`
+	User             string `json:"user"`
+	Started          string `json:"started"`
+	Finished         string `json:"finished"`
+	DescriptorSHA256 string `json:"descriptor_sha256"`
+	Result           string `json:"result"`
+	Error            string `json:"error,omitempty"`
+}
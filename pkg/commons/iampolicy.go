@@ -0,0 +1,201 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commons
+
+import (
+	"encoding/json"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+var awsClusterActions = []string{
+	"ec2:AuthorizeSecurityGroupIngress", "ec2:CreateSecurityGroup", "ec2:CreateTags", "ec2:DeleteSecurityGroup",
+	"ec2:DeleteTags", "ec2:Describe*", "ec2:RevokeSecurityGroupIngress", "elasticloadbalancing:*",
+	"iam:CreateServiceLinkedRole", "iam:PassRole", "ssm:GetParameter", "tag:GetResources",
+}
+var awsUnmanagedActions = []string{
+	"ec2:RunInstances", "ec2:TerminateInstances", "ec2:CreateVolume", "ec2:AttachVolume", "ec2:DeleteVolume",
+	"ec2:ModifyInstanceAttribute", "secretsmanager:CreateSecret", "secretsmanager:DeleteSecret", "secretsmanager:TagResource",
+}
+var awsManagedActions = []string{
+	"eks:*", "iam:AttachRolePolicy", "iam:CreateOpenIDConnectProvider", "iam:CreateRole", "iam:DeleteOpenIDConnectProvider",
+	"iam:DeleteRole", "iam:DetachRolePolicy", "iam:GetOpenIDConnectProvider", "iam:GetRole", "iam:ListAttachedRolePolicies",
+	"iam:TagOpenIDConnectProvider", "iam:TagRole",
+}
+var awsNetworkActions = []string{
+	"ec2:AllocateAddress", "ec2:AssociateRouteTable", "ec2:AttachInternetGateway", "ec2:CreateInternetGateway",
+	"ec2:CreateNatGateway", "ec2:CreateRoute", "ec2:CreateRouteTable", "ec2:CreateSubnet", "ec2:CreateVpc",
+	"ec2:DeleteInternetGateway", "ec2:DeleteNatGateway", "ec2:DeleteRouteTable", "ec2:DeleteSubnet", "ec2:DeleteVpc",
+	"ec2:DetachInternetGateway", "ec2:DisassociateRouteTable", "ec2:ModifySubnetAttribute", "ec2:ModifyVpcAttribute",
+	"ec2:ReleaseAddress",
+}
+var awsMachinePoolActions = []string{"autoscaling:*", "ec2:CreateLaunchTemplate", "ec2:CreateLaunchTemplateVersion", "ec2:DeleteLaunchTemplate", "ec2:DeleteLaunchTemplateVersions"}
+var awsIAMActions = []string{"cloudformation:*", "iam:*"}
+
+var azureClusterActions = []string{
+	"Microsoft.Resources/subscriptions/resourceGroups/*", "Microsoft.Network/loadBalancers/*", "Microsoft.Network/networkSecurityGroups/*",
+	"Microsoft.Network/publicIPAddresses/*", "Microsoft.ManagedIdentity/userAssignedIdentities/assign/action",
+}
+var azureUnmanagedActions = []string{
+	"Microsoft.Compute/virtualMachines/*", "Microsoft.Compute/disks/*", "Microsoft.Compute/images/read",
+	"Microsoft.Network/networkInterfaces/*", "Microsoft.Network/privateDnsZones/*",
+}
+var azureManagedActions = []string{"Microsoft.ContainerService/managedClusters/*"}
+var azureNetworkActions = []string{"Microsoft.Network/virtualNetworks/*", "Microsoft.Network/routeTables/*", "Microsoft.Network/natGateways/*"}
+var azureExistingNetworkActions = []string{"Microsoft.Network/virtualNetworks/read", "Microsoft.Network/virtualNetworks/subnets/read", "Microsoft.Network/virtualNetworks/subnets/join/action"}
+
+var gcpClusterPermissions = []string{
+	"compute.firewalls.create", "compute.firewalls.delete", "compute.firewalls.get", "compute.regions.get", "compute.zones.list",
+	"compute.instances.get", "compute.instances.list", "iam.serviceAccounts.actAs",
+}
+var gcpUnmanagedPermissions = []string{
+	"compute.addresses.create", "compute.addresses.delete", "compute.addresses.get", "compute.backendServices.create",
+	"compute.backendServices.delete", "compute.backendServices.get", "compute.disks.create", "compute.forwardingRules.create",
+	"compute.forwardingRules.delete", "compute.forwardingRules.get", "compute.healthChecks.create", "compute.healthChecks.delete",
+	"compute.images.useReadOnly", "compute.instanceGroups.create", "compute.instanceGroups.delete", "compute.instanceGroups.update",
+	"compute.instances.create", "compute.instances.delete", "compute.instances.setMetadata", "compute.instances.setServiceAccount",
+	"compute.instances.setTags", "compute.subnetworks.use",
+}
+var gcpManagedPermissions = []string{
+	"container.clusters.create", "container.clusters.delete", "container.clusters.get", "container.clusters.update",
+	"container.operations.get", "container.nodes.list",
+}
+var gcpNetworkPermissions = []string{
+	"compute.networks.create", "compute.networks.delete", "compute.networks.get", "compute.routers.create", "compute.routers.delete",
+	"compute.routers.get", "compute.routers.update", "compute.subnetworks.create", "compute.subnetworks.delete", "compute.subnetworks.get",
+}
+var gcpExistingNetworkPermissions = []string{"compute.networks.get", "compute.subnetworks.get", "compute.subnetworks.use"}
+
+// GetIAMPolicy returns the least privilege policy, in the format of the provider, which the
+// credentials need to provision the cluster of the descriptor
+func GetIAMPolicy(keosCluster KeosCluster) ([]byte, error) {
+	spec := keosCluster.Spec
+	createNetwork := spec.Networks.VPCID == ""
+
+	switch spec.InfraProvider {
+	case "aws":
+		actions := append([]string{}, awsClusterActions...)
+		if spec.ControlPlane.Managed {
+			actions = append(actions, awsManagedActions...)
+		} else {
+			actions = append(actions, awsUnmanagedActions...)
+		}
+		if createNetwork {
+			actions = append(actions, awsNetworkActions...)
+		}
+		for _, wn := range spec.WorkerNodes {
+			if wn.MachinePool {
+				actions = append(actions, awsMachinePoolActions...)
+				break
+			}
+		}
+		if spec.Security.AWS.CreateIAM {
+			actions = append(actions, awsIAMActions...)
+		}
+		if spec.ControlPlane.APIServer.CreateRecord {
+			actions = append(actions, "route53:ChangeResourceRecordSets")
+		}
+		for _, dr := range spec.DockerRegistries {
+			if dr.Type == "ecr" {
+				actions = append(actions, "ecr:GetAuthorizationToken", "ecr:BatchGetImage", "ecr:GetDownloadUrlForLayer")
+				break
+			}
+		}
+		policy := map[string]interface{}{
+			"Version": "2012-10-17",
+			"Statement": []map[string]interface{}{
+				{"Effect": "Allow", "Action": uniqueSorted(actions), "Resource": "*"},
+			},
+		}
+		return json.MarshalIndent(policy, "", "  ")
+
+	case "azure":
+		actions := append([]string{}, azureClusterActions...)
+		if spec.ControlPlane.Managed {
+			actions = append(actions, azureManagedActions...)
+		} else {
+			actions = append(actions, azureUnmanagedActions...)
+		}
+		if createNetwork {
+			actions = append(actions, azureNetworkActions...)
+		} else {
+			actions = append(actions, azureExistingNetworkActions...)
+		}
+		if spec.ControlPlane.APIServer.CreateRecord {
+			actions = append(actions, "Microsoft.Network/dnsZones/A/write", "Microsoft.Network/dnsZones/CNAME/write")
+		}
+		for _, dr := range spec.DockerRegistries {
+			if dr.Type == "acr" {
+				actions = append(actions, "Microsoft.ContainerRegistry/registries/pull/read")
+				break
+			}
+		}
+		role := map[string]interface{}{
+			"Name":             keosCluster.Metadata.Name + "-cloud-provisioner",
+			"IsCustom":         true,
+			"Description":      "Permissions to provision the " + keosCluster.Metadata.Name + " cluster",
+			"Actions":          uniqueSorted(actions),
+			"NotActions":       []string{},
+			"AssignableScopes": []string{"/subscriptions/[SUBSCRIPTION_ID]"},
+		}
+		return json.MarshalIndent(role, "", "  ")
+
+	case "gcp":
+		permissions := append([]string{}, gcpClusterPermissions...)
+		if spec.ControlPlane.Managed {
+			permissions = append(permissions, gcpManagedPermissions...)
+		} else {
+			permissions = append(permissions, gcpUnmanagedPermissions...)
+		}
+		if createNetwork {
+			permissions = append(permissions, gcpNetworkPermissions...)
+		} else {
+			permissions = append(permissions, gcpExistingNetworkPermissions...)
+		}
+		if spec.ControlPlane.APIServer.CreateRecord {
+			permissions = append(permissions, "dns.changes.create", "dns.resourceRecordSets.create", "dns.resourceRecordSets.update")
+		}
+		for _, dr := range spec.DockerRegistries {
+			if dr.Type == "gar" || dr.Type == "gcr" {
+				permissions = append(permissions, "artifactregistry.repositories.downloadArtifacts")
+				break
+			}
+		}
+		role := map[string]interface{}{
+			"title":               keosCluster.Metadata.Name + "-cloud-provisioner",
+			"description":         "Permissions to provision the " + keosCluster.Metadata.Name + " cluster",
+			"stage":               "GA",
+			"includedPermissions": uniqueSorted(permissions),
+		}
+		return yaml.Marshal(role)
+	}
+
+	return nil, errors.New("IAM policies are not supported in " + spec.InfraProvider + " clusters")
+}
+
+func uniqueSorted(s []string) []string {
+	var unique []string
+	for _, v := range s {
+		if !Contains(unique, v) {
+			unique = append(unique, v)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
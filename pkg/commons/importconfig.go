@@ -0,0 +1,274 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commons
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+// The fields of an eksctl ClusterConfig converted to the descriptor
+type eksctlClusterConfig struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name    string `yaml:"name"`
+		Region  string `yaml:"region"`
+		Version string `yaml:"version"`
+	} `yaml:"metadata"`
+	VPC struct {
+		ID      string `yaml:"id"`
+		Subnets struct {
+			Private map[string]struct {
+				ID   string `yaml:"id"`
+				CIDR string `yaml:"cidr"`
+			} `yaml:"private"`
+		} `yaml:"subnets"`
+	} `yaml:"vpc"`
+	ManagedNodeGroups []eksctlNodeGroup `yaml:"managedNodeGroups"`
+	NodeGroups        []eksctlNodeGroup `yaml:"nodeGroups"`
+}
+
+type eksctlNodeGroup struct {
+	Name              string            `yaml:"name"`
+	InstanceType      string            `yaml:"instanceType"`
+	DesiredCapacity   *int              `yaml:"desiredCapacity"`
+	MinSize           *int              `yaml:"minSize"`
+	MaxSize           int               `yaml:"maxSize"`
+	Labels            map[string]string `yaml:"labels"`
+	Spot              bool              `yaml:"spot"`
+	AvailabilityZones []string          `yaml:"availabilityZones"`
+	Taints            []struct {
+		Key    string `yaml:"key"`
+		Value  string `yaml:"value"`
+		Effect string `yaml:"effect"`
+	} `yaml:"taints"`
+}
+
+// The fields of `az aks show` converted to the descriptor
+type aksManagedCluster struct {
+	Type              string `yaml:"type"`
+	Name              string `yaml:"name"`
+	Location          string `yaml:"location"`
+	KubernetesVersion string `yaml:"kubernetesVersion"`
+	ResourceGroup     string `yaml:"resourceGroup"`
+	NetworkProfile    struct {
+		PodCIDR string `yaml:"podCidr"`
+	} `yaml:"networkProfile"`
+	AgentPoolProfiles []struct {
+		Name              string            `yaml:"name"`
+		VMSize            string            `yaml:"vmSize"`
+		Count             *int              `yaml:"count"`
+		MinCount          *int              `yaml:"minCount"`
+		MaxCount          int               `yaml:"maxCount"`
+		EnableAutoScaling bool              `yaml:"enableAutoScaling"`
+		NodeLabels        map[string]string `yaml:"nodeLabels"`
+		NodeTaints        []string          `yaml:"nodeTaints"`
+		ScaleSetPriority  string            `yaml:"scaleSetPriority"`
+		AvailabilityZones []string          `yaml:"availabilityZones"`
+	} `yaml:"agentPoolProfiles"`
+}
+
+// The fields of `gcloud container clusters describe` converted to the descriptor
+type gkeCluster struct {
+	Name                 string `yaml:"name"`
+	Location             string `yaml:"location"`
+	CurrentMasterVersion string `yaml:"currentMasterVersion"`
+	ClusterIPv4CIDR      string `yaml:"clusterIpv4Cidr"`
+	Network              string `yaml:"network"`
+	Subnetwork           string `yaml:"subnetwork"`
+	NodePools            []struct {
+		Name   string `yaml:"name"`
+		Config struct {
+			MachineType string            `yaml:"machineType"`
+			Labels      map[string]string `yaml:"labels"`
+			Spot        bool              `yaml:"spot"`
+			Preemptible bool              `yaml:"preemptible"`
+			Taints      []struct {
+				Key    string `yaml:"key"`
+				Value  string `yaml:"value"`
+				Effect string `yaml:"effect"`
+			} `yaml:"taints"`
+		} `yaml:"config"`
+		InitialNodeCount *int `yaml:"initialNodeCount"`
+		Autoscaling      struct {
+			Enabled      bool `yaml:"enabled"`
+			MinNodeCount *int `yaml:"minNodeCount"`
+			MaxNodeCount int  `yaml:"maxNodeCount"`
+		} `yaml:"autoscaling"`
+		Locations []string `yaml:"locations"`
+	} `yaml:"nodePools"`
+}
+
+var gkeTaintEffects = map[string]string{"NO_SCHEDULE": "NoSchedule", "PREFER_NO_SCHEDULE": "PreferNoSchedule", "NO_EXECUTE": "NoExecute"}
+
+// ImportClusterConfig converts an eksctl ClusterConfig, the JSON of `az aks show` or the output of
+// `gcloud container clusters describe` into a managed cluster descriptor. The fields without an
+// equivalent (registries, Helm repository, domain and credentials) are left to be completed
+func ImportClusterConfig(data []byte) (*KeosCluster, error) {
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the cluster config")
+	}
+
+	keosCluster := &KeosCluster{APIVersion: "installer.stratio.com/v1beta1", Kind: "KeosCluster"}
+	keosCluster.Spec.ControlPlane.Managed = true
+	spec := &keosCluster.Spec
+
+	switch {
+	case strings.HasPrefix(stringValue(document["apiVersion"]), "eksctl.io/"):
+		var config eksctlClusterConfig
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return nil, errors.Wrap(err, "failed to parse the eksctl ClusterConfig")
+		}
+		keosCluster.Metadata.Name = config.Metadata.Name
+		spec.InfraProvider = "aws"
+		spec.Region = config.Metadata.Region
+		spec.K8SVersion = importK8SVersion(config.Metadata.Version)
+		spec.Networks.VPCID = config.VPC.ID
+		for _, subnet := range config.VPC.Subnets.Private {
+			spec.Networks.Subnets = append(spec.Networks.Subnets, Subnets{SubnetId: subnet.ID, CidrBlock: subnet.CIDR})
+		}
+		nodeGroups := append(config.ManagedNodeGroups, config.NodeGroups...)
+		spec.WorkerNodes = make(WorkerNodes, len(nodeGroups))
+		for i, ng := range nodeGroups {
+			wn := &spec.WorkerNodes[i]
+			wn.Name = ng.Name
+			wn.Size = ng.InstanceType
+			wn.Quantity = importQuantity(ng.DesiredCapacity, ng.MinSize)
+			wn.NodeGroupMinSize, wn.NodeGroupMaxSize = importNodeGroupSize(ng.MinSize, ng.MaxSize)
+			wn.Labels = ng.Labels
+			wn.Spot = ng.Spot
+			if len(ng.AvailabilityZones) == 1 {
+				wn.AZ = ng.AvailabilityZones[0]
+			}
+			for _, taint := range ng.Taints {
+				wn.Taints = append(wn.Taints, taint.Key+"="+taint.Value+":"+taint.Effect)
+			}
+		}
+
+	case strings.EqualFold(stringValue(document["type"]), "Microsoft.ContainerService/ManagedClusters"):
+		var config aksManagedCluster
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return nil, errors.Wrap(err, "failed to parse the AKS cluster")
+		}
+		keosCluster.Metadata.Name = config.Name
+		spec.InfraProvider = "azure"
+		spec.Region = config.Location
+		spec.K8SVersion = importK8SVersion(config.KubernetesVersion)
+		spec.Networks.PodsCidrBlock = config.NetworkProfile.PodCIDR
+		spec.WorkerNodes = make(WorkerNodes, len(config.AgentPoolProfiles))
+		for i, pool := range config.AgentPoolProfiles {
+			wn := &spec.WorkerNodes[i]
+			wn.Name = pool.Name
+			wn.Size = pool.VMSize
+			wn.Quantity = importQuantity(pool.Count, pool.MinCount)
+			if pool.EnableAutoScaling {
+				wn.NodeGroupMinSize, wn.NodeGroupMaxSize = importNodeGroupSize(pool.MinCount, pool.MaxCount)
+			}
+			wn.Labels = pool.NodeLabels
+			wn.Taints = pool.NodeTaints
+			wn.Spot = pool.ScaleSetPriority == "Spot"
+			if len(pool.AvailabilityZones) == 1 {
+				wn.AZ = pool.AvailabilityZones[0]
+			}
+		}
+
+	case document["nodePools"] != nil && document["currentMasterVersion"] != nil:
+		var config gkeCluster
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return nil, errors.Wrap(err, "failed to parse the GKE cluster")
+		}
+		keosCluster.Metadata.Name = config.Name
+		spec.InfraProvider = "gcp"
+		spec.Region = config.Location
+		spec.K8SVersion = importK8SVersion(config.CurrentMasterVersion)
+		spec.Networks.VPCID = config.Network
+		spec.Networks.PodsCidrBlock = config.ClusterIPv4CIDR
+		if config.Subnetwork != "" {
+			spec.Networks.Subnets = []Subnets{{SubnetId: config.Subnetwork}}
+		}
+		spec.WorkerNodes = make(WorkerNodes, len(config.NodePools))
+		for i, pool := range config.NodePools {
+			wn := &spec.WorkerNodes[i]
+			wn.Name = pool.Name
+			wn.Size = pool.Config.MachineType
+			wn.Quantity = importQuantity(pool.InitialNodeCount, pool.Autoscaling.MinNodeCount)
+			if pool.Autoscaling.Enabled {
+				wn.NodeGroupMinSize, wn.NodeGroupMaxSize = importNodeGroupSize(pool.Autoscaling.MinNodeCount, pool.Autoscaling.MaxNodeCount)
+			}
+			wn.Labels = pool.Config.Labels
+			wn.Spot = pool.Config.Spot || pool.Config.Preemptible
+			if len(pool.Locations) == 1 {
+				wn.AZ = pool.Locations[0]
+			}
+			for _, taint := range pool.Config.Taints {
+				wn.Taints = append(wn.Taints, taint.Key+"="+taint.Value+":"+gkeTaintEffects[taint.Effect])
+			}
+		}
+
+	default:
+		return nil, errors.New("unsupported cluster config: expected an eksctl ClusterConfig, an AKS cluster or a GKE cluster")
+	}
+
+	for _, wn := range spec.WorkerNodes {
+		if wn.NodeGroupMaxSize > 0 {
+			spec.DeployAutoscaler = true
+		}
+	}
+	return keosCluster, nil
+}
+
+// importK8SVersion returns the version in the format of the descriptor, the patch version
+// being 0 when it is not set
+func importK8SVersion(version string) string {
+	version = strings.TrimPrefix(version, "v")
+	if version == "" {
+		return ""
+	}
+	if strings.Count(strings.SplitN(version, "-", 2)[0], ".") == 1 {
+		version += ".0"
+	}
+	return "v" + version
+}
+
+func importQuantity(count *int, minCount *int) *int {
+	if count != nil {
+		return ToPtr(*count)
+	}
+	if minCount != nil {
+		return ToPtr(*minCount)
+	}
+	return ToPtr(1)
+}
+
+func importNodeGroupSize(minCount *int, maxCount int) (*int, int) {
+	if maxCount == 0 {
+		return nil, 0
+	}
+	if minCount == nil {
+		return ToPtr(0), maxCount
+	}
+	return ToPtr(*minCount), maxCount
+}
+
+func stringValue(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
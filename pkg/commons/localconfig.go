@@ -0,0 +1,144 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commons
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+// LocalConfigPath is the user level config file, relative to the home directory
+const LocalConfigPath = ".kind/cloud.yaml"
+
+// LocalConfig holds the user defaults, the values of the descriptor take precedence over them
+type LocalConfig struct {
+	InfraProvider    string           `yaml:"infra_provider,omitempty"`
+	Region           string           `yaml:"region,omitempty"`
+	DockerRegistries []DockerRegistry `yaml:"docker_registries,omitempty"`
+	HelmRepository   *HelmRepository  `yaml:"helm_repository,omitempty"`
+	TemplatesDir     string           `yaml:"templates_dir,omitempty"`
+	// MetricsPushgateway is the Prometheus Pushgateway where the provisioning metrics are pushed
+	MetricsPushgateway string `yaml:"metrics_pushgateway,omitempty"`
+	Proxy              struct {
+		HTTPProxy  string `yaml:"http_proxy,omitempty"`
+		HTTPSProxy string `yaml:"https_proxy,omitempty"`
+		NoProxy    string `yaml:"no_proxy,omitempty"`
+	} `yaml:"proxy,omitempty"`
+	// Bootstrap tunes the local container the workload cluster is provisioned from
+	Bootstrap BootstrapConfig `yaml:"bootstrap,omitempty"`
+}
+
+// BootstrapConfig holds the settings of the local kind cluster, which are taken from the kind
+// defaults if unset
+type BootstrapConfig struct {
+	NodeImage     string           `yaml:"node_image,omitempty"`
+	APIServerPort int32            `yaml:"api_server_port,omitempty"`
+	ExtraMounts   []BootstrapMount `yaml:"extra_mounts,omitempty"`
+	Resources     struct {
+		CPUs   string `yaml:"cpus,omitempty"`
+		Memory string `yaml:"memory,omitempty"`
+	} `yaml:"resources,omitempty"`
+}
+
+// BootstrapMount is a host path mounted in the local container, such as a directory of local
+// manifests or an image cache
+type BootstrapMount struct {
+	HostPath      string `yaml:"host_path"`
+	ContainerPath string `yaml:"container_path"`
+	ReadOnly      bool   `yaml:"read_only,omitempty"`
+}
+
+var bootstrapCPUsRegex = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+var bootstrapMemoryRegex = regexp.MustCompile(`^[0-9]+[bkmgBKMG]?$`)
+
+// GetLocalConfig returns the user level config, which is empty if the file does not exist
+func GetLocalConfig() (*LocalConfig, error) {
+	var localConfig LocalConfig
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return &localConfig, nil
+	}
+	localConfigRAW, err := os.ReadFile(filepath.Join(home, LocalConfigPath))
+	if os.IsNotExist(err) {
+		return &localConfig, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = yaml.Unmarshal(localConfigRAW, &localConfig); err != nil {
+		return nil, errors.Wrap(err, "failed to parse "+LocalConfigPath)
+	}
+	if err = localConfig.Bootstrap.validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid "+LocalConfigPath)
+	}
+	return &localConfig, nil
+}
+
+func (b *BootstrapConfig) validate() error {
+	if b.APIServerPort < 0 || b.APIServerPort > 65535 {
+		return errors.Errorf("bootstrap.api_server_port: Invalid value: \"%d\": must be between 0 and 65535", b.APIServerPort)
+	}
+	for i, mount := range b.ExtraMounts {
+		if !filepath.IsAbs(mount.HostPath) {
+			return errors.Errorf("bootstrap.extra_mounts[%d].host_path: Invalid value: \"%s\": must be an absolute path", i, mount.HostPath)
+		}
+		if _, err := os.Stat(mount.HostPath); err != nil {
+			return errors.Errorf("bootstrap.extra_mounts[%d].host_path: Invalid value: \"%s\": does not exist", i, mount.HostPath)
+		}
+		if !filepath.IsAbs(mount.ContainerPath) {
+			return errors.Errorf("bootstrap.extra_mounts[%d].container_path: Invalid value: \"%s\": must be an absolute path", i, mount.ContainerPath)
+		}
+	}
+	if b.Resources.CPUs != "" && !bootstrapCPUsRegex.MatchString(b.Resources.CPUs) {
+		return errors.Errorf("bootstrap.resources.cpus: Invalid value: \"%s\": must be a number of CPUs, e.g. 2 or 1.5", b.Resources.CPUs)
+	}
+	if b.Resources.Memory != "" && !bootstrapMemoryRegex.MatchString(b.Resources.Memory) {
+		return errors.Errorf("bootstrap.resources.memory: Invalid value: \"%s\": must be a docker memory limit, e.g. 4g or 512m", b.Resources.Memory)
+	}
+	return nil
+}
+
+// SetDefaults sets the user defaults in the spec, before the descriptor is unmarshalled over it
+func (c *LocalConfig) SetDefaults(s KeosSpec) KeosSpec {
+	s.InfraProvider = c.InfraProvider
+	s.Region = c.Region
+	s.DockerRegistries = c.DockerRegistries
+	if c.HelmRepository != nil {
+		s.HelmRepository = *c.HelmRepository
+	}
+	return s
+}
+
+// SetProxyEnv exports the proxy of the user config, unless it is already set in the environment,
+// so it is passed on to the local container
+func (c *LocalConfig) SetProxyEnv() {
+	proxyEnv := map[string]string{
+		"HTTP_PROXY":  c.Proxy.HTTPProxy,
+		"HTTPS_PROXY": c.Proxy.HTTPSProxy,
+		"NO_PROXY":    c.Proxy.NoProxy,
+	}
+	for name, value := range proxyEnv {
+		if value != "" && os.Getenv(name) == "" {
+			os.Setenv(name, value)
+		}
+	}
+}
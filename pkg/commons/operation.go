@@ -0,0 +1,144 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commons
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const (
+	// OperationsPath is the directory of the logs of the asynchronous operations, relative to the home directory
+	OperationsPath = ".kind/operations"
+	// OperationLogMaxSize is the size of the tail of the log reported to the management cluster, so the
+	// ConfigMap of the operation stays far from the size limit of the objects
+	OperationLogMaxSize = 256 * 1024
+	// OperationReportInterval is the interval the running operation is reported at, and after
+	// OperationReportTimeout without a report it is considered interrupted
+	OperationReportInterval = 10 * time.Second
+	OperationReportTimeout  = 2 * time.Minute
+)
+
+// The states of an asynchronous operation
+const (
+	OperationRunning   = "Running"
+	OperationSucceeded = "Succeeded"
+	OperationFailed    = "Failed"
+)
+
+// OperationStatus is the state of an asynchronous operation, with the tail of its log starting at
+// LogOffset of the whole log. It is reported as the data of a ConfigMap of the management cluster
+type OperationStatus struct {
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	Updated   string `json:"updated"`
+	Log       string `json:"log"`
+	LogOffset string `json:"log_offset"`
+}
+
+// Operation is an asynchronous operation, whose process writes its output to the log
+type Operation struct {
+	ID      string
+	LogPath string
+}
+
+// GetOperation returns the asynchronous operation
+func GetOperation(id string) (Operation, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return Operation{}, errors.Wrap(err, "failed to get the home directory")
+	}
+	return Operation{
+		ID:      id,
+		LogPath: filepath.Join(home, OperationsPath, id+".log"),
+	}, nil
+}
+
+// ConfigMapName returns the name of the ConfigMap the operation is reported to
+func (o Operation) ConfigMapName() string {
+	return "cloud-provisioner-operation-" + o.ID
+}
+
+// GetStatus returns the status of the operation, with the tail of its log
+func (o Operation) GetStatus(status string, operationErr error) (OperationStatus, error) {
+	operationStatus := OperationStatus{
+		Status:    status,
+		Updated:   time.Now().UTC().Format(time.RFC3339),
+		LogOffset: "0",
+	}
+	if operationErr != nil {
+		operationStatus.Error = operationErr.Error()
+	}
+
+	logFile, err := os.Open(o.LogPath)
+	if err != nil {
+		return OperationStatus{}, errors.Wrap(err, "failed to open the log of the operation")
+	}
+	defer logFile.Close()
+	info, err := logFile.Stat()
+	if err != nil {
+		return OperationStatus{}, errors.Wrap(err, "failed to read the log of the operation")
+	}
+	offset := info.Size() - OperationLogMaxSize
+	if offset < 0 {
+		offset = 0
+	}
+	tail, err := io.ReadAll(io.NewSectionReader(logFile, offset, info.Size()-offset))
+	if err != nil {
+		return OperationStatus{}, errors.Wrap(err, "failed to read the log of the operation")
+	}
+	operationStatus.Log = string(tail)
+	operationStatus.LogOffset = strconv.FormatInt(offset, 10)
+	return operationStatus, nil
+}
+
+// GetStatusManifest returns the ConfigMap which reports the status of the operation over the cluster
+func (o Operation) GetStatusManifest(clusterName string, status OperationStatus) (string, error) {
+	// The ConfigMap data is the status itself
+	statusJSON, err := json.Marshal(status)
+	if err != nil {
+		return "", err
+	}
+	data := map[string]string{}
+	if err = json.Unmarshal(statusJSON, &data); err != nil {
+		return "", err
+	}
+	configMap := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      o.ConfigMapName(),
+			"namespace": OperationHistoryNamespace,
+			"labels": map[string]string{
+				OperationHistoryClusterLabel: clusterName,
+			},
+		},
+		"data": data,
+	}
+	configMapYAML, err := yaml.Marshal(configMap)
+	if err != nil {
+		return "", err
+	}
+	return string(configMapYAML), nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commons
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestOperationGetStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		logSize int
+		offset  int
+	}{
+		{name: "empty log"},
+		{name: "short log", logSize: 1024},
+		{name: "log at the limit", logSize: OperationLogMaxSize},
+		{name: "long log", logSize: OperationLogMaxSize + 1000, offset: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var log strings.Builder
+			for i := 0; log.Len() < tt.logSize; i++ {
+				log.WriteString(strconv.Itoa(i) + "\n")
+			}
+			logContent := log.String()[:tt.logSize]
+			operation := Operation{ID: "cluster-20240101000000", LogPath: filepath.Join(t.TempDir(), "operation.log")}
+			if err := os.WriteFile(operation.LogPath, []byte(logContent), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			status, err := operation.GetStatus(OperationFailed, errors.New("failed"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status.Status != OperationFailed || status.Error != "failed" {
+				t.Errorf("the status is %s: %s", status.Status, status.Error)
+			}
+			if status.LogOffset != strconv.Itoa(tt.offset) || status.Log != logContent[tt.offset:] {
+				t.Errorf("the log is reported from %s with %d bytes, expected from %d with %d bytes", status.LogOffset, len(status.Log), tt.offset, len(logContent)-tt.offset)
+			}
+		})
+	}
+}
@@ -0,0 +1,392 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commons
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+// The api-versions of the Azure resources which can be orphaned
+var azureOrphanAPIVersions = map[string]string{
+	"microsoft.network/loadbalancers":         "2023-05-01",
+	"microsoft.network/publicipaddresses":     "2023-05-01",
+	"microsoft.network/networksecuritygroups": "2023-05-01",
+	"microsoft.network/natgateways":           "2023-05-01",
+	"microsoft.compute/disks":                 "2023-04-02",
+}
+
+// OrphanResource is a cloud resource tagged for a cluster which no longer exists
+type OrphanResource struct {
+	Type   string
+	ID     string
+	delete func() error
+}
+
+// Delete removes the resource from the provider
+func (r OrphanResource) Delete() error {
+	return r.delete()
+}
+
+// FindOrphanResources returns the load balancers, volumes, security groups and NAT gateways
+// tagged for the cluster by Cluster API or the cloud controllers. The cluster must not exist,
+// so an error is returned if it still has instances
+func FindOrphanResources(keosCluster KeosCluster, credentials map[string]string) ([]OrphanResource, error) {
+	var orphans []OrphanResource
+	var err error
+
+	switch keosCluster.Spec.InfraProvider {
+	case "aws":
+		orphans, err = findAWSOrphans(keosCluster.Metadata.Name, keosCluster.Spec.Region, credentials)
+	case "azure":
+		orphans, err = findAzureOrphans(keosCluster.Metadata.Name, credentials)
+	case "gcp":
+		orphans, err = findGCPOrphans(keosCluster.Metadata.Name, keosCluster.Spec.Region, credentials)
+	default:
+		return nil, errors.New("orphan resources cleanup is not supported in " + keosCluster.Spec.InfraProvider + " clusters")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The resources may be tagged both by Cluster API and the cloud controllers
+	var unique []OrphanResource
+	ids := []string{}
+	for _, orphan := range orphans {
+		if !Contains(ids, orphan.ID) {
+			ids = append(ids, orphan.ID)
+			unique = append(unique, orphan)
+		}
+	}
+	return unique, nil
+}
+
+func findAWSOrphans(clusterName string, region string, credentials map[string]string) ([]OrphanResource, error) {
+	var ctx = context.Background()
+	var orphans []OrphanResource
+
+	client, err := NewAWSClient(ctx, credentials, region, "")
+	if err != nil {
+		return nil, err
+	}
+	svc := client.EC2()
+	tagFilters := [][]types.Filter{
+		{{Name: aws.String("tag:sigs.k8s.io/cluster-api-provider-aws/cluster/" + clusterName), Values: []string{"owned"}}},
+		{{Name: aws.String("tag:kubernetes.io/cluster/" + clusterName), Values: []string{"owned"}}},
+	}
+
+	for _, filters := range tagFilters {
+		instances, err := svc.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+			Filters: append(filters, types.Filter{Name: aws.String("instance-state-name"), Values: []string{"pending", "running", "stopping", "stopped"}}),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to describe the instances")
+		}
+		if len(instances.Reservations) > 0 {
+			return nil, errors.New("the cluster " + clusterName + " still has instances, delete it before cleaning up its resources")
+		}
+	}
+
+	lbs, err := findAWSLoadBalancers(ctx, client, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	orphans = append(orphans, lbs...)
+
+	for _, filters := range tagFilters {
+		natGateways, err := svc.DescribeNatGateways(ctx, &ec2.DescribeNatGatewaysInput{
+			Filter: append(filters, types.Filter{Name: aws.String("state"), Values: []string{"available", "failed"}}),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to describe the NAT gateways")
+		}
+		for _, ngw := range natGateways.NatGateways {
+			id := ngw.NatGatewayId
+			orphans = append(orphans, OrphanResource{Type: "NAT gateway", ID: *id, delete: func() error {
+				_, err := svc.DeleteNatGateway(ctx, &ec2.DeleteNatGatewayInput{NatGatewayId: id})
+				return err
+			}})
+		}
+
+		addresses, err := svc.DescribeAddresses(ctx, &ec2.DescribeAddressesInput{Filters: filters})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to describe the Elastic IPs")
+		}
+		for _, address := range addresses.Addresses {
+			// The addresses of the NAT gateways are released in the next run, once they are deleted
+			if address.AssociationId != nil {
+				continue
+			}
+			id := address.AllocationId
+			orphans = append(orphans, OrphanResource{Type: "Elastic IP", ID: *id, delete: func() error {
+				_, err := svc.ReleaseAddress(ctx, &ec2.ReleaseAddressInput{AllocationId: id})
+				return err
+			}})
+		}
+
+		volumes, err := svc.DescribeVolumes(ctx, &ec2.DescribeVolumesInput{
+			Filters: append(filters, types.Filter{Name: aws.String("status"), Values: []string{"available"}}),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to describe the volumes")
+		}
+		for _, volume := range volumes.Volumes {
+			id := volume.VolumeId
+			orphans = append(orphans, OrphanResource{Type: "volume", ID: *id, delete: func() error {
+				_, err := svc.DeleteVolume(ctx, &ec2.DeleteVolumeInput{VolumeId: id})
+				return err
+			}})
+		}
+
+		securityGroups, err := svc.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{Filters: filters})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to describe the security groups")
+		}
+		for _, sg := range securityGroups.SecurityGroups {
+			id := sg.GroupId
+			orphans = append(orphans, OrphanResource{Type: "security group", ID: *id, delete: func() error {
+				_, err := svc.DeleteSecurityGroup(ctx, &ec2.DeleteSecurityGroupInput{GroupId: id})
+				return err
+			}})
+		}
+	}
+	return orphans, nil
+}
+
+// findAWSLoadBalancers returns the load balancers of the cluster, found with the tagging API
+// as there is no ELB client in the SDK dependencies
+func findAWSLoadBalancers(ctx context.Context, client *AWSClient, clusterName string) ([]OrphanResource, error) {
+	var orphans []OrphanResource
+	var response struct {
+		ResourceTagMappingList []struct {
+			ResourceARN string
+		}
+	}
+
+	for _, tagKey := range []string{"sigs.k8s.io/cluster-api-provider-aws/cluster/" + clusterName, "kubernetes.io/cluster/" + clusterName} {
+		body, err := json.Marshal(map[string]interface{}{
+			"TagFilters":          []map[string]interface{}{{"Key": tagKey, "Values": []string{"owned"}}},
+			"ResourceTypeFilters": []string{"elasticloadbalancing:loadbalancer"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Post(ctx, "tagging", "application/x-amz-json-1.1", "ResourceGroupsTaggingAPI_20170126.GetResources", string(body))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get the load balancers")
+		}
+		if err = json.Unmarshal(resp, &response); err != nil {
+			return nil, err
+		}
+		for _, resource := range response.ResourceTagMappingList {
+			// Classic load balancers are named in the ARN, the rest are identified by it
+			arn := resource.ResourceARN
+			form := url.Values{"Action": {"DeleteLoadBalancer"}}
+			if name := strings.SplitN(arn, ":loadbalancer/", 2)[1]; !strings.Contains(name, "/") {
+				form.Set("Version", "2012-06-01")
+				form.Set("LoadBalancerName", name)
+			} else {
+				form.Set("Version", "2015-12-01")
+				form.Set("LoadBalancerArn", arn)
+			}
+			orphans = append(orphans, OrphanResource{Type: "load balancer", ID: arn, delete: func() error {
+				_, err := client.Post(ctx, "elasticloadbalancing", "application/x-www-form-urlencoded", "", form.Encode())
+				return err
+			}})
+		}
+	}
+	return orphans, nil
+}
+
+func findAzureOrphans(clusterName string, credentials map[string]string) ([]OrphanResource, error) {
+	var ctx = context.Background()
+	var orphans []OrphanResource
+	var resources struct {
+		Value []struct {
+			ID   string `json:"id"`
+			Type string `json:"type"`
+		} `json:"value"`
+	}
+
+	cfg, err := AzureGetConfig(credentials)
+	if err != nil {
+		return nil, err
+	}
+	token, err := cfg.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"https://management.azure.com/.default"}})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get Azure token")
+	}
+	azureRequest := func(method string, resourceURL string) ([]byte, error) {
+		req, err := http.NewRequest(method, resourceURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token.Token)
+		return DoCloudRequest(req)
+	}
+
+	subscriptionURL := "https://management.azure.com/subscriptions/" + credentials["SubscriptionID"]
+	tagFilters := []string{
+		"tagName eq 'sigs.k8s.io_cluster-api-provider-azure_cluster_" + clusterName + "' and tagValue eq 'owned'",
+		"tagName eq 'kubernetes-cluster-name' and tagValue eq '" + clusterName + "'",
+	}
+	for _, filter := range tagFilters {
+		resp, err := azureRequest(http.MethodGet, subscriptionURL+"/resources?api-version=2021-04-01&$filter="+url.QueryEscape(filter))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list the resources")
+		}
+		if err = json.Unmarshal(resp, &resources); err != nil {
+			return nil, err
+		}
+		for _, resource := range resources.Value {
+			resourceType := strings.ToLower(resource.Type)
+			if resourceType == "microsoft.compute/virtualmachines" || resourceType == "microsoft.containerservice/managedclusters" {
+				return nil, errors.New("the cluster " + clusterName + " still has instances, delete it before cleaning up its resources")
+			}
+			apiVersion, ok := azureOrphanAPIVersions[resourceType]
+			if !ok {
+				continue
+			}
+			resourceURL := "https://management.azure.com" + resource.ID + "?api-version=" + apiVersion
+			orphans = append(orphans, OrphanResource{Type: resource.Type, ID: resource.ID, delete: func() error {
+				_, err := azureRequest(http.MethodDelete, resourceURL)
+				return err
+			}})
+		}
+	}
+	return orphans, nil
+}
+
+func findGCPOrphans(clusterName string, region string, credentials map[string]string) ([]OrphanResource, error) {
+	var ctx = context.Background()
+	var orphans []OrphanResource
+
+	computeService, err := compute.NewService(ctx, option.WithCredentialsJSON(gcpCredentialsJSON(credentials)))
+	if err != nil {
+		return nil, err
+	}
+	project := credentials["ProjectID"]
+	filter := "labels.capg-cluster-" + clusterName + "=owned"
+
+	instances, err := computeService.Instances.AggregatedList(project).Filter(filter).Context(ctx).Do()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the instances")
+	}
+	for _, scoped := range instances.Items {
+		if len(scoped.Instances) > 0 {
+			return nil, errors.New("the cluster " + clusterName + " still has instances, delete it before cleaning up its resources")
+		}
+	}
+
+	forwardingRules, err := computeService.ForwardingRules.List(project, region).Filter(filter).Context(ctx).Do()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the forwarding rules")
+	}
+	for _, rule := range forwardingRules.Items {
+		name := rule.Name
+		orphans = append(orphans, OrphanResource{Type: "forwarding rule", ID: name, delete: func() error {
+			_, err := computeService.ForwardingRules.Delete(project, region, name).Context(ctx).Do()
+			return err
+		}})
+	}
+	globalForwardingRules, err := computeService.GlobalForwardingRules.List(project).Filter(filter).Context(ctx).Do()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the global forwarding rules")
+	}
+	for _, rule := range globalForwardingRules.Items {
+		name := rule.Name
+		orphans = append(orphans, OrphanResource{Type: "global forwarding rule", ID: name, delete: func() error {
+			_, err := computeService.GlobalForwardingRules.Delete(project, name).Context(ctx).Do()
+			return err
+		}})
+	}
+
+	addresses, err := computeService.Addresses.List(project, region).Filter(filter).Context(ctx).Do()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the addresses")
+	}
+	for _, address := range addresses.Items {
+		name := address.Name
+		orphans = append(orphans, OrphanResource{Type: "address", ID: name, delete: func() error {
+			_, err := computeService.Addresses.Delete(project, region, name).Context(ctx).Do()
+			return err
+		}})
+	}
+
+	disks, err := computeService.Disks.AggregatedList(project).Filter(filter).Context(ctx).Do()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the disks")
+	}
+	for scope, scoped := range disks.Items {
+		zone := strings.TrimPrefix(scope, "zones/")
+		for _, disk := range scoped.Disks {
+			if len(disk.Users) > 0 {
+				continue
+			}
+			name := disk.Name
+			orphans = append(orphans, OrphanResource{Type: "disk", ID: zone + "/" + name, delete: func() error {
+				_, err := computeService.Disks.Delete(project, zone, name).Context(ctx).Do()
+				return err
+			}})
+		}
+	}
+	return orphans, nil
+}
+
+// gcpCredentialsJSON returns the service account key of the credentials
+func gcpCredentialsJSON(credentials map[string]string) []byte {
+	data := map[string]interface{}{
+		"type":           "service_account",
+		"project_id":     credentials["ProjectID"],
+		"private_key_id": credentials["PrivateKeyID"],
+		"private_key":    credentials["PrivateKey"],
+		"client_email":   credentials["ClientEmail"],
+		"client_id":      credentials["ClientID"],
+		"token_uri":      "https://accounts.google.com/o/oauth2/token",
+	}
+	jsonData, _ := json.Marshal(data)
+	return jsonData
+}
+
+// DoCloudRequest returns the body of the response, or an error with it if the request failed
+func DoCloudRequest(req *http.Request) ([]byte, error) {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, errors.New("request failed: " + resp.Status + " " + string(body))
+	}
+	return body, nil
+}
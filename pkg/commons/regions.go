@@ -0,0 +1,173 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commons
+
+import (
+	"context"
+	"path"
+	"sort"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armsubscriptions"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/fatih/structs"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+// CloudRegion is a region of a provider, with the availability zones the credentials can use in it
+type CloudRegion struct {
+	Name  string
+	Zones []string
+}
+
+// GetSecretsProviderCredentials returns the credentials of the provider in the secrets file
+func GetSecretsProviderCredentials(secrets Secrets, infraProvider string) (map[string]string, error) {
+	var providerCredentials interface{}
+	switch infraProvider {
+	case "aws":
+		providerCredentials = secrets.AWS.Credentials
+	case "azure":
+		providerCredentials = secrets.AZURE.Credentials
+	case "gcp":
+		providerCredentials = secrets.GCP.Credentials
+	default:
+		return nil, errors.New("the provider must be aws, azure or gcp")
+	}
+	if structs.IsZero(providerCredentials) {
+		return nil, errors.New("there are no " + infraProvider + " credentials in the secrets file")
+	}
+	credentials := map[string]string{}
+	for key, value := range structs.Map(providerCredentials) {
+		credentials[key] = value.(string)
+	}
+	return credentials, nil
+}
+
+// ListCloudRegions returns the regions of the provider enabled for the credentials, sorted by name
+func ListCloudRegions(infraProvider string, credentials map[string]string) ([]CloudRegion, error) {
+	var regions []CloudRegion
+	var err error
+
+	switch infraProvider {
+	case "aws":
+		regions, err = listAWSRegions(credentials)
+	case "azure":
+		regions, err = listAzureRegions(credentials)
+	case "gcp":
+		regions, err = listGCPRegions(credentials)
+	default:
+		return nil, errors.New("the provider must be aws, azure or gcp")
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(regions, func(i, j int) bool { return regions[i].Name < regions[j].Name })
+	return regions, nil
+}
+
+func listAWSRegions(credentials map[string]string) ([]CloudRegion, error) {
+	var ctx = context.Background()
+	var regions []CloudRegion
+
+	client, err := NewAWSClient(ctx, credentials, credentials["Region"], "")
+	if err != nil {
+		return nil, err
+	}
+	// Only the regions enabled in the account are returned
+	output, err := client.Global().EC2().DescribeRegions(ctx, &ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to describe the regions")
+	}
+	for _, r := range output.Regions {
+		region := CloudRegion{Name: aws.ToString(r.RegionName)}
+		azs, err := client.WithRegion(region.Name).EC2().DescribeAvailabilityZones(ctx, &ec2.DescribeAvailabilityZonesInput{})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to describe the availability zones of "+region.Name)
+		}
+		// The Local Zones and Wavelength Zones are not availability zones of the region
+		for _, az := range azs.AvailabilityZones {
+			if aws.ToString(az.ZoneType) == "availability-zone" && az.State == "available" {
+				region.Zones = append(region.Zones, aws.ToString(az.ZoneName))
+			}
+		}
+		regions = append(regions, region)
+	}
+	return regions, nil
+}
+
+func listAzureRegions(credentials map[string]string) ([]CloudRegion, error) {
+	var ctx = context.Background()
+	var regions []CloudRegion
+
+	cfg, err := AzureGetConfig(credentials)
+	if err != nil {
+		return nil, err
+	}
+	clientFactory, err := armsubscriptions.NewClientFactory(cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+	pager := clientFactory.NewClient().NewListLocationsPager(credentials["SubscriptionID"], nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list the locations")
+		}
+		for _, location := range page.Value {
+			// The logical locations, as the geographies, can not hold resources
+			if location.Metadata == nil || location.Metadata.RegionType == nil || string(*location.Metadata.RegionType) != "Physical" {
+				continue
+			}
+			region := CloudRegion{Name: *location.Name}
+			for _, az := range location.AvailabilityZoneMappings {
+				region.Zones = append(region.Zones, *az.LogicalZone)
+			}
+			regions = append(regions, region)
+		}
+	}
+	return regions, nil
+}
+
+func listGCPRegions(credentials map[string]string) ([]CloudRegion, error) {
+	var ctx = context.Background()
+	var regions []CloudRegion
+
+	computeService, err := compute.NewService(ctx, option.WithCredentialsJSON(gcpCredentialsJSON(credentials)))
+	if err != nil {
+		return nil, err
+	}
+	err = computeService.Regions.List(credentials["ProjectID"]).Pages(ctx, func(page *compute.RegionList) error {
+		for _, r := range page.Items {
+			if r.Status != "UP" {
+				continue
+			}
+			region := CloudRegion{Name: r.Name}
+			// The zones are the URLs of their resources
+			for _, zone := range r.Zones {
+				region.Zones = append(region.Zones, path.Base(zone))
+			}
+			regions = append(regions, region)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the regions")
+	}
+	return regions, nil
+}
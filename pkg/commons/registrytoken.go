@@ -0,0 +1,148 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commons
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"golang.org/x/oauth2/google"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+// The tokens are refreshed this long before they expire, so they are still valid once sent
+const registryTokenRefreshMargin = 10 * time.Minute
+
+// The user of the ACR refresh tokens
+const acrTokenUser = "00000000-0000-0000-0000-000000000000"
+
+// The ACR refresh tokens are valid for 3 hours, unless their claims say otherwise
+const acrTokenLifetime = 3 * time.Hour
+
+// The user of the GAR and GCR access tokens
+const garTokenUser = "oauth2accesstoken"
+
+// RegistryToken is a short-lived credential of a cloud registry
+type RegistryToken struct {
+	User     string
+	Password string
+	Expiry   time.Time
+}
+
+// Expired returns whether the token must be refreshed before it is used
+func (t RegistryToken) Expired() bool {
+	return time.Until(t.Expiry) < registryTokenRefreshMargin
+}
+
+// GetACRToken exchanges an AAD token of the service principal for an ACR refresh token, which is
+// the password of the null GUID user in the registry
+func GetACRToken(ctx context.Context, credentials map[string]string, registryURL string) (RegistryToken, error) {
+	token := RegistryToken{User: acrTokenUser}
+
+	cfg, err := AzureGetConfig(credentials)
+	if err != nil {
+		return token, err
+	}
+	aadToken, err := cfg.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"https://management.azure.com/.default"}})
+	if err != nil {
+		return token, errors.Wrap(err, "failed to get the AAD token")
+	}
+
+	acrService := strings.Split(registryURL, "/")[0]
+	formData := url.Values{
+		"grant_type":   {"access_token"},
+		"service":      {acrService},
+		"tenant":       {credentials["TenantID"]},
+		"access_token": {aadToken.Token},
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://"+acrService+"/oauth2/exchange", strings.NewReader(formData.Encode()))
+	if err != nil {
+		return token, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return token, errors.Wrap(err, "failed to exchange the AAD token for an ACR token")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return token, errors.Errorf("Failed to obtain the ACR token with the provided credentials (%s), please check the roles assigned to the correspondent Azure AD app", resp.Status)
+	}
+
+	var response struct {
+		RefreshToken string `json:"refresh_token"`
+		AccessToken  string `json:"access_token"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return token, errors.Wrap(err, "failed to parse the ACR token")
+	}
+	if response.RefreshToken == "" {
+		response.RefreshToken = response.AccessToken
+	}
+	if response.RefreshToken == "" {
+		return token, errors.New("Failed to obtain the ACR token with the provided credentials, please check the roles assigned to the correspondent Azure AD app")
+	}
+	token.Password = response.RefreshToken
+	token.Expiry = getJWTExpiry(response.RefreshToken, time.Now().Add(acrTokenLifetime))
+	return token, nil
+}
+
+// GetGARToken mints an OAuth2 access token of the service account, which is the password of the
+// oauth2accesstoken user in Artifact Registry and Container Registry
+func GetGARToken(ctx context.Context, serviceAccountJSON []byte) (RegistryToken, error) {
+	token := RegistryToken{User: garTokenUser}
+
+	creds, err := google.CredentialsFromJSON(ctx, serviceAccountJSON, "https://www.googleapis.com/auth/cloud-platform")
+	if err != nil {
+		return token, errors.Wrap(err, "failed to parse the GCP service account credentials")
+	}
+	accessToken, err := creds.TokenSource.Token()
+	if err != nil {
+		return token, errors.Wrap(err, "failed to obtain the GAR token with the provided credentials")
+	}
+	token.Password = accessToken.AccessToken
+	token.Expiry = accessToken.Expiry
+	return token, nil
+}
+
+// getJWTExpiry returns the expiration claim of the token, which is not verified, as the registry
+// is the one checking it, or the default expiry if it has none
+func getJWTExpiry(jwt string, defaultExpiry time.Time) time.Time {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return defaultExpiry
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return defaultExpiry
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err = json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return defaultExpiry
+	}
+	return time.Unix(claims.Exp, 0)
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commons
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"golang.org/x/oauth2/google"
+	"sigs.k8s.io/kind/pkg/errors"
+)
+
+const (
+	awsSecretsManagerScheme = "awsSecretsManager://"
+	azureKeyVaultScheme     = "azureKeyVault://"
+	gcpSecretManagerScheme  = "gcpSecretManager://"
+)
+
+// IsSecretReference returns whether the credential is a reference to a secret of a cloud secret manager
+func IsSecretReference(value string) bool {
+	return strings.HasPrefix(value, awsSecretsManagerScheme) ||
+		strings.HasPrefix(value, azureKeyVaultScheme) ||
+		strings.HasPrefix(value, gcpSecretManagerScheme)
+}
+
+// ResolveSecretReference returns the secret of the reference, read with the ambient identity:
+// awsSecretsManager://<name or ARN>, azureKeyVault://<vault>/<secret> or
+// gcpSecretManager://<project>/<secret>[/<version>]. A #<key> suffix selects a key of a JSON secret
+func ResolveSecretReference(reference string) (string, error) {
+	var secret string
+	var err error
+
+	ref, key, _ := strings.Cut(reference, "#")
+	switch {
+	case strings.HasPrefix(ref, awsSecretsManagerScheme):
+		secret, err = getAWSSecret(strings.TrimPrefix(ref, awsSecretsManagerScheme))
+	case strings.HasPrefix(ref, azureKeyVaultScheme):
+		secret, err = getAzureSecret(strings.TrimPrefix(ref, azureKeyVaultScheme))
+	case strings.HasPrefix(ref, gcpSecretManagerScheme):
+		secret, err = getGCPSecret(strings.TrimPrefix(ref, gcpSecretManagerScheme))
+	default:
+		return "", errors.New("unsupported secret reference: " + reference)
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "failed to resolve "+reference)
+	}
+	if key == "" {
+		return secret, nil
+	}
+
+	values := map[string]interface{}{}
+	if err = json.Unmarshal([]byte(secret), &values); err != nil {
+		return "", errors.Wrap(err, "the secret of "+reference+" is not a JSON object")
+	}
+	value, ok := values[key].(string)
+	if !ok {
+		return "", errors.New("the secret of " + reference + " has no " + key + " key")
+	}
+	return value, nil
+}
+
+func getAWSSecret(name string) (string, error) {
+	var ctx = context.Background()
+	var response struct {
+		SecretString string
+	}
+
+	// The region of the ARNs prevails over the ambient one
+	var region string
+	if arn := strings.Split(name, ":"); len(arn) > 3 && arn[0] == "arn" {
+		region = arn[3]
+	}
+	client, err := NewAWSClient(ctx, nil, region, "")
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(map[string]string{"SecretId": name})
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Post(ctx, "secretsmanager", "application/x-amz-json-1.1", "secretsmanager.GetSecretValue", string(body))
+	if err != nil {
+		return "", err
+	}
+	if err = json.Unmarshal(resp, &response); err != nil {
+		return "", err
+	}
+	return response.SecretString, nil
+}
+
+func getAzureSecret(path string) (string, error) {
+	var ctx = context.Background()
+	var response struct {
+		Value string `json:"value"`
+	}
+
+	vault, secret, found := strings.Cut(path, "/")
+	if !found || vault == "" || secret == "" {
+		return "", errors.New("the reference must be azureKeyVault://<vault>/<secret>")
+	}
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return "", err
+	}
+	token, err := cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"https://vault.azure.net/.default"}})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get Azure token")
+	}
+	req, err := http.NewRequest(http.MethodGet, "https://"+vault+".vault.azure.net/secrets/"+url.PathEscape(secret)+"?api-version=7.4", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	resp, err := DoCloudRequest(req)
+	if err != nil {
+		return "", err
+	}
+	if err = json.Unmarshal(resp, &response); err != nil {
+		return "", err
+	}
+	return response.Value, nil
+}
+
+func getGCPSecret(path string) (string, error) {
+	var ctx = context.Background()
+	var response struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return "", errors.New("the reference must be gcpSecretManager://<project>/<secret>[/<version>]")
+	}
+	version := "latest"
+	if len(parts) == 3 {
+		version = parts[2]
+	}
+	creds, err := google.FindDefaultCredentials(ctx, "https://www.googleapis.com/auth/cloud-platform")
+	if err != nil {
+		return "", err
+	}
+	token, err := creds.TokenSource.Token()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get GCP token")
+	}
+	req, err := http.NewRequest(http.MethodGet, "https://secretmanager.googleapis.com/v1/projects/"+parts[0]+
+		"/secrets/"+parts[1]+"/versions/"+version+":access", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	resp, err := DoCloudRequest(req)
+	if err != nil {
+		return "", err
+	}
+	if err = json.Unmarshal(resp, &response); err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(response.Payload.Data)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ResolveSecretReferences returns a copy of the credentials struct with its references resolved,
+// which are saved by section and field to be written back in the secrets file
+func ResolveSecretReferences(credentials interface{}, section string, references map[string]string) (interface{}, error) {
+	v := reflect.ValueOf(credentials)
+	if v.Kind() != reflect.Struct {
+		return credentials, nil
+	}
+	resolved := reflect.New(v.Type()).Elem()
+	resolved.Set(v)
+	for i := 0; i < resolved.NumField(); i++ {
+		field := resolved.Field(i)
+		if field.Kind() != reflect.String || !field.CanSet() || !IsSecretReference(field.String()) {
+			continue
+		}
+		secret, err := ResolveSecretReference(field.String())
+		if err != nil {
+			return nil, err
+		}
+		references[section+"."+resolved.Type().Field(i).Name] = field.String()
+		field.SetString(secret)
+	}
+	return resolved.Interface(), nil
+}
+
+// withSecretReferences returns a copy of the credentials of the section with the references
+// they were resolved from, so that no secret is written in the secrets file
+func withSecretReferences(references map[string]string, section string, credentials map[string]string) map[string]string {
+	result := map[string]string{}
+	for k, v := range credentials {
+		if ref, ok := references[section+"."+k]; ok {
+			v = ref
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// withRegistriesSecretReferences returns a copy of the docker registries credentials, whose keys are
+// in snake case, with the references they were resolved from
+func withRegistriesSecretReferences(references map[string]string, registries []map[string]interface{}) []map[string]interface{} {
+	var result []map[string]interface{}
+	for _, registry := range registries {
+		url, _ := registry["url"].(string)
+		section := "docker_registries." + url + "."
+		copied := map[string]interface{}{}
+		for k, v := range registry {
+			copied[k] = v
+		}
+		for key, ref := range references {
+			if strings.HasPrefix(key, section) {
+				copied[snakeCase(strings.TrimPrefix(key, section))] = ref
+			}
+		}
+		result = append(result, copied)
+	}
+	return result
+}
@@ -19,6 +19,9 @@ package commons
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"io"
+	"path/filepath"
 	"regexp"
 	"time"
 	"unicode"
@@ -30,14 +33,13 @@ import (
 	"gopkg.in/yaml.v3"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/alessio/shellescape"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	vault "github.com/sosedoff/ansible-vault-go"
 	"sigs.k8s.io/kind/pkg/cluster/nodes"
 	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/log"
 )
 
 const secretName = "secrets.yml"
@@ -66,11 +68,16 @@ func EnsureSecretsFile(spec KeosSpec, vaultPassword string, clusterCredentials C
 
 	edited := false
 
-	credentials := clusterCredentials.ProviderCredentials
-	dockerRegistry := clusterCredentials.KeosRegistryCredentials
-	dockerRegistries := clusterCredentials.DockerRegistriesCredentials
-	helmRepository := clusterCredentials.HelmRepositoryCredentials
+	// The credentials resolved from secret managers are written as references
+	references := clusterCredentials.SecretReferences
+	credentials := withSecretReferences(references, "provider", clusterCredentials.ProviderCredentials)
+	dockerRegistry := withSecretReferences(references, "keos_registry", clusterCredentials.KeosRegistryCredentials)
+	dockerRegistries := withRegistriesSecretReferences(references, clusterCredentials.DockerRegistriesCredentials)
+	helmRepository := withSecretReferences(references, "helm_repository", clusterCredentials.HelmRepositoryCredentials)
 	github_token := clusterCredentials.GithubToken
+	if ref, ok := references["github_token"]; ok {
+		github_token = ref
+	}
 
 	if spec.InfraProvider == "gcp" || spec.ControlPlane.Managed {
 		credentials["region"] = spec.Region
@@ -168,6 +175,164 @@ func EnsureSecretsFile(spec KeosSpec, vaultPassword string, clusterCredentials C
 	return nil
 }
 
+// descriptorSeparator splits the documents of the descriptor, and not the lines of its block scalars
+var descriptorSeparator = regexp.MustCompile(`(?m)^---\n`)
+
+var descriptorEnvVar = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(?:(:-|:\?)([^}]*))?\}`)
+
+// ExpandEnvVars replaces the ${VAR} references in the string values of the descriptor with the
+// environment, where ${VAR:-default} sets a default value, ${VAR:?message} requires the variable
+// and $${VAR} is kept as is. The references to unset variables are kept as is too, as the user data
+// and the manifests of the descriptor may hold their own. The values are expanded once parsed, so
+// they cannot add keys or documents to the descriptor
+func ExpandEnvVars(descriptor string) (string, error) {
+	var missing []string
+	expand := func(value string) string {
+		return descriptorEnvVar.ReplaceAllStringFunc(value, func(ref string) string {
+			if strings.HasPrefix(ref, "$$") {
+				return ref[1:]
+			}
+			groups := descriptorEnvVar.FindStringSubmatch(ref)
+			value, found := os.LookupEnv(groups[1])
+			switch groups[2] {
+			case ":-":
+				if value == "" {
+					value = groups[3]
+				}
+			case ":?":
+				if value == "" {
+					message := groups[3]
+					if message == "" {
+						message = "is required"
+					}
+					missing = append(missing, groups[1]+" "+message)
+				}
+			default:
+				if !found {
+					return ref
+				}
+			}
+			return value
+		})
+	}
+
+	var expanded bytes.Buffer
+	decoder := yaml.NewDecoder(strings.NewReader(descriptor))
+	encoder := yaml.NewEncoder(&expanded)
+	encoder.SetIndent(2)
+	for {
+		var document yaml.Node
+		err := decoder.Decode(&document)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", errors.Wrap(err, "failed to parse the descriptor")
+		}
+		if len(document.Content) == 0 || document.Content[0].ShortTag() == "!!null" {
+			continue
+		}
+		expandScalars(&document, expand)
+		if err = encoder.Encode(&document); err != nil {
+			return "", err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	if len(missing) > 0 {
+		return "", errors.New("failed to expand the environment variables: " + strings.Join(missing, ", "))
+	}
+	return expanded.String(), nil
+}
+
+// expandScalars expands the string scalars of the node. The plain scalars are typed again once
+// expanded, so a ${VAR} holding a number or a boolean is read as such
+func expandScalars(node *yaml.Node, expand func(string) string) {
+	if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!str" {
+		value := expand(node.Value)
+		if value != node.Value {
+			node.Value = value
+			if node.Style == 0 {
+				node.Tag = ""
+			}
+		}
+	}
+	for _, child := range node.Content {
+		expandScalars(child, expand)
+	}
+}
+
+// extendManifest deep merges the manifest over the manifest of the same kind in the base
+// file referenced by its "extends" key, where mappings are merged and other values replaced
+func extendManifest(manifest string, dir string, extended []string) (string, error) {
+	var overlay map[string]interface{}
+	if err := yaml.Unmarshal([]byte(manifest), &overlay); err != nil {
+		return "", err
+	}
+	basePath, ok := overlay["extends"].(string)
+	if !ok {
+		return manifest, nil
+	}
+	delete(overlay, "extends")
+	if !filepath.IsAbs(basePath) {
+		basePath = filepath.Join(dir, basePath)
+	}
+	if Contains(extended, basePath) {
+		return "", errors.New("failed to extend " + basePath + ": circular extends")
+	}
+
+	baseRAW, err := os.ReadFile(basePath)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read the base descriptor "+basePath)
+	}
+	baseDescriptor, err := ExpandEnvVars(string(baseRAW))
+	if err != nil {
+		return "", err
+	}
+
+	for _, baseManifest := range descriptorSeparator.Split(baseDescriptor, -1) {
+		var base map[string]interface{}
+		if err = yaml.Unmarshal([]byte(baseManifest), &base); err != nil {
+			return "", err
+		}
+		if base == nil || base["kind"] != overlay["kind"] {
+			continue
+		}
+		baseManifest, err = extendManifest(baseManifest, filepath.Dir(basePath), append(extended, basePath))
+		if err != nil {
+			return "", err
+		}
+		base = nil
+		if err = yaml.Unmarshal([]byte(baseManifest), &base); err != nil {
+			return "", err
+		}
+		merged, err := yaml.Marshal(mergeMaps(base, overlay))
+		if err != nil {
+			return "", err
+		}
+		return string(merged), nil
+	}
+	return "", errors.New("failed to extend " + basePath + ": " + fmt.Sprint(overlay["kind"]) + " manifest not found")
+}
+
+func mergeMaps(base map[string]interface{}, overlay map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overlay {
+		baseValue, baseIsMap := merged[k].(map[string]interface{})
+		overlayValue, overlayIsMap := v.(map[string]interface{})
+		if baseIsMap && overlayIsMap {
+			merged[k] = mergeMaps(baseValue, overlayValue)
+		} else {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
 // func RewriteDescriptorFile(descriptorPath string, keosCluster KeosCluster, resources ...interface{}) error {
 func RewriteDescriptorFile(descriptorPath string) error {
 
@@ -222,6 +387,29 @@ func encryptSecret(secretMap map[string]map[string]interface{}, vaultPassword st
 	return nil
 }
 
+// RotateSecretsPassword re-encrypts the secrets file with a new vault password,
+// checking the new file can be decrypted before replacing the old one
+func RotateSecretsPassword(secretsPath string, vaultPassword string, newVaultPassword string) error {
+	data, err := vault.DecryptFile(secretsPath, vaultPassword)
+	if err != nil {
+		return errors.Wrap(err, "failed to decrypt the secrets file")
+	}
+
+	rotatedPath := secretsPath + ".rotated"
+	err = vault.EncryptFile(rotatedPath, data, newVaultPassword)
+	if err != nil {
+		return errors.Wrap(err, "failed to encrypt the secrets file")
+	}
+	defer os.Remove(rotatedPath)
+
+	rotatedData, err := vault.DecryptFile(rotatedPath, newVaultPassword)
+	if err != nil || rotatedData != data {
+		return errors.New("the secrets file does not round-trip with the new vault password")
+	}
+
+	return os.Rename(rotatedPath, secretsPath)
+}
+
 func removeKey(nodes []*yaml.Node, key string) []*yaml.Node {
 	newNodes := []*yaml.Node{}
 	for _, node := range nodes {
@@ -244,31 +432,39 @@ func removeKey(nodes []*yaml.Node, key string) []*yaml.Node {
 	return newNodes
 }
 
+var retryConditions = []*regexp.Regexp{
+	regexp.MustCompile("dial tcp"),
+	regexp.MustCompile("NotFound"),
+	regexp.MustCompile("timed out waiting"),
+	regexp.MustCompile("failed calling webhook.*timeout.*"),
+}
+
 func ExecuteCommand(n nodes.Node, command string, timeout int, retries int, envVars ...[]string) (string, error) {
 	var err error
-	var raw bytes.Buffer
+	newOutput := func() *commandOutput {
+		o := newCommandOutput(MaxCommandOutputSize, retryConditions)
+		if readsSecrets.MatchString(command) {
+			o.logger = log.NoopInfoLogger{}
+		}
+		return o
+	}
+	raw := newOutput()
 	cmd := n.Command("sh", "-c", command)
 	if len(envVars) > 0 {
 		cmd.SetEnv(envVars[0]...)
 	}
-	retryConditions := []string{"dial tcp", "NotFound", "timed out waiting", "failed calling webhook.*timeout.*"}
 	provisionCommands := strings.Contains(command, "kubectl") || strings.Contains(command, "helm") || strings.Contains(command, "clusterctl")
 	for i := 0; i < retries; i++ {
-		raw = bytes.Buffer{}
-		err = cmd.SetStdout(&raw).SetStderr(&raw).Run()
-		retry := false
-		for _, condition := range retryConditions {
-			if regexp.MustCompile(condition).MatchString(raw.String()) {
-				retry = true
-			}
-		}
-		if err == nil || !(provisionCommands && retry) {
+		raw = newOutput()
+		err = cmd.SetStdout(raw).SetStderr(raw).Run()
+		raw.flush()
+		if err == nil || !(provisionCommands && raw.matched) {
 			break
 		}
 		time.Sleep(time.Duration(timeout) * time.Second)
 	}
-	if strings.Contains(raw.String(), "Error:") || strings.Contains(raw.String(), "Error from server") {
-		return "", errors.New("Command Output: " + raw.String())
+	if raw.failed {
+		return "", errors.New("Command Output: " + redactSecrets(raw.String()))
 	}
 	if err != nil {
 		return "", err
@@ -276,6 +472,35 @@ func ExecuteCommand(n nodes.Node, command string, timeout int, retries int, envV
 	return raw.String(), nil
 }
 
+// WriteFile writes the content to the file of the node through the stdin of the command, so it is
+// written as is whatever quotes, $ or backticks it holds, with the Windows line endings converted
+func WriteFile(n nodes.Node, path string, content string) error {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	target := ShellQuote(path)
+	// The home directory is expanded by the shell
+	if strings.HasPrefix(path, "~/") {
+		target = "~/" + ShellQuote(strings.TrimPrefix(path, "~/"))
+	}
+	var stderr bytes.Buffer
+	cmd := n.Command("sh", "-c", "cat > "+target)
+	if err := cmd.SetStdin(strings.NewReader(content)).SetStderr(&stderr).Run(); err != nil {
+		return errors.Wrap(err, "failed to write "+path+": "+strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// ShellQuote returns the arguments as a command line, each one quoted for the shell if needed
+func ShellQuote(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellescape.Quote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
 func snakeCase(s string) string {
 	var result []rune
 	for i, c := range s {
@@ -311,21 +536,6 @@ func Contains(s []string, str string) bool {
 	return false
 }
 
-func AWSGetConfig(ctx context.Context, secrets map[string]string, region string) (aws.Config, error) {
-	customProvider := credentials.NewStaticCredentialsProvider(
-		secrets["AccessKey"], secrets["SecretKey"], "",
-	)
-	cfg, err := config.LoadDefaultConfig(
-		ctx,
-		config.WithCredentialsProvider(customProvider),
-		config.WithRegion(region),
-	)
-	if err != nil {
-		return aws.Config{}, err
-	}
-	return cfg, nil
-}
-
 func AWSIsPrivateSubnet(ctx context.Context, svc *ec2.Client, subnetID *string) (bool, error) {
 	keyname := "association.subnet-id"
 	drtInput := &ec2.DescribeRouteTablesInput{
@@ -407,6 +617,22 @@ func AzureGetConfig(secrets map[string]string) (*azidentity.ClientSecretCredenti
 	return cfg, nil
 }
 
+// AzureNetworkSubscription returns the subscription of the network resources
+func AzureNetworkSubscription(secrets map[string]string) string {
+	if secrets["NetworkSubscriptionID"] != "" {
+		return secrets["NetworkSubscriptionID"]
+	}
+	return secrets["SubscriptionID"]
+}
+
+// GCPNetworkProject returns the project of the network resources
+func GCPNetworkProject(secrets map[string]string) string {
+	if secrets["NetworkProjectID"] != "" {
+		return secrets["NetworkProjectID"]
+	}
+	return secrets["ProjectID"]
+}
+
 func initControlPlaneRootVolume(s KeosSpec, volumeType string, uniqueVolume bool) KeosSpec {
 	size := RootVolumeDefaultSize
 	if uniqueVolume {
@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commons
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestExpandEnvVars(t *testing.T) {
+	t.Setenv("EXPAND_REGION", "eu-west-1")
+	t.Setenv("EXPAND_QUANTITY", "3")
+	t.Setenv("EXPAND_EMPTY", "")
+
+	tests := []struct {
+		name       string
+		descriptor string
+		expanded   string
+		err        string
+	}{
+		{
+			name:       "variable",
+			descriptor: "region: ${EXPAND_REGION}\n",
+			expanded:   "region: eu-west-1\n",
+		},
+		{
+			name:       "plain number",
+			descriptor: "quantity: ${EXPAND_QUANTITY}\n",
+			expanded:   "quantity: 3\n",
+		},
+		{
+			name:       "quoted number",
+			descriptor: "name: \"${EXPAND_QUANTITY}\"\n",
+			expanded:   "name: \"3\"\n",
+		},
+		{
+			name:       "default",
+			descriptor: "region: ${EXPAND_EMPTY:-eu-central-1}\n",
+			expanded:   "region: eu-central-1\n",
+		},
+		{
+			name:       "required",
+			descriptor: "region: ${EXPAND_EMPTY:?must be set}\n",
+			err:        "EXPAND_EMPTY must be set",
+		},
+		{
+			name:       "unset variable",
+			descriptor: "user_data: |\n  echo ${HOSTNAME_OF_THE_NODE}\n",
+			expanded:   "user_data: |\n  echo ${HOSTNAME_OF_THE_NODE}\n",
+		},
+		{
+			name:       "escaped variable",
+			descriptor: "user_data: echo $${EXPAND_REGION}\n",
+			expanded:   "user_data: echo ${EXPAND_REGION}\n",
+		},
+		{
+			name:       "variable in a comment",
+			descriptor: "# ${EXPAND_INJECTION}\nregion: eu-west-1\n",
+			expanded:   "# ${EXPAND_INJECTION}\nregion: eu-west-1\n",
+		},
+		{
+			name:       "documents",
+			descriptor: "region: ${EXPAND_REGION}\n---\n---\nname: ${EXPAND_REGION}\n",
+			expanded:   "region: eu-west-1\n---\nname: eu-west-1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expanded, err := ExpandEnvVars(tt.descriptor)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected the error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expanded != tt.expanded {
+				t.Errorf("expected %q, got %q", tt.expanded, expanded)
+			}
+		})
+	}
+}
+
+// TestExpandEnvVarsInjection checks the values of the variables cannot add keys to the descriptor
+func TestExpandEnvVarsInjection(t *testing.T) {
+	t.Setenv("EXPAND_INJECTION", "x\nadmin: true")
+	t.Setenv("EXPAND_MAPPING", "x: y")
+	t.Setenv("EXPAND_DOCUMENT", "x\n---\nkind: ClusterConfig")
+
+	for _, variable := range []string{"EXPAND_INJECTION", "EXPAND_MAPPING", "EXPAND_DOCUMENT"} {
+		expanded, err := ExpandEnvVars("name: ${" + variable + "}\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		documents := descriptorSeparator.Split(expanded, -1)
+		if len(documents) != 1 {
+			t.Fatalf("the value of %s adds documents to the descriptor: %q", variable, expanded)
+		}
+		var value map[string]string
+		if err := yaml.Unmarshal([]byte(expanded), &value); err != nil {
+			t.Fatalf("the value of %s breaks the descriptor: %v", variable, err)
+		}
+		if len(value) != 1 || value["name"] != os.Getenv(variable) {
+			t.Errorf("the value of %s is not kept as the name: %q", variable, expanded)
+		}
+	}
+}
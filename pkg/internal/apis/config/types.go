@@ -107,6 +107,11 @@ type Node struct {
 	// KubeadmConfigPatchesJSON6902 are applied to the generated kubeadm config
 	// as patchesJson6902 to `kustomize build`
 	KubeadmConfigPatchesJSON6902 []PatchJSON6902
+
+	// Stratio: CPUs and Memory limit the resources of the node container, in
+	// the format of the docker run flags
+	CPUs   string
+	Memory string
 }
 
 // NodeRole defines possible role for nodes in a Kubernetes cluster managed by `kind`
@@ -18,6 +18,7 @@ package cli
 
 import (
 	"fmt"
+	"time"
 
 	"sigs.k8s.io/kind/pkg/log"
 )
@@ -31,6 +32,9 @@ type Status struct {
 	// for controlling coloring etc
 	successFormat string
 	failureFormat string
+	// for measuring the duration of the phases
+	started       time.Time
+	phaseObserver func(phase string, duration time.Duration, success bool)
 }
 
 // StatusForLogger returns a new status object for the logger l,
@@ -55,12 +59,18 @@ func StatusForLogger(l log.Logger) *Status {
 	return s
 }
 
+// SetPhaseObserver sets a function called with the duration and the outcome of each phase
+func (s *Status) SetPhaseObserver(observer func(phase string, duration time.Duration, success bool)) {
+	s.phaseObserver = observer
+}
+
 // Start starts a new phase of the status, if attached to a terminal
 // there will be a loading spinner with this status
 func (s *Status) Start(status string) {
 	s.End(true)
 	// set new status
 	s.status = status
+	s.started = time.Now()
 	if s.spinner != nil {
 		s.spinner.SetSuffix(fmt.Sprintf(" %s ", s.status))
 		s.spinner.Start()
@@ -85,6 +95,9 @@ func (s *Status) End(success bool) {
 	} else {
 		s.logger.V(0).Infof(s.failureFormat, s.status)
 	}
+	if s.phaseObserver != nil {
+		s.phaseObserver(s.status, time.Since(s.started), success)
+	}
 
 	s.status = ""
 }